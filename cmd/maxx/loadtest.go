@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/loadtest"
+)
+
+// runLoadTest implements `maxx loadtest`: synthetic traffic through the
+// executor against a fake upstream, printing a throughput/latency summary
+func runLoadTest(args []string) int {
+	fs := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	providerType := fs.String("provider", loadtest.FakeProviderType, "Upstream to drive traffic against (only \"fake\" is supported)")
+	concurrency := fs.Int("concurrency", 10, "Number of concurrent clients")
+	duration := fs.Duration("duration", 30*time.Second, "How long to send requests")
+	providers := fs.Int("providers", 1, "Number of fake providers to route between")
+	model := fs.String("model", "claude-sonnet-4-5", "Request model")
+	stream := fs.Bool("stream", false, "Send streaming requests")
+	latency := fs.Duration("latency", 200*time.Millisecond, "Simulated upstream latency")
+	jitter := fs.Duration("jitter", 50*time.Millisecond, "Random variation added to the upstream latency (±)")
+	errorRate := fs.Float64("error-rate", 0, "Fraction of upstream calls that fail with a retryable 503 (0-1)")
+	dataDir := fs.String("data", "", "Data directory for the load test database (default: a temporary directory, removed afterwards)")
+	fs.Parse(args)
+
+	if *providerType != loadtest.FakeProviderType {
+		fmt.Fprintf(os.Stderr, "unsupported provider %q: only %q is supported\n", *providerType, loadtest.FakeProviderType)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	fmt.Printf("Load testing for %v with %d clients against %d fake provider(s)...\n", *duration, *concurrency, *providers)
+	report, err := loadtest.Run(ctx, loadtest.Config{
+		Concurrency: *concurrency,
+		Duration:    *duration,
+		Providers:   *providers,
+		Model:       *model,
+		Stream:      *stream,
+		DataDir:     *dataDir,
+		Fake: loadtest.FakeOptions{
+			Latency:      *latency,
+			Jitter:       *jitter,
+			ErrorRate:    *errorRate,
+			InputTokens:  1000,
+			OutputTokens: 200,
+		},
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "load test failed: %v\n", err)
+		return 1
+	}
+	report.Print(os.Stdout)
+	return 0
+}
@@ -44,7 +44,37 @@ func generateInstanceID() string {
 	return fmt.Sprintf("%s-%d", hostname, time.Now().UnixNano())
 }
 
+// resolveDataDir determines the data directory: CLI flag > MAXX_DATA_DIR > default
+func resolveDataDir(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if envDataDir := os.Getenv("MAXX_DATA_DIR"); envDataDir != "" {
+		return envDataDir
+	}
+	return getDefaultDataDir()
+}
+
+// openDatabase opens the database: MAXX_DSN if set, otherwise the SQLite file at dbPath
+func openDatabase(dbPath string) (*sqlite.DB, error) {
+	if dsn := os.Getenv("MAXX_DSN"); dsn != "" {
+		log.Printf("Using database DSN from MAXX_DSN environment variable")
+		return sqlite.NewDBWithDSN(dsn)
+	}
+	return sqlite.NewDB(dbPath)
+}
+
 func main() {
+	// Subcommands
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "loadtest":
+			os.Exit(runLoadTest(os.Args[2:]))
+		case "validate":
+			os.Exit(runValidate(os.Args[2:]))
+		}
+	}
+
 	// Parse flags
 	addr := flag.String("addr", ":9880", "Server address")
 	dataDir := flag.String("data", "", "Data directory for database and logs (default: ~/.config/maxx)")
@@ -57,15 +87,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	// Determine data directory: CLI flag > env var > default
-	var dataDirPath string
-	if *dataDir != "" {
-		dataDirPath = *dataDir
-	} else if envDataDir := os.Getenv("MAXX_DATA_DIR"); envDataDir != "" {
-		dataDirPath = envDataDir
-	} else {
-		dataDirPath = getDefaultDataDir()
-	}
+	dataDirPath := resolveDataDir(*dataDir)
 
 	// Ensure data directory exists
 	if err := os.MkdirAll(dataDirPath, 0755); err != nil {
@@ -76,15 +98,7 @@ func main() {
 	dbPath := filepath.Join(dataDirPath, "maxx.db")
 	logPath := filepath.Join(dataDirPath, "maxx.log")
 
-	// Initialize database (DSN > default SQLite path)
-	var db *sqlite.DB
-	var err error
-	if dsn := os.Getenv("MAXX_DSN"); dsn != "" {
-		log.Printf("Using database DSN from MAXX_DSN environment variable")
-		db, err = sqlite.NewDBWithDSN(dsn)
-	} else {
-		db, err = sqlite.NewDB(dbPath)
-	}
+	db, err := openDatabase(dbPath)
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
@@ -103,6 +117,7 @@ func main() {
 	codexQuotaRepo := sqlite.NewCodexQuotaRepository(db)
 	cooldownRepo := sqlite.NewCooldownRepository(db)
 	failureCountRepo := sqlite.NewFailureCountRepository(db)
+	cooldownEventRepo := sqlite.NewCooldownEventRepository(db)
 	apiTokenRepo := sqlite.NewAPITokenRepository(db)
 	modelMappingRepo := sqlite.NewModelMappingRepository(db)
 	usageStatsRepo := sqlite.NewUsageStatsRepository(db)
@@ -112,9 +127,16 @@ func main() {
 	// Initialize cooldown manager with database persistence
 	cooldown.Default().SetRepository(cooldownRepo)
 	cooldown.Default().SetFailureCountRepository(failureCountRepo)
+	cooldown.Default().SetEventRepository(cooldownEventRepo)
 	if err := cooldown.Default().LoadFromDatabase(); err != nil {
 		log.Printf("Warning: Failed to load cooldowns from database: %v", err)
 	}
+	if err := cooldown.Default().LoadFailureWeights(settingRepo); err != nil {
+		log.Printf("Warning: Failed to load cooldown failure weights: %v", err)
+	}
+	if err := cooldown.Default().LoadEventRetention(settingRepo); err != nil {
+		log.Printf("Warning: Failed to load cooldown event retention: %v", err)
+	}
 
 	// Generate instance ID and mark stale requests as failed
 	instanceID := generateInstanceID()
@@ -141,6 +163,9 @@ func main() {
 		log.Printf("Fixed %d failed attempts without end_time", count)
 	}
 
+	// Readiness for /readyz: not ready until caches and adapters are loaded
+	readiness := handler.NewReadiness(handler.ReadinessCaches, handler.ReadinessAdapters)
+
 	// Create cached repositories
 	cachedProviderRepo := cached.NewProviderRepository(providerRepo)
 	cachedRouteRepo := cached.NewRouteRepository(routeRepo)
@@ -152,35 +177,57 @@ func main() {
 	cachedModelMappingRepo := cached.NewModelMappingRepository(modelMappingRepo)
 
 	// Load cached data
+	cacheLoadFailed := false
 	if err := cachedProviderRepo.Load(); err != nil {
+		cacheLoadFailed = true
 		log.Printf("Warning: Failed to load providers cache: %v", err)
 	}
 	if err := cachedRouteRepo.Load(); err != nil {
+		cacheLoadFailed = true
 		log.Printf("Warning: Failed to load routes cache: %v", err)
 	}
 	if err := cachedRetryConfigRepo.Load(); err != nil {
+		cacheLoadFailed = true
 		log.Printf("Warning: Failed to load retry configs cache: %v", err)
 	}
 	if err := cachedRoutingStrategyRepo.Load(); err != nil {
+		cacheLoadFailed = true
 		log.Printf("Warning: Failed to load routing strategies cache: %v", err)
 	}
 	if err := cachedProjectRepo.Load(); err != nil {
+		cacheLoadFailed = true
 		log.Printf("Warning: Failed to load projects cache: %v", err)
 	}
 	if err := cachedAPITokenRepo.Load(); err != nil {
+		cacheLoadFailed = true
 		log.Printf("Warning: Failed to load API tokens cache: %v", err)
 	}
 	if err := cachedModelMappingRepo.Load(); err != nil {
+		cacheLoadFailed = true
 		log.Printf("Warning: Failed to load model mappings cache: %v", err)
 	}
 
+	if cacheLoadFailed {
+		log.Printf("Warning: Some caches failed to load, /readyz will report not ready")
+	} else {
+		readiness.MarkReady(handler.ReadinessCaches)
+	}
+
 	// Create router
 	r := router.NewRouter(cachedRouteRepo, cachedProviderRepo, cachedRoutingStrategyRepo, cachedRetryConfigRepo, cachedProjectRepo)
+	quotaSource := router.NewQuotaSource(antigravityQuotaRepo, codexQuotaRepo)
+	r.SetQuotaSource(quotaSource)
+	healthScorer := router.NewHealthScorer(cachedProviderRepo, usageStatsRepo, settingRepo, quotaSource)
+	r.SetHealthScorer(healthScorer)
 
 	// Initialize provider adapters
 	if err := r.InitAdapters(); err != nil {
 		log.Printf("Warning: Failed to initialize adapters: %v", err)
 	}
+	readiness.MarkReady(handler.ReadinessAdapters)
+
+	// Optional provider connectivity self-test (bounded timeout)
+	core.RunProviderSelfTest(r, settingRepo)
 
 	// Start cooldown cleanup goroutine with graceful shutdown support
 	cleanupCtx, cleanupCancel := context.WithCancel(context.Background())
@@ -206,6 +253,15 @@ func main() {
 	}()
 	log.Println("[Cooldown] Background cleanup started (runs every 1 hour)")
 
+	// Flush batched API token usage (use_count / last_used_at) periodically
+	go cachedAPITokenRepo.RunUsageFlusher(cleanupCtx, core.APITokenUsageFlushInterval)
+
+	// Refresh provider health scores (health_weighted routing, /admin/provider-health)
+	go healthScorer.Run(cleanupCtx, router.HealthScoreRefreshInterval)
+
+	// Keep connections warm for providers with transport.warmConnections
+	go r.RunConnectionWarmup(cleanupCtx)
+
 	// Create WebSocket hub
 	wsHub := handler.NewWebSocketHub()
 
@@ -237,10 +293,14 @@ func main() {
 		Settings:           settingRepo,
 		AntigravityTaskSvc: antigravityTaskSvc,
 		CodexTaskSvc:       codexTaskSvc,
+		CostAnomalySvc:     service.NewCostAnomalyService(usageStatsRepo, settingRepo, wsHub),
+		DailyDigestSvc:     service.NewDailyDigestService(usageStatsRepo, providerRepo, settingRepo),
+		DetailArchiver:     service.NewRequestDetailArchiver(proxyRequestRepo, attemptRepo, settingRepo),
+		Broadcaster:        wsHub,
 	})
 
 	// Setup log output to broadcast via WebSocket
-	logWriter := handler.NewWebSocketLogWriter(wsHub, os.Stdout, logPath)
+	logWriter := handler.NewWebSocketLogWriter(wsHub, os.Stdout, logPath, handler.LoadLogRotationConfig(settingRepo))
 	log.SetOutput(logWriter)
 
 	// Create project waiter for force project binding
@@ -251,10 +311,16 @@ func main() {
 
 	// Create executor
 	exec := executor.NewExecutor(r, proxyRequestRepo, attemptRepo, cachedRetryConfigRepo, cachedSessionRepo, cachedModelMappingRepo, settingRepo, wsHub, projectWaiter, instanceID, statsAggregator)
+	costCalculator := core.LoadCostCalculator(settingRepo)
+	exec.SetCostCalculator(costCalculator)
 
 	// Create client adapter
 	clientAdapter := client.NewAdapter()
 
+	// Create request tracker for graceful shutdown and concurrency history
+	requestTracker := core.NewRequestTracker()
+	go requestTracker.RunConcurrencySampler(cleanupCtx, core.ConcurrencySampleInterval)
+
 	// Create admin service
 	pprofMgr := core.NewPprofManager(settingRepo)
 	adminService := service.NewAdminService(
@@ -272,11 +338,22 @@ func main() {
 		usageStatsRepo,
 		responseModelRepo,
 		modelPriceRepo,
+		antigravityQuotaRepo,
+		codexQuotaRepo,
 		*addr,
 		r, // Router implements ProviderAdapterRefresher interface
 		wsHub,
-		pprofMgr, // Pprof reloader
+		pprofMgr,       // Pprof reloader
+		exec,           // Executor implements RequestReplayer interface
+		exec,           // Executor implements RequestResolver interface
+		r,              // Router implements ProviderStatusReporter interface
+		exec,           // Executor implements RequestWatcher interface
+		exec,           // Executor implements RouteProber interface
+		requestTracker, // RequestTracker implements ConcurrencyHistorySource interface
 	)
+	adminService.SetCostCalculator(costCalculator)
+
+	go adminService.RunCostReconciler(cleanupCtx, service.CostReconcileInterval)
 
 	// Start pprof manager (will check system settings)
 	if err := pprofMgr.Start(context.Background()); err != nil {
@@ -310,13 +387,14 @@ func main() {
 		log.Println("Proxy token authentication is enabled")
 	}
 
-	// Create request tracker for graceful shutdown
-	requestTracker := core.NewRequestTracker()
-
 	// Create handlers
-	proxyHandler := handler.NewProxyHandler(clientAdapter, exec, cachedSessionRepo, tokenAuthMiddleware)
+	clientIPResolver := handler.NewClientIPResolver(settingRepo)
+	proxyHandler := handler.NewProxyHandler(clientAdapter, exec, cachedSessionRepo, tokenAuthMiddleware, clientIPResolver)
 	proxyHandler.SetRequestTracker(requestTracker)
-	adminHandler := handler.NewAdminHandler(adminService, backupService, logPath)
+	proxyHandler.SetStorageHealth(db.WriteHealth())
+	proxyHandler.SetSettingRepo(settingRepo)
+	proxyHandler.SetTokenQuota(service.NewTokenQuotaService(proxyRequestRepo, settingRepo))
+	adminHandler := handler.NewAdminHandler(adminService, backupService, logPath, logWriter.Stream())
 	authHandler := handler.NewAuthHandler(authMiddleware)
 	antigravityHandler := handler.NewAntigravityHandler(adminService, antigravityQuotaRepo, wsHub)
 	antigravityHandler.SetTaskService(antigravityTaskSvc)
@@ -351,12 +429,9 @@ func main() {
 	// Gemini API (Google AI Studio style)
 	mux.Handle("/v1beta/models/", proxyHandler)
 
-	// Health check
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok"}`))
-	})
+	// Health check (liveness) and readiness
+	mux.HandleFunc("/health", handler.NewHealthHandler(db.WriteHealth()))
+	mux.HandleFunc("/readyz", handler.NewReadyHandler(readiness, db.WriteHealth()))
 
 	// WebSocket endpoint
 	mux.HandleFunc("/ws", wsHub.HandleWebSocket)
@@ -407,6 +482,7 @@ func main() {
 	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 	sig := <-sigCh
 	log.Printf("Received signal %v, initiating graceful shutdown...", sig)
+	readiness.SetShuttingDown()
 
 	// Step 1: Wait for active proxy requests to complete
 	activeCount := requestTracker.ActiveCount()
@@ -449,5 +525,10 @@ func main() {
 		}
 	}
 
+	// Step 4: Persist remaining API token usage
+	if err := cachedAPITokenRepo.FlushUsage(); err != nil {
+		log.Printf("Warning: Failed to flush API token usage: %v", err)
+	}
+
 	log.Printf("Server stopped")
 }
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/awsl-project/maxx/internal/repository/cached"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+	"github.com/awsl-project/maxx/internal/router"
+	"github.com/awsl-project/maxx/internal/service"
+)
+
+// runValidate implements `maxx validate`: load the configuration from the
+// database and report problems. Exits 1 when any error is found (warnings
+// alone exit 0, or 1 with -strict), 2 when the database cannot be read.
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	dataDir := fs.String("data", "", "Data directory containing maxx.db (default: ~/.config/maxx, or MAXX_DATA_DIR / MAXX_DSN)")
+	live := fs.Bool("live", false, "Also check that each provider is reachable and its credentials work")
+	strict := fs.Bool("strict", false, "Exit non-zero on warnings too")
+	jsonOutput := fs.Bool("json", false, "Print the report as JSON")
+	fs.Parse(args)
+
+	// 迁移与适配器初始化日志不混入报告
+	log.SetOutput(io.Discard)
+	dbPath := filepath.Join(resolveDataDir(*dataDir), "maxx.db")
+	if _, err := os.Stat(dbPath); err != nil && os.Getenv("MAXX_DSN") == "" {
+		fmt.Fprintf(os.Stderr, "database not found: %v\n", err)
+		return 2
+	}
+	db, err := openDatabase(dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to open database: %v\n", err)
+		return 2
+	}
+	defer db.Close()
+
+	providerRepo := sqlite.NewProviderRepository(db)
+	routeRepo := sqlite.NewRouteRepository(db)
+	settingRepo := sqlite.NewSystemSettingRepository(db)
+
+	// 路由预览与运行时使用同一个 Router
+	cachedProviderRepo := cached.NewProviderRepository(providerRepo)
+	cachedRouteRepo := cached.NewRouteRepository(routeRepo)
+	cachedRetryConfigRepo := cached.NewRetryConfigRepository(sqlite.NewRetryConfigRepository(db))
+	cachedRoutingStrategyRepo := cached.NewRoutingStrategyRepository(sqlite.NewRoutingStrategyRepository(db))
+	cachedProjectRepo := cached.NewProjectRepository(sqlite.NewProjectRepository(db))
+	for _, load := range []func() error{
+		cachedProviderRepo.Load, cachedRouteRepo.Load, cachedRetryConfigRepo.Load,
+		cachedRoutingStrategyRepo.Load, cachedProjectRepo.Load,
+	} {
+		if err := load(); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to load configuration: %v\n", err)
+			return 2
+		}
+	}
+	r := router.NewRouter(cachedRouteRepo, cachedProviderRepo, cachedRoutingStrategyRepo, cachedRetryConfigRepo, cachedProjectRepo)
+	_ = r.InitAdapters() // 初始化失败的 Provider 会在路由检查中报告
+
+	validator := service.NewConfigValidator(
+		providerRepo,
+		routeRepo,
+		sqlite.NewModelMappingRepository(db),
+		sqlite.NewModelPriceRepository(db),
+		sqlite.NewResponseModelRepository(db),
+		settingRepo,
+		r,
+	)
+	report, err := validator.Validate(context.Background(), *live)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "validation failed: %v\n", err)
+		return 2
+	}
+
+	if *jsonOutput {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		for _, issue := range report.Issues {
+			fmt.Printf("%-7s [%s] %s: %s\n", issue.Severity, issue.Category, issue.Subject, issue.Message)
+		}
+		fmt.Printf("%d error(s), %d warning(s)\n", report.Errors, report.Warnings)
+	}
+
+	if report.Errors > 0 || (*strict && report.Warnings > 0) {
+		return 1
+	}
+	return 0
+}
@@ -35,3 +35,17 @@ func GetAdapterFactory(providerType string) (AdapterFactory, bool) {
 	f, ok := adapterFactories[providerType]
 	return f, ok
 }
+
+// StreamActivityNotifier is optionally implemented by the response writer passed
+// to Execute. Adapters call it for upstream stream data they receive but do not
+// forward (e.g. stripped heartbeats), so the upstream is not treated as stalled
+type StreamActivityNotifier interface {
+	NotifyStreamActivity()
+}
+
+// HealthChecker is optionally implemented by adapters that can verify
+// upstream connectivity and credentials without sending a real request
+type HealthChecker interface {
+	// CheckHealth returns nil if the upstream is reachable and the credentials are accepted
+	CheckHealth(ctx context.Context) error
+}
@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -18,6 +17,7 @@ import (
 	ctxutil "github.com/awsl-project/maxx/internal/context"
 	"github.com/awsl-project/maxx/internal/domain"
 	"github.com/awsl-project/maxx/internal/usage"
+	"golang.org/x/sync/singleflight"
 )
 
 func init() {
@@ -44,10 +44,16 @@ func NewAdapter(p *domain.Provider) (provider.ProviderAdapter, error) {
 	return &AntigravityAdapter{
 		provider:   p,
 		tokenCache: &TokenCache{},
-		httpClient: newUpstreamHTTPClient(),
+		httpClient: newUpstreamHTTPClient(p.Config.Transport),
 	}, nil
 }
 
+// WarmConnections keeps connections to the production endpoint warm; the daily
+// endpoint is only a fallback (see provider.WarmUpstream)
+func (a *AntigravityAdapter) WarmConnections(ctx context.Context, n int) error {
+	return provider.WarmUpstream(ctx, a.httpClient, []string{V1InternalBaseURLProd}, n)
+}
+
 func (a *AntigravityAdapter) SupportedClientTypes() []domain.ClientType {
 	// Antigravity natively supports Claude and Gemini by converting to Gemini/v1internal API
 	// OpenAI requests will be converted to Claude format by Executor before reaching this adapter
@@ -372,14 +378,44 @@ func (a *AntigravityAdapter) getAccessToken(ctx context.Context) (string, error)
 	return accessToken, nil
 }
 
+// refreshGroup 合并同一 refresh token 的并发刷新，token 过期时并发请求只向 Google 发起一次刷新
+var refreshGroup singleflight.Group
+
+// googleTokenURL 刷新端点，测试中可替换
+var googleTokenURL = GoogleTokenURL
+
+type refreshedToken struct {
+	accessToken string
+	expiresIn   int
+}
+
+// refreshGoogleToken 刷新 access token；同一 refresh token 的并发调用共享一次上游请求
 func refreshGoogleToken(ctx context.Context, refreshToken string) (string, int, error) {
+	// 共享的刷新不随单个调用方取消，各调用方仍可按自己的 ctx 提前返回
+	ch := refreshGroup.DoChan(refreshToken, func() (any, error) {
+		accessToken, expiresIn, err := doRefreshGoogleToken(context.WithoutCancel(ctx), refreshToken)
+		return refreshedToken{accessToken, expiresIn}, err
+	})
+	select {
+	case <-ctx.Done():
+		return "", 0, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return "", 0, res.Err
+		}
+		token := res.Val.(refreshedToken)
+		return token.accessToken, token.expiresIn, nil
+	}
+}
+
+func doRefreshGoogleToken(ctx context.Context, refreshToken string) (string, int, error) {
 	data := url.Values{}
 	data.Set("grant_type", "refresh_token")
 	data.Set("refresh_token", refreshToken)
 	data.Set("client_id", OAuthClientID)
 	data.Set("client_secret", OAuthClientSecret)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://oauth2.googleapis.com/token", strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", googleTokenURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return "", 0, err
 	}
@@ -408,28 +444,10 @@ func refreshGoogleToken(ctx context.Context, refreshToken string) (string, int,
 	return result.AccessToken, result.ExpiresIn, nil
 }
 
-func newUpstreamHTTPClient() *http.Client {
-	// Mirrors Antigravity-Manager's reqwest client settings:
+func newUpstreamHTTPClient(cfg *domain.ProviderTransport) *http.Client {
+	// The defaults mirror Antigravity-Manager's reqwest client settings:
 	// connect_timeout=20s, pool_max_idle_per_host=16, pool_idle_timeout=90s, tcp_keepalive=60s, timeout=600s.
-	dialer := &net.Dialer{
-		Timeout:   20 * time.Second,
-		KeepAlive: 60 * time.Second,
-	}
-
-	transport := &http.Transport{
-		Proxy:                 http.ProxyFromEnvironment,
-		DialContext:           dialer.DialContext,
-		ForceAttemptHTTP2:     true,
-		MaxIdleConnsPerHost:   16,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   20 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-	}
-
-	return &http.Client{
-		Transport: transport,
-		Timeout:   600 * time.Second,
-	}
+	return provider.NewUpstreamHTTPClient(cfg, 600*time.Second)
 }
 
 // applyClaudePostProcess applies minimal post-processing for advanced features
@@ -532,6 +550,7 @@ func (a *AntigravityAdapter) handleNonStreamResponse(ctx context.Context, w http
 			CacheCreationCount:   metrics.CacheCreationCount,
 			Cache5mCreationCount: metrics.Cache5mCreationCount,
 			Cache1hCreationCount: metrics.Cache1hCreationCount,
+			ReasoningTokens:      metrics.ReasoningTokens,
 		})
 	}
 
@@ -627,6 +646,7 @@ func (a *AntigravityAdapter) handleStreamResponse(ctx context.Context, w http.Re
 					CacheCreationCount:   metrics.CacheCreationCount,
 					Cache5mCreationCount: metrics.Cache5mCreationCount,
 					Cache1hCreationCount: metrics.Cache1hCreationCount,
+					ReasoningTokens:      metrics.ReasoningTokens,
 				})
 			}
 
@@ -855,6 +875,7 @@ func (a *AntigravityAdapter) handleCollectedStreamResponse(ctx context.Context,
 			CacheCreationCount:   metrics.CacheCreationCount,
 			Cache5mCreationCount: metrics.Cache5mCreationCount,
 			Cache1hCreationCount: metrics.Cache1hCreationCount,
+			ReasoningTokens:      metrics.ReasoningTokens,
 		})
 	}
 
@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net"
 	"net/http"
 	"strings"
 	"sync"
@@ -53,7 +52,7 @@ func NewAdapter(p *domain.Provider) (provider.ProviderAdapter, error) {
 	adapter := &CodexAdapter{
 		provider:   p,
 		tokenCache: &TokenCache{},
-		httpClient: newUpstreamHTTPClient(),
+		httpClient: newUpstreamHTTPClient(p.Config.Transport),
 	}
 
 	// Initialize token cache from persisted config if available
@@ -71,6 +70,11 @@ func NewAdapter(p *domain.Provider) (provider.ProviderAdapter, error) {
 	return adapter, nil
 }
 
+// WarmConnections keeps connections to the Codex backend warm (see provider.WarmUpstream)
+func (a *CodexAdapter) WarmConnections(ctx context.Context, n int) error {
+	return provider.WarmUpstream(ctx, a.httpClient, []string{CodexBaseURL}, n)
+}
+
 func (a *CodexAdapter) SupportedClientTypes() []domain.ClientType {
 	return []domain.ClientType{domain.ClientTypeCodex}
 }
@@ -206,8 +210,13 @@ func (a *CodexAdapter) getAccessToken(ctx context.Context) (string, error) {
 	// Calculate expiration time (with 60s buffer)
 	expiresAt := time.Now().Add(time.Duration(tokenResp.ExpiresIn-60) * time.Second)
 
-	// Update cache
+	// Update cache; concurrent callers share one refresh, so only the first
+	// one to get here updates the cache and persists the token
 	a.tokenMu.Lock()
+	if a.tokenCache.AccessToken == tokenResp.AccessToken {
+		a.tokenMu.Unlock()
+		return tokenResp.AccessToken, nil
+	}
 	a.tokenCache = &TokenCache{
 		AccessToken: tokenResp.AccessToken,
 		ExpiresAt:   expiresAt,
@@ -377,26 +386,8 @@ func (a *CodexAdapter) sendFinalStreamEvents(eventChan domain.AdapterEventChan,
 	}
 }
 
-func newUpstreamHTTPClient() *http.Client {
-	dialer := &net.Dialer{
-		Timeout:   20 * time.Second,
-		KeepAlive: 60 * time.Second,
-	}
-
-	transport := &http.Transport{
-		Proxy:                 http.ProxyFromEnvironment,
-		DialContext:           dialer.DialContext,
-		ForceAttemptHTTP2:     true,
-		MaxIdleConnsPerHost:   16,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   20 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-	}
-
-	return &http.Client{
-		Transport: transport,
-		Timeout:   600 * time.Second,
-	}
+func newUpstreamHTTPClient(cfg *domain.ProviderTransport) *http.Client {
+	return provider.NewUpstreamHTTPClient(cfg, 600*time.Second)
 }
 
 func flattenHeaders(h http.Header) map[string]string {
@@ -12,6 +12,8 @@ import (
 	"net/url"
 	"strings"
 	"time"
+
+	"golang.org/x/sync/singleflight"
 )
 
 // PKCEChallenge holds PKCE verifier and challenge
@@ -182,14 +184,40 @@ func ExchangeCodeForTokens(ctx context.Context, code, redirectURI, codeVerifier
 	return &tokenResp, nil
 }
 
-// RefreshAccessToken refreshes the access token using a refresh token
+// refreshGroup 合并同一 refresh token 的并发刷新：token 过期时大量请求同时到达，
+// 只向 OpenAI 发起一次刷新并共享结果，避免 refresh token 轮换后其余刷新失败
+var refreshGroup singleflight.Group
+
+// tokenRefreshURL 刷新端点，测试中可替换
+var tokenRefreshURL = OpenAITokenURL
+
+// RefreshAccessToken refreshes the access token using a refresh token.
+// Concurrent calls for the same refresh token share a single upstream request.
 func RefreshAccessToken(ctx context.Context, refreshToken string) (*TokenResponse, error) {
+	// 共享的刷新不随单个调用方取消，各调用方仍可按自己的 ctx 提前返回
+	ch := refreshGroup.DoChan(refreshToken, func() (any, error) {
+		return refreshAccessToken(context.WithoutCancel(ctx), refreshToken)
+	})
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		// 每个调用方拿到独立副本，避免共享结果被修改
+		tokenResp := *res.Val.(*TokenResponse)
+		return &tokenResp, nil
+	}
+}
+
+func refreshAccessToken(ctx context.Context, refreshToken string) (*TokenResponse, error) {
 	data := url.Values{}
 	data.Set("grant_type", "refresh_token")
 	data.Set("client_id", OAuthClientID)
 	data.Set("refresh_token", refreshToken)
 
-	req, err := http.NewRequestWithContext(ctx, "POST", OpenAITokenURL, strings.NewReader(data.Encode()))
+	req, err := http.NewRequestWithContext(ctx, "POST", tokenRefreshURL, strings.NewReader(data.Encode()))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
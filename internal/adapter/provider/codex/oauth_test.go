@@ -0,0 +1,85 @@
+package codex
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRefreshAccessTokenDeduplicatesConcurrentRefreshes(t *testing.T) {
+	var hits atomic.Int32
+	arrived := make(chan struct{}, 1)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		select {
+		case arrived <- struct{}{}:
+		default:
+		}
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"access_token":"at-new","refresh_token":"rt-rotated","expires_in":3600}`))
+	}))
+	defer srv.Close()
+	orig := tokenRefreshURL
+	tokenRefreshURL = srv.URL
+	defer func() { tokenRefreshURL = orig }()
+
+	const callers = 20
+	var wg sync.WaitGroup
+	results := make([]*TokenResponse, callers)
+	errs := make([]error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = RefreshAccessToken(context.Background(), "rt-old")
+		}(i)
+	}
+
+	// 第一个刷新到达上游后再稍等，让其余调用方都加入同一次刷新
+	<-arrived
+	time.Sleep(50 * time.Millisecond)
+
+	// 调用方取消只影响自己，不会中断共享的刷新
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan error, 1)
+	go func() {
+		_, err := RefreshAccessToken(ctx, "rt-old")
+		cancelled <- err
+	}()
+	cancel()
+	if err := <-cancelled; err != context.Canceled {
+		t.Errorf("cancelled caller err = %v, want context.Canceled", err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if n := hits.Load(); n != 1 {
+		t.Errorf("upstream refreshes = %d, want 1", n)
+	}
+	for i := 0; i < callers; i++ {
+		if errs[i] != nil {
+			t.Fatalf("caller %d: %v", i, errs[i])
+		}
+		if results[i].AccessToken != "at-new" || results[i].RefreshToken != "rt-rotated" {
+			t.Errorf("caller %d got %+v", i, results[i])
+		}
+	}
+	if results[0] == results[1] {
+		t.Error("callers share the same *TokenResponse")
+	}
+
+	// 刷新完成后不再合并，新的调用重新请求上游
+	if _, err := RefreshAccessToken(context.Background(), "rt-old"); err != nil {
+		t.Fatalf("second refresh: %v", err)
+	}
+	if n := hits.Load(); n != 2 {
+		t.Errorf("upstream refreshes after completion = %d, want 2", n)
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"github.com/awsl-project/maxx/internal/adapter/provider"
 	ctxutil "github.com/awsl-project/maxx/internal/context"
+	"github.com/awsl-project/maxx/internal/converter"
 	"github.com/awsl-project/maxx/internal/domain"
 	"github.com/awsl-project/maxx/internal/usage"
 )
@@ -23,7 +24,8 @@ func init() {
 }
 
 type CustomAdapter struct {
-	provider *domain.Provider
+	provider   *domain.Provider
+	httpClient *http.Client
 }
 
 func NewAdapter(p *domain.Provider) (provider.ProviderAdapter, error) {
@@ -32,6 +34,8 @@ func NewAdapter(p *domain.Provider) (provider.ProviderAdapter, error) {
 	}
 	return &CustomAdapter{
 		provider: p,
+		// Long timeout for LLM requests; the pool is reused across requests
+		httpClient: provider.NewUpstreamHTTPClient(p.Config.Transport, 10*time.Minute),
 	}, nil
 }
 
@@ -60,7 +64,7 @@ func (a *CustomAdapter) Execute(ctx context.Context, w http.ResponseWriter, req
 		requestURI = updateGeminiModelInPath(requestURI, mappedModel)
 	}
 
-	upstreamURL := buildUpstreamURL(baseURL, requestURI)
+	upstreamURL := buildUpstreamURL(baseURL, a.overridePath(clientType, requestURI, mappedModel))
 
 	// For Claude, add query parameters (following CLIProxyAPI)
 	if clientType == domain.ClientTypeClaude {
@@ -84,9 +88,10 @@ func (a *CustomAdapter) Execute(ctx context.Context, w http.ResponseWriter, req
 		}
 		var extraBetas []string
 		requestBody, extraBetas = processClaudeRequestBody(requestBody, clientUA)
+		extraBetas = append(extraBetas, requiredBetas(requestBody)...)
 
 		// 2. Set headers (always streaming for Claude)
-		applyClaudeHeaders(upstreamReq, req, a.provider.Config.Custom.APIKey, extraBetas)
+		applyClaudeHeaders(upstreamReq, req, a.provider.Config.Custom, extraBetas)
 
 		// 3. Update request body and ContentLength (IMPORTANT: body was modified)
 		upstreamReq.Body = io.NopCloser(bytes.NewReader(requestBody))
@@ -100,7 +105,7 @@ func (a *CustomAdapter) Execute(ctx context.Context, w http.ResponseWriter, req
 	default:
 		// Other types: Preserve original header forwarding logic
 		originalHeaders := ctxutil.GetRequestHeaders(ctx)
-		upstreamReq.Header = originalHeaders
+		upstreamReq.Header = originalHeaders.Clone()
 
 		// Override auth headers with provider's credentials
 		if a.provider.Config.Custom.APIKey != "" {
@@ -111,6 +116,12 @@ func (a *CustomAdapter) Execute(ctx context.Context, w http.ResponseWriter, req
 		}
 	}
 
+	// Provider-level User-Agent override (after format headers so it applies to every client type)
+	applyUserAgent(upstreamReq, req, a.provider.Config.Custom)
+
+	// OpenAI-Organization / OpenAI-Project passthrough or per-provider override
+	applyOpenAIHeaders(upstreamReq, req, clientType, a.provider.Config.Custom)
+
 	// Send request info via EventChannel
 	if eventChan := ctxutil.GetEventChan(ctx); eventChan != nil {
 		eventChan.SendRequestInfo(&domain.RequestInfo{
@@ -121,11 +132,7 @@ func (a *CustomAdapter) Execute(ctx context.Context, w http.ResponseWriter, req
 		})
 	}
 
-	// Execute request with reasonable timeout
-	client := &http.Client{
-		Timeout: 10 * time.Minute, // Long timeout for LLM requests
-	}
-	resp, err := client.Do(upstreamReq)
+	resp, err := a.httpClient.Do(upstreamReq)
 	if err != nil {
 		proxyErr := domain.NewProxyErrorWithMessage(domain.ErrUpstreamError, true, "failed to connect to upstream")
 		proxyErr.IsNetworkError = true
@@ -199,6 +206,16 @@ func (a *CustomAdapter) getBaseURL(clientType domain.ClientType) string {
 	return config.BaseURL
 }
 
+// overridePath applies the provider's path override for the client type to the
+// client request URI; the query string is always kept
+func (a *CustomAdapter) overridePath(clientType domain.ClientType, requestURI, model string) string {
+	override, ok := a.provider.Config.Custom.ClientPathOverride[clientType]
+	if !ok {
+		return requestURI
+	}
+	return applyPathOverride(requestURI, override, model)
+}
+
 func (a *CustomAdapter) handleNonStreamResponse(ctx context.Context, w http.ResponseWriter, resp *http.Response, clientType domain.ClientType) error {
 	// Decompress response body if needed
 	reader, err := decompressResponse(resp)
@@ -221,8 +238,19 @@ func (a *CustomAdapter) handleNonStreamResponse(ctx context.Context, w http.Resp
 		Body:    string(body),
 	})
 
+	// 200 响应体为错误结构：按上游错误处理，不写给客户端，交给 Executor 重试
+	if resp.StatusCode == http.StatusOK && !a.provider.Config.Custom.PassthroughErrorBodies {
+		if bodyErr := detectErrorBody(body, clientType); bodyErr != nil {
+			proxyErr := bodyErr.proxyError(body)
+			if proxyErr.HTTPStatusCode == http.StatusTooManyRequests {
+				proxyErr.RateLimitInfo = parseRateLimitInfo(resp, body, clientType)
+			}
+			return proxyErr
+		}
+	}
+
 	// Extract and send token usage metrics
-	if metrics := usage.ExtractFromResponse(string(body)); metrics != nil {
+	if metrics := usage.ExtractFromResponseWithMapping(string(body), a.usageMapping()); metrics != nil {
 		// Adjust for client-specific quirks (e.g., Codex input_tokens includes cached tokens)
 		metrics = usage.AdjustForClientType(metrics, clientType)
 		eventChan.SendMetrics(&domain.AdapterMetrics{
@@ -232,6 +260,7 @@ func (a *CustomAdapter) handleNonStreamResponse(ctx context.Context, w http.Resp
 			CacheCreationCount:   metrics.CacheCreationCount,
 			Cache5mCreationCount: metrics.Cache5mCreationCount,
 			Cache1hCreationCount: metrics.Cache1hCreationCount,
+			ReasoningTokens:      metrics.ReasoningTokens,
 		})
 	}
 
@@ -308,7 +337,7 @@ func (a *CustomAdapter) handleStreamResponse(ctx context.Context, w http.Respons
 			})
 
 			// Extract and send token usage
-			if metrics := usage.ExtractFromStreamContent(sseBuffer.String()); metrics != nil {
+			if metrics := usage.ExtractFromResponseWithMapping(sseBuffer.String(), a.usageMapping()); metrics != nil {
 				// Adjust for client-specific quirks (e.g., Codex input_tokens includes cached tokens)
 				metrics = usage.AdjustForClientType(metrics, clientType)
 				eventChan.SendMetrics(&domain.AdapterMetrics{
@@ -318,6 +347,7 @@ func (a *CustomAdapter) handleStreamResponse(ctx context.Context, w http.Respons
 					CacheCreationCount:   metrics.CacheCreationCount,
 					Cache5mCreationCount: metrics.Cache5mCreationCount,
 					Cache1hCreationCount: metrics.Cache1hCreationCount,
+					ReasoningTokens:      metrics.ReasoningTokens,
 				})
 			}
 
@@ -368,6 +398,12 @@ func (a *CustomAdapter) handleStreamResponse(ctx context.Context, w http.Respons
 		return nil
 	}
 
+	// SSE comments (heartbeats) are forwarded unless the provider strips them; either
+	// way they are not response content: not captured, not parsed, not counted as TTFT
+	stripComments := a.provider.Config.Custom.StripSSEComments
+	activity, _ := w.(provider.StreamActivityNotifier)
+	eventHasContent, eventStripped := false, false
+
 	// Use buffer-based approach to handle incomplete lines properly
 	var lineBuffer bytes.Buffer
 	buf := make([]byte, 4096)
@@ -395,8 +431,30 @@ func (a *CustomAdapter) handleStreamResponse(ctx context.Context, w http.Respons
 					break
 				}
 
+				isComment := converter.IsSSEComment(line)
+				isBlank := strings.TrimSpace(line) == ""
+				switch {
+				case isComment && stripComments:
+					eventStripped = true
+					if activity != nil {
+						activity.NotifyStreamActivity()
+					}
+					continue
+				case isBlank:
+					// Drop the blank line terminating a heartbeat-only event whose comment was stripped
+					dropBlank := eventStripped && !eventHasContent
+					eventHasContent, eventStripped = false, false
+					if dropBlank {
+						continue
+					}
+				case !isComment:
+					eventHasContent = true
+				}
+
 				// Collect all SSE content (preserve complete format including newlines)
-				sseBuffer.WriteString(line)
+				if !isComment {
+					sseBuffer.WriteString(line)
+				}
 
 				// Check for SSE error events in data lines
 				lineStr := line
@@ -418,8 +476,8 @@ func (a *CustomAdapter) handleStreamResponse(ctx context.Context, w http.Respons
 					}
 					flusher.Flush()
 
-					// Track TTFT: send first token time on first successful write
-					if !firstChunkSent {
+					// Track TTFT: send first token time on first successful content write
+					if !firstChunkSent && !isComment && !isBlank {
 						firstChunkSent = true
 						eventChan.SendFirstToken(time.Now().UnixMilli())
 					}
@@ -480,6 +538,27 @@ func buildUpstreamURL(baseURL string, requestPath string) string {
 	return strings.TrimSuffix(baseURL, "/") + requestPath
 }
 
+// applyPathOverride replaces the path of requestURI with the override path
+// ({model} substituted), or prefixes it when AppendStandardPath is set
+func applyPathOverride(requestURI string, override domain.PathOverride, model string) string {
+	if strings.TrimSpace(override.Path) == "" {
+		return requestURI
+	}
+	path, query, hasQuery := strings.Cut(requestURI, "?")
+
+	overridePath := strings.ReplaceAll(strings.TrimSpace(override.Path), "{model}", model)
+	if !strings.HasPrefix(overridePath, "/") {
+		overridePath = "/" + overridePath
+	}
+	if override.AppendStandardPath {
+		overridePath = strings.TrimSuffix(overridePath, "/") + path
+	}
+	if hasQuery {
+		overridePath += "?" + query
+	}
+	return overridePath
+}
+
 // addClaudeQueryParams adds query parameters to URL for Claude API (following CLIProxyAPI)
 // Adds: beta=true
 // Skips adding if parameter already exists
@@ -838,3 +917,11 @@ func extractResponseModelFromSSE(sseContent string, targetType domain.ClientType
 
 	return lastModel
 }
+
+// usageMapping returns the provider's usage field mapping, nil if not configured
+func (a *CustomAdapter) usageMapping() *domain.UsageFieldMapping {
+	if a.provider.Config == nil || a.provider.Config.Custom == nil {
+		return nil
+	}
+	return a.provider.Config.Custom.UsageMapping
+}
@@ -3,22 +3,34 @@ package custom
 import (
 	"net/http"
 	"strings"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/tidwall/gjson"
 )
 
 const (
 	defaultAnthropicVersion = "2023-06-01"
 	defaultClaudeUserAgent  = "claude-cli/2.1.23 (external, cli)"
+
+	// 1 小时缓存（cache_control.ttl = "1h"）需要的 beta
+	extendedCacheTTLBeta = "extended-cache-ttl-2025-04-11"
 )
 
 // applyClaudeHeaders sets Claude API request headers
 // Following CLIProxyAPI pattern: build headers from scratch, use EnsureHeader for selective client passthrough
 // Always sets streaming headers (Accept: text/event-stream)
-func applyClaudeHeaders(req *http.Request, clientReq *http.Request, apiKey string, extraBetas []string) {
+// Anthropic-Version / Anthropic-Beta come from the provider override when set,
+// otherwise from the client; the betas required by the body are always kept.
+func applyClaudeHeaders(req *http.Request, clientReq *http.Request, config *domain.ProviderConfigCustom, extraBetas []string) {
 	// Get client headers for EnsureHeader
 	var clientHeaders http.Header
 	if clientReq != nil {
 		clientHeaders = clientReq.Header
 	}
+	if config == nil {
+		config = &domain.ProviderConfigCustom{}
+	}
+	apiKey := config.APIKey
 
 	// 1. Set authentication (only if apiKey is provided)
 	if apiKey != "" {
@@ -46,30 +58,26 @@ func applyClaudeHeaders(req *http.Request, clientReq *http.Request, apiKey strin
 	isClaudeClient := isClaudeCodeClient(clientUA)
 
 	// 4. Build Anthropic-Beta header
+	// Provider override: replaces the client's betas
 	// For Claude Code clients: use their betas if provided
-	// For non-Claude clients: force default betas (cloaking)
+	// For non-Claude clients: default betas plus the client's own (cloaking
+	// keeps the defaults, but features like 1h cache must still engage)
 	baseBetas := "interleaved-thinking-2025-05-14,context-management-2025-06-27,prompt-caching-scope-2026-01-05,structured-outputs-2025-12-15"
-	if isClaudeClient && clientHeaders != nil {
-		if val := strings.TrimSpace(clientHeaders.Get("Anthropic-Beta")); val != "" {
-			baseBetas = val
-		}
+	var clientBetas string
+	if clientHeaders != nil {
+		clientBetas = strings.TrimSpace(clientHeaders.Get("Anthropic-Beta"))
+	}
+	switch {
+	case strings.TrimSpace(config.AnthropicBeta) != "":
+		baseBetas = strings.TrimSpace(config.AnthropicBeta)
+	case isClaudeClient && clientBetas != "":
+		baseBetas = clientBetas
+	case clientBetas != "":
+		baseBetas = mergeBetas(baseBetas, strings.Split(clientBetas, ","))
 	}
 
 	// Merge extra betas from request body
-	if len(extraBetas) > 0 {
-		existingSet := make(map[string]bool)
-		for _, b := range strings.Split(baseBetas, ",") {
-			existingSet[strings.TrimSpace(b)] = true
-		}
-		for _, beta := range extraBetas {
-			beta = strings.TrimSpace(beta)
-			if beta != "" && !existingSet[beta] {
-				baseBetas += "," + beta
-				existingSet[beta] = true
-			}
-		}
-	}
-	req.Header.Set("Anthropic-Beta", baseBetas)
+	req.Header.Set("Anthropic-Beta", mergeBetas(baseBetas, extraBetas))
 
 	// 5. Set headers: passthrough for Claude Code clients, force defaults for others (cloaking)
 	if isClaudeClient {
@@ -88,7 +96,8 @@ func applyClaudeHeaders(req *http.Request, clientReq *http.Request, apiKey strin
 		ensureHeader(req.Header, clientHeaders, "User-Agent", defaultClaudeUserAgent)
 	} else {
 		// Non-Claude client: force all headers to default values (cloaking)
-		req.Header.Set("Anthropic-Version", defaultAnthropicVersion)
+		// except Anthropic-Version, which selects API behavior rather than identifying the client
+		ensureHeader(req.Header, clientHeaders, "Anthropic-Version", defaultAnthropicVersion)
 		req.Header.Set("Anthropic-Dangerous-Direct-Browser-Access", "true")
 		req.Header.Set("X-App", "cli")
 		req.Header.Set("X-Stainless-Retry-Count", "0")
@@ -102,6 +111,10 @@ func applyClaudeHeaders(req *http.Request, clientReq *http.Request, apiKey strin
 		req.Header.Set("User-Agent", defaultClaudeUserAgent)
 	}
 
+	if v := strings.TrimSpace(config.AnthropicVersion); v != "" {
+		req.Header.Set("Anthropic-Version", v)
+	}
+
 	// 6. Set connection and encoding headers (always override)
 	req.Header.Set("Connection", "keep-alive")
 	req.Header.Set("Accept-Encoding", "gzip, deflate, br, zstd")
@@ -132,3 +145,48 @@ func ensureHeader(target http.Header, source http.Header, key, defaultValue stri
 		target.Set(key, val)
 	}
 }
+
+// mergeBetas appends the betas missing from the comma-separated base list
+func mergeBetas(base string, betas []string) string {
+	if len(betas) == 0 {
+		return base
+	}
+	existingSet := make(map[string]bool)
+	for _, b := range strings.Split(base, ",") {
+		existingSet[strings.TrimSpace(b)] = true
+	}
+	for _, beta := range betas {
+		beta = strings.TrimSpace(beta)
+		if beta != "" && !existingSet[beta] {
+			base += "," + beta
+			existingSet[beta] = true
+		}
+	}
+	return base
+}
+
+// requiredBetas returns the betas the body needs to behave as requested,
+// e.g. 1h cache_control TTLs are silently ignored without the extended TTL beta.
+// This also covers requests converted from other formats, whose clients never
+// send Anthropic headers.
+func requiredBetas(body []byte) []string {
+	if hasCacheTTL(gjson.ParseBytes(body), "1h") {
+		return []string{extendedCacheTTLBeta}
+	}
+	return nil
+}
+
+// hasCacheTTL reports whether any cache_control in the JSON value uses ttl
+func hasCacheTTL(v gjson.Result, ttl string) bool {
+	if v.IsObject() && v.Get("cache_control.ttl").String() == ttl {
+		return true
+	}
+	found := false
+	if v.IsObject() || v.IsArray() {
+		v.ForEach(func(_, child gjson.Result) bool {
+			found = hasCacheTTL(child, ttl)
+			return !found
+		})
+	}
+	return found
+}
@@ -0,0 +1,117 @@
+package custom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestApplyClaudeHeadersAnthropicPassthrough(t *testing.T) {
+	const claudeCodeUA = "claude-cli/2.1.23 (external, cli)"
+	tests := []struct {
+		name        string
+		userAgent   string
+		config      *domain.ProviderConfigCustom
+		clientBeta  string
+		clientVer   string
+		extraBetas  []string
+		wantVersion string
+		wantBetas   []string // 必须包含
+		notBetas    []string // 不得包含
+	}{
+		{
+			name:        "claude code passthrough",
+			userAgent:   claudeCodeUA,
+			config:      &domain.ProviderConfigCustom{},
+			clientBeta:  "prompt-caching-2024-07-31,extended-cache-ttl-2025-04-11",
+			clientVer:   "2023-06-01",
+			wantVersion: "2023-06-01",
+			wantBetas:   []string{"prompt-caching-2024-07-31", extendedCacheTTLBeta},
+			notBetas:    []string{"interleaved-thinking-2025-05-14"},
+		},
+		{
+			name:        "sdk client betas merged with defaults",
+			userAgent:   "anthropic-python/0.40.0",
+			config:      &domain.ProviderConfigCustom{},
+			clientBeta:  "extended-cache-ttl-2025-04-11",
+			clientVer:   "2024-01-01",
+			wantVersion: "2024-01-01",
+			wantBetas:   []string{"interleaved-thinking-2025-05-14", extendedCacheTTLBeta},
+		},
+		{
+			name:        "defaults without client headers",
+			userAgent:   "anthropic-python/0.40.0",
+			config:      &domain.ProviderConfigCustom{},
+			wantVersion: defaultAnthropicVersion,
+			wantBetas:   []string{"interleaved-thinking-2025-05-14"},
+		},
+		{
+			name:        "provider override",
+			userAgent:   claudeCodeUA,
+			config:      &domain.ProviderConfigCustom{AnthropicVersion: "2025-01-01", AnthropicBeta: "beta-a,beta-b"},
+			clientBeta:  "beta-client",
+			clientVer:   "2023-06-01",
+			extraBetas:  []string{extendedCacheTTLBeta},
+			wantVersion: "2025-01-01",
+			wantBetas:   []string{"beta-a", "beta-b", extendedCacheTTLBeta},
+			notBetas:    []string{"beta-client"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientReq := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+			clientReq.Header.Set("User-Agent", tt.userAgent)
+			if tt.clientBeta != "" {
+				clientReq.Header.Set("Anthropic-Beta", tt.clientBeta)
+			}
+			if tt.clientVer != "" {
+				clientReq.Header.Set("Anthropic-Version", tt.clientVer)
+			}
+			upstreamReq := httptest.NewRequest(http.MethodPost, "https://upstream.example.com/v1/messages", nil)
+			upstreamReq.Header = http.Header{}
+
+			applyClaudeHeaders(upstreamReq, clientReq, tt.config, tt.extraBetas)
+			if got := upstreamReq.Header.Get("Anthropic-Version"); got != tt.wantVersion {
+				t.Errorf("Anthropic-Version = %q, want %q", got, tt.wantVersion)
+			}
+			betas := make(map[string]bool)
+			for _, b := range strings.Split(upstreamReq.Header.Get("Anthropic-Beta"), ",") {
+				betas[b] = true
+			}
+			for _, b := range tt.wantBetas {
+				if !betas[b] {
+					t.Errorf("Anthropic-Beta = %q, missing %q", upstreamReq.Header.Get("Anthropic-Beta"), b)
+				}
+			}
+			for _, b := range tt.notBetas {
+				if betas[b] {
+					t.Errorf("Anthropic-Beta = %q, should not contain %q", upstreamReq.Header.Get("Anthropic-Beta"), b)
+				}
+			}
+		})
+	}
+}
+
+func TestRequiredBetas(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"no cache control", `{"messages":[{"role":"user","content":"hi"}]}`, nil},
+		{"5m cache", `{"tools":[{"name":"a","cache_control":{"type":"ephemeral"}}]}`, nil},
+		{"1h cache on tool", `{"tools":[{"name":"a","cache_control":{"type":"ephemeral","ttl":"1h"}}]}`, []string{extendedCacheTTLBeta}},
+		{"1h cache nested", `{"messages":[{"role":"user","content":[{"type":"text","text":"x","cache_control":{"type":"ephemeral","ttl":"1h"}}]}]}`, []string{extendedCacheTTLBeta}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := requiredBetas([]byte(tt.body))
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("requiredBetas = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package custom
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// claudeErrorStatus maps Claude error types to the HTTP status Anthropic uses for them
+var claudeErrorStatus = map[string]int{
+	"invalid_request_error": 400,
+	"authentication_error":  401,
+	"permission_error":      403,
+	"not_found_error":       404,
+	"request_too_large":     413,
+	"rate_limit_error":      429,
+	"api_error":             500,
+	"overloaded_error":      529,
+	"timeout_error":         504,
+}
+
+// geminiErrorStatus maps Google RPC status names to HTTP status codes
+var geminiErrorStatus = map[string]int{
+	"INVALID_ARGUMENT":    400,
+	"FAILED_PRECONDITION": 400,
+	"UNAUTHENTICATED":     401,
+	"PERMISSION_DENIED":   403,
+	"NOT_FOUND":           404,
+	"RESOURCE_EXHAUSTED":  429,
+	"INTERNAL":            500,
+	"UNAVAILABLE":         503,
+	"DEADLINE_EXCEEDED":   504,
+}
+
+// upstreamBodyError is an error found in the body of a 200 response
+type upstreamBodyError struct {
+	status  int // equivalent HTTP status, 0 if unknown
+	errType string
+	message string
+}
+
+// detectErrorBody reports whether a non-streaming 200 response body is an
+// error in the client format instead of a result. Some relays answer 200 and
+// put the upstream error in the body; treated as success, those would be
+// billed and never retried.
+//
+//	claude:        {"type":"error","error":{"type":"overloaded_error","message":"..."}}
+//	openai:        {"error":{"message":"...","type":"server_error","code":...}} without choices
+//	codex:         the same, or a response object with "status":"failed"
+//	gemini:        {"error":{"code":503,"message":"...","status":"UNAVAILABLE"}} without candidates
+func detectErrorBody(body []byte, clientType domain.ClientType) *upstreamBodyError {
+	trimmed := strings.TrimSpace(string(body))
+	// Gemini 偶尔以数组包裹单个错误对象
+	if clientType == domain.ClientTypeGemini && strings.HasPrefix(trimmed, "[") {
+		var items []map[string]interface{}
+		if json.Unmarshal([]byte(trimmed), &items) != nil || len(items) != 1 {
+			return nil
+		}
+		return geminiBodyError(items[0])
+	}
+
+	var payload map[string]interface{}
+	if json.Unmarshal([]byte(trimmed), &payload) != nil {
+		return nil
+	}
+	switch clientType {
+	case domain.ClientTypeClaude:
+		if payload["type"] != "error" {
+			return nil
+		}
+		errObj, _ := payload["error"].(map[string]interface{})
+		e := &upstreamBodyError{errType: stringField(errObj, "type"), message: stringField(errObj, "message")}
+		e.status = claudeErrorStatus[e.errType]
+		return e
+	case domain.ClientTypeOpenAI:
+		if _, ok := payload["choices"]; ok {
+			return nil
+		}
+		return openAIBodyError(payload)
+	case domain.ClientTypeCodex:
+		if _, ok := payload["output"]; ok && payload["status"] != "failed" {
+			return nil
+		}
+		return openAIBodyError(payload)
+	case domain.ClientTypeGemini:
+		return geminiBodyError(payload)
+	}
+	return nil
+}
+
+func openAIBodyError(payload map[string]interface{}) *upstreamBodyError {
+	errObj, ok := payload["error"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	e := &upstreamBodyError{errType: stringField(errObj, "type"), message: stringField(errObj, "message")}
+	// code 可能是数字状态码，也可能是字符串（如 "rate_limit_exceeded"）
+	var codeName string
+	switch code := errObj["code"].(type) {
+	case float64:
+		e.status = int(code)
+	case string:
+		if n, err := strconv.Atoi(code); err == nil {
+			e.status = n
+		} else {
+			codeName = code
+		}
+	}
+	if e.errType == "" {
+		e.errType = codeName
+	}
+	if e.status == 0 && (strings.Contains(e.errType, "rate_limit") || strings.Contains(codeName, "rate_limit")) {
+		e.status = 429
+	}
+	return e
+}
+
+func geminiBodyError(payload map[string]interface{}) *upstreamBodyError {
+	if _, ok := payload["candidates"]; ok {
+		return nil
+	}
+	errObj, ok := payload["error"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	e := &upstreamBodyError{errType: stringField(errObj, "status"), message: stringField(errObj, "message")}
+	if code, ok := errObj["code"].(float64); ok {
+		e.status = int(code)
+	} else {
+		e.status = geminiErrorStatus[e.errType]
+	}
+	return e
+}
+
+func stringField(m map[string]interface{}, key string) string {
+	s, _ := m[key].(string)
+	return s
+}
+
+// proxyError converts the body error like a non-2xx upstream response. Errors
+// without a recognizable client-side status are retried: a 200 that carries an
+// error is a provider fault, another route may well succeed.
+func (e *upstreamBodyError) proxyError(body []byte) *domain.ProxyError {
+	msg := e.message
+	if msg == "" {
+		msg = "upstream returned an error body with status 200"
+	}
+	retryable := e.status == 0 || e.status == 529 || isRetryableSSEError(e.status, e.errType, msg)
+	proxyErr := domain.NewProxyErrorWithMessage(
+		fmt.Errorf("upstream error in 200 response: %s", string(body)),
+		retryable,
+		msg,
+	)
+	proxyErr.HTTPStatusCode = e.status
+	proxyErr.IsServerError = e.status >= 500 && e.status < 600
+	return proxyErr
+}
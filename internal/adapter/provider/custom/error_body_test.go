@@ -0,0 +1,92 @@
+package custom
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestDetectErrorBody(t *testing.T) {
+	tests := []struct {
+		name       string
+		clientType domain.ClientType
+		body       string
+		wantError  bool
+		wantStatus int
+		retryable  bool
+	}{
+		// Claude
+		{"claude overloaded", domain.ClientTypeClaude, `{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, true, 529, true},
+		{"claude invalid request", domain.ClientTypeClaude, `{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}`, true, 400, false},
+		{"claude message", domain.ClientTypeClaude, `{"type":"message","content":[{"type":"text","text":"hi"}]}`, false, 0, false},
+
+		// OpenAI
+		{"openai server error", domain.ClientTypeOpenAI, `{"error":{"message":"The server had an error","type":"server_error","code":null}}`, true, 0, true},
+		{"openai rate limit code string", domain.ClientTypeOpenAI, `{"error":{"message":"slow down","type":"requests","code":"rate_limit_exceeded"}}`, true, 429, true},
+		{"openai numeric code", domain.ClientTypeOpenAI, `{"error":{"message":"invalid model","code":400}}`, true, 400, false},
+		{"openai completion", domain.ClientTypeOpenAI, `{"id":"chatcmpl-1","choices":[{"message":{"content":"hi"}}]}`, false, 0, false},
+
+		// Codex (Responses API)
+		{"codex error object", domain.ClientTypeCodex, `{"error":{"message":"upstream timeout","type":"server_error"}}`, true, 0, true},
+		{"codex failed response", domain.ClientTypeCodex, `{"object":"response","status":"failed","output":[],"error":{"code":"server_error","message":"failed"}}`, true, 0, true},
+		{"codex completed response", domain.ClientTypeCodex, `{"object":"response","status":"completed","output":[],"error":null}`, false, 0, false},
+
+		// Gemini
+		{"gemini unavailable", domain.ClientTypeGemini, `{"error":{"code":503,"message":"The model is overloaded.","status":"UNAVAILABLE"}}`, true, 503, true},
+		{"gemini status only", domain.ClientTypeGemini, `{"error":{"message":"quota","status":"RESOURCE_EXHAUSTED"}}`, true, 429, true},
+		{"gemini array wrapped", domain.ClientTypeGemini, `[{"error":{"code":500,"message":"internal","status":"INTERNAL"}}]`, true, 500, true},
+		{"gemini candidates", domain.ClientTypeGemini, `{"candidates":[{"content":{"parts":[{"text":"hi"}]}}]}`, false, 0, false},
+
+		{"not json", domain.ClientTypeOpenAI, `plain text`, false, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detectErrorBody([]byte(tt.body), tt.clientType)
+			if (got != nil) != tt.wantError {
+				t.Fatalf("detectErrorBody = %+v, want error %v", got, tt.wantError)
+			}
+			if got == nil {
+				return
+			}
+			proxyErr := got.proxyError([]byte(tt.body))
+			if proxyErr.HTTPStatusCode != tt.wantStatus || proxyErr.Retryable != tt.retryable {
+				t.Errorf("status = %d, retryable = %v; want %d, %v", proxyErr.HTTPStatusCode, proxyErr.Retryable, tt.wantStatus, tt.retryable)
+			}
+		})
+	}
+}
+
+func TestNonStreamErrorBodyNotWritten(t *testing.T) {
+	body := `{"type":"error","error":{"type":"api_error","message":"Internal server error"}}`
+	newResp := func() *http.Response {
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}
+	}
+
+	a := &CustomAdapter{provider: &domain.Provider{Config: &domain.ProviderConfig{Custom: &domain.ProviderConfigCustom{}}}}
+	rec := httptest.NewRecorder()
+	err := a.handleNonStreamResponse(context.Background(), rec, newResp(), domain.ClientTypeClaude)
+	var proxyErr *domain.ProxyError
+	if !errors.As(err, &proxyErr) || !proxyErr.Retryable || !proxyErr.IsServerError {
+		t.Fatalf("err = %v, want retryable server error", err)
+	}
+	// 未写给客户端，Executor 可以换路由重试
+	if rec.Body.Len() != 0 {
+		t.Errorf("error body written to client: %q", rec.Body.String())
+	}
+
+	// 关闭识别时按成功透传
+	a.provider.Config.Custom.PassthroughErrorBodies = true
+	rec = httptest.NewRecorder()
+	if err := a.handleNonStreamResponse(context.Background(), rec, newResp(), domain.ClientTypeClaude); err != nil {
+		t.Fatalf("passthrough err = %v", err)
+	}
+	if rec.Body.String() != body {
+		t.Errorf("passthrough body = %q", rec.Body.String())
+	}
+}
@@ -0,0 +1,63 @@
+package custom
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/awsl-project/maxx/internal/adapter/provider"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// CheckHealth 通过请求上游模型列表接口验证连通性和 API Key
+// 只有网络错误和 401/403 视为失败，其余状态码（如中转站未实现 /models 返回 404）视为可达
+func (a *CustomAdapter) CheckHealth(ctx context.Context) error {
+	clientType := domain.ClientTypeOpenAI
+	if len(a.provider.SupportedClientTypes) > 0 {
+		clientType = a.provider.SupportedClientTypes[0]
+	}
+
+	baseURL := a.getBaseURL(clientType)
+	if baseURL == "" {
+		return fmt.Errorf("base URL is empty")
+	}
+
+	path := "/v1/models"
+	if clientType == domain.ClientTypeGemini {
+		path = "/v1beta/models"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, buildUpstreamURL(baseURL, path), nil)
+	if err != nil {
+		return err
+	}
+	if clientType == domain.ClientTypeClaude {
+		req.Header.Set("anthropic-version", "2023-06-01")
+	}
+	if apiKey := a.provider.Config.Custom.APIKey; apiKey != "" {
+		setAuthHeader(req, clientType, apiKey, true)
+	}
+	applyUserAgent(req, nil, a.provider.Config.Custom)
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to upstream: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("upstream rejected credentials: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// WarmConnections 预热各客户端格式所用上游地址的连接（见 provider.WarmUpstream）
+func (a *CustomAdapter) WarmConnections(ctx context.Context, n int) error {
+	urls := []string{a.provider.Config.Custom.BaseURL}
+	for _, ct := range a.provider.SupportedClientTypes {
+		urls = append(urls, a.getBaseURL(ct))
+	}
+	return provider.WarmUpstream(ctx, a.httpClient, urls, n)
+}
@@ -0,0 +1,41 @@
+package custom
+
+import (
+	"net/http"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// applyOpenAIHeaders sets OpenAI-Organization and OpenAI-Project on requests
+// to OpenAI-compatible upstreams (openai and codex formats). For each header
+// the provider's override wins; otherwise the client's value is forwarded, so
+// the headers survive format conversion and header rebuilding. Other formats
+// are left untouched.
+func applyOpenAIHeaders(upstreamReq, clientReq *http.Request, clientType domain.ClientType, config *domain.ProviderConfigCustom) {
+	if clientType != domain.ClientTypeOpenAI && clientType != domain.ClientTypeCodex {
+		return
+	}
+	if upstreamReq.Header == nil {
+		upstreamReq.Header = make(http.Header)
+	}
+
+	var organization, project string
+	if config != nil {
+		organization = config.OpenAIOrganization
+		project = config.OpenAIProject
+	}
+	setOpenAIHeader(upstreamReq, clientReq, "OpenAI-Organization", organization)
+	setOpenAIHeader(upstreamReq, clientReq, "OpenAI-Project", project)
+}
+
+func setOpenAIHeader(upstreamReq, clientReq *http.Request, key, override string) {
+	if override != "" {
+		upstreamReq.Header.Set(key, override)
+		return
+	}
+	if clientReq != nil {
+		if v := clientReq.Header.Get(key); v != "" {
+			upstreamReq.Header.Set(key, v)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package custom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestApplyOpenAIHeaders(t *testing.T) {
+	tests := []struct {
+		name        string
+		clientType  domain.ClientType
+		config      *domain.ProviderConfigCustom
+		clientOrg   string
+		clientProj  string
+		wantOrg     string
+		wantProject string
+	}{
+		{"passthrough", domain.ClientTypeOpenAI, &domain.ProviderConfigCustom{}, "org-client", "proj-client", "org-client", "proj-client"},
+		{"passthrough codex", domain.ClientTypeCodex, &domain.ProviderConfigCustom{}, "org-client", "", "org-client", ""},
+		{"override", domain.ClientTypeOpenAI, &domain.ProviderConfigCustom{OpenAIOrganization: "org-provider", OpenAIProject: "proj-provider"}, "org-client", "proj-client", "org-provider", "proj-provider"},
+		{"override one header", domain.ClientTypeOpenAI, &domain.ProviderConfigCustom{OpenAIProject: "proj-provider"}, "org-client", "proj-client", "org-client", "proj-provider"},
+		{"override without client headers", domain.ClientTypeOpenAI, &domain.ProviderConfigCustom{OpenAIOrganization: "org-provider"}, "", "", "org-provider", ""},
+		{"non-openai upstream untouched", domain.ClientTypeClaude, &domain.ProviderConfigCustom{OpenAIOrganization: "org-provider"}, "org-client", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientReq := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+			if tt.clientOrg != "" {
+				clientReq.Header.Set("OpenAI-Organization", tt.clientOrg)
+			}
+			if tt.clientProj != "" {
+				clientReq.Header.Set("OpenAI-Project", tt.clientProj)
+			}
+			// 上游请求头由格式相关逻辑重建，不含客户端的请求头
+			upstreamReq := httptest.NewRequest(http.MethodPost, "https://upstream.example.com/v1/chat/completions", nil)
+			upstreamReq.Header = http.Header{}
+
+			applyOpenAIHeaders(upstreamReq, clientReq, tt.clientType, tt.config)
+			if got := upstreamReq.Header.Get("OpenAI-Organization"); got != tt.wantOrg {
+				t.Errorf("OpenAI-Organization = %q, want %q", got, tt.wantOrg)
+			}
+			if got := upstreamReq.Header.Get("OpenAI-Project"); got != tt.wantProject {
+				t.Errorf("OpenAI-Project = %q, want %q", got, tt.wantProject)
+			}
+		})
+	}
+}
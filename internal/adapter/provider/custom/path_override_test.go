@@ -0,0 +1,40 @@
+package custom
+
+import (
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestUpstreamURLWithPathOverride(t *testing.T) {
+	tests := []struct {
+		name       string
+		requestURI string
+		override   domain.PathOverride
+		want       string
+	}{
+		{"no override", "/v1/chat/completions", domain.PathOverride{}, "https://relay.example.com/v1/chat/completions"},
+		{"replace path", "/v1/chat/completions", domain.PathOverride{Path: "/api/v2/chat"}, "https://relay.example.com/api/v2/chat"},
+		{"missing leading slash", "/v1/chat/completions", domain.PathOverride{Path: "api/v2/chat"}, "https://relay.example.com/api/v2/chat"},
+		{"append standard path", "/v1/chat/completions", domain.PathOverride{Path: "/openai/", AppendStandardPath: true}, "https://relay.example.com/openai/v1/chat/completions"},
+		{"model placeholder keeps query", "/v1beta/models/gemini-2.5-pro:streamGenerateContent?alt=sse", domain.PathOverride{Path: "/gemini/{model}/stream"}, "https://relay.example.com/gemini/gemini-2.5-pro/stream?alt=sse"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := buildUpstreamURL("https://relay.example.com/", applyPathOverride(tt.requestURI, tt.override, "gemini-2.5-pro"))
+			if got != tt.want {
+				t.Errorf("upstream URL = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	a := &CustomAdapter{provider: &domain.Provider{Config: &domain.ProviderConfig{Custom: &domain.ProviderConfigCustom{
+		ClientPathOverride: map[domain.ClientType]domain.PathOverride{domain.ClientTypeOpenAI: {Path: "/api/v2/chat"}},
+	}}}}
+	if got := a.overridePath(domain.ClientTypeOpenAI, "/v1/chat/completions", ""); got != "/api/v2/chat" {
+		t.Errorf("openai path = %q, want override", got)
+	}
+	if got := a.overridePath(domain.ClientTypeClaude, "/v1/messages", ""); got != "/v1/messages" {
+		t.Errorf("claude path = %q, want unchanged", got)
+	}
+}
@@ -0,0 +1,66 @@
+package custom
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	ctxutil "github.com/awsl-project/maxx/internal/context"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// activityRecorder 记录 NotifyStreamActivity 调用次数
+type activityRecorder struct {
+	*httptest.ResponseRecorder
+	activity int
+}
+
+func (r *activityRecorder) NotifyStreamActivity() { r.activity++ }
+
+func TestStreamSSEComments(t *testing.T) {
+	upstream := ": ping\n\n" +
+		"event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-x\"}}\n\n" +
+		": keep-alive\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+
+	run := func(strip bool) (*activityRecorder, []*domain.AdapterEvent) {
+		a := &CustomAdapter{provider: &domain.Provider{Config: &domain.ProviderConfig{Custom: &domain.ProviderConfigCustom{StripSSEComments: strip}}}}
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(upstream))}
+		events := domain.NewAdapterEventChan()
+		rec := &activityRecorder{ResponseRecorder: httptest.NewRecorder()}
+		if err := a.handleStreamResponse(ctxutil.WithEventChan(context.Background(), events), rec, resp, domain.ClientTypeClaude); err != nil {
+			t.Fatalf("handleStreamResponse: %v", err)
+		}
+		close(events)
+		var got []*domain.AdapterEvent
+		for e := range events {
+			got = append(got, e)
+		}
+		return rec, got
+	}
+
+	// 默认转发心跳，但心跳不计入响应内容
+	rec, events := run(false)
+	if rec.Body.String() != upstream {
+		t.Errorf("forwarded body = %q, want upstream unchanged", rec.Body.String())
+	}
+	for _, e := range events {
+		if e.Type == domain.EventResponseInfo && strings.Contains(e.ResponseInfo.Body, "ping") {
+			t.Errorf("captured response body contains heartbeat: %q", e.ResponseInfo.Body)
+		}
+	}
+
+	// 丢弃心跳：心跳事件（含结束空行）不写给客户端，但仍通知上游活动
+	rec, _ = run(true)
+	want := "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"model\":\"claude-x\"}}\n\n" +
+		"event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n"
+	if rec.Body.String() != want {
+		t.Errorf("stripped body = %q, want %q", rec.Body.String(), want)
+	}
+	if rec.activity != 2 {
+		t.Errorf("activity notifications = %d, want 2", rec.activity)
+	}
+}
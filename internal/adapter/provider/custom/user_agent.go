@@ -0,0 +1,38 @@
+package custom
+
+import (
+	"net/http"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/version"
+)
+
+// maxxUserAgent 客户端与 Provider 均未提供 User-Agent 时使用，避免发送 Go 默认的 UA
+func maxxUserAgent() string {
+	return "maxx/" + version.Version
+}
+
+// applyUserAgent applies the provider's User-Agent settings on top of the
+// format-specific headers:
+//   - PassthroughUserAgent: the client's UA is forwarded when present
+//   - UserAgent: replaces the UA chosen by the format headers
+//   - otherwise the format's choice is kept (client UA or a built-in default),
+//     falling back to a maxx UA
+func applyUserAgent(upstreamReq, clientReq *http.Request, config *domain.ProviderConfigCustom) {
+	if upstreamReq.Header == nil {
+		upstreamReq.Header = make(http.Header)
+	}
+
+	clientUA := ""
+	if clientReq != nil {
+		clientUA = clientReq.Header.Get("User-Agent")
+	}
+	switch {
+	case config != nil && config.PassthroughUserAgent && clientUA != "":
+		upstreamReq.Header.Set("User-Agent", clientUA)
+	case config != nil && config.UserAgent != "":
+		upstreamReq.Header.Set("User-Agent", config.UserAgent)
+	case upstreamReq.Header.Get("User-Agent") == "":
+		upstreamReq.Header.Set("User-Agent", maxxUserAgent())
+	}
+}
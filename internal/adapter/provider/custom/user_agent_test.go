@@ -0,0 +1,43 @@
+package custom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestApplyUserAgent(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *domain.ProviderConfigCustom
+		clientUA string
+		formatUA string // 按客户端类型设置的 UA
+		want     string
+	}{
+		{"format default kept", &domain.ProviderConfigCustom{}, "curl/8", defaultClaudeUserAgent, defaultClaudeUserAgent},
+		{"maxx fallback", &domain.ProviderConfigCustom{}, "", "", maxxUserAgent()},
+		{"custom overrides", &domain.ProviderConfigCustom{UserAgent: "my-agent/1.0"}, "curl/8", "curl/8", "my-agent/1.0"},
+		{"passthrough client", &domain.ProviderConfigCustom{UserAgent: "my-agent/1.0", PassthroughUserAgent: true}, "curl/8", defaultClaudeUserAgent, "curl/8"},
+		{"passthrough without client UA", &domain.ProviderConfigCustom{UserAgent: "my-agent/1.0", PassthroughUserAgent: true}, "", defaultClaudeUserAgent, "my-agent/1.0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientReq := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+			if tt.clientUA != "" {
+				clientReq.Header.Set("User-Agent", tt.clientUA)
+			}
+			upstreamReq := httptest.NewRequest(http.MethodPost, "https://upstream.example.com/v1/messages", nil)
+			upstreamReq.Header = http.Header{}
+			if tt.formatUA != "" {
+				upstreamReq.Header.Set("User-Agent", tt.formatUA)
+			}
+
+			applyUserAgent(upstreamReq, clientReq, tt.config)
+			if got := upstreamReq.Header.Get("User-Agent"); got != tt.want {
+				t.Errorf("User-Agent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -16,6 +16,7 @@ import (
 	ctxutil "github.com/awsl-project/maxx/internal/context"
 	"github.com/awsl-project/maxx/internal/converter"
 	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/tokenizer"
 	"github.com/awsl-project/maxx/internal/usage"
 )
 
@@ -418,6 +419,7 @@ func (a *KiroAdapter) sendFinalEvents(ctx context.Context, body string, inputTok
 			CacheCreationCount:   metrics.CacheCreationCount,
 			Cache5mCreationCount: metrics.Cache5mCreationCount,
 			Cache1hCreationCount: metrics.Cache1hCreationCount,
+			ReasoningTokens:      metrics.ReasoningTokens,
 		})
 	} else {
 		// Fall back to estimated token counts
@@ -495,7 +497,7 @@ func (a *KiroAdapter) handleCollectedStreamResponse(ctx context.Context, w http.
 
 	stopReasonManager := NewStopReasonManager()
 	outputTokens := 0
-	estimator := NewTokenEstimator()
+	estimator := tokenizer.NewEstimator()
 	for _, contentBlock := range contexts {
 		blockType, _ := contentBlock["type"].(string)
 		switch blockType {
@@ -679,7 +681,7 @@ func calculateInputTokens(requestBody []byte) int {
 		claudeReq.Tools = filtered
 	}
 
-	estimator := NewTokenEstimator()
+	estimator := tokenizer.NewEstimator()
 	return estimator.EstimateInputTokens(&claudeReq)
 }
 
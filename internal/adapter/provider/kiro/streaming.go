@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/awsl-project/maxx/internal/tokenizer"
 )
 
 // streamProcessorContext holds streaming state.
@@ -18,7 +20,7 @@ type streamProcessorContext struct {
 	inputTokens        int
 	sseStateManager    *SSEStateManager
 	stopReasonManager  *StopReasonManager
-	tokenEstimator     *TokenEstimator
+	tokenEstimator     *tokenizer.Estimator
 	compliantParser    *CompliantEventStreamParser
 	totalOutputTokens  int
 	totalProcessedEvents int
@@ -42,7 +44,7 @@ func newStreamProcessorContext(w http.ResponseWriter, model string, inputTokens
 		inputTokens:          inputTokens,
 		sseStateManager:      NewSSEStateManager(writer, false),
 		stopReasonManager:    NewStopReasonManager(),
-		tokenEstimator:       NewTokenEstimator(),
+		tokenEstimator:       tokenizer.NewEstimator(),
 		compliantParser:      NewCompliantEventStreamParser(),
 		toolUseIdByBlockIndex: make(map[int]string),
 		completedToolUseIds:   make(map[string]bool),
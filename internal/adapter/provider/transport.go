@@ -0,0 +1,61 @@
+package provider
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// Upstream transport defaults, used for any ProviderTransport field left at 0
+const (
+	DefaultMaxIdleConnsPerHost = 16
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultKeepAlive           = 60 * time.Second
+)
+
+// NewUpstreamHTTPClient builds an HTTP client for a provider's upstream
+// requests. The client owns its connection pool, so adapters should create it
+// once and reuse it across requests. cfg may be nil.
+func NewUpstreamHTTPClient(cfg *domain.ProviderTransport, timeout time.Duration) *http.Client {
+	if cfg == nil {
+		cfg = &domain.ProviderTransport{}
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   20 * time.Second,
+		KeepAlive: durationOr(cfg.KeepAliveSeconds, DefaultKeepAlive),
+	}
+
+	transport := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     !cfg.DisableHTTP2,
+		MaxIdleConnsPerHost:   DefaultMaxIdleConnsPerHost,
+		IdleConnTimeout:       durationOr(cfg.IdleConnTimeoutSeconds, DefaultIdleConnTimeout),
+		TLSHandshakeTimeout:   20 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+		DisableKeepAlives:     cfg.DisableKeepAlives,
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.DisableHTTP2 {
+		// 非 nil 的空 map 阻止 net/http 在 TLS 握手时协商 h2
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}
+}
+
+func durationOr(seconds int, fallback time.Duration) time.Duration {
+	if seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	return fallback
+}
@@ -0,0 +1,89 @@
+package provider
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestNewUpstreamHTTPClient(t *testing.T) {
+	client := NewUpstreamHTTPClient(nil, time.Minute)
+	transport := client.Transport.(*http.Transport)
+	if !transport.ForceAttemptHTTP2 || transport.TLSNextProto != nil {
+		t.Error("HTTP/2 should be negotiated by default")
+	}
+	if transport.MaxIdleConnsPerHost != DefaultMaxIdleConnsPerHost || transport.IdleConnTimeout != DefaultIdleConnTimeout {
+		t.Errorf("pool = (%d, %v), want defaults", transport.MaxIdleConnsPerHost, transport.IdleConnTimeout)
+	}
+	if client.Timeout != time.Minute {
+		t.Errorf("timeout = %v, want 1m", client.Timeout)
+	}
+
+	client = NewUpstreamHTTPClient(&domain.ProviderTransport{
+		DisableHTTP2:           true,
+		MaxIdleConnsPerHost:    64,
+		IdleConnTimeoutSeconds: 30,
+		DisableKeepAlives:      true,
+	}, time.Minute)
+	transport = client.Transport.(*http.Transport)
+	if transport.ForceAttemptHTTP2 || transport.TLSNextProto == nil {
+		t.Error("DisableHTTP2 should turn off h2 negotiation")
+	}
+	if transport.MaxIdleConnsPerHost != 64 || transport.IdleConnTimeout != 30*time.Second || !transport.DisableKeepAlives {
+		t.Errorf("pool = (%d, %v, %v), want (64, 30s, true)", transport.MaxIdleConnsPerHost, transport.IdleConnTimeout, transport.DisableKeepAlives)
+	}
+}
+
+func TestWarmUpstream(t *testing.T) {
+	var heads, conns atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead && r.URL.Path == "/" && r.Header.Get("Authorization") == "" {
+			heads.Add(1)
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	client := NewUpstreamHTTPClient(nil, time.Minute)
+	// 同一 origin 的多个地址只预热一次
+	urls := []string{srv.URL + "/v1", srv.URL + "/v1beta", "not a url"}
+	if err := WarmUpstream(context.Background(), client, urls, 3); err != nil {
+		t.Fatalf("WarmUpstream: %v", err)
+	}
+	if heads.Load() != 3 {
+		t.Errorf("HEAD requests = %d, want 3", heads.Load())
+	}
+	opened := conns.Load()
+
+	// 再次预热复用连接池中的空闲连接
+	if err := WarmUpstream(context.Background(), client, urls, 3); err != nil {
+		t.Fatalf("WarmUpstream: %v", err)
+	}
+	if conns.Load() != opened {
+		t.Errorf("connections = %d after rewarm, want %d reused", conns.Load(), opened)
+	}
+}
+
+func TestWarmInterval(t *testing.T) {
+	if got := WarmInterval(nil); got != DefaultIdleConnTimeout/3 {
+		t.Errorf("default interval = %v", got)
+	}
+	if got := WarmInterval(&domain.ProviderTransport{IdleConnTimeoutSeconds: 60}); got != 20*time.Second {
+		t.Errorf("interval = %v, want 20s", got)
+	}
+	if got := WarmInterval(&domain.ProviderTransport{WarmIntervalSeconds: 10}); got != 10*time.Second {
+		t.Errorf("interval = %v, want 10s", got)
+	}
+}
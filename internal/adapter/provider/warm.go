@@ -0,0 +1,97 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// ConnectionWarmer is optionally implemented by adapters that can keep idle
+// upstream connections open with lightweight requests (ProviderTransport.WarmConnections)
+type ConnectionWarmer interface {
+	// WarmConnections opens or refreshes n connections to each upstream host
+	// through the adapter's own HTTP client, so they land in its pool
+	WarmConnections(ctx context.Context, n int) error
+}
+
+// EffectiveMaxIdleConnsPerHost returns the idle pool size used for cfg
+func EffectiveMaxIdleConnsPerHost(cfg *domain.ProviderTransport) int {
+	if cfg != nil && cfg.MaxIdleConnsPerHost > 0 {
+		return cfg.MaxIdleConnsPerHost
+	}
+	return DefaultMaxIdleConnsPerHost
+}
+
+// EffectiveIdleConnTimeout returns how long idle connections are kept for cfg
+func EffectiveIdleConnTimeout(cfg *domain.ProviderTransport) time.Duration {
+	if cfg == nil {
+		return DefaultIdleConnTimeout
+	}
+	return durationOr(cfg.IdleConnTimeoutSeconds, DefaultIdleConnTimeout)
+}
+
+// WarmInterval returns the connection warming cadence for cfg: WarmIntervalSeconds,
+// or a third of the idle timeout so every warm connection is touched at least
+// twice before the pool would close it
+func WarmInterval(cfg *domain.ProviderTransport) time.Duration {
+	if cfg != nil && cfg.WarmIntervalSeconds > 0 {
+		return time.Duration(cfg.WarmIntervalSeconds) * time.Second
+	}
+	return EffectiveIdleConnTimeout(cfg) / 3
+}
+
+// WarmUpstream sends n concurrent unauthenticated HEAD requests to the origin
+// of each URL through client. Concurrency makes HTTP/1.1 pools keep n
+// connections; HTTP/2 multiplexes them over one. Any response status counts as
+// success since only the connection matters.
+func WarmUpstream(ctx context.Context, client *http.Client, urls []string, n int) error {
+	origins := make(map[string]bool)
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			continue
+		}
+		origins[u.Scheme+"://"+u.Host+"/"] = true
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for origin := range origins {
+		for i := 0; i < n; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := headOnce(ctx, client, origin); err != nil {
+					mu.Lock()
+					errs = append(errs, err)
+					mu.Unlock()
+				}
+			}()
+		}
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func headOnce(ctx context.Context, client *http.Client, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	// 读完并关闭响应体，连接才会回到连接池
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
@@ -27,6 +27,13 @@ const (
 	CtxKeyIsStream           contextKey = "is_stream"
 	CtxKeyAPITokenID         contextKey = "api_token_id"
 	CtxKeyEventChan          contextKey = "event_chan"
+	CtxKeyClientIP           contextKey = "client_ip"
+	CtxKeyModelFallbacks     contextKey = "model_fallbacks"     // API Token 配置的模型回退链
+	CtxKeyBillable           contextKey = "billable"            // Token / 请求头确定的计费标记
+	CtxKeyRouteOverride      contextKey = "route_override"      // 请求重放：指定执行的路由 ID
+	CtxKeyComparisonTag      contextKey = "comparison_tag"      // 请求重放：路由对比标记
+	CtxKeyNonStreamOverride  contextKey = "non_stream_override" // 客户端请求流式，被强制为非流式返回
+	CtxKeyRouteProbe         contextKey = "route_probe"         // 路由测试：不重试，不影响冷却与失败计数
 )
 
 // Setters
@@ -206,3 +213,83 @@ func GetEventChan(ctx context.Context) domain.AdapterEventChan {
 	}
 	return nil
 }
+
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, CtxKeyClientIP, ip)
+}
+
+func GetClientIP(ctx context.Context) string {
+	if v, ok := ctx.Value(CtxKeyClientIP).(string); ok {
+		return v
+	}
+	return ""
+}
+
+func WithModelFallbacks(ctx context.Context, fallbacks []domain.ModelFallback) context.Context {
+	return context.WithValue(ctx, CtxKeyModelFallbacks, fallbacks)
+}
+
+func GetModelFallbacks(ctx context.Context) []domain.ModelFallback {
+	if v, ok := ctx.Value(CtxKeyModelFallbacks).([]domain.ModelFallback); ok {
+		return v
+	}
+	return nil
+}
+
+// WithBillable 设置由 Token 或请求头确定的计费标记，未设置时由项目配置决定
+func WithBillable(ctx context.Context, billable bool) context.Context {
+	return context.WithValue(ctx, CtxKeyBillable, billable)
+}
+
+// GetBillable 返回计费标记，ok 为 false 表示未显式设置
+func GetBillable(ctx context.Context) (billable bool, ok bool) {
+	billable, ok = ctx.Value(CtxKeyBillable).(bool)
+	return billable, ok
+}
+
+// WithRouteOverride 指定请求只在该路由上执行（用于请求重放）
+func WithRouteOverride(ctx context.Context, routeID uint64) context.Context {
+	return context.WithValue(ctx, CtxKeyRouteOverride, routeID)
+}
+
+func GetRouteOverride(ctx context.Context) uint64 {
+	if v, ok := ctx.Value(CtxKeyRouteOverride).(uint64); ok {
+		return v
+	}
+	return 0
+}
+
+func WithComparisonTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, CtxKeyComparisonTag, tag)
+}
+
+func GetComparisonTag(ctx context.Context) string {
+	if v, ok := ctx.Value(CtxKeyComparisonTag).(string); ok {
+		return v
+	}
+	return ""
+}
+
+// WithNonStreamOverride 标记客户端请求的流式被强制为非流式：上游仍流式请求，聚合后一次性返回
+func WithNonStreamOverride(ctx context.Context, override bool) context.Context {
+	return context.WithValue(ctx, CtxKeyNonStreamOverride, override)
+}
+
+func GetNonStreamOverride(ctx context.Context) bool {
+	if v, ok := ctx.Value(CtxKeyNonStreamOverride).(bool); ok {
+		return v
+	}
+	return false
+}
+
+// WithRouteProbe 标记请求为路由测试：只尝试一次，成功或失败都不记入冷却与失败计数
+func WithRouteProbe(ctx context.Context, probe bool) context.Context {
+	return context.WithValue(ctx, CtxKeyRouteProbe, probe)
+}
+
+func GetRouteProbe(ctx context.Context) bool {
+	if v, ok := ctx.Value(CtxKeyRouteProbe).(bool); ok {
+		return v
+	}
+	return false
+}
@@ -496,7 +496,8 @@ func (c *claudeToGeminiRequest) Transform(body []byte, model string, stream bool
 	// Build generation config (like Antigravity-Manager)
 	genConfig := &GeminiGenerationConfig{
 		MaxOutputTokens: 64000, // Fixed value like Antigravity-Manager
-		StopSequences:   defaultStopSequences(),
+		// 客户端的 stop_sequences 优先，剩余名额填充默认值
+		StopSequences: normalizeStopSequences(append(append([]string(nil), req.StopSequences...), defaultStopSequences()...), geminiMaxStopSequences),
 	}
 
 	if req.Temperature != nil {
@@ -121,9 +121,7 @@ func (c *claudeToOpenAIRequest) Transform(body []byte, model string, stream bool
 	}
 
 	// Convert stop sequences
-	if len(req.StopSequences) > 0 {
-		openaiReq.Stop = req.StopSequences
-	}
+	openaiReq.Stop = openAIStop(req.StopSequences)
 
 	return json.Marshal(openaiReq)
 }
@@ -229,10 +227,21 @@ func (c *claudeToOpenAIResponse) TransformChunk(chunk []byte, state *TransformSt
 				state.CurrentBlockType = claudeEvent.ContentBlock.Type
 				state.CurrentIndex = claudeEvent.Index
 				if claudeEvent.ContentBlock.Type == "tool_use" {
-					state.ToolCalls[claudeEvent.Index] = &ToolCallState{
-						ID:   claudeEvent.ContentBlock.ID,
-						Name: claudeEvent.ContentBlock.Name,
+					// OpenAI tool_calls 的 index 从 0 连续编号，与 Claude 内容块索引无关
+					tc := &ToolCallState{
+						ID:           claudeEvent.ContentBlock.ID,
+						Name:         claudeEvent.ContentBlock.Name,
+						ContentIndex: claudeEvent.Index,
+						ToolIndex:    len(state.ToolCalls),
+						Started:      true,
 					}
+					state.ToolCalls[claudeEvent.Index] = tc
+					output = append(output, openAIToolCallChunk(state.MessageID, OpenAIToolCallWithIndex{
+						Index:    tc.ToolIndex,
+						ID:       tc.ID,
+						Type:     "function",
+						Function: OpenAIFunctionCall{Name: tc.Name},
+					})...)
 				}
 			}
 
@@ -251,25 +260,13 @@ func (c *claudeToOpenAIResponse) TransformChunk(chunk []byte, state *TransformSt
 					}
 					output = append(output, FormatSSE("", chunk)...)
 				case "input_json_delta":
-					if tc, ok := state.ToolCalls[state.CurrentIndex]; ok {
+					// id/name 只在首个分片发送，后续分片仅带 index 和参数片段
+					if tc, ok := state.ToolCalls[claudeEvent.Index]; ok && claudeEvent.Delta.PartialJSON != "" {
 						tc.Arguments += claudeEvent.Delta.PartialJSON
-						chunk := OpenAIStreamChunk{
-							ID:      state.MessageID,
-							Object:  "chat.completion.chunk",
-							Created: time.Now().Unix(),
-							Choices: []OpenAIChoice{{
-								Index: 0,
-								Delta: &OpenAIMessage{
-									ToolCalls: []OpenAIToolCall{{
-										Index:    state.CurrentIndex,
-										ID:       tc.ID,
-										Type:     "function",
-										Function: OpenAIFunctionCall{Name: tc.Name, Arguments: claudeEvent.Delta.PartialJSON},
-									}},
-								},
-							}},
-						}
-						output = append(output, FormatSSE("", chunk)...)
+						output = append(output, openAIToolCallChunk(state.MessageID, OpenAIToolCallWithIndex{
+							Index:    tc.ToolIndex,
+							Function: OpenAIFunctionCall{Arguments: claudeEvent.Delta.PartialJSON},
+						})...)
 					}
 				}
 			}
@@ -317,3 +314,22 @@ type OpenAIToolCallWithIndex struct {
 	Type     string             `json:"type,omitempty"`
 	Function OpenAIFunctionCall `json:"function,omitempty"`
 }
+
+// openAIToolCallChunk formats a chat.completion.chunk carrying one tool call
+// delta. OpenAIToolCall drops index 0 (omitempty), so the delta is built with
+// OpenAIToolCallWithIndex instead.
+func openAIToolCallChunk(id string, toolCall OpenAIToolCallWithIndex) []byte {
+	chunk := map[string]interface{}{
+		"id":      id,
+		"object":  "chat.completion.chunk",
+		"created": time.Now().Unix(),
+		"model":   "",
+		"choices": []map[string]interface{}{{
+			"index": 0,
+			"delta": map[string]interface{}{
+				"tool_calls": []OpenAIToolCallWithIndex{toolCall},
+			},
+		}},
+	}
+	return FormatSSE("", chunk)
+}
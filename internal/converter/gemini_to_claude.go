@@ -113,7 +113,7 @@ func (c *geminiToClaudeRequest) Transform(body []byte, model string, stream bool
 		claudeReq.Temperature = req.GenerationConfig.Temperature
 		claudeReq.TopP = req.GenerationConfig.TopP
 		claudeReq.TopK = req.GenerationConfig.TopK
-		claudeReq.StopSequences = req.GenerationConfig.StopSequences
+		claudeReq.StopSequences = normalizeStopSequences(req.GenerationConfig.StopSequences, 0)
 	}
 
 	// Convert systemInstruction
@@ -29,9 +29,7 @@ func (c *geminiToOpenAIRequest) Transform(body []byte, model string, stream bool
 		openaiReq.MaxTokens = req.GenerationConfig.MaxOutputTokens
 		openaiReq.Temperature = req.GenerationConfig.Temperature
 		openaiReq.TopP = req.GenerationConfig.TopP
-		if len(req.GenerationConfig.StopSequences) > 0 {
-			openaiReq.Stop = req.GenerationConfig.StopSequences
-		}
+		openaiReq.Stop = openAIStop(req.GenerationConfig.StopSequences)
 	}
 
 	// Convert systemInstruction
@@ -0,0 +1,57 @@
+package converter
+
+// OpenAI Chat Completions request parameters across conversions.
+//
+// Same format (openai -> openai): the request body is forwarded byte for byte,
+// so every parameter (logprobs, top_logprobs, seed, stop, n, penalties,
+// logit_bias, response_format, ...) reaches the upstream unchanged.
+//
+// Cross format, parameters are either mapped or dropped; they are never
+// forwarded under a field the target API would misinterpret:
+//
+//	parameter                   claude               gemini                            codex
+//	max_tokens / max_completion max_tokens           maxOutputTokens                   max_output_tokens
+//	temperature, top_p          mapped               mapped                            mapped
+//	stop                        stop_sequences       stopSequences (max 5)             dropped
+//	seed                        dropped              seed                              dropped
+//	presence/frequency_penalty  dropped              presencePenalty/frequencyPenalty  dropped
+//	response_format             dropped              json_* -> responseMimeType        dropped
+//	user                        metadata.user_id     dropped                           dropped
+//	logprobs, top_logprobs      dropped              dropped                           dropped
+//	n, logit_bias               dropped              dropped                           dropped
+//
+// logprobs are dropped rather than mapped because no response converter turns
+// upstream log probabilities back into the OpenAI format; requesting them would
+// only add cost. Empty stop strings are dropped since Claude and Gemini reject
+// empty stop sequences.
+
+// openAIStopSequences normalizes OpenAI's stop (string or array of strings)
+// into a list of non-empty stop sequences, see stop_sequences.go
+func openAIStopSequences(stop interface{}) []string {
+	var sequences []string
+	switch stop := stop.(type) {
+	case string:
+		sequences = append(sequences, stop)
+	case []string:
+		sequences = stop
+	case []interface{}:
+		for _, s := range stop {
+			if str, ok := s.(string); ok {
+				sequences = append(sequences, str)
+			}
+		}
+	}
+	return normalizeStopSequences(sequences, 0)
+}
+
+// openAIResponseMimeType maps response_format to a Gemini responseMimeType
+func openAIResponseMimeType(format *OpenAIResponseFormat) string {
+	if format == nil {
+		return ""
+	}
+	switch format.Type {
+	case "json_object", "json_schema":
+		return "application/json"
+	}
+	return ""
+}
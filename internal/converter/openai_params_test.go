@@ -0,0 +1,130 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+const openAIParamsRequest = `{
+	"model": "gpt-4o",
+	"messages": [{"role": "user", "content": "hi"}],
+	"max_tokens": 100,
+	"temperature": 0.2,
+	"top_p": 0.9,
+	"n": 2,
+	"stop": ["END", ""],
+	"seed": 42,
+	"presence_penalty": 0.5,
+	"frequency_penalty": 0.25,
+	"logprobs": true,
+	"top_logprobs": 3,
+	"logit_bias": {"50256": -100},
+	"response_format": {"type": "json_object"},
+	"user": "user-1"
+}`
+
+func TestOpenAIParamsSameFormatPassthrough(t *testing.T) {
+	out, err := NewRegistry().TransformRequest(domain.ClientTypeOpenAI, domain.ClientTypeOpenAI, []byte(openAIParamsRequest), "gpt-4o", false)
+	if err != nil {
+		t.Fatalf("TransformRequest failed: %v", err)
+	}
+	if string(out) != openAIParamsRequest {
+		t.Errorf("same-format body changed:\n%s", out)
+	}
+}
+
+func TestOpenAIParamsCrossFormat(t *testing.T) {
+	tests := []struct {
+		name    string
+		to      domain.ClientType
+		path    []string // 参数所在的对象路径
+		want    map[string]interface{}
+		dropped []string
+	}{
+		{
+			name: "claude",
+			to:   domain.ClientTypeClaude,
+			want: map[string]interface{}{
+				"max_tokens":     float64(100),
+				"temperature":    0.2,
+				"top_p":          0.9,
+				"stop_sequences": []interface{}{"END"},
+				"metadata":       map[string]interface{}{"user_id": "user-1"},
+			},
+			dropped: []string{"n", "stop", "seed", "presence_penalty", "frequency_penalty", "logprobs", "top_logprobs", "logit_bias", "response_format", "user"},
+		},
+		{
+			name: "gemini",
+			to:   domain.ClientTypeGemini,
+			path: []string{"generationConfig"},
+			want: map[string]interface{}{
+				"maxOutputTokens":  float64(100),
+				"temperature":      0.2,
+				"topP":             0.9,
+				"stopSequences":    []interface{}{"END"},
+				"seed":             float64(42),
+				"presencePenalty":  0.5,
+				"frequencyPenalty": 0.25,
+				"responseMimeType": "application/json",
+			},
+			dropped: []string{"candidateCount", "responseLogprobs", "logprobs"},
+		},
+		{
+			name: "codex",
+			to:   domain.ClientTypeCodex,
+			want: map[string]interface{}{
+				"max_output_tokens": float64(100),
+				"temperature":       0.2,
+				"top_p":             0.9,
+			},
+			dropped: []string{"n", "stop", "seed", "presence_penalty", "frequency_penalty", "logprobs", "top_logprobs", "logit_bias", "response_format", "user"},
+		},
+	}
+
+	registry := NewRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := registry.TransformRequest(domain.ClientTypeOpenAI, tt.to, []byte(openAIParamsRequest), "target-model", false)
+			if err != nil {
+				t.Fatalf("TransformRequest failed: %v", err)
+			}
+			obj := decodeJSON(t, out)
+			for _, key := range tt.path {
+				nested, ok := obj[key].(map[string]interface{})
+				if !ok {
+					t.Fatalf("missing %q in %s", key, out)
+				}
+				obj = nested
+			}
+			for key, want := range tt.want {
+				if got := obj[key]; !reflect.DeepEqual(got, want) {
+					t.Errorf("%s = %#v, want %#v", key, got, want)
+				}
+			}
+			for _, key := range tt.dropped {
+				if got, ok := obj[key]; ok {
+					t.Errorf("%s = %#v, want dropped", key, got)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenAIStopSequences(t *testing.T) {
+	tests := []struct {
+		stop interface{}
+		want []string
+	}{
+		{nil, nil},
+		{"", nil},
+		{"###", []string{"###"}},
+		{[]interface{}{"a", "", 1, "b"}, []string{"a", "b"}},
+	}
+	for _, tt := range tests {
+		if got := openAIStopSequences(tt.stop); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("openAIStopSequences(%#v) = %#v, want %#v", tt.stop, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package converter
 
 import (
 	"encoding/json"
+	"sort"
 
 	"github.com/awsl-project/maxx/internal/domain"
 )
@@ -122,15 +123,10 @@ func (c *openaiToClaudeRequest) Transform(body []byte, model string, stream bool
 	}
 
 	// Convert stop
-	switch stop := req.Stop.(type) {
-	case string:
-		claudeReq.StopSequences = []string{stop}
-	case []interface{}:
-		for _, s := range stop {
-			if str, ok := s.(string); ok {
-				claudeReq.StopSequences = append(claudeReq.StopSequences, str)
-			}
-		}
+	claudeReq.StopSequences = openAIStopSequences(req.Stop)
+
+	if req.User != "" {
+		claudeReq.Metadata = &ClaudeMetadata{UserID: req.User}
 	}
 
 	return json.Marshal(claudeReq)
@@ -247,6 +243,7 @@ func (c *openaiToClaudeResponse) TransformChunk(chunk []byte, state *TransformSt
 			if content, ok := choice.Delta.Content.(string); ok && content != "" {
 				// Ensure text block is started
 				if state.CurrentBlockType != "text" {
+					output = append(output, c.closeOpenBlock(state)...)
 					blockStart := map[string]interface{}{
 						"type":  "content_block_start",
 						"index": state.CurrentIndex,
@@ -271,72 +268,8 @@ func (c *openaiToClaudeResponse) TransformChunk(chunk []byte, state *TransformSt
 			}
 
 			// Handle tool calls
-			if len(choice.Delta.ToolCalls) > 0 {
-				for _, toolCall := range choice.Delta.ToolCalls {
-					toolIndex := toolCall.Index
-
-					// Initialize tool call state if needed
-					if state.ToolCalls == nil {
-						state.ToolCalls = make(map[int]*ToolCallState)
-					}
-
-					tc, exists := state.ToolCalls[toolIndex]
-					if !exists {
-						// Close previous text block if any
-						if state.CurrentBlockType == "text" {
-							blockStop := map[string]interface{}{
-								"type":  "content_block_stop",
-								"index": state.CurrentIndex,
-							}
-							output = append(output, FormatSSE("content_block_stop", blockStop)...)
-							state.CurrentIndex++
-							state.CurrentBlockType = ""
-						}
-
-						// New tool call - send content_block_start
-						tc = &ToolCallState{
-							ID:           toolCall.ID,
-							Name:         toolCall.Function.Name,
-							ContentIndex: state.CurrentIndex,
-						}
-						state.ToolCalls[toolIndex] = tc
-
-						blockStart := map[string]interface{}{
-							"type":  "content_block_start",
-							"index": tc.ContentIndex,
-							"content_block": map[string]interface{}{
-								"type":  "tool_use",
-								"id":    tc.ID,
-								"name":  tc.Name,
-								"input": map[string]interface{}{},
-							},
-						}
-						output = append(output, FormatSSE("content_block_start", blockStart)...)
-						state.CurrentIndex++
-					} else {
-						// Update existing tool call state
-						if toolCall.ID != "" {
-							tc.ID = toolCall.ID
-						}
-						if toolCall.Function.Name != "" {
-							tc.Name = toolCall.Function.Name
-						}
-					}
-
-					// Send arguments delta if present
-					if toolCall.Function.Arguments != "" {
-						tc.Arguments += toolCall.Function.Arguments
-						delta := map[string]interface{}{
-							"type":  "content_block_delta",
-							"index": tc.ContentIndex,
-							"delta": map[string]interface{}{
-								"type":         "input_json_delta",
-								"partial_json": toolCall.Function.Arguments,
-							},
-						}
-						output = append(output, FormatSSE("content_block_delta", delta)...)
-					}
-				}
+			for _, toolCall := range choice.Delta.ToolCalls {
+				output = append(output, c.handleToolCallDelta(state, toolCall)...)
 			}
 		}
 
@@ -349,29 +282,120 @@ func (c *openaiToClaudeResponse) TransformChunk(chunk []byte, state *TransformSt
 	return output, nil
 }
 
-// handleFinish closes all open blocks and sends final events
-func (c *openaiToClaudeResponse) handleFinish(state *TransformState) []byte {
-	var output []byte
+// handleToolCallDelta converts one OpenAI tool call delta into Claude events.
+//
+// OpenAI streams a tool call as deltas sharing an index: the first carries id
+// and name, the following ones carry argument fragments. Claude needs one
+// tool_use block per call, opened with id and name and closed before the next
+// block starts. Arguments received before the name is known are buffered and
+// emitted when the block opens, so no fragment is lost. A fragment for a call
+// whose block was already closed (interleaved parallel calls) is still sent on
+// that block's index; clients accumulate input_json_delta by index.
+func (c *openaiToClaudeResponse) handleToolCallDelta(state *TransformState, toolCall OpenAIToolCall) []byte {
+	if state.ToolCalls == nil {
+		state.ToolCalls = make(map[int]*ToolCallState)
+	}
+	tc, exists := state.ToolCalls[toolCall.Index]
+	if !exists {
+		tc = &ToolCallState{}
+		state.ToolCalls[toolCall.Index] = tc
+	}
+	if toolCall.ID != "" && tc.ID == "" {
+		tc.ID = toolCall.ID
+	}
+	if toolCall.Function.Name != "" && !tc.Started {
+		tc.Name = toolCall.Function.Name
+	}
+	tc.Arguments += toolCall.Function.Arguments
 
-	// Close text block if open
-	if state.CurrentBlockType == "text" {
-		blockStop := map[string]interface{}{
-			"type":  "content_block_stop",
-			"index": state.CurrentIndex,
+	if !tc.Started {
+		if tc.Name == "" {
+			return nil
 		}
-		output = append(output, FormatSSE("content_block_stop", blockStop)...)
+		// 首次输出该调用：包含此前缓冲的全部参数
+		return c.startToolBlock(state, tc)
+	}
+	if toolCall.Function.Arguments == "" {
+		return nil
+	}
+	return inputJSONDelta(tc.ContentIndex, toolCall.Function.Arguments)
+}
+
+// startToolBlock closes the open block and opens a tool_use block for tc,
+// emitting the arguments buffered so far
+func (c *openaiToClaudeResponse) startToolBlock(state *TransformState, tc *ToolCallState) []byte {
+	output := c.closeOpenBlock(state)
+
+	tc.ContentIndex = state.CurrentIndex
+	tc.Started = true
+	state.CurrentIndex++
+	state.CurrentBlockType = "tool_use"
+	state.ActiveToolCall = tc
+
+	blockStart := map[string]interface{}{
+		"type":  "content_block_start",
+		"index": tc.ContentIndex,
+		"content_block": map[string]interface{}{
+			"type":  "tool_use",
+			"id":    tc.ID,
+			"name":  tc.Name,
+			"input": map[string]interface{}{},
+		},
+	}
+	output = append(output, FormatSSE("content_block_start", blockStart)...)
+	if tc.Arguments != "" {
+		output = append(output, inputJSONDelta(tc.ContentIndex, tc.Arguments)...)
+	}
+	return output
+}
+
+// closeOpenBlock sends content_block_stop for the open text or tool_use block
+func (c *openaiToClaudeResponse) closeOpenBlock(state *TransformState) []byte {
+	var index int
+	switch state.CurrentBlockType {
+	case "text":
+		index = state.CurrentIndex
 		state.CurrentIndex++
-		state.CurrentBlockType = ""
+	case "tool_use":
+		index = state.ActiveToolCall.ContentIndex
+	default:
+		return nil
 	}
+	state.CurrentBlockType = ""
+	state.ActiveToolCall = nil
+	return FormatSSE("content_block_stop", map[string]interface{}{
+		"type":  "content_block_stop",
+		"index": index,
+	})
+}
+
+func inputJSONDelta(index int, partialJSON string) []byte {
+	return FormatSSE("content_block_delta", map[string]interface{}{
+		"type":  "content_block_delta",
+		"index": index,
+		"delta": map[string]interface{}{
+			"type":         "input_json_delta",
+			"partial_json": partialJSON,
+		},
+	})
+}
 
-	// Close all tool blocks
-	for _, tc := range state.ToolCalls {
-		blockStop := map[string]interface{}{
-			"type":  "content_block_stop",
-			"index": tc.ContentIndex,
+// handleFinish closes all open blocks and sends final events
+func (c *openaiToClaudeResponse) handleFinish(state *TransformState) []byte {
+	var output []byte
+
+	// Tool calls whose name never arrived are still emitted, in tool_calls order
+	indexes := make([]int, 0, len(state.ToolCalls))
+	for i, tc := range state.ToolCalls {
+		if !tc.Started {
+			indexes = append(indexes, i)
 		}
-		output = append(output, FormatSSE("content_block_stop", blockStop)...)
 	}
+	sort.Ints(indexes)
+	for _, i := range indexes {
+		output = append(output, c.startToolBlock(state, state.ToolCalls[i])...)
+	}
+	output = append(output, c.closeOpenBlock(state)...)
 
 	// Map finish reason
 	stopReason := "end_turn"
@@ -21,9 +21,13 @@ func (c *openaiToGeminiRequest) Transform(body []byte, model string, stream bool
 
 	geminiReq := GeminiRequest{
 		GenerationConfig: &GeminiGenerationConfig{
-			MaxOutputTokens: req.MaxTokens,
-			Temperature:     req.Temperature,
-			TopP:            req.TopP,
+			MaxOutputTokens:  req.MaxTokens,
+			Temperature:      req.Temperature,
+			TopP:             req.TopP,
+			Seed:             req.Seed,
+			PresencePenalty:  req.PresencePenalty,
+			FrequencyPenalty: req.FrequencyPenalty,
+			ResponseMimeType: openAIResponseMimeType(req.ResponseFormat),
 		},
 	}
 
@@ -32,16 +36,7 @@ func (c *openaiToGeminiRequest) Transform(body []byte, model string, stream bool
 	}
 
 	// Convert stop sequences
-	switch stop := req.Stop.(type) {
-	case string:
-		geminiReq.GenerationConfig.StopSequences = []string{stop}
-	case []interface{}:
-		for _, s := range stop {
-			if str, ok := s.(string); ok {
-				geminiReq.GenerationConfig.StopSequences = append(geminiReq.GenerationConfig.StopSequences, str)
-			}
-		}
-	}
+	geminiReq.GenerationConfig.StopSequences = normalizeStopSequences(openAIStopSequences(req.Stop), geminiMaxStopSequences)
 
 	// Convert messages
 	for _, msg := range req.Messages {
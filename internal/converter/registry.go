@@ -13,7 +13,8 @@ type TransformState struct {
 	CurrentIndex     int
 	CurrentBlockType string // "text", "thinking", "tool_use"
 	ToolCalls        map[int]*ToolCallState
-	Buffer           string // SSE line buffer
+	ActiveToolCall   *ToolCallState // tool_use block currently open (OpenAI → Claude)
+	Buffer           string         // SSE line buffer
 	Usage            *Usage
 	StopReason       string
 }
@@ -22,8 +23,10 @@ type TransformState struct {
 type ToolCallState struct {
 	ID           string
 	Name         string
-	Arguments    string
-	ContentIndex int // assigned Claude content block index
+	Arguments    string // arguments received so far
+	ContentIndex int    // assigned Claude content block index
+	ToolIndex    int    // assigned OpenAI tool_calls index
+	Started      bool   // block/tool call header already emitted
 }
 
 // Usage tracks token usage during streaming
@@ -89,6 +92,15 @@ func (r *Registry) NeedConvert(clientType domain.ClientType, supportedTypes []do
 	return true
 }
 
+// CanConvert reports whether requests of client type from can be served by an
+// upstream speaking to: the request converts from → to and the response back
+func (r *Registry) CanConvert(from, to domain.ClientType) bool {
+	if from == to {
+		return true
+	}
+	return r.requests[from][to] != nil && r.responses[to][from] != nil
+}
+
 // GetTargetFormat returns the target format (first supported type)
 func (r *Registry) GetTargetFormat(supportedTypes []domain.ClientType) domain.ClientType {
 	if len(supportedTypes) > 0 {
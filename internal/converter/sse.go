@@ -60,6 +60,28 @@ func ParseSSE(text string) ([]SSEEvent, string) {
 	return events, remaining.String()
 }
 
+// IsSSEComment reports whether an SSE line is a comment (starts with ":"),
+// which upstreams commonly send as keep-alive heartbeats
+func IsSSEComment(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), ":")
+}
+
+// IsSSECommentOnly reports whether text contains SSE comments and nothing
+// but comments and blank lines, i.e. a heartbeat without any event content
+func IsSSECommentOnly(text string) bool {
+	hasComment := false
+	for _, line := range strings.Split(text, "\n") {
+		switch {
+		case strings.TrimSpace(line) == "":
+		case IsSSEComment(line):
+			hasComment = true
+		default:
+			return false
+		}
+	}
+	return hasComment
+}
+
 // IsSSE checks if text looks like SSE format
 func IsSSE(text string) bool {
 	lines := strings.Split(text, "\n")
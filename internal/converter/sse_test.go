@@ -0,0 +1,21 @@
+package converter
+
+import "testing"
+
+func TestIsSSECommentOnly(t *testing.T) {
+	cases := map[string]bool{
+		": ping\n":                true,
+		": ping\n\n":              true,
+		":\n":                     true,
+		"\n":                      false,
+		"":                        false,
+		"data: {}\n":              false,
+		": ping\ndata: {}\n\n":    false,
+		"event: ping\ndata: {}\n": false,
+	}
+	for text, want := range cases {
+		if got := IsSSECommentOnly(text); got != want {
+			t.Errorf("IsSSECommentOnly(%q) = %v, want %v", text, got, want)
+		}
+	}
+}
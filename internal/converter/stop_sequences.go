@@ -0,0 +1,43 @@
+package converter
+
+// Stop sequences across formats:
+//
+//	openai  stop            string or array of strings, at most 4
+//	claude  stop_sequences  array of strings
+//	gemini  stopSequences   array of strings, at most 5
+//	codex   (none)          dropped
+//
+// Every conversion normalizes through a plain list: empty and duplicate
+// sequences are dropped (Claude and Gemini reject empty ones) and the list is
+// cut to the target's limit, keeping the client's order. OpenAI's stop is always
+// sent as an array.
+const (
+	openAIMaxStopSequences = 4
+	geminiMaxStopSequences = 5
+)
+
+// normalizeStopSequences drops empty and duplicate sequences and keeps at most
+// max of them (0 means no limit); nil when nothing is left
+func normalizeStopSequences(sequences []string, max int) []string {
+	var out []string
+	seen := make(map[string]bool, len(sequences))
+	for _, s := range sequences {
+		if s == "" || seen[s] {
+			continue
+		}
+		if max > 0 && len(out) >= max {
+			break
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out
+}
+
+// openAIStop converts stop sequences to OpenAI's stop, nil when there are none
+func openAIStop(sequences []string) interface{} {
+	if normalized := normalizeStopSequences(sequences, openAIMaxStopSequences); len(normalized) > 0 {
+		return normalized
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package converter
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestNormalizeStopSequences(t *testing.T) {
+	got := normalizeStopSequences([]string{"a", "", "b", "a", "c"}, 2)
+	if want := []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("normalizeStopSequences = %#v, want %#v", got, want)
+	}
+	if got := normalizeStopSequences([]string{""}, 0); got != nil {
+		t.Errorf("normalizeStopSequences of empty strings = %#v, want nil", got)
+	}
+}
+
+func TestStopSequencesAcrossFormats(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to domain.ClientType
+		body     string
+		path     []string
+		key      string
+		want     interface{}
+	}{
+		{
+			name: "openai string stop to claude array",
+			from: domain.ClientTypeOpenAI, to: domain.ClientTypeClaude,
+			body: `{"model":"m","messages":[{"role":"user","content":"hi"}],"stop":"###"}`,
+			key:  "stop_sequences",
+			want: []interface{}{"###"},
+		},
+		{
+			name: "claude to openai keeps at most 4",
+			from: domain.ClientTypeClaude, to: domain.ClientTypeOpenAI,
+			body: `{"model":"m","max_tokens":10,"messages":[{"role":"user","content":"hi"}],"stop_sequences":["a","","b","a","c","d","e"]}`,
+			key:  "stop",
+			want: []interface{}{"a", "b", "c", "d"},
+		},
+		{
+			name: "claude to openai without stop",
+			from: domain.ClientTypeClaude, to: domain.ClientTypeOpenAI,
+			body: `{"model":"m","max_tokens":10,"messages":[{"role":"user","content":"hi"}],"stop_sequences":[""]}`,
+			key:  "stop",
+			want: nil,
+		},
+		{
+			name: "claude to gemini puts client sequences first",
+			from: domain.ClientTypeClaude, to: domain.ClientTypeGemini,
+			body: `{"model":"m","max_tokens":10,"messages":[{"role":"user","content":"hi"}],"stop_sequences":["END"]}`,
+			path: []string{"generationConfig"},
+			key:  "stopSequences",
+			want: []interface{}{"END", "<|user|>", "<|endoftext|>", "<|end_of_turn|>", "[DONE]"},
+		},
+		{
+			name: "gemini to openai",
+			from: domain.ClientTypeGemini, to: domain.ClientTypeOpenAI,
+			body: `{"contents":[{"role":"user","parts":[{"text":"hi"}]}],"generationConfig":{"stopSequences":["x",""]}}`,
+			key:  "stop",
+			want: []interface{}{"x"},
+		},
+		{
+			name: "openai to gemini keeps at most 5",
+			from: domain.ClientTypeOpenAI, to: domain.ClientTypeGemini,
+			body: `{"model":"m","messages":[{"role":"user","content":"hi"}],"stop":["1","2","3","4","5","6"]}`,
+			path: []string{"generationConfig"},
+			key:  "stopSequences",
+			want: []interface{}{"1", "2", "3", "4", "5"},
+		},
+	}
+
+	registry := NewRegistry()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, err := registry.TransformRequest(tt.from, tt.to, []byte(tt.body), "target-model", false)
+			if err != nil {
+				t.Fatalf("TransformRequest failed: %v", err)
+			}
+			obj := decodeJSON(t, out)
+			for _, key := range tt.path {
+				nested, ok := obj[key].(map[string]interface{})
+				if !ok {
+					t.Fatalf("missing %q in %s", key, out)
+				}
+				obj = nested
+			}
+			if got := obj[tt.key]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s = %#v, want %#v", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,683 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// AggregateStream collects a complete SSE stream into a single non-streaming
+// JSON response of the same client format.
+// Used when the client requested a non-streaming response but the upstream only streams.
+func AggregateStream(clientType domain.ClientType, sse []byte) ([]byte, error) {
+	// Ensure the trailing event is terminated so ParseSSE emits it
+	events, _ := ParseSSE(string(sse) + "\n\n")
+
+	switch clientType {
+	case domain.ClientTypeClaude:
+		return aggregateClaudeStream(events)
+	case domain.ClientTypeOpenAI:
+		return aggregateOpenAIStream(events)
+	case domain.ClientTypeCodex:
+		return aggregateCodexStream(events)
+	case domain.ClientTypeGemini:
+		return aggregateGeminiStream(events)
+	default:
+		return nil, fmt.Errorf("stream aggregation not supported for %s", clientType)
+	}
+}
+
+// SynthesizeStream splits a non-streaming JSON response into the SSE events
+// a streaming response of the same client format would have produced.
+// Used when the client requested a streaming response but the upstream does not stream.
+func SynthesizeStream(clientType domain.ClientType, body []byte) ([]byte, error) {
+	var resp map[string]interface{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid response body: %w", err)
+	}
+
+	switch clientType {
+	case domain.ClientTypeClaude:
+		return synthesizeClaudeStream(resp), nil
+	case domain.ClientTypeOpenAI:
+		return synthesizeOpenAIStream(resp), nil
+	case domain.ClientTypeCodex:
+		return synthesizeCodexStream(resp), nil
+	case domain.ClientTypeGemini:
+		// Gemini 流式每个 chunk 与非流式响应结构相同，整体作为一个 chunk 发送即可
+		return FormatSSE("", body), nil
+	default:
+		return nil, fmt.Errorf("stream synthesis not supported for %s", clientType)
+	}
+}
+
+func decodeEventData(ev SSEEvent) map[string]interface{} {
+	if len(ev.Data) == 0 {
+		return nil
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(ev.Data, &data); err != nil {
+		return nil
+	}
+	return data
+}
+
+func streamErrorMessage(data map[string]interface{}) string {
+	if errObj, ok := data["error"].(map[string]interface{}); ok {
+		if msg, ok := errObj["message"].(string); ok && msg != "" {
+			return msg
+		}
+	}
+	return "upstream stream returned an error event"
+}
+
+// ===== Claude =====
+
+func aggregateClaudeStream(events []SSEEvent) ([]byte, error) {
+	var msg map[string]interface{}
+	blocks := make(map[int]map[string]interface{})
+	toolInputs := make(map[int]*strings.Builder)
+
+	for _, ev := range events {
+		data := decodeEventData(ev)
+		if data == nil {
+			continue
+		}
+		typ, _ := data["type"].(string)
+		switch typ {
+		case "message_start":
+			msg, _ = data["message"].(map[string]interface{})
+		case "content_block_start":
+			idx := intValue(data["index"])
+			block, _ := data["content_block"].(map[string]interface{})
+			if block == nil {
+				block = map[string]interface{}{}
+			}
+			blocks[idx] = block
+			if t, _ := block["type"].(string); t == "tool_use" || t == "server_tool_use" {
+				toolInputs[idx] = &strings.Builder{}
+			}
+		case "content_block_delta":
+			idx := intValue(data["index"])
+			block := blocks[idx]
+			delta, _ := data["delta"].(map[string]interface{})
+			if block == nil || delta == nil {
+				continue
+			}
+			switch delta["type"] {
+			case "text_delta":
+				block["text"] = stringValue(block["text"]) + stringValue(delta["text"])
+			case "thinking_delta":
+				block["thinking"] = stringValue(block["thinking"]) + stringValue(delta["thinking"])
+			case "signature_delta":
+				block["signature"] = stringValue(delta["signature"])
+			case "input_json_delta":
+				if b := toolInputs[idx]; b != nil {
+					b.WriteString(stringValue(delta["partial_json"]))
+				}
+			case "citations_delta":
+				citations, _ := block["citations"].([]interface{})
+				block["citations"] = append(citations, delta["citation"])
+			}
+		case "message_delta":
+			if msg == nil {
+				continue
+			}
+			if delta, ok := data["delta"].(map[string]interface{}); ok {
+				if v, ok := delta["stop_reason"]; ok {
+					msg["stop_reason"] = v
+				}
+				if v, ok := delta["stop_sequence"]; ok {
+					msg["stop_sequence"] = v
+				}
+			}
+			if usage, ok := data["usage"].(map[string]interface{}); ok {
+				msgUsage, _ := msg["usage"].(map[string]interface{})
+				if msgUsage == nil {
+					msgUsage = map[string]interface{}{}
+				}
+				for k, v := range usage {
+					msgUsage[k] = v
+				}
+				msg["usage"] = msgUsage
+			}
+		case "error":
+			return nil, fmt.Errorf("%s", streamErrorMessage(data))
+		}
+	}
+
+	if msg == nil {
+		return nil, fmt.Errorf("claude stream has no message_start event")
+	}
+
+	for idx, b := range toolInputs {
+		input := map[string]interface{}{}
+		if raw := strings.TrimSpace(b.String()); raw != "" {
+			if err := json.Unmarshal([]byte(raw), &input); err != nil {
+				return nil, fmt.Errorf("invalid tool input json for block %d: %w", idx, err)
+			}
+		}
+		blocks[idx]["input"] = input
+	}
+
+	content := make([]interface{}, 0, len(blocks))
+	for _, idx := range sortedKeys(blocks) {
+		content = append(content, blocks[idx])
+	}
+	msg["content"] = content
+	return json.Marshal(msg)
+}
+
+func synthesizeClaudeStream(resp map[string]interface{}) []byte {
+	var out []byte
+
+	start := make(map[string]interface{}, len(resp))
+	for k, v := range resp {
+		start[k] = v
+	}
+	start["content"] = []interface{}{}
+	start["stop_reason"] = nil
+	start["stop_sequence"] = nil
+	usage, _ := resp["usage"].(map[string]interface{})
+	if usage != nil {
+		startUsage := make(map[string]interface{}, len(usage))
+		for k, v := range usage {
+			startUsage[k] = v
+		}
+		startUsage["output_tokens"] = 0
+		start["usage"] = startUsage
+	}
+	out = append(out, FormatSSE("message_start", map[string]interface{}{"type": "message_start", "message": start})...)
+
+	content, _ := resp["content"].([]interface{})
+	for i, raw := range content {
+		block, _ := raw.(map[string]interface{})
+		if block == nil {
+			continue
+		}
+		var startBlock map[string]interface{}
+		var deltas []map[string]interface{}
+
+		switch block["type"] {
+		case "text":
+			startBlock = map[string]interface{}{"type": "text", "text": ""}
+			deltas = append(deltas, map[string]interface{}{"type": "text_delta", "text": stringValue(block["text"])})
+		case "thinking":
+			startBlock = map[string]interface{}{"type": "thinking", "thinking": ""}
+			deltas = append(deltas, map[string]interface{}{"type": "thinking_delta", "thinking": stringValue(block["thinking"])})
+			if sig := stringValue(block["signature"]); sig != "" {
+				deltas = append(deltas, map[string]interface{}{"type": "signature_delta", "signature": sig})
+			}
+		case "tool_use", "server_tool_use":
+			startBlock = make(map[string]interface{}, len(block))
+			for k, v := range block {
+				startBlock[k] = v
+			}
+			startBlock["input"] = map[string]interface{}{}
+			input := block["input"]
+			if input == nil {
+				input = map[string]interface{}{}
+			}
+			deltas = append(deltas, map[string]interface{}{"type": "input_json_delta", "partial_json": string(mustMarshal(input))})
+		default:
+			// redacted_thinking、web_search_tool_result 等没有增量形式，直接在 start 中给出完整内容
+			startBlock = block
+		}
+
+		out = append(out, FormatSSE("content_block_start", map[string]interface{}{
+			"type": "content_block_start", "index": i, "content_block": startBlock,
+		})...)
+		for _, delta := range deltas {
+			out = append(out, FormatSSE("content_block_delta", map[string]interface{}{
+				"type": "content_block_delta", "index": i, "delta": delta,
+			})...)
+		}
+		out = append(out, FormatSSE("content_block_stop", map[string]interface{}{
+			"type": "content_block_stop", "index": i,
+		})...)
+	}
+
+	deltaUsage := map[string]interface{}{"output_tokens": 0}
+	if usage != nil {
+		deltaUsage["output_tokens"] = usage["output_tokens"]
+	}
+	out = append(out, FormatSSE("message_delta", map[string]interface{}{
+		"type":  "message_delta",
+		"delta": map[string]interface{}{"stop_reason": resp["stop_reason"], "stop_sequence": resp["stop_sequence"]},
+		"usage": deltaUsage,
+	})...)
+	out = append(out, FormatSSE("message_stop", map[string]interface{}{"type": "message_stop"})...)
+	return out
+}
+
+// ===== OpenAI =====
+
+type openAIChoiceAcc struct {
+	role         string
+	content      strings.Builder
+	hasContent   bool
+	reasoning    strings.Builder
+	toolCalls    map[int]map[string]interface{}
+	toolArgs     map[int]*strings.Builder
+	finishReason interface{}
+}
+
+func aggregateOpenAIStream(events []SSEEvent) ([]byte, error) {
+	resp := map[string]interface{}{"object": "chat.completion"}
+	choices := make(map[int]*openAIChoiceAcc)
+	seen := false
+
+	for _, ev := range events {
+		data := decodeEventData(ev)
+		if data == nil {
+			continue
+		}
+		if _, ok := data["error"]; ok {
+			return nil, fmt.Errorf("%s", streamErrorMessage(data))
+		}
+		seen = true
+		for _, key := range []string{"id", "created", "model", "system_fingerprint"} {
+			if v, ok := data[key]; ok && v != nil {
+				resp[key] = v
+			}
+		}
+		if usage, ok := data["usage"].(map[string]interface{}); ok {
+			resp["usage"] = usage
+		}
+
+		rawChoices, _ := data["choices"].([]interface{})
+		for _, rc := range rawChoices {
+			choice, _ := rc.(map[string]interface{})
+			if choice == nil {
+				continue
+			}
+			idx := intValue(choice["index"])
+			acc := choices[idx]
+			if acc == nil {
+				acc = &openAIChoiceAcc{
+					toolCalls: make(map[int]map[string]interface{}),
+					toolArgs:  make(map[int]*strings.Builder),
+				}
+				choices[idx] = acc
+			}
+			if fr, ok := choice["finish_reason"]; ok && fr != nil {
+				acc.finishReason = fr
+			}
+			delta, _ := choice["delta"].(map[string]interface{})
+			if delta == nil {
+				continue
+			}
+			if role := stringValue(delta["role"]); role != "" {
+				acc.role = role
+			}
+			if c, ok := delta["content"].(string); ok {
+				acc.content.WriteString(c)
+				acc.hasContent = true
+			}
+			if r, ok := delta["reasoning_content"].(string); ok {
+				acc.reasoning.WriteString(r)
+			}
+			toolCalls, _ := delta["tool_calls"].([]interface{})
+			for _, rtc := range toolCalls {
+				tc, _ := rtc.(map[string]interface{})
+				if tc == nil {
+					continue
+				}
+				tcIdx := intValue(tc["index"])
+				call := acc.toolCalls[tcIdx]
+				if call == nil {
+					call = map[string]interface{}{"type": "function"}
+					acc.toolCalls[tcIdx] = call
+					acc.toolArgs[tcIdx] = &strings.Builder{}
+				}
+				if id := stringValue(tc["id"]); id != "" {
+					call["id"] = id
+				}
+				if t := stringValue(tc["type"]); t != "" {
+					call["type"] = t
+				}
+				if fn, ok := tc["function"].(map[string]interface{}); ok {
+					if name := stringValue(fn["name"]); name != "" {
+						call["name"] = name
+					}
+					acc.toolArgs[tcIdx].WriteString(stringValue(fn["arguments"]))
+				}
+			}
+		}
+	}
+
+	if !seen {
+		return nil, fmt.Errorf("openai stream has no chunks")
+	}
+
+	outChoices := make([]interface{}, 0, len(choices))
+	for _, idx := range sortedKeys(choices) {
+		acc := choices[idx]
+		role := acc.role
+		if role == "" {
+			role = "assistant"
+		}
+		message := map[string]interface{}{"role": role, "content": nil}
+		if acc.hasContent || len(acc.toolCalls) == 0 {
+			message["content"] = acc.content.String()
+		}
+		if acc.reasoning.Len() > 0 {
+			message["reasoning_content"] = acc.reasoning.String()
+		}
+		if len(acc.toolCalls) > 0 {
+			calls := make([]interface{}, 0, len(acc.toolCalls))
+			for _, tcIdx := range sortedKeys(acc.toolCalls) {
+				call := acc.toolCalls[tcIdx]
+				calls = append(calls, map[string]interface{}{
+					"id":   call["id"],
+					"type": call["type"],
+					"function": map[string]interface{}{
+						"name":      stringValue(call["name"]),
+						"arguments": acc.toolArgs[tcIdx].String(),
+					},
+				})
+			}
+			message["tool_calls"] = calls
+		}
+		outChoices = append(outChoices, map[string]interface{}{
+			"index":         idx,
+			"message":       message,
+			"finish_reason": acc.finishReason,
+		})
+	}
+	resp["choices"] = outChoices
+	return json.Marshal(resp)
+}
+
+func synthesizeOpenAIStream(resp map[string]interface{}) []byte {
+	var out []byte
+	chunk := func(choices []interface{}) map[string]interface{} {
+		c := map[string]interface{}{"object": "chat.completion.chunk", "choices": choices}
+		for _, key := range []string{"id", "created", "model", "system_fingerprint"} {
+			if v, ok := resp[key]; ok {
+				c[key] = v
+			}
+		}
+		return c
+	}
+
+	rawChoices, _ := resp["choices"].([]interface{})
+	for _, rc := range rawChoices {
+		choice, _ := rc.(map[string]interface{})
+		if choice == nil {
+			continue
+		}
+		idx := choice["index"]
+		message, _ := choice["message"].(map[string]interface{})
+		if message == nil {
+			message = map[string]interface{}{}
+		}
+		role := stringValue(message["role"])
+		if role == "" {
+			role = "assistant"
+		}
+
+		delta := map[string]interface{}{"role": role}
+		if content, ok := message["content"].(string); ok {
+			delta["content"] = content
+		}
+		if reasoning, ok := message["reasoning_content"].(string); ok && reasoning != "" {
+			delta["reasoning_content"] = reasoning
+		}
+		out = append(out, FormatSSE("", chunk([]interface{}{map[string]interface{}{"index": idx, "delta": delta}}))...)
+
+		toolCalls, _ := message["tool_calls"].([]interface{})
+		for i, rtc := range toolCalls {
+			tc, _ := rtc.(map[string]interface{})
+			if tc == nil {
+				continue
+			}
+			call := map[string]interface{}{"index": i}
+			for k, v := range tc {
+				call[k] = v
+			}
+			out = append(out, FormatSSE("", chunk([]interface{}{map[string]interface{}{
+				"index": idx,
+				"delta": map[string]interface{}{"tool_calls": []interface{}{call}},
+			}}))...)
+		}
+
+		out = append(out, FormatSSE("", chunk([]interface{}{map[string]interface{}{
+			"index":         idx,
+			"delta":         map[string]interface{}{},
+			"finish_reason": choice["finish_reason"],
+		}}))...)
+	}
+
+	if usage, ok := resp["usage"]; ok && usage != nil {
+		c := chunk([]interface{}{})
+		c["usage"] = usage
+		out = append(out, FormatSSE("", c)...)
+	}
+	out = append(out, FormatDone()...)
+	return out
+}
+
+// ===== Codex (Responses API) =====
+
+func aggregateCodexStream(events []SSEEvent) ([]byte, error) {
+	var final map[string]interface{}
+	var items []interface{}
+
+	for _, ev := range events {
+		data := decodeEventData(ev)
+		if data == nil {
+			continue
+		}
+		switch data["type"] {
+		case "response.output_item.done":
+			if item, ok := data["item"]; ok {
+				items = append(items, item)
+			}
+		case "response.completed", "response.incomplete", "response.failed":
+			final, _ = data["response"].(map[string]interface{})
+		case "error":
+			return nil, fmt.Errorf("%s", streamErrorMessage(data))
+		}
+	}
+
+	if final == nil {
+		return nil, fmt.Errorf("codex stream has no response.completed event")
+	}
+	// 部分上游在 response.completed 中不回传 output，用 output_item.done 事件补全
+	if output, _ := final["output"].([]interface{}); len(output) == 0 && len(items) > 0 {
+		final["output"] = items
+	}
+	return json.Marshal(final)
+}
+
+func synthesizeCodexStream(resp map[string]interface{}) []byte {
+	var out []byte
+	event := func(typ string, data map[string]interface{}) {
+		data["type"] = typ
+		out = append(out, FormatSSE(typ, data)...)
+	}
+
+	created := make(map[string]interface{}, len(resp))
+	for k, v := range resp {
+		created[k] = v
+	}
+	created["status"] = "in_progress"
+	created["output"] = []interface{}{}
+	delete(created, "usage")
+	event("response.created", map[string]interface{}{"response": created})
+
+	output, _ := resp["output"].([]interface{})
+	for i, raw := range output {
+		item, _ := raw.(map[string]interface{})
+		if item == nil {
+			continue
+		}
+		event("response.output_item.added", map[string]interface{}{"output_index": i, "item": item})
+		if item["type"] == "message" {
+			parts, _ := item["content"].([]interface{})
+			for j, rp := range parts {
+				part, _ := rp.(map[string]interface{})
+				if part == nil || part["type"] != "output_text" {
+					continue
+				}
+				event("response.output_text.delta", map[string]interface{}{
+					"item_id":       item["id"],
+					"output_index":  i,
+					"content_index": j,
+					"delta":         stringValue(part["text"]),
+				})
+				event("response.output_text.done", map[string]interface{}{
+					"item_id":       item["id"],
+					"output_index":  i,
+					"content_index": j,
+					"text":          stringValue(part["text"]),
+				})
+			}
+		}
+		event("response.output_item.done", map[string]interface{}{"output_index": i, "item": item})
+	}
+
+	event("response.completed", map[string]interface{}{"response": resp})
+	return out
+}
+
+// ===== Gemini =====
+
+type geminiCandidateAcc struct {
+	candidate map[string]interface{}
+	role      interface{}
+	parts     []map[string]interface{}
+}
+
+func aggregateGeminiStream(events []SSEEvent) ([]byte, error) {
+	resp := map[string]interface{}{}
+	candidates := make(map[int]*geminiCandidateAcc)
+	seen := false
+
+	for _, ev := range events {
+		data := decodeEventData(ev)
+		if data == nil {
+			continue
+		}
+		if _, ok := data["error"]; ok {
+			return nil, fmt.Errorf("%s", streamErrorMessage(data))
+		}
+		seen = true
+		for k, v := range data {
+			if k != "candidates" {
+				resp[k] = v
+			}
+		}
+
+		rawCandidates, _ := data["candidates"].([]interface{})
+		for _, rc := range rawCandidates {
+			cand, _ := rc.(map[string]interface{})
+			if cand == nil {
+				continue
+			}
+			idx := intValue(cand["index"])
+			acc := candidates[idx]
+			if acc == nil {
+				acc = &geminiCandidateAcc{candidate: map[string]interface{}{}}
+				candidates[idx] = acc
+			}
+			for k, v := range cand {
+				if k != "content" {
+					acc.candidate[k] = v
+				}
+			}
+			content, _ := cand["content"].(map[string]interface{})
+			if content == nil {
+				continue
+			}
+			if role, ok := content["role"]; ok {
+				acc.role = role
+			}
+			parts, _ := content["parts"].([]interface{})
+			for _, rp := range parts {
+				part, _ := rp.(map[string]interface{})
+				if part == nil {
+					continue
+				}
+				acc.parts = appendGeminiPart(acc.parts, part)
+			}
+		}
+	}
+
+	if !seen {
+		return nil, fmt.Errorf("gemini stream has no chunks")
+	}
+
+	outCandidates := make([]interface{}, 0, len(candidates))
+	for _, idx := range sortedKeys(candidates) {
+		acc := candidates[idx]
+		parts := make([]interface{}, 0, len(acc.parts))
+		for _, p := range acc.parts {
+			parts = append(parts, p)
+		}
+		content := map[string]interface{}{"parts": parts}
+		if acc.role != nil {
+			content["role"] = acc.role
+		}
+		acc.candidate["content"] = content
+		outCandidates = append(outCandidates, acc.candidate)
+	}
+	resp["candidates"] = outCandidates
+	return json.Marshal(resp)
+}
+
+// appendGeminiPart merges consecutive text parts (same thought flag) and appends anything else
+func appendGeminiPart(parts []map[string]interface{}, part map[string]interface{}) []map[string]interface{} {
+	text, isText := part["text"].(string)
+	if isText && len(parts) > 0 && isPlainGeminiText(part) {
+		last := parts[len(parts)-1]
+		if _, lastIsText := last["text"].(string); lastIsText && isPlainGeminiText(last) && last["thought"] == part["thought"] {
+			last["text"] = last["text"].(string) + text
+			if sig, ok := part["thoughtSignature"]; ok {
+				last["thoughtSignature"] = sig
+			}
+			return parts
+		}
+	}
+	copied := make(map[string]interface{}, len(part))
+	for k, v := range part {
+		copied[k] = v
+	}
+	return append(parts, copied)
+}
+
+func isPlainGeminiText(part map[string]interface{}) bool {
+	for k := range part {
+		if k != "text" && k != "thought" && k != "thoughtSignature" {
+			return false
+		}
+	}
+	return true
+}
+
+// ===== helpers =====
+
+func intValue(v interface{}) int {
+	if f, ok := v.(float64); ok {
+		return int(f)
+	}
+	return 0
+}
+
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+func sortedKeys[V any](m map[int]V) []int {
+	keys := make([]int, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+	return keys
+}
@@ -0,0 +1,225 @@
+package converter
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func decodeJSON(t *testing.T, b []byte) map[string]interface{} {
+	t.Helper()
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		t.Fatalf("invalid JSON %s: %v", b, err)
+	}
+	return m
+}
+
+func TestAggregateClaudeStream(t *testing.T) {
+	sse := `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"claude-sonnet-4","stop_reason":null,"usage":{"input_tokens":30,"output_tokens":1}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":" world"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"city\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"Paris\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":12}}
+
+event: message_stop
+data: {"type":"message_stop"}
+`
+	out, err := AggregateStream(domain.ClientTypeClaude, []byte(sse))
+	if err != nil {
+		t.Fatalf("AggregateStream failed: %v", err)
+	}
+
+	var resp ClaudeResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("invalid Claude response: %v", err)
+	}
+	if resp.ID != "msg_1" || resp.StopReason != "tool_use" {
+		t.Errorf("id/stop_reason = %q/%q", resp.ID, resp.StopReason)
+	}
+	if resp.Usage.InputTokens != 30 || resp.Usage.OutputTokens != 12 {
+		t.Errorf("usage = %+v, want input 30 output 12", resp.Usage)
+	}
+	if len(resp.Content) != 2 {
+		t.Fatalf("expected 2 content blocks, got %d", len(resp.Content))
+	}
+	if resp.Content[0].Text != "Hello world" {
+		t.Errorf("text = %q", resp.Content[0].Text)
+	}
+	if !reflect.DeepEqual(resp.Content[1].Input, map[string]interface{}{"city": "Paris"}) {
+		t.Errorf("tool input = %v", resp.Content[1].Input)
+	}
+}
+
+func TestSynthesizeClaudeStreamRoundTrip(t *testing.T) {
+	body := `{"id":"msg_2","type":"message","role":"assistant","model":"claude-sonnet-4",
+		"content":[{"type":"thinking","thinking":"hmm","signature":"sig"},{"type":"text","text":"Hi"},{"type":"tool_use","id":"toolu_2","name":"search","input":{"q":"go"}}],
+		"stop_reason":"tool_use","stop_sequence":null,"usage":{"input_tokens":5,"output_tokens":7}}`
+
+	sse, err := SynthesizeStream(domain.ClientTypeClaude, []byte(body))
+	if err != nil {
+		t.Fatalf("SynthesizeStream failed: %v", err)
+	}
+	if !IsSSE(string(sse)) {
+		t.Fatalf("expected SSE output, got %s", sse)
+	}
+
+	out, err := AggregateStream(domain.ClientTypeClaude, sse)
+	if err != nil {
+		t.Fatalf("AggregateStream failed: %v", err)
+	}
+	if got, want := decodeJSON(t, out), decodeJSON(t, []byte(body)); !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch:\n got  %v\n want %v", got, want)
+	}
+}
+
+func TestAggregateOpenAIStream(t *testing.T) {
+	sse := `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"role":"assistant","content":""}}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":"{\"ci"}}]}}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"ty\":\"Paris\"}"}}]}}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[],"usage":{"prompt_tokens":10,"completion_tokens":4,"total_tokens":14}}
+
+data: [DONE]
+`
+	out, err := AggregateStream(domain.ClientTypeOpenAI, []byte(sse))
+	if err != nil {
+		t.Fatalf("AggregateStream failed: %v", err)
+	}
+
+	var resp OpenAIResponse
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("invalid OpenAI response: %v", err)
+	}
+	if resp.Object != "chat.completion" || resp.ID != "chatcmpl-1" || resp.Model != "gpt-4o" {
+		t.Errorf("object/id/model = %q/%q/%q", resp.Object, resp.ID, resp.Model)
+	}
+	if resp.Usage.PromptTokens != 10 || resp.Usage.CompletionTokens != 4 {
+		t.Errorf("usage = %+v", resp.Usage)
+	}
+	if len(resp.Choices) != 1 || resp.Choices[0].FinishReason != "tool_calls" {
+		t.Fatalf("choices = %+v", resp.Choices)
+	}
+	calls := resp.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].ID != "call_1" || calls[0].Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("tool_calls = %+v", calls)
+	}
+}
+
+func TestSynthesizeOpenAIStreamRoundTrip(t *testing.T) {
+	body := `{"id":"chatcmpl-2","object":"chat.completion","created":2,"model":"gpt-4o",
+		"choices":[{"index":0,"message":{"role":"assistant","content":"Hello there"},"finish_reason":"stop"}],
+		"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`
+
+	sse, err := SynthesizeStream(domain.ClientTypeOpenAI, []byte(body))
+	if err != nil {
+		t.Fatalf("SynthesizeStream failed: %v", err)
+	}
+	events, _ := ParseSSE(string(sse))
+	if len(events) == 0 || events[len(events)-1].Event != "done" {
+		t.Errorf("expected stream to end with [DONE], got %s", sse)
+	}
+
+	out, err := AggregateStream(domain.ClientTypeOpenAI, sse)
+	if err != nil {
+		t.Fatalf("AggregateStream failed: %v", err)
+	}
+	if got, want := decodeJSON(t, out), decodeJSON(t, []byte(body)); !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch:\n got  %v\n want %v", got, want)
+	}
+}
+
+func TestCodexStreamRoundTrip(t *testing.T) {
+	body := `{"id":"resp_1","object":"response","status":"completed","model":"gpt-5-codex",
+		"output":[{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"output_text","text":"done"}]}],
+		"usage":{"input_tokens":9,"output_tokens":1,"total_tokens":10}}`
+
+	sse, err := SynthesizeStream(domain.ClientTypeCodex, []byte(body))
+	if err != nil {
+		t.Fatalf("SynthesizeStream failed: %v", err)
+	}
+	out, err := AggregateStream(domain.ClientTypeCodex, sse)
+	if err != nil {
+		t.Fatalf("AggregateStream failed: %v", err)
+	}
+	if got, want := decodeJSON(t, out), decodeJSON(t, []byte(body)); !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch:\n got  %v\n want %v", got, want)
+	}
+
+	if _, err := AggregateStream(domain.ClientTypeCodex, []byte("data: {\"type\":\"response.created\"}\n\n")); err == nil {
+		t.Error("expected error for stream without response.completed")
+	}
+}
+
+func TestAggregateGeminiStream(t *testing.T) {
+	sse := `data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Hel"}]},"index":0}],"modelVersion":"gemini-2.5-pro"}
+
+data: {"candidates":[{"content":{"role":"model","parts":[{"text":"lo"}]},"index":0}]}
+
+data: {"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"f","args":{"a":1}}}]},"finishReason":"STOP","index":0}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":2,"totalTokenCount":6}}
+`
+	out, err := AggregateStream(domain.ClientTypeGemini, []byte(sse))
+	if err != nil {
+		t.Fatalf("AggregateStream failed: %v", err)
+	}
+
+	want := decodeJSON(t, []byte(`{
+		"candidates":[{"content":{"role":"model","parts":[{"text":"Hello"},{"functionCall":{"name":"f","args":{"a":1}}}]},"finishReason":"STOP","index":0}],
+		"modelVersion":"gemini-2.5-pro",
+		"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":2,"totalTokenCount":6}}`))
+	if got := decodeJSON(t, out); !reflect.DeepEqual(got, want) {
+		t.Errorf("aggregate mismatch:\n got  %v\n want %v", got, want)
+	}
+
+	// 非流式 → 流式 → 聚合应还原
+	sse2, err := SynthesizeStream(domain.ClientTypeGemini, out)
+	if err != nil {
+		t.Fatalf("SynthesizeStream failed: %v", err)
+	}
+	back, err := AggregateStream(domain.ClientTypeGemini, sse2)
+	if err != nil {
+		t.Fatalf("AggregateStream failed: %v", err)
+	}
+	if got := decodeJSON(t, back); !reflect.DeepEqual(got, want) {
+		t.Errorf("round trip mismatch:\n got  %v\n want %v", got, want)
+	}
+}
+
+func TestAggregateStreamErrorEvent(t *testing.T) {
+	sse := `event: error
+data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}
+`
+	if _, err := AggregateStream(domain.ClientTypeClaude, []byte(sse)); err == nil || err.Error() != "Overloaded" {
+		t.Errorf("expected Overloaded error, got %v", err)
+	}
+}
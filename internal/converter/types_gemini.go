@@ -49,6 +49,9 @@ type GeminiGenerationConfig struct {
 	MaxOutputTokens  int                   `json:"maxOutputTokens,omitempty"`
 	StopSequences    []string              `json:"stopSequences,omitempty"`
 	CandidateCount   int                   `json:"candidateCount,omitempty"`
+	Seed             *int64                `json:"seed,omitempty"`
+	PresencePenalty  *float64              `json:"presencePenalty,omitempty"`
+	FrequencyPenalty *float64              `json:"frequencyPenalty,omitempty"`
 	ResponseMimeType string                `json:"responseMimeType,omitempty"`
 	ThinkingConfig   *GeminiThinkingConfig `json:"thinkingConfig,omitempty"`
 	EffortLevel      string                `json:"effortLevel,omitempty"` // Claude API v2.0.67+ effort mapping
@@ -15,6 +15,9 @@ type OpenAIRequest struct {
 	PresencePenalty  *float64         `json:"presence_penalty,omitempty"`
 	FrequencyPenalty *float64         `json:"frequency_penalty,omitempty"`
 	LogitBias        map[string]int   `json:"logit_bias,omitempty"`
+	Logprobs         *bool            `json:"logprobs,omitempty"`
+	TopLogprobs      *int             `json:"top_logprobs,omitempty"`
+	Seed             *int64           `json:"seed,omitempty"`
 	User             string           `json:"user,omitempty"`
 	Tools            []OpenAITool     `json:"tools,omitempty"`
 	ToolChoice       interface{}      `json:"tool_choice,omitempty"`
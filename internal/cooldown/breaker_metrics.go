@@ -0,0 +1,215 @@
+package cooldown
+
+import (
+	"sort"
+	"time"
+)
+
+// BreakerMetricsWindow is the rolling window breaker metrics cover. Metrics are
+// kept in memory only and start from zero after a restart.
+const BreakerMetricsWindow = 24 * time.Hour
+
+// Breaker states of a provider/client type, derived from its cooldown:
+// open while in cooldown, half-open after the cooldown ran out until the next
+// request succeeds (closed) or fails (open again), closed otherwise
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open"
+)
+
+// BreakerMetrics summarizes how often a provider/client type trips and recovers
+// within the window
+type BreakerMetrics struct {
+	ProviderID       uint64  `json:"providerID"`
+	ProviderName     string  `json:"providerName,omitempty"`
+	ClientType       string  `json:"clientType,omitempty"` // Empty = all types
+	State            string  `json:"state"`
+	WindowMs         int64   `json:"windowMs"` // covered time: the window, or less shortly after a restart
+	OpenMs           int64   `json:"openMs"`
+	HalfOpenMs       int64   `json:"halfOpenMs"`
+	ClosedMs         int64   `json:"closedMs"`
+	Trips            int     `json:"trips"`
+	TripsPerHour     float64 `json:"tripsPerHour"`
+	Probes           int     `json:"probes"` // requests that ended a half-open state
+	ProbeSuccesses   int     `json:"probeSuccesses"`
+	ProbeSuccessRate float64 `json:"probeSuccessRate"` // 0 when there were no probes
+}
+
+// breakerPeriod is one trip: open from start to until, then half-open until end
+type breakerPeriod struct {
+	start time.Time
+	until time.Time
+	end   time.Time // zero while the period is ongoing
+}
+
+type breakerProbe struct {
+	at      time.Time
+	success bool
+}
+
+type breakerHistory struct {
+	periods []breakerPeriod
+	probes  []breakerProbe
+}
+
+// breakerTracker records breaker transitions per cooldown key in memory
+type breakerTracker struct {
+	started time.Time
+	keys    map[CooldownKey]*breakerHistory
+}
+
+func newBreakerTracker() *breakerTracker {
+	return &breakerTracker{started: time.Now(), keys: make(map[CooldownKey]*breakerHistory)}
+}
+
+func (t *breakerTracker) history(key CooldownKey) *breakerHistory {
+	h, ok := t.keys[key]
+	if !ok {
+		h = &breakerHistory{}
+		t.keys[key] = h
+	}
+	return h
+}
+
+// enter records a cooldown being set. Extending an open cooldown is not a trip;
+// failing after the previous cooldown ran out is a failed probe plus a new trip.
+func (t *breakerTracker) enter(key CooldownKey, now, until time.Time) {
+	h := t.history(key)
+	if n := len(h.periods); n > 0 && h.periods[n-1].end.IsZero() {
+		last := &h.periods[n-1]
+		if now.Before(last.until) {
+			last.until = until
+			return
+		}
+		last.end = now
+		h.probes = append(h.probes, breakerProbe{at: now})
+	}
+	h.periods = append(h.periods, breakerPeriod{start: now, until: until})
+	t.prune(h, now)
+}
+
+// exit records a cooldown being removed; success marks the request outcome
+// that cleared it, which counts as a probe when the cooldown had run out
+func (t *breakerTracker) exit(key CooldownKey, now time.Time, success bool) {
+	h, ok := t.keys[key]
+	if !ok || len(h.periods) == 0 || !h.periods[len(h.periods)-1].end.IsZero() {
+		return
+	}
+	last := &h.periods[len(h.periods)-1]
+	last.end = now
+	if success && !now.Before(last.until) {
+		h.probes = append(h.probes, breakerProbe{at: now, success: true})
+	}
+	t.prune(h, now)
+}
+
+// prune drops trips and probes that ended before the window
+func (t *breakerTracker) prune(h *breakerHistory, now time.Time) {
+	cutoff := now.Add(-BreakerMetricsWindow)
+	i := 0
+	for i < len(h.periods) && !h.periods[i].end.IsZero() && h.periods[i].end.Before(cutoff) {
+		i++
+	}
+	h.periods = h.periods[i:]
+	j := 0
+	for j < len(h.probes) && h.probes[j].at.Before(cutoff) {
+		j++
+	}
+	h.probes = h.probes[j:]
+}
+
+// metrics computes the key's metrics over the window ending at now;
+// ok is false when nothing happened within the window
+func (t *breakerTracker) metrics(key CooldownKey, h *breakerHistory, now time.Time) (m BreakerMetrics, ok bool) {
+	from := now.Add(-BreakerMetricsWindow)
+	if t.started.After(from) {
+		from = t.started
+	}
+	m = BreakerMetrics{ProviderID: key.ProviderID, ClientType: key.ClientType, State: BreakerClosed}
+	var open, halfOpen time.Duration
+	for _, p := range h.periods {
+		end := p.end
+		if end.IsZero() {
+			end = now
+			if now.Before(p.until) {
+				m.State = BreakerOpen
+			} else {
+				m.State = BreakerHalfOpen
+			}
+		}
+		openEnd := p.until
+		if end.Before(openEnd) {
+			openEnd = end
+		}
+		open += overlap(p.start, openEnd, from, now)
+		halfOpen += overlap(p.until, end, from, now)
+		if !p.start.Before(from) {
+			m.Trips++
+		}
+		if !end.Before(from) {
+			ok = true
+		}
+	}
+	for _, probe := range h.probes {
+		if probe.at.Before(from) {
+			continue
+		}
+		m.Probes++
+		if probe.success {
+			m.ProbeSuccesses++
+		}
+	}
+	if !ok && m.Probes == 0 {
+		return m, false
+	}
+
+	window := now.Sub(from)
+	m.WindowMs = window.Milliseconds()
+	m.OpenMs = open.Milliseconds()
+	m.HalfOpenMs = halfOpen.Milliseconds()
+	m.ClosedMs = max(window-open-halfOpen, 0).Milliseconds()
+	if window > 0 {
+		m.TripsPerHour = float64(m.Trips) / window.Hours()
+	}
+	if m.Probes > 0 {
+		m.ProbeSuccessRate = float64(m.ProbeSuccesses) / float64(m.Probes)
+	}
+	return m, true
+}
+
+// overlap returns how much of [start, end) lies within [from, to)
+func overlap(start, end, from, to time.Time) time.Duration {
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// BreakerMetrics returns per provider/client type breaker metrics over the last
+// BreakerMetricsWindow, ordered by provider ID then client type. Keys without
+// any cooldown activity in the window are omitted (closed the whole time).
+func (m *Manager) BreakerMetrics(now time.Time) []*BreakerMetrics {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	result := []*BreakerMetrics{}
+	for key, h := range m.breaker.keys {
+		if metrics, ok := m.breaker.metrics(key, h, now); ok {
+			result = append(result, &metrics)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ProviderID != result[j].ProviderID {
+			return result[i].ProviderID < result[j].ProviderID
+		}
+		return result[i].ClientType < result[j].ClientType
+	})
+	return result
+}
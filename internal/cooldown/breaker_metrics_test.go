@@ -0,0 +1,70 @@
+package cooldown
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerMetrics(t *testing.T) {
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	tr := &breakerTracker{started: start, keys: make(map[CooldownKey]*breakerHistory)}
+	key := CooldownKey{ProviderID: 1, ClientType: "claude"}
+	at := func(minutes int) time.Time { return start.Add(time.Duration(minutes) * time.Minute) }
+
+	// 0-10 分钟打开（第 5 分钟延长不算新的熔断），10-15 半开，第 15 分钟请求失败再次打开
+	tr.enter(key, at(0), at(5))
+	tr.enter(key, at(4), at(10))
+	tr.enter(key, at(15), at(20))
+	// 20-30 半开，第 30 分钟请求成功，之后关闭
+	tr.exit(key, at(30), true)
+
+	m, ok := tr.metrics(key, tr.keys[key], at(60))
+	if !ok {
+		t.Fatal("metrics not reported")
+	}
+	want := BreakerMetrics{
+		ProviderID: 1, ClientType: "claude", State: BreakerClosed,
+		WindowMs:       time.Hour.Milliseconds(),
+		OpenMs:         (15 * time.Minute).Milliseconds(),
+		HalfOpenMs:     (15 * time.Minute).Milliseconds(),
+		ClosedMs:       (30 * time.Minute).Milliseconds(),
+		Trips:          2,
+		TripsPerHour:   2,
+		Probes:         2,
+		ProbeSuccesses: 1, ProbeSuccessRate: 0.5,
+	}
+	if m != want {
+		t.Errorf("metrics = %+v\nwant %+v", m, want)
+	}
+
+	// 进行中的冷却：到期前为打开，到期后为半开
+	other := CooldownKey{ProviderID: 2}
+	tr.enter(other, at(50), at(70))
+	if m, _ := tr.metrics(other, tr.keys[other], at(60)); m.State != BreakerOpen || m.OpenMs != (10*time.Minute).Milliseconds() {
+		t.Errorf("ongoing cooldown = %+v, want open for 10m", m)
+	}
+	if m, _ := tr.metrics(other, tr.keys[other], at(80)); m.State != BreakerHalfOpen || m.HalfOpenMs != (10*time.Minute).Milliseconds() {
+		t.Errorf("expired cooldown = %+v, want half-open for 10m", m)
+	}
+
+	// 窗口外的活动不再上报
+	later := at(30).Add(BreakerMetricsWindow + time.Minute)
+	if _, ok := tr.metrics(key, tr.keys[key], later); ok {
+		t.Error("activity outside the window still reported")
+	}
+}
+
+func TestManagerBreakerMetrics(t *testing.T) {
+	m := NewManager()
+	m.RecordFailure(1, "claude", ReasonServerError, nil)
+	m.ClearCooldown(1, "claude")
+	m.RecordSuccess(2, "claude") // 没有冷却的成功不算探测
+
+	metrics := m.BreakerMetrics(time.Now())
+	if len(metrics) != 1 {
+		t.Fatalf("metrics = %+v, want provider 1 only", metrics)
+	}
+	if got := metrics[0]; got.ProviderID != 1 || got.State != BreakerClosed || got.Trips != 1 || got.Probes != 0 {
+		t.Errorf("metrics = %+v, want one trip, closed, no probes", got)
+	}
+}
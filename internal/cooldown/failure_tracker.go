@@ -54,13 +54,19 @@ func (ft *FailureTracker) LoadFromDatabase() error {
 // IncrementFailure increments the failure count and persists to database
 // Returns the new failure count
 func (ft *FailureTracker) IncrementFailure(providerID uint64, clientType string, reason CooldownReason) int {
+	return ft.IncrementFailureBy(providerID, clientType, reason, 1)
+}
+
+// IncrementFailureBy adds weight to the failure count and persists to database
+// Returns the new failure count
+func (ft *FailureTracker) IncrementFailureBy(providerID uint64, clientType string, reason CooldownReason, weight int) int {
 	key := FailureKey{
 		ProviderID: providerID,
 		ClientType: clientType,
 		Reason:     reason,
 	}
 
-	ft.failureCounts[key]++
+	ft.failureCounts[key] += weight
 	newCount := ft.failureCounts[key]
 
 	// Persist to database
@@ -120,6 +126,16 @@ func (ft *FailureTracker) ResetFailures(providerID uint64, clientType string) {
 	}
 }
 
+// forgetProvider drops all in-memory failure counts of a provider without
+// touching the database, for callers that already deleted the rows
+func (ft *FailureTracker) forgetProvider(providerID uint64) {
+	for key := range ft.failureCounts {
+		if key.ProviderID == providerID {
+			delete(ft.failureCounts, key)
+		}
+	}
+}
+
 // CleanupExpired removes failure counts that are too old
 // This prevents indefinite accumulation of failures
 func (ft *FailureTracker) CleanupExpired(olderThanSeconds int64) {
@@ -0,0 +1,140 @@
+package cooldown
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+// DefaultEventRetention 冷却历史默认保留时长
+const DefaultEventRetention = 30 * 24 * time.Hour
+
+// SetEventRepository sets the repository for the append-only cooldown event history
+func (m *Manager) SetEventRepository(repo repository.CooldownEventRepository) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = repo
+}
+
+// SetEventRetention sets how long cooldown events are kept; 0 disables the history
+func (m *Manager) SetEventRetention(retention time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.eventRetention = retention
+}
+
+// ParseEventRetentionDays parses the cooldown_event_retention_days setting;
+// an empty value means DefaultEventRetention
+func ParseEventRetentionDays(value string) (time.Duration, error) {
+	if strings.TrimSpace(value) == "" {
+		return DefaultEventRetention, nil
+	}
+	days, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || days < 0 {
+		return 0, fmt.Errorf("retention days must be a non-negative integer")
+	}
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// LoadEventRetention loads the history retention from the cooldown_event_retention_days setting
+func (m *Manager) LoadEventRetention(settingRepo repository.SystemSettingRepository) error {
+	value, err := settingRepo.Get(domain.SettingKeyCooldownEventRetentionDays)
+	if err != nil {
+		return err
+	}
+	retention, err := ParseEventRetentionDays(value)
+	if err != nil {
+		return err
+	}
+	m.SetEventRetention(retention)
+	return nil
+}
+
+// History returns a provider's cooldown events since the given time (zero for
+// all retained events), newest first, at most limit of them (0 for no limit)
+func (m *Manager) History(providerID uint64, since time.Time, limit int) ([]*domain.CooldownEvent, error) {
+	m.mu.RLock()
+	events := m.events
+	m.mu.RUnlock()
+	if events == nil {
+		return []*domain.CooldownEvent{}, nil
+	}
+	return events.List(repository.CooldownEventFilter{ProviderID: providerID, Since: since, Limit: limit})
+}
+
+// recordEnterLocked logs entering (or extending) a cooldown. A previous cooldown
+// of the key that already expired is logged as exited first.
+func (m *Manager) recordEnterLocked(key CooldownKey, until time.Time, reason CooldownReason) {
+	now := time.Now()
+	m.breaker.enter(key, now, until)
+	if prev, ok := m.cooldowns[key]; !ok || !now.Before(prev) {
+		if ok {
+			m.recordExitLocked(key, domain.CooldownExitExpired)
+		}
+		m.enteredAt[key] = now
+	}
+	m.appendEventLocked(&domain.CooldownEvent{
+		CreatedAt:    now,
+		ProviderID:   key.ProviderID,
+		ClientType:   key.ClientType,
+		Type:         domain.CooldownEventEnter,
+		Reason:       domain.CooldownReason(reason),
+		UntilTime:    &until,
+		DurationMs:   until.Sub(now).Milliseconds(),
+		FailureCount: m.failureTracker.GetFailureCount(key.ProviderID, key.ClientType, reason),
+	})
+}
+
+// recordExitLocked logs the end of the key's cooldown; must be called before it
+// is removed. A cooldown that already ran out is logged as expired at its end time.
+func (m *Manager) recordExitLocked(key CooldownKey, cause string) {
+	until, ok := m.cooldowns[key]
+	if !ok {
+		return
+	}
+	at := time.Now()
+	if until.Before(at) {
+		at = until
+		cause = domain.CooldownExitExpired
+	}
+	var durationMs int64
+	if entered, ok := m.enteredAt[key]; ok && at.After(entered) {
+		durationMs = at.Sub(entered).Milliseconds()
+	}
+	delete(m.enteredAt, key)
+	m.appendEventLocked(&domain.CooldownEvent{
+		CreatedAt:  at,
+		ProviderID: key.ProviderID,
+		ClientType: key.ClientType,
+		Type:       domain.CooldownEventExit,
+		Reason:     domain.CooldownReason(m.reasons[key]),
+		DurationMs: durationMs,
+		ExitCause:  cause,
+	})
+}
+
+func (m *Manager) appendEventLocked(event *domain.CooldownEvent) {
+	if m.events == nil || m.eventRetention <= 0 {
+		return
+	}
+	if err := m.events.Create(event); err != nil {
+		log.Printf("[Cooldown] Failed to record %s event for provider %d: %v", event.Type, event.ProviderID, err)
+	}
+}
+
+// cleanupEventsLocked drops events older than the retention window
+func (m *Manager) cleanupEventsLocked(now time.Time) {
+	if m.events == nil || m.eventRetention <= 0 {
+		return
+	}
+	if n, err := m.events.DeleteOlderThan(now.Add(-m.eventRetention)); err != nil {
+		log.Printf("[Cooldown] Failed to delete old cooldown events: %v", err)
+	} else if n > 0 {
+		log.Printf("[Cooldown] Deleted %d cooldown events older than %v", n, m.eventRetention)
+	}
+}
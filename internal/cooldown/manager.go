@@ -17,7 +17,12 @@ type Manager struct {
 	reasons        map[CooldownKey]CooldownReason    // cooldown key -> reason
 	failureTracker *FailureTracker                   // tracks failure counts
 	policies       map[CooldownReason]CooldownPolicy // cooldown calculation strategies
+	weights        map[CooldownReason]int            // failures counted per occurrence, see SetFailureWeights
+	enteredAt      map[CooldownKey]time.Time         // cooldown key -> when the current cooldown started
 	repository     repository.CooldownRepository
+	events         repository.CooldownEventRepository // cooldown enter/exit history, see history.go
+	eventRetention time.Duration                      // 0 disables the history
+	breaker        *breakerTracker                    // in-memory breaker metrics, see breaker_metrics.go
 }
 
 // NewManager creates a new cooldown manager
@@ -25,8 +30,11 @@ func NewManager() *Manager {
 	return &Manager{
 		cooldowns:      make(map[CooldownKey]time.Time),
 		reasons:        make(map[CooldownKey]CooldownReason),
+		enteredAt:      make(map[CooldownKey]time.Time),
 		failureTracker: NewFailureTracker(),
 		policies:       DefaultPolicies(),
+		eventRetention: DefaultEventRetention,
+		breaker:        newBreakerTracker(),
 	}
 }
 
@@ -66,6 +74,7 @@ func (m *Manager) LoadFromDatabase() error {
 
 		m.cooldowns = make(map[CooldownKey]time.Time)
 		m.reasons = make(map[CooldownKey]CooldownReason)
+		m.enteredAt = make(map[CooldownKey]time.Time)
 		for _, cd := range cooldowns {
 			key := CooldownKey{
 				ProviderID: cd.ProviderID,
@@ -73,6 +82,7 @@ func (m *Manager) LoadFromDatabase() error {
 			}
 			m.cooldowns[key] = cd.UntilTime
 			m.reasons[key] = CooldownReason(cd.Reason)
+			m.enteredAt[key] = cd.CreatedAt
 		}
 
 		log.Printf("[Cooldown] Loaded %d cooldowns from database", len(cooldowns))
@@ -103,8 +113,8 @@ func (m *Manager) RecordFailure(providerID uint64, clientType string, reason Coo
 	}
 
 	// Otherwise, calculate cooldown based on policy and failure count
-	// Increment failure count
-	failureCount := m.failureTracker.IncrementFailure(providerID, clientType, reason)
+	// Increment failure count by the reason's severity weight
+	failureCount := m.failureTracker.IncrementFailureBy(providerID, clientType, reason, m.failureWeightLocked(reason))
 
 	// Get policy for this reason
 	policy, ok := m.policies[reason]
@@ -153,6 +163,8 @@ func (m *Manager) RecordSuccess(providerID uint64, clientType string) {
 
 	// Clear cooldown from memory
 	key := CooldownKey{ProviderID: providerID, ClientType: clientType}
+	m.breaker.exit(key, time.Now(), true)
+	m.recordExitLocked(key, domain.CooldownExitSuccess)
 	delete(m.cooldowns, key)
 	delete(m.reasons, key)
 
@@ -172,6 +184,7 @@ func (m *Manager) RecordSuccess(providerID uint64, clientType string) {
 // setCooldownLocked sets cooldown without acquiring lock (internal use only)
 func (m *Manager) setCooldownLocked(providerID uint64, clientType string, until time.Time, reason CooldownReason) {
 	key := CooldownKey{ProviderID: providerID, ClientType: clientType}
+	m.recordEnterLocked(key, until, reason)
 	m.cooldowns[key] = until
 	m.reasons[key] = reason
 
@@ -225,6 +238,8 @@ func (m *Manager) ClearCooldown(providerID uint64, clientType string) {
 			}
 		}
 		for _, key := range keysToDelete {
+			m.breaker.exit(key, time.Now(), false)
+			m.recordExitLocked(key, domain.CooldownExitCleared)
 			delete(m.cooldowns, key)
 			delete(m.reasons, key)
 		}
@@ -241,6 +256,8 @@ func (m *Manager) ClearCooldown(providerID uint64, clientType string) {
 	} else {
 		// Clear specific cooldown
 		key := CooldownKey{ProviderID: providerID, ClientType: clientType}
+		m.breaker.exit(key, time.Now(), false)
+		m.recordExitLocked(key, domain.CooldownExitCleared)
 		delete(m.cooldowns, key)
 		delete(m.reasons, key)
 
@@ -256,6 +273,35 @@ func (m *Manager) ClearCooldown(providerID uint64, clientType string) {
 	}
 }
 
+// ResetProvider clears every cooldown and failure count of a provider across all
+// client types. The database rows are deleted in one transaction before memory
+// is cleared, all under the manager lock, so a failed reset leaves state untouched
+// and concurrent failures are recorded either before or after the reset.
+func (m *Manager) ResetProvider(providerID uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.repository != nil {
+		if err := m.repository.ResetProvider(providerID); err != nil {
+			return err
+		}
+		m.failureTracker.forgetProvider(providerID)
+	} else {
+		m.failureTracker.ResetFailures(providerID, "")
+	}
+
+	for key := range m.cooldowns {
+		if key.ProviderID == providerID {
+			m.breaker.exit(key, time.Now(), false)
+			m.recordExitLocked(key, domain.CooldownExitReset)
+			delete(m.cooldowns, key)
+			delete(m.reasons, key)
+		}
+	}
+	log.Printf("[Cooldown] Provider %d: Reset cooldowns and failure counts", providerID)
+	return nil
+}
+
 // IsInCooldown checks if a provider is currently in cooldown for a specific client type
 // Checks both:
 // 1. Global cooldown (clientType = "")
@@ -341,6 +387,8 @@ func (m *Manager) CleanupExpired() {
 
 	for key, until := range m.cooldowns {
 		if now.After(until) {
+			m.breaker.exit(key, now, false)
+			m.recordExitLocked(key, domain.CooldownExitExpired)
 			delete(m.cooldowns, key)
 			delete(m.reasons, key)
 			expiredKeys = append(expiredKeys, key)
@@ -362,6 +410,9 @@ func (m *Manager) CleanupExpired() {
 	// Cleanup old failure counts (older than 24 hours)
 	m.failureTracker.CleanupExpired(24 * 60 * 60)
 
+	// Drop cooldown history past its retention
+	m.cleanupEventsLocked(now)
+
 	if len(expiredKeys) > 0 {
 		log.Printf("[Cooldown] Cleaned up %d expired cooldowns and reset their failure counts", len(expiredKeys))
 	}
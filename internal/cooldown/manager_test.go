@@ -0,0 +1,183 @@
+package cooldown
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+func TestResetProvider(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	cooldownRepo := sqlite.NewCooldownRepository(db)
+	failureRepo := sqlite.NewFailureCountRepository(db)
+
+	m := NewManager()
+	m.SetRepository(cooldownRepo)
+	m.SetFailureCountRepository(failureRepo)
+
+	m.RecordFailure(1, "claude", ReasonServerError, nil)
+	m.RecordFailure(1, "codex", ReasonNetworkError, nil)
+	m.RecordFailure(2, "claude", ReasonServerError, nil)
+
+	if err := m.ResetProvider(1); err != nil {
+		t.Fatalf("ResetProvider failed: %v", err)
+	}
+
+	if m.IsInCooldown(1, "claude") || m.IsInCooldown(1, "codex") {
+		t.Error("provider 1 still in cooldown after reset")
+	}
+	if !m.IsInCooldown(2, "claude") {
+		t.Error("provider 2 cooldown was cleared by resetting provider 1")
+	}
+	if n := m.failureTracker.GetFailureCount(1, "claude", ReasonServerError); n != 0 {
+		t.Errorf("provider 1 in-memory failure count = %d, want 0", n)
+	}
+
+	cooldowns, err := cooldownRepo.GetAll()
+	if err != nil {
+		t.Fatalf("list cooldowns: %v", err)
+	}
+	failures, err := failureRepo.GetAll()
+	if err != nil {
+		t.Fatalf("list failure counts: %v", err)
+	}
+	for _, cd := range cooldowns {
+		if cd.ProviderID == 1 {
+			t.Errorf("cooldown row of provider 1 left in database: %+v", cd)
+		}
+	}
+	for _, fc := range failures {
+		if fc.ProviderID == 1 {
+			t.Errorf("failure count row of provider 1 left in database: %+v", fc)
+		}
+	}
+	if len(cooldowns) != 1 || len(failures) != 1 {
+		t.Errorf("got %d cooldowns and %d failure counts, want provider 2's 1 each", len(cooldowns), len(failures))
+	}
+}
+
+func TestRecordFailureWeighted(t *testing.T) {
+	m := NewManager()
+	m.SetFailureWeights(map[CooldownReason]int{ReasonServerError: 3})
+
+	// 服务端错误每次计 3 次：线性策略 5s * 3 = 15s，第二次 5s * 6 = 30s
+	for i, want := range []int{3, 6} {
+		until := m.RecordFailure(1, "claude", ReasonServerError, nil)
+		if got := m.failureTracker.GetFailureCount(1, "claude", ReasonServerError); got != want {
+			t.Errorf("server error #%d: failure count = %d, want %d", i+1, got, want)
+		}
+		wantCooldown := time.Duration(5*want) * time.Second
+		if d := time.Until(until); d > wantCooldown || d < wantCooldown-time.Second {
+			t.Errorf("server error #%d: cooldown %v, want ~%v", i+1, d, wantCooldown)
+		}
+	}
+
+	// 未配置权重的网络错误仍按 1 次升级：指数策略 5s, 10s
+	m.RecordFailure(2, "claude", ReasonNetworkError, nil)
+	until := m.RecordFailure(2, "claude", ReasonNetworkError, nil)
+	if got := m.failureTracker.GetFailureCount(2, "claude", ReasonNetworkError); got != 2 {
+		t.Errorf("network error failure count = %d, want 2", got)
+	}
+	if d := time.Until(until); d > 10*time.Second || d < 9*time.Second {
+		t.Errorf("network error cooldown %v, want ~10s", d)
+	}
+}
+
+func TestParseFailureWeights(t *testing.T) {
+	weights, err := ParseFailureWeights(`{"server_error":2,"network_error":1}`)
+	if err != nil {
+		t.Fatalf("ParseFailureWeights: %v", err)
+	}
+	if weights[ReasonServerError] != 2 || weights[ReasonNetworkError] != 1 {
+		t.Errorf("weights = %v", weights)
+	}
+	if weights, err := ParseFailureWeights(" "); err != nil || weights != nil {
+		t.Errorf("empty value = %v, %v, want nil weights", weights, err)
+	}
+	for _, bad := range []string{`{"server_error":0}`, `{"server_error":101}`, `[1]`} {
+		if _, err := ParseFailureWeights(bad); err == nil {
+			t.Errorf("ParseFailureWeights(%s) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestCooldownHistory(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	eventRepo := sqlite.NewCooldownEventRepository(db)
+
+	m := NewManager()
+	m.SetEventRepository(eventRepo)
+
+	m.RecordFailure(1, "claude", ReasonServerError, nil)
+	m.RecordSuccess(1, "claude")
+	m.RecordFailure(1, "codex", ReasonNetworkError, nil)
+	m.ClearCooldown(1, "")
+	m.SetCooldownDuration(1, "", 20*time.Millisecond)
+	time.Sleep(30 * time.Millisecond)
+	m.CleanupExpired()
+	m.RecordFailure(2, "claude", ReasonServerError, nil)
+	m.RecordSuccess(2, "gemini") // 未处于冷却，不记录
+
+	events, err := m.History(1, time.Time{}, 0)
+	if err != nil {
+		t.Fatalf("History failed: %v", err)
+	}
+	want := []struct {
+		typ    domain.CooldownEventType
+		client string
+		cause  string
+	}{
+		{domain.CooldownEventExit, "", domain.CooldownExitExpired},
+		{domain.CooldownEventEnter, "", ""},
+		{domain.CooldownEventExit, "codex", domain.CooldownExitCleared},
+		{domain.CooldownEventEnter, "codex", ""},
+		{domain.CooldownEventExit, "claude", domain.CooldownExitSuccess},
+		{domain.CooldownEventEnter, "claude", ""},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events for provider 1, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Type != w.typ || e.ClientType != w.client || e.ExitCause != w.cause {
+			t.Errorf("event %d = %s/%q/%q, want %s/%q/%q", i, e.Type, e.ClientType, e.ExitCause, w.typ, w.client, w.cause)
+		}
+	}
+	if enter := events[5]; enter.Reason != domain.CooldownReason(ReasonServerError) || enter.UntilTime == nil || enter.FailureCount != 1 {
+		t.Errorf("enter event = %+v, want server_error with until time and failure count 1", enter)
+	}
+
+	if events, _ := m.History(2, time.Time{}, 0); len(events) != 1 {
+		t.Errorf("got %d events for provider 2, want 1", len(events))
+	}
+
+	// 保留期为 0 时不再记录
+	m.SetEventRetention(0)
+	m.RecordSuccess(2, "claude")
+	if events, _ := m.History(2, time.Time{}, 0); len(events) != 1 {
+		t.Errorf("got %d events for provider 2 with history disabled, want 1", len(events))
+	}
+}
+
+func TestParseEventRetentionDays(t *testing.T) {
+	if d, err := ParseEventRetentionDays(""); err != nil || d != DefaultEventRetention {
+		t.Errorf("empty value = %v, %v; want default", d, err)
+	}
+	if d, err := ParseEventRetentionDays("7"); err != nil || d != 7*24*time.Hour {
+		t.Errorf("7 = %v, %v; want 7 days", d, err)
+	}
+	for _, v := range []string{"-1", "abc"} {
+		if _, err := ParseEventRetentionDays(v); err == nil {
+			t.Errorf("%q: expected error", v)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package cooldown
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+// DefaultFailureWeight 未配置权重的失败原因每次失败计 1 次
+const DefaultFailureWeight = 1
+
+// maxFailureWeight 单次失败最多计入的次数
+const maxFailureWeight = 100
+
+// ParseFailureWeights parses the cooldown_failure_weights setting, a JSON object
+// mapping CooldownReason to how many failures one occurrence counts as, e.g.
+// {"server_error":2,"network_error":1}. An empty value means every reason weighs 1.
+func ParseFailureWeights(value string) (map[CooldownReason]int, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var weights map[CooldownReason]int
+	if err := json.Unmarshal([]byte(value), &weights); err != nil {
+		return nil, err
+	}
+	for reason, weight := range weights {
+		if weight < 1 || weight > maxFailureWeight {
+			return nil, fmt.Errorf("weight of %s must be between 1 and %d", reason, maxFailureWeight)
+		}
+	}
+	return weights, nil
+}
+
+// SetFailureWeights sets how many failures one occurrence of each reason counts
+// as; reasons not in weights (or nil weights) count as DefaultFailureWeight
+func (m *Manager) SetFailureWeights(weights map[CooldownReason]int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.weights = weights
+}
+
+// LoadFailureWeights loads failure weights from the cooldown_failure_weights setting
+func (m *Manager) LoadFailureWeights(settingRepo repository.SystemSettingRepository) error {
+	value, err := settingRepo.Get(domain.SettingKeyCooldownFailureWeights)
+	if err != nil {
+		return err
+	}
+	weights, err := ParseFailureWeights(value)
+	if err != nil {
+		return err
+	}
+	m.SetFailureWeights(weights)
+	return nil
+}
+
+func (m *Manager) failureWeightLocked(reason CooldownReason) int {
+	if weight, ok := m.weights[reason]; ok && weight > 0 {
+		return weight
+	}
+	return DefaultFailureWeight
+}
@@ -0,0 +1,98 @@
+package core
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+const (
+	// ConcurrencySampleInterval is how often active request counts are sampled
+	ConcurrencySampleInterval = 5 * time.Second
+
+	// 5 秒一次，保留最近 1 小时
+	concurrencyHistorySize = 720
+)
+
+// concurrencyHistory is a fixed-size ring of concurrency samples
+type concurrencyHistory struct {
+	mu      sync.RWMutex
+	samples []domain.ConcurrencySample
+	next    int
+	full    bool
+}
+
+func newConcurrencyHistory(size int) concurrencyHistory {
+	return concurrencyHistory{samples: make([]domain.ConcurrencySample, size)}
+}
+
+func (h *concurrencyHistory) add(sample domain.ConcurrencySample) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.samples[h.next] = sample
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list returns the samples taken after since, oldest first
+func (h *concurrencyHistory) list(since time.Time) []domain.ConcurrencySample {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	ordered := h.samples[:h.next]
+	if h.full {
+		ordered = append(append([]domain.ConcurrencySample{}, h.samples[h.next:]...), h.samples[:h.next]...)
+	}
+	result := make([]domain.ConcurrencySample, 0, len(ordered))
+	for _, sample := range ordered {
+		if sample.Time.After(since) {
+			result = append(result, sample)
+		}
+	}
+	return result
+}
+
+// SampleConcurrency records the current global and per-project active counts
+func (t *RequestTracker) SampleConcurrency(now time.Time) {
+	sample := domain.ConcurrencySample{Time: now, Active: t.ActiveCount()}
+	t.projectMu.Lock()
+	if len(t.projectActive) > 0 {
+		sample.Projects = make(map[uint64]int64, len(t.projectActive))
+		for projectID, count := range t.projectActive {
+			sample.Projects[projectID] = count
+		}
+	}
+	t.projectMu.Unlock()
+	t.history.add(sample)
+}
+
+// RunConcurrencySampler samples active request counts every interval until ctx is done
+func (t *RequestTracker) RunConcurrencySampler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			t.SampleConcurrency(now)
+		}
+	}
+}
+
+// ConcurrencyHistory returns the samples taken after since, oldest first.
+// With a projectID, Active holds that project's count and Projects is omitted.
+func (t *RequestTracker) ConcurrencyHistory(since time.Time, projectID uint64) []domain.ConcurrencySample {
+	samples := t.history.list(since)
+	if projectID == 0 {
+		return samples
+	}
+	for i := range samples {
+		samples[i] = domain.ConcurrencySample{Time: samples[i].Time, Active: samples[i].Projects[projectID]}
+	}
+	return samples
+}
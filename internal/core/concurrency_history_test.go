@@ -0,0 +1,44 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConcurrencyHistory(t *testing.T) {
+	tracker := NewRequestTracker()
+	tracker.history = newConcurrencyHistory(3)
+	base := time.Unix(1700000000, 0)
+
+	tracker.Add()
+	doneA := tracker.TrackProject(7)
+	tracker.SampleConcurrency(base)
+	tracker.Add()
+	doneB := tracker.TrackProject(7)
+	tracker.SampleConcurrency(base.Add(5 * time.Second))
+	doneA()
+	tracker.Done()
+	tracker.SampleConcurrency(base.Add(10 * time.Second))
+	doneB()
+	tracker.Done()
+	tracker.SampleConcurrency(base.Add(15 * time.Second)) // 覆盖最早的采样
+
+	all := tracker.ConcurrencyHistory(time.Time{}, 0)
+	if len(all) != 3 {
+		t.Fatalf("len = %d, want 3", len(all))
+	}
+	wantActive := []int64{2, 1, 0}
+	for i, sample := range all {
+		if sample.Active != wantActive[i] {
+			t.Errorf("sample %d active = %d, want %d", i, sample.Active, wantActive[i])
+		}
+	}
+	if all[2].Projects != nil {
+		t.Errorf("idle sample projects = %v, want nil", all[2].Projects)
+	}
+
+	project := tracker.ConcurrencyHistory(base.Add(5*time.Second), 7)
+	if len(project) != 2 || project[0].Active != 1 || project[1].Active != 0 {
+		t.Errorf("project history = %+v, want active 1 then 0", project)
+	}
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"context"
 	"log"
 	"os"
 	"time"
@@ -9,6 +10,7 @@ import (
 	_ "github.com/awsl-project/maxx/internal/adapter/provider/codex"
 	_ "github.com/awsl-project/maxx/internal/adapter/provider/custom"
 	"github.com/awsl-project/maxx/internal/cooldown"
+	"github.com/awsl-project/maxx/internal/domain"
 	"github.com/awsl-project/maxx/internal/event"
 	"github.com/awsl-project/maxx/internal/executor"
 	"github.com/awsl-project/maxx/internal/handler"
@@ -46,6 +48,7 @@ type DatabaseRepos struct {
 	CodexQuotaRepo           repository.CodexQuotaRepository
 	CooldownRepo             repository.CooldownRepository
 	FailureCountRepo         repository.FailureCountRepository
+	CooldownEventRepo        repository.CooldownEventRepository
 	CachedProviderRepo        *cached.ProviderRepository
 	CachedRouteRepo          *cached.RouteRepository
 	CachedRetryConfigRepo    *cached.RetryConfigRepository
@@ -59,6 +62,9 @@ type DatabaseRepos struct {
 	UsageStatsRepo           repository.UsageStatsRepository
 	ResponseModelRepo        repository.ResponseModelRepository
 	ModelPriceRepo           repository.ModelPriceRepository
+
+	// stopUsageFlusher 停止 InitializeServerComponents 启动的 API Token 用量刷新
+	stopUsageFlusher context.CancelFunc
 }
 
 // ServerComponents 包含服务器运行所需的所有组件
@@ -78,6 +84,8 @@ type ServerComponents struct {
 	ProjectProxyHandler *handler.ProjectProxyHandler
 	RequestTracker      *RequestTracker
 	PprofManager        *PprofManager
+	StorageHealth       handler.StorageHealthChecker
+	Readiness           *handler.Readiness
 }
 
 // InitializeDatabase 初始化数据库和所有仓库
@@ -110,6 +118,7 @@ func InitializeDatabase(config *DatabaseConfig) (*DatabaseRepos, error) {
 	codexQuotaRepo := sqlite.NewCodexQuotaRepository(db)
 	cooldownRepo := sqlite.NewCooldownRepository(db)
 	failureCountRepo := sqlite.NewFailureCountRepository(db)
+	cooldownEventRepo := sqlite.NewCooldownEventRepository(db)
 	apiTokenRepo := sqlite.NewAPITokenRepository(db)
 	modelMappingRepo := sqlite.NewModelMappingRepository(db)
 	usageStatsRepo := sqlite.NewUsageStatsRepository(db)
@@ -142,6 +151,7 @@ func InitializeDatabase(config *DatabaseConfig) (*DatabaseRepos, error) {
 		CodexQuotaRepo:           codexQuotaRepo,
 		CooldownRepo:             cooldownRepo,
 		FailureCountRepo:         failureCountRepo,
+		CooldownEventRepo:        cooldownEventRepo,
 		CachedProviderRepo:        cachedProviderRepo,
 		CachedRouteRepo:          cachedRouteRepo,
 		CachedRetryConfigRepo:    cachedRetryConfigRepo,
@@ -173,9 +183,16 @@ func InitializeServerComponents(
 	log.Printf("[Core] Initializing cooldown manager with database persistence")
 	cooldown.Default().SetRepository(repos.CooldownRepo)
 	cooldown.Default().SetFailureCountRepository(repos.FailureCountRepo)
+	cooldown.Default().SetEventRepository(repos.CooldownEventRepo)
 	if err := cooldown.Default().LoadFromDatabase(); err != nil {
 		log.Printf("[Core] Warning: Failed to load cooldowns from database: %v", err)
 	}
+	if err := cooldown.Default().LoadFailureWeights(repos.SettingRepo); err != nil {
+		log.Printf("[Core] Warning: Failed to load cooldown failure weights: %v", err)
+	}
+	if err := cooldown.Default().LoadEventRetention(repos.SettingRepo); err != nil {
+		log.Printf("[Core] Warning: Failed to load cooldown event retention: %v", err)
+	}
 
 	log.Printf("[Core] Marking stale requests as failed")
 	if count, err := repos.ProxyRequestRepo.MarkStaleAsFailed(instanceID); err != nil {
@@ -201,34 +218,52 @@ func InitializeServerComponents(
 		log.Printf("[Core] Fixed %d failed attempts without end_time", count)
 	}
 
+	readiness := handler.NewReadiness(handler.ReadinessCaches, handler.ReadinessAdapters)
+
 	log.Printf("[Core] Loading cached data")
+	cacheLoadFailed := false
 	if err := repos.CachedProviderRepo.Load(); err != nil {
+		cacheLoadFailed = true
 		log.Printf("[Core] Warning: Failed to load providers cache: %v", err)
 	}
 	if err := repos.CachedRouteRepo.Load(); err != nil {
+		cacheLoadFailed = true
 		log.Printf("[Core] Warning: Failed to load routes cache: %v", err)
 	}
 	if err := repos.CachedRetryConfigRepo.Load(); err != nil {
+		cacheLoadFailed = true
 		log.Printf("[Core] Warning: Failed to load retry configs cache: %v", err)
 	}
 	if err := repos.CachedRoutingStrategyRepo.Load(); err != nil {
+		cacheLoadFailed = true
 		log.Printf("[Core] Warning: Failed to load routing strategies cache: %v", err)
 	}
 	if err := repos.CachedProjectRepo.Load(); err != nil {
+		cacheLoadFailed = true
 		log.Printf("[Core] Warning: Failed to load projects cache: %v", err)
 	}
 	if err := repos.CachedAPITokenRepo.Load(); err != nil {
+		cacheLoadFailed = true
 		log.Printf("[Core] Warning: Failed to load api tokens cache: %v", err)
 	}
 	if err := repos.CachedModelMappingRepo.Load(); err != nil {
+		cacheLoadFailed = true
 		log.Printf("[Core] Warning: Failed to load model mappings cache: %v", err)
 	}
+	if !cacheLoadFailed {
+		readiness.MarkReady(handler.ReadinessCaches)
+	}
 
 	// Initialize model prices and load into Calculator
 	if err := initializeModelPrices(repos.ModelPriceRepo); err != nil {
 		log.Printf("[Core] Warning: Failed to initialize model prices: %v", err)
 	}
 
+	// Load model name normalization rules
+	if err := initializeModelNormalizer(repos.SettingRepo); err != nil {
+		log.Printf("[Core] Warning: Failed to load model normalization rules, using defaults: %v", err)
+	}
+
 	log.Printf("[Core] Creating router")
 	r := router.NewRouter(
 		repos.CachedRouteRepo,
@@ -237,11 +272,29 @@ func InitializeServerComponents(
 		repos.CachedRetryConfigRepo,
 		repos.CachedProjectRepo,
 	)
+	quotaSource := router.NewQuotaSource(repos.AntigravityQuotaRepo, repos.CodexQuotaRepo)
+	r.SetQuotaSource(quotaSource)
+	healthScorer := router.NewHealthScorer(repos.CachedProviderRepo, repos.UsageStatsRepo, repos.SettingRepo, quotaSource)
+	r.SetHealthScorer(healthScorer)
 
 	log.Printf("[Core] Initializing provider adapters")
 	if err := r.InitAdapters(); err != nil {
 		log.Printf("[Core] Warning: Failed to initialize adapters: %v", err)
 	}
+	readiness.MarkReady(handler.ReadinessAdapters)
+
+	RunProviderSelfTest(r, repos.SettingRepo)
+
+	log.Printf("[Core] Starting API token usage flusher")
+	usageCtx, stopUsageFlusher := context.WithCancel(context.Background())
+	repos.stopUsageFlusher = stopUsageFlusher
+	go repos.CachedAPITokenRepo.RunUsageFlusher(usageCtx, APITokenUsageFlushInterval)
+
+	log.Printf("[Core] Starting provider health scorer")
+	go healthScorer.Run(context.Background(), router.HealthScoreRefreshInterval)
+
+	log.Printf("[Core] Starting provider connection warmup")
+	go r.RunConnectionWarmup(context.Background())
 
 	log.Printf("[Core] Starting cooldown cleanup goroutine")
 	go func() {
@@ -266,7 +319,7 @@ func InitializeServerComponents(
 	wailsBroadcaster := event.NewWailsBroadcaster(wsHub)
 
 	log.Printf("[Core] Setting up log output to broadcast via WebSocket")
-	logWriter := handler.NewWebSocketLogWriter(wsHub, os.Stdout, logPath)
+	logWriter := handler.NewWebSocketLogWriter(wsHub, os.Stdout, logPath, handler.LoadLogRotationConfig(repos.SettingRepo))
 	log.SetOutput(logWriter)
 
 	log.Printf("[Core] Creating project waiter")
@@ -289,6 +342,8 @@ func InitializeServerComponents(
 		instanceID,
 		statsAggregator,
 	)
+	costCalculator := LoadCostCalculator(repos.SettingRepo)
+	exec.SetCostCalculator(costCalculator)
 
 	log.Printf("[Core] Creating client adapter")
 	clientAdapter := client.NewAdapter()
@@ -296,6 +351,10 @@ func InitializeServerComponents(
 	log.Printf("[Core] Creating pprof manager")
 	pprofMgr := NewPprofManager(repos.SettingRepo)
 
+	log.Printf("[Core] Creating request tracker for graceful shutdown")
+	requestTracker := NewRequestTracker()
+	go requestTracker.RunConcurrencySampler(context.Background(), ConcurrencySampleInterval)
+
 	log.Printf("[Core] Creating admin service")
 	adminService := service.NewAdminService(
 		repos.CachedProviderRepo,
@@ -312,11 +371,22 @@ func InitializeServerComponents(
 		repos.UsageStatsRepo,
 		repos.ResponseModelRepo,
 		repos.ModelPriceRepo,
+		repos.AntigravityQuotaRepo,
+		repos.CodexQuotaRepo,
 		addr,
 		r,
 		wailsBroadcaster,
 		pprofMgr, // 直接传入 pprofMgr
+		exec,
+		exec,
+		r,
+		exec,
+		exec,
+		requestTracker,
 	)
+	adminService.SetCostCalculator(costCalculator)
+
+	go adminService.RunCostReconciler(context.Background(), service.CostReconcileInterval)
 
 	log.Printf("[Core] Creating backup service")
 	backupService := service.NewBackupService(
@@ -333,17 +403,19 @@ func InitializeServerComponents(
 
 	log.Printf("[Core] Creating handlers")
 	tokenAuthMiddleware := handler.NewTokenAuthMiddleware(repos.CachedAPITokenRepo, repos.SettingRepo)
-	proxyHandler := handler.NewProxyHandler(clientAdapter, exec, repos.CachedSessionRepo, tokenAuthMiddleware)
-	adminHandler := handler.NewAdminHandler(adminService, backupService, logPath)
+	clientIPResolver := handler.NewClientIPResolver(repos.SettingRepo)
+	proxyHandler := handler.NewProxyHandler(clientAdapter, exec, repos.CachedSessionRepo, tokenAuthMiddleware, clientIPResolver)
+	adminHandler := handler.NewAdminHandler(adminService, backupService, logPath, logWriter.Stream())
 	antigravityHandler := handler.NewAntigravityHandler(adminService, repos.AntigravityQuotaRepo, wailsBroadcaster)
 	kiroHandler := handler.NewKiroHandler(adminService)
 	codexHandler := handler.NewCodexHandler(adminService, repos.CodexQuotaRepo, wailsBroadcaster)
 	codexOAuthServer := NewCodexOAuthServer(codexHandler)
 	projectProxyHandler := handler.NewProjectProxyHandler(proxyHandler, repos.CachedProjectRepo)
 
-	log.Printf("[Core] Creating request tracker for graceful shutdown")
-	requestTracker := NewRequestTracker()
 	proxyHandler.SetRequestTracker(requestTracker)
+	proxyHandler.SetStorageHealth(repos.DB.WriteHealth())
+	proxyHandler.SetSettingRepo(repos.SettingRepo)
+	proxyHandler.SetTokenQuota(service.NewTokenQuotaService(repos.ProxyRequestRepo, repos.SettingRepo))
 
 	components := &ServerComponents{
 		Router:              r,
@@ -361,15 +433,29 @@ func InitializeServerComponents(
 		ProjectProxyHandler: projectProxyHandler,
 		RequestTracker:      requestTracker,
 		PprofManager:        pprofMgr,
+		StorageHealth:       repos.DB.WriteHealth(),
+		Readiness:           readiness,
 	}
 
 	log.Printf("[Core] Server components initialized successfully")
 	return components, nil
 }
 
-// CloseDatabase 关闭数据库连接
+// CloseDatabase 停止 API Token 用量刷新并写入剩余用量，然后关闭数据库连接
+// （应在服务器停止、不再有请求之后调用）
 func CloseDatabase(repos *DatabaseRepos) error {
-	if repos != nil && repos.DB != nil {
+	if repos == nil {
+		return nil
+	}
+	if repos.stopUsageFlusher != nil {
+		repos.stopUsageFlusher()
+	}
+	if repos.CachedAPITokenRepo != nil {
+		if err := repos.CachedAPITokenRepo.FlushUsage(); err != nil {
+			log.Printf("[Core] Warning: Failed to flush API token usage: %v", err)
+		}
+	}
+	if repos.DB != nil {
 		return repos.DB.Close()
 	}
 	return nil
@@ -403,6 +489,41 @@ func initializeModelPrices(repo repository.ModelPriceRepository) error {
 	return nil
 }
 
+// initializeModelNormalizer 从系统设置加载模型名称归一化规则
+// 未配置时使用默认规则
+func initializeModelNormalizer(settingRepo repository.SystemSettingRepository) error {
+	value, err := settingRepo.Get(domain.SettingKeyModelNormalizationRules)
+	if err != nil {
+		return err
+	}
+	rules, err := pricing.ParseNormalizationRules(value)
+	if err != nil {
+		return err
+	}
+	pricing.GlobalNormalizer().SetRules(rules)
+	return nil
+}
+
+// LoadCostCalculator 按系统设置创建执行器计费与管理端重算共用的成本计算器
+// 未配置阶梯折扣或配置无效时使用默认的 pricing.GlobalCalculator()
+func LoadCostCalculator(settingRepo repository.SystemSettingRepository) pricing.CostCalculator {
+	value, err := settingRepo.Get(domain.SettingKeyCostVolumeDiscounts)
+	if err != nil {
+		log.Printf("[Core] Warning: Failed to load cost volume discounts: %v", err)
+		return pricing.GlobalCalculator()
+	}
+	tiers, err := pricing.ParseVolumeDiscountTiers(value)
+	if err != nil {
+		log.Printf("[Core] Warning: Invalid cost volume discounts, billing without discounts: %v", err)
+		return pricing.GlobalCalculator()
+	}
+	if len(tiers) == 0 {
+		return pricing.GlobalCalculator()
+	}
+	log.Printf("[Core] Billing with %d cost volume discount tier(s)", len(tiers))
+	return pricing.NewVolumeDiscountCalculator(pricing.GlobalCalculator(), tiers)
+}
+
 // seedDefaultModelPrices 从内置价格表导入默认价格
 func seedDefaultModelPrices(repo repository.ModelPriceRepository) error {
 	pt := pricing.DefaultPriceTable()
@@ -0,0 +1,70 @@
+package core
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/pricing"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+func TestLoadCostCalculator(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	settingRepo := sqlite.NewSystemSettingRepository(db)
+
+	// 未配置：默认价格表计算器
+	if calc := LoadCostCalculator(settingRepo); calc != pricing.CostCalculator(pricing.GlobalCalculator()) {
+		t.Errorf("unset calculator = %T, want the global calculator", calc)
+	}
+
+	if err := settingRepo.Set(domain.SettingKeyCostVolumeDiscounts, `[{"minTokens":1000,"percent":10}]`); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if calc, ok := LoadCostCalculator(settingRepo).(*pricing.VolumeDiscountCalculator); !ok {
+		t.Errorf("configured calculator = %T, want *pricing.VolumeDiscountCalculator", calc)
+	}
+
+	// 配置无效时不折扣
+	if err := settingRepo.Set(domain.SettingKeyCostVolumeDiscounts, `[{"percent":150}]`); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if calc := LoadCostCalculator(settingRepo); calc != pricing.CostCalculator(pricing.GlobalCalculator()) {
+		t.Errorf("invalid config calculator = %T, want the global calculator", calc)
+	}
+}
+
+func TestCloseDatabaseFlushesTokenUsage(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	repos, err := InitializeDatabase(&DatabaseConfig{DBPath: dbPath})
+	if err != nil {
+		t.Fatalf("InitializeDatabase failed: %v", err)
+	}
+	token := &domain.APIToken{Name: "desktop", Token: "maxx_test_token", IsEnabled: true}
+	if err := repos.APITokenRepo.Create(token); err != nil {
+		t.Fatalf("create token: %v", err)
+	}
+	// 用量先在内存中累计，关闭数据库时写入
+	if err := repos.CachedAPITokenRepo.IncrementUseCount(token.ID); err != nil {
+		t.Fatalf("IncrementUseCount: %v", err)
+	}
+	if err := CloseDatabase(repos); err != nil {
+		t.Fatalf("CloseDatabase: %v", err)
+	}
+
+	db, err := sqlite.NewDB(dbPath)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer db.Close()
+	got, err := sqlite.NewAPITokenRepository(db).GetByID(token.ID)
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.UseCount != 1 || got.LastUsedAt == nil {
+		t.Errorf("use count = %d, last used %v; want the pending use flushed", got.UseCount, got.LastUsedAt)
+	}
+}
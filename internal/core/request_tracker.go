@@ -17,12 +17,19 @@ type RequestTracker struct {
 	// notifyCh is used to notify when a request completes during shutdown
 	notifyCh chan struct{}
 	notifyMu sync.Mutex
+
+	// per-project in-flight counts and sampled history for the dashboard
+	projectMu     sync.Mutex
+	projectActive map[uint64]int64
+	history       concurrencyHistory
 }
 
 // NewRequestTracker creates a new request tracker
 func NewRequestTracker() *RequestTracker {
 	return &RequestTracker{
-		shutdownCh: make(chan struct{}),
+		shutdownCh:    make(chan struct{}),
+		projectActive: make(map[uint64]int64),
+		history:       newConcurrencyHistory(concurrencyHistorySize),
 	}
 }
 
@@ -63,6 +70,24 @@ func (t *RequestTracker) ActiveCount() int64 {
 	return atomic.LoadInt64(&t.activeCount)
 }
 
+// TrackProject counts a request as in flight for the project until the
+// returned func is called. Requests without a project are only counted globally.
+func (t *RequestTracker) TrackProject(projectID uint64) func() {
+	if projectID == 0 {
+		return func() {}
+	}
+	t.projectMu.Lock()
+	t.projectActive[projectID]++
+	t.projectMu.Unlock()
+	return func() {
+		t.projectMu.Lock()
+		defer t.projectMu.Unlock()
+		if t.projectActive[projectID]--; t.projectActive[projectID] <= 0 {
+			delete(t.projectActive, projectID)
+		}
+	}
+}
+
 // WaitWithTimeout waits for all active requests to complete with a timeout
 // Returns true if all requests completed, false if timeout occurred
 func (t *RequestTracker) WaitWithTimeout(timeout time.Duration) bool {
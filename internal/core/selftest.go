@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/cooldown"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+	"github.com/awsl-project/maxx/internal/router"
+)
+
+const (
+	// providerSelfTestTimeout 启动自检的总超时，超时后不再等待，避免阻塞启动
+	providerSelfTestTimeout = 10 * time.Second
+	// providerSelfTestCooldown 严格模式下自检失败的 Provider 冷却时长
+	providerSelfTestCooldown = 5 * time.Minute
+)
+
+// RunProviderSelfTest 启动时并发检测各 Provider 的连通性/认证并输出汇总
+// 由 startup_provider_selftest 设置控制，默认关闭；最长阻塞 providerSelfTestTimeout
+func RunProviderSelfTest(r *router.Router, settingRepo repository.SystemSettingRepository) {
+	if !getSettingBool(settingRepo, domain.SettingKeyStartupProviderSelfTest) {
+		return
+	}
+	strict := getSettingBool(settingRepo, domain.SettingKeyStartupSelfTestStrict)
+
+	log.Printf("[SelfTest] Checking provider connectivity (timeout %v)", providerSelfTestTimeout)
+	ctx, cancel := context.WithTimeout(context.Background(), providerSelfTestTimeout)
+	defer cancel()
+
+	var ok, failed, skipped int
+	for _, result := range r.CheckProviders(ctx) {
+		switch {
+		case result.Disabled:
+			skipped++
+			log.Printf("[SelfTest]   SKIP  %s (id=%d): disabled, no enabled route uses it", result.ProviderName, result.ProviderID)
+		case result.Skipped:
+			skipped++
+			log.Printf("[SelfTest]   SKIP  %s (id=%d): health check not supported", result.ProviderName, result.ProviderID)
+		case result.Err != nil:
+			failed++
+			log.Printf("[SelfTest]   FAIL  %s (id=%d) in %v: %v", result.ProviderName, result.ProviderID, result.Duration.Round(time.Millisecond), result.Err)
+			if strict {
+				cooldown.Default().SetCooldownDuration(result.ProviderID, "", providerSelfTestCooldown)
+			}
+		default:
+			ok++
+			log.Printf("[SelfTest]   OK    %s (id=%d) in %v", result.ProviderName, result.ProviderID, result.Duration.Round(time.Millisecond))
+		}
+	}
+
+	log.Printf("[SelfTest] Summary: %d ok, %d failed, %d skipped", ok, failed, skipped)
+	if failed > 0 && strict {
+		log.Printf("[SelfTest] %d failed provider(s) put into cooldown for %v", failed, providerSelfTestCooldown)
+	}
+	if ok == 0 && failed > 0 {
+		log.Printf("[SelfTest] Warning: no provider is reachable, check your provider configuration")
+	}
+}
+
+func getSettingBool(settingRepo repository.SystemSettingRepository, key string) bool {
+	if settingRepo == nil {
+		return false
+	}
+	val, err := settingRepo.Get(key)
+	return err == nil && val == "true"
+}
@@ -18,6 +18,9 @@ const (
 	HTTPShutdownTimeout = 5 * time.Second
 )
 
+// APITokenUsageFlushInterval is how often batched API token usage is written to the database
+const APITokenUsageFlushInterval = 30 * time.Second
+
 // ServerConfig 服务器配置
 type ServerConfig struct {
 	Addr        string
@@ -82,11 +85,8 @@ func (s *ManagedServer) setupRoutes() *http.ServeMux {
 	mux.Handle("/responses", components.ProxyHandler)
 	mux.Handle("/v1beta/models/", components.ProxyHandler)
 
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"ok"}`))
-	})
+	mux.HandleFunc("/health", handler.NewHealthHandler(components.StorageHealth))
+	mux.HandleFunc("/readyz", handler.NewReadyHandler(components.Readiness, components.StorageHealth))
 
 	mux.HandleFunc("/ws", components.WebSocketHub.HandleWebSocket)
 
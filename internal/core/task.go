@@ -2,17 +2,26 @@ package core
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"strconv"
 	"time"
 
+	"github.com/awsl-project/maxx/internal/cooldown"
 	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/event"
 	"github.com/awsl-project/maxx/internal/repository"
 	"github.com/awsl-project/maxx/internal/service"
 )
 
 const (
 	defaultRequestRetentionHours = 168 // 默认保留 168 小时（7天）
+
+	// 待清理详情达到此行数时才通过 WebSocket 广播进度，避免常规小批量清理刷屏
+	detailCleanupProgressThreshold = 1000
+
+	// breakerMetricsBroadcastInterval 熔断指标的 WebSocket 推送间隔
+	breakerMetricsBroadcastInterval = 30 * time.Second
 )
 
 // BackgroundTaskDeps 后台任务依赖
@@ -23,6 +32,10 @@ type BackgroundTaskDeps struct {
 	Settings            repository.SystemSettingRepository
 	AntigravityTaskSvc  *service.AntigravityTaskService
 	CodexTaskSvc        *service.CodexTaskService
+	CostAnomalySvc      *service.CostAnomalyService
+	DailyDigestSvc      *service.DailyDigestService
+	DetailArchiver      *service.RequestDetailArchiver
+	Broadcaster         event.Broadcaster
 }
 
 // StartBackgroundTasks 启动所有后台任务
@@ -66,6 +79,21 @@ func StartBackgroundTasks(deps BackgroundTaskDeps) {
 		go deps.runCodexQuotaRefresh()
 	}
 
+	// 模型成本异常检测（每 5 分钟，未启用时跳过）
+	if deps.CostAnomalySvc != nil {
+		go deps.runCostAnomalyCheck()
+	}
+
+	// 熔断指标推送（每 30 秒）
+	if deps.Broadcaster != nil {
+		go deps.runBreakerMetricsBroadcast()
+	}
+
+	// 每日用量摘要推送（每分钟检查是否到达推送时间，未启用时跳过）
+	if deps.DailyDigestSvc != nil {
+		go deps.runDailyDigest()
+	}
+
 	log.Println("[Task] Background tasks started (aggregation:30s, cleanup:1h, detail-cleanup:dynamic)")
 }
 
@@ -82,6 +110,11 @@ func (d *BackgroundTaskDeps) runCleanupTasks() {
 	// 3. 清理过期请求记录
 	d.cleanupOldRequests()
 
+	// 4. 合并因时区变更产生的错位 day/month 统计桶
+	if _, err := d.UsageStats.CompactTimeBuckets(); err != nil {
+		log.Printf("[Task] Failed to compact usage stats: %v", err)
+	}
+
 	// 注：请求详情清理由独立的 runRequestDetailCleanup 任务处理（动态间隔）
 }
 
@@ -120,23 +153,66 @@ func (d *BackgroundTaskDeps) cleanupOldRequestDetails() {
 		return // -1 永久保存，0 在 executor 中处理，不需要后台清理
 	}
 
-	before := time.Now().Add(-time.Duration(seconds) * time.Second)
+	now := time.Now()
+	before := now.Add(-time.Duration(seconds) * time.Second)
+
+	// 开启归档时先归档即将清理的详情，归档失败则本轮不清理
+	if d.DetailArchiver != nil {
+		archived, err := d.DetailArchiver.Archive(before, now)
+		if err != nil {
+			log.Printf("[Task] Failed to archive request details, skipping detail cleanup: %v", err)
+			return
+		}
+		if archived != nil && (archived.Requests > 0 || archived.Attempts > 0) {
+			log.Printf("[Task] Archived details of %d requests and %d attempts", archived.Requests, archived.Attempts)
+		}
+	}
+
+	// 大量积压时（如调低保留时间后）通过 WebSocket 广播清理进度
+	progressChan := make(chan domain.Progress, 10)
+	reported := make(chan bool, 1)
+	go func() {
+		broadcasted := false
+		for progress := range progressChan {
+			if d.Broadcaster == nil || progress.Total < detailCleanupProgressThreshold {
+				continue
+			}
+			d.Broadcaster.BroadcastMessage("request_detail_cleanup_progress", progress)
+			broadcasted = true
+		}
+		reported <- broadcasted
+	}()
+
+	var requestsCleared, attemptsCleared int64
 
 	// 清理 ProxyRequest 详情
-	if deleted, err := d.ProxyRequest.ClearDetailOlderThan(before); err != nil {
+	if deleted, err := d.ProxyRequest.ClearDetailOlderThanWithProgress(before, progressChan); err != nil {
 		log.Printf("[Task] Failed to clear request details: %v", err)
 	} else if deleted > 0 {
+		requestsCleared = deleted
 		log.Printf("[Task] Cleared details for %d requests older than %d seconds", deleted, seconds)
 	}
 
 	// 清理 ProxyUpstreamAttempt 详情
 	if d.AttemptRepo != nil {
-		if deleted, err := d.AttemptRepo.ClearDetailOlderThan(before); err != nil {
+		if deleted, err := d.AttemptRepo.ClearDetailOlderThanWithProgress(before, progressChan); err != nil {
 			log.Printf("[Task] Failed to clear attempt details: %v", err)
 		} else if deleted > 0 {
+			attemptsCleared = deleted
 			log.Printf("[Task] Cleared details for %d attempts older than %d seconds", deleted, seconds)
 		}
 	}
+
+	close(progressChan)
+	if <-reported {
+		d.Broadcaster.BroadcastMessage("request_detail_cleanup_progress", domain.Progress{
+			Phase:      "completed",
+			Current:    100,
+			Total:      100,
+			Percentage: 100,
+			Message:    fmt.Sprintf("Cleared details of %d requests and %d attempts", requestsCleared, attemptsCleared),
+		})
+	}
 }
 
 // runRequestDetailCleanup 动态间隔清理请求详情
@@ -213,3 +289,47 @@ func (d *BackgroundTaskDeps) runCodexQuotaRefresh() {
 		time.Sleep(time.Duration(interval) * time.Minute)
 	}
 }
+
+// runCostAnomalyCheck 定期检测模型平均每请求成本异常
+func (d *BackgroundTaskDeps) runCostAnomalyCheck() {
+	time.Sleep(1 * time.Minute) // 初始延迟，等待首次统计聚合完成
+
+	ticker := time.NewTicker(5 * time.Minute)
+	defer ticker.Stop()
+	for {
+		if d.CostAnomalySvc.IsEnabled() {
+			d.CostAnomalySvc.Evaluate()
+		}
+		<-ticker.C
+	}
+}
+
+// runDailyDigest 每分钟检查是否需要推送前一天的用量摘要
+func (d *BackgroundTaskDeps) runDailyDigest() {
+	time.Sleep(1 * time.Minute) // 初始延迟，等待首次统计聚合完成
+
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+	for {
+		if d.DailyDigestSvc.IsEnabled() {
+			d.DailyDigestSvc.RunIfDue(time.Now())
+		}
+		<-ticker.C
+	}
+}
+
+// runBreakerMetricsBroadcast 每 30 秒通过 WebSocket 推送熔断指标（不含 Provider 名称），
+// 没有任何冷却活动时不推送，指标全部滑出窗口后推送一次空列表
+func (d *BackgroundTaskDeps) runBreakerMetricsBroadcast() {
+	ticker := time.NewTicker(breakerMetricsBroadcastInterval)
+	defer ticker.Stop()
+	sentEmpty := true
+	for range ticker.C {
+		metrics := cooldown.Default().BreakerMetrics(time.Now())
+		if len(metrics) == 0 && sentEmpty {
+			continue
+		}
+		d.Broadcaster.BroadcastMessage("breaker_metrics", metrics)
+		sentEmpty = len(metrics) == 0
+	}
+}
@@ -24,6 +24,7 @@ type AdapterMetrics struct {
 	CacheCreationCount   uint64
 	Cache5mCreationCount uint64
 	Cache1hCreationCount uint64
+	ReasoningTokens      uint64
 }
 
 // AdapterEvent represents an event from adapter to executor
@@ -38,6 +38,7 @@ type BackupProvider struct {
 	Config               *ProviderConfig `json:"config,omitempty"`
 	SupportedClientTypes []ClientType    `json:"supportedClientTypes,omitempty"`
 	SupportModels        []string        `json:"supportModels,omitempty"`
+	DisabledClientTypes  []ClientType    `json:"disabledClientTypes,omitempty"`
 }
 
 // BackupProject represents a project for backup (using slug as identifier)
@@ -66,6 +67,10 @@ type BackupRoute struct {
 	ProviderName    string     `json:"providerName"`
 	Position        int        `json:"position"`
 	RetryConfigName string     `json:"retryConfigName"` // empty = default
+	ForceDetail     bool       `json:"forceDetail,omitempty"`
+	Multiplier      uint64     `json:"multiplier,omitempty"` // 0 = provider multiplier
+
+	MaxStreamDurationMs int `json:"maxStreamDurationMs,omitempty"` // 0 = provider limit
 }
 
 // BackupRoutingStrategy represents a routing strategy for backup
@@ -104,6 +109,9 @@ type BackupModelMapping struct {
 type ImportOptions struct {
 	ConflictStrategy string `json:"conflictStrategy"` // "skip", "overwrite", "error"
 	DryRun           bool   `json:"dryRun"`
+	// RenameOnConflict imports providers whose name already exists under a
+	// unique name ("name (2)") instead of applying ConflictStrategy
+	RenameOnConflict bool `json:"renameOnConflict"`
 }
 
 // ImportSummary contains counts for a single entity type
@@ -119,6 +127,8 @@ type ImportResult struct {
 	Summary  map[string]ImportSummary `json:"summary"`
 	Errors   []string                 `json:"errors"`
 	Warnings []string                 `json:"warnings"`
+	// ProviderRenames maps backup provider names to the names they were imported under
+	ProviderRenames map[string]string `json:"providerRenames,omitempty"`
 }
 
 // NewImportResult creates a new ImportResult with initialized fields
@@ -24,3 +24,39 @@ type Cooldown struct {
 	UntilTime  time.Time      `json:"untilTime"`  // Absolute time when cooldown ends
 	Reason     CooldownReason `json:"reason"`     // Reason for cooldown
 }
+
+// CooldownEventType 冷却历史事件类型
+type CooldownEventType string
+
+const (
+	CooldownEventEnter CooldownEventType = "enter" // 进入或延长冷却
+	CooldownEventExit  CooldownEventType = "exit"  // 冷却结束
+)
+
+// 冷却结束的原因
+const (
+	CooldownExitExpired = "expired" // 到期
+	CooldownExitSuccess = "success" // 请求成功后清除
+	CooldownExitCleared = "cleared" // 管理端手动清除
+	CooldownExitReset   = "reset"   // Provider 健康状态重置
+)
+
+// CooldownEvent 冷却状态变化的历史记录（只追加），用于事后分析 Provider 健康时间线
+type CooldownEvent struct {
+	ID         uint64            `json:"id"`
+	CreatedAt  time.Time         `json:"createdAt"` // 事件发生时间（到期事件为冷却结束时间）
+	ProviderID uint64            `json:"providerID"`
+	ClientType string            `json:"clientType"` // Empty for global cooldown
+	Type       CooldownEventType `json:"type"`
+	Reason     CooldownReason    `json:"reason"`
+
+	// enter：冷却结束时间与计划时长；exit：实际处于冷却的时长（进入时间未知时为 0）
+	UntilTime  *time.Time `json:"untilTime,omitempty"`
+	DurationMs int64      `json:"durationMs"`
+
+	// enter：该原因当时的失败计数（按权重累计）
+	FailureCount int `json:"failureCount,omitempty"`
+
+	// exit：结束原因，见 CooldownExit* 常量
+	ExitCause string `json:"exitCause,omitempty"`
+}
@@ -7,17 +7,19 @@ import (
 )
 
 var (
-    ErrNotFound          = errors.New("not found")
-    ErrAlreadyExists     = errors.New("already exists")
-    ErrSlugExists        = errors.New("slug already exists")
-    ErrInvalidInput      = errors.New("invalid input")
-    ErrNoRoutes          = errors.New("no routes available")
-    ErrAllRoutesFailed   = errors.New("all routes failed")
-    ErrFirstByteTimeout  = errors.New("first byte timeout")
-    ErrStreamIdleTimeout = errors.New("stream idle timeout")
-    ErrUpstreamError     = errors.New("upstream error")
-    ErrFormatConversion  = errors.New("format conversion error")
-    ErrUnsupportedFormat = errors.New("unsupported format")
+    ErrNotFound             = errors.New("not found")
+    ErrAlreadyExists        = errors.New("already exists")
+    ErrSlugExists           = errors.New("slug already exists")
+    ErrInvalidInput         = errors.New("invalid input")
+    ErrNoRoutes             = errors.New("no routes available")
+    ErrAllRoutesFailed      = errors.New("all routes failed")
+    ErrFirstByteTimeout     = errors.New("first byte timeout")
+    ErrStreamIdleTimeout    = errors.New("stream idle timeout")
+    ErrUpstreamError        = errors.New("upstream error")
+    ErrFormatConversion     = errors.New("format conversion error")
+    ErrUnsupportedFormat    = errors.New("unsupported format")
+    ErrRequestTooLarge      = errors.New("request too large")
+    ErrNoAvailableProviders = errors.New("no available providers")
 )
 
 // ProxyError represents an error during proxy execution
@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// 实验分组依据
+const (
+	ExperimentAssignByToken   = "token"   // 按 API Token 分组，无 Token 时退回 Session
+	ExperimentAssignBySession = "session" // 按 Session 分组
+)
+
+// ModelVariant 实验中的一个模型变体
+type ModelVariant struct {
+	// 变体名称（如 "A"、"B"），记录在请求上用于分析
+	Name string `json:"name"`
+
+	// 替换后的请求模型
+	Model string `json:"model"`
+
+	// 权重，按权重比例分配调用方
+	Weight int `json:"weight"`
+}
+
+// ModelExperiment A/B 模型实验：匹配到的请求按调用方稳定地分配到某个变体，
+// 在路由前用变体模型替换请求模型
+type ModelExperiment struct {
+	// 实验名称，同时作为哈希盐：改名会重新分配调用方
+	Name string `json:"name"`
+
+	Enabled bool `json:"enabled"`
+
+	// 请求模型匹配模式（支持通配符），为空表示匹配所有模型
+	Model string `json:"model,omitempty"`
+
+	// 分组依据：token（默认）或 session
+	AssignBy string `json:"assignBy,omitempty"`
+
+	Variants []ModelVariant `json:"variants"`
+}
+
+// ParseModelExperiments parses and validates the model_experiments setting
+func ParseModelExperiments(value string) ([]ModelExperiment, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var experiments []ModelExperiment
+	if err := json.Unmarshal([]byte(value), &experiments); err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, exp := range experiments {
+		if exp.Name == "" {
+			return nil, fmt.Errorf("experiment name is required")
+		}
+		if names[exp.Name] {
+			return nil, fmt.Errorf("duplicate experiment %q", exp.Name)
+		}
+		names[exp.Name] = true
+		switch exp.AssignBy {
+		case "", ExperimentAssignByToken, ExperimentAssignBySession:
+		default:
+			return nil, fmt.Errorf("experiment %q: assignBy must be %q or %q", exp.Name, ExperimentAssignByToken, ExperimentAssignBySession)
+		}
+		if len(exp.Variants) == 0 {
+			return nil, fmt.Errorf("experiment %q has no variants", exp.Name)
+		}
+		total := 0
+		for _, v := range exp.Variants {
+			if v.Name == "" || v.Model == "" {
+				return nil, fmt.Errorf("experiment %q: variant name and model are required", exp.Name)
+			}
+			if v.Weight < 0 {
+				return nil, fmt.Errorf("experiment %q: variant %q has a negative weight", exp.Name, v.Name)
+			}
+			total += v.Weight
+		}
+		if total == 0 {
+			return nil, fmt.Errorf("experiment %q: variant weights sum to 0", exp.Name)
+		}
+	}
+	return experiments, nil
+}
+
+// AssignModelVariant returns the first enabled experiment matching the
+// request model and the variant assigned to the caller. The assignment is a
+// hash of the experiment name and the caller key, so the same token (or
+// session) always gets the same variant while weights are unchanged.
+// Returns nil, nil when no experiment applies or the caller can't be keyed.
+func AssignModelVariant(experiments []ModelExperiment, requestModel string, apiTokenID uint64, sessionID string) (*ModelExperiment, *ModelVariant) {
+	for i := range experiments {
+		exp := &experiments[i]
+		if !exp.Enabled || (exp.Model != "" && !MatchWildcard(exp.Model, requestModel)) {
+			continue
+		}
+		key := experimentKey(exp.AssignBy, apiTokenID, sessionID)
+		if key == "" {
+			continue
+		}
+		total := 0
+		for _, v := range exp.Variants {
+			total += max(v.Weight, 0)
+		}
+		if total == 0 {
+			continue
+		}
+		h := fnv.New64a()
+		h.Write([]byte(exp.Name + "\x00" + key))
+		point := int(h.Sum64() % uint64(total))
+		for j := range exp.Variants {
+			v := &exp.Variants[j]
+			point -= max(v.Weight, 0)
+			if point < 0 {
+				return exp, v
+			}
+		}
+	}
+	return nil, nil
+}
+
+func experimentKey(assignBy string, apiTokenID uint64, sessionID string) string {
+	if assignBy != ExperimentAssignBySession && apiTokenID != 0 {
+		return "token:" + strconv.FormatUint(apiTokenID, 10)
+	}
+	if sessionID != "" {
+		return "session:" + sessionID
+	}
+	return ""
+}
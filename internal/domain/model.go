@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 // 各种请求的客户端
 type ClientType string
@@ -12,6 +15,15 @@ var (
 	ClientTypeOpenAI ClientType = "openai"
 )
 
+// ParseClientType 解析客户端类型名称（不区分大小写），未知类型返回 false
+func ParseClientType(s string) (ClientType, bool) {
+	switch ct := ClientType(strings.ToLower(strings.TrimSpace(s))); ct {
+	case ClientTypeClaude, ClientTypeCodex, ClientTypeGemini, ClientTypeOpenAI:
+		return ct, true
+	}
+	return "", false
+}
+
 type ProviderConfigCustom struct {
 	// 中转站的 URL
 	BaseURL string `json:"baseURL"`
@@ -22,13 +34,80 @@ type ProviderConfigCustom struct {
 	// 某个 Client 有特殊的 BaseURL
 	ClientBaseURL map[ClientType]string `json:"clientBaseURL,omitempty"`
 
+	// 某个 Client 的上游请求路径覆盖，用于接口路径不标准的中转站（如 /api/v2/chat）
+	// 未配置时使用客户端请求的路径
+	ClientPathOverride map[ClientType]PathOverride `json:"clientPathOverride,omitempty"`
+
 	// 某个 Client 的价格倍率 (10000=1倍，15000=1.5倍)
 	ClientMultiplier map[ClientType]uint64 `json:"clientMultiplier,omitempty"`
 
 	// Model 映射: RequestModel → MappedModel
 	ModelMapping map[string]string `json:"modelMapping,omitempty"`
+
+	// 上游流式模式，为空表示跟随客户端请求
+	// 与客户端请求不一致时由 Executor 在服务端完成流式/非流式转换
+	StreamMode StreamMode `json:"streamMode,omitempty"`
+
+	// 非标准响应的 usage 字段路径映射，为空表示使用标准提取
+	UsageMapping *UsageFieldMapping `json:"usageMapping,omitempty"`
+
+	// 发往上游的 User-Agent，为空表示按客户端类型的默认行为（透传客户端 UA 或使用内置 UA）
+	UserAgent string `json:"userAgent,omitempty"`
+
+	// 优先透传客户端的 User-Agent，客户端未提供时才使用 UserAgent
+	PassthroughUserAgent bool `json:"passthroughUserAgent,omitempty"`
+
+	// 发往上游的 OpenAI-Organization / OpenAI-Project，为空表示透传客户端的请求头
+	// 仅对 OpenAI / Codex 格式的上游请求生效
+	OpenAIOrganization string `json:"openaiOrganization,omitempty"`
+	OpenAIProject      string `json:"openaiProject,omitempty"`
+
+	// 发往上游的 Anthropic-Version / Anthropic-Beta（逗号分隔），为空表示透传客户端的请求头
+	// 仅对 Claude 格式的上游请求生效；请求体需要的 beta（如 1 小时缓存）始终会追加
+	AnthropicVersion string `json:"anthropicVersion,omitempty"`
+	AnthropicBeta    string `json:"anthropicBeta,omitempty"`
+
+	// 非流式 200 响应的响应体为错误结构时仍按成功透传
+	// 默认识别为上游错误（可重试、不计费），用于响应体本身就是合法业务数据的上游
+	PassthroughErrorBodies bool `json:"passthroughErrorBodies,omitempty"`
+
+	// 丢弃上游流式响应中的 SSE 注释行（以 ":" 开头，常用作心跳），默认原样转发给客户端
+	// 丢弃的心跳仍视为上游活动，不会触发流式卡住检测
+	StripSSEComments bool `json:"stripSSEComments,omitempty"`
+}
+
+// PathOverride 上游请求路径覆盖
+type PathOverride struct {
+	// 路径模板，拼接在 BaseURL 之后，支持 {model} 占位符（替换为映射后的模型）
+	Path string `json:"path"`
+	// 在 Path 之后追加客户端请求的标准路径（如 /v1/chat/completions），否则 Path 即完整路径
+	AppendStandardPath bool `json:"appendStandardPath,omitempty"`
+}
+
+// UsageFieldMapping 描述从响应 JSON 中读取 token 数量的位置（gjson 路径，如 "token_usage.prompt"）
+// 流式响应按每个 SSE data 事件分别匹配。未配置的字段不读取
+type UsageFieldMapping struct {
+	InputTokens      string `json:"inputTokens,omitempty"`
+	OutputTokens     string `json:"outputTokens,omitempty"`
+	CacheReadTokens  string `json:"cacheReadTokens,omitempty"`
+	CacheWriteTokens string `json:"cacheWriteTokens,omitempty"`
+	ReasoningTokens  string `json:"reasoningTokens,omitempty"` // 推理 token（输出 token 的一部分）
 }
 
+// IsEmpty 是否没有配置任何路径
+func (m *UsageFieldMapping) IsEmpty() bool {
+	return m == nil || (m.InputTokens == "" && m.OutputTokens == "" && m.CacheReadTokens == "" && m.CacheWriteTokens == "" && m.ReasoningTokens == "")
+}
+
+// StreamMode 上游流式模式
+type StreamMode string
+
+const (
+	StreamModeAuto      StreamMode = ""           // 跟随客户端请求
+	StreamModeStream    StreamMode = "stream"     // 上游只支持流式
+	StreamModeNonStream StreamMode = "non-stream" // 上游只支持非流式
+)
+
 type ProviderConfigAntigravity struct {
 	// 邮箱（用于标识帐号）
 	Email string `json:"email"`
@@ -107,11 +186,121 @@ type ProviderConfigCodex struct {
 	ModelMapping map[string]string `json:"modelMapping,omitempty"`
 }
 
+// ProviderTransport Provider 级别的上游 HTTP 连接配置，各字段为 0 时使用默认值
+// 连接池随 Adapter 创建，在该 Provider 的所有请求间复用；修改配置后 Adapter 重建时生效
+//
+// 关于 HTTP/2 与流式响应：HTTP/2 下多个 SSE 流复用同一条连接，逐帧转发不受影响，
+// 但该连接断开时其上的所有流会同时失败（由重试处理）；部分反向代理对 HTTP/2 流缓冲，
+// 遇到流式响应卡顿时可关闭 HTTP/2 排查
+type ProviderTransport struct {
+	// 禁用 HTTP/2，强制使用 HTTP/1.1（默认通过 TLS ALPN 协商 HTTP/2）
+	DisableHTTP2 bool `json:"disableHTTP2,omitempty"`
+
+	// 每个 Host 保留的最大空闲连接数，默认 16
+	MaxIdleConnsPerHost int `json:"maxIdleConnsPerHost,omitempty"`
+
+	// 空闲连接关闭前的保留秒数，默认 90
+	IdleConnTimeoutSeconds int `json:"idleConnTimeoutSeconds,omitempty"`
+
+	// TCP keep-alive 探测间隔秒数，默认 60
+	KeepAliveSeconds int `json:"keepAliveSeconds,omitempty"`
+
+	// 禁用连接复用，每个请求新建连接
+	DisableKeepAlives bool `json:"disableKeepAlives,omitempty"`
+
+	// 预热连接数：后台定期经该 Provider 的连接池向上游发送轻量请求（不带凭证的 HEAD，不计入统计和限流），
+	// 保持这么多条连接处于已建立状态，空闲后的首个真实请求无需重新握手。0 表示不启用（默认），不能与 DisableKeepAlives 同时使用
+	WarmConnections int `json:"warmConnections,omitempty"`
+
+	// 预热间隔秒数，默认为空闲连接保留时间的 1/3（默认 30 秒），须小于空闲连接保留时间，连接才会在被关闭前刷新
+	WarmIntervalSeconds int `json:"warmIntervalSeconds,omitempty"`
+}
+
+// ProviderRateLimit Provider 级别的请求/Token 速率上限（1 分钟滑动窗口，仅在内存中统计）
+type ProviderRateLimit struct {
+	// 每分钟最大请求数，0 表示不限制
+	RPM uint64 `json:"rpm,omitempty"`
+
+	// 每分钟最大 Token 数（输入 + 输出），0 表示不限制
+	TPM uint64 `json:"tpm,omitempty"`
+}
+
+// Enabled reports whether any cap is set
+func (l *ProviderRateLimit) Enabled() bool {
+	return l != nil && (l.RPM > 0 || l.TPM > 0)
+}
+
 type ProviderConfig struct {
 	Custom      *ProviderConfigCustom      `json:"custom,omitempty"`
 	Antigravity *ProviderConfigAntigravity `json:"antigravity,omitempty"`
 	Kiro        *ProviderConfigKiro        `json:"kiro,omitempty"`
 	Codex       *ProviderConfigCodex       `json:"codex,omitempty"`
+
+	// 速率上限（对所有类型的 Provider 生效）
+	RateLimit *ProviderRateLimit `json:"rateLimit,omitempty"`
+
+	// 上游 HTTP 连接配置（对 Custom、Codex、Antigravity 生效；Kiro 固定使用 HTTP/1.1）
+	Transport *ProviderTransport `json:"transport,omitempty"`
+
+	// 输出 token 上限，请求的 max_tokens 超过时下调到该值（0 表示不限制）
+	MaxOutputTokens uint64 `json:"maxOutputTokens,omitempty"`
+
+	// 输入大小上限：请求体字节数 / 估算的输入 token 数超过时跳过该 Provider 的路由（0 表示不限制）
+	MaxInputBytes  uint64 `json:"maxInputBytes,omitempty"`
+	MaxInputTokens uint64 `json:"maxInputTokens,omitempty"`
+
+	// 价格覆盖：计算该 Provider 的成本时优先于全局 model_prices
+	// ModelID 为模型名或前缀，匹配规则同 model_prices；ID/CreatedAt 不使用
+	PriceOverrides []*ModelPrice `json:"priceOverrides,omitempty"`
+
+	// 格式转换偏好：客户端类型 → 按优先级排列的目标类型
+	// Provider 不支持客户端类型时，取第一个 Provider 支持的目标类型；未配置或均不支持时使用默认选择（优先 Claude）
+	ConversionPreference map[ClientType][]ClientType `json:"conversionPreference,omitempty"`
+
+	// Provider 分组：同名分组的 Provider 视为共享配额池（如同一服务的多个账号）
+	// 路由匹配时，组内成员在其占据的位置内按剩余配额加权重排，剩余越多越靠前
+	Group string `json:"group,omitempty"`
+
+	// 软失败规则（正则）：上游返回 2xx 但响应内容匹配任一规则时（如 "overloaded, try again"），
+	// 视为可重试的失败并切换到下一个路由。流式响应只匹配开头累计的一段内容
+	SoftFailurePatterns []string `json:"softFailurePatterns,omitempty"`
+
+	// 上游返回 2xx 但响应为空或 usage 显示输出 token 为 0 时，视为可重试的失败并切换到下一个路由
+	// 判断需要完整响应：开启后不超过 8KB 的响应（含流式）会在上游结束后才发给客户端
+	RetryEmptyResponses bool `json:"retryEmptyResponses,omitempty"`
+
+	// 不支持的请求参数（如 "top_k"、"seed"）：发往该 Provider 前从请求体中删除，避免可预见的 400
+	// 常见采样参数按各格式的字段名处理（如 Gemini 的 generationConfig.topK），其他名称按同名顶层字段删除
+	UnsupportedParams []string `json:"unsupportedParams,omitempty"`
+
+	// 流式文本改写规则（如去掉上游注入的免责声明前缀），为空时不启用
+	// 只作用于流式响应中发给客户端的文本增量，不改动 SSE 框架、工具调用与思考内容；跨 chunk 的匹配同样生效
+	StreamTextRewrites []StreamTextRewrite `json:"streamTextRewrites,omitempty"`
+
+	// 流式响应总时长上限（毫秒，从第一块数据开始计时），0 表示不限制；路由可单独覆盖
+	// 与首字超时、数据间隔超时（stream_stall_timeout_seconds）不同，限制的是持续输出的总时长
+	MaxStreamDurationMs int `json:"maxStreamDurationMs,omitempty"`
+
+	// 发往上游前压缩请求体 JSON（去掉结构间的空白），用于按请求体大小计费/限制的 Provider
+	// 字符串内容、数字写法与字段顺序不变；请求体不是合法 JSON 时原样发送
+	MinifyRequestBody bool `json:"minifyRequestBody,omitempty"`
+}
+
+// StreamTextRewriteMaxFind 流式文本改写规则查找串的最大字节数，也是跨 chunk 最多暂存的文本长度
+const StreamTextRewriteMaxFind = 1024
+
+// StreamTextRewrite 一条流式文本替换规则：Find 为字面字符串（非正则），多条规则在同一位置都能匹配时取靠前的规则
+type StreamTextRewrite struct {
+	Find    string `json:"find"`
+	Replace string `json:"replace"`
+}
+
+// GroupName returns the provider's quota group, or "" if it isn't in one
+func (p *Provider) GroupName() string {
+	if p == nil || p.Config == nil {
+		return ""
+	}
+	return p.Config.Group
 }
 
 // Provider 供应商
@@ -143,6 +332,24 @@ type Provider struct {
 	// 如果配置了，在 Route 匹配时会检查前置映射后的模型是否在支持列表中
 	// 空数组表示支持所有模型
 	SupportModels []string `json:"supportModels,omitempty"`
+
+	// 禁用的 ClientType 列表：Router 匹配时跳过该 Provider 上这些 ClientType 的所有路由，
+	// 无需删除或逐条禁用路由。优先级：路由禁用 > ClientType 禁用 > 冷却
+	DisabledClientTypes []ClientType `json:"disabledClientTypes,omitempty"`
+
+	// 排空中：Router 不再为新请求匹配该 Provider，已匹配的请求（包括其重试）正常完成
+	// 用于平滑下线：排空后进行中请求数归零即可安全删除或禁用
+	Draining bool `json:"draining,omitempty"`
+}
+
+// IsClientTypeDisabled 是否在该 Provider 上禁用了指定 ClientType
+func (p *Provider) IsClientTypeDisabled(clientType ClientType) bool {
+	for _, ct := range p.DisabledClientTypes {
+		if ct == clientType {
+			return true
+		}
+	}
+	return false
 }
 
 type Project struct {
@@ -158,6 +365,64 @@ type Project struct {
 
 	// 启用自定义路由的 ClientType 列表，空数组表示所有 ClientType 都使用全局路由
 	EnabledCustomRoutes []ClientType `json:"enabledCustomRoutes"`
+
+	// 统计时区（IANA 名称，如 America/New_York），按项目过滤查询 day/month 统计时使用
+	// 为空表示使用全局时区设置
+	Timezone string `json:"timezone,omitempty"`
+
+	// 模型回退链，请求模型在所有路由上都失败后依次改用回退模型
+	ModelFallbacks []ModelFallback `json:"modelFallbacks,omitempty"`
+
+	// 不计费项目：该项目下的请求默认标记为不计费（如内部测试），不计入成本统计
+	NonBillable bool `json:"nonBillable,omitempty"`
+
+	// 每个请求最多尝试的不同路由数，0 表示使用全局设置 max_routes_attempted
+	MaxRoutesAttempted int `json:"maxRoutesAttempted,omitempty"`
+
+	// 曾用 slug：修改 slug 后，旧 slug 在宽限期（project_slug_grace_days）内仍可用于
+	// /project/{slug}/ 代理路径，响应带弃用提示头。由服务端在 slug 变更时维护，更新项目时忽略客户端传入的值
+	PreviousSlugs []PreviousSlug `json:"previousSlugs,omitempty"`
+}
+
+// PreviousSlug 项目的曾用 slug，ExpiresAt 之后不再解析
+type PreviousSlug struct {
+	Slug      string    `json:"slug"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// ActivePreviousSlug 返回 now 时仍有效的曾用 slug 记录，没有则返回 nil
+func (p *Project) ActivePreviousSlug(slug string, now time.Time) *PreviousSlug {
+	for i := range p.PreviousSlugs {
+		if p.PreviousSlugs[i].Slug == slug && now.Before(p.PreviousSlugs[i].ExpiresAt) {
+			return &p.PreviousSlugs[i]
+		}
+	}
+	return nil
+}
+
+// ModelFallback 模型回退链
+// 请求模型匹配 Pattern 时，先按路由顺序尝试该模型的所有路由；全部失败后
+// 依次改用 Fallbacks 中的模型重新匹配路由（同样按路由顺序），直到成功或耗尽
+type ModelFallback struct {
+	Pattern   string   `json:"pattern"`   // 请求模型，支持通配符 *
+	Fallbacks []string `json:"fallbacks"` // 依次尝试的回退模型
+}
+
+// MatchModelFallback 返回第一个匹配 model 的回退链中的回退模型（去掉与 model 相同的项），无匹配返回 nil
+func MatchModelFallback(fallbacks []ModelFallback, model string) []string {
+	for _, f := range fallbacks {
+		if !MatchWildcard(f.Pattern, model) {
+			continue
+		}
+		var models []string
+		for _, m := range f.Fallbacks {
+			if m != "" && m != model {
+				models = append(models, m)
+			}
+		}
+		return models
+	}
+	return nil
 }
 
 type Session struct {
@@ -203,6 +468,15 @@ type Route struct {
 
 	// 重试配置，0 表示使用系统默认
 	RetryConfigID uint64 `json:"retryConfigID"`
+
+	// 强制保存该路由的请求/响应详情，不受 request_detail_retention_seconds=0 影响
+	ForceDetail bool `json:"forceDetail"`
+
+	// 成本倍率覆盖（10000=1倍），0 表示不覆盖。计费倍率优先级：路由 > Provider 按客户端类型的倍率 > 默认 1 倍
+	Multiplier uint64 `json:"multiplier,omitempty"`
+
+	// 流式响应总时长上限（毫秒），0 表示使用 Provider 的 MaxStreamDurationMs
+	MaxStreamDurationMs int `json:"maxStreamDurationMs,omitempty"`
 }
 
 // RoutePositionUpdate represents a route position update
@@ -284,6 +558,9 @@ type ProxyRequest struct {
 	Cache5mWriteCount uint64 `json:"cache5mWriteCount"`
 	Cache1hWriteCount uint64 `json:"cache1hWriteCount"`
 
+	// 推理/思考 tokens，已包含在 OutputTokenCount 中，单独记录以便按推理价格计费
+	ReasoningTokenCount uint64 `json:"reasoningTokenCount"`
+
 	// 价格信息（来自最终 Attempt）
 	ModelPriceID uint64 `json:"modelPriceId"` // 使用的模型价格记录ID
 	Multiplier   uint64 `json:"multiplier"`   // 倍率（10000=1倍）
@@ -293,6 +570,157 @@ type ProxyRequest struct {
 
 	// 使用的 API Token ID，0 表示未使用 Token
 	APITokenID uint64 `json:"apiTokenID"`
+
+	// 客户端 IP（经过 trusted_proxies 校验后的真实 IP）
+	ClientIP string `json:"clientIP"`
+
+	// 是否计费，创建请求时确定：
+	//   1. Token 开启 AllowBillableOverride 时，以 X-Maxx-Billable 请求头为准
+	//   2. 否则 Token 配置 NonBillable 时为 false
+	//   3. 否则项目配置 NonBillable 时为 false
+	//   4. 默认为 true
+	// 不计费请求不计入成本统计，但仍计入请求数/成功率等可靠性统计
+	Billable bool `json:"billable"`
+
+	// 路由对比标记，非空表示该请求是路由对比（CompareRoutes）中的重放请求
+	ComparisonTag string `json:"comparisonTag,omitempty"`
+
+	// A/B 模型实验：命中的实验与分配到的变体，RequestModel 保留客户端原始请求模型
+	Experiment        string `json:"experiment,omitempty"`
+	ExperimentVariant string `json:"experimentVariant,omitempty"`
+
+	// 客户端请求流式，但被 Token 配置或 X-Maxx-Force-Non-Stream 请求头强制为非流式：
+	// 上游仍以流式请求，聚合后一次性返回（IsStream 记录实际返回给客户端的模式）
+	NonStreamOverride bool `json:"nonStreamOverride,omitempty"`
+
+	// 路由决策记录，仅在开启 routing_trace_enabled 时记录，列表接口不返回
+	RoutingTrace *RoutingTrace `json:"routingTrace,omitempty"`
+
+	// 运维备注，仅通过 PATCH /admin/requests/{id}/notes 修改，代理过程中的更新不会覆盖；
+	// 与详情字段分开存储，清理详情后仍保留
+	Notes string `json:"notes,omitempty"`
+}
+
+// ProxyRequestSummary 请求的精简视图，用于实时列表的 WebSocket 推送
+// 不含 RequestInfo/ResponseInfo/RoutingTrace 等大字段，JSON 字段名与 ProxyRequest 一致，
+// 前端可直接合并到已缓存的 ProxyRequest 上，详情在打开时再按需获取
+type ProxyRequestSummary struct {
+	ID                          uint64        `json:"id"`
+	CreatedAt                   time.Time     `json:"createdAt"`
+	UpdatedAt                   time.Time     `json:"updatedAt"`
+	InstanceID                  string        `json:"instanceID"`
+	RequestID                   string        `json:"requestID"`
+	SessionID                   string        `json:"sessionID"`
+	ClientType                  ClientType    `json:"clientType"`
+	RequestModel                string        `json:"requestModel"`
+	ResponseModel               string        `json:"responseModel"`
+	StartTime                   time.Time     `json:"startTime"`
+	EndTime                     time.Time     `json:"endTime"`
+	Duration                    time.Duration `json:"duration"`
+	TTFT                        time.Duration `json:"ttft"`
+	IsStream                    bool          `json:"isStream"`
+	Status                      string        `json:"status"`
+	StatusCode                  int           `json:"statusCode"`
+	Error                       string        `json:"error"`
+	ProxyUpstreamAttemptCount   uint64        `json:"proxyUpstreamAttemptCount"`
+	FinalProxyUpstreamAttemptID uint64        `json:"finalProxyUpstreamAttemptID"`
+	RouteID                     uint64        `json:"routeID"`
+	ProviderID                  uint64        `json:"providerID"`
+	ProjectID                   uint64        `json:"projectID"`
+	InputTokenCount             uint64        `json:"inputTokenCount"`
+	OutputTokenCount            uint64        `json:"outputTokenCount"`
+	CacheReadCount              uint64        `json:"cacheReadCount"`
+	CacheWriteCount             uint64        `json:"cacheWriteCount"`
+	Cache5mWriteCount           uint64        `json:"cache5mWriteCount"`
+	Cache1hWriteCount           uint64        `json:"cache1hWriteCount"`
+	ReasoningTokenCount         uint64        `json:"reasoningTokenCount"`
+	ModelPriceID                uint64        `json:"modelPriceId"`
+	Multiplier                  uint64        `json:"multiplier"`
+	Cost                        uint64        `json:"cost"`
+	APITokenID                  uint64        `json:"apiTokenID"`
+	ClientIP                    string        `json:"clientIP"`
+	Billable                    bool          `json:"billable"`
+	ComparisonTag               string        `json:"comparisonTag,omitempty"`
+	Experiment                  string        `json:"experiment,omitempty"`
+	ExperimentVariant           string        `json:"experimentVariant,omitempty"`
+	NonStreamOverride           bool          `json:"nonStreamOverride,omitempty"`
+}
+
+// Summary returns the compact view of the request broadcast to the live list
+func (r *ProxyRequest) Summary() *ProxyRequestSummary {
+	return &ProxyRequestSummary{
+		ID:                          r.ID,
+		CreatedAt:                   r.CreatedAt,
+		UpdatedAt:                   r.UpdatedAt,
+		InstanceID:                  r.InstanceID,
+		RequestID:                   r.RequestID,
+		SessionID:                   r.SessionID,
+		ClientType:                  r.ClientType,
+		RequestModel:                r.RequestModel,
+		ResponseModel:               r.ResponseModel,
+		StartTime:                   r.StartTime,
+		EndTime:                     r.EndTime,
+		Duration:                    r.Duration,
+		TTFT:                        r.TTFT,
+		IsStream:                    r.IsStream,
+		Status:                      r.Status,
+		StatusCode:                  r.StatusCode,
+		Error:                       r.Error,
+		ProxyUpstreamAttemptCount:   r.ProxyUpstreamAttemptCount,
+		FinalProxyUpstreamAttemptID: r.FinalProxyUpstreamAttemptID,
+		RouteID:                     r.RouteID,
+		ProviderID:                  r.ProviderID,
+		ProjectID:                   r.ProjectID,
+		InputTokenCount:             r.InputTokenCount,
+		OutputTokenCount:            r.OutputTokenCount,
+		CacheReadCount:              r.CacheReadCount,
+		CacheWriteCount:             r.CacheWriteCount,
+		Cache5mWriteCount:           r.Cache5mWriteCount,
+		Cache1hWriteCount:           r.Cache1hWriteCount,
+		ReasoningTokenCount:         r.ReasoningTokenCount,
+		ModelPriceID:                r.ModelPriceID,
+		Multiplier:                  r.Multiplier,
+		Cost:                        r.Cost,
+		APITokenID:                  r.APITokenID,
+		ClientIP:                    r.ClientIP,
+		Billable:                    r.Billable,
+		ComparisonTag:               r.ComparisonTag,
+		Experiment:                  r.Experiment,
+		ExperimentVariant:           r.ExperimentVariant,
+		NonStreamOverride:           r.NonStreamOverride,
+	}
+}
+
+// Routing trace step actions
+const (
+	RoutingTraceMatched  = "matched"  // 路由进入候选列表
+	RoutingTraceSkipped  = "skipped"  // 路由被过滤或跳过
+	RoutingTraceFailed   = "failed"   // 在该路由上的尝试失败，转到下一条
+	RoutingTraceSelected = "selected" // 最终由该路由完成请求
+)
+
+// RoutingTrace explains how a request was routed: which routes were
+// considered, why some were skipped, and which one served the request
+type RoutingTrace struct {
+	Strategy RoutingStrategyType `json:"strategy"`
+	Steps    []RoutingTraceStep  `json:"steps"`
+}
+
+// RoutingTraceStep 路由决策中的一步
+type RoutingTraceStep struct {
+	Action     string `json:"action"`
+	RouteID    uint64 `json:"routeId,omitempty"`
+	ProviderID uint64 `json:"providerId,omitempty"`
+	Model      string `json:"model,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// Add appends a step; a nil trace (tracing disabled) ignores it
+func (t *RoutingTrace) Add(step RoutingTraceStep) {
+	if t == nil {
+		return
+	}
+	t.Steps = append(t.Steps, step)
 }
 
 type ProxyUpstreamAttempt struct {
@@ -344,27 +772,116 @@ type ProxyUpstreamAttempt struct {
 	Cache5mWriteCount uint64 `json:"cache5mWriteCount"`
 	Cache1hWriteCount uint64 `json:"cache1hWriteCount"`
 
+	// 推理/思考 tokens，已包含在 OutputTokenCount 中，单独记录以便按推理价格计费
+	ReasoningTokenCount uint64 `json:"reasoningTokenCount"`
+
 	// 价格信息
 	ModelPriceID uint64 `json:"modelPriceId"` // 使用的模型价格记录ID
 	Multiplier   uint64 `json:"multiplier"`   // 倍率（10000=1倍）
 
 	Cost uint64 `json:"cost"`
+
+	// 客户端请求的输出 token 上限被 Provider 的 MaxOutputTokens 下调时，记录原始值（0 表示未下调）
+	MaxTokensClampedFrom uint64 `json:"maxTokensClampedFrom,omitempty"`
+
+	// 按 Provider 的 UnsupportedParams 从请求体中删除的参数
+	StrippedParams []string `json:"strippedParams,omitempty"`
+
+	// 按 Provider 的 MinifyRequestBody 压缩请求体节省的字节数（0 表示未压缩）
+	MinifiedBytesSaved uint64 `json:"minifiedBytesSaved,omitempty"`
+
+	// 按该 Provider 的价格覆盖计费时记录 Provider ID（0 表示使用全局价格），成本重算时据此查找覆盖
+	PriceOverrideProviderID uint64 `json:"priceOverrideProviderID,omitempty"`
+
+	// 流式输出速度（tokens/s），按首字到结束的耗时计算；非流式或流太短无法测量时为 0
+	OutputTPS float64 `json:"outputTps,omitempty"`
+
+	// 失败原因（仅 FAILED），不随详情清理，供错误聚合使用；StatusCode 为上游状态码，0 表示未收到响应
+	Error      string `json:"error,omitempty"`
+	StatusCode int    `json:"statusCode,omitempty"`
+
+	// 流式输出超过总时长上限被截断：已发给客户端的内容保留并追加错误事件，attempt 记为 FAILED 且不再切换路由
+	StreamTruncated bool `json:"streamTruncated,omitempty"`
 }
 
 // AttemptCostData contains minimal data needed for cost recalculation
 type AttemptCostData struct {
-	ID               uint64
-	ProxyRequestID   uint64
-	ResponseModel    string
-	MappedModel      string
-	RequestModel     string
-	InputTokenCount  uint64
-	OutputTokenCount uint64
-	CacheReadCount   uint64
-	CacheWriteCount  uint64
-	Cache5mWriteCount uint64
-	Cache1hWriteCount uint64
-	Cost             uint64
+	ID                  uint64
+	ProxyRequestID      uint64
+	ResponseModel       string
+	MappedModel         string
+	RequestModel        string
+	InputTokenCount     uint64
+	OutputTokenCount    uint64
+	CacheReadCount      uint64
+	CacheWriteCount     uint64
+	Cache5mWriteCount   uint64
+	Cache1hWriteCount   uint64
+	ReasoningTokenCount uint64
+	Multiplier          uint64 // 计费时生效的倍率（10000=1倍），0 表示旧记录未保存
+	Cost                uint64
+	ModelPriceID        uint64 // 计费时使用的价格记录ID，0 表示内置价格表或价格覆盖
+
+	PriceOverrideProviderID uint64 // 使用了该 Provider 的价格覆盖，0 表示全局价格
+}
+
+// RequestCostData 请求成本核对所需的最小字段
+type RequestCostData struct {
+	ID   uint64
+	Cost uint64
+}
+
+// MultiplierUsage 某 Provider 在某客户端类型下实际生效过的倍率（按 attempt 统计）
+type MultiplierUsage struct {
+	ProviderID uint64     `json:"providerID"`
+	ClientType ClientType `json:"clientType"`
+	Multiplier uint64     `json:"multiplier"` // 10000=1倍，0 表示旧记录未保存倍率
+	Attempts   uint64     `json:"attempts"`
+	Cost       uint64     `json:"cost"` // 纳美元，不含不计费请求
+	FirstSeen  time.Time  `json:"firstSeen"`
+	LastSeen   time.Time  `json:"lastSeen"`
+}
+
+// AttemptFailure 错误聚合所需的失败 attempt 字段
+type AttemptFailure struct {
+	ID             uint64
+	ProxyRequestID uint64
+	ProviderID     uint64
+	StartTime      time.Time
+	StatusCode     int
+	Error          string
+}
+
+// ErrorGroup 归一化错误信息和状态码相同的一组失败 attempt
+type ErrorGroup struct {
+	Pattern           string    `json:"pattern"`    // 归一化后的错误信息（ID、时间、数字等替换为占位符）
+	StatusCode        int       `json:"statusCode"` // 上游状态码，0 表示未收到响应（网络错误、超时等）
+	Count             uint64    `json:"count"`
+	ProviderIDs       []uint64  `json:"providerIDs"`
+	ExampleRequestIDs []uint64  `json:"exampleRequestIDs"` // 最近的几个请求
+	ExampleError      string    `json:"exampleError"`      // 最近一条原始错误信息
+	FirstSeen         time.Time `json:"firstSeen"`
+	LastSeen          time.Time `json:"lastSeen"`
+}
+
+// ErrorSummary 一段时间内失败 attempt 的分组统计，按数量降序
+type ErrorSummary struct {
+	Start         time.Time     `json:"start"`
+	End           time.Time     `json:"end"`
+	TotalFailures uint64        `json:"totalFailures"`
+	Truncated     bool          `json:"truncated"` // 失败数超过扫描上限，只统计了最近的部分
+	Groups        []*ErrorGroup `json:"groups"`
+}
+
+// ClientIPStats 一段时间内单个客户端 IP 的请求统计
+type ClientIPStats struct {
+	ClientIP     string    `json:"clientIP"`
+	Requests     uint64    `json:"requests"`
+	Failed       uint64    `json:"failed"`
+	InputTokens  uint64    `json:"inputTokens"`
+	OutputTokens uint64    `json:"outputTokens"`
+	Cost         uint64    `json:"cost"` // 纳美元，不含不计费请求
+	LastSeen     time.Time `json:"lastSeen"`
 }
 
 // 重试配置
@@ -403,6 +920,8 @@ var (
 	RoutingStrategyPriority RoutingStrategyType = "priority"
 	// 加权随机
 	RoutingStrategyWeightedRandom RoutingStrategyType = "weighted_random"
+	// 按 Provider 健康分加权随机，健康分越高越常被优先尝试
+	RoutingStrategyHealthWeighted RoutingStrategyType = "health_weighted"
 )
 
 // 路由策略配置（策略特定参数）
@@ -450,6 +969,52 @@ const (
 	SettingKeyEnablePprof                   = "enable_pprof"                     // 是否启用 pprof 性能分析，"true" 或 "false"，默认 "false"
 	SettingKeyPprofPort                     = "pprof_port"                       // pprof 服务端口，默认 6060
 	SettingKeyPprofPassword                 = "pprof_password"                   // pprof 访问密码，为空表示不需要密码
+	SettingKeyModelNormalizationRules       = "model_normalization_rules"        // 模型名称归一化规则（JSON），为空表示使用默认规则
+	SettingKeyTrustedProxies                = "trusted_proxies"                  // 可信代理 CIDR 列表（逗号分隔），仅来自这些地址的 X-Forwarded-For 才会被采信
+	SettingKeyIPDenyList                    = "ip_deny_list"                     // 客户端 IP 黑名单（逗号分隔，支持 CIDR），为空表示不限制
+	SettingKeyStreamBufferMaxBytes          = "stream_buffer_max_bytes"          // 流式响应缓冲上限（字节），慢客户端时先缓冲上游数据以尽早释放上游连接，0 表示禁用（默认）
+	SettingKeyStreamStallTimeoutSeconds     = "stream_stall_timeout_seconds"     // 流式响应相邻数据块的最大间隔（秒），超过视为上游卡住，中止本次尝试并按可重试错误处理，默认 120，0 表示禁用
+	SettingKeyCooldownBroadcastIntervalMs   = "cooldown_broadcast_interval_ms"   // 每个 Provider 的 cooldown_update 广播最小间隔（毫秒），默认 3000，0 表示不节流
+	SettingKeyCooldownFailureWeights        = "cooldown_failure_weights"         // 各冷却原因每次失败计入的次数（JSON 对象，如 {"server_error":2,"network_error":1}，1-100），权重越大冷却升级越快，未配置的原因计 1
+	SettingKeyCooldownEventRetentionDays    = "cooldown_event_retention_days"    // 冷却进入/退出历史的保留天数，默认 30，0 表示不记录历史
+	SettingKeyAttemptBroadcastMaxQPS        = "attempt_broadcast_max_qps"        // 每秒最多推送中间状态（请求进度、attempt 变化）的新请求数，超出的请求只推送终态，0 表示不限制（默认）
+	SettingKeyStartupProviderSelfTest       = "startup_provider_selftest"        // 启动时并发检测各 Provider 连通性并输出汇总，"true" 或 "false"，默认 "false"
+	SettingKeyStartupSelfTestStrict         = "startup_selftest_strict"          // 启动自检失败的 Provider 进入冷却（5 分钟），冷却期间不会被路由，"true" 或 "false"，默认 "false"
+	SettingKeyCostAnomalyEnabled            = "cost_anomaly_enabled"             // 是否启用模型成本异常检测，"true" 或 "false"，默认 "false"
+	SettingKeyCostAnomalyWindowHours        = "cost_anomaly_window_hours"        // 成本异常检测近期窗口（小时，截至当前的滑动窗口，最多 24），默认 1
+	SettingKeyCostAnomalyBaselineHours      = "cost_anomaly_baseline_hours"      // 成本异常检测基线窗口（小时，紧接近期窗口之前），默认 24
+	SettingKeyCostAnomalyThreshold          = "cost_anomaly_threshold"           // 近期平均每请求成本达到基线的多少倍视为异常，默认 2
+	SettingKeyCostAnomalyMinRequests        = "cost_anomaly_min_requests"        // 近期窗口与基线窗口各自的最少请求数，不足则不判定，默认 10
+	SettingKeyLogMaxSizeMB                  = "log_max_size_mb"                  // maxx.log 轮转大小（MB），默认 50，0 表示不按大小轮转，重启后生效
+	SettingKeyLogMaxFiles                   = "log_max_files"                    // 保留的历史日志文件数（maxx.log.1 ~ maxx.log.N），默认 5，重启后生效
+	SettingKeyLogMaxAgeDays                 = "log_max_age_days"                 // 日志文件最长保留天数，超过后轮转/删除，默认 0 表示不限制，重启后生效
+	SettingKeyProviderRateLimitMode         = "provider_rate_limit_mode"         // Provider 达到 RPM/TPM 上限时的处理方式："skip" 跳到下一条路由（默认），"queue" 排队等待窗口释放（受请求超时约束）
+	SettingKeyAPITokenConcurrencyMode       = "api_token_concurrency_mode"       // API Token 达到同时请求数上限时的处理方式："reject" 返回 429（默认），"queue" 排队等待名额释放（客户端断开即放弃）
+	SettingKeyRoutingTraceEnabled           = "routing_trace_enabled"            // 在请求记录上保存路由决策过程（匹配、跳过原因、最终选择），用于排查路由配置，"true" 或 "false"，默认 "false"
+	SettingKeyDailyDigestEnabled            = "daily_digest_enabled"             // 是否每日推送前一天的用量/成本摘要，"true" 或 "false"，默认 "false"
+	SettingKeyDailyDigestTime               = "daily_digest_time"                // 每日摘要推送时间（HH:MM，按 timezone 设置），默认 "09:00"
+	SettingKeyDailyDigestWebhookURL         = "daily_digest_webhook_url"         // 每日摘要 Webhook 地址，以 JSON POST 推送，为空表示不推送
+	SettingKeyDailyDigestSMTP               = "daily_digest_smtp"                // 每日摘要邮件配置（JSON：host/port/username/password/from/to），为空表示不发邮件
+	SettingKeyDailyDigestMetrics            = "daily_digest_metrics"             // 摘要包含的指标（逗号分隔：requests,tokens,cost,models,providers），为空表示全部
+	SettingKeyDailyDigestLastDate           = "daily_digest_last_date"           // 最近一次已推送摘要的日期（YYYY-MM-DD），由系统维护，避免重启后重复推送
+	SettingKeyCostDisplayDecimals           = "cost_display_decimals"            // 成本换算为美元显示时保留的小数位（0-9，四舍五入），默认 4
+	SettingKeyStreamDedupEnabled            = "stream_dedup_enabled"             // 相同的并发流式请求（同 Token、同请求体）共享一个上游流，"true" 或 "false"，默认 "false"
+	SettingKeyEnforceContentType            = "enforce_content_type"             // 强制 Content-Type：/v1/* 非 JSON 请求返回 415，响应按流式/非流式改写为 SSE/JSON，"true" 或 "false"，默认 "false"
+	SettingKeyModelExperiments              = "model_experiments"                // A/B 模型实验（JSON 数组：name/enabled/model/assignBy/variants），按 Token 或 Session 稳定分配模型变体，为空表示不启用
+	SettingKeyStatsTimezone                 = "stats_timezone"                   // 最近一次重建 day/month 统计所用的时区，由系统维护，与 timezone 不一致时自动按新时区重建
+	SettingKeyDisconnectGraceSeconds        = "client_disconnect_grace_seconds"  // 非流式且带 Idempotency-Key 的请求在客户端断开后继续等待上游的宽限期（秒），期间完成的结果缓存供客户端重试取回，0 表示禁用（默认）
+	SettingKeyCostReconcileEnabled          = "cost_reconcile_enabled"           // 是否每小时核对请求成本与其 attempts 成本（按计费时的价格记录重算）是否一致，"true" 或 "false"，默认 "false"
+	SettingKeyCostReconcileFix              = "cost_reconcile_fix"               // 成本核对发现偏差时是否自动修正，"false" 表示仅报告（默认）
+	SettingKeyCostReconcileLookbackHours    = "cost_reconcile_lookback_hours"    // 定期成本核对覆盖最近多少小时内创建的请求，默认 24
+	SettingKeyProviderHealthWeights         = "provider_health_weights"          // Provider 健康分权重，如 "success=40,latency=20,cooldown=20,quota=20"（默认）
+	SettingKeyProviderHealthLatencySLOMs    = "provider_health_latency_slo_ms"   // 健康分的延迟 SLO（毫秒），平均请求耗时超过后延迟分项按比例下降，默认 30000
+	SettingKeyMaxRoutesAttempted            = "max_routes_attempted"             // 每个请求最多尝试的不同路由数（与重试次数无关），达到后不再切换路由，项目可单独覆盖，0 表示不限制（默认）
+	SettingKeyProjectSlugGraceDays          = "project_slug_grace_days"          // 项目修改 slug 后旧 slug 继续可用的天数（代理响应带 Deprecation/Sunset 头），默认 30，0 表示立即失效
+	SettingKeyRetryAfterJitterPercent       = "retry_after_jitter_percent"       // 按上游 Retry-After 等待重试时加入的随机抖动（±百分比，0-100），避免同时被限流的请求同时重试，默认 20，0 表示不抖动
+	SettingKeyRetryAfterMaxWaitSeconds      = "retry_after_max_wait_seconds"     // 按 Retry-After 重试前的最长等待（秒），Retry-After 超过该值时不再等待、直接切换到下一条路由，0 表示不限制（默认）
+	SettingKeyRequestDetailArchiveDir       = "request_detail_archive_dir"       // 详情清理前归档的目录（绝对路径），每轮清理写一个 maxx-details-<UTC时间>.jsonl.gz，为空表示不归档（默认）
+	SettingKeyRequestDetailArchiveDays      = "request_detail_archive_days"      // 归档文件保留天数，超过后在下次归档时删除，0 表示永久保留（默认）
+	SettingKeyCostVolumeDiscounts           = "cost_volume_discounts"            // 按单次请求 token 总量的阶梯折扣（JSON 数组：minTokens/percent），计费与重算都按折扣后成本，为空表示不折扣（默认），重启后生效
 )
 
 // ModelPrice 模型价格（每个模型可有多条记录，每条代表一个版本）
@@ -465,6 +1030,9 @@ type ModelPrice struct {
 	Cache5mWritePriceMicro uint64 `json:"cache5mWritePriceMicro"`
 	Cache1hWritePriceMicro uint64 `json:"cache1hWritePriceMicro"`
 
+	// 推理/思考 token 价格，0 表示按 OutputPriceMicro 计费
+	ReasoningPriceMicro uint64 `json:"reasoningPriceMicro"`
+
 	// 1M Context 分层定价
 	Has1MContext       bool   `json:"has1mContext"`
 	Context1MThreshold uint64 `json:"context1mThreshold"`
@@ -512,6 +1080,95 @@ type AntigravityQuota struct {
 	Models []AntigravityModelQuota `json:"models"`
 }
 
+// Provider 排空状态
+type ProviderDrainStatus struct {
+	ProviderID uint64 `json:"providerID"`
+	Draining   bool   `json:"draining"`
+
+	// 本实例上正在执行的上游请求数
+	InFlight int64 `json:"inFlight"`
+
+	// 排空完成：Draining 且没有进行中的请求
+	Drained bool `json:"drained"`
+}
+
+// Provider 分组成员的配额状态
+type ProviderGroupMember struct {
+	ProviderID uint64 `json:"providerID"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+
+	// 剩余配额百分比 0-100，nil 表示没有配额数据
+	RemainingPercent *float64 `json:"remainingPercent,omitempty"`
+
+	// 配额来源：codex / antigravity / rateLimit
+	QuotaSource string `json:"quotaSource,omitempty"`
+
+	// 是否处于冷却中
+	InCooldown bool `json:"inCooldown"`
+}
+
+// Provider 分组（共享配额池）状态
+type ProviderGroupStatus struct {
+	Name    string                 `json:"name"`
+	Members []*ProviderGroupMember `json:"members"`
+
+	// 池内剩余配额百分比：有配额数据的成员的平均值，nil 表示均无数据
+	RemainingPercent *float64 `json:"remainingPercent,omitempty"`
+}
+
+// Provider 综合健康分（GET /admin/provider-health），计算方式见 stats.HealthScore
+type ProviderHealthScore struct {
+	ProviderID uint64 `json:"providerID"`
+	Name       string `json:"name"`
+
+	// 0-100，越高越健康
+	Score float64 `json:"score"`
+
+	// 统计窗口内的上游请求数；样本不足时成功率与延迟为空，不参与评分
+	Requests     uint64   `json:"requests"`
+	SuccessRate  *float64 `json:"successRate,omitempty"`  // 0-100
+	AvgLatencyMs *float64 `json:"avgLatencyMs,omitempty"` // 平均请求耗时
+
+	// 处于冷却中的 ClientType，"" 表示全局冷却
+	CooldownClientTypes []string `json:"cooldownClientTypes"`
+
+	// 剩余配额百分比及来源，无配额数据时为空
+	QuotaPercent *float64 `json:"quotaPercent,omitempty"`
+	QuotaSource  string   `json:"quotaSource,omitempty"`
+
+	// 成功率、延迟与配额数据的刷新时间
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Capabilities 客户端类型与 Provider 类型的能力元数据（GET /admin/capabilities）
+type Capabilities struct {
+	ClientTypes   []*ClientTypeCapabilities   `json:"clientTypes"`
+	ProviderTypes []*ProviderTypeCapabilities `json:"providerTypes"`
+}
+
+// ClientTypeCapabilities 客户端类型可转换到的上游格式
+type ClientTypeCapabilities struct {
+	Type       ClientType   `json:"type"`
+	ConvertsTo []ClientType `json:"convertsTo"`
+}
+
+// ProviderTypeCapabilities Provider 类型原生支持的客户端类型、可转换接入的客户端类型及功能
+type ProviderTypeCapabilities struct {
+	Type string `json:"type"`
+
+	// 原生支持的客户端类型；ConfigurableClientTypes 为 true 时是未配置时的默认值
+	NativeClientTypes       []ClientType `json:"nativeClientTypes"`
+	ConfigurableClientTypes bool         `json:"configurableClientTypes"`
+
+	// 经格式转换后可以接入的其他客户端类型
+	ConvertedClientTypes []ClientType `json:"convertedClientTypes"`
+
+	Streaming  bool `json:"streaming"`
+	Tools      bool `json:"tools"`
+	Embeddings bool `json:"embeddings"`
+}
+
 // Codex 额度窗口信息
 type CodexQuotaWindow struct {
 	UsedPercent        *float64 `json:"usedPercent,omitempty"`
@@ -580,6 +1237,9 @@ type ProviderStats struct {
 
 	// 成本 (纳美元)
 	TotalCost uint64 `json:"totalCost"`
+
+	// 按 client_type 查询时，该 Provider 是否禁用了此 ClientType
+	ClientTypeDisabled bool `json:"clientTypeDisabled,omitempty"`
 }
 
 // Granularity 统计数据的时间粒度
@@ -616,11 +1276,16 @@ type UsageStats struct {
 	TotalDurationMs    uint64 `json:"totalDurationMs"` // 累计请求耗时（毫秒）
 	TotalTTFTMs        uint64 `json:"totalTtftMs"`     // 累计首字时长（毫秒）
 
+	// 流式输出速度：仅累计测得 TPS 的 attempt，平均 TPS = TPSOutputTokens * 1000 / TPSDurationMs
+	TPSOutputTokens uint64 `json:"tpsOutputTokens"` // 累计输出 tokens
+	TPSDurationMs   uint64 `json:"tpsDurationMs"`   // 累计首字到结束的耗时（毫秒）
+
 	// Token 统计
-	InputTokens  uint64 `json:"inputTokens"`
-	OutputTokens uint64 `json:"outputTokens"`
-	CacheRead    uint64 `json:"cacheRead"`
-	CacheWrite   uint64 `json:"cacheWrite"`
+	InputTokens     uint64 `json:"inputTokens"`
+	OutputTokens    uint64 `json:"outputTokens"`
+	CacheRead       uint64 `json:"cacheRead"`
+	CacheWrite      uint64 `json:"cacheWrite"`
+	ReasoningTokens uint64 `json:"reasoningTokens"` // 推理 tokens（已包含在 OutputTokens 中）
 
 	// 成本 (纳美元)
 	Cost uint64 `json:"cost"`
@@ -636,7 +1301,195 @@ type UsageStatsSummary struct {
 	TotalOutputTokens  uint64  `json:"totalOutputTokens"`
 	TotalCacheRead     uint64  `json:"totalCacheRead"`
 	TotalCacheWrite    uint64  `json:"totalCacheWrite"`
+	TotalReasoning     uint64  `json:"totalReasoning"` // 推理 tokens（已包含在 TotalOutputTokens 中）
 	TotalCost          uint64  `json:"totalCost"`
+	AvgOutputTPS       float64 `json:"avgOutputTps"`  // 流式输出平均速度（tokens/s），无测量数据时为 0
+	CacheHitRatio      float64 `json:"cacheHitRatio"` // 缓存命中率 cacheRead / (input + cacheRead)，0-1，无输入时为 0
+}
+
+// CostAnomaly 模型单次请求平均成本异常（近期窗口相对基线窗口显著上升）
+type CostAnomaly struct {
+	Model            string    `json:"model"`
+	DetectedAt       time.Time `json:"detectedAt"`
+	RecentRequests   uint64    `json:"recentRequests"`
+	RecentAvgCost    uint64    `json:"recentAvgCost"` // 近期窗口平均每请求成本（纳美元）
+	BaselineRequests uint64    `json:"baselineRequests"`
+	BaselineAvgCost  uint64    `json:"baselineAvgCost"` // 基线窗口平均每请求成本（纳美元）
+	Ratio            float64   `json:"ratio"`           // RecentAvgCost / BaselineAvgCost
+}
+
+// StorageHealth 数据库写入健康状态，Degraded 时代理请求返回 503
+type StorageHealth struct {
+	Degraded            bool       `json:"degraded"`
+	Reason              string     `json:"reason,omitempty"` // 最近一次持续性写入失败的错误
+	Since               *time.Time `json:"since,omitempty"`  // 进入降级的时间
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+}
+
+// DailyDigest 每日用量/成本摘要，未包含的指标为空
+type DailyDigest struct {
+	Date     string `json:"date"` // 统计日期 YYYY-MM-DD（按 Timezone）
+	Timezone string `json:"timezone"`
+
+	Requests *DailyDigestRequests `json:"requests,omitempty"`
+	Tokens   *DailyDigestTokens   `json:"tokens,omitempty"`
+	Cost     *uint64              `json:"cost,omitempty"` // 纳美元
+
+	// 美元金额按 CostDecimals 位小数四舍五入，分项按最大余数法分配，使全部分项之和等于合计
+	CostUSD      *float64 `json:"costUsd,omitempty"`
+	CostDecimals int      `json:"costDecimals"`
+
+	TopModels []*DailyDigestEntry `json:"topModels,omitempty"` // 按成本排序的前几个模型
+	Providers []*DailyDigestEntry `json:"providers,omitempty"` // 按成本排序的全部 Provider
+}
+
+// DailyDigestRequests 每日摘要中的请求数
+type DailyDigestRequests struct {
+	Total       uint64  `json:"total"`
+	Successful  uint64  `json:"successful"`
+	Failed      uint64  `json:"failed"`
+	SuccessRate float64 `json:"successRate"`
+}
+
+// DailyDigestTokens 每日摘要中的 token 数
+type DailyDigestTokens struct {
+	Input      uint64 `json:"input"`
+	Output     uint64 `json:"output"`
+	CacheRead  uint64 `json:"cacheRead"`
+	CacheWrite uint64 `json:"cacheWrite"`
+	Reasoning  uint64 `json:"reasoning"` // 已包含在 Output 中
+}
+
+// DailyDigestEntry 每日摘要中按模型/Provider 的分项
+type DailyDigestEntry struct {
+	Name       string  `json:"name"`
+	ProviderID uint64  `json:"providerID,omitempty"`
+	Requests   uint64  `json:"requests"`
+	Tokens     uint64  `json:"tokens"` // 输入 + 输出
+	Cost       uint64  `json:"cost"`   // 纳美元
+	CostUSD    float64 `json:"costUsd"`
+}
+
+// RouteComparisonRun 单个请求在某条路由上的重放结果
+type RouteComparisonRun struct {
+	ProxyRequestID uint64 `json:"proxyRequestID"` // 重放产生的请求记录 ID，0 表示未能发起
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+	DurationMs     int64  `json:"durationMs"`
+	TTFTMs         int64  `json:"ttftMs"`
+	InputTokens    uint64 `json:"inputTokens"`
+	OutputTokens   uint64 `json:"outputTokens"`
+	Cost           uint64 `json:"cost"` // 纳美元
+}
+
+// RouteComparisonSample 单个请求在两条路由上的对比
+type RouteComparisonSample struct {
+	RequestID uint64              `json:"requestID"` // 原始请求 ID
+	A         *RouteComparisonRun `json:"a"`
+	B         *RouteComparisonRun `json:"b"`
+	// 两次响应文本的粗略相似度（0-1，按词集合计算），任一侧失败时为 0
+	Similarity float64 `json:"similarity"`
+}
+
+// RouteComparisonSide 一条路由的汇总
+type RouteComparisonSide struct {
+	RouteID       uint64 `json:"routeID"`
+	Successful    int    `json:"successful"`
+	Failed        int    `json:"failed"`
+	AvgDurationMs int64  `json:"avgDurationMs"` // 仅统计成功的重放
+	AvgTTFTMs     int64  `json:"avgTtftMs"`     // 仅统计成功的流式重放
+	InputTokens   uint64 `json:"inputTokens"`
+	OutputTokens  uint64 `json:"outputTokens"`
+	TotalCost     uint64 `json:"totalCost"`
+}
+
+// RouteComparisonReport 路由对比报告，Delta 字段均为 B 相对 A 的差值（B - A）
+type RouteComparisonReport struct {
+	ComparisonTag string                   `json:"comparisonTag"` // 重放请求记录上的标记
+	A             RouteComparisonSide      `json:"a"`
+	B             RouteComparisonSide      `json:"b"`
+	Samples       []*RouteComparisonSample `json:"samples"`
+
+	AvgDurationDeltaMs int64   `json:"avgDurationDeltaMs"`
+	OutputTokensDelta  int64   `json:"outputTokensDelta"`
+	CostDelta          int64   `json:"costDelta"`
+	AvgSimilarity      float64 `json:"avgSimilarity"` // 双方均成功的样本的平均相似度
+}
+
+// RouteTestResult 一条路由的测试结果
+type RouteTestResult struct {
+	RouteID        uint64 `json:"routeID"`
+	Position       int    `json:"position"`
+	ProviderID     uint64 `json:"providerID"`
+	ProviderName   string `json:"providerName"`
+	Model          string `json:"model"`          // 测试请求使用的模型
+	ProxyRequestID uint64 `json:"proxyRequestID"` // 测试产生的请求记录 ID，0 表示未能发起
+	Success        bool   `json:"success"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+	DurationMs     int64  `json:"durationMs"`
+	InputTokens    uint64 `json:"inputTokens"`
+	OutputTokens   uint64 `json:"outputTokens"`
+	Cost           uint64 `json:"cost"` // 纳美元
+}
+
+// ProjectRouteTestReport 项目某 ClientType 下全部启用路由的测试结果，按路由位置排序
+type ProjectRouteTestReport struct {
+	ProjectID     uint64             `json:"projectID"`
+	ClientType    ClientType         `json:"clientType"`
+	ProjectRoutes bool               `json:"projectRoutes"` // false 表示项目未启用自定义路由，测试的是全局路由
+	ProbeTag      string             `json:"probeTag"`      // 测试请求记录上的标记（comparisonTag）
+	Succeeded     int                `json:"succeeded"`
+	Failed        int                `json:"failed"`
+	Results       []*RouteTestResult `json:"results"`
+}
+
+// ResolveRequest 路由调试：描述一个假想请求，只解析路由决策，不会发往上游
+type ResolveRequest struct {
+	ClientType ClientType        `json:"clientType"`
+	ProjectID  uint64            `json:"projectID"`       // 0 时依次使用 Token 关联项目、X-Maxx-Project-ID 请求头
+	Token      string            `json:"token,omitempty"` // API Token 明文，为空时从 headers 中提取
+	Model      string            `json:"model"`
+	Stream     bool              `json:"stream"`
+	Headers    map[string]string `json:"headers,omitempty"`
+}
+
+// ResolvedRoute 假想请求在一条候选路由上的处理方式
+type ResolvedRoute struct {
+	Position         int          `json:"position"` // 尝试顺序，从 1 开始
+	RouteID          uint64       `json:"routeID"`
+	ProviderID       uint64       `json:"providerID"`
+	ProviderName     string       `json:"providerName"`
+	ProviderType     string       `json:"providerType"`
+	RequestModel     string       `json:"requestModel"` // 在该路由上请求的模型（回退路由为回退模型）
+	Fallback         bool         `json:"fallback"`     // 是否来自模型回退链
+	MappedModel      string       `json:"mappedModel"`  // 模型映射后实际发往上游的模型
+	NeedsConversion  bool         `json:"needsConversion"`
+	TargetClientType ClientType   `json:"targetClientType"` // 转换后的格式，无需转换时与请求格式相同
+	UpstreamStream   bool         `json:"upstreamStream"`   // 上游实际使用的流式模式
+	RetryConfig      *RetryConfig `json:"retryConfig"`
+}
+
+// ResolvedProviderCooldown Provider 当前的冷却状态
+type ResolvedProviderCooldown struct {
+	ProviderID   uint64     `json:"providerID"`
+	ProviderName string     `json:"providerName"`
+	InCooldown   bool       `json:"inCooldown"`
+	Until        *time.Time `json:"until,omitempty"`
+	Reason       string     `json:"reason,omitempty"`
+}
+
+// RequestResolution 假想请求的完整路由决策
+type RequestResolution struct {
+	ClientType     ClientType                  `json:"clientType"`
+	ProjectID      uint64                      `json:"projectID"`
+	APITokenID     uint64                      `json:"apiTokenID"`
+	RequestModel   string                      `json:"requestModel"`
+	FallbackModels []string                    `json:"fallbackModels"`
+	Routes         []*ResolvedRoute            `json:"routes"` // 按尝试顺序排列
+	Cooldowns      []*ResolvedProviderCooldown `json:"cooldowns"`
+	Trace          *RoutingTrace               `json:"trace"` // 包含被跳过的路由及原因
+	Error          string                      `json:"error,omitempty"`
 }
 
 // APIToken API 访问令牌
@@ -670,10 +1523,54 @@ type APIToken struct {
 	// 使用次数
 	UseCount uint64 `json:"useCount"`
 
+	// 模型回退链，匹配时优先于项目配置
+	ModelFallbacks []ModelFallback `json:"modelFallbacks,omitempty"`
+
+	// 不计费 Token：使用该 Token 的请求默认标记为不计费，优先于项目配置
+	NonBillable bool `json:"nonBillable,omitempty"`
+
+	// 允许通过 X-Maxx-Billable 请求头覆盖计费标记
+	AllowBillableOverride bool `json:"allowBillableOverride,omitempty"`
+
+	// 强制非流式：客户端请求流式时，上游仍流式请求，聚合为完整响应后一次性返回（便于获取完整 usage / 调试）
+	ForceNonStream bool `json:"forceNonStream,omitempty"`
+
+	// 允许通过 X-Maxx-Force-Non-Stream 请求头覆盖强制非流式
+	AllowStreamOverride bool `json:"allowStreamOverride,omitempty"`
+
+	// 同时进行中的请求数上限，0 表示不限制；超出时按 api_token_concurrency_mode 拒绝（429）或排队
+	MaxConcurrent int `json:"maxConcurrent,omitempty"`
+
+	// 按周期的模型用量配额，任一配额用尽时拒绝该模型的请求（429），周期边界按配置的时区重置
+	ModelQuotas []TokenModelQuota `json:"modelQuotas,omitempty"`
+
 	// 软删除时间
 	DeletedAt *time.Time `json:"deletedAt,omitempty"`
 }
 
+// QuotaPeriod 配额统计周期
+type QuotaPeriod string
+
+const (
+	QuotaPeriodDay   QuotaPeriod = "day"
+	QuotaPeriodMonth QuotaPeriod = "month"
+)
+
+// TokenModelQuota API Token 在一个周期内对匹配模型的用量上限（按 usage_stats 统计，多个匹配模型合计）
+type TokenModelQuota struct {
+	// 请求的模型名，支持通配符（如 claude-*），空表示所有模型
+	Model string `json:"model"`
+
+	// 统计周期：day / month
+	Period QuotaPeriod `json:"period"`
+
+	// 周期内最大请求数，0 表示不限制
+	MaxRequests uint64 `json:"maxRequests,omitempty"`
+
+	// 周期内最大 Token 数（输入 + 输出），0 表示不限制
+	MaxTokens uint64 `json:"maxTokens,omitempty"`
+}
+
 // APITokenCreateResult 创建 Token 的返回结果（包含明文 Token，仅返回一次）
 type APITokenCreateResult struct {
 	Token    string    `json:"token"`    // 明文 Token（仅创建时返回）
@@ -737,6 +1634,17 @@ type ModelMappingQuery struct {
 	APITokenID   uint64
 }
 
+// MatchModelMapping 返回第一个匹配的映射规则，mappings 需已按优先级排序
+// 同时使用原始模型名和归一化模型名匹配，未匹配返回 nil
+func MatchModelMapping(mappings []*ModelMapping, model, normalizedModel string) *ModelMapping {
+	for _, m := range mappings {
+		if MatchWildcard(m.Pattern, model) || (normalizedModel != "" && MatchWildcard(m.Pattern, normalizedModel)) {
+			return m
+		}
+	}
+	return nil
+}
+
 // ResponseModel 记录所有出现过的 response model
 // 用于快速查询可选的模型列表，避免每次 DISTINCT 查询
 type ResponseModel struct {
@@ -903,6 +1811,13 @@ type DashboardData struct {
 	Timezone      string                            `json:"timezone"` // 配置的时区，如 "Asia/Shanghai"
 }
 
+// ConcurrencySample 某一采样时刻的在途代理请求数
+type ConcurrencySample struct {
+	Time     time.Time        `json:"time"`
+	Active   int64            `json:"active"`             // 全局（或按项目过滤后该项目）的在途请求数
+	Projects map[uint64]int64 `json:"projects,omitempty"` // projectID -> 在途请求数，仅包含非零项目
+}
+
 // ===== Progress Reporting =====
 
 // Progress represents a progress update for long-running operations
@@ -916,7 +1831,7 @@ type Progress struct {
 
 // AggregateEvent represents a progress event during stats aggregation
 type AggregateEvent struct {
-	Phase     string      `json:"phase"`      // "aggregate_minute", "rollup_hour", "rollup_day", "rollup_month"
+	Phase     string      `json:"phase"`      // "aggregate_minute", "rollup_hour", "rebuild_timezone", "rollup_day", "rollup_month"
 	From      Granularity `json:"from"`       // Source granularity (for rollup)
 	To        Granularity `json:"to"`         // Target granularity
 	StartTime int64       `json:"start_time"` // Start of time range (unix ms)
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 )
@@ -10,6 +11,8 @@ var (
 	nonAlphanumericRegex = regexp.MustCompile(`[^a-z0-9]+`)
 	// 匹配开头和结尾的连字符
 	trimHyphenRegex = regexp.MustCompile(`^-+|-+$`)
+	// 合法的 slug：小写字母、数字和单个连字符分隔的片段
+	validSlugRegex = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
 )
 
 // GenerateSlug 从名称生成 URL 友好的 slug
@@ -30,3 +33,11 @@ func GenerateSlug(name string) string {
 
 	return slug
 }
+
+// ValidateSlug 校验自定义 slug 是否与 GenerateSlug 的输出格式一致（可直接用于 URL 路径）
+func ValidateSlug(slug string) error {
+	if len(slug) > 128 || !validSlugRegex.MatchString(slug) {
+		return fmt.Errorf("%w: slug %q must contain only lowercase letters, digits and single hyphens (max 128 characters)", ErrInvalidInput, slug)
+	}
+	return nil
+}
@@ -6,6 +6,8 @@ import "github.com/awsl-project/maxx/internal/domain"
 // WebSocket 和 Wails 都实现此接口
 type Broadcaster interface {
 	BroadcastProxyRequest(req *domain.ProxyRequest)
+	// BroadcastProxyRequestSummary 推送请求的精简视图（实时列表用，不含请求/响应详情）
+	BroadcastProxyRequestSummary(summary *domain.ProxyRequestSummary)
 	BroadcastProxyUpstreamAttempt(attempt *domain.ProxyUpstreamAttempt)
 	BroadcastLog(message string)
 	BroadcastMessage(messageType string, data interface{})
@@ -14,7 +16,8 @@ type Broadcaster interface {
 // NopBroadcaster 空实现，用于测试或不需要广播的场景
 type NopBroadcaster struct{}
 
-func (n *NopBroadcaster) BroadcastProxyRequest(req *domain.ProxyRequest)                {}
+func (n *NopBroadcaster) BroadcastProxyRequest(req *domain.ProxyRequest)                     {}
+func (n *NopBroadcaster) BroadcastProxyRequestSummary(summary *domain.ProxyRequestSummary)   {}
 func (n *NopBroadcaster) BroadcastProxyUpstreamAttempt(attempt *domain.ProxyUpstreamAttempt) {}
-func (n *NopBroadcaster) BroadcastLog(message string)                                   {}
-func (n *NopBroadcaster) BroadcastMessage(messageType string, data interface{})         {}
+func (n *NopBroadcaster) BroadcastLog(message string)                                        {}
+func (n *NopBroadcaster) BroadcastMessage(messageType string, data interface{})              {}
@@ -55,6 +55,14 @@ func (w *WailsBroadcaster) BroadcastProxyRequest(req *domain.ProxyRequest) {
 	w.emitWailsEvent("proxy_request_update", req)
 }
 
+// BroadcastProxyRequestSummary broadcasts a compact proxy request update
+func (w *WailsBroadcaster) BroadcastProxyRequestSummary(summary *domain.ProxyRequestSummary) {
+	if w.inner != nil {
+		w.inner.BroadcastProxyRequestSummary(summary)
+	}
+	w.emitWailsEvent("proxy_request_summary", summary)
+}
+
 // BroadcastProxyUpstreamAttempt broadcasts a proxy upstream attempt update
 func (w *WailsBroadcaster) BroadcastProxyUpstreamAttempt(attempt *domain.ProxyUpstreamAttempt) {
 	if w.inner != nil {
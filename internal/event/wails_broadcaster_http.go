@@ -38,6 +38,13 @@ func (w *WailsBroadcaster) BroadcastProxyRequest(req *domain.ProxyRequest) {
 	}
 }
 
+// BroadcastProxyRequestSummary broadcasts a compact proxy request update
+func (w *WailsBroadcaster) BroadcastProxyRequestSummary(summary *domain.ProxyRequestSummary) {
+	if w.inner != nil {
+		w.inner.BroadcastProxyRequestSummary(summary)
+	}
+}
+
 // BroadcastProxyUpstreamAttempt broadcasts a proxy upstream attempt update
 func (w *WailsBroadcaster) BroadcastProxyUpstreamAttempt(attempt *domain.ProxyUpstreamAttempt) {
 	if w.inner != nil {
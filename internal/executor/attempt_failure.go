@@ -0,0 +1,37 @@
+package executor
+
+import (
+	"errors"
+	"unicode/utf8"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// maxAttemptErrorBytes caps the error message stored on a failed attempt
+const maxAttemptErrorBytes = 2048
+
+// attemptFailure returns the error message and upstream status code recorded
+// on a failed attempt; the status is 0 when no upstream response was received
+func attemptFailure(err error, resp *domain.ResponseInfo) (string, int) {
+	if err == nil {
+		return "", 0
+	}
+	msg := err.Error()
+	if len(msg) > maxAttemptErrorBytes {
+		// 不截断在多字节字符中间
+		cut := maxAttemptErrorBytes
+		for cut > 0 && !utf8.RuneStart(msg[cut]) {
+			cut--
+		}
+		msg = msg[:cut]
+	}
+
+	status := 0
+	var proxyErr *domain.ProxyError
+	if errors.As(err, &proxyErr) && proxyErr.HTTPStatusCode > 0 {
+		status = proxyErr.HTTPStatusCode
+	} else if resp != nil {
+		status = resp.Status
+	}
+	return msg, status
+}
@@ -0,0 +1,32 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestGetBillingMultiplier(t *testing.T) {
+	provider := &domain.Provider{Config: &domain.ProviderConfig{Custom: &domain.ProviderConfigCustom{
+		ClientMultiplier: map[domain.ClientType]uint64{domain.ClientTypeClaude: 15000},
+	}}}
+
+	tests := []struct {
+		name       string
+		route      *domain.Route
+		provider   *domain.Provider
+		clientType domain.ClientType
+		want       uint64
+	}{
+		{"route override wins", &domain.Route{Multiplier: 8000}, provider, domain.ClientTypeClaude, 8000},
+		{"provider multiplier", &domain.Route{}, provider, domain.ClientTypeClaude, 15000},
+		{"default for other client types", &domain.Route{}, provider, domain.ClientTypeOpenAI, 10000},
+		{"route override without provider config", &domain.Route{Multiplier: 20000}, &domain.Provider{}, domain.ClientTypeOpenAI, 20000},
+		{"no route", nil, nil, domain.ClientTypeClaude, 10000},
+	}
+	for _, tt := range tests {
+		if got := getBillingMultiplier(tt.route, tt.provider, tt.clientType); got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package executor
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/event"
+)
+
+// requestWatchDuration 管理端按需订阅单个请求完整广播的有效期
+const requestWatchDuration = 5 * time.Minute
+
+// broadcastSampler 在高 QPS 下抽样请求/attempt 的中间状态广播
+// 每秒只有前 attempt_broadcast_max_qps 个新请求会推送中间状态（PENDING/IN_PROGRESS、attempt 变化），
+// 其余请求只推送终态（COMPLETED/FAILED/CANCELLED/REJECTED）。被管理端订阅的请求始终完整推送。
+// 数据库记录不受影响，这里只减少推送给 WebSocket 的消息数量。
+// 请求更新默认以精简视图（ProxyRequestSummary）推送，只有被订阅的请求推送完整记录。
+type broadcastSampler struct {
+	event.Broadcaster
+	settingsRepo settingGetter
+
+	mu          sync.Mutex
+	windowStart time.Time
+	windowCount int
+	maxQPS      int
+	loadedAt    time.Time
+
+	// 已决定的请求：true 表示完整推送，false 表示只推送终态
+	decided map[uint64]bool
+	// 管理端订阅的请求 -> 订阅到期时间
+	watched map[uint64]time.Time
+}
+
+// settingGetter is the subset of the settings repository the sampler reads
+type settingGetter interface {
+	Get(key string) (string, error)
+}
+
+func newBroadcastSampler(inner event.Broadcaster, settingsRepo settingGetter) *broadcastSampler {
+	return &broadcastSampler{
+		Broadcaster:  inner,
+		settingsRepo: settingsRepo,
+		decided:      make(map[uint64]bool),
+		watched:      make(map[uint64]time.Time),
+	}
+}
+
+// BroadcastProxyRequest 终态总是推送，中间状态按抽样决定
+func (b *broadcastSampler) BroadcastProxyRequest(req *domain.ProxyRequest) {
+	if isTerminalRequestStatus(req.Status) {
+		b.mu.Lock()
+		delete(b.decided, req.ID)
+		b.mu.Unlock()
+		b.send(req)
+		return
+	}
+	if b.allow(req.ID, true) {
+		b.send(req)
+	}
+}
+
+// send 推送请求更新：被订阅的请求推送完整记录，其余只推送精简视图
+func (b *broadcastSampler) send(req *domain.ProxyRequest) {
+	if b.watching(req.ID) {
+		b.Broadcaster.BroadcastProxyRequest(req)
+		return
+	}
+	b.Broadcaster.BroadcastProxyRequestSummary(req.Summary())
+}
+
+// watching reports whether the admin UI currently watches the request
+func (b *broadcastSampler) watching(proxyRequestID uint64) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	until, ok := b.watched[proxyRequestID]
+	return ok && time.Now().Before(until)
+}
+
+// BroadcastProxyUpstreamAttempt attempt 变化都属于中间状态，跟随所属请求的抽样结果
+func (b *broadcastSampler) BroadcastProxyUpstreamAttempt(attempt *domain.ProxyUpstreamAttempt) {
+	if b.allow(attempt.ProxyRequestID, false) {
+		b.Broadcaster.BroadcastProxyUpstreamAttempt(attempt)
+	}
+}
+
+// allow reports whether an intermediate update of the request should be
+// broadcast. Only request updates admit new requests into the current window.
+func (b *broadcastSampler) allow(proxyRequestID uint64, admit bool) bool {
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if now.Sub(b.loadedAt) >= time.Second {
+		b.maxQPS = b.loadMaxQPS()
+		b.loadedAt = now
+	}
+	if b.maxQPS <= 0 {
+		return true
+	}
+	if until, ok := b.watched[proxyRequestID]; ok {
+		if now.Before(until) {
+			return true
+		}
+		delete(b.watched, proxyRequestID)
+	}
+	if full, ok := b.decided[proxyRequestID]; ok {
+		return full
+	}
+	if !admit || proxyRequestID == 0 {
+		return false
+	}
+
+	if now.Sub(b.windowStart) >= time.Second {
+		b.windowStart = now
+		b.windowCount = 0
+	}
+	b.windowCount++
+	full := b.windowCount <= b.maxQPS
+	b.decided[proxyRequestID] = full
+	return full
+}
+
+// watch 订阅单个请求的完整广播，返回订阅到期时间
+func (b *broadcastSampler) watch(proxyRequestID uint64) time.Time {
+	now := time.Now()
+	until := now.Add(requestWatchDuration)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for id, t := range b.watched {
+		if now.After(t) {
+			delete(b.watched, id)
+		}
+	}
+	b.watched[proxyRequestID] = until
+	return until
+}
+
+// loadMaxQPS 读取 attempt_broadcast_max_qps，未配置或无效时不限制
+func (b *broadcastSampler) loadMaxQPS() int {
+	if b.settingsRepo == nil {
+		return 0
+	}
+	val, err := b.settingsRepo.Get(domain.SettingKeyAttemptBroadcastMaxQPS)
+	if err != nil || val == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+func isTerminalRequestStatus(status string) bool {
+	switch status {
+	case "COMPLETED", "FAILED", "CANCELLED", "REJECTED":
+		return true
+	}
+	return false
+}
+
+// WatchRequest makes every update of the proxy request broadcast in full (not as
+// a summary) until the returned time, even when global broadcasting is sampled
+func (e *Executor) WatchRequest(proxyRequestID uint64) time.Time {
+	if e.broadcastSampler == nil {
+		return time.Now().Add(requestWatchDuration)
+	}
+	return e.broadcastSampler.watch(proxyRequestID)
+}
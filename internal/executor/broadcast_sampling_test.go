@@ -0,0 +1,83 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/event"
+)
+
+type fixedSetting string
+
+func (s fixedSetting) Get(key string) (string, error) { return string(s), nil }
+
+type countingBroadcaster struct {
+	event.NopBroadcaster
+	requests map[uint64][]string
+	attempts map[uint64]int
+	full     map[uint64]int // 完整（非精简视图）推送次数
+}
+
+func (c *countingBroadcaster) BroadcastProxyRequest(req *domain.ProxyRequest) {
+	c.requests[req.ID] = append(c.requests[req.ID], req.Status)
+	if c.full != nil {
+		c.full[req.ID]++
+	}
+}
+
+func (c *countingBroadcaster) BroadcastProxyRequestSummary(summary *domain.ProxyRequestSummary) {
+	c.requests[summary.ID] = append(c.requests[summary.ID], summary.Status)
+}
+
+func (c *countingBroadcaster) BroadcastProxyUpstreamAttempt(a *domain.ProxyUpstreamAttempt) {
+	c.attempts[a.ProxyRequestID]++
+}
+
+func TestBroadcastSamplerKeepsTerminalStates(t *testing.T) {
+	inner := &countingBroadcaster{requests: map[uint64][]string{}, attempts: map[uint64]int{}, full: map[uint64]int{}}
+	b := newBroadcastSampler(inner, fixedSetting("2"))
+	b.watch(4)
+
+	for id := uint64(1); id <= 4; id++ {
+		b.BroadcastProxyRequest(&domain.ProxyRequest{ID: id, Status: "PENDING"})
+		b.BroadcastProxyRequest(&domain.ProxyRequest{ID: id, Status: "IN_PROGRESS"})
+		b.BroadcastProxyUpstreamAttempt(&domain.ProxyUpstreamAttempt{ProxyRequestID: id, Status: "IN_PROGRESS"})
+		b.BroadcastProxyRequest(&domain.ProxyRequest{ID: id, Status: "COMPLETED"})
+	}
+
+	// 前 2 个请求在预算内完整推送，第 3 个只推送终态，第 4 个被订阅后完整推送
+	want := map[uint64]struct {
+		requests int
+		attempts int
+	}{1: {3, 1}, 2: {3, 1}, 3: {1, 0}, 4: {3, 1}}
+	for id, w := range want {
+		if got := len(inner.requests[id]); got != w.requests {
+			t.Errorf("request %d: %d request broadcasts (%v), want %d", id, got, inner.requests[id], w.requests)
+		}
+		if got := inner.attempts[id]; got != w.attempts {
+			t.Errorf("request %d: %d attempt broadcasts, want %d", id, got, w.attempts)
+		}
+	}
+	if last := inner.requests[3]; len(last) != 1 || last[0] != "COMPLETED" {
+		t.Errorf("sampled request broadcasts = %v, want only the terminal state", last)
+	}
+	// 只有被订阅的请求推送完整记录，其余为精简视图
+	if len(inner.full) != 1 || inner.full[4] != 3 {
+		t.Errorf("full broadcasts = %v, want only the 3 updates of watched request 4", inner.full)
+	}
+	if len(b.decided) != 0 {
+		t.Errorf("decided = %v, want cleared after terminal states", b.decided)
+	}
+}
+
+func TestBroadcastSamplerUnlimited(t *testing.T) {
+	inner := &countingBroadcaster{requests: map[uint64][]string{}, attempts: map[uint64]int{}}
+	b := newBroadcastSampler(inner, fixedSetting("0"))
+	for id := uint64(1); id <= 10; id++ {
+		b.BroadcastProxyRequest(&domain.ProxyRequest{ID: id, Status: "IN_PROGRESS"})
+		b.BroadcastProxyUpstreamAttempt(&domain.ProxyUpstreamAttempt{ProxyRequestID: id})
+	}
+	if len(inner.requests) != 10 || len(inner.attempts) != 10 {
+		t.Errorf("got %d requests and %d attempts broadcast, want 10 each", len(inner.requests), len(inner.attempts))
+	}
+}
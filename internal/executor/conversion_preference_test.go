@@ -0,0 +1,42 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestGetProviderTargetType(t *testing.T) {
+	supported := []domain.ClientType{domain.ClientTypeGemini, domain.ClientTypeClaude}
+	withPreference := func(pref map[domain.ClientType][]domain.ClientType) *domain.Provider {
+		return &domain.Provider{Config: &domain.ProviderConfig{ConversionPreference: pref}}
+	}
+
+	tests := []struct {
+		name     string
+		provider *domain.Provider
+		original domain.ClientType
+		want     domain.ClientType
+	}{
+		{"default prefers claude", &domain.Provider{}, domain.ClientTypeOpenAI, domain.ClientTypeClaude},
+		{"preference wins", withPreference(map[domain.ClientType][]domain.ClientType{
+			domain.ClientTypeOpenAI: {domain.ClientTypeGemini, domain.ClientTypeClaude},
+		}), domain.ClientTypeOpenAI, domain.ClientTypeGemini},
+		{"unsupported preference skipped", withPreference(map[domain.ClientType][]domain.ClientType{
+			domain.ClientTypeOpenAI: {domain.ClientTypeCodex, domain.ClientTypeGemini},
+		}), domain.ClientTypeOpenAI, domain.ClientTypeGemini},
+		{"no preference for client type", withPreference(map[domain.ClientType][]domain.ClientType{
+			domain.ClientTypeCodex: {domain.ClientTypeGemini},
+		}), domain.ClientTypeOpenAI, domain.ClientTypeClaude},
+		{"supported type needs no conversion", withPreference(map[domain.ClientType][]domain.ClientType{
+			domain.ClientTypeClaude: {domain.ClientTypeGemini},
+		}), domain.ClientTypeClaude, domain.ClientTypeClaude},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := GetProviderTargetType(tt.provider, supported, tt.original); got != tt.want {
+				t.Errorf("GetProviderTargetType() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -104,6 +104,16 @@ func (c *ConvertingResponseWriter) Write(b []byte) (int, error) {
 
 // writeStream handles streaming response conversion
 func (c *ConvertingResponseWriter) writeStream(b []byte) (int, error) {
+	// SSE comments (heartbeats) have no format; the converter would drop them,
+	// so forward them as a standalone comment block to keep the client connection alive
+	if converter.IsSSECommentOnly(string(b)) {
+		comment := strings.TrimRight(string(b), "\r\n") + "\n\n"
+		if _, err := c.underlying.Write([]byte(comment)); err != nil {
+			return 0, err
+		}
+		return len(b), nil
+	}
+
 	// Convert the chunk
 	converted, err := c.converter.TransformStreamChunk(c.targetType, c.originalType, b, c.streamState)
 	if err != nil {
@@ -208,6 +218,26 @@ func GetPreferredTargetType(supportedTypes []domain.ClientType, originalType dom
 	return originalType
 }
 
+// GetProviderTargetType is GetPreferredTargetType with the provider's
+// ConversionPreference for originalType consulted first
+func GetProviderTargetType(provider *domain.Provider, supportedTypes []domain.ClientType, originalType domain.ClientType) domain.ClientType {
+	for _, t := range supportedTypes {
+		if t == originalType {
+			return originalType
+		}
+	}
+	if provider != nil && provider.Config != nil {
+		for _, preferred := range provider.Config.ConversionPreference[originalType] {
+			for _, t := range supportedTypes {
+				if t == preferred {
+					return t
+				}
+			}
+		}
+	}
+	return GetPreferredTargetType(supportedTypes, originalType)
+}
+
 // IsSSELine checks if a line is an SSE data line
 func IsSSELine(line string) bool {
 	return strings.HasPrefix(line, "data: ")
@@ -0,0 +1,207 @@
+package executor
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/converter"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files in testdata")
+
+var createdPattern = regexp.MustCompile(`"created":\d+`)
+
+// streamThroughWriter feeds an upstream SSE stream line by line, the way the
+// custom adapter forwards it, and returns the converted client stream
+func streamThroughWriter(t *testing.T, upstream string, clientType, providerType domain.ClientType) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	w := NewConvertingResponseWriter(rec, converter.GetGlobalRegistry(), clientType, providerType, true)
+	w.WriteHeader(200)
+
+	scanner := bufio.NewScanner(strings.NewReader(upstream))
+	for scanner.Scan() {
+		if _, err := w.Write([]byte(scanner.Text() + "\n")); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}
+	return createdPattern.ReplaceAllString(rec.Body.String(), `"created":0`)
+}
+
+func checkGolden(t *testing.T, name, got string) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatalf("update golden: %v", err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	if got != string(want) {
+		t.Errorf("output differs from %s (run with -update to regenerate)\ngot:\n%s", path, got)
+	}
+}
+
+// sseData returns the JSON payloads of the data lines in an SSE stream
+func sseData(stream string) []map[string]interface{} {
+	var events []map[string]interface{}
+	for _, line := range strings.Split(stream, "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var event map[string]interface{}
+		if json.Unmarshal([]byte(data), &event) == nil {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func TestConvertingWriterStreamsToolCallsOpenAIToClaude(t *testing.T) {
+	upstream, err := os.ReadFile(filepath.Join("testdata", "tool_calls_openai.sse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := streamThroughWriter(t, string(upstream), domain.ClientTypeClaude, domain.ClientTypeOpenAI)
+	checkGolden(t, "tool_calls_openai_to_claude.golden", got)
+
+	// 按内容块重组参数，每个 tool_use 块必须完整且各自闭合
+	blocks := map[int]map[string]interface{}{}
+	args := map[int]string{}
+	open := map[int]bool{}
+	for _, event := range sseData(got) {
+		index := int(toFloat(event["index"]))
+		switch event["type"] {
+		case "content_block_start":
+			if open[index] {
+				t.Errorf("block %d started twice", index)
+			}
+			open[index] = true
+			blocks[index] = event["content_block"].(map[string]interface{})
+		case "content_block_delta":
+			delta := event["delta"].(map[string]interface{})
+			if delta["type"] == "input_json_delta" {
+				args[index] += delta["partial_json"].(string)
+			}
+		case "content_block_stop":
+			if !open[index] {
+				t.Errorf("block %d stopped without being open", index)
+			}
+			open[index] = false
+		}
+	}
+	for index, stillOpen := range open {
+		if stillOpen {
+			t.Errorf("block %d never closed", index)
+		}
+	}
+	want := map[string]string{
+		"get_weather": `{"city":"Paris","unit":"celsius"}`,
+		"get_time":    `{"timezone":"Europe/Paris"}`,
+	}
+	for index, block := range blocks {
+		if block["type"] != "tool_use" {
+			continue
+		}
+		name := block["name"].(string)
+		if args[index] != want[name] {
+			t.Errorf("%s arguments = %q, want %q", name, args[index], want[name])
+		}
+		delete(want, name)
+	}
+	if len(want) != 0 {
+		t.Errorf("missing tool_use blocks: %v", want)
+	}
+}
+
+func TestConvertingWriterStreamsToolCallsClaudeToOpenAI(t *testing.T) {
+	upstream, err := os.ReadFile(filepath.Join("testdata", "tool_calls_claude.sse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := streamThroughWriter(t, string(upstream), domain.ClientTypeOpenAI, domain.ClientTypeClaude)
+	checkGolden(t, "tool_calls_claude_to_openai.golden", got)
+
+	// 按 OpenAI tool_calls index 重组，模拟客户端的累积方式
+	type call struct{ id, name, args string }
+	calls := map[int]*call{}
+	for _, event := range sseData(got) {
+		for _, choice := range event["choices"].([]interface{}) {
+			delta, _ := choice.(map[string]interface{})["delta"].(map[string]interface{})
+			toolCalls, _ := delta["tool_calls"].([]interface{})
+			for _, raw := range toolCalls {
+				tc := raw.(map[string]interface{})
+				index, ok := tc["index"]
+				if !ok {
+					t.Fatalf("tool call delta without index: %v", tc)
+				}
+				c := calls[int(toFloat(index))]
+				if c == nil {
+					c = &call{}
+					calls[int(toFloat(index))] = c
+				}
+				if id, _ := tc["id"].(string); id != "" {
+					c.id = id
+				}
+				fn := tc["function"].(map[string]interface{})
+				c.name += fn["name"].(string)
+				c.args += fn["arguments"].(string)
+			}
+		}
+	}
+	want := []call{
+		{"toolu_01", "get_weather", `{"city":"Paris","unit":"celsius"}`},
+		{"toolu_02", "get_time", `{"timezone":"Europe/Paris"}`},
+	}
+	if len(calls) != len(want) {
+		t.Fatalf("got %d tool calls, want %d", len(calls), len(want))
+	}
+	for i, w := range want {
+		if c := calls[i]; c == nil || *c != w {
+			t.Errorf("tool call %d = %+v, want %+v", i, c, w)
+		}
+	}
+}
+
+func toFloat(v interface{}) float64 {
+	f, _ := v.(float64)
+	return f
+}
+
+func TestConvertingWriterForwardsSSEComments(t *testing.T) {
+	upstream := ": ping\n\n" +
+		"data: {\"id\":\"c1\",\"object\":\"chat.completion.chunk\",\"model\":\"gpt-x\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"hi\"}}]}\n\n" +
+		": keep-alive\n\n"
+	got := streamThroughWriter(t, upstream, domain.ClientTypeClaude, domain.ClientTypeOpenAI)
+
+	// 心跳原样转发为独立的注释块，格式转换不受影响
+	if !strings.HasPrefix(got, ": ping\n\n") || !strings.HasSuffix(got, ": keep-alive\n\n") {
+		t.Errorf("heartbeats not forwarded: %q", got)
+	}
+	if !strings.Contains(got, "event: message_start") {
+		t.Errorf("content not converted: %q", got)
+	}
+
+	rc := NewResponseCapture(httptest.NewRecorder())
+	rc.Write([]byte(": ping\n"))
+	rc.Write([]byte("\n"))
+	if rc.HasContent() {
+		t.Error("heartbeat counted as response content")
+	}
+	rc.Write([]byte("data: {}\n"))
+	if !rc.HasContent() {
+		t.Error("data line not counted as response content")
+	}
+}
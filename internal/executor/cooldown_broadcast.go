@@ -0,0 +1,96 @@
+package executor
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// defaultCooldownBroadcastInterval 默认每个 Provider 的 cooldown_update 广播最小间隔
+const defaultCooldownBroadcastInterval = 3 * time.Second
+
+// cooldownBroadcastThrottle 按 Provider 节流/合并 cooldown_update 广播
+// 冷却状态本身仍然立即持久化，这里只减少故障期间推送给前端的消息数量。
+// 间隔内的多次广播会被合并为间隔结束时的一次（trailing），保证前端最终拿到最新状态。
+type cooldownBroadcastThrottle struct {
+	mu      sync.Mutex
+	last    map[uint64]time.Time
+	pending map[uint64]bool
+
+	now       func() time.Time
+	afterFunc func(d time.Duration, f func()) // 延迟执行合并广播，测试中可替换
+}
+
+func newCooldownBroadcastThrottle() *cooldownBroadcastThrottle {
+	return &cooldownBroadcastThrottle{
+		last:    make(map[uint64]time.Time),
+		pending: make(map[uint64]bool),
+		now:     time.Now,
+		afterFunc: func(d time.Duration, f func()) {
+			time.AfterFunc(d, f)
+		},
+	}
+}
+
+// trigger 立即执行 send，或在间隔结束后合并执行一次
+func (t *cooldownBroadcastThrottle) trigger(providerID uint64, interval time.Duration, send func()) {
+	if interval <= 0 {
+		send()
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// 已有待发送的合并广播，直接合并
+	if t.pending[providerID] {
+		return
+	}
+
+	now := t.now()
+	elapsed := now.Sub(t.last[providerID])
+	if elapsed >= interval {
+		t.last[providerID] = now
+		go send()
+		return
+	}
+
+	t.pending[providerID] = true
+	t.afterFunc(interval-elapsed, func() {
+		t.mu.Lock()
+		delete(t.pending, providerID)
+		t.last[providerID] = t.now()
+		t.mu.Unlock()
+		send()
+	})
+}
+
+// broadcastCooldownUpdate 广播 cooldown_update 事件（按 Provider 节流）
+func (e *Executor) broadcastCooldownUpdate(providerID uint64) {
+	if e.broadcaster == nil {
+		return
+	}
+	e.cooldownThrottle.trigger(providerID, e.getCooldownBroadcastInterval(), func() {
+		e.broadcaster.BroadcastMessage("cooldown_update", map[string]interface{}{
+			"providerID": providerID,
+		})
+	})
+}
+
+// getCooldownBroadcastInterval 获取 cooldown_update 广播节流间隔
+func (e *Executor) getCooldownBroadcastInterval() time.Duration {
+	if e.settingsRepo == nil {
+		return defaultCooldownBroadcastInterval
+	}
+	val, err := e.settingsRepo.Get(domain.SettingKeyCooldownBroadcastIntervalMs)
+	if err != nil || val == "" {
+		return defaultCooldownBroadcastInterval
+	}
+	ms, err := strconv.Atoi(val)
+	if err != nil || ms < 0 {
+		return defaultCooldownBroadcastInterval
+	}
+	return time.Duration(ms) * time.Millisecond
+}
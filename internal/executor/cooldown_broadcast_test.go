@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeThrottleClock drives a cooldownBroadcastThrottle without real timers
+type fakeThrottleClock struct {
+	now    time.Time
+	delays []time.Duration
+	timers []func()
+}
+
+func newFakeThrottle() (*cooldownBroadcastThrottle, *fakeThrottleClock) {
+	clock := &fakeThrottleClock{now: time.Unix(1700000000, 0)}
+	t := newCooldownBroadcastThrottle()
+	t.now = func() time.Time { return clock.now }
+	t.afterFunc = func(d time.Duration, f func()) {
+		clock.delays = append(clock.delays, d)
+		clock.timers = append(clock.timers, f)
+	}
+	return t, clock
+}
+
+// fire runs the pending timers, as if their delays had elapsed
+func (c *fakeThrottleClock) fire() {
+	timers := c.timers
+	c.timers = nil
+	for _, f := range timers {
+		f()
+	}
+}
+
+func expectSends(t *testing.T, sent <-chan uint64, want ...uint64) {
+	t.Helper()
+	for _, id := range want {
+		select {
+		case got := <-sent:
+			if got != id {
+				t.Fatalf("sent provider %d, want %d", got, id)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("no broadcast for provider %d", id)
+		}
+	}
+	select {
+	case got := <-sent:
+		t.Fatalf("unexpected broadcast for provider %d", got)
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestCooldownBroadcastThrottleCoalesces(t *testing.T) {
+	throttle, clock := newFakeThrottle()
+	sent := make(chan uint64, 16)
+	trigger := func(id uint64) {
+		throttle.trigger(id, 3*time.Second, func() { sent <- id })
+	}
+
+	// 第一次立即发送
+	trigger(1)
+	expectSends(t, sent, 1)
+
+	// 间隔内的多次触发合并为一个 trailing 广播，在间隔结束时发送
+	clock.now = clock.now.Add(time.Second)
+	trigger(1)
+	clock.now = clock.now.Add(500 * time.Millisecond)
+	trigger(1)
+	trigger(1)
+	expectSends(t, sent)
+	if len(clock.delays) != 1 || clock.delays[0] != 2*time.Second {
+		t.Fatalf("timers = %v, want one trailing timer for the rest of the window", clock.delays)
+	}
+
+	// 其他 Provider 各自节流
+	trigger(2)
+	expectSends(t, sent, 2)
+
+	clock.now = clock.now.Add(1500 * time.Millisecond)
+	clock.fire()
+	expectSends(t, sent, 1)
+
+	// trailing 广播开启新的间隔
+	clock.now = clock.now.Add(time.Second)
+	trigger(1)
+	expectSends(t, sent)
+	if len(clock.timers) != 1 || clock.delays[1] != 2*time.Second {
+		t.Fatalf("timers = %v, want a trailing timer measured from the trailing send", clock.delays)
+	}
+	clock.now = clock.now.Add(2 * time.Second)
+	clock.fire()
+	expectSends(t, sent, 1)
+
+	// 间隔过后再次立即发送
+	clock.now = clock.now.Add(3 * time.Second)
+	trigger(1)
+	expectSends(t, sent, 1)
+}
+
+func TestCooldownBroadcastThrottleDisabled(t *testing.T) {
+	throttle, clock := newFakeThrottle()
+	sends := 0
+	for i := 0; i < 3; i++ {
+		throttle.trigger(1, 0, func() { sends++ })
+	}
+	if sends != 3 || len(clock.timers) != 0 {
+		t.Errorf("sends = %d, timers = %d, want every broadcast sent synchronously", sends, len(clock.timers))
+	}
+}
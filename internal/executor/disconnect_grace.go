@@ -0,0 +1,234 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	ctxutil "github.com/awsl-project/maxx/internal/context"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// graceResultTTL 宽限期内完成的结果保留多久供客户端重试取回
+const graceResultTTL = 5 * time.Minute
+
+// Disconnect grace keeps a non-streaming request running for a short while after
+// its client disconnects, so a client that briefly drops and retries gets the
+// result instead of paying for a second upstream call.
+//
+// Interaction with idempotency and when it is safe:
+//   - Only non-streaming requests carrying an Idempotency-Key header take part.
+//     The header is the client's promise that a retry with the same key is the
+//     same logical request; without it a disconnect is treated as before.
+//   - The result is keyed by the key together with API token, project, client
+//     type, URI and request body, so it is only ever served to the same caller
+//     sending the same request. A mismatching body is a different request.
+//   - Only abandoned requests are cached. While the upstream call is still
+//     running, a retry with the same key waits for it instead of starting a
+//     second call; once it completed, retries within graceResultTTL get the
+//     stored response. Failed or cancelled results are never cached, a retry
+//     then runs normally.
+//   - If the upstream call is not done within client_disconnect_grace_seconds
+//     it is cancelled and recorded as CANCELLED, exactly like an immediate
+//     disconnect. Requests completed within the grace period are recorded
+//     COMPLETED and count toward usage and cooldown as usual.
+//   - Streaming requests are excluded: their output is consumed incrementally
+//     and replaying it from the start is not something clients expect.
+type disconnectGrace struct {
+	mu      sync.Mutex
+	results map[string]*graceResult
+}
+
+func newDisconnectGrace() *disconnectGrace {
+	return &disconnectGrace{results: make(map[string]*graceResult)}
+}
+
+// graceResult is the outcome of an abandoned request; done is closed once it is filled
+type graceResult struct {
+	done    chan struct{}
+	status  int
+	header  http.Header
+	body    []byte
+	err     error
+	expires time.Time // 零值表示仍在进行
+}
+
+// executeWithDisconnectGrace runs Execute detached from the client when the
+// request qualifies for a disconnect grace period, see disconnectGrace
+func (e *Executor) executeWithDisconnectGrace(ctx context.Context, w http.ResponseWriter, req *http.Request) error {
+	grace := e.getDisconnectGrace()
+	idempotencyKey := ctxutil.GetRequestHeaders(ctx).Get("Idempotency-Key")
+	if grace <= 0 || idempotencyKey == "" {
+		return e.Execute(ctx, w, req)
+	}
+
+	key := disconnectGraceKey(ctx, idempotencyKey)
+	if r := e.disconnectGrace.lookup(key); r != nil {
+		select {
+		case <-r.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if r.err == nil {
+			log.Printf("[Executor] Serving result of disconnected request for idempotency key %q", idempotencyKey)
+			r.writeTo(w)
+			return nil
+		}
+		// 原请求失败或被取消，按新请求执行
+	}
+
+	execCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	rec := &graceRecorder{header: make(http.Header)}
+	var execErr error
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		execErr = e.Execute(execCtx, rec, req)
+	}()
+
+	select {
+	case <-finished:
+		cancel()
+		rec.writeTo(w)
+		return execErr
+	case <-ctx.Done():
+	}
+
+	// 客户端已断开：宽限期内继续等待上游，完成的结果留给客户端重试
+	r := e.disconnectGrace.register(key)
+	timer := time.AfterFunc(grace, cancel)
+	go func() {
+		<-finished
+		timer.Stop()
+		cancel()
+		e.disconnectGrace.complete(key, r, rec, execErr)
+	}()
+	return ctx.Err()
+}
+
+// lookup returns the in-flight or unexpired result for key, or nil
+func (d *disconnectGrace) lookup(key string) *graceResult {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.purgeLocked(time.Now())
+	return d.results[key]
+}
+
+// register records an abandoned in-flight request under key
+func (d *disconnectGrace) register(key string) *graceResult {
+	r := &graceResult{done: make(chan struct{})}
+	d.mu.Lock()
+	d.results[key] = r
+	d.mu.Unlock()
+	return r
+}
+
+// complete fills r; failed results are dropped so a retry runs normally
+func (d *disconnectGrace) complete(key string, r *graceResult, rec *graceRecorder, err error) {
+	d.mu.Lock()
+	r.status, r.header, r.body, r.err = rec.status, rec.header, rec.body.Bytes(), err
+	if err != nil {
+		if d.results[key] == r {
+			delete(d.results, key)
+		}
+	} else {
+		r.expires = time.Now().Add(graceResultTTL)
+	}
+	d.mu.Unlock()
+	close(r.done)
+}
+
+func (d *disconnectGrace) purgeLocked(now time.Time) {
+	for key, r := range d.results {
+		if !r.expires.IsZero() && now.After(r.expires) {
+			delete(d.results, key)
+		}
+	}
+}
+
+func (r *graceResult) writeTo(w http.ResponseWriter) {
+	writeBufferedResponse(w, r.header, r.status, r.body)
+}
+
+// disconnectGraceKey hashes the idempotency key with everything identifying the caller and request
+func disconnectGraceKey(ctx context.Context, idempotencyKey string) string {
+	h := sha256.New()
+	write := func(s string) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	write(idempotencyKey)
+	write(string(ctxutil.GetClientType(ctx)))
+	write(strconv.FormatUint(ctxutil.GetProjectID(ctx), 10))
+	write(strconv.FormatUint(ctxutil.GetAPITokenID(ctx), 10))
+	write(ctxutil.GetRequestURI(ctx))
+	h.Write(ctxutil.GetRequestBody(ctx))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// graceRecorder buffers a non-streaming response until it is known whether the
+// client is still there to receive it
+type graceRecorder struct {
+	header http.Header
+	status int // 0 表示尚未写入状态码
+	body   bytes.Buffer
+}
+
+func (gr *graceRecorder) Header() http.Header {
+	return gr.header
+}
+
+func (gr *graceRecorder) WriteHeader(status int) {
+	if gr.status == 0 {
+		gr.status = status
+	}
+}
+
+func (gr *graceRecorder) Write(b []byte) (int, error) {
+	if gr.status == 0 {
+		gr.status = http.StatusOK
+	}
+	return gr.body.Write(b)
+}
+
+// Flush implements http.Flusher; the response is written out as a whole
+func (gr *graceRecorder) Flush() {}
+
+func (gr *graceRecorder) writeTo(w http.ResponseWriter) {
+	writeBufferedResponse(w, gr.header, gr.status, gr.body.Bytes())
+}
+
+// writeBufferedResponse copies a buffered response to w; nothing is written if
+// Execute did not produce a response (the caller writes the error instead)
+func writeBufferedResponse(w http.ResponseWriter, header http.Header, status int, body []byte) {
+	if status == 0 {
+		return
+	}
+	for k, v := range header {
+		w.Header()[k] = v
+	}
+	w.WriteHeader(status)
+	_, _ = w.Write(body)
+}
+
+// getDisconnectGrace 获取客户端断开后的宽限期，0 表示禁用
+func (e *Executor) getDisconnectGrace() time.Duration {
+	if e.settingsRepo == nil {
+		return 0
+	}
+	val, err := e.settingsRepo.Get(domain.SettingKeyDisconnectGraceSeconds)
+	if err != nil || val == "" {
+		return 0
+	}
+	seconds, err := strconv.Atoi(val)
+	if err != nil || seconds < 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,60 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDisconnectGraceServesCompletedResult(t *testing.T) {
+	d := newDisconnectGrace()
+	r := d.register("k")
+	if got := d.lookup("k"); got != r {
+		t.Fatalf("lookup of in-flight request = %v, want registered result", got)
+	}
+
+	rec := &graceRecorder{header: make(http.Header)}
+	rec.Header().Set("Content-Type", "application/json")
+	rec.WriteHeader(http.StatusOK)
+	_, _ = rec.Write([]byte(`{"ok":true}`))
+	d.complete("k", r, rec, nil)
+
+	got := d.lookup("k")
+	if got == nil {
+		t.Fatal("completed result not cached")
+	}
+	<-got.done
+	w := httptest.NewRecorder()
+	got.writeTo(w)
+	if w.Code != http.StatusOK || w.Body.String() != `{"ok":true}` || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("served %d %q %v, want the cached response", w.Code, w.Body.String(), w.Header())
+	}
+}
+
+func TestDisconnectGraceDropsFailedResult(t *testing.T) {
+	d := newDisconnectGrace()
+	r := d.register("k")
+	d.complete("k", r, &graceRecorder{header: make(http.Header)}, context.Canceled)
+
+	select {
+	case <-r.done:
+	default:
+		t.Fatal("waiters not released after completion")
+	}
+	if !errors.Is(r.err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled for waiting retries", r.err)
+	}
+	if d.lookup("k") != nil {
+		t.Error("failed result still cached, want a retry to run normally")
+	}
+}
+
+func TestGraceRecorderWritesNothingWithoutResponse(t *testing.T) {
+	w := httptest.NewRecorder()
+	(&graceRecorder{header: make(http.Header)}).writeTo(w)
+	if w.Body.Len() != 0 || len(w.Header()) != 0 {
+		t.Errorf("wrote %q %v, want nothing so the caller can write the error", w.Body.String(), w.Header())
+	}
+}
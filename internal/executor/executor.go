@@ -2,6 +2,8 @@ package executor
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
@@ -13,6 +15,7 @@ import (
 	"github.com/awsl-project/maxx/internal/domain"
 	"github.com/awsl-project/maxx/internal/event"
 	"github.com/awsl-project/maxx/internal/pricing"
+	"github.com/awsl-project/maxx/internal/ratelimit"
 	"github.com/awsl-project/maxx/internal/repository"
 	"github.com/awsl-project/maxx/internal/router"
 	"github.com/awsl-project/maxx/internal/stats"
@@ -34,6 +37,11 @@ type Executor struct {
 	instanceID         string
 	statsAggregator    *stats.StatsAggregator
 	converter          *converter.Registry
+	cooldownThrottle   *cooldownBroadcastThrottle
+	streamDedup        *streamDedup
+	disconnectGrace    *disconnectGrace
+	broadcastSampler   *broadcastSampler
+	costCalculator     pricing.CostCalculator
 }
 
 // NewExecutor creates a new executor
@@ -50,7 +58,7 @@ func NewExecutor(
 	instanceID string,
 	statsAggregator *stats.StatsAggregator,
 ) *Executor {
-	return &Executor{
+	e := &Executor{
 		router:             r,
 		proxyRequestRepo:   prr,
 		attemptRepo:        ar,
@@ -63,7 +71,44 @@ func NewExecutor(
 		instanceID:         instanceID,
 		statsAggregator:    statsAggregator,
 		converter:          converter.GetGlobalRegistry(),
+		cooldownThrottle:   newCooldownBroadcastThrottle(),
+		streamDedup:        newStreamDedup(),
+		disconnectGrace:    newDisconnectGrace(),
+		costCalculator:     pricing.GlobalCalculator(),
 	}
+	if bc != nil {
+		e.broadcastSampler = newBroadcastSampler(bc, settingsRepo)
+		e.broadcaster = e.broadcastSampler
+	}
+	return e
+}
+
+// SetCostCalculator replaces the calculator used to bill attempts
+// (default pricing.GlobalCalculator())
+func (e *Executor) SetCostCalculator(calculator pricing.CostCalculator) {
+	e.costCalculator = calculator
+}
+
+// admitRequest fails fast, before a request record or any attempt is created,
+// when every candidate provider is cooling down. The error carries a
+// Retry-After of the soonest cooldown expiry. Replays and requests still
+// waiting for a project binding are always admitted since their routes are
+// not known yet.
+func (e *Executor) admitRequest(ctx context.Context, clientType domain.ClientType, projectID uint64) error {
+	if ctxutil.GetRouteOverride(ctx) != 0 {
+		return nil
+	}
+	if projectID == 0 && e.projectWaiter != nil && e.projectWaiter.IsForceProjectEnabled() {
+		return nil
+	}
+	retryAfter, blocked := e.router.CooldownRetryAfter(clientType, projectID)
+	if !blocked {
+		return nil
+	}
+	log.Printf("[Executor] Rejecting %s request for project %d, all providers in cooldown (retry after %s)", clientType, projectID, retryAfter.Round(time.Second))
+	proxyErr := domain.NewProxyErrorWithMessage(domain.ErrNoAvailableProviders, true, "all providers are in cooldown")
+	proxyErr.RetryAfter = retryAfter
+	return proxyErr
 }
 
 // Execute handles the proxy request with routing and retry logic
@@ -73,48 +118,63 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 	sessionID := ctxutil.GetSessionID(ctx)
 	requestModel := ctxutil.GetRequestModel(ctx)
 	isStream := ctxutil.GetIsStream(ctx)
+	nonStreamOverride := ctxutil.GetNonStreamOverride(ctx)
 
 	// Get API Token ID from context
 	apiTokenID := ctxutil.GetAPITokenID(ctx)
+	comparisonTag := ctxutil.GetComparisonTag(ctx)
+
+	if err := e.admitRequest(ctx, clientType, projectID); err != nil {
+		return err
+	}
+
+	// Replays keep the original token for routing and model mapping, but their
+	// usage isn't attributed to it (token stats and quotas)
+	recordTokenID := apiTokenID
+	if comparisonTag != "" {
+		recordTokenID = 0
+	}
 
 	// Create proxy request record immediately (PENDING status)
 	proxyReq := &domain.ProxyRequest{
-		InstanceID:   e.instanceID,
-		RequestID:    generateRequestID(),
-		SessionID:    sessionID,
-		ClientType:   clientType,
-		ProjectID:    projectID,
-		RequestModel: requestModel,
-		StartTime:    time.Now(),
-		IsStream:     isStream,
-		Status:       "PENDING",
-		APITokenID:   apiTokenID,
+		InstanceID:    e.instanceID,
+		RequestID:     generateRequestID(),
+		SessionID:     sessionID,
+		ClientType:    clientType,
+		ProjectID:     projectID,
+		RequestModel:  requestModel,
+		StartTime:     time.Now(),
+		IsStream:      isStream,
+		Status:        "PENDING",
+		APITokenID:    recordTokenID,
+		ClientIP:      ctxutil.GetClientIP(ctx),
+		Billable:      e.resolveBillable(ctx, projectID),
+		ComparisonTag: comparisonTag,
+
+		NonStreamOverride: nonStreamOverride,
+	}
+
+	// A/B 模型实验：路由前替换请求模型，记录保留客户端原始模型
+	if exp, variant := e.assignModelExperiment(requestModel, apiTokenID, sessionID); variant != nil {
+		proxyReq.Experiment = exp.Name
+		proxyReq.ExperimentVariant = variant.Name
+		requestModel = variant.Model
+		ctx = ctxutil.WithRequestModel(ctx, requestModel)
 	}
 
 	// Capture client's original request info unless detail retention is disabled.
-	if !e.shouldClearRequestDetail() {
-		requestURI := ctxutil.GetRequestURI(ctx)
-		requestHeaders := ctxutil.GetRequestHeaders(ctx)
-		requestBody := ctxutil.GetRequestBody(ctx)
-		headers := flattenHeaders(requestHeaders)
-		// Go stores Host separately from headers, add it explicitly
-		if req.Host != "" {
-			if headers == nil {
-				headers = make(map[string]string)
-			}
-			headers["Host"] = req.Host
-		}
-		proxyReq.RequestInfo = &domain.RequestInfo{
-			Method:  req.Method,
-			URL:     requestURI,
-			Headers: headers,
-			Body:    string(requestBody),
-		}
+	// Routes with ForceDetail capture it later from clientCtx when they are tried.
+	clientCtx := ctx
+	if !e.shouldClearRequestDetail(nil) {
+		proxyReq.RequestInfo = clientRequestInfo(clientCtx, req)
 	}
 
 	if err := e.proxyRequestRepo.Create(proxyReq); err != nil {
 		log.Printf("[Executor] Failed to create proxy request: %v", err)
 	}
+	if record, ok := ctx.Value(replayRecordKey{}).(*replayRecord); ok {
+		record.proxyReq = proxyReq
+	}
 
 	// Broadcast the new request immediately
 	if e.broadcaster != nil {
@@ -123,8 +183,8 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 
 	ctx = ctxutil.WithProxyRequest(ctx, proxyReq)
 
-	// Check for project binding if required
-	if projectID == 0 && e.projectWaiter != nil {
+	// Check for project binding if required (replays never wait for binding)
+	if projectID == 0 && e.projectWaiter != nil && ctxutil.GetRouteOverride(ctx) == 0 {
 		// Get session for project waiter
 		session, _ := e.sessionRepo.GetBySessionID(sessionID)
 		if session == nil {
@@ -168,17 +228,15 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 		// Update projectID from the now-bound session
 		projectID = session.ProjectID
 		proxyReq.ProjectID = projectID
+		proxyReq.Billable = e.resolveBillable(ctx, projectID)
 		ctx = ctxutil.WithProjectID(ctx, projectID)
 	}
 
-	// Match routes
-	routes, err := e.router.Match(&router.MatchContext{
-		ClientType:   clientType,
-		ProjectID:    projectID,
-		RequestModel: requestModel,
-		APITokenID:   apiTokenID,
-	})
-	if err != nil {
+	trace := e.newRoutingTrace()
+	proxyReq.RoutingTrace = trace
+	candidates, err := e.matchCandidates(ctx, clientType, projectID, requestModel, apiTokenID, trace)
+
+	if err != nil && len(candidates) == 0 {
 		proxyReq.Status = "FAILED"
 		proxyReq.Error = "no routes available"
 		proxyReq.EndTime = time.Now()
@@ -190,7 +248,7 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 		return domain.NewProxyErrorWithMessage(domain.ErrNoRoutes, false, "no routes available")
 	}
 
-	if len(routes) == 0 {
+	if len(candidates) == 0 {
 		proxyReq.Status = "FAILED"
 		proxyReq.Error = "no routes configured"
 		proxyReq.EndTime = time.Now()
@@ -262,12 +320,50 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 
 	// Try routes in order with retry logic
 	var lastErr error
-	for _, matchedRoute := range routes {
+	var lastRoute *domain.Route
+	size := &inputSize{registry: e.converter, body: ctxutil.GetRequestBody(ctx), clientType: clientType}
+	routeLimit := newRouteAttemptLimit(e.getMaxRoutesAttempted(projectID))
+	routeLimitHit := false
+	streamTruncated := false
+	for i, candidate := range candidates {
+		matchedRoute := candidate.MatchedRoute
+		routeModel := candidate.model
+
 		// Check context before starting new route
 		if ctx.Err() != nil {
 			return ctx.Err()
 		}
 
+		// Hard cap on distinct routes tried, bounds latency on long failing route lists
+		if routeLimit.reached() {
+			log.Printf("[Executor] Tried %d routes (max_routes_attempted), giving up with %d candidates left",
+				routeLimit.max, len(candidates)-i)
+			trace.Add(routeTraceStep(domain.RoutingTraceSkipped, candidate, "max routes attempted reached"))
+			routeLimitHit = true
+			break
+		}
+
+		// Skip providers deleted after route matching. Adapters refreshed in the
+		// meantime are fine: the attempt keeps using the adapter captured at match
+		// time, which a refresh never modifies.
+		if !e.router.HasAdapter(matchedRoute.Provider.ID) {
+			log.Printf("[Executor] Provider %d was removed, skipping route %d", matchedRoute.Provider.ID, matchedRoute.Route.ID)
+			trace.Add(routeTraceStep(domain.RoutingTraceSkipped, candidate, "provider removed after matching"))
+			continue
+		}
+		adp := matchedRoute.ProviderAdapter
+
+		if routeModel != requestModel && (i == 0 || candidates[i-1].model != routeModel) {
+			log.Printf("[Executor] Model %s failed on all routes, falling back to %s", requestModel, routeModel)
+		}
+		ctx = ctxutil.WithRequestModel(ctx, routeModel)
+
+		// 强制保留详情的路由：全局不保存详情时补录客户端请求
+		lastRoute = matchedRoute.Route
+		if lastRoute.ForceDetail && proxyReq.RequestInfo == nil {
+			proxyReq.RequestInfo = clientRequestInfo(clientCtx, req)
+		}
+
 		// Update proxyReq with current route/provider for real-time tracking
 		proxyReq.RouteID = matchedRoute.Route.ID
 		proxyReq.ProviderID = matchedRoute.Provider.ID
@@ -279,7 +375,7 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 		// Determine model mapping
 		// Model mapping is done in Executor after Router has filtered by SupportModels
 		clientType := ctxutil.GetClientType(ctx)
-		mappedModel := e.mapModel(requestModel, matchedRoute.Route, matchedRoute.Provider, clientType, projectID, apiTokenID)
+		mappedModel := e.mapModel(routeModel, matchedRoute.Route, matchedRoute.Provider, clientType, projectID, apiTokenID)
 		ctx = ctxutil.WithMappedModel(ctx, mappedModel)
 
 		// Format conversion: check if client type is supported by provider
@@ -288,9 +384,9 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 		targetClientType := clientType
 		needsConversion := false
 
-		supportedTypes := matchedRoute.ProviderAdapter.SupportedClientTypes()
+		supportedTypes := adp.SupportedClientTypes()
 		if e.converter.NeedConvert(clientType, supportedTypes) {
-			targetClientType = GetPreferredTargetType(supportedTypes, clientType)
+			targetClientType = GetProviderTargetType(matchedRoute.Provider, supportedTypes, clientType)
 			if targetClientType != clientType {
 				needsConversion = true
 				log.Printf("[Executor] Format conversion needed: %s -> %s for provider %s",
@@ -320,28 +416,124 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 			}
 		}
 
+		// Stream mode mismatch: the provider only streams (or never streams),
+		// so the upstream request differs from what the client asked for.
+		// A forced non-stream request keeps streaming upstream as the client
+		// originally asked, and is accumulated into one response.
+		upstreamStream := resolveUpstreamStream(isStream || nonStreamOverride, matchedRoute.Provider)
+		upstreamClientType := ctxutil.GetClientType(ctx)
+		var upstreamBody []byte
+		var upstreamURI string
+		if upstreamStream != isStream {
+			upstreamBody, upstreamURI = applyUpstreamStream(
+				ctxutil.GetRequestBody(ctx), ctxutil.GetRequestURI(ctx), upstreamClientType, upstreamStream)
+			log.Printf("[Executor] Stream mode conversion: client stream=%v, upstream stream=%v for provider %s",
+				isStream, upstreamStream, matchedRoute.Provider.Name)
+		}
+
+		// Provider output token cap: clamp max_tokens if the client asked for more
+		var maxTokensClampedFrom uint64
+		if limit := providerMaxOutputTokens(matchedRoute.Provider); limit > 0 {
+			body := upstreamBody
+			if body == nil {
+				body = ctxutil.GetRequestBody(ctx)
+			}
+			if clamped, from := clampMaxOutputTokens(body, upstreamClientType, limit); from > 0 {
+				upstreamBody = clamped
+				maxTokensClampedFrom = from
+				log.Printf("[Executor] Clamped max output tokens %d -> %d for provider %s",
+					from, limit, matchedRoute.Provider.Name)
+			}
+		}
+
+		// Provider unsupported parameters: strip them so the request isn't rejected
+		var strippedParams []string
+		if params := providerUnsupportedParams(matchedRoute.Provider); len(params) > 0 {
+			body := upstreamBody
+			if body == nil {
+				body = ctxutil.GetRequestBody(ctx)
+			}
+			if sanitized, stripped := stripUnsupportedParams(body, upstreamClientType, params); len(stripped) > 0 {
+				upstreamBody = sanitized
+				strippedParams = stripped
+				log.Printf("[Executor] Stripped unsupported params %v for provider %s",
+					stripped, matchedRoute.Provider.Name)
+			}
+		}
+
+		// Provider body minification: drop insignificant JSON whitespace before forwarding
+		var minifiedBytesSaved uint64
+		if matchedRoute.Provider.Config != nil && matchedRoute.Provider.Config.MinifyRequestBody {
+			body := upstreamBody
+			if body == nil {
+				body = ctxutil.GetRequestBody(ctx)
+			}
+			if minified, ok := minifyRequestBody(body); ok {
+				upstreamBody = minified
+				minifiedBytesSaved = uint64(len(body) - len(minified))
+				log.Printf("[Executor] Minified request body %d -> %d bytes for provider %s",
+					len(body), len(minified), matchedRoute.Provider.Name)
+			}
+		}
+
+		// Provider input size limit: skip routes that would predictably reject the request
+		inputBody := upstreamBody
+		if inputBody == nil {
+			inputBody = ctxutil.GetRequestBody(ctx)
+		}
+		if reason := exceedsInputLimit(matchedRoute.Provider, inputBody, size); reason != "" {
+			log.Printf("[Executor] Provider %d can't take the request (%s), skipping route %d", matchedRoute.Provider.ID, reason, matchedRoute.Route.ID)
+			trace.Add(routeTraceStep(domain.RoutingTraceSkipped, candidate, reason))
+			if lastErr == nil {
+				lastErr = domain.NewProxyErrorWithMessage(domain.ErrRequestTooLarge, false, reason)
+			}
+			continue
+		}
+
 		// Get retry config
 		retryConfig := e.getRetryConfig(matchedRoute.RetryConfig)
+		if ctxutil.GetRouteProbe(ctx) && retryConfig.MaxRetries > 0 {
+			// 路由测试只尝试一次
+			noRetry := *retryConfig
+			noRetry.MaxRetries = 0
+			retryConfig = &noRetry
+		}
 
 		// Execute with retries
+		var routeErr error
 		for attempt := 0; attempt <= retryConfig.MaxRetries; attempt++ {
 			// Check context before each attempt
 			if ctx.Err() != nil {
 				return ctx.Err()
 			}
 
+			// Provider RPM/TPM caps: skip to the next route or queue, per setting
+			allowed, rlErr := e.acquireProviderRateLimit(ctx, matchedRoute.Provider)
+			if rlErr != nil {
+				return rlErr
+			}
+			if !allowed {
+				log.Printf("[Executor] Provider %d reached its rate limit, skipping route %d", matchedRoute.Provider.ID, matchedRoute.Route.ID)
+				trace.Add(routeTraceStep(domain.RoutingTraceSkipped, candidate, "provider rate limit reached"))
+				break // Move to next route
+			}
+
 			// Create attempt record with start time and request info
 			attemptStartTime := time.Now()
 			attemptRecord := &domain.ProxyUpstreamAttempt{
 				ProxyRequestID: proxyReq.ID,
 				RouteID:        matchedRoute.Route.ID,
 				ProviderID:     matchedRoute.Provider.ID,
-				IsStream:       isStream,
+				IsStream:       upstreamStream,
 				Status:         "IN_PROGRESS",
 				StartTime:      attemptStartTime,
-				RequestModel:   requestModel,
+				RequestModel:   routeModel,
 				MappedModel:    mappedModel,
 				RequestInfo:    proxyReq.RequestInfo, // Use original request info initially
+
+				MaxTokensClampedFrom: maxTokensClampedFrom,
+				StrippedParams:       strippedParams,
+				MinifiedBytesSaved:   minifiedBytesSaved,
 			}
 			if err := e.attemptRepo.Create(attemptRecord); err != nil {
 				log.Printf("[Executor] Failed to create attempt record: %v", err)
@@ -363,6 +555,13 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 
 			// Put attempt into context so adapter can populate request/response info
 			attemptCtx := ctxutil.WithUpstreamAttempt(ctx, attemptRecord)
+			if upstreamBody != nil {
+				attemptCtx = ctxutil.WithRequestBody(attemptCtx, upstreamBody)
+			}
+			if upstreamStream != isStream {
+				attemptCtx = ctxutil.WithRequestURI(attemptCtx, upstreamURI)
+				attemptCtx = ctxutil.WithIsStream(attemptCtx, upstreamStream)
+			}
 
 			// Create event channel for adapter to send events
 			eventChan := domain.NewAdapterEventChan()
@@ -371,25 +570,98 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 			// Start real-time event processing goroutine
 			// This ensures RequestInfo is broadcast as soon as adapter sends it
 			eventDone := make(chan struct{})
-			go e.processAdapterEventsRealtime(eventChan, attemptRecord, eventDone)
+			go e.processAdapterEventsRealtime(eventChan, attemptRecord, matchedRoute.Route, eventDone)
 
 			// Wrap ResponseWriter to capture actual client response
 			// If format conversion is needed, use ConvertingResponseWriter
 			var responseWriter http.ResponseWriter
 			var convertingWriter *ConvertingResponseWriter
-			responseCapture := NewResponseCapture(w)
+
+			// Optionally buffer streaming output so slow clients don't hold the upstream connection
+			clientWriter := w
+			var streamBuffer *StreamBuffer
+			if isStream {
+				if maxBytes := e.getStreamBufferMaxBytes(); maxBytes > 0 {
+					streamBuffer = NewStreamBuffer(w, maxBytes)
+					clientWriter = streamBuffer
+				}
+			}
+			responseCapture := NewResponseCapture(clientWriter)
+
+			// Provider text rewrite rules apply to the client-format stream, after conversion
+			var baseWriter http.ResponseWriter = responseCapture
+			var streamRewrite *streamRewriteWriter
+			if isStream {
+				if rules := getStreamTextRewrites(matchedRoute.Provider); len(rules) > 0 {
+					streamRewrite = newStreamRewriteWriter(responseCapture, originalClientType, rules)
+					baseWriter = streamRewrite
+				}
+			}
 
 			if needsConversion {
 				// Use ConvertingResponseWriter to transform response from targetType back to originalType
 				convertingWriter = NewConvertingResponseWriter(
-					responseCapture, e.converter, originalClientType, targetClientType, isStream)
+					baseWriter, e.converter, originalClientType, targetClientType, isStream)
 				responseWriter = convertingWriter
 			} else {
-				responseWriter = responseCapture
+				responseWriter = baseWriter
+			}
+
+			// Stream <-> non-stream conversion happens in the upstream format,
+			// before any cross-format conversion
+			var streamModeWriter *StreamModeWriter
+			if upstreamStream != isStream {
+				streamModeWriter = NewStreamModeWriter(responseWriter, upstreamClientType, upstreamStream)
+				responseWriter = streamModeWriter
 			}
 
-			// Execute request
-			err := matchedRoute.ProviderAdapter.Execute(attemptCtx, responseWriter, req, matchedRoute.Provider)
+			// The provider usage mapping describes the raw upstream response, so capture
+			// what the adapter writes before stream-mode or format conversion rewrites
+			// the body and drops the non-standard fields
+			usageMapping := getProviderUsageMapping(matchedRoute.Provider)
+			var upstreamCapture *ResponseCapture
+			if (needsConversion || streamModeWriter != nil) && !usageMapping.IsEmpty() {
+				upstreamCapture = NewResponseCapture(responseWriter)
+				responseWriter = upstreamCapture
+			}
+
+			// Provider soft failures (2xx with an error-like body, or without output
+			// when retry_empty_responses is on) fail over before anything reaches the client
+			var softFailure *softFailureWriter
+			patterns := getSoftFailureRegexps(matchedRoute.Provider)
+			retryEmpty := matchedRoute.Provider.Config != nil && matchedRoute.Provider.Config.RetryEmptyResponses
+			if len(patterns) > 0 || retryEmpty {
+				attemptCtx, softFailure = withSoftFailureDetection(attemptCtx, responseWriter, patterns)
+				softFailure.checkEmpty = retryEmpty
+				softFailure.usageMapping = usageMapping
+				responseWriter = softFailure
+			}
+
+			// Execute request (streaming upstreams are aborted when they stop sending data,
+			// streams to the client are cut off at the route/provider max stream duration)
+			var stallTimeout, maxStreamDuration time.Duration
+			if upstreamStream {
+				stallTimeout = e.getStreamStallTimeout()
+				if isStream {
+					maxStreamDuration = getMaxStreamDuration(matchedRoute.Route, matchedRoute.Provider)
+				}
+			}
+			var err error
+			func() {
+				defer e.router.BeginAttempt(matchedRoute.Provider.ID)()
+				err = executeWithMaxStreamDuration(attemptCtx, responseWriter, maxStreamDuration, func(ctx context.Context, w http.ResponseWriter) error {
+					return executeWithStallDetection(ctx, adp, w, req, matchedRoute.Provider, stallTimeout)
+				})
+			}()
+			if softFailure != nil {
+				err = softFailure.finish(err)
+			}
+
+			if streamModeWriter != nil {
+				if finalizeErr := streamModeWriter.Finalize(); finalizeErr != nil {
+					log.Printf("[Executor] Stream mode conversion finalize failed: %v", finalizeErr)
+				}
+			}
 
 			// For non-streaming responses with conversion, finalize the conversion
 			if needsConversion && convertingWriter != nil && !isStream {
@@ -398,15 +670,44 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 				}
 			}
 
+			if streamRewrite != nil {
+				if finishErr := streamRewrite.Finish(); finishErr != nil {
+					log.Printf("[Executor] Stream text rewrite flush failed: %v", finishErr)
+				}
+			}
+
+			// A stream cut off at the max duration, or stalled after content reached the
+			// client, ends with an error event after what was already delivered; it can't
+			// fail over to another route without corrupting the client's stream
+			if code := streamTruncationCode(err); code != "" && responseCapture.HasContent() {
+				attemptRecord.StreamTruncated = true
+				if proxyErr, ok := err.(*domain.ProxyError); ok {
+					proxyErr.Retryable = false
+				}
+				if _, writeErr := responseCapture.Write(streamTerminalErrorEvent(originalClientType, code, err.Error())); writeErr == nil {
+					responseCapture.Flush()
+				}
+				log.Printf("[Executor] Stream truncated (%s), route %d: %v", code, matchedRoute.Route.ID, err)
+			}
+
+			// Upstream is released at this point; drain remaining buffered data to the client
+			if streamBuffer != nil {
+				if drainErr := streamBuffer.Close(ctx); drainErr != nil {
+					log.Printf("[Executor] Stream buffer drain to client failed: %v", drainErr)
+				}
+			}
+
 			// Close event channel and wait for processing goroutine to finish
 			eventChan.Close()
 			<-eventDone
+			ratelimit.Default().RecordTokens(matchedRoute.Provider.ID, attemptRecord.InputTokenCount+attemptRecord.OutputTokenCount)
 
 			if err == nil {
 				// Success - set end time and duration
 				attemptRecord.EndTime = time.Now()
 				attemptRecord.Duration = attemptRecord.EndTime.Sub(attemptRecord.StartTime)
 				attemptRecord.Status = "COMPLETED"
+				attemptRecord.OutputTPS = outputTPS(attemptRecord)
 
 				// Calculate cost in executor (unified for all adapters)
 				// Adapter only needs to set token counts, executor handles pricing
@@ -418,22 +719,26 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 						CacheCreationCount:   attemptRecord.CacheWriteCount,
 						Cache5mCreationCount: attemptRecord.Cache5mWriteCount,
 						Cache1hCreationCount: attemptRecord.Cache1hWriteCount,
+						ReasoningTokens:      attemptRecord.ReasoningTokenCount,
 					}
 					// Use ResponseModel for pricing (actual model from API response), fallback to MappedModel
 					pricingModel := attemptRecord.ResponseModel
 					if pricingModel == "" {
 						pricingModel = attemptRecord.MappedModel
 					}
-					// Get multiplier from provider config
-					multiplier := getProviderMultiplier(matchedRoute.Provider, clientType)
-					result := pricing.GlobalCalculator().CalculateWithResult(pricingModel, metrics, multiplier)
+					// Get multiplier from route override or provider config
+					multiplier := getBillingMultiplier(matchedRoute.Route, matchedRoute.Provider, clientType)
+					result := e.costCalculator.CalculateWithOverrides(pricingModel, metrics, multiplier, getProviderPriceOverrides(matchedRoute.Provider))
 					attemptRecord.Cost = result.Cost
 					attemptRecord.ModelPriceID = result.ModelPriceID
 					attemptRecord.Multiplier = result.Multiplier
+					if result.PriceOverride {
+						attemptRecord.PriceOverrideProviderID = matchedRoute.Provider.ID
+					}
 				}
 
 				// 检查是否需要立即清理 attempt 详情（设置为 0 时不保存）
-				if e.shouldClearRequestDetail() {
+				if e.shouldClearRequestDetail(matchedRoute.Route) {
 					attemptRecord.RequestInfo = nil
 					attemptRecord.ResponseInfo = nil
 				}
@@ -444,11 +749,14 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 				}
 				currentAttempt = nil // Clear so defer doesn't update
 
-				// Reset failure counts on success
-				clientType := string(ctxutil.GetClientType(attemptCtx))
-				cooldown.Default().RecordSuccess(matchedRoute.Provider.ID, clientType)
+				// Reset failure counts on success (route probes leave provider health alone)
+				if !ctxutil.GetRouteProbe(ctx) {
+					clientType := string(ctxutil.GetClientType(attemptCtx))
+					cooldown.Default().RecordSuccess(matchedRoute.Provider.ID, clientType)
+				}
 
 				proxyReq.Status = "COMPLETED"
+				trace.Add(routeTraceStep(domain.RoutingTraceSelected, candidate, "completed on attempt "+strconv.Itoa(attempt+1)))
 				proxyReq.EndTime = time.Now()
 				proxyReq.Duration = proxyReq.EndTime.Sub(proxyReq.StartTime)
 				proxyReq.FinalProxyUpstreamAttemptID = attemptRecord.ID
@@ -458,7 +766,7 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 
 				// Capture actual client response (what was sent to client, e.g. Claude format)
 				// This is different from attemptRecord.ResponseInfo which is upstream response (Gemini format)
-				if !e.shouldClearRequestDetail() {
+				if !e.shouldClearRequestDetail(matchedRoute.Route) {
 					proxyReq.ResponseInfo = &domain.ResponseInfo{
 						Status:  responseCapture.StatusCode(),
 						Headers: responseCapture.CapturedHeaders(),
@@ -469,19 +777,20 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 
 				// Extract token usage from final client response (not from upstream attempt)
 				// This ensures we use the correct format (Claude/OpenAI/Gemini) for the client type
-				if metrics := usage.ExtractFromResponse(responseCapture.Body()); metrics != nil {
+				if metrics := extractResponseUsage(responseCapture, upstreamCapture, usageMapping); metrics != nil {
 					proxyReq.InputTokenCount = metrics.InputTokens
 					proxyReq.OutputTokenCount = metrics.OutputTokens
 					proxyReq.CacheReadCount = metrics.CacheReadCount
 					proxyReq.CacheWriteCount = metrics.CacheCreationCount
 					proxyReq.Cache5mWriteCount = metrics.Cache5mCreationCount
 					proxyReq.Cache1hWriteCount = metrics.Cache1hCreationCount
+					proxyReq.ReasoningTokenCount = metrics.ReasoningTokens
 				}
 				proxyReq.Cost = attemptRecord.Cost
 				proxyReq.TTFT = attemptRecord.TTFT
 
 				// 检查是否需要立即清理 proxyReq 详情（设置为 0 时不保存）
-				if e.shouldClearRequestDetail() {
+				if e.shouldClearRequestDetail(matchedRoute.Route) {
 					proxyReq.RequestInfo = nil
 					proxyReq.ResponseInfo = nil
 				}
@@ -500,12 +809,24 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 			attemptRecord.EndTime = time.Now()
 			attemptRecord.Duration = attemptRecord.EndTime.Sub(attemptRecord.StartTime)
 			lastErr = err
+			routeErr = err
 
 			// Update attempt status first (before checking context)
 			if ctx.Err() != nil {
 				attemptRecord.Status = "CANCELLED"
 			} else {
 				attemptRecord.Status = "FAILED"
+				attemptRecord.Error, attemptRecord.StatusCode = attemptFailure(err, attemptRecord.ResponseInfo)
+			}
+
+			// A stream cut off by the client, the max duration or a stall never got its final usage: bill what was delivered
+			partialUsage := false
+			if (attemptRecord.Status == "CANCELLED" || attemptRecord.StreamTruncated) && isStream && responseCapture.HasContent() {
+				partialUsage = completeCancelledStreamUsage(attemptRecord, clientType, responseCapture.Body(), size)
+				if partialUsage {
+					log.Printf("[Executor] Estimated usage of cancelled stream (attempt %d): input=%d output=%d",
+						attemptRecord.ID, attemptRecord.InputTokenCount, attemptRecord.OutputTokenCount)
+				}
 			}
 
 			// Calculate cost in executor even for failed attempts (may have partial token usage)
@@ -517,22 +838,26 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 					CacheCreationCount:   attemptRecord.CacheWriteCount,
 					Cache5mCreationCount: attemptRecord.Cache5mWriteCount,
 					Cache1hCreationCount: attemptRecord.Cache1hWriteCount,
+					ReasoningTokens:      attemptRecord.ReasoningTokenCount,
 				}
 				// Use ResponseModel for pricing (actual model from API response), fallback to MappedModel
 				pricingModel := attemptRecord.ResponseModel
 				if pricingModel == "" {
 					pricingModel = attemptRecord.MappedModel
 				}
-				// Get multiplier from provider config
-				multiplier := getProviderMultiplier(matchedRoute.Provider, clientType)
-				result := pricing.GlobalCalculator().CalculateWithResult(pricingModel, metrics, multiplier)
+				// Get multiplier from route override or provider config
+				multiplier := getBillingMultiplier(matchedRoute.Route, matchedRoute.Provider, clientType)
+				result := e.costCalculator.CalculateWithOverrides(pricingModel, metrics, multiplier, getProviderPriceOverrides(matchedRoute.Provider))
 				attemptRecord.Cost = result.Cost
 				attemptRecord.ModelPriceID = result.ModelPriceID
 				attemptRecord.Multiplier = result.Multiplier
+				if result.PriceOverride {
+					attemptRecord.PriceOverrideProviderID = matchedRoute.Provider.ID
+				}
 			}
 
 			// 检查是否需要立即清理 attempt 详情（设置为 0 时不保存）
-			if e.shouldClearRequestDetail() {
+			if e.shouldClearRequestDetail(matchedRoute.Route) {
 				attemptRecord.RequestInfo = nil
 				attemptRecord.ResponseInfo = nil
 			}
@@ -548,10 +873,11 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 			proxyReq.ModelPriceID = attemptRecord.ModelPriceID
 			proxyReq.Multiplier = attemptRecord.Multiplier
 
-			// Capture actual client response (even on failure, if any response was sent)
-			if responseCapture.Body() != "" {
+			// Capture actual client response (even on failure, if any response was sent;
+			// heartbeats alone are not a response)
+			if responseCapture.HasContent() {
 				proxyReq.StatusCode = responseCapture.StatusCode()
-				if !e.shouldClearRequestDetail() {
+				if !e.shouldClearRequestDetail(matchedRoute.Route) {
 					proxyReq.ResponseInfo = &domain.ResponseInfo{
 						Status:  responseCapture.StatusCode(),
 						Headers: responseCapture.CapturedHeaders(),
@@ -560,15 +886,25 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 				}
 
 				// Extract token usage from final client response
-				if metrics := usage.ExtractFromResponse(responseCapture.Body()); metrics != nil {
+				if metrics := extractResponseUsage(responseCapture, upstreamCapture, usageMapping); metrics != nil {
 					proxyReq.InputTokenCount = metrics.InputTokens
 					proxyReq.OutputTokenCount = metrics.OutputTokens
 					proxyReq.CacheReadCount = metrics.CacheReadCount
 					proxyReq.CacheWriteCount = metrics.CacheCreationCount
 					proxyReq.Cache5mWriteCount = metrics.Cache5mCreationCount
 					proxyReq.Cache1hWriteCount = metrics.Cache1hCreationCount
+					proxyReq.ReasoningTokenCount = metrics.ReasoningTokens
 				}
 			}
+			if partialUsage {
+				proxyReq.InputTokenCount = attemptRecord.InputTokenCount
+				proxyReq.OutputTokenCount = attemptRecord.OutputTokenCount
+				proxyReq.CacheReadCount = attemptRecord.CacheReadCount
+				proxyReq.CacheWriteCount = attemptRecord.CacheWriteCount
+				proxyReq.Cache5mWriteCount = attemptRecord.Cache5mWriteCount
+				proxyReq.Cache1hWriteCount = attemptRecord.Cache1hWriteCount
+				proxyReq.ReasoningTokenCount = attemptRecord.ReasoningTokenCount
+			}
 			proxyReq.Cost = attemptRecord.Cost
 			proxyReq.TTFT = attemptRecord.TTFT
 
@@ -579,17 +915,17 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 
 			// Handle cooldown only for real server/network errors, NOT client-side cancellations
 			proxyErr, ok := err.(*domain.ProxyError)
-			if ok && ctx.Err() != context.Canceled {
+			if ok && ctxutil.GetRouteProbe(ctx) {
+				log.Printf("[Executor] Route probe failed, skipping cooldown for Provider: %d", matchedRoute.Provider.ID)
+			} else if ok && errors.Is(proxyErr, ErrStreamMaxDuration) {
+				log.Printf("[Executor] Stream hit max duration, skipping cooldown for Provider: %d", matchedRoute.Provider.ID)
+			} else if ok && ctx.Err() != context.Canceled {
 				log.Printf("[Executor] ProxyError - IsNetworkError: %v, IsServerError: %v, Retryable: %v, Provider: %d",
 					proxyErr.IsNetworkError, proxyErr.IsServerError, proxyErr.Retryable, matchedRoute.Provider.ID)
 				// Handle cooldown (unified cooldown logic for all providers)
 				e.handleCooldown(attemptCtx, proxyErr, matchedRoute.Provider)
-				// Broadcast cooldown update event to frontend
-				if e.broadcaster != nil {
-					e.broadcaster.BroadcastMessage("cooldown_update", map[string]interface{}{
-						"providerID": matchedRoute.Provider.ID,
-					})
-				}
+				// Broadcast cooldown update event to frontend (throttled per provider)
+				e.broadcastCooldownUpdate(matchedRoute.Provider.ID)
 			} else if ok && ctx.Err() == context.Canceled {
 				log.Printf("[Executor] Client disconnected, skipping cooldown for Provider: %d", matchedRoute.Provider.ID)
 			} else if !ok {
@@ -615,6 +951,12 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 				return ctx.Err()
 			}
 
+			// The client already has part of the response: end the request here
+			if attemptRecord.StreamTruncated {
+				streamTruncated = true
+				break
+			}
+
 			// Check if retryable
 			if !ok {
 				break // Move to next route
@@ -628,7 +970,14 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 			if attempt < retryConfig.MaxRetries {
 				waitTime := e.calculateBackoff(retryConfig, attempt)
 				if proxyErr.RetryAfter > 0 {
-					waitTime = proxyErr.RetryAfter
+					// Honor the upstream hint, spread out so throttled requests don't retry in lockstep
+					wait, ok := e.retryAfterWait(proxyErr.RetryAfter)
+					if !ok {
+						log.Printf("[Executor] Retry-After %v exceeds retry_after_max_wait_seconds, moving on from route %d",
+							proxyErr.RetryAfter, matchedRoute.Route.ID)
+						break // Move to next route
+					}
+					waitTime = wait
 				}
 				select {
 				case <-ctx.Done():
@@ -653,6 +1002,13 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 			}
 		}
 		// Inner loop ended, will try next route if available
+		if routeErr != nil {
+			trace.Add(routeTraceStep(domain.RoutingTraceFailed, candidate, routeErr.Error()))
+			routeLimit.record(matchedRoute.Route.ID)
+		}
+		if streamTruncated {
+			break
+		}
 	}
 
 	// All routes failed
@@ -662,9 +1018,12 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 	if lastErr != nil {
 		proxyReq.Error = lastErr.Error()
 	}
+	if routeLimitHit {
+		proxyReq.Error = fmt.Sprintf("max routes attempted (%d) reached, last error: %s", routeLimit.max, proxyReq.Error)
+	}
 
-	// 检查是否需要立即清理详情（设置为 0 时不保存）
-	if e.shouldClearRequestDetail() {
+	// 检查是否需要立即清理详情（设置为 0 时不保存，最后尝试的路由强制保留详情时除外）
+	if e.shouldClearRequestDetail(lastRoute) {
 		proxyReq.RequestInfo = nil
 		proxyReq.ResponseInfo = nil
 	}
@@ -682,25 +1041,83 @@ func (e *Executor) Execute(ctx context.Context, w http.ResponseWriter, req *http
 	return domain.NewProxyErrorWithMessage(domain.ErrAllRoutesFailed, false, "all routes exhausted")
 }
 
-func (e *Executor) mapModel(requestModel string, route *domain.Route, provider *domain.Provider, clientType domain.ClientType, projectID uint64, apiTokenID uint64) string {
-	// Database model mapping with full query conditions
-	query := &domain.ModelMappingQuery{
+// matchCandidates matches routes for the requested model, then appends routes for
+// each fallback model. Route order is preserved within each model; fallback models
+// are only tried after every route of the previous model has failed (model-centric
+// failover). A route override (request replay) yields exactly that route.
+func (e *Executor) matchCandidates(ctx context.Context, clientType domain.ClientType, projectID uint64, requestModel string, apiTokenID uint64, trace *domain.RoutingTrace) ([]routeCandidate, error) {
+	if routeID := ctxutil.GetRouteOverride(ctx); routeID != 0 {
+		matched, err := e.router.MatchRoute(routeID)
+		if err != nil {
+			return nil, err
+		}
+		candidate := routeCandidate{MatchedRoute: matched, model: requestModel}
+		trace.Add(routeTraceStep(domain.RoutingTraceMatched, candidate, "route override (replay)"))
+		return []routeCandidate{candidate}, nil
+	}
+
+	routes, err := e.router.Match(&router.MatchContext{
 		ClientType:   clientType,
-		ProviderType: provider.Type,
-		ProviderID:   provider.ID,
 		ProjectID:    projectID,
-		RouteID:      route.ID,
+		RequestModel: requestModel,
 		APITokenID:   apiTokenID,
+		Trace:        trace,
+	})
+	candidates := make([]routeCandidate, 0, len(routes))
+	for _, r := range routes {
+		candidates = append(candidates, routeCandidate{MatchedRoute: r, model: requestModel})
 	}
-	mappings, _ := e.modelMappingRepo.ListByQuery(query)
-	for _, m := range mappings {
-		if domain.MatchWildcard(m.Pattern, requestModel) {
-			return m.Target
+	for _, fallbackModel := range e.resolveModelFallbacks(ctx, projectID, requestModel) {
+		fallbackRoutes, fallbackErr := e.router.Match(&router.MatchContext{
+			ClientType:   clientType,
+			ProjectID:    projectID,
+			RequestModel: fallbackModel,
+			APITokenID:   apiTokenID,
+			Trace:        trace,
+		})
+		if fallbackErr != nil {
+			continue
+		}
+		for _, r := range fallbackRoutes {
+			candidates = append(candidates, routeCandidate{MatchedRoute: r, model: fallbackModel})
 		}
 	}
+	return candidates, err
+}
 
-	// No mapping, use original
-	return requestModel
+// routeCandidate is a matched route together with the model to request on it
+// (the original request model, or a fallback model from a fallback chain)
+type routeCandidate struct {
+	*router.MatchedRoute
+	model string
+}
+
+// resolveBillable determines whether the request is billable at creation time.
+// A flag set by the proxy handler (token NonBillable or the privileged
+// X-Maxx-Billable header) wins; otherwise the project's NonBillable applies.
+func (e *Executor) resolveBillable(ctx context.Context, projectID uint64) bool {
+	if billable, ok := ctxutil.GetBillable(ctx); ok {
+		return billable
+	}
+	return e.router.ProjectBillable(projectID)
+}
+
+// resolveModelFallbacks returns the fallback models for requestModel.
+// A matching chain on the API token takes precedence over the project's.
+func (e *Executor) resolveModelFallbacks(ctx context.Context, projectID uint64, requestModel string) []string {
+	if requestModel == "" {
+		return nil
+	}
+	if models := domain.MatchModelFallback(ctxutil.GetModelFallbacks(ctx), requestModel); models != nil {
+		return models
+	}
+	return domain.MatchModelFallback(e.router.ProjectModelFallbacks(projectID), requestModel)
+}
+
+func (e *Executor) mapModel(requestModel string, route *domain.Route, provider *domain.Provider, clientType domain.ClientType, projectID uint64, apiTokenID uint64) string {
+	// Database model mapping with full query conditions (no mapping keeps the original)
+	res, _ := ResolveModelMapping(e.modelMappingRepo, RouteModelMappingQuery(route, provider, clientType, projectID, apiTokenID), requestModel)
+	return res.Target
 }
 
 func (e *Executor) getRetryConfig(config *domain.RetryConfig) *domain.RetryConfig {
@@ -738,6 +1155,24 @@ func generateRequestID() string {
 	return time.Now().Format("20060102150405.000000")
 }
 
+// clientRequestInfo builds the client's original request info from the context
+func clientRequestInfo(ctx context.Context, req *http.Request) *domain.RequestInfo {
+	headers := flattenHeaders(ctxutil.GetRequestHeaders(ctx))
+	// Go stores Host separately from headers, add it explicitly
+	if req.Host != "" {
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+		headers["Host"] = req.Host
+	}
+	return &domain.RequestInfo{
+		Method:  req.Method,
+		URL:     ctxutil.GetRequestURI(ctx),
+		Headers: headers,
+		Body:    string(ctxutil.GetRequestBody(ctx)),
+	}
+}
+
 // flattenHeaders converts http.Header to map[string]string (taking first value)
 func flattenHeaders(h http.Header) map[string]string {
 	if h == nil {
@@ -876,6 +1311,7 @@ func (e *Executor) processAdapterEvents(eventChan domain.AdapterEventChan, attem
 					attempt.CacheWriteCount = event.Metrics.CacheCreationCount
 					attempt.Cache5mWriteCount = event.Metrics.Cache5mCreationCount
 					attempt.Cache1hWriteCount = event.Metrics.Cache1hCreationCount
+					attempt.ReasoningTokenCount = event.Metrics.ReasoningTokens
 				}
 			case domain.EventResponseModel:
 				if event.ResponseModel != "" {
@@ -896,7 +1332,7 @@ func (e *Executor) processAdapterEvents(eventChan domain.AdapterEventChan, attem
 
 // processAdapterEventsRealtime processes events in real-time during adapter execution
 // It broadcasts updates immediately when RequestInfo/ResponseInfo are received
-func (e *Executor) processAdapterEventsRealtime(eventChan domain.AdapterEventChan, attempt *domain.ProxyUpstreamAttempt, done chan struct{}) {
+func (e *Executor) processAdapterEventsRealtime(eventChan domain.AdapterEventChan, attempt *domain.ProxyUpstreamAttempt, route *domain.Route, done chan struct{}) {
 	defer close(done)
 
 	if eventChan == nil || attempt == nil {
@@ -912,12 +1348,12 @@ func (e *Executor) processAdapterEventsRealtime(eventChan domain.AdapterEventCha
 
 		switch event.Type {
 		case domain.EventRequestInfo:
-			if !e.shouldClearRequestDetail() && event.RequestInfo != nil {
+			if !e.shouldClearRequestDetail(route) && event.RequestInfo != nil {
 				attempt.RequestInfo = event.RequestInfo
 				needsBroadcast = true
 			}
 		case domain.EventResponseInfo:
-			if !e.shouldClearRequestDetail() && event.ResponseInfo != nil {
+			if !e.shouldClearRequestDetail(route) && event.ResponseInfo != nil {
 				attempt.ResponseInfo = event.ResponseInfo
 				needsBroadcast = true
 			}
@@ -929,6 +1365,7 @@ func (e *Executor) processAdapterEventsRealtime(eventChan domain.AdapterEventCha
 				attempt.CacheWriteCount = event.Metrics.CacheCreationCount
 				attempt.Cache5mWriteCount = event.Metrics.Cache5mCreationCount
 				attempt.Cache1hWriteCount = event.Metrics.Cache1hCreationCount
+				attempt.ReasoningTokenCount = event.Metrics.ReasoningTokens
 				needsBroadcast = true
 			}
 		case domain.EventResponseModel:
@@ -969,12 +1406,40 @@ func (e *Executor) getRequestDetailRetentionSeconds() int {
 	return seconds
 }
 
+// getStreamBufferMaxBytes 获取流式响应缓冲上限（字节），0 表示禁用
+func (e *Executor) getStreamBufferMaxBytes() int {
+	if e.settingsRepo == nil {
+		return 0
+	}
+	val, err := e.settingsRepo.Get(domain.SettingKeyStreamBufferMaxBytes)
+	if err != nil || val == "" {
+		return 0
+	}
+	maxBytes, err := strconv.Atoi(val)
+	if err != nil || maxBytes < 0 {
+		return 0
+	}
+	return maxBytes
+}
+
 // shouldClearRequestDetail 检查是否应该立即清理请求详情
-// 当设置为 0 时返回 true
-func (e *Executor) shouldClearRequestDetail() bool {
+// 当设置为 0 时返回 true，路由开启 ForceDetail 时始终保留详情
+func (e *Executor) shouldClearRequestDetail(route *domain.Route) bool {
+	if route != nil && route.ForceDetail {
+		return false
+	}
 	return e.getRequestDetailRetentionSeconds() == 0
 }
 
+// getBillingMultiplier 获取 attempt 计费使用的倍率，并随 attempt 保存供成本重算使用
+// 优先级：路由的倍率覆盖 > Provider 针对 ClientType 的倍率 > 默认 1 倍
+func getBillingMultiplier(route *domain.Route, provider *domain.Provider, clientType domain.ClientType) uint64 {
+	if route != nil && route.Multiplier > 0 {
+		return route.Multiplier
+	}
+	return getProviderMultiplier(provider, clientType)
+}
+
 // getProviderMultiplier 获取 Provider 针对特定 ClientType 的倍率
 // 返回 10000 表示 1 倍，15000 表示 1.5 倍
 func getProviderMultiplier(provider *domain.Provider, clientType domain.ClientType) uint64 {
@@ -990,3 +1455,33 @@ func getProviderMultiplier(provider *domain.Provider, clientType domain.ClientTy
 	return 10000
 }
 
+// getProviderPriceOverrides 获取 Provider 配置的价格覆盖，未配置返回 nil
+func getProviderPriceOverrides(provider *domain.Provider) []*domain.ModelPrice {
+	if provider == nil || provider.Config == nil {
+		return nil
+	}
+	return provider.Config.PriceOverrides
+}
+
+// extractResponseUsage 提取请求的 token 用量
+// usage 字段映射按上游响应格式配置：发生格式转换时从 upstream（转换前的响应）按映射读取，
+// 否则从客户端响应读取；映射未配置或未命中时从客户端响应做标准提取
+func extractResponseUsage(client, upstream *ResponseCapture, mapping *domain.UsageFieldMapping) *usage.Metrics {
+	mappedBody := client.Body()
+	if upstream != nil {
+		mappedBody = upstream.Body()
+	}
+	if metrics := usage.ExtractWithMapping(mappedBody, mapping); metrics != nil {
+		return metrics
+	}
+	return usage.ExtractFromResponse(client.Body())
+}
+
+// getProviderUsageMapping 获取 provider 配置的 usage 字段映射，未配置返回 nil
+func getProviderUsageMapping(provider *domain.Provider) *domain.UsageFieldMapping {
+	if provider == nil || provider.Config == nil || provider.Config.Custom == nil {
+		return nil
+	}
+	return provider.Config.Custom.UsageMapping
+}
+
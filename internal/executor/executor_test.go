@@ -0,0 +1,320 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/adapter/provider"
+	ctxutil "github.com/awsl-project/maxx/internal/context"
+	"github.com/awsl-project/maxx/internal/cooldown"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/pricing"
+	"github.com/awsl-project/maxx/internal/repository/cached"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+	"github.com/awsl-project/maxx/internal/router"
+	"github.com/awsl-project/maxx/internal/usage"
+)
+
+// testUpstream is one provider of a test executor. route holds optional route
+// settings; its enabled flag, client type, provider and position are filled in.
+type testUpstream struct {
+	adapter provider.ProviderAdapter
+	config  domain.ProviderConfig
+	route   domain.Route
+}
+
+// testExecutor is an Executor over a temporary database, with one route per
+// upstream in the order given
+type testExecutor struct {
+	*Executor
+	db        *sqlite.DB
+	providers []*domain.Provider
+	routes    []*domain.Route
+}
+
+// newTestExecutor registers each upstream adapter under its own provider type and
+// routes clientType requests through them in order. settings are applied before the
+// executor starts.
+func newTestExecutor(t *testing.T, clientType domain.ClientType, settings map[string]string, upstreams ...testUpstream) *testExecutor {
+	t.Helper()
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	providerRepo := sqlite.NewProviderRepository(db)
+	routeRepo := sqlite.NewRouteRepository(db)
+	settingRepo := sqlite.NewSystemSettingRepository(db)
+	for key, value := range settings {
+		if err := settingRepo.Set(key, value); err != nil {
+			t.Fatalf("set %s: %v", key, err)
+		}
+	}
+
+	te := &testExecutor{db: db}
+	for i, upstream := range upstreams {
+		providerType := t.Name() + "/" + string(rune('a'+i))
+		provider.RegisterAdapterFactory(providerType, func(*domain.Provider) (provider.ProviderAdapter, error) {
+			return upstream.adapter, nil
+		})
+		p := &domain.Provider{Name: providerType, Type: providerType, Config: &upstream.config}
+		if err := providerRepo.Create(p); err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+		r := upstream.route
+		r.IsEnabled, r.ClientType, r.ProviderID, r.Position = true, clientType, p.ID, i
+		if err := routeRepo.Create(&r); err != nil {
+			t.Fatalf("create route: %v", err)
+		}
+		te.providers = append(te.providers, p)
+		te.routes = append(te.routes, &r)
+		t.Cleanup(func() { _ = cooldown.Default().ResetProvider(p.ID) })
+	}
+
+	cachedProviders := cached.NewProviderRepository(providerRepo)
+	cachedRoutes := cached.NewRouteRepository(routeRepo)
+	cachedRetry := cached.NewRetryConfigRepository(sqlite.NewRetryConfigRepository(db))
+	cachedStrategies := cached.NewRoutingStrategyRepository(sqlite.NewRoutingStrategyRepository(db))
+	cachedProjects := cached.NewProjectRepository(sqlite.NewProjectRepository(db))
+	for _, load := range []func() error{cachedProviders.Load, cachedRoutes.Load, cachedRetry.Load, cachedStrategies.Load, cachedProjects.Load} {
+		if err := load(); err != nil {
+			t.Fatalf("load cache: %v", err)
+		}
+	}
+	r := router.NewRouter(cachedRoutes, cachedProviders, cachedStrategies, cachedRetry, cachedProjects)
+	if err := r.InitAdapters(); err != nil {
+		t.Fatalf("InitAdapters: %v", err)
+	}
+
+	te.Executor = NewExecutor(r,
+		sqlite.NewProxyRequestRepository(db),
+		sqlite.NewProxyUpstreamAttemptRepository(db),
+		cachedRetry,
+		sqlite.NewSessionRepository(db),
+		sqlite.NewModelMappingRepository(db),
+		settingRepo,
+		nil, nil, "test", nil)
+	return te
+}
+
+// execute sends a request of clientType for model through the executor
+func (te *testExecutor) execute(clientType domain.ClientType, model string, stream bool, w http.ResponseWriter) error {
+	req, _ := http.NewRequest(http.MethodPost, "/v1/messages", nil)
+	ctx := ctxutil.WithClientType(context.Background(), clientType)
+	ctx = ctxutil.WithRequestModel(ctx, model)
+	ctx = ctxutil.WithIsStream(ctx, stream)
+	ctx = ctxutil.WithRequestBody(ctx, []byte(`{"model":"`+model+`","messages":[{"role":"user","content":"hi"}]}`))
+	return te.Execute(ctx, w, req)
+}
+
+// attempts returns the upstream attempts of the only proxy request so far
+func (te *testExecutor) attempts(t *testing.T) []*domain.ProxyUpstreamAttempt {
+	t.Helper()
+	attempts, err := sqlite.NewProxyUpstreamAttemptRepository(te.db).ListByProxyRequestID(1)
+	if err != nil {
+		t.Fatalf("list attempts: %v", err)
+	}
+	return attempts
+}
+
+// staticAdapter writes a fixed body in the only client type it supports, and
+// reports metrics when set
+type staticAdapter struct {
+	clientType domain.ClientType
+	body       string
+	metrics    *domain.AdapterMetrics
+}
+
+func (a *staticAdapter) SupportedClientTypes() []domain.ClientType {
+	return []domain.ClientType{a.clientType}
+}
+
+func (a *staticAdapter) Execute(ctx context.Context, w http.ResponseWriter, req *http.Request, p *domain.Provider) error {
+	if a.metrics != nil {
+		ctxutil.GetEventChan(ctx).SendMetrics(a.metrics)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, err := w.Write([]byte(a.body))
+	return err
+}
+
+func TestExecutorUsageMappingAppliesBeforeConversion(t *testing.T) {
+	// 上游只支持 OpenAI 格式，token 数量放在非标准字段中；转换为 Claude 格式后这些字段不再存在
+	upstream := &staticAdapter{
+		clientType: domain.ClientTypeOpenAI,
+		body: `{"id":"chatcmpl-1","object":"chat.completion","model":"gpt-4o",` +
+			`"choices":[{"index":0,"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}],` +
+			`"token_usage":{"prompt":12,"completion":34}}`,
+	}
+	te := newTestExecutor(t, domain.ClientTypeClaude, nil, testUpstream{
+		adapter: upstream,
+		config: domain.ProviderConfig{Custom: &domain.ProviderConfigCustom{
+			UsageMapping: &domain.UsageFieldMapping{InputTokens: "token_usage.prompt", OutputTokens: "token_usage.completion"},
+		}},
+	})
+
+	rec := httptest.NewRecorder()
+	if err := te.execute(domain.ClientTypeClaude, "claude-sonnet-4", false, rec); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"type":"message"`) || strings.Contains(body, "token_usage") {
+		t.Fatalf("body = %s, want a converted Claude message", body)
+	}
+	proxyReq, err := sqlite.NewProxyRequestRepository(te.db).GetByID(1)
+	if err != nil {
+		t.Fatalf("get proxy request: %v", err)
+	}
+	if proxyReq.InputTokenCount != 12 || proxyReq.OutputTokenCount != 34 {
+		t.Errorf("tokens = %d/%d, want 12/34 from the upstream usage mapping", proxyReq.InputTokenCount, proxyReq.OutputTokenCount)
+	}
+}
+
+func TestExecutorUsageMappingAppliesBeforeStreamMode(t *testing.T) {
+	// 上游只支持流式：非流式请求的响应由 SSE 聚合而成，非标准 usage 字段不会保留
+	upstream := &staticAdapter{
+		clientType: domain.ClientTypeClaude,
+		body: "event: message_start\n" +
+			`data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4","content":[]}}` + "\n\n" +
+			"event: content_block_start\n" +
+			`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}` + "\n\n" +
+			"event: content_block_delta\n" +
+			`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"hello"}}` + "\n\n" +
+			"event: content_block_stop\n" +
+			`data: {"type":"content_block_stop","index":0}` + "\n\n" +
+			"event: message_delta\n" +
+			`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"token_usage":{"prompt":12,"completion":34,"thinking":8}}` + "\n\n" +
+			"event: message_stop\n" +
+			`data: {"type":"message_stop"}` + "\n\n",
+	}
+	te := newTestExecutor(t, domain.ClientTypeClaude, nil, testUpstream{
+		adapter: upstream,
+		config: domain.ProviderConfig{Custom: &domain.ProviderConfigCustom{
+			StreamMode: domain.StreamModeStream,
+			UsageMapping: &domain.UsageFieldMapping{
+				InputTokens: "token_usage.prompt", OutputTokens: "token_usage.completion", ReasoningTokens: "token_usage.thinking",
+			},
+		}},
+	})
+
+	rec := httptest.NewRecorder()
+	if err := te.execute(domain.ClientTypeClaude, "claude-sonnet-4", false, rec); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"hello"`) || strings.Contains(body, "token_usage") {
+		t.Fatalf("body = %s, want an aggregated Claude message", body)
+	}
+	proxyReq, err := sqlite.NewProxyRequestRepository(te.db).GetByID(1)
+	if err != nil {
+		t.Fatalf("get proxy request: %v", err)
+	}
+	if proxyReq.InputTokenCount != 12 || proxyReq.OutputTokenCount != 34 || proxyReq.ReasoningTokenCount != 8 {
+		t.Errorf("tokens = %d/%d/%d, want 12/34/8 from the upstream usage mapping",
+			proxyReq.InputTokenCount, proxyReq.OutputTokenCount, proxyReq.ReasoningTokenCount)
+	}
+}
+
+// fixedCostCalculator bills every request at cost, keeping the table price record
+type fixedCostCalculator struct {
+	pricing.CostCalculator
+	cost uint64
+}
+
+func (c *fixedCostCalculator) CalculateWithOverrides(model string, metrics *usage.Metrics, multiplier uint64, overrides []*domain.ModelPrice) pricing.CostResult {
+	result := c.CostCalculator.CalculateWithOverrides(model, metrics, multiplier, overrides)
+	result.Cost = c.cost
+	return result
+}
+
+func TestExecutorUsesCustomCostCalculator(t *testing.T) {
+	upstream := &staticAdapter{
+		clientType: domain.ClientTypeClaude,
+		body:       `{"type":"message","role":"assistant","content":[{"type":"text","text":"hi"}]}`,
+		metrics:    &domain.AdapterMetrics{InputTokens: 100, OutputTokens: 50},
+	}
+	te := newTestExecutor(t, domain.ClientTypeClaude, nil, testUpstream{adapter: upstream})
+	te.SetCostCalculator(&fixedCostCalculator{CostCalculator: pricing.GlobalCalculator(), cost: 4242})
+
+	if err := te.execute(domain.ClientTypeClaude, "claude-sonnet-4", false, httptest.NewRecorder()); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	attempts := te.attempts(t)
+	if len(attempts) != 1 || attempts[0].Cost != 4242 {
+		t.Fatalf("attempts = %+v, want one attempt billed by the custom calculator", attempts)
+	}
+	if attempts[0].Multiplier != 10000 {
+		t.Errorf("multiplier = %d, want the default 10000 passed through", attempts[0].Multiplier)
+	}
+	proxyReq, err := sqlite.NewProxyRequestRepository(te.db).GetByID(1)
+	if err != nil {
+		t.Fatalf("get proxy request: %v", err)
+	}
+	if proxyReq.Cost != 4242 {
+		t.Errorf("request cost = %d, want 4242", proxyReq.Cost)
+	}
+}
+
+func TestShouldClearRequestDetail(t *testing.T) {
+	tests := []struct {
+		retention string
+		route     *domain.Route
+		want      bool
+	}{
+		// 未配置时默认永久保存
+		{"", nil, false},
+		{"", &domain.Route{}, false},
+		{"-1", &domain.Route{}, false},
+		{"3600", &domain.Route{}, false},
+		{"0", nil, true},
+		{"0", &domain.Route{}, true},
+		// 路由开启 ForceDetail 时不受全局设置影响
+		{"0", &domain.Route{ForceDetail: true}, false},
+		{"3600", &domain.Route{ForceDetail: true}, false},
+	}
+	for _, tt := range tests {
+		db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+		if err != nil {
+			t.Fatalf("NewDB failed: %v", err)
+		}
+		settingRepo := sqlite.NewSystemSettingRepository(db)
+		if tt.retention != "" {
+			if err := settingRepo.Set(domain.SettingKeyRequestDetailRetentionSeconds, tt.retention); err != nil {
+				t.Fatalf("set retention: %v", err)
+			}
+		}
+		e := &Executor{settingsRepo: settingRepo}
+		if got := e.shouldClearRequestDetail(tt.route); got != tt.want {
+			t.Errorf("retention %q, route %+v: shouldClearRequestDetail = %v, want %v", tt.retention, tt.route, got, tt.want)
+		}
+	}
+}
+
+func TestExecutorForceDetailRoute(t *testing.T) {
+	body := `{"type":"message","role":"assistant","content":[{"type":"text","text":"hi"}]}`
+	for _, forceDetail := range []bool{true, false} {
+		t.Run(fmt.Sprintf("force_detail=%v", forceDetail), func(t *testing.T) {
+			te := newTestExecutor(t, domain.ClientTypeClaude,
+				map[string]string{domain.SettingKeyRequestDetailRetentionSeconds: "0"},
+				testUpstream{
+					adapter: &staticAdapter{clientType: domain.ClientTypeClaude, body: body},
+					route:   domain.Route{ForceDetail: forceDetail},
+				})
+			if err := te.execute(domain.ClientTypeClaude, "claude-sonnet-4", false, httptest.NewRecorder()); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			proxyReq, err := sqlite.NewProxyRequestRepository(te.db).GetByID(1)
+			if err != nil {
+				t.Fatalf("get proxy request: %v", err)
+			}
+			kept := proxyReq.RequestInfo != nil && proxyReq.ResponseInfo != nil && proxyReq.ResponseInfo.Body == body
+			cleared := proxyReq.RequestInfo == nil && proxyReq.ResponseInfo == nil
+			if forceDetail && !kept || !forceDetail && !cleared {
+				t.Errorf("request detail = %+v / %+v with retention 0", proxyReq.RequestInfo, proxyReq.ResponseInfo)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package executor
+
+import (
+	"log"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// assignModelExperiment picks the caller's variant of the first enabled A/B
+// model experiment matching requestModel (model_experiments setting).
+// Returns nil, nil when no experiment applies.
+func (e *Executor) assignModelExperiment(requestModel string, apiTokenID uint64, sessionID string) (*domain.ModelExperiment, *domain.ModelVariant) {
+	if e.settingsRepo == nil {
+		return nil, nil
+	}
+	val, err := e.settingsRepo.Get(domain.SettingKeyModelExperiments)
+	if err != nil || val == "" {
+		return nil, nil
+	}
+	experiments, err := domain.ParseModelExperiments(val)
+	if err != nil {
+		log.Printf("[Executor] Ignoring invalid model experiments: %v", err)
+		return nil, nil
+	}
+	return domain.AssignModelVariant(experiments, requestModel, apiTokenID, sessionID)
+}
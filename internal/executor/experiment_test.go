@@ -0,0 +1,66 @@
+package executor
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestAssignModelVariant(t *testing.T) {
+	experiments, err := domain.ParseModelExperiments(`[
+		{"name":"disabled","enabled":false,"variants":[{"name":"X","model":"never","weight":1}]},
+		{"name":"sonnet-ab","enabled":true,"model":"claude-sonnet-*","variants":[
+			{"name":"A","model":"claude-sonnet-4-5","weight":1},
+			{"name":"B","model":"claude-opus-4-1","weight":1}
+		]}
+	]`)
+	if err != nil {
+		t.Fatalf("ParseModelExperiments failed: %v", err)
+	}
+
+	if exp, v := domain.AssignModelVariant(experiments, "gpt-4o", 1, "s"); exp != nil || v != nil {
+		t.Errorf("unmatched model assigned %v", v)
+	}
+	if exp, _ := domain.AssignModelVariant(experiments, "claude-sonnet-4", 0, ""); exp != nil {
+		t.Error("assigned a caller without token or session")
+	}
+
+	// 同一 Token 始终分到同一变体，与 Session 无关；两个变体都会被分到
+	counts := map[string]int{}
+	for token := uint64(1); token <= 200; token++ {
+		exp, first := domain.AssignModelVariant(experiments, "claude-sonnet-4", token, "s1")
+		if exp == nil || exp.Name != "sonnet-ab" {
+			t.Fatalf("token %d: experiment = %v, want sonnet-ab", token, exp)
+		}
+		for i := 0; i < 3; i++ {
+			_, again := domain.AssignModelVariant(experiments, "claude-sonnet-4-5", token, fmt.Sprintf("s%d", i))
+			if again.Name != first.Name {
+				t.Fatalf("token %d: variant changed from %s to %s", token, first.Name, again.Name)
+			}
+		}
+		counts[first.Name]++
+	}
+	if counts["A"] < 60 || counts["B"] < 60 {
+		t.Errorf("variant split = %v, want roughly even", counts)
+	}
+
+	// 无 Token 时退回 Session
+	if _, v := domain.AssignModelVariant(experiments, "claude-sonnet-4", 0, "session-1"); v == nil {
+		t.Error("session fallback not assigned")
+	}
+}
+
+func TestParseModelExperimentsRejectsInvalid(t *testing.T) {
+	for _, value := range []string{
+		`[{"name":"","variants":[{"name":"A","model":"m","weight":1}]}]`,
+		`[{"name":"x","variants":[]}]`,
+		`[{"name":"x","assignBy":"ip","variants":[{"name":"A","model":"m","weight":1}]}]`,
+		`[{"name":"x","variants":[{"name":"A","model":"m","weight":0}]}]`,
+		`[{"name":"x","variants":[{"name":"A","model":"m","weight":1}]},{"name":"x","variants":[{"name":"A","model":"m","weight":1}]}]`,
+	} {
+		if _, err := domain.ParseModelExperiments(value); err == nil {
+			t.Errorf("ParseModelExperiments(%s) succeeded, want error", value)
+		}
+	}
+}
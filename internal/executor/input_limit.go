@@ -0,0 +1,68 @@
+package executor
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/awsl-project/maxx/internal/converter"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/tokenizer"
+)
+
+// inputSize measures a request against provider input limits. The token
+// estimate is computed at most once per request, on the client's body, since
+// format conversion hardly changes the amount of input.
+type inputSize struct {
+	registry   *converter.Registry
+	body       []byte
+	clientType domain.ClientType
+
+	tokens    int
+	estimated bool
+}
+
+// estimatedTokens estimates the input tokens with the Claude token estimator,
+// converting other formats to Claude first. Bodies that can't be parsed are
+// estimated as plain text.
+func (s *inputSize) estimatedTokens() int {
+	if s.estimated {
+		return s.tokens
+	}
+	s.estimated = true
+
+	estimator := tokenizer.NewEstimator()
+	claudeBody := s.body
+	if s.clientType != domain.ClientTypeClaude && s.registry != nil {
+		converted, err := s.registry.TransformRequest(s.clientType, domain.ClientTypeClaude, s.body, "", false)
+		if err != nil {
+			s.tokens = estimator.EstimateTextTokens(string(s.body))
+			return s.tokens
+		}
+		claudeBody = converted
+	}
+	var req converter.ClaudeRequest
+	if err := json.Unmarshal(claudeBody, &req); err != nil {
+		s.tokens = estimator.EstimateTextTokens(string(s.body))
+		return s.tokens
+	}
+	s.tokens = estimator.EstimateInputTokens(&req)
+	return s.tokens
+}
+
+// exceedsInputLimit checks the upstream body against the provider's
+// MaxInputBytes / MaxInputTokens and returns why it can't take the request,
+// "" if it can
+func exceedsInputLimit(p *domain.Provider, upstreamBody []byte, size *inputSize) string {
+	if p == nil || p.Config == nil {
+		return ""
+	}
+	if limit := p.Config.MaxInputBytes; limit > 0 && uint64(len(upstreamBody)) > limit {
+		return fmt.Sprintf("request body %d bytes exceeds provider limit of %d bytes", len(upstreamBody), limit)
+	}
+	if limit := p.Config.MaxInputTokens; limit > 0 {
+		if tokens := size.estimatedTokens(); uint64(tokens) > limit {
+			return fmt.Sprintf("estimated %d input tokens exceeds provider limit of %d tokens", tokens, limit)
+		}
+	}
+	return ""
+}
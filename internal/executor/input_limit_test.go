@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/converter"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestExceedsInputLimit(t *testing.T) {
+	long := strings.Repeat("hello world ", 2000)
+	claudeBody := []byte(`{"model":"claude-sonnet-4","max_tokens":1024,"messages":[{"role":"user","content":"` + long + `"}]}`)
+	openaiBody := []byte(`{"model":"gpt-4o","messages":[{"role":"user","content":"` + long + `"}]}`)
+
+	provider := func(maxBytes, maxTokens uint64) *domain.Provider {
+		return &domain.Provider{Config: &domain.ProviderConfig{MaxInputBytes: maxBytes, MaxInputTokens: maxTokens}}
+	}
+	tests := []struct {
+		name       string
+		provider   *domain.Provider
+		body       []byte
+		clientType domain.ClientType
+		want       string // 跳过原因中应包含的内容，空表示不跳过
+	}{
+		{"no limit", provider(0, 0), claudeBody, domain.ClientTypeClaude, ""},
+		{"nil config", &domain.Provider{}, claudeBody, domain.ClientTypeClaude, ""},
+		{"bytes within limit", provider(1<<20, 0), claudeBody, domain.ClientTypeClaude, ""},
+		{"bytes exceeded", provider(1000, 0), claudeBody, domain.ClientTypeClaude, "bytes"},
+		{"tokens within limit", provider(0, 1_000_000), claudeBody, domain.ClientTypeClaude, ""},
+		{"tokens exceeded", provider(0, 1000), claudeBody, domain.ClientTypeClaude, "input tokens"},
+		{"tokens exceeded after conversion", provider(0, 1000), openaiBody, domain.ClientTypeOpenAI, "input tokens"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := &inputSize{registry: converter.GetGlobalRegistry(), body: tt.body, clientType: tt.clientType}
+			got := exceedsInputLimit(tt.provider, tt.body, size)
+			if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+				t.Errorf("exceedsInputLimit = %q, want reason containing %q", got, tt.want)
+			}
+		})
+	}
+}
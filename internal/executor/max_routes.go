@@ -0,0 +1,51 @@
+package executor
+
+import (
+	"strconv"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// routeAttemptLimit caps how many distinct routes one request is tried on,
+// independent of each route's retry config. Routes skipped before any
+// attempt (rate limited, input too large, provider removed) don't count.
+type routeAttemptLimit struct {
+	max   int // 0 = unlimited
+	tried map[uint64]bool
+}
+
+func newRouteAttemptLimit(max int) *routeAttemptLimit {
+	return &routeAttemptLimit{max: max, tried: make(map[uint64]bool)}
+}
+
+// record marks a route as tried
+func (l *routeAttemptLimit) record(routeID uint64) {
+	l.tried[routeID] = true
+}
+
+// reached reports whether no further route may be tried
+func (l *routeAttemptLimit) reached() bool {
+	return l.max > 0 && len(l.tried) >= l.max
+}
+
+// getMaxRoutesAttempted returns the project's max_routes_attempted override,
+// falling back to the global setting; 0 means unlimited
+func (e *Executor) getMaxRoutesAttempted(projectID uint64) int {
+	if projectID != 0 && e.router != nil {
+		if max := e.router.ProjectMaxRoutesAttempted(projectID); max > 0 {
+			return max
+		}
+	}
+	if e.settingsRepo == nil {
+		return 0
+	}
+	val, err := e.settingsRepo.Get(domain.SettingKeyMaxRoutesAttempted)
+	if err != nil || val == "" {
+		return 0
+	}
+	max, err := strconv.Atoi(val)
+	if err != nil || max < 0 {
+		return 0
+	}
+	return max
+}
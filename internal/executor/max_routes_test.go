@@ -0,0 +1,51 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+type maxRoutesSettings struct {
+	repository.SystemSettingRepository
+	value string
+}
+
+func (s maxRoutesSettings) Get(key string) (string, error) {
+	if key == domain.SettingKeyMaxRoutesAttempted {
+		return s.value, nil
+	}
+	return "", nil
+}
+
+func TestRouteAttemptLimit(t *testing.T) {
+	l := newRouteAttemptLimit(2)
+	l.record(1)
+	// 同一路由（如回退模型）重复尝试只计一次
+	l.record(1)
+	if l.reached() {
+		t.Fatal("limit reached after one distinct route")
+	}
+	l.record(2)
+	if !l.reached() {
+		t.Error("limit not reached after two distinct routes")
+	}
+
+	unlimited := newRouteAttemptLimit(0)
+	for id := uint64(1); id <= 10; id++ {
+		unlimited.record(id)
+	}
+	if unlimited.reached() {
+		t.Error("max 0 should be unlimited")
+	}
+}
+
+func TestGetMaxRoutesAttempted(t *testing.T) {
+	for value, want := range map[string]int{"": 0, "3": 3, "-1": 0, "abc": 0} {
+		e := &Executor{settingsRepo: maxRoutesSettings{value: value}}
+		if got := e.getMaxRoutesAttempted(0); got != want {
+			t.Errorf("max_routes_attempted=%q: got %d, want %d", value, got, want)
+		}
+	}
+}
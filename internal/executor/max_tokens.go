@@ -0,0 +1,47 @@
+package executor
+
+import (
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// maxTokensPaths 各格式请求体中的输出 token 上限字段
+var maxTokensPaths = map[domain.ClientType][]string{
+	domain.ClientTypeClaude: {"max_tokens"},
+	domain.ClientTypeOpenAI: {"max_tokens", "max_completion_tokens"},
+	domain.ClientTypeCodex:  {"max_output_tokens"},
+	domain.ClientTypeGemini: {"generationConfig.maxOutputTokens"},
+}
+
+// providerMaxOutputTokens returns the provider's output token cap, 0 if unset
+func providerMaxOutputTokens(p *domain.Provider) uint64 {
+	if p == nil || p.Config == nil {
+		return 0
+	}
+	return p.Config.MaxOutputTokens
+}
+
+// clampMaxOutputTokens lowers the output token limit in body (in clientType
+// format) to limit when the client asked for more. It returns the rewritten
+// body and the largest requested value that was clamped, 0 if nothing changed.
+// A request without a limit is left alone so the upstream default applies.
+func clampMaxOutputTokens(body []byte, clientType domain.ClientType, limit uint64) ([]byte, uint64) {
+	if limit == 0 {
+		return body, 0
+	}
+	var clampedFrom uint64
+	for _, path := range maxTokensPaths[clientType] {
+		value := gjson.GetBytes(body, path)
+		if value.Type != gjson.Number || value.Uint() <= limit {
+			continue
+		}
+		updated, err := sjson.SetBytes(body, path, limit)
+		if err != nil {
+			continue
+		}
+		body = updated
+		clampedFrom = max(clampedFrom, value.Uint())
+	}
+	return body, clampedFrom
+}
@@ -0,0 +1,43 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/tidwall/gjson"
+)
+
+func TestClampMaxOutputTokens(t *testing.T) {
+	tests := []struct {
+		name        string
+		clientType  domain.ClientType
+		body        string
+		limit       uint64
+		path        string
+		want        int64
+		wantClamped uint64
+	}{
+		{"claude over", domain.ClientTypeClaude, `{"max_tokens":100000}`, 4096, "max_tokens", 4096, 100000},
+		{"claude under", domain.ClientTypeClaude, `{"max_tokens":1000}`, 4096, "max_tokens", 1000, 0},
+		{"openai max_tokens", domain.ClientTypeOpenAI, `{"max_tokens":8000}`, 4096, "max_tokens", 4096, 8000},
+		{"openai max_completion_tokens", domain.ClientTypeOpenAI, `{"max_completion_tokens":9000}`, 4096, "max_completion_tokens", 4096, 9000},
+		{"codex", domain.ClientTypeCodex, `{"max_output_tokens":50000}`, 4096, "max_output_tokens", 4096, 50000},
+		{"gemini", domain.ClientTypeGemini, `{"generationConfig":{"maxOutputTokens":65536}}`, 4096, "generationConfig.maxOutputTokens", 4096, 65536},
+		{"absent", domain.ClientTypeOpenAI, `{"model":"gpt-4o"}`, 4096, "max_tokens", 0, 0},
+		{"no limit", domain.ClientTypeClaude, `{"max_tokens":100000}`, 0, "max_tokens", 100000, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, clampedFrom := clampMaxOutputTokens([]byte(tt.body), tt.clientType, tt.limit)
+			if clampedFrom != tt.wantClamped {
+				t.Errorf("clampedFrom = %d, want %d", clampedFrom, tt.wantClamped)
+			}
+			if got := gjson.GetBytes(out, tt.path).Int(); got != tt.want {
+				t.Errorf("%s = %d, want %d (body %s)", tt.path, got, tt.want, out)
+			}
+			if tt.wantClamped == 0 && string(out) != tt.body {
+				t.Errorf("body changed without clamping: %s", out)
+			}
+		})
+	}
+}
@@ -0,0 +1,25 @@
+package executor
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// minifyRequestBody strips insignificant whitespace from a JSON request body.
+// Only whitespace between tokens is removed: strings (including their escapes),
+// number literals and key order are copied byte for byte. ok is false when the
+// body isn't valid JSON or is already compact.
+func minifyRequestBody(body []byte) ([]byte, bool) {
+	if len(body) == 0 {
+		return nil, false
+	}
+	var buf bytes.Buffer
+	buf.Grow(len(body))
+	if err := json.Compact(&buf, body); err != nil {
+		return nil, false
+	}
+	if buf.Len() >= len(body) {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
@@ -0,0 +1,18 @@
+package executor
+
+import "testing"
+
+func TestMinifyRequestBody(t *testing.T) {
+	body := "{\n  \"model\": \"claude\",\n  \"messages\": [\n    {\"role\": \"user\", \"content\": \"a  b\\n\\u00e9 \\\"q\\\" </x>\"}\n  ],\n  \"temperature\": 1.50,\n  \"max_tokens\": 1e3\n}\n"
+	want := `{"model":"claude","messages":[{"role":"user","content":"a  b\n\u00e9 \"q\" </x>"}],"temperature":1.50,"max_tokens":1e3}`
+	got, ok := minifyRequestBody([]byte(body))
+	if !ok || string(got) != want {
+		t.Errorf("minified = %s (ok=%v)\nwant %s", got, ok, want)
+	}
+
+	for _, body := range []string{"", want, `{"model": "x"`, "not json"} {
+		if got, ok := minifyRequestBody([]byte(body)); ok {
+			t.Errorf("minifyRequestBody(%q) = %s, want unchanged", body, got)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package executor
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	ctxutil "github.com/awsl-project/maxx/internal/context"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestMatchModelFallback(t *testing.T) {
+	chains := []domain.ModelFallback{
+		{Pattern: "gpt-4o", Fallbacks: []string{"gpt-4o-mini", "claude-haiku"}},
+		{Pattern: "claude-*", Fallbacks: []string{"claude-sonnet-4", "claude-haiku"}},
+	}
+
+	tests := []struct {
+		model string
+		want  []string
+	}{
+		{"gpt-4o", []string{"gpt-4o-mini", "claude-haiku"}},
+		{"gpt-4o-mini", nil},
+		// 回退链中与请求模型相同的项会被跳过
+		{"claude-sonnet-4", []string{"claude-haiku"}},
+		{"gemini-2.5-pro", nil},
+	}
+	for _, tt := range tests {
+		if got := domain.MatchModelFallback(chains, tt.model); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("MatchModelFallback(%q) = %v, want %v", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestResolveModelFallbacksPrefersToken(t *testing.T) {
+	e := &Executor{}
+	ctx := ctxutil.WithModelFallbacks(context.Background(), []domain.ModelFallback{
+		{Pattern: "gpt-4o", Fallbacks: []string{"gpt-4o-mini"}},
+	})
+
+	// projectID 为 0 时不会查询项目配置，直接使用 token 的回退链
+	if got := e.resolveModelFallbacks(ctx, 0, "gpt-4o"); !reflect.DeepEqual(got, []string{"gpt-4o-mini"}) {
+		t.Errorf("resolveModelFallbacks = %v, want [gpt-4o-mini]", got)
+	}
+}
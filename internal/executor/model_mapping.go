@@ -0,0 +1,50 @@
+package executor
+
+import (
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/pricing"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+// ModelMappingResolution is the outcome of resolving a model against the model
+// mappings in scope
+type ModelMappingResolution struct {
+	NormalizedModel string
+	Rule            *domain.ModelMapping // nil when no rule matched
+	Target          string               // the mapped model, or the model itself when no rule matched
+	Candidates      int                  // rules in scope
+}
+
+// ResolveModelMapping resolves model against the mappings matching query. This is
+// the single rule-resolution path: the executor maps each route's model through
+// it and the admin mapping test previews the same result. Rules match the raw
+// model name or its normalized form, so rules written for full model names keep
+// working. On a lookup error the model maps to itself.
+func ResolveModelMapping(repo repository.ModelMappingRepository, query *domain.ModelMappingQuery, model string) (*ModelMappingResolution, error) {
+	res := &ModelMappingResolution{
+		NormalizedModel: pricing.GlobalNormalizer().Normalize(model),
+		Target:          model,
+	}
+	mappings, err := repo.ListByQuery(query)
+	if err != nil {
+		return res, err
+	}
+	res.Candidates = len(mappings)
+	if m := domain.MatchModelMapping(mappings, model, res.NormalizedModel); m != nil {
+		res.Rule = m
+		res.Target = m.Target
+	}
+	return res, nil
+}
+
+// RouteModelMappingQuery is the mapping query of a request on route
+func RouteModelMappingQuery(route *domain.Route, provider *domain.Provider, clientType domain.ClientType, projectID, apiTokenID uint64) *domain.ModelMappingQuery {
+	return &domain.ModelMappingQuery{
+		ClientType:   clientType,
+		ProviderType: provider.Type,
+		ProviderID:   provider.ID,
+		ProjectID:    projectID,
+		RouteID:      route.ID,
+		APITokenID:   apiTokenID,
+	}
+}
@@ -0,0 +1,37 @@
+package executor
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+func TestMapModelRouteScopeOverridesGlobal(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	mappingRepo := sqlite.NewModelMappingRepository(db)
+	for _, m := range []*domain.ModelMapping{
+		{Scope: domain.ModelMappingScopeGlobal, Pattern: "claude-*", Target: "global-target", Priority: 0},
+		{Scope: domain.ModelMappingScopeRoute, RouteID: 2, Pattern: "claude-*", Target: "route-target", Priority: 5},
+	} {
+		if err := mappingRepo.Create(m); err != nil {
+			t.Fatalf("create mapping: %v", err)
+		}
+	}
+
+	e := &Executor{modelMappingRepo: mappingRepo}
+	provider := &domain.Provider{ID: 1, Type: "custom"}
+	if got := e.mapModel("claude-sonnet-4", &domain.Route{ID: 2}, provider, domain.ClientTypeClaude, 0, 0); got != "route-target" {
+		t.Errorf("route 2 maps to %q, want route-target", got)
+	}
+	if got := e.mapModel("claude-sonnet-4", &domain.Route{ID: 3}, provider, domain.ClientTypeClaude, 0, 0); got != "global-target" {
+		t.Errorf("route 3 maps to %q, want global-target", got)
+	}
+	if got := e.mapModel("gpt-4o", &domain.Route{ID: 2}, provider, domain.ClientTypeClaude, 0, 0); got != "gpt-4o" {
+		t.Errorf("unmapped model maps to %q, want gpt-4o", got)
+	}
+}
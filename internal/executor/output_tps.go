@@ -0,0 +1,24 @@
+package executor
+
+import (
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// minTPSStreamDuration 首字到结束短于该时长的流不计算 TPS，否则极短的流会得到失真的超大速度
+const minTPSStreamDuration = 200 * time.Millisecond
+
+// outputTPS returns the attempt's output tokens per second over the streaming
+// phase, from first token to end. It returns 0 when the speed can't be measured:
+// non-streaming attempts (no TTFT), no output tokens, or streams too short.
+func outputTPS(a *domain.ProxyUpstreamAttempt) float64 {
+	if a.TTFT <= 0 || a.OutputTokenCount == 0 {
+		return 0
+	}
+	streamed := a.Duration - a.TTFT
+	if streamed < minTPSStreamDuration {
+		return 0
+	}
+	return float64(a.OutputTokenCount) / streamed.Seconds()
+}
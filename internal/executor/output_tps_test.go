@@ -0,0 +1,33 @@
+package executor
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestOutputTPS(t *testing.T) {
+	tests := []struct {
+		name     string
+		duration time.Duration
+		ttft     time.Duration
+		tokens   uint64
+		want     float64
+	}{
+		{"streamed", 3 * time.Second, time.Second, 100, 50},
+		{"non-stream has no ttft", 2 * time.Second, 0, 100, 0},
+		{"no output tokens", 3 * time.Second, time.Second, 0, 0},
+		{"stream too short", time.Second + 100*time.Millisecond, time.Second, 10, 0},
+		{"ttft equals duration", time.Second, time.Second, 10, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &domain.ProxyUpstreamAttempt{Duration: tt.duration, TTFT: tt.ttft, OutputTokenCount: tt.tokens}
+			if got := outputTPS(a); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("outputTPS = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
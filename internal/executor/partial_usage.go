@@ -0,0 +1,110 @@
+package executor
+
+import (
+	"strings"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/tokenizer"
+	"github.com/tidwall/gjson"
+)
+
+// completeCancelledStreamUsage fills in the usage of a stream the client
+// disconnected from. Upstreams report the final usage only at the end of a
+// stream, so what the adapter extracted from the partial stream (typically the
+// input tokens of message_start and a placeholder output count) undercounts:
+//   - output tokens are raised to an estimate of the content already
+//     delivered to the client (text, tool arguments and reasoning)
+//   - input tokens, if none were reported, are estimated from the request,
+//     since the upstream has processed the whole prompt
+//
+// Reported counts are never lowered. Returns whether any count was estimated.
+func completeCancelledStreamUsage(a *domain.ProxyUpstreamAttempt, clientType domain.ClientType, delivered string, size *inputSize) bool {
+	text, reasoning := deliveredStreamContent(clientType, delivered)
+	estimator := tokenizer.NewEstimator()
+	var outputTokens, reasoningTokens uint64
+	if text != "" {
+		outputTokens += uint64(estimator.EstimateTextTokens(text))
+	}
+	if reasoning != "" {
+		reasoningTokens = uint64(estimator.EstimateTextTokens(reasoning))
+		outputTokens += reasoningTokens
+	}
+
+	estimated := false
+	if outputTokens > a.OutputTokenCount {
+		a.OutputTokenCount = outputTokens
+		estimated = true
+	}
+	if reasoningTokens > a.ReasoningTokenCount {
+		a.ReasoningTokenCount = reasoningTokens
+		estimated = true
+	}
+	if a.InputTokenCount == 0 && a.CacheReadCount == 0 && size != nil {
+		if tokens := size.estimatedTokens(); tokens > 0 {
+			a.InputTokenCount = uint64(tokens)
+			estimated = true
+		}
+	}
+	return estimated
+}
+
+// deliveredStreamContent collects the generated content of a client-format SSE
+// body: output text plus tool call arguments, and reasoning separately
+func deliveredStreamContent(clientType domain.ClientType, body string) (text, reasoning string) {
+	var out, thought strings.Builder
+	add := func(b *strings.Builder, r gjson.Result) {
+		// 数组逐项拼接，单个值只迭代一次
+		r.ForEach(func(_, v gjson.Result) bool {
+			b.WriteString(v.String())
+			return true
+		})
+	}
+
+	for _, line := range strings.Split(body, "\n") {
+		data, ok := strings.CutPrefix(strings.TrimSpace(line), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if !gjson.Valid(data) {
+			continue
+		}
+		root := gjson.Parse(data)
+		switch clientType {
+		case domain.ClientTypeClaude:
+			if root.Get("type").String() == "content_block_delta" {
+				add(&out, root.Get("delta.text"))
+				add(&out, root.Get("delta.partial_json"))
+				add(&thought, root.Get("delta.thinking"))
+			}
+		case domain.ClientTypeOpenAI:
+			add(&out, root.Get("choices.#.delta.content"))
+			add(&out, root.Get("choices.#.delta.tool_calls.#.function.arguments|@flatten"))
+			add(&thought, root.Get("choices.#.delta.reasoning_content"))
+		case domain.ClientTypeCodex:
+			switch root.Get("type").String() {
+			case "response.output_text.delta", "response.function_call_arguments.delta":
+				add(&out, root.Get("delta"))
+			case "response.reasoning_text.delta", "response.reasoning_summary_text.delta":
+				add(&thought, root.Get("delta"))
+			}
+		case domain.ClientTypeGemini:
+			// Gemini CLI 的响应包在 response 字段中
+			if r := root.Get("response"); r.Exists() {
+				root = r
+			}
+			root.Get("candidates.#.content.parts|@flatten").ForEach(func(_, part gjson.Result) bool {
+				switch {
+				case part.Get("thought").Bool():
+					thought.WriteString(part.Get("text").String())
+				case part.Get("functionCall").Exists():
+					out.WriteString(part.Get("functionCall.args").Raw)
+				default:
+					out.WriteString(part.Get("text").String())
+				}
+				return true
+			})
+		}
+	}
+	return out.String(), thought.String()
+}
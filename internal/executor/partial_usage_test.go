@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/converter"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/tokenizer"
+)
+
+// cancellingStreamAdapter streams a Claude response one text token at a time
+// and simulates the client disconnecting after n tokens
+type cancellingStreamAdapter struct {
+	n      int
+	cancel context.CancelFunc
+}
+
+func (a *cancellingStreamAdapter) SupportedClientTypes() []domain.ClientType {
+	return []domain.ClientType{domain.ClientTypeClaude}
+}
+
+func (a *cancellingStreamAdapter) Execute(ctx context.Context, w http.ResponseWriter, req *http.Request, p *domain.Provider) error {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\",\"message\":{\"usage\":{\"input_tokens\":25,\"output_tokens\":1}}}\n\n")
+	fmt.Fprint(w, "event: content_block_start\ndata: {\"type\":\"content_block_start\",\"index\":0,\"content_block\":{\"type\":\"text\",\"text\":\"\"}}\n\n")
+	for i := 0; i < a.n; i++ {
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"token \"}}\n\n")
+		w.(http.Flusher).Flush()
+	}
+	a.cancel()
+	return domain.NewProxyErrorWithMessage(ctx.Err(), false, "client disconnected")
+}
+
+func TestCompleteCancelledStreamUsage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	adp := &cancellingStreamAdapter{n: 40, cancel: cancel}
+	capture := NewResponseCapture(httptest.NewRecorder())
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	if err := executeWithStallDetection(ctx, adp, capture, req, &domain.Provider{}, 0); err == nil || ctx.Err() == nil {
+		t.Fatalf("stream not cancelled: err=%v", err)
+	}
+
+	// 适配器只能从已收到的 message_start 中提取用量
+	attempt := &domain.ProxyUpstreamAttempt{InputTokenCount: 25, OutputTokenCount: 1}
+	if !completeCancelledStreamUsage(attempt, domain.ClientTypeClaude, capture.Body(), nil) {
+		t.Fatal("usage not estimated")
+	}
+	want := uint64(tokenizer.NewEstimator().EstimateTextTokens(strings.Repeat("token ", 40)))
+	if attempt.OutputTokenCount != want || attempt.InputTokenCount != 25 {
+		t.Errorf("usage = input %d, output %d; want input 25, output %d", attempt.InputTokenCount, attempt.OutputTokenCount, want)
+	}
+
+	// 上游已报告的更大用量不会被下调
+	attempt = &domain.ProxyUpstreamAttempt{InputTokenCount: 25, OutputTokenCount: want + 100}
+	if completeCancelledStreamUsage(attempt, domain.ClientTypeClaude, capture.Body(), nil) || attempt.OutputTokenCount != want+100 {
+		t.Errorf("reported usage changed: output %d", attempt.OutputTokenCount)
+	}
+}
+
+func TestCompleteCancelledStreamUsageEstimatesInput(t *testing.T) {
+	// OpenAI 流只在结尾返回 usage：输入按请求估算
+	body := []byte(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"Write a long story about a lighthouse keeper."}]}`)
+	size := &inputSize{registry: converter.GetGlobalRegistry(), body: body, clientType: domain.ClientTypeOpenAI}
+	stream := `data: {"choices":[{"index":0,"delta":{"reasoning_content":"Plan the plot."}}]}` + "\n\n" +
+		`data: {"choices":[{"index":0,"delta":{"content":"Once upon a time"}}]}` + "\n\n"
+
+	attempt := &domain.ProxyUpstreamAttempt{}
+	if !completeCancelledStreamUsage(attempt, domain.ClientTypeOpenAI, stream, size) {
+		t.Fatal("usage not estimated")
+	}
+	estimator := tokenizer.NewEstimator()
+	reasoning := uint64(estimator.EstimateTextTokens("Plan the plot."))
+	output := uint64(estimator.EstimateTextTokens("Once upon a time")) + reasoning
+	if attempt.InputTokenCount != uint64(size.estimatedTokens()) || attempt.InputTokenCount == 0 {
+		t.Errorf("input = %d, want %d", attempt.InputTokenCount, size.estimatedTokens())
+	}
+	if attempt.OutputTokenCount != output || attempt.ReasoningTokenCount != reasoning {
+		t.Errorf("output = %d (reasoning %d), want %d (%d)", attempt.OutputTokenCount, attempt.ReasoningTokenCount, output, reasoning)
+	}
+}
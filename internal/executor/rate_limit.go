@@ -0,0 +1,46 @@
+package executor
+
+import (
+	"context"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/ratelimit"
+)
+
+// Provider 达到速率上限时的处理方式
+const (
+	rateLimitModeSkip  = "skip"  // 跳到下一条路由（默认）
+	rateLimitModeQueue = "queue" // 排队等待窗口释放
+)
+
+// acquireProviderRateLimit reports whether an attempt on p may be dispatched.
+// In skip mode a provider over its RPM/TPM cap returns false so the caller
+// moves to the next route; in queue mode it waits for the window, bounded by
+// ctx, and returns ctx's error if the request is cancelled or times out first.
+func (e *Executor) acquireProviderRateLimit(ctx context.Context, p *domain.Provider) (bool, error) {
+	if p.Config == nil || !p.Config.RateLimit.Enabled() {
+		return true, nil
+	}
+	limit := p.Config.RateLimit
+
+	if e.getProviderRateLimitMode() == rateLimitModeQueue {
+		if err := ratelimit.Default().Wait(ctx, p.ID, limit); err != nil {
+			return false, err
+		}
+		return true, nil
+	}
+	ok, _ := ratelimit.Default().Reserve(p.ID, limit)
+	return ok, nil
+}
+
+// getProviderRateLimitMode 获取速率上限的处理方式
+func (e *Executor) getProviderRateLimitMode() string {
+	if e.settingsRepo == nil {
+		return rateLimitModeSkip
+	}
+	val, err := e.settingsRepo.Get(domain.SettingKeyProviderRateLimitMode)
+	if err != nil || val != rateLimitModeQueue {
+		return rateLimitModeSkip
+	}
+	return rateLimitModeQueue
+}
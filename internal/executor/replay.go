@@ -0,0 +1,153 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	ctxutil "github.com/awsl-project/maxx/internal/context"
+	"github.com/awsl-project/maxx/internal/converter"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/tidwall/gjson"
+)
+
+// ErrRequestDetailMissing is returned when a request cannot be replayed because
+// its original request info was not retained
+var ErrRequestDetailMissing = errors.New("request detail not retained")
+
+// replayHeaderSkip lists client headers that are not replayed: credentials are
+// supplied by the provider adapter, and maxx control headers would change routing
+var replayHeaderSkip = map[string]bool{
+	"host":               true,
+	"authorization":      true,
+	"x-api-key":          true,
+	"x-goog-api-key":     true,
+	"content-length":     true,
+	"x-maxx-project-id":  true,
+	"x-maxx-billable":    true,
+	"x-maxx-client-type": true,
+}
+
+// replayRecordKey carries a *replayRecord through Execute so the replay caller
+// can read back the proxy request that Execute created
+type replayRecordKey struct{}
+
+type replayRecord struct {
+	proxyReq *domain.ProxyRequest
+}
+
+// ReplayOnRoute replays a recorded request on the given route only (no failover,
+// no model fallback, no project binding wait). The replay is recorded as a new
+// non-billable proxy request tagged with comparisonTag and not attributed to the
+// original API token. Returns the new request record and the assistant text of
+// the response.
+func (e *Executor) ReplayOnRoute(ctx context.Context, original *domain.ProxyRequest, routeID uint64, comparisonTag string) (*domain.ProxyRequest, string, error) {
+	info := original.RequestInfo
+	if info == nil || info.Body == "" {
+		return nil, "", ErrRequestDetailMissing
+	}
+
+	method := info.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	body := []byte(info.Body)
+	req, err := http.NewRequestWithContext(ctx, method, info.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	for k, v := range info.Headers {
+		if !replayHeaderSkip[strings.ToLower(k)] {
+			req.Header.Set(k, v)
+		}
+	}
+	if host, ok := info.Headers["Host"]; ok {
+		req.Host = host
+	}
+
+	record := &replayRecord{}
+	ctx = context.WithValue(ctx, replayRecordKey{}, record)
+	ctx = ctxutil.WithClientType(ctx, original.ClientType)
+	ctx = ctxutil.WithSessionID(ctx, original.SessionID)
+	ctx = ctxutil.WithRequestModel(ctx, original.RequestModel)
+	ctx = ctxutil.WithRequestBody(ctx, body)
+	ctx = ctxutil.WithRequestHeaders(ctx, req.Header)
+	ctx = ctxutil.WithRequestURI(ctx, info.URL)
+	ctx = ctxutil.WithIsStream(ctx, original.IsStream)
+	ctx = ctxutil.WithProjectID(ctx, original.ProjectID)
+	ctx = ctxutil.WithAPITokenID(ctx, original.APITokenID)
+	ctx = ctxutil.WithBillable(ctx, false)
+	ctx = ctxutil.WithRouteOverride(ctx, routeID)
+	ctx = ctxutil.WithComparisonTag(ctx, comparisonTag)
+
+	w := newBufferResponseWriter()
+	execErr := e.Execute(ctx, w, req.WithContext(ctx))
+	if record.proxyReq == nil {
+		// Execute 在创建请求记录之前就失败了
+		return nil, "", execErr
+	}
+
+	respBody := w.body.Bytes()
+	if original.IsStream {
+		if aggregated, err := converter.AggregateStream(original.ClientType, respBody); err == nil {
+			respBody = aggregated
+		}
+	}
+	return record.proxyReq, extractResponseText(original.ClientType, respBody), nil
+}
+
+// extractResponseText returns the assistant text of a non-streaming response
+func extractResponseText(clientType domain.ClientType, body []byte) string {
+	if !gjson.ValidBytes(body) {
+		return ""
+	}
+	var path string
+	switch clientType {
+	case domain.ClientTypeClaude:
+		path = `content.#(type=="text")#.text`
+	case domain.ClientTypeOpenAI:
+		path = "choices.#.message.content"
+	case domain.ClientTypeCodex:
+		path = `output.#(type=="message")#.content.#.text`
+	case domain.ClientTypeGemini:
+		path = "candidates.0.content.parts.#.text"
+	default:
+		return ""
+	}
+	var parts []string
+	collectStrings(gjson.GetBytes(body, path), &parts)
+	return strings.Join(parts, "")
+}
+
+func collectStrings(r gjson.Result, out *[]string) {
+	if r.IsArray() {
+		for _, item := range r.Array() {
+			collectStrings(item, out)
+		}
+		return
+	}
+	if r.Type == gjson.String {
+		*out = append(*out, r.String())
+	}
+}
+
+// bufferResponseWriter is an in-memory http.ResponseWriter used for replays
+type bufferResponseWriter struct {
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newBufferResponseWriter() *bufferResponseWriter {
+	return &bufferResponseWriter{header: make(http.Header), statusCode: http.StatusOK}
+}
+
+func (w *bufferResponseWriter) Header() http.Header { return w.header }
+
+func (w *bufferResponseWriter) Write(b []byte) (int, error) { return w.body.Write(b) }
+
+func (w *bufferResponseWriter) WriteHeader(code int) { w.statusCode = code }
+
+func (w *bufferResponseWriter) Flush() {}
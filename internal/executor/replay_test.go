@@ -0,0 +1,86 @@
+package executor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+func TestExtractResponseText(t *testing.T) {
+	tests := []struct {
+		name       string
+		clientType domain.ClientType
+		body       string
+		want       string
+	}{
+		{
+			"claude",
+			domain.ClientTypeClaude,
+			`{"content":[{"type":"thinking","thinking":"hmm"},{"type":"text","text":"Hello "},{"type":"text","text":"world"}]}`,
+			"Hello world",
+		},
+		{
+			"openai",
+			domain.ClientTypeOpenAI,
+			`{"choices":[{"message":{"role":"assistant","content":"Hi there"}}]}`,
+			"Hi there",
+		},
+		{
+			"codex",
+			domain.ClientTypeCodex,
+			`{"output":[{"type":"reasoning"},{"type":"message","content":[{"type":"output_text","text":"Done."}]}]}`,
+			"Done.",
+		},
+		{
+			"gemini",
+			domain.ClientTypeGemini,
+			`{"candidates":[{"content":{"parts":[{"text":"Bonjour"},{"text":" monde"}]}}]}`,
+			"Bonjour monde",
+		},
+		{"invalid json", domain.ClientTypeClaude, `event: message_start`, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extractResponseText(tt.clientType, []byte(tt.body)); got != tt.want {
+				t.Errorf("extractResponseText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplayOnRouteNotBilledToToken(t *testing.T) {
+	te := newTestExecutor(t, domain.ClientTypeClaude, nil, testUpstream{adapter: &staticAdapter{
+		clientType: domain.ClientTypeClaude,
+		body:       `{"type":"message","role":"assistant","content":[{"type":"text","text":"hi"}]}`,
+	}})
+	original := &domain.ProxyRequest{
+		ClientType:   domain.ClientTypeClaude,
+		RequestModel: "claude-sonnet-4",
+		APITokenID:   7,
+		Billable:     true,
+		RequestInfo: &domain.RequestInfo{
+			Method: "POST",
+			URL:    "/v1/messages",
+			Body:   `{"model":"claude-sonnet-4","messages":[{"role":"user","content":"hi"}]}`,
+		},
+	}
+
+	replayed, text, err := te.ReplayOnRoute(context.Background(), original, te.routes[0].ID, "cmp-1")
+	if err != nil {
+		t.Fatalf("ReplayOnRoute: %v", err)
+	}
+	if text != "hi" {
+		t.Errorf("text = %q, want hi", text)
+	}
+	stored, err := sqlite.NewProxyRequestRepository(te.db).GetByID(replayed.ID)
+	if err != nil {
+		t.Fatalf("get proxy request: %v", err)
+	}
+	// 对比重放不计费，也不计入原 Token 的用量与配额
+	if stored.Billable || stored.APITokenID != 0 || stored.ComparisonTag != "cmp-1" {
+		t.Errorf("replay = billable %v, token %d, tag %q; want non-billable, no token, cmp-1",
+			stored.Billable, stored.APITokenID, stored.ComparisonTag)
+	}
+}
@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"context"
+	"sort"
+
+	"github.com/awsl-project/maxx/internal/cooldown"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// Resolve reports how a request would be routed without dispatching it: the
+// candidate routes in order (including model fallbacks), the model mapping,
+// retry config, format conversion and stream mode on each, plus the cooldown
+// status of every provider involved. Nothing is recorded and no rate limit or
+// upstream request is taken. Model fallbacks of the API token are read from ctx.
+func (e *Executor) Resolve(ctx context.Context, clientType domain.ClientType, projectID uint64, requestModel string, apiTokenID uint64, stream bool) *domain.RequestResolution {
+	trace := &domain.RoutingTrace{}
+	result := &domain.RequestResolution{
+		ClientType:     clientType,
+		ProjectID:      projectID,
+		APITokenID:     apiTokenID,
+		RequestModel:   requestModel,
+		FallbackModels: e.resolveModelFallbacks(ctx, projectID, requestModel),
+		Routes:         []*domain.ResolvedRoute{},
+		Cooldowns:      []*domain.ResolvedProviderCooldown{},
+		Trace:          trace,
+	}
+
+	candidates, err := e.matchCandidates(ctx, clientType, projectID, requestModel, apiTokenID, trace)
+	if err != nil && len(candidates) == 0 {
+		result.Error = err.Error()
+	}
+
+	providers := make(map[uint64]*domain.Provider)
+	for _, c := range candidates {
+		route := c.Route
+		prov := c.Provider
+		providers[prov.ID] = prov
+
+		targetClientType := clientType
+		needsConversion := false
+		if supported := c.ProviderAdapter.SupportedClientTypes(); e.converter.NeedConvert(clientType, supported) {
+			if target := GetProviderTargetType(prov, supported, clientType); target != clientType {
+				targetClientType = target
+				needsConversion = true
+			}
+		}
+
+		result.Routes = append(result.Routes, &domain.ResolvedRoute{
+			Position:         len(result.Routes) + 1,
+			RouteID:          route.ID,
+			ProviderID:       prov.ID,
+			ProviderName:     prov.Name,
+			ProviderType:     prov.Type,
+			RequestModel:     c.model,
+			Fallback:         c.model != requestModel,
+			MappedModel:      e.mapModel(c.model, route, prov, clientType, projectID, apiTokenID),
+			NeedsConversion:  needsConversion,
+			TargetClientType: targetClientType,
+			UpstreamStream:   resolveUpstreamStream(stream, prov),
+			RetryConfig:      e.getRetryConfig(c.RetryConfig),
+		})
+	}
+
+	// Providers skipped by the router (cooldown, disabled client type...) only
+	// show up in the trace
+	for _, step := range trace.Steps {
+		if _, ok := providers[step.ProviderID]; ok || step.ProviderID == 0 {
+			continue
+		}
+		if prov := e.router.GetProvider(step.ProviderID); prov != nil {
+			providers[prov.ID] = prov
+		}
+	}
+
+	for _, prov := range providers {
+		status := &domain.ResolvedProviderCooldown{ProviderID: prov.ID, ProviderName: prov.Name}
+		if info := cooldown.Default().GetCooldownInfo(prov.ID, string(clientType), prov.Name); info != nil {
+			until := info.Until
+			status.InCooldown = true
+			status.Until = &until
+			status.Reason = string(info.Reason)
+		}
+		result.Cooldowns = append(result.Cooldowns, status)
+	}
+	sort.Slice(result.Cooldowns, func(i, j int) bool {
+		return result.Cooldowns[i].ProviderID < result.Cooldowns[j].ProviderID
+	})
+	return result
+}
@@ -0,0 +1,76 @@
+package executor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/cooldown"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+	"github.com/awsl-project/maxx/internal/router"
+)
+
+func TestResolveMatchesRouter(t *testing.T) {
+	te := newTestExecutor(t, domain.ClientTypeClaude, nil,
+		testUpstream{adapter: &staticAdapter{clientType: domain.ClientTypeClaude}},
+		testUpstream{adapter: &staticAdapter{clientType: domain.ClientTypeClaude}},
+		testUpstream{adapter: &staticAdapter{clientType: domain.ClientTypeClaude}},
+		testUpstream{adapter: &staticAdapter{clientType: domain.ClientTypeOpenAI}},
+	)
+	mapping := &domain.ModelMapping{Scope: domain.ModelMappingScopeRoute, RouteID: te.routes[1].ID, Pattern: "claude-*", Target: "mapped-model"}
+	if err := sqlite.NewModelMappingRepository(te.db).Create(mapping); err != nil {
+		t.Fatalf("create mapping: %v", err)
+	}
+	cooling := te.providers[2]
+	cooldown.Default().SetCooldownDuration(cooling.ID, "", time.Minute)
+
+	const model = "claude-sonnet-4"
+	resolution := te.Resolve(context.Background(), domain.ClientTypeClaude, 0, model, 0, false)
+	matched, err := te.router.Match(&router.MatchContext{ClientType: domain.ClientTypeClaude, RequestModel: model})
+	if err != nil {
+		t.Fatalf("Match: %v", err)
+	}
+
+	// 路由顺序与实际匹配一致，冷却中的 Provider 不在候选中
+	if len(resolution.Routes) != len(matched) || len(matched) != 3 {
+		t.Fatalf("resolved %d routes, router matched %d, want 3", len(resolution.Routes), len(matched))
+	}
+	for i, route := range resolution.Routes {
+		if route.Position != i+1 || route.RouteID != matched[i].Route.ID || route.ProviderID != matched[i].Provider.ID {
+			t.Errorf("route %d = %+v, router matched route %d", i, route, matched[i].Route.ID)
+		}
+		if route.ProviderID == cooling.ID {
+			t.Errorf("cooling provider %d resolved as a candidate", cooling.ID)
+		}
+	}
+
+	for _, route := range resolution.Routes {
+		wantModel, wantConversion := model, false
+		switch route.RouteID {
+		case te.routes[1].ID:
+			wantModel = "mapped-model"
+		case te.routes[3].ID:
+			wantConversion = true
+		}
+		if route.MappedModel != wantModel || route.NeedsConversion != wantConversion {
+			t.Errorf("route %d: mapped %q, conversion %v; want %q, %v", route.RouteID, route.MappedModel, route.NeedsConversion, wantModel, wantConversion)
+		}
+	}
+	if route := resolution.Routes[2]; route.TargetClientType != domain.ClientTypeOpenAI {
+		t.Errorf("converted route target = %s, want openai", route.TargetClientType)
+	}
+
+	// 冷却状态覆盖所有涉及的 Provider，包括被跳过的
+	if len(resolution.Cooldowns) != len(te.providers) {
+		t.Fatalf("cooldowns = %+v, want every provider", resolution.Cooldowns)
+	}
+	for _, status := range resolution.Cooldowns {
+		if status.InCooldown != (status.ProviderID == cooling.ID) {
+			t.Errorf("provider %d in cooldown = %v", status.ProviderID, status.InCooldown)
+		}
+	}
+	if status := resolution.Cooldowns[2]; status.Until == nil || time.Until(*status.Until) <= 0 {
+		t.Errorf("cooling provider status = %+v, want a future expiry", status)
+	}
+}
@@ -3,6 +3,9 @@ package executor
 import (
 	"bytes"
 	"net/http"
+	"strings"
+
+	"github.com/awsl-project/maxx/internal/converter"
 )
 
 // ResponseCapture wraps http.ResponseWriter to capture the response
@@ -12,6 +15,7 @@ type ResponseCapture struct {
 	statusCode int
 	body       bytes.Buffer
 	headers    http.Header
+	hasContent bool // 是否写入过 SSE 注释（心跳）以外的内容
 }
 
 // NewResponseCapture creates a new ResponseCapture wrapper
@@ -32,6 +36,10 @@ func (rc *ResponseCapture) WriteHeader(code int) {
 // Write captures the body and forwards to underlying writer
 func (rc *ResponseCapture) Write(b []byte) (int, error) {
 	rc.body.Write(b)
+	if !rc.hasContent {
+		text := string(b)
+		rc.hasContent = strings.TrimSpace(text) != "" && !converter.IsSSECommentOnly(text)
+	}
 	return rc.ResponseWriter.Write(b)
 }
 
@@ -57,6 +65,11 @@ func (rc *ResponseCapture) Body() string {
 	return rc.body.String()
 }
 
+// HasContent reports whether anything other than SSE heartbeats was written
+func (rc *ResponseCapture) HasContent() bool {
+	return rc.hasContent
+}
+
 // CapturedHeaders returns the headers that were set
 func (rc *ResponseCapture) CapturedHeaders() map[string]string {
 	result := make(map[string]string)
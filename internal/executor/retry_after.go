@@ -0,0 +1,58 @@
+package executor
+
+import (
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// defaultRetryAfterJitterPercent 未配置 retry_after_jitter_percent 时的抖动幅度
+const defaultRetryAfterJitterPercent = 20
+
+// retryAfterWait returns how long to wait before retrying after a Retry-After
+// hint: the hint ±jitterPercent, so requests throttled together don't all
+// retry at the same instant, clamped to maxWait (0 = no cap). ok is false when
+// the hint itself exceeds maxWait: retrying that much earlier would most likely
+// be throttled again, so the route is given up instead.
+func retryAfterWait(retryAfter time.Duration, jitterPercent int, maxWait time.Duration, rnd func() float64) (wait time.Duration, ok bool) {
+	if maxWait > 0 && retryAfter > maxWait {
+		return 0, false
+	}
+	wait = retryAfter
+	if jitterPercent > 0 {
+		spread := float64(retryAfter) * float64(jitterPercent) / 100
+		wait += time.Duration((rnd()*2 - 1) * spread)
+	}
+	if maxWait > 0 && wait > maxWait {
+		wait = maxWait
+	}
+	return wait, true
+}
+
+// getRetryAfterJitter returns the retry_after_jitter_percent and
+// retry_after_max_wait_seconds settings
+func (e *Executor) getRetryAfterJitter() (jitterPercent int, maxWait time.Duration) {
+	jitterPercent = defaultRetryAfterJitterPercent
+	if e.settingsRepo == nil {
+		return jitterPercent, 0
+	}
+	if val, err := e.settingsRepo.Get(domain.SettingKeyRetryAfterJitterPercent); err == nil && val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 && n <= 100 {
+			jitterPercent = n
+		}
+	}
+	if val, err := e.settingsRepo.Get(domain.SettingKeyRetryAfterMaxWaitSeconds); err == nil && val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			maxWait = time.Duration(n) * time.Second
+		}
+	}
+	return jitterPercent, maxWait
+}
+
+// retryAfterWait applies the configured jitter and cap to a Retry-After hint
+func (e *Executor) retryAfterWait(retryAfter time.Duration) (time.Duration, bool) {
+	jitterPercent, maxWait := e.getRetryAfterJitter()
+	return retryAfterWait(retryAfter, jitterPercent, maxWait, rand.Float64)
+}
@@ -0,0 +1,34 @@
+package executor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryAfterWait(t *testing.T) {
+	fixed := func(v float64) func() float64 { return func() float64 { return v } }
+	tests := []struct {
+		name       string
+		retryAfter time.Duration
+		jitter     int
+		maxWait    time.Duration
+		rnd        float64
+		want       time.Duration
+		ok         bool
+	}{
+		{"no jitter", 10 * time.Second, 0, 0, 0.9, 10 * time.Second, true},
+		{"lowest jitter", 10 * time.Second, 20, 0, 0, 8 * time.Second, true},
+		{"highest jitter", 10 * time.Second, 20, 0, 1, 12 * time.Second, true},
+		{"jitter clamped to cap", 10 * time.Second, 20, 11 * time.Second, 1, 11 * time.Second, true},
+		// Retry-After 本身超过上限时不提前重试，改为切换路由
+		{"hint above cap", 30 * time.Second, 20, 20 * time.Second, 0.5, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := retryAfterWait(tt.retryAfter, tt.jitter, tt.maxWait, fixed(tt.rnd))
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("retryAfterWait = (%v, %v), want (%v, %v)", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
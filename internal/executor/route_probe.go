@@ -0,0 +1,88 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	ctxutil "github.com/awsl-project/maxx/internal/context"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// probeMaxTokens 路由测试请求的输出上限，够判断路由可用即可
+const probeMaxTokens = 64
+
+// ProbeRoute sends a canned non-streaming request with prompt to the given route
+// only. The probe is pinned: no failover, no retry, no model fallback, and its
+// outcome does not touch the provider's cooldown or failure counts. It is
+// recorded as a proxy request tagged with tag and billed normally.
+// Returns the request record (nil if Execute failed before creating it).
+func (e *Executor) ProbeRoute(ctx context.Context, routeID uint64, clientType domain.ClientType, projectID uint64, model, prompt, tag string) (*domain.ProxyRequest, error) {
+	path, body, err := probeRequest(clientType, model, prompt)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	record := &replayRecord{}
+	ctx = context.WithValue(ctx, replayRecordKey{}, record)
+	ctx = ctxutil.WithClientType(ctx, clientType)
+	ctx = ctxutil.WithRequestModel(ctx, model)
+	ctx = ctxutil.WithRequestBody(ctx, body)
+	ctx = ctxutil.WithRequestHeaders(ctx, req.Header)
+	ctx = ctxutil.WithRequestURI(ctx, path)
+	ctx = ctxutil.WithIsStream(ctx, false)
+	ctx = ctxutil.WithProjectID(ctx, projectID)
+	ctx = ctxutil.WithRouteOverride(ctx, routeID)
+	ctx = ctxutil.WithRouteProbe(ctx, true)
+	ctx = ctxutil.WithComparisonTag(ctx, tag)
+
+	execErr := e.Execute(ctx, newBufferResponseWriter(), req.WithContext(ctx))
+	return record.proxyReq, execErr
+}
+
+// probeRequest builds the request path and body of a minimal single-turn
+// request in the client type's native format
+func probeRequest(clientType domain.ClientType, model, prompt string) (string, []byte, error) {
+	var path string
+	var payload map[string]any
+	switch clientType {
+	case domain.ClientTypeClaude:
+		path = "/v1/messages"
+		payload = map[string]any{
+			"model":      model,
+			"max_tokens": probeMaxTokens,
+			"messages":   []map[string]any{{"role": "user", "content": prompt}},
+		}
+	case domain.ClientTypeOpenAI:
+		path = "/v1/chat/completions"
+		payload = map[string]any{
+			"model":      model,
+			"max_tokens": probeMaxTokens,
+			"messages":   []map[string]any{{"role": "user", "content": prompt}},
+		}
+	case domain.ClientTypeCodex:
+		path = "/v1/responses"
+		payload = map[string]any{
+			"model":             model,
+			"max_output_tokens": probeMaxTokens,
+			"input":             []map[string]any{{"role": "user", "content": prompt}},
+		}
+	case domain.ClientTypeGemini:
+		path = "/v1beta/models/" + model + ":generateContent"
+		payload = map[string]any{
+			"contents":         []map[string]any{{"role": "user", "parts": []map[string]any{{"text": prompt}}}},
+			"generationConfig": map[string]any{"maxOutputTokens": probeMaxTokens},
+		}
+	default:
+		return "", nil, fmt.Errorf("%w: unsupported client type %q", domain.ErrInvalidInput, clientType)
+	}
+	body, err := json.Marshal(payload)
+	return path, body, err
+}
@@ -0,0 +1,29 @@
+package executor
+
+import (
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// newRoutingTrace returns an empty trace when routing_trace_enabled is on,
+// nil otherwise (RoutingTrace.Add is a no-op on nil)
+func (e *Executor) newRoutingTrace() *domain.RoutingTrace {
+	if e.settingsRepo == nil {
+		return nil
+	}
+	val, err := e.settingsRepo.Get(domain.SettingKeyRoutingTraceEnabled)
+	if err != nil || val != "true" {
+		return nil
+	}
+	return &domain.RoutingTrace{}
+}
+
+// routeTraceStep builds a trace step for a candidate route
+func routeTraceStep(action string, c routeCandidate, reason string) domain.RoutingTraceStep {
+	return domain.RoutingTraceStep{
+		Action:     action,
+		RouteID:    c.Route.ID,
+		ProviderID: c.Provider.ID,
+		Model:      c.model,
+		Reason:     reason,
+	}
+}
@@ -0,0 +1,228 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/awsl-project/maxx/internal/converter"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/usage"
+)
+
+// softFailureMaxBytes 软失败匹配最多累计的响应字节数，超过后不再匹配，缓冲内容直接发给客户端
+const softFailureMaxBytes = 8 << 10
+
+// ErrSoftFailure is the cancel cause of an attempt whose 2xx response matched one
+// of the provider's soft failure patterns
+var ErrSoftFailure = errors.New("upstream soft failure")
+
+// ErrEmptyResponse is returned for a 2xx response without any output when the
+// provider has retry_empty_responses enabled
+var ErrEmptyResponse = errors.New("upstream returned an empty response")
+
+// softFailureRegexps caches compiled provider patterns (nil for invalid ones)
+var softFailureRegexps sync.Map
+
+// getSoftFailureRegexps compiles the provider's soft failure patterns, skipping invalid ones
+func getSoftFailureRegexps(p *domain.Provider) []*regexp.Regexp {
+	if p == nil || p.Config == nil || len(p.Config.SoftFailurePatterns) == 0 {
+		return nil
+	}
+	var res []*regexp.Regexp
+	for _, pattern := range p.Config.SoftFailurePatterns {
+		if pattern == "" {
+			continue
+		}
+		if v, ok := softFailureRegexps.Load(pattern); ok {
+			if re := v.(*regexp.Regexp); re != nil {
+				res = append(res, re)
+			}
+			continue
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			log.Printf("[Executor] Invalid soft failure pattern %q for provider %d: %v", pattern, p.ID, err)
+			re = nil
+		}
+		softFailureRegexps.Store(pattern, re)
+		if re != nil {
+			res = append(res, re)
+		}
+	}
+	return res
+}
+
+// softFailureWriter holds back the start of a 2xx response until it either
+// matches a soft failure pattern or grows past softFailureMaxBytes. On a match
+// nothing has reached the client yet, so the attempt can fail over cleanly;
+// otherwise the held bytes are released and the rest passes straight through.
+// Patterns are matched against the raw upstream bytes (SSE/JSON as sent).
+// With checkEmpty, a response that is still held when the attempt succeeds is
+// also rejected if it has no output (see isEmptyResponse).
+type softFailureWriter struct {
+	http.ResponseWriter
+	patterns []*regexp.Regexp
+	cancel   context.CancelCauseFunc
+
+	checkEmpty   bool
+	usageMapping *domain.UsageFieldMapping // Provider 的 usage 字段映射，用于判断输出 token
+
+	header   http.Header // 创建时的响应头快照，命中后恢复
+	status   int         // 0 表示尚未写入状态码
+	buf      bytes.Buffer
+	released bool
+	matched  *regexp.Regexp
+}
+
+// withSoftFailureDetection wraps w so the provider's soft failure patterns are
+// checked against the response. The returned context is cancelled on a match.
+func withSoftFailureDetection(ctx context.Context, w http.ResponseWriter, patterns []*regexp.Regexp) (context.Context, *softFailureWriter) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	return ctx, &softFailureWriter{
+		ResponseWriter: w,
+		patterns:       patterns,
+		cancel:         cancel,
+		header:         w.Header().Clone(),
+	}
+}
+
+// WriteHeader delays the status until the response is released; non-2xx
+// responses are not soft failures and are released immediately
+func (sw *softFailureWriter) WriteHeader(code int) {
+	if sw.released {
+		sw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if sw.status != 0 {
+		return
+	}
+	sw.status = code
+	if code < 200 || code >= 300 {
+		_ = sw.release()
+	}
+}
+
+func (sw *softFailureWriter) Write(b []byte) (int, error) {
+	if sw.matched != nil {
+		return 0, ErrSoftFailure
+	}
+	if sw.released {
+		return sw.ResponseWriter.Write(b)
+	}
+	if sw.status == 0 {
+		sw.status = http.StatusOK
+	}
+	sw.buf.Write(b)
+	for _, re := range sw.patterns {
+		if re.Match(sw.buf.Bytes()) {
+			sw.matched = re
+			sw.cancel(ErrSoftFailure)
+			return 0, ErrSoftFailure
+		}
+	}
+	if sw.buf.Len() >= softFailureMaxBytes {
+		if err := sw.release(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush implements http.Flusher; held content is not flushed until released
+func (sw *softFailureWriter) Flush() {
+	if !sw.released {
+		return
+	}
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// release forwards the held status and bytes and switches to pass-through
+func (sw *softFailureWriter) release() error {
+	if sw.released {
+		return nil
+	}
+	sw.released = true
+	if sw.status != 0 {
+		sw.ResponseWriter.WriteHeader(sw.status)
+	}
+	if sw.buf.Len() > 0 {
+		_, err := sw.ResponseWriter.Write(sw.buf.Bytes())
+		sw.buf.Reset()
+		if err != nil {
+			return err
+		}
+		sw.Flush()
+	}
+	return nil
+}
+
+// finish completes the attempt: a matched response becomes a retryable server
+// error (with the response headers restored so the next attempt starts clean).
+// Held bytes of a failed attempt are dropped so a retry starts clean too;
+// on success whatever is still held is released to the client.
+func (sw *softFailureWriter) finish(err error) error {
+	defer sw.cancel(nil)
+	if sw.matched != nil {
+		sw.restoreHeader()
+		return &domain.ProxyError{
+			Err:            ErrSoftFailure,
+			Retryable:      true,
+			IsServerError:  true,
+			HTTPStatusCode: sw.status,
+			Message:        "response matched soft failure pattern " + sw.matched.String(),
+		}
+	}
+	if err != nil {
+		if !sw.released {
+			sw.buf.Reset()
+		}
+		return err
+	}
+	// 超过上限已放行的响应必然有内容；非 2xx 响应在 WriteHeader 时已放行
+	if sw.checkEmpty && !sw.released && isEmptyResponse(sw.buf.String(), sw.usageMapping) {
+		sw.buf.Reset()
+		sw.restoreHeader()
+		status := sw.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		return &domain.ProxyError{
+			Err:            ErrEmptyResponse,
+			Retryable:      true,
+			IsServerError:  true,
+			HTTPStatusCode: status,
+			Message:        "upstream returned a successful response without output",
+		}
+	}
+	return sw.release()
+}
+
+// restoreHeader resets the response headers to the snapshot taken before the attempt
+func (sw *softFailureWriter) restoreHeader() {
+	h := sw.ResponseWriter.Header()
+	for k := range h {
+		delete(h, k)
+	}
+	for k, v := range sw.header {
+		h[k] = v
+	}
+}
+
+// isEmptyResponse reports whether a successful response carries no output: an
+// empty body, only SSE heartbeats, or usage reporting zero output tokens.
+// Responses without any usage information are not judged.
+func isEmptyResponse(body string, mapping *domain.UsageFieldMapping) bool {
+	if strings.TrimSpace(body) == "" || converter.IsSSECommentOnly(body) {
+		return true
+	}
+	metrics := usage.ExtractFromResponseWithMapping(body, mapping)
+	return metrics != nil && metrics.OutputTokens == 0
+}
@@ -0,0 +1,128 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestSoftFailureWriterMatch(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("X-Request-Id", "abc")
+	ctx, sw := withSoftFailureDetection(context.Background(), rec, []*regexp.Regexp{regexp.MustCompile(`currently overloaded`)})
+
+	sw.Header().Set("Content-Type", "text/event-stream")
+	sw.WriteHeader(http.StatusOK)
+	if _, err := sw.Write([]byte("data: {\"text\":\"I'm currently \"}\n\n")); err != nil {
+		t.Fatalf("first chunk: %v", err)
+	}
+	if _, err := sw.Write([]byte("data: {\"text\":\"I'm currently overloaded\"}\n\n")); !errors.Is(err, ErrSoftFailure) {
+		t.Fatalf("matching chunk err = %v, want ErrSoftFailure", err)
+	}
+	if !errors.Is(context.Cause(ctx), ErrSoftFailure) {
+		t.Errorf("attempt context cause = %v, want ErrSoftFailure", context.Cause(ctx))
+	}
+
+	err := sw.finish(ctx.Err())
+	var proxyErr *domain.ProxyError
+	if !errors.As(err, &proxyErr) || !proxyErr.Retryable || !proxyErr.IsServerError {
+		t.Fatalf("finish = %#v, want retryable server ProxyError", err)
+	}
+	if rec.Body.Len() != 0 || rec.Code != http.StatusOK || rec.Flushed {
+		t.Errorf("client received body %q (flushed %v), want nothing", rec.Body.String(), rec.Flushed)
+	}
+	if rec.Header().Get("Content-Type") != "" || rec.Header().Get("X-Request-Id") != "abc" {
+		t.Errorf("headers = %v, want restored to the snapshot", rec.Header())
+	}
+}
+
+func TestSoftFailureWriterPassThrough(t *testing.T) {
+	rec := httptest.NewRecorder()
+	ctx, sw := withSoftFailureDetection(context.Background(), rec, []*regexp.Regexp{regexp.MustCompile(`overloaded`)})
+
+	sw.WriteHeader(http.StatusCreated)
+	_, _ = sw.Write([]byte("hello "))
+	if rec.Body.Len() != 0 {
+		t.Fatalf("body released before finish: %q", rec.Body.String())
+	}
+	// 超过匹配上限后不再匹配，直接透传
+	big := strings.Repeat("x", softFailureMaxBytes)
+	_, _ = sw.Write([]byte(big))
+	_, _ = sw.Write([]byte(" overloaded"))
+	if err := sw.finish(nil); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	if ctx.Err() == nil {
+		t.Error("attempt context not released after finish")
+	}
+	if rec.Code != http.StatusCreated || rec.Body.String() != "hello "+big+" overloaded" {
+		t.Errorf("client got %d with %d bytes, want 201 with the full body", rec.Code, rec.Body.Len())
+	}
+}
+
+func TestSoftFailureWriterNon2xx(t *testing.T) {
+	rec := httptest.NewRecorder()
+	_, sw := withSoftFailureDetection(context.Background(), rec, []*regexp.Regexp{regexp.MustCompile(`overloaded`)})
+
+	sw.WriteHeader(http.StatusServiceUnavailable)
+	_, _ = sw.Write([]byte("overloaded"))
+	if err := sw.finish(nil); err != nil {
+		t.Fatalf("finish: %v", err)
+	}
+	if rec.Code != http.StatusServiceUnavailable || rec.Body.String() != "overloaded" {
+		t.Errorf("client got %d %q, want the error response passed through", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSoftFailureWriterEmptyResponse(t *testing.T) {
+	run := func(body string) (*httptest.ResponseRecorder, error) {
+		rec := httptest.NewRecorder()
+		ctx, sw := withSoftFailureDetection(context.Background(), rec, nil)
+		sw.checkEmpty = true
+		sw.Header().Set("Content-Type", "text/event-stream")
+		sw.WriteHeader(http.StatusOK)
+		if body != "" {
+			_, _ = sw.Write([]byte(body))
+		}
+		return rec, sw.finish(ctx.Err())
+	}
+
+	empty := map[string]string{
+		"no body":      "",
+		"heartbeats":   ": ping\n\n: ping\n\n",
+		"zero output":  "event: message_delta\ndata: {\"type\":\"message_delta\",\"usage\":{\"input_tokens\":12,\"output_tokens\":0}}\n\n",
+		"openai empty": `{"choices":[{"message":{"content":""}}],"usage":{"prompt_tokens":12,"completion_tokens":0}}`,
+	}
+	for name, body := range empty {
+		rec, err := run(body)
+		var proxyErr *domain.ProxyError
+		if !errors.As(err, &proxyErr) || !errors.Is(err, ErrEmptyResponse) || !proxyErr.Retryable {
+			t.Errorf("%s: finish = %v, want retryable ErrEmptyResponse", name, err)
+			continue
+		}
+		// 客户端未收到任何内容，可以切换到下一个路由
+		if rec.Body.Len() != 0 || rec.Header().Get("Content-Type") != "" {
+			t.Errorf("%s: client got body %q, headers %v", name, rec.Body.String(), rec.Header())
+		}
+	}
+
+	passed := map[string]string{
+		"with output": "data: {\"type\":\"message_delta\",\"usage\":{\"input_tokens\":12,\"output_tokens\":5}}\n\n",
+		"no usage":    `{"result":"ok"}`,
+	}
+	for name, body := range passed {
+		rec, err := run(body)
+		if err != nil {
+			t.Errorf("%s: finish = %v, want success", name, err)
+		}
+		if rec.Body.String() != body {
+			t.Errorf("%s: client got %q, want the response passed through", name, rec.Body.String())
+		}
+	}
+}
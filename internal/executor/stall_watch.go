@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/adapter/provider"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// defaultStreamStallTimeout 默认的流式响应最大数据间隔
+const defaultStreamStallTimeout = 120 * time.Second
+
+// ErrStreamStalled is the cancel cause of an attempt whose upstream stream stopped
+// sending data without closing the connection
+var ErrStreamStalled = errors.New("upstream stream stalled")
+
+// stallWatchWriter records when the adapter last wrote a chunk
+type stallWatchWriter struct {
+	http.ResponseWriter
+	lastWrite atomic.Int64 // UnixNano，0 表示尚未收到任何数据
+}
+
+func (sw *stallWatchWriter) Write(b []byte) (int, error) {
+	sw.lastWrite.Store(time.Now().UnixNano())
+	return sw.ResponseWriter.Write(b)
+}
+
+// NotifyStreamActivity implements provider.StreamActivityNotifier
+func (sw *stallWatchWriter) NotifyStreamActivity() {
+	sw.lastWrite.Store(time.Now().UnixNano())
+}
+
+// Flush implements http.Flusher for streaming support
+func (sw *stallWatchWriter) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// executeWithStallDetection runs the adapter and aborts the attempt when the gap
+// between two streamed chunks exceeds timeout. The clock starts at the first
+// chunk, so time to first token is not limited here; slow generation is fine as
+// long as the upstream sends something (tokens or keep-alive pings) within timeout.
+// A stall is reported as a retryable network error; the executor makes it final
+// when part of the stream already reached the client. timeout <= 0 disables detection.
+func executeWithStallDetection(ctx context.Context, adp provider.ProviderAdapter, w http.ResponseWriter, req *http.Request, p *domain.Provider, timeout time.Duration) error {
+	if timeout <= 0 {
+		return adp.Execute(ctx, w, req, p)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	sw := &stallWatchWriter{ResponseWriter: w}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(timeout / 4)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				last := sw.lastWrite.Load()
+				if last != 0 && time.Since(time.Unix(0, last)) > timeout {
+					cancel(ErrStreamStalled)
+					return
+				}
+			}
+		}
+	}()
+
+	err := adp.Execute(ctx, sw, req, p)
+	close(done)
+
+	if errors.Is(context.Cause(ctx), ErrStreamStalled) {
+		return &domain.ProxyError{
+			Err:            ErrStreamStalled,
+			Retryable:      true,
+			IsNetworkError: true,
+			Message:        fmt.Sprintf("no stream data for %s", timeout),
+		}
+	}
+	return err
+}
+
+// streamTruncationCode returns the terminal error code for errors that cut a
+// stream short (max duration, stall), or "" for any other error
+func streamTruncationCode(err error) string {
+	switch {
+	case errors.Is(err, ErrStreamMaxDuration):
+		return "stream_max_duration"
+	case errors.Is(err, ErrStreamStalled):
+		return "stream_stalled"
+	}
+	return ""
+}
+
+// getStreamStallTimeout 获取流式响应最大数据间隔，0 表示禁用
+func (e *Executor) getStreamStallTimeout() time.Duration {
+	if e.settingsRepo == nil {
+		return defaultStreamStallTimeout
+	}
+	val, err := e.settingsRepo.Get(domain.SettingKeyStreamStallTimeoutSeconds)
+	if err != nil || val == "" {
+		return defaultStreamStallTimeout
+	}
+	seconds, err := strconv.Atoi(val)
+	if err != nil || seconds < 0 {
+		return defaultStreamStallTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -0,0 +1,157 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// pausingAdapter streams chunks with a fixed delay between them, optionally
+// pausing (until the context is cancelled) after pauseAfter chunks
+type pausingAdapter struct {
+	chunks     []string
+	interval   time.Duration
+	pauseAfter int // 0 表示不暂停
+}
+
+func (a *pausingAdapter) SupportedClientTypes() []domain.ClientType {
+	return []domain.ClientType{domain.ClientTypeClaude}
+}
+
+func (a *pausingAdapter) Execute(ctx context.Context, w http.ResponseWriter, req *http.Request, p *domain.Provider) error {
+	for i, chunk := range a.chunks {
+		if a.pauseAfter > 0 && i == a.pauseAfter {
+			// 连接不关闭，也不再发送数据
+			<-ctx.Done()
+			return domain.NewProxyErrorWithMessage(ctx.Err(), false, "client disconnected")
+		}
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return domain.NewProxyErrorWithMessage(ctx.Err(), false, "client disconnected")
+			case <-time.After(a.interval):
+			}
+		}
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			return err
+		}
+		w.(http.Flusher).Flush()
+	}
+	return nil
+}
+
+// slowStartAdapter waits before writing its only chunk
+type slowStartAdapter struct {
+	delay time.Duration
+}
+
+func (a *slowStartAdapter) SupportedClientTypes() []domain.ClientType {
+	return []domain.ClientType{domain.ClientTypeClaude}
+}
+
+func (a *slowStartAdapter) Execute(ctx context.Context, w http.ResponseWriter, req *http.Request, p *domain.Provider) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(a.delay):
+	}
+	_, err := w.Write([]byte("done"))
+	return err
+}
+
+func TestExecuteWithStallDetectionAbortsStalledStream(t *testing.T) {
+	adp := &pausingAdapter{chunks: []string{"data: 1\n\n", "data: 2\n\n", "data: 3\n\n"}, pauseAfter: 1}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+
+	start := time.Now()
+	err := executeWithStallDetection(context.Background(), adp, rec, req, &domain.Provider{}, 100*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("stall not detected in time: %v", elapsed)
+	}
+
+	var proxyErr *domain.ProxyError
+	if !errors.As(err, &proxyErr) {
+		t.Fatalf("err = %v, want ProxyError", err)
+	}
+	if !errors.Is(err, ErrStreamStalled) || !proxyErr.Retryable {
+		t.Errorf("err = %v (retryable=%v), want retryable ErrStreamStalled", err, proxyErr.Retryable)
+	}
+	if got := rec.Body.String(); got != "data: 1\n\n" {
+		t.Errorf("body = %q, want first chunk only", got)
+	}
+}
+
+func TestExecuteWithStallDetectionAllowsSlowStream(t *testing.T) {
+	// 每块间隔低于阈值，总耗时远超阈值，不应被判定为卡住
+	adp := &pausingAdapter{chunks: []string{"a", "b", "c", "d", "e", "f"}, interval: 60 * time.Millisecond}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+
+	if err := executeWithStallDetection(context.Background(), adp, rec, req, &domain.Provider{}, 150*time.Millisecond); err != nil {
+		t.Fatalf("slow stream flagged: %v", err)
+	}
+	if got := rec.Body.String(); got != "abcdef" {
+		t.Errorf("body = %q, want abcdef", got)
+	}
+}
+
+func TestExecuteWithStallDetectionIgnoresTimeToFirstChunk(t *testing.T) {
+	// 首块之前的等待不计入数据间隔
+	adp := &slowStartAdapter{delay: 300 * time.Millisecond}
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+
+	if err := executeWithStallDetection(context.Background(), adp, rec, req, &domain.Provider{}, 100*time.Millisecond); err != nil {
+		t.Fatalf("slow first chunk flagged: %v", err)
+	}
+}
+
+func TestExecutorStallAfterPartialOutputEndsStream(t *testing.T) {
+	stalled := &pausingAdapter{chunks: []string{"event: message_start\ndata: {}\n\n", "never sent"}, pauseAfter: 1}
+	fallback := &pausingAdapter{chunks: []string{"data: fallback\n\n"}}
+	te := newTestExecutor(t, domain.ClientTypeClaude,
+		map[string]string{domain.SettingKeyStreamStallTimeoutSeconds: "1"},
+		testUpstream{adapter: stalled}, testUpstream{adapter: fallback})
+
+	rec := httptest.NewRecorder()
+	err := te.execute(domain.ClientTypeClaude, "claude-sonnet-4", true, rec)
+
+	var proxyErr *domain.ProxyError
+	if !errors.As(err, &proxyErr) || !errors.Is(err, ErrStreamStalled) || proxyErr.Retryable {
+		t.Fatalf("err = %v, want non-retryable ErrStreamStalled", err)
+	}
+	// 客户端已收到部分内容：以错误事件结束，不再切换到下一条路由
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "event: message_start\ndata: {}\n\nevent: error\ndata: ") || strings.Contains(body, "fallback") {
+		t.Errorf("body = %q, want partial output followed by an error event", body)
+	}
+	attempts := te.attempts(t)
+	if len(attempts) != 1 || !attempts[0].StreamTruncated || attempts[0].Status != "FAILED" {
+		t.Fatalf("attempts = %+v, want one truncated failed attempt", attempts)
+	}
+}
+
+func TestExecutorStallBeforeOutputFailsOver(t *testing.T) {
+	// 数据块被软失败检测缓存、尚未发给客户端时卡住，仍可切换路由
+	stalled := &pausingAdapter{chunks: []string{"data: {}\n\n", "never sent"}, pauseAfter: 1}
+	fallback := &pausingAdapter{chunks: []string{"data: fallback\n\n"}}
+	te := newTestExecutor(t, domain.ClientTypeClaude,
+		map[string]string{domain.SettingKeyStreamStallTimeoutSeconds: "1"},
+		testUpstream{adapter: stalled, config: domain.ProviderConfig{SoftFailurePatterns: []string{"overloaded"}}},
+		testUpstream{adapter: fallback})
+
+	rec := httptest.NewRecorder()
+	if err := te.execute(domain.ClientTypeClaude, "claude-sonnet-4", true, rec); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if got := rec.Body.String(); got != "data: fallback\n\n" {
+		t.Errorf("body = %q, want the fallback route's stream only", got)
+	}
+}
@@ -0,0 +1,201 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// streamBufferCloseTimeout bounds how long Close waits for buffered data to reach the client
+const streamBufferCloseTimeout = 30 * time.Second
+
+var (
+	errStreamBufferClosed       = errors.New("stream buffer closed")
+	errStreamBufferCloseTimeout = errors.New("stream buffer drain to client timed out")
+)
+
+// StreamBuffer buffers streaming response chunks in memory and drains them
+// to the client from a background goroutine.
+//
+// 作用：客户端读取缓慢时，上游写入不会被客户端阻塞，adapter 可以尽快读完上游响应并释放连接，
+// 剩余数据再由后台 goroutine 慢慢写给客户端。
+//
+// Tradeoffs:
+//   - 以内存换上游连接周转速度，每个慢客户端最多占用 maxBytes 内存
+//   - 缓冲超过上限后退化为直接写（等待缓冲区排空后同步写入），此时行为与未开启缓冲一致
+//   - 客户端断开时，错误会延迟到下一次 Write 才返回给 adapter
+//   - Close 最多等待 closeTimeout（或请求 context 结束），超时后丢弃未写出的数据；
+//     底层 writer 不支持写截止时间时，还需等待正在进行的那次写入结束
+type StreamBuffer struct {
+	w            http.ResponseWriter
+	maxBytes     int
+	closeTimeout time.Duration
+
+	mu          sync.Mutex
+	cond        *sync.Cond
+	queue       [][]byte
+	pending     int
+	closed      bool
+	passthrough bool
+	writeErr    error
+	done        chan struct{}
+}
+
+// NewStreamBuffer creates a StreamBuffer and starts its drain goroutine
+func NewStreamBuffer(w http.ResponseWriter, maxBytes int) *StreamBuffer {
+	sb := &StreamBuffer{
+		w:            w,
+		maxBytes:     maxBytes,
+		closeTimeout: streamBufferCloseTimeout,
+		done:         make(chan struct{}),
+	}
+	sb.cond = sync.NewCond(&sb.mu)
+	go sb.drain()
+	return sb
+}
+
+// Header returns the underlying header map
+func (sb *StreamBuffer) Header() http.Header {
+	return sb.w.Header()
+}
+
+// WriteHeader forwards the status code directly (headers are always sent before body)
+func (sb *StreamBuffer) WriteHeader(code int) {
+	sb.w.WriteHeader(code)
+}
+
+// Write queues the chunk, or writes directly once the buffer cap is exceeded
+func (sb *StreamBuffer) Write(b []byte) (int, error) {
+	sb.mu.Lock()
+	if sb.writeErr != nil {
+		err := sb.writeErr
+		sb.mu.Unlock()
+		return 0, err
+	}
+	if sb.closed {
+		sb.mu.Unlock()
+		return 0, errStreamBufferClosed
+	}
+
+	if sb.passthrough || sb.pending+len(b) > sb.maxBytes {
+		// 超出缓冲上限，等待已缓冲的数据写完后直接写入
+		sb.passthrough = true
+		for sb.pending > 0 && sb.writeErr == nil {
+			sb.cond.Wait()
+		}
+		err := sb.writeErr
+		sb.mu.Unlock()
+		if err != nil {
+			return 0, err
+		}
+		return sb.w.Write(b)
+	}
+
+	chunk := make([]byte, len(b))
+	copy(chunk, b)
+	sb.queue = append(sb.queue, chunk)
+	sb.pending += len(chunk)
+	sb.cond.Broadcast()
+	sb.mu.Unlock()
+	return len(b), nil
+}
+
+// Flush flushes the underlying writer in passthrough mode.
+// In buffered mode the drain goroutine flushes after every chunk.
+func (sb *StreamBuffer) Flush() {
+	sb.mu.Lock()
+	direct := sb.passthrough && sb.pending == 0
+	sb.mu.Unlock()
+	if direct {
+		if f, ok := sb.w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+}
+
+// Close stops accepting writes and waits until all buffered data has been
+// written to the client (or the client write failed). A slow or stuck client
+// is given at most closeTimeout, and nothing once ctx (the client request) is
+// done; the remaining data is then dropped and the error returned.
+func (sb *StreamBuffer) Close(ctx context.Context) error {
+	sb.mu.Lock()
+	sb.closed = true
+	sb.cond.Broadcast()
+	sb.mu.Unlock()
+
+	timer := time.NewTimer(sb.closeTimeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-sb.done:
+		sb.mu.Lock()
+		defer sb.mu.Unlock()
+		return sb.writeErr
+	case <-ctx.Done():
+		err = context.Cause(ctx)
+	case <-timer.C:
+		err = errStreamBufferCloseTimeout
+	}
+	sb.abandon(err)
+	return err
+}
+
+// abandon drops the data not yet written and waits for the drain goroutine, so
+// nothing touches the response after the handler returns. A client write still
+// in progress is cut short by expiring the connection's write deadline; where
+// the writer doesn't support write deadlines that last write has to finish
+// (or fail) first.
+func (sb *StreamBuffer) abandon(err error) {
+	sb.mu.Lock()
+	if sb.writeErr == nil {
+		sb.writeErr = err
+	}
+	sb.queue = nil
+	sb.pending = 0
+	sb.cond.Broadcast()
+	sb.mu.Unlock()
+
+	_ = http.NewResponseController(sb.w).SetWriteDeadline(time.Now())
+	<-sb.done
+}
+
+func (sb *StreamBuffer) drain() {
+	defer close(sb.done)
+	flusher, _ := sb.w.(http.Flusher)
+
+	for {
+		sb.mu.Lock()
+		for len(sb.queue) == 0 && !sb.closed {
+			sb.cond.Wait()
+		}
+		if len(sb.queue) == 0 || sb.writeErr != nil {
+			sb.mu.Unlock()
+			return
+		}
+		chunk := sb.queue[0]
+		sb.queue[0] = nil
+		sb.queue = sb.queue[1:]
+		sb.mu.Unlock()
+
+		_, err := sb.w.Write(chunk)
+		if err == nil && flusher != nil {
+			flusher.Flush()
+		}
+
+		sb.mu.Lock()
+		if err != nil && sb.writeErr == nil {
+			sb.writeErr = err
+		}
+		if sb.writeErr != nil {
+			// 写入失败或已被 Close 放弃
+			sb.queue = nil
+			sb.pending = 0
+		} else {
+			sb.pending -= len(chunk)
+		}
+		sb.cond.Broadcast()
+		sb.mu.Unlock()
+	}
+}
@@ -0,0 +1,202 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gatedWriter blocks every Write until release is closed, or until its write
+// deadline is expired through http.ResponseController
+type gatedWriter struct {
+	*httptest.ResponseRecorder
+	release  chan struct{}
+	deadline chan struct{}
+	once     sync.Once
+	flushes  int
+}
+
+func newGatedWriter() *gatedWriter {
+	return &gatedWriter{ResponseRecorder: httptest.NewRecorder(), release: make(chan struct{}), deadline: make(chan struct{})}
+}
+
+func (w *gatedWriter) Write(b []byte) (int, error) {
+	select {
+	case <-w.release:
+		return w.ResponseRecorder.Write(b)
+	case <-w.deadline:
+		return 0, errors.New("write deadline exceeded")
+	}
+}
+
+func (w *gatedWriter) Flush() {
+	w.flushes++
+}
+
+func (w *gatedWriter) SetWriteDeadline(time.Time) error {
+	w.once.Do(func() { close(w.deadline) })
+	return nil
+}
+
+// unwrappingWriter wraps a writer the way the handler middlewares do
+type unwrappingWriter struct {
+	http.ResponseWriter
+}
+
+func (w unwrappingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func TestStreamBufferKeepsOrderAcrossOverflow(t *testing.T) {
+	w := newGatedWriter()
+	sb := NewStreamBuffer(w, 4)
+
+	// 前两块在缓冲内；第三块超过 maxBytes，需等缓冲写完后直接写入
+	for _, chunk := range []string{"a", "bc"} {
+		if n, err := sb.Write([]byte(chunk)); err != nil || n != len(chunk) {
+			t.Fatalf("buffered write = %d, %v", n, err)
+		}
+	}
+	written := make(chan error, 1)
+	go func() {
+		_, err := sb.Write([]byte("def"))
+		written <- err
+	}()
+	select {
+	case err := <-written:
+		t.Fatalf("overflow write returned before the buffer drained: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(w.release)
+	if err := <-written; err != nil {
+		t.Fatalf("overflow write: %v", err)
+	}
+	if _, err := sb.Write([]byte("g")); err != nil {
+		t.Fatalf("passthrough write: %v", err)
+	}
+	if err := sb.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if got := w.Body.String(); got != "abcdefg" {
+		t.Errorf("body = %q, want abcdefg", got)
+	}
+	if _, err := sb.Write([]byte("h")); !errors.Is(err, errStreamBufferClosed) {
+		t.Errorf("write after close err = %v", err)
+	}
+}
+
+func TestStreamBufferFlush(t *testing.T) {
+	w := newGatedWriter()
+	close(w.release)
+	sb := NewStreamBuffer(w, 2)
+
+	// 缓冲模式下由后台 goroutine 每块刷新，Flush 本身不直接刷新
+	_, _ = sb.Write([]byte("a"))
+	sb.Flush()
+	if err := sb.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if w.flushes != 1 {
+		t.Errorf("flushes = %d, want 1 from the drain goroutine", w.flushes)
+	}
+
+	// 直写模式下 Flush 透传给底层 writer
+	sb = NewStreamBuffer(w, 2)
+	_, _ = sb.Write([]byte("too long"))
+	sb.Flush()
+	if err := sb.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if w.flushes != 2 {
+		t.Errorf("flushes = %d, want passthrough Flush", w.flushes)
+	}
+}
+
+func TestStreamBufferCloseWithBlockedWriter(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		w := newGatedWriter()
+		sb := NewStreamBuffer(w, 64)
+		sb.closeTimeout = 50 * time.Millisecond
+		_, _ = sb.Write([]byte("a"))
+		_, _ = sb.Write([]byte("b"))
+
+		start := time.Now()
+		if err := sb.Close(context.Background()); !errors.Is(err, errStreamBufferCloseTimeout) {
+			t.Fatalf("Close err = %v, want timeout", err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Close took %v", elapsed)
+		}
+		// 写入被截止时间中断，后台 goroutine 已退出
+		select {
+		case <-sb.done:
+		default:
+			t.Error("drain goroutine still running after Close")
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("body = %q, want nothing written", w.Body.String())
+		}
+	})
+
+	t.Run("request context done", func(t *testing.T) {
+		w := newGatedWriter()
+		sb := NewStreamBuffer(w, 64)
+		_, _ = sb.Write([]byte("a"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		if err := sb.Close(ctx); !errors.Is(err, context.Canceled) {
+			t.Fatalf("Close err = %v, want context.Canceled", err)
+		}
+	})
+
+	t.Run("writer without deadlines", func(t *testing.T) {
+		// 包装层不支持写截止时间时，Close 等待正在进行的写入结束后才返回，
+		// 之后不再写入底层 writer
+		w := newGatedWriter()
+		sb := NewStreamBuffer(struct{ http.ResponseWriter }{w}, 64)
+		sb.closeTimeout = 50 * time.Millisecond
+		_, _ = sb.Write([]byte("a"))
+		_, _ = sb.Write([]byte("b"))
+
+		closed := make(chan error, 1)
+		go func() { closed <- sb.Close(context.Background()) }()
+		select {
+		case err := <-closed:
+			t.Fatalf("Close returned during a client write: %v", err)
+		case <-time.After(150 * time.Millisecond):
+		}
+		close(w.release)
+		if err := <-closed; !errors.Is(err, errStreamBufferCloseTimeout) {
+			t.Fatalf("Close err = %v, want timeout", err)
+		}
+		select {
+		case <-sb.done:
+		default:
+			t.Error("drain goroutine still running after Close")
+		}
+		if got := w.Body.String(); got != "a" {
+			t.Errorf("body = %q, want only the write in progress", got)
+		}
+	})
+
+	t.Run("wrapper with Unwrap", func(t *testing.T) {
+		// 实现 Unwrap 的包装层让写截止时间到达底层连接
+		w := newGatedWriter()
+		sb := NewStreamBuffer(unwrappingWriter{w}, 64)
+		sb.closeTimeout = 50 * time.Millisecond
+		_, _ = sb.Write([]byte("a"))
+
+		if err := sb.Close(context.Background()); !errors.Is(err, errStreamBufferCloseTimeout) {
+			t.Fatalf("Close err = %v, want timeout", err)
+		}
+		if w.Body.Len() != 0 {
+			t.Errorf("body = %q, want nothing written", w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package executor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// ErrStreamMaxDuration is the cancel cause of an attempt whose stream kept
+// going past the configured max stream duration
+var ErrStreamMaxDuration = errors.New("stream exceeded max duration")
+
+// getMaxStreamDuration returns the cap on a streaming response's total duration:
+// the route's MaxStreamDurationMs, else the provider's; 0 means unlimited
+func getMaxStreamDuration(route *domain.Route, provider *domain.Provider) time.Duration {
+	if route != nil && route.MaxStreamDurationMs > 0 {
+		return time.Duration(route.MaxStreamDurationMs) * time.Millisecond
+	}
+	if provider != nil && provider.Config != nil && provider.Config.MaxStreamDurationMs > 0 {
+		return time.Duration(provider.Config.MaxStreamDurationMs) * time.Millisecond
+	}
+	return 0
+}
+
+// streamDeadlineWriter starts the max duration timer on the first chunk
+type streamDeadlineWriter struct {
+	http.ResponseWriter
+	once  sync.Once
+	start func()
+}
+
+func (dw *streamDeadlineWriter) Write(b []byte) (int, error) {
+	dw.once.Do(dw.start)
+	return dw.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher for streaming support
+func (dw *streamDeadlineWriter) Flush() {
+	if f, ok := dw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// executeWithMaxStreamDuration runs the attempt and aborts it once max has
+// passed since the first streamed chunk. Unlike stall detection this bounds the
+// total time of a stream that keeps producing data, e.g. a model stuck in a
+// repetition loop. Exceeding it is reported as a non-retryable error; the caller
+// decides how to end what was already sent. max <= 0 disables the cap.
+func executeWithMaxStreamDuration(ctx context.Context, w http.ResponseWriter, max time.Duration, run func(context.Context, http.ResponseWriter) error) error {
+	if max <= 0 {
+		return run(ctx, w)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var timer *time.Timer
+	var mu sync.Mutex
+	dw := &streamDeadlineWriter{ResponseWriter: w}
+	dw.start = func() {
+		mu.Lock()
+		timer = time.AfterFunc(max, func() { cancel(ErrStreamMaxDuration) })
+		mu.Unlock()
+	}
+
+	err := run(ctx, dw)
+	mu.Lock()
+	if timer != nil {
+		timer.Stop()
+	}
+	mu.Unlock()
+
+	if errors.Is(context.Cause(ctx), ErrStreamMaxDuration) {
+		return &domain.ProxyError{
+			Err:       ErrStreamMaxDuration,
+			Retryable: false,
+			Message:   fmt.Sprintf("stream exceeded max duration of %s", max),
+		}
+	}
+	return err
+}
+
+// streamTerminalErrorEvent builds the SSE error event that ends a stream cut off
+// after part of it reached the client, in the client's own error format.
+// code identifies the cause (stream_max_duration, stream_stalled) where the
+// format has a machine-readable error code.
+func streamTerminalErrorEvent(clientType domain.ClientType, code, message string) []byte {
+	var event string
+	var payload any
+	switch clientType {
+	case domain.ClientTypeClaude:
+		event = "error"
+		payload = map[string]any{
+			"type":  "error",
+			"error": map[string]any{"type": "api_error", "message": message},
+		}
+	case domain.ClientTypeCodex:
+		event = "error"
+		payload = map[string]any{"type": "error", "code": code, "message": message}
+	case domain.ClientTypeGemini:
+		payload = map[string]any{
+			"error": map[string]any{"code": http.StatusGatewayTimeout, "message": message, "status": "DEADLINE_EXCEEDED"},
+		}
+	default:
+		payload = map[string]any{
+			"error": map[string]any{"message": message, "type": "server_error", "code": code},
+		}
+	}
+	data, _ := json.Marshal(payload)
+	if event != "" {
+		return []byte("event: " + event + "\ndata: " + string(data) + "\n\n")
+	}
+	return []byte("data: " + string(data) + "\n\n")
+}
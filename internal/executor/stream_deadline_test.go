@@ -0,0 +1,93 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/tidwall/gjson"
+)
+
+// runWithMaxStreamDuration streams adp through both the duration cap and stall detection, like the executor
+func runWithMaxStreamDuration(adp *pausingAdapter, max time.Duration) (*httptest.ResponseRecorder, error) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	err := executeWithMaxStreamDuration(context.Background(), rec, max, func(ctx context.Context, w http.ResponseWriter) error {
+		return executeWithStallDetection(ctx, adp, w, req, &domain.Provider{}, time.Second)
+	})
+	return rec, err
+}
+
+func TestExecuteWithMaxStreamDurationCutsLongStream(t *testing.T) {
+	// 数据持续不断（不会被判定为卡住），但总时长超过上限
+	chunks := make([]string, 50)
+	for i := range chunks {
+		chunks[i] = "x"
+	}
+	start := time.Now()
+	rec, err := runWithMaxStreamDuration(&pausingAdapter{chunks: chunks, interval: 40 * time.Millisecond}, 150*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("stream not cut in time: %v", elapsed)
+	}
+
+	var proxyErr *domain.ProxyError
+	if !errors.As(err, &proxyErr) || !errors.Is(err, ErrStreamMaxDuration) || proxyErr.Retryable {
+		t.Fatalf("err = %v, want non-retryable ErrStreamMaxDuration", err)
+	}
+	if n := rec.Body.Len(); n == 0 || n >= len(chunks) {
+		t.Errorf("delivered %d chunks, want a truncated stream", n)
+	}
+}
+
+func TestExecuteWithMaxStreamDurationAllowsStreamUnderCap(t *testing.T) {
+	// 首块之前的等待不计入总时长
+	rec, err := runWithMaxStreamDuration(&pausingAdapter{chunks: []string{"a", "b", "c"}, interval: 30 * time.Millisecond}, 500*time.Millisecond)
+	if err != nil {
+		t.Fatalf("stream under cap flagged: %v", err)
+	}
+	if got := rec.Body.String(); got != "abc" {
+		t.Errorf("body = %q, want abc", got)
+	}
+}
+
+func TestGetMaxStreamDuration(t *testing.T) {
+	provider := &domain.Provider{Config: &domain.ProviderConfig{MaxStreamDurationMs: 60000}}
+	if got := getMaxStreamDuration(&domain.Route{}, provider); got != time.Minute {
+		t.Errorf("provider cap = %v, want 1m", got)
+	}
+	if got := getMaxStreamDuration(&domain.Route{MaxStreamDurationMs: 5000}, provider); got != 5*time.Second {
+		t.Errorf("route override = %v, want 5s", got)
+	}
+	if got := getMaxStreamDuration(&domain.Route{}, &domain.Provider{}); got != 0 {
+		t.Errorf("unset = %v, want 0", got)
+	}
+}
+
+func TestStreamTerminalErrorEvent(t *testing.T) {
+	tests := []struct {
+		clientType domain.ClientType
+		prefix     string
+		path       string
+	}{
+		{domain.ClientTypeClaude, "event: error\ndata: ", "error.message"},
+		{domain.ClientTypeOpenAI, "data: ", "error.message"},
+		{domain.ClientTypeCodex, "event: error\ndata: ", "message"},
+		{domain.ClientTypeGemini, "data: ", "error.message"},
+	}
+	for _, tt := range tests {
+		event := string(streamTerminalErrorEvent(tt.clientType, "stream_max_duration", "too long"))
+		data, ok := strings.CutPrefix(event, tt.prefix)
+		if !ok || !strings.HasSuffix(data, "\n\n") {
+			t.Errorf("%s: event = %q, want prefix %q", tt.clientType, event, tt.prefix)
+			continue
+		}
+		if got := gjson.Get(data, tt.path).String(); got != "too long" {
+			t.Errorf("%s: %s = %q in %s", tt.clientType, tt.path, got, data)
+		}
+	}
+}
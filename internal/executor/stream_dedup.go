@@ -0,0 +1,264 @@
+package executor
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+
+	ctxutil "github.com/awsl-project/maxx/internal/context"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// streamDedupMaxBytes 单个共享流最多缓存的字节数，超过后不再接纳新的客户端
+const streamDedupMaxBytes = 8 << 20
+
+// streamDedupHeaders 会影响上游行为、需要参与去重 key 的请求头
+var streamDedupHeaders = []string{"Anthropic-Beta", "Anthropic-Version", "OpenAI-Organization", "OpenAI-Project"}
+
+// Stream deduplication fans a single upstream stream out to every client that
+// sends an identical streaming request (same API token, project, client type,
+// URI, relevant headers and body) while the first one is still in flight.
+//
+// Memory/ordering model:
+//   - The first request starts a flight that runs Execute once on a context
+//     detached from its client. Everything Execute writes (status, headers and
+//     the client-format SSE bytes) is appended to one shared, append-only chunk
+//     list, so every client sees the same bytes in the same order.
+//   - Each client, including the first, is a subscriber with its own read
+//     offset; a client joining late replays the chunks written so far and then
+//     follows live. A slow client only delays itself, never the upstream.
+//   - The chunk list lives until the flight ends. Once it exceeds
+//     streamDedupMaxBytes the flight stops accepting new subscribers (they get
+//     their own upstream request instead) but keeps serving existing ones.
+//   - A subscriber whose client disconnects leaves the flight; the upstream
+//     request is cancelled only when the last subscriber has left.
+//   - Only the flight's Execute is recorded as a proxy request, so duplicate
+//     clients do not appear in request logs or usage stats.
+type streamDedup struct {
+	mu      sync.Mutex
+	flights map[string]*streamFlight
+}
+
+func newStreamDedup() *streamDedup {
+	return &streamDedup{flights: make(map[string]*streamFlight)}
+}
+
+// streamFlight is one shared upstream stream and its subscribers
+type streamFlight struct {
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	cond        *sync.Cond
+	header      http.Header
+	status      int // 0 表示尚未写入状态码
+	chunks      [][]byte
+	size        int
+	done        bool
+	err         error
+	subscribers int
+}
+
+// ExecuteDeduplicated runs Execute, sharing one upstream stream among identical
+// concurrent streaming requests when stream_dedup_enabled is on. Non-streaming
+// requests get the client disconnect grace period instead (see disconnectGrace).
+func (e *Executor) ExecuteDeduplicated(ctx context.Context, w http.ResponseWriter, req *http.Request) error {
+	if !ctxutil.GetIsStream(ctx) {
+		return e.executeWithDisconnectGrace(ctx, w, req)
+	}
+	if !e.isStreamDedupEnabled() {
+		return e.Execute(ctx, w, req)
+	}
+
+	key := streamDedupKey(ctx)
+	flight, leader := e.streamDedup.join(key)
+	if leader {
+		flightCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		flight.cancel = cancel
+		go func() {
+			defer cancel()
+			err := e.Execute(flightCtx, &flightWriter{flight: flight}, req)
+			e.streamDedup.remove(key, flight)
+			flight.finish(err)
+		}()
+	} else {
+		log.Printf("[Executor] Joined in-flight identical stream %s", key[:12])
+	}
+	return flight.serve(ctx, w)
+}
+
+func (e *Executor) isStreamDedupEnabled() bool {
+	if e.settingsRepo == nil {
+		return false
+	}
+	val, err := e.settingsRepo.Get(domain.SettingKeyStreamDedupEnabled)
+	return err == nil && val == "true"
+}
+
+// streamDedupKey hashes everything that determines the upstream response
+func streamDedupKey(ctx context.Context) string {
+	h := sha256.New()
+	write := func(s string) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	write(string(ctxutil.GetClientType(ctx)))
+	write(strconv.FormatUint(ctxutil.GetProjectID(ctx), 10))
+	write(strconv.FormatUint(ctxutil.GetAPITokenID(ctx), 10))
+	write(ctxutil.GetRequestURI(ctx))
+	headers := ctxutil.GetRequestHeaders(ctx)
+	for _, name := range streamDedupHeaders {
+		write(headers.Get(name))
+	}
+	h.Write(ctxutil.GetRequestBody(ctx))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// join subscribes to the in-flight stream for key, or registers a new flight
+// (leader = true) when there is none or it no longer accepts subscribers
+func (d *streamDedup) join(key string) (*streamFlight, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if f, ok := d.flights[key]; ok {
+		f.mu.Lock()
+		accepting := !f.done && f.subscribers > 0 && f.size <= streamDedupMaxBytes
+		if accepting {
+			f.subscribers++
+		}
+		f.mu.Unlock()
+		if accepting {
+			return f, false
+		}
+	}
+
+	f := &streamFlight{header: make(http.Header), subscribers: 1}
+	f.cond = sync.NewCond(&f.mu)
+	d.flights[key] = f
+	return f, true
+}
+
+func (d *streamDedup) remove(key string, f *streamFlight) {
+	d.mu.Lock()
+	if d.flights[key] == f {
+		delete(d.flights, key)
+	}
+	d.mu.Unlock()
+}
+
+func (f *streamFlight) finish(err error) {
+	f.mu.Lock()
+	f.done = true
+	f.err = err
+	f.cond.Broadcast()
+	f.mu.Unlock()
+}
+
+// leave drops a subscriber and cancels the upstream once nobody is listening
+func (f *streamFlight) leave() {
+	f.mu.Lock()
+	f.subscribers--
+	last := f.subscribers == 0 && !f.done
+	f.mu.Unlock()
+	if last {
+		f.cancel()
+	}
+}
+
+// serve copies the flight's output to w from the beginning, following it live
+// until the flight finishes (returning Execute's error) or ctx is done
+func (f *streamFlight) serve(ctx context.Context, w http.ResponseWriter) error {
+	stop := context.AfterFunc(ctx, func() {
+		f.mu.Lock()
+		f.cond.Broadcast()
+		f.mu.Unlock()
+	})
+	defer stop()
+
+	flusher, _ := w.(http.Flusher)
+	headerSent := false
+	next := 0
+	for {
+		f.mu.Lock()
+		for ctx.Err() == nil && !f.done && next == len(f.chunks) && (headerSent || f.status == 0) {
+			f.cond.Wait()
+		}
+		if ctx.Err() != nil {
+			f.mu.Unlock()
+			f.leave()
+			return ctx.Err()
+		}
+		var header http.Header
+		status := f.status
+		if !headerSent && status != 0 {
+			header = f.header.Clone()
+		}
+		pending := f.chunks[next:]
+		next = len(f.chunks)
+		done, err := f.done, f.err
+		f.mu.Unlock()
+
+		if header != nil {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			headerSent = true
+		}
+		for _, chunk := range pending {
+			if _, werr := w.Write(chunk); werr != nil {
+				f.leave()
+				return werr
+			}
+		}
+		if flusher != nil && (header != nil || len(pending) > 0) {
+			flusher.Flush()
+		}
+		if done {
+			// finish 之后不会再有新数据，此时已全部写出
+			f.leave()
+			return err
+		}
+	}
+}
+
+// flightWriter is the ResponseWriter Execute writes to for a shared stream
+type flightWriter struct {
+	flight *streamFlight
+}
+
+// Header is only modified by Execute before WriteHeader; subscribers clone it
+// after the status is set
+func (fw *flightWriter) Header() http.Header {
+	return fw.flight.header
+}
+
+func (fw *flightWriter) WriteHeader(status int) {
+	f := fw.flight
+	f.mu.Lock()
+	if f.status == 0 {
+		f.status = status
+		f.cond.Broadcast()
+	}
+	f.mu.Unlock()
+}
+
+func (fw *flightWriter) Write(b []byte) (int, error) {
+	f := fw.flight
+	chunk := append([]byte(nil), b...)
+	f.mu.Lock()
+	if f.status == 0 {
+		f.status = http.StatusOK
+	}
+	f.chunks = append(f.chunks, chunk)
+	f.size += len(chunk)
+	f.cond.Broadcast()
+	f.mu.Unlock()
+	return len(b), nil
+}
+
+// Flush implements http.Flusher; subscribers flush after every write
+func (fw *flightWriter) Flush() {}
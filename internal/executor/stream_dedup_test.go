@@ -0,0 +1,102 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStreamFlightFanOut(t *testing.T) {
+	d := newStreamDedup()
+	flight, leader := d.join("k")
+	if !leader {
+		t.Fatal("first join is not the leader")
+	}
+	cancelled := make(chan struct{})
+	flight.cancel = func() { close(cancelled) }
+
+	fw := &flightWriter{flight: flight}
+	fw.Header().Set("Content-Type", "text/event-stream")
+	fw.WriteHeader(http.StatusOK)
+	_, _ = fw.Write([]byte("data: 1\n\n"))
+
+	// 晚加入的客户端先回放已有数据
+	joined, leader := d.join("k")
+	if leader || joined != flight {
+		t.Fatal("second join did not attach to the in-flight stream")
+	}
+
+	results := make(chan *httptest.ResponseRecorder, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			rec := httptest.NewRecorder()
+			if err := flight.serve(context.Background(), rec); err != nil {
+				t.Errorf("serve: %v", err)
+			}
+			results <- rec
+		}()
+	}
+
+	_, _ = fw.Write([]byte("data: 2\n\n"))
+	d.remove("k", flight)
+	flight.finish(nil)
+
+	for i := 0; i < 2; i++ {
+		rec := <-results
+		if got := rec.Body.String(); got != "data: 1\n\ndata: 2\n\n" {
+			t.Errorf("body = %q", got)
+		}
+		if got := rec.Header().Get("Content-Type"); got != "text/event-stream" {
+			t.Errorf("Content-Type = %q", got)
+		}
+	}
+	select {
+	case <-cancelled:
+		t.Error("completed flight was cancelled")
+	default:
+	}
+
+	if _, leader := d.join("k"); !leader {
+		t.Error("join after the flight finished did not start a new one")
+	}
+}
+
+func TestStreamFlightCancelledWhenAllClientsLeave(t *testing.T) {
+	d := newStreamDedup()
+	flight, _ := d.join("k")
+	d.join("k")
+	cancelled := make(chan struct{})
+	flight.cancel = func() { close(cancelled) }
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	go func() { errs <- flight.serve(ctx1, httptest.NewRecorder()) }()
+	go func() { errs <- flight.serve(ctx2, httptest.NewRecorder()) }()
+
+	cancel1()
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Errorf("serve = %v, want Canceled", err)
+	}
+	select {
+	case <-cancelled:
+		t.Fatal("upstream cancelled while a client is still subscribed")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	cancel2()
+	<-errs
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("upstream not cancelled after the last client left")
+	}
+
+	// 已取消的 flight 不再接纳新客户端
+	if _, leader := d.join("k"); !leader {
+		t.Error("joined a flight with no subscribers")
+	}
+}
@@ -0,0 +1,162 @@
+package executor
+
+import (
+	"bytes"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/awsl-project/maxx/internal/converter"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/tidwall/sjson"
+)
+
+// resolveUpstreamStream returns whether the upstream request should stream,
+// based on the provider's stream mode and what the client requested
+func resolveUpstreamStream(clientStream bool, p *domain.Provider) bool {
+	if p == nil || p.Config == nil || p.Config.Custom == nil {
+		return clientStream
+	}
+	switch p.Config.Custom.StreamMode {
+	case domain.StreamModeStream:
+		return true
+	case domain.StreamModeNonStream:
+		return false
+	default:
+		return clientStream
+	}
+}
+
+// applyUpstreamStream rewrites the request body / URI so the upstream request
+// uses the given stream mode. clientType is the format sent to the upstream.
+func applyUpstreamStream(body []byte, uri string, clientType domain.ClientType, stream bool) ([]byte, string) {
+	if clientType == domain.ClientTypeGemini {
+		// Gemini 通过 URL 区分流式：:streamGenerateContent?alt=sse / :generateContent
+		return body, rewriteGeminiStreamURI(uri, stream)
+	}
+
+	if updated, err := sjson.SetBytes(body, "stream", stream); err == nil {
+		body = updated
+	}
+	if clientType == domain.ClientTypeOpenAI {
+		if stream {
+			// 需要 usage 才能正确计费
+			if updated, err := sjson.SetBytes(body, "stream_options.include_usage", true); err == nil {
+				body = updated
+			}
+		} else if updated, err := sjson.DeleteBytes(body, "stream_options"); err == nil {
+			body = updated
+		}
+	}
+	return body, uri
+}
+
+func rewriteGeminiStreamURI(uri string, stream bool) string {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return uri
+	}
+	query := u.Query()
+	if stream {
+		u.Path = strings.Replace(u.Path, ":generateContent", ":streamGenerateContent", 1)
+		query.Set("alt", "sse")
+	} else {
+		u.Path = strings.Replace(u.Path, ":streamGenerateContent", ":generateContent", 1)
+		query.Del("alt")
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// StreamModeWriter converts between streaming and non-streaming responses when
+// the upstream stream mode differs from what the client requested.
+// It buffers the whole upstream response and rewrites it in Finalize:
+//   - upstream streams, client wants JSON: SSE events are aggregated into one JSON response
+//   - upstream returns JSON, client wants SSE: the response is split into SSE events
+//
+// Data stays in clientType format (the upstream format); any cross-format
+// conversion is done by the ConvertingResponseWriter it wraps.
+type StreamModeWriter struct {
+	underlying     http.ResponseWriter
+	clientType     domain.ClientType
+	upstreamStream bool
+	statusCode     int
+	wroteHeader    bool
+	buffer         bytes.Buffer
+}
+
+// NewStreamModeWriter creates a new StreamModeWriter
+func NewStreamModeWriter(w http.ResponseWriter, clientType domain.ClientType, upstreamStream bool) *StreamModeWriter {
+	return &StreamModeWriter{
+		underlying:     w,
+		clientType:     clientType,
+		upstreamStream: upstreamStream,
+		statusCode:     http.StatusOK,
+	}
+}
+
+// Header returns the header map
+func (s *StreamModeWriter) Header() http.Header {
+	return s.underlying.Header()
+}
+
+// WriteHeader captures the status code until Finalize
+func (s *StreamModeWriter) WriteHeader(code int) {
+	s.statusCode = code
+	s.wroteHeader = true
+}
+
+// Write buffers the upstream response
+func (s *StreamModeWriter) Write(b []byte) (int, error) {
+	s.wroteHeader = true
+	return s.buffer.Write(b)
+}
+
+// Flush is a no-op: nothing is sent to the client before Finalize
+func (s *StreamModeWriter) Flush() {}
+
+// Finalize rewrites the buffered response and writes it to the underlying writer.
+// Error responses and bodies that fail to convert are passed through unchanged.
+func (s *StreamModeWriter) Finalize() error {
+	if !s.wroteHeader {
+		return nil // adapter failed before writing anything; keep the writer clean for retries
+	}
+
+	body := s.buffer.Bytes()
+	header := s.underlying.Header()
+
+	if s.statusCode < http.StatusBadRequest {
+		var converted []byte
+		var err error
+		if s.upstreamStream {
+			converted, err = converter.AggregateStream(s.clientType, body)
+		} else {
+			converted, err = converter.SynthesizeStream(s.clientType, body)
+		}
+
+		if err != nil {
+			log.Printf("[Executor] Stream mode conversion failed, passing through upstream response: %v", err)
+		} else {
+			body = converted
+			header.Del("Content-Length")
+			if s.upstreamStream {
+				header.Set("Content-Type", "application/json")
+				header.Del("Cache-Control")
+				header.Del("X-Accel-Buffering")
+			} else {
+				header.Set("Content-Type", "text/event-stream")
+				header.Set("Cache-Control", "no-cache")
+			}
+		}
+	}
+
+	s.underlying.WriteHeader(s.statusCode)
+	_, err := s.underlying.Write(body)
+	if !s.upstreamStream {
+		if f, ok := s.underlying.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	return err
+}
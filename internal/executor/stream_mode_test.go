@@ -0,0 +1,81 @@
+package executor
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestStreamModeWriterAggregatesStream(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", "text/event-stream")
+	w := NewStreamModeWriter(rec, domain.ClientTypeOpenAI, true)
+
+	w.WriteHeader(200)
+	w.Write([]byte("data: {\"id\":\"c1\",\"object\":\"chat.completion.chunk\",\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\",\"content\":\"hi\"}}]}\n\n"))
+	w.Write([]byte("data: {\"id\":\"c1\",\"object\":\"chat.completion.chunk\",\"model\":\"m\",\"choices\":[{\"index\":0,\"delta\":{},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n"))
+	if err := w.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `"content":"hi"`) || strings.Contains(body, "data:") {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+func TestStreamModeWriterSynthesizesStream(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rec.Header().Set("Content-Type", "application/json")
+	w := NewStreamModeWriter(rec, domain.ClientTypeClaude, false)
+
+	w.WriteHeader(200)
+	w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","model":"m","content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`))
+	if err := w.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+
+	if ct := rec.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+	body := rec.Body.String()
+	for _, event := range []string{"message_start", "content_block_delta", "message_stop"} {
+		if !strings.Contains(body, "event: "+event) {
+			t.Errorf("missing %s event in body: %s", event, body)
+		}
+	}
+}
+
+func TestStreamModeWriterPassesThroughErrors(t *testing.T) {
+	rec := httptest.NewRecorder()
+	w := NewStreamModeWriter(rec, domain.ClientTypeClaude, false)
+
+	w.WriteHeader(429)
+	w.Write([]byte(`{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`))
+	if err := w.Finalize(); err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	if rec.Code != 429 || !strings.Contains(rec.Body.String(), "rate_limit_error") {
+		t.Errorf("error response not passed through: %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestApplyUpstreamStream(t *testing.T) {
+	body, _ := applyUpstreamStream([]byte(`{"model":"m","stream":true,"stream_options":{"include_usage":true}}`), "/v1/chat/completions", domain.ClientTypeOpenAI, false)
+	if strings.Contains(string(body), "stream_options") || !strings.Contains(string(body), `"stream":false`) {
+		t.Errorf("unexpected OpenAI body: %s", body)
+	}
+
+	_, uri := applyUpstreamStream(nil, "/v1beta/models/gemini-2.5-pro:generateContent", domain.ClientTypeGemini, true)
+	if uri != "/v1beta/models/gemini-2.5-pro:streamGenerateContent?alt=sse" {
+		t.Errorf("stream URI = %q", uri)
+	}
+	_, uri = applyUpstreamStream(nil, uri, domain.ClientTypeGemini, false)
+	if uri != "/v1beta/models/gemini-2.5-pro:generateContent" {
+		t.Errorf("non-stream URI = %q", uri)
+	}
+}
@@ -0,0 +1,397 @@
+package executor
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// getStreamTextRewrites returns the provider's stream text rewrite rules, skipping empty ones
+func getStreamTextRewrites(p *domain.Provider) []domain.StreamTextRewrite {
+	if p == nil || p.Config == nil {
+		return nil
+	}
+	var rules []domain.StreamTextRewrite
+	for _, rule := range p.Config.StreamTextRewrites {
+		if rule.Find != "" {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// textRewriter applies literal replacement rules left to right without
+// overlapping matches; at each position the first matching rule wins
+type textRewriter []domain.StreamTextRewrite
+
+// rewrite rewrites s and returns the text that can be sent now. Unless final,
+// a tail that is the start of some rule's Find is returned as pending, to be
+// prepended to the next delta of the same text stream.
+func (rules textRewriter) rewrite(s string, final bool) (out, pending string) {
+	var b strings.Builder
+	i := 0
+scan:
+	for i < len(s) {
+		rest := s[i:]
+		for _, rule := range rules {
+			if strings.HasPrefix(rest, rule.Find) {
+				b.WriteString(rule.Replace)
+				i += len(rule.Find)
+				continue scan
+			}
+			// 可能与后续增量拼成匹配：等待更多数据
+			if !final && len(rest) < len(rule.Find) && strings.HasPrefix(rule.Find, rest) {
+				return b.String(), rest
+			}
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String(), ""
+}
+
+// textSlot is one text field inside a client-format SSE event
+type textSlot struct {
+	path string // gjson/sjson path of the text field
+	key  string // text stream the field belongs to (content block, choice, candidate...)
+
+	// full: the field holds the complete text (e.g. Codex *.done events)
+	// final: no more deltas follow for the key, nothing may be held back
+	full  bool
+	final bool
+
+	// template/templatePath build a standalone delta event carrying held-back text
+	template     []byte
+	templatePath string
+}
+
+// pendingText is text held back for a stream until the next delta shows whether it completes a match
+type pendingText struct {
+	text         string
+	event        string
+	template     []byte
+	templatePath string
+}
+
+// streamRewriteWriter rewrites the text deltas of a client-format SSE stream
+// with the provider's StreamTextRewrites. It works on whole SSE events: bytes
+// are buffered until an event is complete, the text fields of the format's
+// delta events are rewritten in place and everything else (framing, other
+// fields, tool calls, thinking) passes through unchanged.
+//
+// A match may span several deltas: the tail of a delta that could be the
+// start of a match is held back and prepended to the next delta of the same
+// text stream. Held-back text is released as its own delta event when any
+// other event arrives (block end, finish, usage...) or on Finish.
+// Non-2xx and non-SSE responses pass through untouched.
+type streamRewriteWriter struct {
+	http.ResponseWriter
+	clientType domain.ClientType
+	rules      textRewriter
+
+	decided     bool
+	passthrough bool
+	buf         []byte
+
+	pending map[string]*pendingText
+	order   []string // keys with held-back text, in arrival order
+}
+
+func newStreamRewriteWriter(w http.ResponseWriter, clientType domain.ClientType, rules []domain.StreamTextRewrite) *streamRewriteWriter {
+	return &streamRewriteWriter{
+		ResponseWriter: w,
+		clientType:     clientType,
+		rules:          rules,
+		pending:        make(map[string]*pendingText),
+	}
+}
+
+func (sw *streamRewriteWriter) WriteHeader(code int) {
+	if !sw.decided {
+		sw.decide(code)
+	}
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *streamRewriteWriter) decide(code int) {
+	sw.decided = true
+	sw.passthrough = code < 200 || code >= 300 ||
+		!strings.Contains(sw.ResponseWriter.Header().Get("Content-Type"), "text/event-stream")
+}
+
+func (sw *streamRewriteWriter) Write(b []byte) (int, error) {
+	if !sw.decided {
+		sw.decide(http.StatusOK)
+	}
+	if sw.passthrough {
+		return sw.ResponseWriter.Write(b)
+	}
+
+	sw.buf = append(sw.buf, b...)
+	var out []byte
+	for {
+		end := sseEventEnd(sw.buf)
+		if end < 0 {
+			break
+		}
+		out = append(out, sw.processEvent(sw.buf[:end])...)
+		sw.buf = sw.buf[end:]
+	}
+	if len(out) > 0 {
+		if _, err := sw.ResponseWriter.Write(out); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush implements http.Flusher; incomplete events stay buffered
+func (sw *streamRewriteWriter) Flush() {
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Finish releases held-back text and any trailing incomplete event. Called once the attempt ends.
+func (sw *streamRewriteWriter) Finish() error {
+	if sw.passthrough {
+		return nil
+	}
+	out := sw.flushAll()
+	out = append(out, sw.buf...)
+	sw.buf = nil
+	if len(out) == 0 {
+		return nil
+	}
+	_, err := sw.ResponseWriter.Write(out)
+	sw.Flush()
+	return err
+}
+
+// sseEventEnd returns the length of the first complete event in buf, or -1
+func sseEventEnd(buf []byte) int {
+	lf := bytes.Index(buf, []byte("\n\n"))
+	crlf := bytes.Index(buf, []byte("\r\n\r\n"))
+	switch {
+	case crlf >= 0 && (lf < 0 || crlf < lf):
+		return crlf + 4
+	case lf >= 0:
+		return lf + 2
+	}
+	return -1
+}
+
+// processEvent rewrites one complete SSE event (including its terminating blank line)
+func (sw *streamRewriteWriter) processEvent(block []byte) []byte {
+	lines := strings.Split(strings.ReplaceAll(string(block), "\r\n", "\n"), "\n")
+	dataLine := -1
+	var event string
+	for i, line := range lines {
+		switch {
+		case strings.HasPrefix(line, "data:"):
+			if dataLine >= 0 {
+				return append(sw.flushAll(), block...) // 多行 data 不改写
+			}
+			dataLine = i
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		}
+	}
+	if dataLine < 0 {
+		return block // 注释（心跳）或空事件
+	}
+	data := []byte(strings.TrimSpace(strings.TrimPrefix(lines[dataLine], "data:")))
+	if !gjson.ValidBytes(data) {
+		return append(sw.flushAll(), block...)
+	}
+
+	slots := textSlots(sw.clientType, data)
+	if len(slots) == 0 {
+		if event == "ping" || gjson.GetBytes(data, "type").String() == "ping" {
+			return block
+		}
+		return append(sw.flushAll(), block...)
+	}
+
+	var prefix []byte
+	for _, slot := range slots {
+		text := gjson.GetBytes(data, slot.path).String()
+		var out string
+		if slot.full {
+			prefix = append(prefix, sw.flushKey(slot.key)...)
+			out, _ = sw.rules.rewrite(text, true)
+		} else {
+			var held string
+			if p := sw.pending[slot.key]; p != nil {
+				held = p.text
+			}
+			var rest string
+			out, rest = sw.rules.rewrite(held+text, slot.final)
+			sw.setPending(slot, event, rest)
+		}
+		if updated, err := sjson.SetBytes(data, slot.path, out); err == nil {
+			data = updated
+		}
+	}
+
+	lines[dataLine] = "data: " + string(data)
+	return append(prefix, strings.Join(lines, "\n")...)
+}
+
+func (sw *streamRewriteWriter) setPending(slot textSlot, event, text string) {
+	if text == "" {
+		if _, ok := sw.pending[slot.key]; ok {
+			delete(sw.pending, slot.key)
+			sw.order = removeString(sw.order, slot.key)
+		}
+		return
+	}
+	if _, ok := sw.pending[slot.key]; !ok {
+		sw.order = append(sw.order, slot.key)
+	}
+	sw.pending[slot.key] = &pendingText{text: text, event: event, template: slot.template, templatePath: slot.templatePath}
+}
+
+// flushKey releases the held-back text of one stream as a delta event
+func (sw *streamRewriteWriter) flushKey(key string) []byte {
+	p, ok := sw.pending[key]
+	if !ok {
+		return nil
+	}
+	delete(sw.pending, key)
+	sw.order = removeString(sw.order, key)
+
+	data, err := sjson.SetBytes(p.template, p.templatePath, p.text)
+	if err != nil {
+		return nil
+	}
+	var b bytes.Buffer
+	if p.event != "" {
+		b.WriteString("event: " + p.event + "\n")
+	}
+	b.WriteString("data: ")
+	b.Write(data)
+	b.WriteString("\n\n")
+	return b.Bytes()
+}
+
+func (sw *streamRewriteWriter) flushAll() []byte {
+	var out []byte
+	for len(sw.order) > 0 {
+		out = append(out, sw.flushKey(sw.order[0])...)
+	}
+	return out
+}
+
+func removeString(list []string, s string) []string {
+	for i, v := range list {
+		if v == s {
+			return append(list[:i], list[i+1:]...)
+		}
+	}
+	return list
+}
+
+// textSlots finds the content text fields of a client-format stream event
+func textSlots(clientType domain.ClientType, data []byte) []textSlot {
+	root := gjson.ParseBytes(data)
+	var slots []textSlot
+	switch clientType {
+	case domain.ClientTypeClaude:
+		// content_block_delta + text_delta（thinking_delta、input_json_delta 不改写）
+		if root.Get("type").String() == "content_block_delta" && root.Get("delta.type").String() == "text_delta" {
+			slots = append(slots, textSlot{
+				path: "delta.text", key: "block:" + root.Get("index").Raw,
+				template: data, templatePath: "delta.text",
+			})
+		}
+
+	case domain.ClientTypeOpenAI:
+		root.Get("choices").ForEach(func(i, choice gjson.Result) bool {
+			content := choice.Get("delta.content")
+			if content.Type != gjson.String {
+				return true
+			}
+			index := choice.Get("index").Int()
+			template, _ := sjson.SetRawBytes(data, "choices", []byte(fmt.Sprintf(`[{"index":%d,"delta":{"content":""},"finish_reason":null}]`, index)))
+			template, _ = sjson.DeleteBytes(template, "usage")
+			finish := choice.Get("finish_reason")
+			slots = append(slots, textSlot{
+				path:     fmt.Sprintf("choices.%d.delta.content", i.Int()),
+				key:      fmt.Sprintf("choice:%d", index),
+				final:    finish.Exists() && finish.Type != gjson.Null,
+				template: template, templatePath: "choices.0.delta.content",
+			})
+			return true
+		})
+
+	case domain.ClientTypeCodex:
+		key := func(output, content gjson.Result) string {
+			return "output:" + output.Raw + ":" + content.Raw
+		}
+		switch root.Get("type").String() {
+		case "response.output_text.delta":
+			slots = append(slots, textSlot{
+				path: "delta", key: key(root.Get("output_index"), root.Get("content_index")),
+				template: data, templatePath: "delta",
+			})
+		case "response.output_text.done":
+			slots = append(slots, textSlot{path: "text", key: key(root.Get("output_index"), root.Get("content_index")), full: true})
+		case "response.content_part.done":
+			if root.Get("part.type").String() == "output_text" {
+				slots = append(slots, textSlot{path: "part.text", key: key(root.Get("output_index"), root.Get("content_index")), full: true})
+			}
+		case "response.output_item.done":
+			output := root.Get("output_index")
+			root.Get("item.content").ForEach(func(k, part gjson.Result) bool {
+				if part.Get("type").String() == "output_text" {
+					slots = append(slots, textSlot{path: fmt.Sprintf("item.content.%d.text", k.Int()), key: key(output, k), full: true})
+				}
+				return true
+			})
+		case "response.completed":
+			root.Get("response.output").ForEach(func(o, item gjson.Result) bool {
+				item.Get("content").ForEach(func(k, part gjson.Result) bool {
+					if part.Get("type").String() == "output_text" {
+						slots = append(slots, textSlot{
+							path: fmt.Sprintf("response.output.%d.content.%d.text", o.Int(), k.Int()),
+							key:  "output:" + o.String() + ":" + k.String(), full: true,
+						})
+					}
+					return true
+				})
+				return true
+			})
+		}
+
+	case domain.ClientTypeGemini:
+		root.Get("candidates").ForEach(func(c, candidate gjson.Result) bool {
+			index := candidate.Get("index").Int()
+			template, _ := sjson.SetRawBytes(data, "candidates", []byte(fmt.Sprintf(`[{"index":%d,"content":{"role":"model","parts":[{"text":""}]}}]`, index)))
+			template, _ = sjson.DeleteBytes(template, "usageMetadata")
+			var last = -1
+			candidate.Get("content.parts").ForEach(func(p, part gjson.Result) bool {
+				if part.Get("text").Type == gjson.String && !part.Get("thought").Bool() {
+					slots = append(slots, textSlot{
+						path:     fmt.Sprintf("candidates.%d.content.parts.%d.text", c.Int(), p.Int()),
+						key:      fmt.Sprintf("candidate:%d", index),
+						template: template, templatePath: "candidates.0.content.parts.0.text",
+					})
+					last = len(slots) - 1
+				}
+				return true
+			})
+			// 同一候选的多个文本片段按顺序衔接，只有最后一个片段在结束时不暂存
+			if last >= 0 && candidate.Get("finishReason").Exists() {
+				slots[last].final = true
+			}
+			return true
+		})
+	}
+	return slots
+}
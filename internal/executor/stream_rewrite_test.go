@@ -0,0 +1,112 @@
+package executor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/tidwall/gjson"
+)
+
+var disclaimerRules = []domain.StreamTextRewrite{{Find: "[Notice: AI generated] ", Replace: ""}}
+
+// streamText writes chunks through a rewrite writer and returns the client
+// body plus the concatenated text found at path in every data event
+func streamText(t *testing.T, clientType domain.ClientType, rules []domain.StreamTextRewrite, path string, chunks ...string) (string, string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	sw := newStreamRewriteWriter(rec, clientType, rules)
+	sw.Header().Set("Content-Type", "text/event-stream")
+	sw.WriteHeader(http.StatusOK)
+	for _, chunk := range chunks {
+		if _, err := sw.Write([]byte(chunk)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	if err := sw.Finish(); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+
+	var text strings.Builder
+	for _, line := range strings.Split(rec.Body.String(), "\n") {
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			text.WriteString(gjson.Get(data, path).String())
+		}
+	}
+	return rec.Body.String(), text.String()
+}
+
+func TestStreamRewriteClaudeAcrossChunks(t *testing.T) {
+	delta := func(s string) string {
+		return `event: content_block_delta` + "\n" +
+			`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"` + s + `"}}` + "\n\n"
+	}
+	event := func(name string) string {
+		return "event: " + name + "\ndata: {\"type\":\"" + name + "\"}\n\n"
+	}
+	body := delta("[Notice: AI") + event("ping") + delta(" generated] Hel") + delta("lo")
+	// 事件本身也可能被拆到多个 chunk 中
+	body1, body2 := body[:30], body[30:]
+	out, text := streamText(t, domain.ClientTypeClaude, disclaimerRules, "delta.text",
+		body1, body2, `event: content_block_stop`+"\n"+`data: {"type":"content_block_stop","index":0}`+"\n\n")
+
+	if text != "Hello" {
+		t.Errorf("text = %q, want %q\nbody:\n%s", text, "Hello", out)
+	}
+	if !strings.Contains(out, "event: ping\n") || !strings.HasSuffix(out, `{"type":"content_block_stop","index":0}`+"\n\n") {
+		t.Errorf("non-text events not passed through:\n%s", out)
+	}
+}
+
+func TestStreamRewriteHeldTextFlushed(t *testing.T) {
+	// "[Notice" 是查找串的前缀，但后续内容不匹配：暂存的文本需在下一个增量或流结束时原样发出
+	chunk := func(s string) string {
+		return `data: {"id":"c1","choices":[{"index":0,"delta":{"content":"` + s + `"},"finish_reason":null}]}` + "\n\n"
+	}
+	_, text := streamText(t, domain.ClientTypeOpenAI, disclaimerRules, "choices.0.delta.content",
+		chunk("a [Notice"), chunk(": something else"), chunk(" [No"))
+	if text != "a [Notice: something else [No" {
+		t.Errorf("text = %q", text)
+	}
+
+	// finish_reason 所在的 chunk 不再暂存；usage chunk 前释放暂存内容
+	out, text := streamText(t, domain.ClientTypeOpenAI, []domain.StreamTextRewrite{{Find: "foo", Replace: "bar"}}, "choices.0.delta.content",
+		chunk("f"), chunk("o"), `data: {"id":"c1","choices":[],"usage":{"total_tokens":3}}`+"\n\n", "data: [DONE]\n\n")
+	if text != "fo" || !strings.HasSuffix(out, "data: [DONE]\n\n") {
+		t.Errorf("text = %q, body:\n%s", text, out)
+	}
+	if strings.Index(out, `"content":"fo"`) > strings.Index(out, `"usage"`) {
+		t.Errorf("held text released after usage chunk:\n%s", out)
+	}
+}
+
+func TestStreamRewriteCodexDoneEvents(t *testing.T) {
+	rules := []domain.StreamTextRewrite{{Find: "secret", Replace: "******"}}
+	out, text := streamText(t, domain.ClientTypeCodex, rules, "delta",
+		`data: {"type":"response.output_text.delta","output_index":0,"content_index":0,"delta":"a sec"}`+"\n\n",
+		`data: {"type":"response.output_text.delta","output_index":0,"content_index":0,"delta":"ret!"}`+"\n\n",
+		`data: {"type":"response.output_text.done","output_index":0,"content_index":0,"text":"a secret!"}`+"\n\n",
+		`data: {"type":"response.completed","response":{"output":[{"type":"message","content":[{"type":"output_text","text":"a secret!"}]}]}}`+"\n\n")
+	if text != "a ******!" {
+		t.Errorf("delta text = %q", text)
+	}
+	if strings.Contains(out, "secret") {
+		t.Errorf("done events not rewritten:\n%s", out)
+	}
+}
+
+func TestStreamRewritePassThrough(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sw := newStreamRewriteWriter(rec, domain.ClientTypeClaude, disclaimerRules)
+	sw.Header().Set("Content-Type", "application/json")
+	body := `{"content":[{"type":"text","text":"[Notice: AI generated] hi"}]}`
+	_, _ = sw.Write([]byte(body))
+	if err := sw.Finish(); err != nil {
+		t.Fatalf("Finish: %v", err)
+	}
+	if rec.Body.String() != body {
+		t.Errorf("non-SSE body = %q, want unchanged", rec.Body.String())
+	}
+}
@@ -0,0 +1,95 @@
+package executor
+
+import (
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// samplingParamPaths 采样参数在各格式请求体中的字段路径，未列出的格式不支持该参数
+// Provider 配置中不在此表内的参数名按同名顶层字段处理
+var samplingParamPaths = map[string]map[domain.ClientType][]string{
+	"temperature": {
+		domain.ClientTypeClaude: {"temperature"},
+		domain.ClientTypeOpenAI: {"temperature"},
+		domain.ClientTypeCodex:  {"temperature"},
+		domain.ClientTypeGemini: {"generationConfig.temperature"},
+	},
+	"top_p": {
+		domain.ClientTypeClaude: {"top_p"},
+		domain.ClientTypeOpenAI: {"top_p"},
+		domain.ClientTypeCodex:  {"top_p"},
+		domain.ClientTypeGemini: {"generationConfig.topP"},
+	},
+	"top_k": {
+		domain.ClientTypeClaude: {"top_k"},
+		domain.ClientTypeOpenAI: {"top_k"},
+		domain.ClientTypeGemini: {"generationConfig.topK"},
+	},
+	"frequency_penalty": {
+		domain.ClientTypeOpenAI: {"frequency_penalty"},
+		domain.ClientTypeGemini: {"generationConfig.frequencyPenalty"},
+	},
+	"presence_penalty": {
+		domain.ClientTypeOpenAI: {"presence_penalty"},
+		domain.ClientTypeGemini: {"generationConfig.presencePenalty"},
+	},
+	"seed": {
+		domain.ClientTypeOpenAI: {"seed"},
+		domain.ClientTypeGemini: {"generationConfig.seed"},
+	},
+	"stop": {
+		domain.ClientTypeClaude: {"stop_sequences"},
+		domain.ClientTypeOpenAI: {"stop"},
+		domain.ClientTypeGemini: {"generationConfig.stopSequences"},
+	},
+	"logit_bias": {
+		domain.ClientTypeOpenAI: {"logit_bias"},
+	},
+	"logprobs": {
+		domain.ClientTypeOpenAI: {"logprobs", "top_logprobs"},
+		domain.ClientTypeGemini: {"generationConfig.responseLogprobs", "generationConfig.logprobs"},
+	},
+	"n": {
+		domain.ClientTypeOpenAI: {"n"},
+		domain.ClientTypeGemini: {"generationConfig.candidateCount"},
+	},
+}
+
+// providerUnsupportedParams returns the request parameters the provider rejects
+func providerUnsupportedParams(p *domain.Provider) []string {
+	if p == nil || p.Config == nil {
+		return nil
+	}
+	return p.Config.UnsupportedParams
+}
+
+// stripUnsupportedParams removes the given parameters from body (in clientType
+// format) so the provider does not reject the request with a 400. It returns
+// the rewritten body and the parameter names that were present and removed.
+func stripUnsupportedParams(body []byte, clientType domain.ClientType, params []string) ([]byte, []string) {
+	var stripped []string
+	for _, param := range params {
+		paths, known := samplingParamPaths[param]
+		fields := paths[clientType]
+		if !known {
+			fields = []string{param}
+		}
+		removed := false
+		for _, path := range fields {
+			if !gjson.GetBytes(body, path).Exists() {
+				continue
+			}
+			updated, err := sjson.DeleteBytes(body, path)
+			if err != nil {
+				continue
+			}
+			body = updated
+			removed = true
+		}
+		if removed {
+			stripped = append(stripped, param)
+		}
+	}
+	return body, stripped
+}
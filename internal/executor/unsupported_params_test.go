@@ -0,0 +1,54 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/tidwall/gjson"
+)
+
+func TestStripUnsupportedParams(t *testing.T) {
+	tests := []struct {
+		name         string
+		clientType   domain.ClientType
+		body         string
+		params       []string
+		gone         []string
+		kept         []string
+		wantStripped []string
+	}{
+		{"claude top_k", domain.ClientTypeClaude, `{"top_k":40,"temperature":0.5}`, []string{"top_k"},
+			[]string{"top_k"}, []string{"temperature"}, []string{"top_k"}},
+		{"claude stop", domain.ClientTypeClaude, `{"stop_sequences":["x"]}`, []string{"stop"},
+			[]string{"stop_sequences"}, nil, []string{"stop"}},
+		{"gemini generationConfig", domain.ClientTypeGemini, `{"generationConfig":{"topK":40,"topP":0.9}}`, []string{"top_k", "seed"},
+			[]string{"generationConfig.topK"}, []string{"generationConfig.topP"}, []string{"top_k"}},
+		{"openai logprobs", domain.ClientTypeOpenAI, `{"logprobs":true,"top_logprobs":3,"seed":1}`, []string{"logprobs"},
+			[]string{"logprobs", "top_logprobs"}, []string{"seed"}, []string{"logprobs"}},
+		{"not in format", domain.ClientTypeCodex, `{"top_k":40}`, []string{"top_k"},
+			nil, []string{"top_k"}, nil},
+		{"unknown param as top-level field", domain.ClientTypeOpenAI, `{"reasoning_effort":"high","model":"m"}`, []string{"reasoning_effort"},
+			[]string{"reasoning_effort"}, []string{"model"}, []string{"reasoning_effort"}},
+		{"absent", domain.ClientTypeClaude, `{"model":"m"}`, []string{"top_k", "temperature"},
+			nil, []string{"model"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stripped := stripUnsupportedParams([]byte(tt.body), tt.clientType, tt.params)
+			for _, path := range tt.gone {
+				if gjson.GetBytes(got, path).Exists() {
+					t.Errorf("%s still present in %s", path, got)
+				}
+			}
+			for _, path := range tt.kept {
+				if !gjson.GetBytes(got, path).Exists() {
+					t.Errorf("%s removed from %s", path, got)
+				}
+			}
+			if !reflect.DeepEqual(stripped, tt.wantStripped) {
+				t.Errorf("stripped = %v, want %v", stripped, tt.wantStripped)
+			}
+		})
+	}
+}
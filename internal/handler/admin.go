@@ -2,6 +2,7 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strconv"
@@ -21,14 +22,16 @@ type AdminHandler struct {
 	svc       *service.AdminService
 	backupSvc *service.BackupService
 	logPath   string
+	logStream *LogStream
 }
 
-// NewAdminHandler creates a new admin handler
-func NewAdminHandler(svc *service.AdminService, backupSvc *service.BackupService, logPath string) *AdminHandler {
+// NewAdminHandler creates a new admin handler; logStream may be nil when logs aren't captured
+func NewAdminHandler(svc *service.AdminService, backupSvc *service.BackupService, logPath string, logStream *LogStream) *AdminHandler {
 	return &AdminHandler{
 		svc:       svc,
 		backupSvc: backupSvc,
 		logPath:   logPath,
+		logStream: logStream,
 	}
 }
 
@@ -55,6 +58,8 @@ func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case "routes":
 		if len(parts) > 2 && parts[2] == "batch-positions" {
 			h.handleBatchUpdateRoutePositions(w, r)
+		} else if len(parts) > 2 && parts[2] == "compare" {
+			h.handleCompareRoutes(w, r)
 		} else {
 			h.handleRoutes(w, r, id)
 		}
@@ -75,25 +80,73 @@ func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case "provider-stats":
 		h.handleProviderStats(w, r)
 	case "cooldowns":
-		h.handleCooldowns(w, r, id)
+		if len(parts) > 3 && parts[3] == "reset" && id > 0 {
+			h.handleResetProviderHealth(w, r, id)
+		} else if len(parts) > 3 && parts[3] == "history" && id > 0 {
+			h.handleCooldownHistory(w, r, id)
+		} else if len(parts) == 3 && parts[2] == "metrics" {
+			h.handleBreakerMetrics(w, r)
+		} else {
+			h.handleCooldowns(w, r, id)
+		}
+	case "provider-groups":
+		h.handleProviderGroups(w, r)
+	case "provider-health":
+		h.handleProviderHealth(w, r)
+	case "capabilities":
+		h.handleCapabilities(w, r)
 	case "logs":
-		h.handleLogs(w, r)
+		if len(parts) > 2 && parts[2] == "stream" {
+			h.handleLogStream(w, r)
+		} else {
+			h.handleLogs(w, r)
+		}
 	case "api-tokens":
-		h.handleAPITokens(w, r, id)
+		if len(parts) > 2 && parts[2] == "stale" {
+			h.handleStaleAPITokens(w, r)
+		} else {
+			h.handleAPITokens(w, r, id)
+		}
 	case "model-mappings":
 		h.handleModelMappings(w, r, id)
 	case "usage-stats":
 		h.handleUsageStats(w, r)
+	case "stats":
+		if len(parts) == 3 && parts[2] == "aggregate-now" {
+			h.handleAggregateStatsNow(w, r)
+		} else {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		}
 	case "dashboard":
-		h.handleDashboard(w, r)
+		h.handleDashboard(w, r, parts)
 	case "response-models":
-		h.handleResponseModels(w, r)
+		if len(parts) > 2 && parts[2] == "rebuild" {
+			h.handleRebuildResponseModels(w, r)
+		} else {
+			h.handleResponseModels(w, r)
+		}
+	case "client-ips":
+		h.handleClientIPStats(w, r)
+	case "errors":
+		if len(parts) > 2 && parts[2] == "summary" {
+			h.handleErrorSummary(w, r)
+		} else {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		}
 	case "backup":
 		h.handleBackup(w, r, parts)
 	case "pricing":
 		h.handlePricing(w, r)
 	case "model-prices":
 		h.handleModelPrices(w, r, id)
+	case "export":
+		h.handleExport(w, r, parts)
+	case "debug":
+		if len(parts) > 2 && parts[2] == "resolve" {
+			h.handleResolveRequest(w, r)
+		} else {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		}
 	default:
 		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
 	}
@@ -103,6 +156,10 @@ func (h *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (h *AdminHandler) handleProviders(w http.ResponseWriter, r *http.Request, id uint64) {
 	// Check for special endpoints
 	path := r.URL.Path
+	if strings.HasSuffix(path, "/credentials/export") || strings.HasSuffix(path, "/credentials/import") {
+		h.handleProviderCredentials(w, r, id)
+		return
+	}
 	if strings.HasSuffix(path, "/export") {
 		h.handleProvidersExport(w, r)
 		return
@@ -111,6 +168,10 @@ func (h *AdminHandler) handleProviders(w http.ResponseWriter, r *http.Request, i
 		h.handleProvidersImport(w, r)
 		return
 	}
+	if strings.HasSuffix(path, "/drain") {
+		h.handleProviderDrain(w, r, id)
+		return
+	}
 
 	switch r.Method {
 	case http.MethodGet:
@@ -136,7 +197,11 @@ func (h *AdminHandler) handleProviders(w http.ResponseWriter, r *http.Request, i
 			return
 		}
 		if err := h.svc.CreateProvider(&provider); err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			status := http.StatusInternalServerError
+			if errors.Is(err, domain.ErrInvalidInput) {
+				status = http.StatusBadRequest
+			}
+			writeJSON(w, status, map[string]string{"error": err.Error()})
 			return
 		}
 		writeJSON(w, http.StatusCreated, provider)
@@ -162,7 +227,11 @@ func (h *AdminHandler) handleProviders(w http.ResponseWriter, r *http.Request, i
 		provider.ID = existing.ID
 		provider.CreatedAt = existing.CreatedAt
 		if err := h.svc.UpdateProvider(&provider); err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			status := http.StatusInternalServerError
+			if errors.Is(err, domain.ErrInvalidInput) {
+				status = http.StatusBadRequest
+			}
+			writeJSON(w, status, map[string]string{"error": err.Error()})
 			return
 		}
 		writeJSON(w, http.StatusOK, provider)
@@ -200,6 +269,79 @@ func (h *AdminHandler) handleProvidersExport(w http.ResponseWriter, r *http.Requ
 	json.NewEncoder(w).Encode(providers)
 }
 
+// handleProviderDrain handles /providers/{id}/drain:
+// GET returns the drain status, POST starts draining, DELETE stops it
+func (h *AdminHandler) handleProviderDrain(w http.ResponseWriter, r *http.Request, id uint64) {
+	if id == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id required"})
+		return
+	}
+
+	var status *domain.ProviderDrainStatus
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		status, err = h.svc.GetProviderDrainStatus(id)
+	case http.MethodPost:
+		status, err = h.svc.SetProviderDraining(id, true)
+	case http.MethodDelete:
+		status, err = h.svc.SetProviderDraining(id, false)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "provider not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// credentialPassphraseHeader 凭证导出/导入的口令通过请求头传递，避免出现在 URL 和访问日志中
+const credentialPassphraseHeader = "X-Credential-Passphrase"
+
+// handleProviderCredentials exports / imports a provider's credentials encrypted with an operator-supplied passphrase
+func (h *AdminHandler) handleProviderCredentials(w http.ResponseWriter, r *http.Request, id uint64) {
+	if id == 0 {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "id required"})
+		return
+	}
+	passphrase := r.Header.Get(credentialPassphraseHeader)
+
+	var result any
+	var err error
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/export") && r.Method == http.MethodGet:
+		result, err = h.svc.ExportProviderCredentials(id, passphrase)
+	case strings.HasSuffix(r.URL.Path, "/import") && r.Method == http.MethodPost:
+		var bundle service.CredentialBundle
+		if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid JSON: " + err.Error()})
+			return
+		}
+		result, err = h.svc.ImportProviderCredentials(id, &bundle, passphrase)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrNotFound) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, domain.ErrInvalidInput) {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, result)
+}
+
 // handleProvidersImport imports providers from JSON
 func (h *AdminHandler) handleProvidersImport(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -213,7 +355,7 @@ func (h *AdminHandler) handleProvidersImport(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	result, err := h.svc.ImportProviders(providers)
+	result, err := h.svc.ImportProviders(providers, r.URL.Query().Get("renameOnConflict") == "true")
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		return
@@ -305,6 +447,21 @@ func (h *AdminHandler) handleRoutes(w http.ResponseWriter, r *http.Request, id u
 				existing.RetryConfigID = uint64(f)
 			}
 		}
+		if v, ok := updates["forceDetail"]; ok {
+			if b, ok := v.(bool); ok {
+				existing.ForceDetail = b
+			}
+		}
+		if v, ok := updates["multiplier"]; ok {
+			if f, ok := v.(float64); ok && f >= 0 {
+				existing.Multiplier = uint64(f)
+			}
+		}
+		if v, ok := updates["maxStreamDurationMs"]; ok {
+			if f, ok := v.(float64); ok && f >= 0 {
+				existing.MaxStreamDurationMs = int(f)
+			}
+		}
 		if err := h.svc.UpdateRoute(existing); err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 			return
@@ -346,6 +503,100 @@ func (h *AdminHandler) handleBatchUpdateRoutePositions(w http.ResponseWriter, r
 	writeJSON(w, http.StatusOK, map[string]string{"message": "positions updated successfully"})
 }
 
+// handleCompareRoutes replays recorded requests on two routes and returns a comparison report
+// POST /admin/routes/compare
+func (h *AdminHandler) handleCompareRoutes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var body struct {
+		RequestIDs []uint64 `json:"requestIDs"`
+		RouteA     uint64   `json:"routeA"`
+		RouteB     uint64   `json:"routeB"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	report, err := h.svc.CompareRoutes(r.Context(), body.RequestIDs, body.RouteA, body.RouteB)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, domain.ErrInvalidInput):
+			status = http.StatusBadRequest
+		case errors.Is(err, domain.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, service.ErrComparisonInProgress):
+			status = http.StatusTooManyRequests
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleTestProjectRoutes sends a canned request to every enabled route of a project
+// POST /admin/projects/{id}/test-routes（id 为 0 时测试全局路由）
+func (h *AdminHandler) handleTestProjectRoutes(w http.ResponseWriter, r *http.Request, projectID uint64) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var body struct {
+		ClientType domain.ClientType `json:"clientType"`
+		Prompt     string            `json:"prompt"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	report, err := h.svc.TestProjectRoutes(r.Context(), projectID, body.ClientType, body.Prompt)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, domain.ErrInvalidInput):
+			status = http.StatusBadRequest
+		case errors.Is(err, domain.ErrNotFound):
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleResolveRequest returns the routing decision for a hypothetical request
+// without dispatching it
+// POST /admin/debug/resolve
+func (h *AdminHandler) handleResolveRequest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var body domain.ResolveRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	resolution, err := h.svc.ResolveRequest(r.Context(), &body)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidInput) {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, resolution)
+}
+
 // Project handlers
 func (h *AdminHandler) handleProjects(w http.ResponseWriter, r *http.Request, id uint64, parts []string) {
 	// Check for by-slug endpoint: /admin/projects/by-slug/{slug}
@@ -353,6 +604,11 @@ func (h *AdminHandler) handleProjects(w http.ResponseWriter, r *http.Request, id
 		h.handleProjectBySlug(w, r, parts)
 		return
 	}
+	// /admin/projects/{id}/test-routes
+	if len(parts) > 3 && parts[3] == "test-routes" {
+		h.handleTestProjectRoutes(w, r, id)
+		return
+	}
 
 	switch r.Method {
 	case http.MethodGet:
@@ -378,7 +634,13 @@ func (h *AdminHandler) handleProjects(w http.ResponseWriter, r *http.Request, id
 			return
 		}
 		if err := h.svc.CreateProject(&project); err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			status := http.StatusInternalServerError
+			if errors.Is(err, domain.ErrInvalidInput) {
+				status = http.StatusBadRequest
+			} else if errors.Is(err, domain.ErrSlugExists) {
+				status = http.StatusConflict
+			}
+			writeJSON(w, status, map[string]string{"error": err.Error()})
 			return
 		}
 		writeJSON(w, http.StatusCreated, project)
@@ -410,7 +672,13 @@ func (h *AdminHandler) handleProjects(w http.ResponseWriter, r *http.Request, id
 		project.ID = existing.ID
 		project.CreatedAt = existing.CreatedAt
 		if err := h.svc.UpdateProject(&project); err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			status := http.StatusInternalServerError
+			if errors.Is(err, domain.ErrInvalidInput) {
+				status = http.StatusBadRequest
+			} else if errors.Is(err, domain.ErrSlugExists) {
+				status = http.StatusConflict
+			}
+			writeJSON(w, status, map[string]string{"error": err.Error()})
 			return
 		}
 		writeJSON(w, http.StatusOK, project)
@@ -451,8 +719,14 @@ func (h *AdminHandler) handleProjectBySlug(w http.ResponseWriter, r *http.Reques
 }
 
 // Session handlers
-// Routes: /admin/sessions, /admin/sessions/{sessionID}/project, /admin/sessions/{sessionID}/reject
+// Routes: /admin/sessions, /admin/sessions/merge, /admin/sessions/{sessionID}/project, /admin/sessions/{sessionID}/reject
 func (h *AdminHandler) handleSessions(w http.ResponseWriter, r *http.Request, parts []string) {
+	// Check for merge endpoint: /admin/sessions/merge
+	if len(parts) == 3 && parts[2] == "merge" {
+		h.handleMergeSessions(w, r)
+		return
+	}
+
 	// Check for sub-resource: /admin/sessions/{sessionID}/project
 	if len(parts) > 3 && parts[3] == "project" {
 		h.handleSessionProject(w, r, parts[2])
@@ -507,6 +781,38 @@ func (h *AdminHandler) handleSessionProject(w http.ResponseWriter, r *http.Reque
 	writeJSON(w, http.StatusOK, result)
 }
 
+// handleMergeSessions handles POST /admin/sessions/merge
+func (h *AdminHandler) handleMergeSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var body struct {
+		PrimarySessionID string   `json:"primarySessionID"`
+		SessionIDs       []string `json:"sessionIDs"`
+		RetagRequests    bool     `json:"retagRequests"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	result, err := h.svc.MergeSessions(body.PrimarySessionID, body.SessionIDs, body.RetagRequests)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, domain.ErrInvalidInput):
+			status = http.StatusBadRequest
+		case errors.Is(err, domain.ErrNotFound):
+			status = http.StatusNotFound
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
 // handleSessionReject handles POST /admin/sessions/{sessionID}/reject
 func (h *AdminHandler) handleSessionReject(w http.ResponseWriter, r *http.Request, sessionID string) {
 	if r.Method != http.MethodPost {
@@ -665,7 +971,7 @@ func (h *AdminHandler) handleRoutingStrategies(w http.ResponseWriter, r *http.Re
 }
 
 // ProxyRequest handlers
-// Routes: /admin/requests, /admin/requests/count, /admin/requests/active, /admin/requests/{id}, /admin/requests/{id}/attempts, /admin/requests/{id}/recalculate-cost
+// Routes: /admin/requests, /admin/requests/count, /admin/requests/active, /admin/requests/{id}, /admin/requests/{id}/attempts, /admin/requests/{id}/recalculate-cost, /admin/requests/{id}/notes
 func (h *AdminHandler) handleProxyRequests(w http.ResponseWriter, r *http.Request, id uint64, parts []string) {
 	// Check for count endpoint: /admin/requests/count
 	if len(parts) > 2 && parts[2] == "count" {
@@ -679,6 +985,12 @@ func (h *AdminHandler) handleProxyRequests(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Check for bulk delete endpoint: /admin/requests/delete
+	if len(parts) > 2 && parts[2] == "delete" {
+		h.handleDeleteProxyRequests(w, r)
+		return
+	}
+
 	// Check for sub-resource: /admin/requests/{id}/attempts
 	if len(parts) > 3 && parts[3] == "attempts" && id > 0 {
 		h.handleProxyUpstreamAttempts(w, r, id)
@@ -691,6 +1003,18 @@ func (h *AdminHandler) handleProxyRequests(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
+	// Check for sub-resource: /admin/requests/{id}/notes
+	if len(parts) > 3 && parts[3] == "notes" && id > 0 {
+		h.handleProxyRequestNotes(w, r, id)
+		return
+	}
+
+	// Check for sub-resource: /admin/requests/{id}/watch
+	if len(parts) > 3 && parts[3] == "watch" && id > 0 {
+		h.handleWatchProxyRequest(w, r, id)
+		return
+	}
+
 	switch r.Method {
 	case http.MethodGet:
 		if id > 0 {
@@ -717,8 +1041,10 @@ func (h *AdminHandler) handleProxyRequests(w http.ResponseWriter, r *http.Reques
 			var filter *repository.ProxyRequestFilter
 			providerIDStr := r.URL.Query().Get("providerId")
 			statusStr := r.URL.Query().Get("status")
+			clientIPStr := r.URL.Query().Get("clientIp")
+			experimentStr := r.URL.Query().Get("experiment")
 
-			if providerIDStr != "" || statusStr != "" {
+			if providerIDStr != "" || statusStr != "" || clientIPStr != "" || experimentStr != "" {
 				filter = &repository.ProxyRequestFilter{}
 				if providerIDStr != "" {
 					if providerID, err := strconv.ParseUint(providerIDStr, 10, 64); err == nil {
@@ -728,6 +1054,12 @@ func (h *AdminHandler) handleProxyRequests(w http.ResponseWriter, r *http.Reques
 				if statusStr != "" {
 					filter.Status = &statusStr
 				}
+				if clientIPStr != "" {
+					filter.ClientIP = &clientIPStr
+				}
+				if experimentStr != "" {
+					filter.Experiment = &experimentStr
+				}
 			}
 
 			result, err := h.svc.GetProxyRequestsCursor(limit, before, after, filter)
@@ -742,6 +1074,52 @@ func (h *AdminHandler) handleProxyRequests(w http.ResponseWriter, r *http.Reques
 	}
 }
 
+// handleDeleteProxyRequests deletes requests (and their attempts) matching a filter
+// POST /admin/requests/delete
+func (h *AdminHandler) handleDeleteProxyRequests(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var body struct {
+		Start      *time.Time `json:"start"` // RFC3339
+		End        *time.Time `json:"end"`   // RFC3339
+		Status     *string    `json:"status"`
+		ProjectID  *uint64    `json:"projectId"`
+		APITokenID *uint64    `json:"apiTokenId"`
+		ProviderID *uint64    `json:"providerId"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	filter := repository.ProxyRequestDeleteFilter{
+		Status:     body.Status,
+		ProjectID:  body.ProjectID,
+		APITokenID: body.APITokenID,
+		ProviderID: body.ProviderID,
+	}
+	if body.Start != nil {
+		filter.Start = *body.Start
+	}
+	if body.End != nil {
+		filter.End = *body.End
+	}
+
+	result, err := h.svc.DeleteRequests(filter)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidInput) {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
 // ProxyRequestsCount handler
 func (h *AdminHandler) handleProxyRequestsCount(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -753,8 +1131,10 @@ func (h *AdminHandler) handleProxyRequestsCount(w http.ResponseWriter, r *http.R
 	var filter *repository.ProxyRequestFilter
 	providerIDStr := r.URL.Query().Get("providerId")
 	statusStr := r.URL.Query().Get("status")
+	clientIPStr := r.URL.Query().Get("clientIp")
+	experimentStr := r.URL.Query().Get("experiment")
 
-	if providerIDStr != "" || statusStr != "" {
+	if providerIDStr != "" || statusStr != "" || clientIPStr != "" || experimentStr != "" {
 		filter = &repository.ProxyRequestFilter{}
 		if providerIDStr != "" {
 			providerID, err := strconv.ParseUint(providerIDStr, 10, 64)
@@ -767,6 +1147,12 @@ func (h *AdminHandler) handleProxyRequestsCount(w http.ResponseWriter, r *http.R
 		if statusStr != "" {
 			filter.Status = &statusStr
 		}
+		if clientIPStr != "" {
+			filter.ClientIP = &clientIPStr
+		}
+		if experimentStr != "" {
+			filter.Experiment = &experimentStr
+		}
 	}
 
 	count, err := h.svc.GetProxyRequestsCountWithFilter(filter)
@@ -793,12 +1179,43 @@ func (h *AdminHandler) handleActiveProxyRequests(w http.ResponseWriter, r *http.
 }
 
 // ProxyUpstreamAttempt handlers
+// GET /admin/requests/{id}/attempts - 全部 attempts；带 limit（最大 1000）时返回分页结果，cursor 为上一页的 nextCursor
 func (h *AdminHandler) handleProxyUpstreamAttempts(w http.ResponseWriter, r *http.Request, proxyRequestID uint64) {
 	if r.Method != http.MethodGet {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
 		return
 	}
 
+	if l := r.URL.Query().Get("limit"); l != "" {
+		limit, err := strconv.Atoi(l)
+		if err != nil || limit <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be a positive integer"})
+			return
+		}
+		if limit > 1000 {
+			limit = 1000
+		}
+		var cursor uint64
+		if c := r.URL.Query().Get("cursor"); c != "" {
+			cursor, err = strconv.ParseUint(c, 10, 64)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid cursor"})
+				return
+			}
+		}
+		page, err := h.svc.GetProxyUpstreamAttemptsPage(proxyRequestID, limit, cursor)
+		if err != nil {
+			if errors.Is(err, domain.ErrNotFound) {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "cursor does not belong to this request"})
+				return
+			}
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, page)
+		return
+	}
+
 	attempts, err := h.svc.GetProxyUpstreamAttempts(proxyRequestID)
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
@@ -822,6 +1239,52 @@ func (h *AdminHandler) handleRecalculateRequestCost(w http.ResponseWriter, r *ht
 	writeJSON(w, http.StatusOK, result)
 }
 
+// handleProxyRequestNotes handles PATCH /admin/requests/{id}/notes
+func (h *AdminHandler) handleProxyRequestNotes(w http.ResponseWriter, r *http.Request, requestID uint64) {
+	if r.Method != http.MethodPatch {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var body struct {
+		Notes string `json:"notes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	req, err := h.svc.UpdateProxyRequestNotes(requestID, body.Notes)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrInvalidInput):
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		case errors.Is(err, domain.ErrNotFound):
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "proxy request not found"})
+		default:
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
+		return
+	}
+	writeJSON(w, http.StatusOK, req)
+}
+
+// handleWatchProxyRequest returns the full request detail and subscribes to its
+// complete broadcast updates, bypassing attempt broadcast sampling
+func (h *AdminHandler) handleWatchProxyRequest(w http.ResponseWriter, r *http.Request, requestID uint64) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	result, err := h.svc.WatchProxyRequest(requestID)
+	if err != nil {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "proxy request not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
 // Settings handlers
 func (h *AdminHandler) handleSettings(w http.ResponseWriter, r *http.Request, parts []string) {
 	var key string
@@ -859,7 +1322,11 @@ func (h *AdminHandler) handleSettings(w http.ResponseWriter, r *http.Request, pa
 			return
 		}
 		if err := h.svc.UpdateSetting(key, body.Value); err != nil {
-			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			status := http.StatusInternalServerError
+			if errors.Is(err, domain.ErrInvalidInput) {
+				status = http.StatusBadRequest
+			}
+			writeJSON(w, status, map[string]string{"error": err.Error()})
 			return
 		}
 		writeJSON(w, http.StatusOK, map[string]string{"key": key, "value": body.Value})
@@ -939,6 +1406,33 @@ func (h *AdminHandler) handleLogs(w http.ResponseWriter, r *http.Request) {
 // GET /admin/cooldowns - list all active cooldowns
 // PUT /admin/cooldowns/{id} - set cooldown for a provider until a specific time
 // DELETE /admin/cooldowns/{id} - clear cooldown for a provider
+// ProviderGroups handler
+func (h *AdminHandler) handleProviderGroups(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, h.svc.GetProviderGroups())
+}
+
+// handleProviderHealth handles GET /admin/provider-health
+func (h *AdminHandler) handleProviderHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, h.svc.GetProviderHealthScores())
+}
+
+// handleCapabilities handles GET /admin/capabilities
+func (h *AdminHandler) handleCapabilities(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, h.svc.GetCapabilities())
+}
+
 func (h *AdminHandler) handleCooldowns(w http.ResponseWriter, r *http.Request, providerID uint64) {
 	cm := cooldown.Default()
 
@@ -1005,7 +1499,99 @@ func (h *AdminHandler) handleCooldowns(w http.ResponseWriter, r *http.Request, p
 	}
 }
 
+// GET /admin/cooldowns/metrics - 各 Provider 的熔断指标（滚动窗口，仅保存在内存中，重启后清零）
+func (h *AdminHandler) handleBreakerMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	writeJSON(w, http.StatusOK, h.svc.GetBreakerMetrics())
+}
+
+// POST /admin/cooldowns/{id}/reset - 清除 Provider 所有 clientType 的冷却和失败计数
+func (h *AdminHandler) handleResetProviderHealth(w http.ResponseWriter, r *http.Request, providerID uint64) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if err := h.svc.ResetProviderHealth(providerID); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "provider not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"message": "provider health reset"})
+}
+
+// GET /admin/cooldowns/{id}/history?since=RFC3339&limit=N - 冷却进入/退出历史，按时间倒序（limit 默认 100，最大 1000）
+func (h *AdminHandler) handleCooldownHistory(w http.ResponseWriter, r *http.Request, providerID uint64) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "since must be an RFC3339 time"})
+			return
+		}
+		since = t
+	}
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = n
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+
+	events, err := h.svc.GetCooldownHistory(providerID, since, limit)
+	if err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "provider not found"})
+			return
+		}
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
 // API Token handlers
+// GET /admin/api-tokens/stale?days=N - 列出 N 天内未使用的 token（默认 30 天）
+func (h *AdminHandler) handleStaleAPITokens(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	days := 30
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "days must be a positive integer"})
+			return
+		}
+		days = n
+	}
+
+	tokens, err := h.svc.GetStaleAPITokens(days)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, tokens)
+}
+
 func (h *AdminHandler) handleAPITokens(w http.ResponseWriter, r *http.Request, id uint64) {
 	switch r.Method {
 	case http.MethodGet:
@@ -1070,6 +1656,14 @@ func (h *AdminHandler) handleAPITokens(w http.ResponseWriter, r *http.Request, i
 			ProjectID   *uint64 `json:"projectID"`
 			IsEnabled   *bool   `json:"isEnabled"`
 			ExpiresAt   *string `json:"expiresAt"`
+
+			ModelFallbacks        *[]domain.ModelFallback   `json:"modelFallbacks"`
+			NonBillable           *bool                     `json:"nonBillable"`
+			AllowBillableOverride *bool                     `json:"allowBillableOverride"`
+			ForceNonStream        *bool                     `json:"forceNonStream"`
+			AllowStreamOverride   *bool                     `json:"allowStreamOverride"`
+			MaxConcurrent         *int                      `json:"maxConcurrent"`
+			ModelQuotas           *[]domain.TokenModelQuota `json:"modelQuotas"`
 		}
 		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
 			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
@@ -1103,6 +1697,35 @@ func (h *AdminHandler) handleAPITokens(w http.ResponseWriter, r *http.Request, i
 				existing.ExpiresAt = &t
 			}
 		}
+		if body.ModelFallbacks != nil {
+			existing.ModelFallbacks = *body.ModelFallbacks
+		}
+		if body.NonBillable != nil {
+			existing.NonBillable = *body.NonBillable
+		}
+		if body.AllowBillableOverride != nil {
+			existing.AllowBillableOverride = *body.AllowBillableOverride
+		}
+		if body.ForceNonStream != nil {
+			existing.ForceNonStream = *body.ForceNonStream
+		}
+		if body.AllowStreamOverride != nil {
+			existing.AllowStreamOverride = *body.AllowStreamOverride
+		}
+		if body.MaxConcurrent != nil {
+			if *body.MaxConcurrent < 0 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "maxConcurrent must be >= 0"})
+				return
+			}
+			existing.MaxConcurrent = *body.MaxConcurrent
+		}
+		if body.ModelQuotas != nil {
+			if err := service.ValidateTokenModelQuotas(*body.ModelQuotas); err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+				return
+			}
+			existing.ModelQuotas = *body.ModelQuotas
+		}
 		if err := h.svc.UpdateAPIToken(existing); err != nil {
 			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
 			return
@@ -1136,6 +1759,11 @@ func (h *AdminHandler) handleModelMappings(w http.ResponseWriter, r *http.Reques
 		h.handleResetModelMappingsToDefaults(w, r)
 		return
 	}
+	// Check for test endpoint: /admin/model-mappings/test
+	if strings.HasSuffix(path, "/test") {
+		h.handleTestModelMapping(w, r)
+		return
+	}
 
 	switch r.Method {
 	case http.MethodGet:
@@ -1261,6 +1889,44 @@ func (h *AdminHandler) handleResetModelMappingsToDefaults(w http.ResponseWriter,
 	writeJSON(w, http.StatusOK, map[string]string{"message": "mappings reset to defaults"})
 }
 
+// handleTestModelMapping handles POST /admin/model-mappings/test
+// Resolves a sample model name using the same rules as the executor
+func (h *AdminHandler) handleTestModelMapping(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var body struct {
+		ClientType   string `json:"clientType"`
+		ProviderType string `json:"providerType"`
+		ProviderID   uint64 `json:"providerID"`
+		ProjectID    uint64 `json:"projectID"`
+		RouteID      uint64 `json:"routeID"`
+		APITokenID   uint64 `json:"apiTokenID"`
+		Model        string `json:"model"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+	if body.Model == "" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "model is required"})
+		return
+	}
+
+	result, err := h.svc.TestModelMapping(domain.ClientType(body.ClientType), body.ProviderType, body.ProviderID, body.ProjectID, body.RouteID, body.APITokenID, body.Model)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidInput) {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
 // Usage Stats handlers
 func (h *AdminHandler) handleUsageStats(w http.ResponseWriter, r *http.Request) {
 	// Check for recalculate endpoint: /admin/usage-stats/recalculate
@@ -1274,6 +1940,16 @@ func (h *AdminHandler) handleUsageStats(w http.ResponseWriter, r *http.Request)
 		h.handleRecalculateCosts(w, r)
 		return
 	}
+	// Check for reconcile-costs endpoint: /admin/usage-stats/reconcile-costs
+	if strings.HasSuffix(path, "/reconcile-costs") {
+		h.handleReconcileCosts(w, r)
+		return
+	}
+	// Check for multipliers endpoint: /admin/usage-stats/multipliers
+	if strings.HasSuffix(path, "/multipliers") {
+		h.handleMultiplierUsage(w, r)
+		return
+	}
 
 	if r.Method != http.MethodGet {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
@@ -1379,6 +2055,172 @@ func (h *AdminHandler) handleRecalculateCosts(w http.ResponseWriter, r *http.Req
 	writeJSON(w, http.StatusOK, result)
 }
 
+// handleReconcileCosts handles POST /admin/usage-stats/reconcile-costs?hours=24&fix=false
+// Compares request costs with their attempts' costs recomputed at billing-time prices; fix=true corrects the drift
+func (h *AdminHandler) handleReconcileCosts(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	hours := 24
+	if v := r.URL.Query().Get("hours"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid hours"})
+			return
+		}
+		hours = n
+	}
+	fix := r.URL.Query().Get("fix") == "true"
+
+	result, err := h.svc.ReconcileCosts(time.Now().Add(-time.Duration(hours)*time.Hour), fix)
+	if errors.Is(err, service.ErrCostReconcileRunning) {
+		writeJSON(w, http.StatusConflict, map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleAggregateStatsNow handles POST /admin/stats/aggregate-now
+// Runs minute aggregation and rollups immediately and returns per-phase counts
+func (h *AdminHandler) handleAggregateStatsNow(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	result, err := h.svc.AggregateStatsNow()
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrAggregationInProgress) {
+			status = http.StatusTooManyRequests
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// handleMultiplierUsage handles GET /admin/usage-stats/multipliers
+// Returns the cost multipliers applied per provider and client type
+func (h *AdminHandler) handleMultiplierUsage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var start, end *time.Time
+	query := r.URL.Query()
+	if startStr := query.Get("start"); startStr != "" {
+		if t, err := time.Parse(time.RFC3339, startStr); err == nil {
+			utc := t.UTC()
+			start = &utc
+		}
+	}
+	if endStr := query.Get("end"); endStr != "" {
+		if t, err := time.Parse(time.RFC3339, endStr); err == nil {
+			utc := t.UTC()
+			end = &utc
+		}
+	}
+
+	usage, err := h.svc.GetMultiplierUsage(start, end)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	if usage == nil {
+		usage = []*domain.MultiplierUsage{}
+	}
+	writeJSON(w, http.StatusOK, usage)
+}
+
+// handleErrorSummary handles GET /admin/errors/summary?start=...&end=...
+// Returns failed upstream attempts grouped by status code and normalized error message
+func (h *AdminHandler) handleErrorSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var start, end time.Time
+	query := r.URL.Query()
+	for _, param := range []struct {
+		name string
+		dst  *time.Time
+	}{{"start", &start}, {"end", &end}} {
+		if v := query.Get(param.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid " + param.name + ": must be RFC3339"})
+				return
+			}
+			*param.dst = t.UTC()
+		}
+	}
+
+	summary, err := h.svc.GetErrorSummary(start, end)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidInput) {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// handleClientIPStats handles GET /admin/client-ips?start=...&end=...&limit=...
+// Returns requests grouped by client IP, busiest first
+func (h *AdminHandler) handleClientIPStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	var start, end time.Time
+	query := r.URL.Query()
+	for _, param := range []struct {
+		name string
+		dst  *time.Time
+	}{{"start", &start}, {"end", &end}} {
+		if v := query.Get(param.name); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid " + param.name + ": must be RFC3339"})
+				return
+			}
+			*param.dst = t.UTC()
+		}
+	}
+	limit := 0
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	stats, err := h.svc.GetClientIPStats(start, end, limit)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, domain.ErrInvalidInput) {
+			status = http.StatusBadRequest
+		}
+		writeJSON(w, status, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
 // handleResponseModels handles GET /admin/response-models
 func (h *AdminHandler) handleResponseModels(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
@@ -1394,14 +2236,50 @@ func (h *AdminHandler) handleResponseModels(w http.ResponseWriter, r *http.Reque
 	writeJSON(w, http.StatusOK, names)
 }
 
+// handleRebuildResponseModels handles POST /admin/response-models/rebuild
+func (h *AdminHandler) handleRebuildResponseModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	result, err := h.svc.RebuildResponseModels()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
 // handleDashboard handles GET /admin/dashboard
 // Returns all dashboard data in a single request
-func (h *AdminHandler) handleDashboard(w http.ResponseWriter, r *http.Request) {
+func (h *AdminHandler) handleDashboard(w http.ResponseWriter, r *http.Request, parts []string) {
 	if r.Method != http.MethodGet {
 		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
 		return
 	}
 
+	// GET /admin/dashboard/concurrency?since=RFC3339&projectId=N - 在途请求数采样历史
+	if len(parts) > 2 && parts[2] == "concurrency" {
+		h.handleConcurrencyHistory(w, r)
+		return
+	}
+
+	// GET /admin/dashboard/providers - 每个 Provider 的统计、冷却与配额
+	if len(parts) > 2 && parts[2] != "" {
+		if parts[2] != "providers" {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+			return
+		}
+		providers, err := h.svc.GetDashboardProviders()
+		if err != nil {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		writeJSON(w, http.StatusOK, providers)
+		return
+	}
+
 	data, err := h.svc.GetDashboardData()
 	if err != nil {
 		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
@@ -1410,6 +2288,30 @@ func (h *AdminHandler) handleDashboard(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, data)
 }
 
+// handleConcurrencyHistory returns sampled in-flight request counts, oldest
+// first; without since it returns everything retained (the last hour)
+func (h *AdminHandler) handleConcurrencyHistory(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if v := r.URL.Query().Get("since"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "since must be an RFC3339 time"})
+			return
+		}
+		since = t
+	}
+	var projectID uint64
+	if v := r.URL.Query().Get("projectId"); v != "" {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid projectId"})
+			return
+		}
+		projectID = id
+	}
+	writeJSON(w, http.StatusOK, h.svc.GetConcurrencyHistory(since, projectID))
+}
+
 // handleBackup routes backup requests
 func (h *AdminHandler) handleBackup(w http.ResponseWriter, r *http.Request, parts []string) {
 	if len(parts) < 3 {
@@ -1465,6 +2367,7 @@ func (h *AdminHandler) handleBackupImport(w http.ResponseWriter, r *http.Request
 	opts := domain.ImportOptions{
 		ConflictStrategy: r.URL.Query().Get("conflictStrategy"),
 		DryRun:           r.URL.Query().Get("dryRun") == "true",
+		RenameOnConflict: r.URL.Query().Get("renameOnConflict") == "true",
 	}
 	if opts.ConflictStrategy == "" {
 		opts.ConflictStrategy = "skip"
@@ -1500,6 +2403,7 @@ func (h *AdminHandler) handlePricing(w http.ResponseWriter, r *http.Request) {
 				CacheReadPriceMicro:    p.CacheReadPriceMicro,
 				Cache5mWritePriceMicro: p.Cache5mWritePriceMicro,
 				Cache1hWritePriceMicro: p.Cache1hWritePriceMicro,
+				ReasoningPriceMicro:    p.ReasoningPriceMicro,
 				Has1MContext:           p.Has1MContext,
 				Context1MThreshold:     p.Context1MThreshold,
 				InputPremiumNum:        p.InputPremiumNum,
@@ -0,0 +1,597 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/cooldown"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/pricing"
+	"github.com/awsl-project/maxx/internal/service"
+	"github.com/awsl-project/maxx/internal/version"
+)
+
+// adminRoute describes one admin API endpoint for the OpenAPI spec.
+// 路由表手工维护，新增/修改 AdminHandler 路由时需要同步更新（admin_openapi_test.go 会校验资源列表）
+type adminRoute struct {
+	Method   string
+	Path     string // 相对 /admin 的路径模板，如 /providers/{id}
+	Tag      string
+	Summary  string
+	Query    []adminParam
+	Request  any // 请求体示例值（用于反射生成 schema），nil 表示无请求体
+	Response any // 响应体示例值，nil 表示无响应体
+	Status   int // 成功状态码，默认 200
+}
+
+type adminParam struct {
+	Name        string
+	Type        string // string / integer / boolean
+	Description string
+}
+
+type messageResponse struct {
+	Message string `json:"message"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+type settingResponse struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+var requestFilterParams = []adminParam{
+	{"providerId", "integer", "Filter by provider ID"},
+	{"status", "string", "Filter by request status"},
+	{"clientIp", "string", "Filter by client IP"},
+	{"experiment", "string", "Filter by A/B model experiment name"},
+}
+
+var adminRoutes = []adminRoute{
+	// Providers
+	{Method: http.MethodGet, Path: "/providers", Tag: "providers", Summary: "List providers", Response: []*domain.Provider{}},
+	{Method: http.MethodPost, Path: "/providers", Tag: "providers", Summary: "Create a provider", Request: domain.Provider{}, Response: domain.Provider{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/providers/{id}", Tag: "providers", Summary: "Get a provider", Response: domain.Provider{}},
+	{Method: http.MethodPut, Path: "/providers/{id}", Tag: "providers", Summary: "Update a provider", Request: domain.Provider{}, Response: domain.Provider{}},
+	{Method: http.MethodDelete, Path: "/providers/{id}", Tag: "providers", Summary: "Delete a provider", Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/providers/export", Tag: "providers", Summary: "Export providers", Response: []*domain.Provider{}},
+	{Method: http.MethodPost, Path: "/providers/import", Tag: "providers", Summary: "Import providers",
+		Query: []adminParam{
+			{"renameOnConflict", "boolean", "Import providers with a taken name as \"name (2)\" instead of skipping them"},
+		},
+		Request: []*domain.Provider{}, Response: service.ImportResult{}},
+	{Method: http.MethodGet, Path: "/providers/{id}/credentials/export", Tag: "providers", Summary: "Export a provider's credentials encrypted with the passphrase in the X-Credential-Passphrase header (PBKDF2-SHA256 + AES-256-GCM)", Response: service.CredentialBundle{}},
+	{Method: http.MethodPost, Path: "/providers/{id}/credentials/import", Tag: "providers", Summary: "Decrypt exported credentials with the passphrase in the X-Credential-Passphrase header and apply them to a provider of the same type", Request: service.CredentialBundle{}, Response: domain.Provider{}},
+	{Method: http.MethodGet, Path: "/providers/{id}/drain", Tag: "providers", Summary: "Get a provider's drain status and in-flight request count", Response: domain.ProviderDrainStatus{}},
+	{Method: http.MethodPost, Path: "/providers/{id}/drain", Tag: "providers", Summary: "Start draining a provider: no new requests are routed to it, in-flight requests finish", Response: domain.ProviderDrainStatus{}},
+	{Method: http.MethodDelete, Path: "/providers/{id}/drain", Tag: "providers", Summary: "Stop draining a provider", Response: domain.ProviderDrainStatus{}},
+	{Method: http.MethodGet, Path: "/provider-groups", Tag: "providers", Summary: "List provider groups (shared quota pools) with per-member and pooled remaining quota", Response: []*domain.ProviderGroupStatus{}},
+	{Method: http.MethodGet, Path: "/provider-health", Tag: "providers", Summary: "Get each provider's 0-100 health score combining 15-minute success rate, average latency vs the provider_health_latency_slo_ms SLO, cooldown state and remaining quota, weighted by provider_health_weights. Stats and quota refresh every 30s; cooldowns are live", Response: []*domain.ProviderHealthScore{}},
+	{Method: http.MethodGet, Path: "/capabilities", Tag: "providers", Summary: "List client types and provider types with native client types, available conversions and supported features", Response: domain.Capabilities{}},
+
+	// Routes
+	{Method: http.MethodGet, Path: "/routes", Tag: "routes", Summary: "List routes", Response: []*domain.Route{}},
+	{Method: http.MethodPost, Path: "/routes", Tag: "routes", Summary: "Create a route", Request: domain.Route{}, Response: domain.Route{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/routes/{id}", Tag: "routes", Summary: "Get a route", Response: domain.Route{}},
+	{Method: http.MethodPut, Path: "/routes/{id}", Tag: "routes", Summary: "Partially update a route (only sent fields are applied)", Request: domain.Route{}, Response: domain.Route{}},
+	{Method: http.MethodDelete, Path: "/routes/{id}", Tag: "routes", Summary: "Delete a route", Status: http.StatusNoContent},
+	{Method: http.MethodPut, Path: "/routes/batch-positions", Tag: "routes", Summary: "Batch update route positions", Request: []domain.RoutePositionUpdate{}, Response: messageResponse{}},
+	{Method: http.MethodPost, Path: "/routes/compare", Tag: "routes", Summary: "Replay recorded requests on two routes and compare latency, tokens, cost and responses",
+		Request: struct {
+			RequestIDs []uint64 `json:"requestIDs"`
+			RouteA     uint64   `json:"routeA"`
+			RouteB     uint64   `json:"routeB"`
+		}{}, Response: domain.RouteComparisonReport{}},
+
+	// Projects
+	{Method: http.MethodGet, Path: "/projects", Tag: "projects", Summary: "List projects", Response: []*domain.Project{}},
+	{Method: http.MethodPost, Path: "/projects", Tag: "projects", Summary: "Create a project", Request: domain.Project{}, Response: domain.Project{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/projects/{id}", Tag: "projects", Summary: "Get a project", Response: domain.Project{}},
+	{Method: http.MethodPut, Path: "/projects/{id}", Tag: "projects", Summary: "Update a project", Request: domain.Project{}, Response: domain.Project{}},
+	{Method: http.MethodDelete, Path: "/projects/{id}", Tag: "projects", Summary: "Delete a project", Status: http.StatusNoContent},
+	{Method: http.MethodGet, Path: "/projects/by-slug/{slug}", Tag: "projects", Summary: "Get a project by slug", Response: domain.Project{}},
+	{Method: http.MethodPost, Path: "/projects/{id}/test-routes", Tag: "projects", Summary: "Send a test request to every enabled route of a project (id 0 for global routes); pinned, no retry or cooldown effects",
+		Request: struct {
+			ClientType domain.ClientType `json:"clientType"`
+			Prompt     string            `json:"prompt,omitempty"`
+		}{}, Response: domain.ProjectRouteTestReport{}},
+
+	// Sessions
+	{Method: http.MethodGet, Path: "/sessions", Tag: "sessions", Summary: "List sessions", Response: []*domain.Session{}},
+	{Method: http.MethodPut, Path: "/sessions/{sessionID}/project", Tag: "sessions", Summary: "Bind a session to a project",
+		Request: struct {
+			ProjectID uint64 `json:"projectID"`
+		}{}, Response: service.UpdateSessionProjectResult{}},
+	{Method: http.MethodPost, Path: "/sessions/{sessionID}/reject", Tag: "sessions", Summary: "Reject a pending session", Response: domain.Session{}},
+	{Method: http.MethodPost, Path: "/sessions/merge", Tag: "sessions", Summary: "Merge sessions into a primary session (requests are reassigned, merged sessions deleted)",
+		Request: struct {
+			PrimarySessionID string   `json:"primarySessionID"`
+			SessionIDs       []string `json:"sessionIDs"`
+			RetagRequests    bool     `json:"retagRequests"`
+		}{}, Response: service.MergeSessionsResult{}},
+
+	// Retry configs
+	{Method: http.MethodGet, Path: "/retry-configs", Tag: "retry-configs", Summary: "List retry configs", Response: []*domain.RetryConfig{}},
+	{Method: http.MethodPost, Path: "/retry-configs", Tag: "retry-configs", Summary: "Create a retry config", Request: domain.RetryConfig{}, Response: domain.RetryConfig{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/retry-configs/{id}", Tag: "retry-configs", Summary: "Get a retry config", Response: domain.RetryConfig{}},
+	{Method: http.MethodPut, Path: "/retry-configs/{id}", Tag: "retry-configs", Summary: "Update a retry config", Request: domain.RetryConfig{}, Response: domain.RetryConfig{}},
+	{Method: http.MethodDelete, Path: "/retry-configs/{id}", Tag: "retry-configs", Summary: "Delete a retry config", Status: http.StatusNoContent},
+
+	// Routing strategies
+	{Method: http.MethodGet, Path: "/routing-strategies", Tag: "routing-strategies", Summary: "List routing strategies", Response: []*domain.RoutingStrategy{}},
+	{Method: http.MethodPost, Path: "/routing-strategies", Tag: "routing-strategies", Summary: "Create a routing strategy", Request: domain.RoutingStrategy{}, Response: domain.RoutingStrategy{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/routing-strategies/{id}", Tag: "routing-strategies", Summary: "Get a routing strategy", Response: domain.RoutingStrategy{}},
+	{Method: http.MethodPut, Path: "/routing-strategies/{id}", Tag: "routing-strategies", Summary: "Update a routing strategy", Request: domain.RoutingStrategy{}, Response: domain.RoutingStrategy{}},
+	{Method: http.MethodDelete, Path: "/routing-strategies/{id}", Tag: "routing-strategies", Summary: "Delete a routing strategy", Status: http.StatusNoContent},
+
+	// Proxy requests
+	{Method: http.MethodGet, Path: "/requests", Tag: "requests", Summary: "List proxy requests (cursor pagination)",
+		Query: append([]adminParam{
+			{"limit", "integer", "Page size"},
+			{"before", "integer", "Return requests with ID lower than this"},
+			{"after", "integer", "Return requests with ID higher than this"},
+		}, requestFilterParams...),
+		Response: service.CursorPaginationResult{}},
+	{Method: http.MethodGet, Path: "/requests/{id}", Tag: "requests", Summary: "Get a proxy request", Response: domain.ProxyRequest{}},
+	{Method: http.MethodGet, Path: "/requests/count", Tag: "requests", Summary: "Count proxy requests", Query: requestFilterParams, Response: int64(0)},
+	{Method: http.MethodGet, Path: "/requests/active", Tag: "requests", Summary: "List in-flight proxy requests", Response: []*domain.ProxyRequest{}},
+	{Method: http.MethodGet, Path: "/requests/{id}/attempts", Tag: "requests", Summary: "List upstream attempts of a request, ordered by start time then ID. Without limit all attempts are returned as an array; with limit the response is one page ({items, hasMore, nextCursor})",
+		Query: []adminParam{
+			{"limit", "integer", "Page size (max 1000); switches the response to a page object"},
+			{"cursor", "integer", "nextCursor of the previous page"},
+		}, Response: service.AttemptPaginationResult{}},
+	{Method: http.MethodPost, Path: "/requests/{id}/recalculate-cost", Tag: "requests", Summary: "Recalculate the cost of a request", Response: service.RecalculateRequestCostResult{}},
+	{Method: http.MethodPatch, Path: "/requests/{id}/notes", Tag: "requests", Summary: "Set the operator notes of a request (empty clears them). Notes are kept when request details are cleared by retention",
+		Request: struct {
+			Notes string `json:"notes"`
+		}{}, Response: domain.ProxyRequest{}},
+	{Method: http.MethodPost, Path: "/requests/{id}/watch", Tag: "requests", Summary: "Get full request detail and broadcast all its updates despite sampling", Response: service.WatchProxyRequestResult{}},
+	{Method: http.MethodPost, Path: "/requests/delete", Tag: "requests", Summary: "Bulk delete requests matching a filter (at least one filter required)",
+		Request: struct {
+			Start      *time.Time `json:"start"`
+			End        *time.Time `json:"end"`
+			Status     *string    `json:"status"`
+			ProjectID  *uint64    `json:"projectId"`
+			APITokenID *uint64    `json:"apiTokenId"`
+			ProviderID *uint64    `json:"providerId"`
+		}{}, Response: service.DeleteRequestsResult{}},
+
+	// Settings
+	{Method: http.MethodGet, Path: "/settings", Tag: "settings", Summary: "List all settings", Response: map[string]string{}},
+	{Method: http.MethodGet, Path: "/settings/{key}", Tag: "settings", Summary: "Get a setting", Response: settingResponse{}},
+	{Method: http.MethodPut, Path: "/settings/{key}", Tag: "settings", Summary: "Update a setting (POST is also accepted)",
+		Request: struct {
+			Value string `json:"value"`
+		}{}, Response: settingResponse{}},
+	{Method: http.MethodDelete, Path: "/settings/{key}", Tag: "settings", Summary: "Delete a setting", Status: http.StatusNoContent},
+
+	// Status & stats
+	{Method: http.MethodGet, Path: "/proxy-status", Tag: "status", Summary: "Get proxy status", Response: service.ProxyStatus{}},
+	{Method: http.MethodGet, Path: "/provider-stats", Tag: "status", Summary: "Get per-provider statistics",
+		Query: []adminParam{
+			{"client_type", "string", "Filter by client type"},
+			{"project_id", "integer", "Filter by project ID"},
+		},
+		Response: map[string]*domain.ProviderStats{}},
+	{Method: http.MethodGet, Path: "/logs", Tag: "status", Summary: "Tail the server log",
+		Query: []adminParam{{"limit", "integer", "Number of lines (default 100, max 1000)"}},
+		Response: struct {
+			Lines []string `json:"lines"`
+			Count int      `json:"count"`
+		}{}},
+	{Method: http.MethodGet, Path: "/logs/stream", Tag: "status", Summary: "Stream live log lines as Server-Sent Events (text/event-stream), filtered server-side",
+		Query: []adminParam{{"filter", "string", "Space-separated terms that must all match: provider:<id>, tag:<name> (e.g. tag:Executor) or a case-insensitive substring"}}},
+	{Method: http.MethodGet, Path: "/dashboard", Tag: "status", Summary: "Get dashboard data", Response: domain.DashboardData{}},
+	{Method: http.MethodGet, Path: "/dashboard/providers", Tag: "status", Summary: "Get per-provider stats, live cooldowns and quotas", Response: []*service.DashboardProviderStatus{}},
+	{Method: http.MethodGet, Path: "/dashboard/concurrency", Tag: "status", Summary: "Get sampled in-flight request counts (global, or one project with projectId) for the last hour", Response: []domain.ConcurrencySample{}},
+
+	// Cooldowns
+	{Method: http.MethodGet, Path: "/cooldowns", Tag: "cooldowns", Summary: "List active cooldowns", Response: []*cooldown.CooldownInfo{}},
+	{Method: http.MethodPut, Path: "/cooldowns/{id}", Tag: "cooldowns", Summary: "Freeze a provider until a given time",
+		Request: struct {
+			UntilTime  string `json:"untilTime"`
+			ClientType string `json:"clientType,omitempty"`
+		}{}, Response: messageResponse{}},
+	{Method: http.MethodDelete, Path: "/cooldowns/{id}", Tag: "cooldowns", Summary: "Clear cooldowns of a provider", Response: messageResponse{}},
+	{Method: http.MethodGet, Path: "/cooldowns/metrics", Tag: "cooldowns", Summary: "Get per-provider circuit breaker metrics over the last 24h derived from cooldowns: state, time open/half-open/closed, trips per hour and probe success rate. Kept in memory only, reset on restart; also pushed as the breaker_metrics WebSocket event every 30s", Response: []*cooldown.BreakerMetrics{}},
+	{Method: http.MethodPost, Path: "/cooldowns/{id}/reset", Tag: "cooldowns", Summary: "Clear cooldowns and failure counts of a provider across all client types", Response: messageResponse{}},
+	{Method: http.MethodGet, Path: "/cooldowns/{id}/history", Tag: "cooldowns", Summary: "List cooldown enter/exit events of a provider, newest first",
+		Query: []adminParam{
+			{"since", "string", "Only events at or after this RFC3339 time"},
+			{"limit", "integer", "Max number of events (default 100, max 1000)"},
+		}, Response: []*domain.CooldownEvent{}},
+
+	// Debug
+	{Method: http.MethodPost, Path: "/debug/resolve", Tag: "debug", Summary: "Resolve routes, model mapping, retry config, conversion and cooldowns for a hypothetical request (nothing is sent upstream)",
+		Request: domain.ResolveRequest{}, Response: domain.RequestResolution{}},
+
+	// API tokens
+	{Method: http.MethodGet, Path: "/api-tokens", Tag: "api-tokens", Summary: "List API tokens", Response: []*domain.APIToken{}},
+	{Method: http.MethodPost, Path: "/api-tokens", Tag: "api-tokens", Summary: "Create an API token",
+		Request: struct {
+			Name        string  `json:"name"`
+			Description string  `json:"description"`
+			ProjectID   uint64  `json:"projectID"`
+			ExpiresAt   *string `json:"expiresAt"`
+		}{}, Response: domain.APITokenCreateResult{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/api-tokens/stale", Tag: "api-tokens", Summary: "List API tokens unused for N days",
+		Query:    []adminParam{{"days", "integer", "Days without use (default 30)"}},
+		Response: []*domain.APIToken{}},
+	{Method: http.MethodGet, Path: "/api-tokens/{id}", Tag: "api-tokens", Summary: "Get an API token", Response: domain.APIToken{}},
+	{Method: http.MethodPut, Path: "/api-tokens/{id}", Tag: "api-tokens", Summary: "Partially update an API token",
+		Request: struct {
+			Name        *string `json:"name"`
+			Description *string `json:"description"`
+			ProjectID   *uint64 `json:"projectID"`
+			IsEnabled   *bool   `json:"isEnabled"`
+			ExpiresAt   *string `json:"expiresAt"`
+
+			ModelFallbacks        *[]domain.ModelFallback   `json:"modelFallbacks"`
+			NonBillable           *bool                     `json:"nonBillable"`
+			AllowBillableOverride *bool                     `json:"allowBillableOverride"`
+			ForceNonStream        *bool                     `json:"forceNonStream"`
+			AllowStreamOverride   *bool                     `json:"allowStreamOverride"`
+			MaxConcurrent         *int                      `json:"maxConcurrent"`
+			ModelQuotas           *[]domain.TokenModelQuota `json:"modelQuotas"`
+		}{}, Response: domain.APIToken{}},
+	{Method: http.MethodDelete, Path: "/api-tokens/{id}", Tag: "api-tokens", Summary: "Delete an API token", Status: http.StatusNoContent},
+
+	// Model mappings
+	{Method: http.MethodGet, Path: "/model-mappings", Tag: "model-mappings", Summary: "List model mappings", Response: []*domain.ModelMapping{}},
+	{Method: http.MethodPost, Path: "/model-mappings", Tag: "model-mappings", Summary: "Create a model mapping", Request: domain.ModelMapping{}, Response: domain.ModelMapping{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/model-mappings/{id}", Tag: "model-mappings", Summary: "Get a model mapping", Response: domain.ModelMapping{}},
+	{Method: http.MethodPut, Path: "/model-mappings/{id}", Tag: "model-mappings", Summary: "Partially update a model mapping", Request: domain.ModelMapping{}, Response: domain.ModelMapping{}},
+	{Method: http.MethodDelete, Path: "/model-mappings/{id}", Tag: "model-mappings", Summary: "Delete a model mapping", Status: http.StatusNoContent},
+	{Method: http.MethodDelete, Path: "/model-mappings/clear-all", Tag: "model-mappings", Summary: "Delete all model mappings", Response: messageResponse{}},
+	{Method: http.MethodPost, Path: "/model-mappings/reset-defaults", Tag: "model-mappings", Summary: "Reset model mappings to defaults", Response: messageResponse{}},
+	{Method: http.MethodPost, Path: "/model-mappings/test", Tag: "model-mappings", Summary: "Resolve which mapping applies to a model",
+		Request: struct {
+			ClientType   string `json:"clientType"`
+			ProviderType string `json:"providerType"`
+			ProviderID   uint64 `json:"providerID"`
+			ProjectID    uint64 `json:"projectID"`
+			RouteID      uint64 `json:"routeID"`
+			APITokenID   uint64 `json:"apiTokenID"`
+			Model        string `json:"model"`
+		}{}, Response: service.ModelMappingTestResult{}},
+
+	// Usage stats
+	{Method: http.MethodGet, Path: "/usage-stats", Tag: "usage-stats", Summary: "Query usage statistics",
+		Query: []adminParam{
+			{"granularity", "string", "minute, hour, day or month"},
+			{"start", "string", "Start time (RFC3339)"},
+			{"end", "string", "End time (RFC3339)"},
+			{"routeId", "integer", "Filter by route ID"},
+			{"providerId", "integer", "Filter by provider ID"},
+			{"projectId", "integer", "Filter by project ID"},
+			{"clientType", "string", "Filter by client type"},
+			{"apiTokenId", "integer", "Filter by API token ID"},
+			{"model", "string", "Filter by model"},
+		},
+		Response: []*domain.UsageStats{}},
+	{Method: http.MethodPost, Path: "/usage-stats/recalculate", Tag: "usage-stats", Summary: "Rebuild usage statistics", Response: messageResponse{}},
+	{Method: http.MethodPost, Path: "/usage-stats/recalculate-costs", Tag: "usage-stats", Summary: "Recalculate costs of all requests", Response: service.RecalculateCostsResult{}},
+	{Method: http.MethodPost, Path: "/usage-stats/reconcile-costs", Tag: "usage-stats", Summary: "Check request costs against attempts recomputed at billing-time prices",
+		Query: []adminParam{
+			{"hours", "integer", "Check requests created within the last N hours (default 24)"},
+			{"fix", "boolean", "Correct the drifted costs (default false, report only)"},
+		},
+		Response: service.ReconcileCostsResult{}},
+	{Method: http.MethodPost, Path: "/stats/aggregate-now", Tag: "usage-stats", Summary: "Run minute aggregation and rollups now (per-phase counts)", Response: service.AggregateStatsResult{}},
+	{Method: http.MethodGet, Path: "/usage-stats/multipliers", Tag: "usage-stats", Summary: "Cost multipliers applied per provider and client type",
+		Query: []adminParam{
+			{"start", "string", "Start time (RFC3339)"},
+			{"end", "string", "End time (RFC3339)"},
+		},
+		Response: []*domain.MultiplierUsage{}},
+	{Method: http.MethodGet, Path: "/errors/summary", Tag: "usage-stats", Summary: "Failed upstream attempts grouped by status code and normalized error message",
+		Query: []adminParam{
+			{"start", "string", "Start time (RFC3339, default 24 hours before end)"},
+			{"end", "string", "End time (RFC3339, default now)"},
+		},
+		Response: domain.ErrorSummary{}},
+	{Method: http.MethodGet, Path: "/client-ips", Tag: "usage-stats", Summary: "Requests grouped by client IP, busiest first",
+		Query: []adminParam{
+			{"start", "string", "Start time (RFC3339, default 24 hours before end)"},
+			{"end", "string", "End time (RFC3339, default now)"},
+			{"limit", "integer", "Max client IPs (default 100, max 1000)"},
+		},
+		Response: []*domain.ClientIPStats{}},
+	{Method: http.MethodGet, Path: "/response-models", Tag: "usage-stats", Summary: "List model names seen in responses", Response: []string{}},
+	{Method: http.MethodPost, Path: "/response-models/rebuild", Tag: "usage-stats", Summary: "Rebuild the response model list from stored upstream attempts", Response: service.RebuildResponseModelsResult{}},
+
+	// Backup
+	{Method: http.MethodGet, Path: "/backup/export", Tag: "backup", Summary: "Export configuration backup", Response: domain.BackupFile{}},
+	{Method: http.MethodPost, Path: "/backup/import", Tag: "backup", Summary: "Import configuration backup",
+		Query: []adminParam{
+			{"conflictStrategy", "string", "skip, overwrite or error"},
+			{"dryRun", "boolean", "Validate without writing"},
+			{"renameOnConflict", "boolean", "Import providers with a taken name as \"name (2)\"; routes and model mappings in the backup follow the rename"},
+		},
+		Request: domain.BackupFile{}, Response: domain.ImportResult{}},
+
+	// Pricing
+	{Method: http.MethodGet, Path: "/pricing", Tag: "pricing", Summary: "Get the effective price table", Response: pricing.PriceTable{}},
+	{Method: http.MethodGet, Path: "/model-prices", Tag: "pricing", Summary: "List model prices", Response: []*domain.ModelPrice{}},
+	{Method: http.MethodPost, Path: "/model-prices", Tag: "pricing", Summary: "Create a model price", Request: domain.ModelPrice{}, Response: domain.ModelPrice{}, Status: http.StatusCreated},
+	{Method: http.MethodGet, Path: "/model-prices/{id}", Tag: "pricing", Summary: "Get a model price", Response: domain.ModelPrice{}},
+	{Method: http.MethodPut, Path: "/model-prices/{id}", Tag: "pricing", Summary: "Update a model price", Request: domain.ModelPrice{}, Response: domain.ModelPrice{}},
+	{Method: http.MethodDelete, Path: "/model-prices/{id}", Tag: "pricing", Summary: "Delete a model price", Status: http.StatusNoContent},
+	{Method: http.MethodPost, Path: "/model-prices/reset", Tag: "pricing", Summary: "Reset model prices to defaults", Response: []*domain.ModelPrice{}},
+
+	// Export
+	{Method: http.MethodGet, Path: "/export/openapi", Tag: "export", Summary: "Get this OpenAPI document", Response: map[string]any{}},
+}
+
+var (
+	adminOpenAPIOnce sync.Once
+	adminOpenAPISpec map[string]any
+)
+
+// handleExport handles /admin/export/*
+func (h *AdminHandler) handleExport(w http.ResponseWriter, r *http.Request, parts []string) {
+	if len(parts) < 3 || parts[2] != "openapi" {
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "not found"})
+		return
+	}
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+
+	adminOpenAPIOnce.Do(func() {
+		adminOpenAPISpec = buildAdminOpenAPISpec()
+	})
+	writeJSON(w, http.StatusOK, adminOpenAPISpec)
+}
+
+var pathParamPattern = regexp.MustCompile(`\{([^}]+)\}`)
+
+// buildAdminOpenAPISpec builds an OpenAPI 3 document from adminRoutes
+func buildAdminOpenAPISpec() map[string]any {
+	sb := newSchemaBuilder()
+	errSchema := sb.schemaFor(reflect.TypeOf(errorResponse{}))
+
+	paths := make(map[string]map[string]any)
+	tagSet := make(map[string]bool)
+	for _, route := range adminRoutes {
+		tagSet[route.Tag] = true
+
+		var params []map[string]any
+		for _, m := range pathParamPattern.FindAllStringSubmatch(route.Path, -1) {
+			paramType := "string"
+			if m[1] == "id" {
+				paramType = "integer"
+			}
+			params = append(params, map[string]any{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]any{"type": paramType},
+			})
+		}
+		for _, q := range route.Query {
+			params = append(params, map[string]any{
+				"name":        q.Name,
+				"in":          "query",
+				"description": q.Description,
+				"schema":      map[string]any{"type": q.Type},
+			})
+		}
+
+		status := route.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		success := map[string]any{"description": http.StatusText(status)}
+		if route.Response != nil && status != http.StatusNoContent {
+			success["content"] = map[string]any{
+				"application/json": map[string]any{"schema": sb.schemaFor(reflect.TypeOf(route.Response))},
+			}
+		}
+
+		op := map[string]any{
+			"tags":        []string{route.Tag},
+			"summary":     route.Summary,
+			"operationId": operationID(route),
+			"responses": map[string]any{
+				strconv.Itoa(status): success,
+				"default": map[string]any{
+					"description": "Error",
+					"content": map[string]any{
+						"application/json": map[string]any{"schema": errSchema},
+					},
+				},
+			},
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+		if route.Request != nil {
+			op["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": sb.schemaFor(reflect.TypeOf(route.Request))},
+				},
+			}
+		}
+
+		if paths[route.Path] == nil {
+			paths[route.Path] = make(map[string]any)
+		}
+		paths[route.Path][strings.ToLower(route.Method)] = op
+	}
+
+	var tags []map[string]any
+	for tag := range tagSet {
+		tags = append(tags, map[string]any{"name": tag})
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i]["name"].(string) < tags[j]["name"].(string) })
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":       "maxx Admin API",
+			"version":     version.Version,
+			"description": "Admin API of maxx. Authentication is only enforced when MAXX_ADMIN_PASSWORD is set; obtain a token from /api/admin/auth/login.",
+		},
+		"servers":  []map[string]any{{"url": "/api/admin"}},
+		"security": []map[string]any{{"bearerAuth": []string{}}},
+		"tags":     tags,
+		"paths":    paths,
+		"components": map[string]any{
+			"schemas": sb.components,
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{
+					"type":         "http",
+					"scheme":       "bearer",
+					"bearerFormat": "JWT",
+				},
+			},
+		},
+	}
+}
+
+// operationID builds a stable operationId like get_providers_id
+func operationID(route adminRoute) string {
+	path := pathParamPattern.ReplaceAllString(route.Path, "$1")
+	path = strings.NewReplacer("/", "_", "-", "_").Replace(strings.Trim(path, "/"))
+	return strings.ToLower(route.Method) + "_" + path
+}
+
+// schemaBuilder reflects Go types into OpenAPI schemas.
+// Named struct types are registered under components/schemas as "pkg.Type".
+type schemaBuilder struct {
+	components map[string]any
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{components: make(map[string]any)}
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+func (sb *schemaBuilder) schemaFor(t reflect.Type) map[string]any {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]any{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer", "format": intFormat(t)}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": sb.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": sb.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return sb.structSchema(t)
+		}
+		name := componentName(t)
+		if _, ok := sb.components[name]; !ok {
+			// 先占位，防止递归类型无限展开
+			sb.components[name] = map[string]any{}
+			sb.components[name] = sb.structSchema(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + name}
+	default:
+		return map[string]any{}
+	}
+}
+
+func (sb *schemaBuilder) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+	sb.collectFields(t, properties, &required)
+
+	schema := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (sb *schemaBuilder) collectFields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		// 匿名嵌入且无 json 名称：字段提升到外层
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			for ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				sb.collectFields(ft, properties, required)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		properties[name] = sb.schemaFor(f.Type)
+		if !strings.Contains(opts, "omitempty") && f.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
+
+func componentName(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if idx := strings.LastIndex(pkg, "/"); idx >= 0 {
+		pkg = pkg[idx+1:]
+	}
+	if pkg == "" {
+		return t.Name()
+	}
+	return pkg + "." + t.Name()
+}
+
+func intFormat(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Int64, reflect.Uint64, reflect.Int, reflect.Uint:
+		return "int64"
+	default:
+		return "int32"
+	}
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// adminHandlerResources 解析 admin.go 中 ServeHTTP 的 switch resource 分支
+func adminHandlerResources(t *testing.T) []string {
+	t.Helper()
+	file, err := parser.ParseFile(token.NewFileSet(), "admin.go", nil, 0)
+	if err != nil {
+		t.Fatalf("parse admin.go: %v", err)
+	}
+
+	var resources []string
+	ast.Inspect(file, func(n ast.Node) bool {
+		fn, ok := n.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != "ServeHTTP" {
+			return true
+		}
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			sw, ok := n.(*ast.SwitchStmt)
+			if !ok {
+				return true
+			}
+			if ident, ok := sw.Tag.(*ast.Ident); !ok || ident.Name != "resource" {
+				return true
+			}
+			for _, stmt := range sw.Body.List {
+				for _, expr := range stmt.(*ast.CaseClause).List {
+					if lit, ok := expr.(*ast.BasicLit); ok {
+						s, _ := strconv.Unquote(lit.Value)
+						resources = append(resources, s)
+					}
+				}
+			}
+			return false
+		})
+		return false
+	})
+	sort.Strings(resources)
+	return resources
+}
+
+func TestAdminOpenAPIRoutesInSync(t *testing.T) {
+	handled := adminHandlerResources(t)
+	if len(handled) == 0 {
+		t.Fatal("no resources found in AdminHandler.ServeHTTP")
+	}
+
+	documented := make(map[string]bool)
+	for _, route := range adminRoutes {
+		resource, _, _ := strings.Cut(strings.TrimPrefix(route.Path, "/"), "/")
+		documented[resource] = true
+	}
+
+	for _, resource := range handled {
+		if !documented[resource] {
+			t.Errorf("resource %q is handled by AdminHandler but missing from adminRoutes", resource)
+		}
+		delete(documented, resource)
+	}
+	for resource := range documented {
+		t.Errorf("resource %q is documented in adminRoutes but not handled by AdminHandler", resource)
+	}
+}
+
+func TestBuildAdminOpenAPISpec(t *testing.T) {
+	spec := buildAdminOpenAPISpec()
+	data, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatalf("marshal spec: %v", err)
+	}
+
+	var doc struct {
+		OpenAPI    string                               `json:"openapi"`
+		Paths      map[string]map[string]map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("unmarshal spec: %v", err)
+	}
+
+	if doc.OpenAPI != "3.0.3" {
+		t.Errorf("openapi = %q, want 3.0.3", doc.OpenAPI)
+	}
+	if _, ok := doc.Paths["/providers/{id}"]["put"]; !ok {
+		t.Error("missing PUT /providers/{id}")
+	}
+	if _, ok := doc.Components.Schemas["domain.Provider"]; !ok {
+		t.Error("missing domain.Provider schema")
+	}
+
+	// 所有 $ref 都必须能在 components 中找到
+	for _, ref := range strings.Split(string(data), `"$ref":"#/components/schemas/`)[1:] {
+		name := ref[:strings.Index(ref, `"`)]
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("dangling $ref to %q", name)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+	"github.com/awsl-project/maxx/internal/service"
+)
+
+func TestAggregateStatsNowRoute(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	svc := service.NewAdminService(nil, nil, nil, nil, nil, nil,
+		sqlite.NewProxyRequestRepository(db), sqlite.NewProxyUpstreamAttemptRepository(db),
+		sqlite.NewSystemSettingRepository(db), nil, nil, sqlite.NewUsageStatsRepository(db),
+		nil, nil, nil, nil, "", nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	h := NewAdminHandler(svc, nil, "", nil)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/admin/stats/aggregate-now", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var result service.AggregateStatsResult
+	if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil || len(result.Phases) == 0 {
+		t.Fatalf("result = %+v, %v, want per-phase counts", result, err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		want   int
+	}{
+		{http.MethodGet, "/admin/stats/aggregate-now", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/admin/stats/aggregate-now/extra", http.StatusNotFound},
+		{http.MethodPost, "/admin/stats/other/aggregate-now", http.StatusNotFound},
+		// 路径精确匹配，不再挂在 usage-stats 下
+		{http.MethodPost, "/admin/usage-stats/aggregate-now", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, nil))
+		if rec.Code != tt.want {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package handler
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+// ClientIPResolver resolves the real client IP of a proxy request.
+// X-Forwarded-For / X-Real-IP are only honored when the direct peer is
+// listed in the trusted_proxies setting, so the header can't be spoofed.
+type ClientIPResolver struct {
+	settingRepo repository.SystemSettingRepository
+}
+
+// NewClientIPResolver creates a new client IP resolver
+func NewClientIPResolver(settingRepo repository.SystemSettingRepository) *ClientIPResolver {
+	return &ClientIPResolver{settingRepo: settingRepo}
+}
+
+// Resolve returns the client IP for the request
+func (c *ClientIPResolver) Resolve(r *http.Request) string {
+	remoteIP := parseRemoteIP(r.RemoteAddr)
+	if c == nil || c.settingRepo == nil {
+		return remoteIP
+	}
+
+	trusted := parseIPNets(c.getSetting(domain.SettingKeyTrustedProxies))
+	if len(trusted) == 0 || !ipInNets(remoteIP, trusted) {
+		return remoteIP
+	}
+
+	// Walk X-Forwarded-For from right to left, skipping trusted proxies.
+	// The first untrusted hop is the real client.
+	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			if !ipInNets(hop, trusted) {
+				return hop
+			}
+			if i == 0 {
+				return hop
+			}
+		}
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+
+	return remoteIP
+}
+
+// IsDenied checks whether the client IP matches the ip_deny_list setting
+func (c *ClientIPResolver) IsDenied(ip string) bool {
+	if c == nil || c.settingRepo == nil || ip == "" {
+		return false
+	}
+	denied := parseIPNets(c.getSetting(domain.SettingKeyIPDenyList))
+	return ipInNets(ip, denied)
+}
+
+func (c *ClientIPResolver) getSetting(key string) string {
+	val, err := c.settingRepo.Get(key)
+	if err != nil {
+		return ""
+	}
+	return val
+}
+
+// parseRemoteIP strips the port from http.Request.RemoteAddr
+func parseRemoteIP(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		return strings.TrimSpace(remoteAddr)
+	}
+	return host
+}
+
+// parseIPNets parses a comma separated list of CIDRs or plain IPs
+func parseIPNets(value string) []*net.IPNet {
+	var nets []*net.IPNet
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				continue
+			}
+			if ip.To4() != nil {
+				item += "/32"
+			} else {
+				item += "/128"
+			}
+		}
+		if _, ipNet, err := net.ParseCIDR(item); err == nil {
+			nets = append(nets, ipNet)
+		}
+	}
+	return nets
+}
+
+func ipInNets(ip string, nets []*net.IPNet) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range nets {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+type clientIPSettings struct {
+	repository.SystemSettingRepository
+	values map[string]string
+}
+
+func (s clientIPSettings) Get(key string) (string, error) {
+	return s.values[key], nil
+}
+
+func TestClientIPResolverResolve(t *testing.T) {
+	resolver := NewClientIPResolver(clientIPSettings{values: map[string]string{
+		// 无效条目被忽略
+		domain.SettingKeyTrustedProxies: "10.0.0.0/8, ::1, bad-cidr/99, 192.168.1.300, fd00::/8",
+	}})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		xff        string
+		realIP     string
+		want       string
+	}{
+		{"untrusted peer, spoofed XFF", "203.0.113.5:1234", "1.2.3.4", "5.6.7.8", "203.0.113.5"},
+		{"trusted peer, one hop", "10.0.0.2:1234", "198.51.100.7", "", "198.51.100.7"},
+		{"multi-hop, spoofed leftmost", "10.0.0.2:1234", "6.6.6.6, 198.51.100.7, 10.0.0.3", "", "198.51.100.7"},
+		{"all hops trusted", "10.0.0.2:1234", "10.0.0.9, 10.0.0.3", "", "10.0.0.9"},
+		{"invalid hop stops the walk", "10.0.0.2:1234", "garbage, 10.0.0.3", "198.51.100.9", "198.51.100.9"},
+		{"X-Real-IP from trusted peer", "10.0.0.2:1234", "", "198.51.100.8", "198.51.100.8"},
+		{"invalid X-Real-IP", "10.0.0.2:1234", "", "not-an-ip", "10.0.0.2"},
+		{"IPv6 trusted peer", "[::1]:443", "2001:db8::1", "", "2001:db8::1"},
+		{"IPv6 multi-hop", "[fd00::2]:443", "2001:db8::1, fd00::3", "", "2001:db8::1"},
+		{"IPv6 untrusted peer", "[2001:db8::2]:443", "198.51.100.7", "", "2001:db8::2"},
+		{"bad CIDR entry trusts nothing", "192.168.1.1:1234", "198.51.100.7", "", "192.168.1.1"},
+		{"remote addr without port", "203.0.113.5", "", "", "203.0.113.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/v1/messages", nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				r.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := resolver.Resolve(r); got != tt.want {
+				t.Errorf("Resolve = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// 未配置可信代理时从不信任转发头
+	r := httptest.NewRequest("POST", "/v1/messages", nil)
+	r.RemoteAddr = "10.0.0.2:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.7")
+	if got := NewClientIPResolver(clientIPSettings{}).Resolve(r); got != "10.0.0.2" {
+		t.Errorf("Resolve without trusted proxies = %q, want 10.0.0.2", got)
+	}
+	if got := (*ClientIPResolver)(nil).Resolve(r); got != "10.0.0.2" {
+		t.Errorf("nil resolver = %q, want 10.0.0.2", got)
+	}
+}
+
+func TestClientIPResolverIsDenied(t *testing.T) {
+	resolver := NewClientIPResolver(clientIPSettings{values: map[string]string{
+		domain.SettingKeyIPDenyList: "203.0.113.0/24, 198.51.100.7, 2001:db8::/32, not-an-ip, 10.0.0.0/99",
+	}})
+
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"203.0.113.9", true},
+		{"198.51.100.7", true},
+		{"198.51.100.8", false},
+		{"2001:db8::5", true},
+		{"2001:db9::5", false},
+		{"10.0.0.1", false}, // 无效 CIDR 被忽略
+		{"", false},
+		{"garbage", false},
+	}
+	for _, tt := range tests {
+		if got := resolver.IsDenied(tt.ip); got != tt.want {
+			t.Errorf("IsDenied(%q) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+	if NewClientIPResolver(clientIPSettings{}).IsDenied("203.0.113.9") {
+		t.Error("empty deny list denied an IP")
+	}
+}
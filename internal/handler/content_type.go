@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	contentTypeJSON = "application/json"
+	contentTypeSSE  = "text/event-stream"
+)
+
+// isJSONContentType reports whether the Content-Type header is JSON
+// (application/json or any +json media type)
+func isJSONContentType(value string) bool {
+	mediaType, _, err := mime.ParseMediaType(value)
+	if err != nil {
+		return false
+	}
+	return mediaType == contentTypeJSON || strings.HasSuffix(mediaType, "+json")
+}
+
+// expectedStreamContentType returns the Content-Type a successful response to r
+// must carry. Gemini streamGenerateContent without alt=sse answers with a
+// streamed JSON array, so no type is forced there.
+func expectedStreamContentType(r *http.Request, stream bool) string {
+	if !stream {
+		return contentTypeJSON
+	}
+	if strings.Contains(r.URL.Path, "streamGenerateContent") && r.URL.Query().Get("alt") != "sse" {
+		return ""
+	}
+	return contentTypeSSE
+}
+
+// contentTypeWriter overrides the Content-Type of proxied responses right
+// before the header is sent: SSE for successful streaming responses, JSON for
+// everything else (including errors on streaming requests), whatever the
+// upstream declared.
+type contentTypeWriter struct {
+	http.ResponseWriter
+	successType string
+	wroteHeader bool
+}
+
+func newContentTypeWriter(w http.ResponseWriter, successType string) *contentTypeWriter {
+	return &contentTypeWriter{ResponseWriter: w, successType: successType}
+}
+
+func (c *contentTypeWriter) WriteHeader(code int) {
+	if !c.wroteHeader {
+		c.wroteHeader = true
+		expected := contentTypeJSON
+		if code >= 200 && code < 300 {
+			expected = c.successType
+		}
+		if expected != "" {
+			// 保留上游声明的参数（如 charset），仅在类型不一致时覆盖
+			mediaType, _, _ := mime.ParseMediaType(c.Header().Get("Content-Type"))
+			if mediaType != expected {
+				c.Header().Set("Content-Type", expected)
+			}
+		}
+	}
+	c.ResponseWriter.WriteHeader(code)
+}
+
+func (c *contentTypeWriter) Write(b []byte) (int, error) {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+// Flush implements http.Flusher for streaming support
+func (c *contentTypeWriter) Flush() {
+	if !c.wroteHeader {
+		c.WriteHeader(http.StatusOK)
+	}
+	if f, ok := c.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer (write deadlines etc.)
+func (c *contentTypeWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
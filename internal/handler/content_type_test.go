@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIsJSONContentType(t *testing.T) {
+	tests := []struct {
+		value string
+		want  bool
+	}{
+		{"application/json", true},
+		{"Application/JSON; charset=utf-8", true},
+		{"application/vnd.api+json", true},
+		{"text/plain", false},
+		{"application/x-www-form-urlencoded", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := isJSONContentType(tt.value); got != tt.want {
+			t.Errorf("isJSONContentType(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestContentTypeWriter(t *testing.T) {
+	tests := []struct {
+		name        string
+		successType string
+		upstream    string
+		status      int
+		want        string
+	}{
+		{"stream mislabeled as text", contentTypeSSE, "text/plain", http.StatusOK, contentTypeSSE},
+		{"stream error is json", contentTypeSSE, "text/html", http.StatusBadRequest, contentTypeJSON},
+		{"json keeps charset", contentTypeJSON, "application/json; charset=utf-8", http.StatusOK, "application/json; charset=utf-8"},
+		{"json without header", contentTypeJSON, "", http.StatusOK, contentTypeJSON},
+		{"gemini json array untouched", "", "application/json", http.StatusOK, "application/json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			w := newContentTypeWriter(rec, tt.successType)
+			if tt.upstream != "" {
+				w.Header().Set("Content-Type", tt.upstream)
+			}
+			w.WriteHeader(tt.status)
+			if got := rec.Header().Get("Content-Type"); got != tt.want {
+				t.Errorf("Content-Type = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpectedStreamContentType(t *testing.T) {
+	tests := []struct {
+		target string
+		stream bool
+		want   string
+	}{
+		{"/v1/messages", false, contentTypeJSON},
+		{"/v1/messages", true, contentTypeSSE},
+		{"/v1beta/models/gemini-2.5-pro:streamGenerateContent?alt=sse", true, contentTypeSSE},
+		{"/v1beta/models/gemini-2.5-pro:streamGenerateContent", true, ""},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, tt.target, nil)
+		if got := expectedStreamContentType(r, tt.stream); got != tt.want {
+			t.Errorf("expectedStreamContentType(%q, %v) = %q, want %q", tt.target, tt.stream, got, tt.want)
+		}
+	}
+}
+
+// deadlineRecorder records write deadlines set through http.ResponseController
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	deadline time.Time
+}
+
+func (r *deadlineRecorder) SetWriteDeadline(t time.Time) error {
+	r.deadline = t
+	return nil
+}
+
+func TestResponseWrappersUnwrap(t *testing.T) {
+	// 代理响应经过 LoggingMiddleware 和 contentTypeWriter 两层包装，写截止时间需能到达底层连接
+	rec := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	w := newContentTypeWriter(&responseWriter{ResponseWriter: rec, statusCode: http.StatusOK}, contentTypeSSE)
+
+	deadline := time.Now()
+	if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil {
+		t.Fatalf("SetWriteDeadline: %v", err)
+	}
+	if !rec.deadline.Equal(deadline) {
+		t.Errorf("deadline = %v, want %v", rec.deadline, deadline)
+	}
+}
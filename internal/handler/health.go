@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// StorageHealthChecker reports whether the database still accepts writes
+type StorageHealthChecker interface {
+	Status() domain.StorageHealth
+}
+
+// Startup steps tracked by Readiness
+const (
+	ReadinessCaches   = "caches"
+	ReadinessAdapters = "adapters"
+)
+
+// Readiness tracks the startup steps that must finish before the instance
+// should receive traffic, and whether it is shutting down
+type Readiness struct {
+	mu           sync.RWMutex
+	pending      map[string]bool
+	shuttingDown bool
+}
+
+// NewReadiness creates a Readiness waiting for the given steps
+func NewReadiness(steps ...string) *Readiness {
+	pending := make(map[string]bool, len(steps))
+	for _, step := range steps {
+		pending[step] = true
+	}
+	return &Readiness{pending: pending}
+}
+
+// MarkReady marks a startup step as finished
+func (r *Readiness) MarkReady(step string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, step)
+}
+
+// SetShuttingDown reports not ready from now on, so load balancers stop
+// sending new requests while in-flight ones finish
+func (r *Readiness) SetShuttingDown() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.shuttingDown = true
+}
+
+// Pending returns the unfinished startup steps, sorted
+func (r *Readiness) Pending() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	steps := make([]string, 0, len(r.pending))
+	for step := range r.pending {
+		steps = append(steps, step)
+	}
+	sort.Strings(steps)
+	return steps
+}
+
+func (r *Readiness) isShuttingDown() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.shuttingDown
+}
+
+// NewHealthHandler returns the /health handler. It reports 503 with
+// status "degraded" while storage rejects writes, so load balancers and
+// monitors stop routing to an instance that can't record requests.
+// It does not wait for startup; use /readyz for that.
+func NewHealthHandler(storage StorageHealthChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if storage != nil {
+			if status := storage.Status(); status.Degraded {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"status":  "degraded",
+					"storage": status,
+				})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"ok"}`))
+	}
+}
+
+// NewReadyHandler returns the /readyz handler. It reports 200 only once every
+// startup step has finished and storage accepts writes, and 503 again after
+// shutdown begins.
+func NewReadyHandler(readiness *Readiness, storage StorageHealthChecker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if readiness != nil {
+			if readiness.isShuttingDown() {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"status":"shutting_down"}`))
+				return
+			}
+			if pending := readiness.Pending(); len(pending) > 0 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"status":  "starting",
+					"pending": pending,
+				})
+				return
+			}
+		}
+		if storage != nil {
+			if status := storage.Status(); status.Degraded {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"status":  "degraded",
+					"storage": status,
+				})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"ready"}`))
+	}
+}
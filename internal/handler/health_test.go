@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+type fakeStorageHealth struct{ degraded bool }
+
+func (f *fakeStorageHealth) Status() domain.StorageHealth {
+	return domain.StorageHealth{Degraded: f.degraded}
+}
+
+func TestReadyHandler(t *testing.T) {
+	readiness := NewReadiness(ReadinessCaches, ReadinessAdapters)
+	storage := &fakeStorageHealth{}
+	handler := NewReadyHandler(readiness, storage)
+	check := func(step string, want int) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		if rec.Code != want {
+			t.Errorf("%s: status = %d, want %d (body %s)", step, rec.Code, want, rec.Body.String())
+		}
+	}
+
+	check("starting", http.StatusServiceUnavailable)
+	readiness.MarkReady(ReadinessCaches)
+	check("adapters pending", http.StatusServiceUnavailable)
+	readiness.MarkReady(ReadinessAdapters)
+	check("ready", http.StatusOK)
+
+	storage.degraded = true
+	check("storage degraded", http.StatusServiceUnavailable)
+	storage.degraded = false
+
+	readiness.SetShuttingDown()
+	check("shutting down", http.StatusServiceUnavailable)
+
+	// /health 是存活检查，不等待启动完成
+	rec := httptest.NewRecorder()
+	NewHealthHandler(storage)(rec, httptest.NewRequest(http.MethodGet, "/health", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("/health status = %d, want 200", rec.Code)
+	}
+}
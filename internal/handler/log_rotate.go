@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+// Default log rotation settings
+const (
+	defaultLogMaxSizeMB = 50
+	defaultLogMaxFiles  = 5
+)
+
+// LogRotationConfig configures log file rotation
+type LogRotationConfig struct {
+	MaxSizeBytes int64         // 当前日志文件超过该大小时轮转，0 表示不按大小轮转
+	MaxFiles     int           // 保留的历史文件数量（maxx.log.1 ... maxx.log.N）
+	MaxAge       time.Duration // 历史文件及当前文件的最长保留时间，0 表示不限制
+}
+
+// LoadLogRotationConfig reads log rotation settings, falling back to defaults
+func LoadLogRotationConfig(settingRepo repository.SystemSettingRepository) LogRotationConfig {
+	cfg := LogRotationConfig{
+		MaxSizeBytes: defaultLogMaxSizeMB * 1024 * 1024,
+		MaxFiles:     defaultLogMaxFiles,
+	}
+	if settingRepo == nil {
+		return cfg
+	}
+	if val, err := settingRepo.Get(domain.SettingKeyLogMaxSizeMB); err == nil && val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			cfg.MaxSizeBytes = int64(n) * 1024 * 1024
+		}
+	}
+	if val, err := settingRepo.Get(domain.SettingKeyLogMaxFiles); err == nil && val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n >= 0 {
+			cfg.MaxFiles = n
+		}
+	}
+	if val, err := settingRepo.Get(domain.SettingKeyLogMaxAgeDays); err == nil && val != "" {
+		if n, err := strconv.Atoi(val); err == nil && n > 0 {
+			cfg.MaxAge = time.Duration(n) * 24 * time.Hour
+		}
+	}
+	return cfg
+}
+
+// rotatingFile is an append-only log file that rotates by size and age.
+// Rotated files are named <path>.1 (newest) ... <path>.N (oldest).
+type rotatingFile struct {
+	path   string
+	config LogRotationConfig
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+func openRotatingFile(path string, config LogRotationConfig) (*rotatingFile, error) {
+	rf := &rotatingFile{path: path, config: config}
+	if err := rf.open(); err != nil {
+		return nil, err
+	}
+	rf.pruneBackups()
+	return rf, nil
+}
+
+func (rf *rotatingFile) open() error {
+	f, err := os.OpenFile(rf.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	rf.file = f
+	rf.size = info.Size()
+	rf.openedAt = time.Now()
+	if rf.size > 0 {
+		// 已有内容时以文件修改时间估算起始时间，避免重启后永远不按时间轮转
+		rf.openedAt = info.ModTime()
+	}
+	return nil
+}
+
+// Write appends p, rotating first if the write would exceed the limits
+func (rf *rotatingFile) Write(p []byte) (int, error) {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+
+	if rf.file == nil {
+		return 0, os.ErrClosed
+	}
+	if rf.shouldRotate(int64(len(p))) {
+		if err := rf.rotate(); err != nil {
+			// 轮转失败时继续写入当前文件（如果仍可用），不丢日志
+			fmt.Fprintf(os.Stderr, "Warning: failed to rotate log file %s: %v\n", rf.path, err)
+			if rf.file == nil {
+				return 0, err
+			}
+		}
+	}
+
+	n, err := rf.file.Write(p)
+	rf.size += int64(n)
+	return n, err
+}
+
+func (rf *rotatingFile) shouldRotate(incoming int64) bool {
+	if rf.size == 0 {
+		return false
+	}
+	if rf.config.MaxSizeBytes > 0 && rf.size+incoming > rf.config.MaxSizeBytes {
+		return true
+	}
+	return rf.config.MaxAge > 0 && time.Since(rf.openedAt) > rf.config.MaxAge
+}
+
+// rotate closes the current file, shifts backups and opens a fresh file. Caller holds mu.
+func (rf *rotatingFile) rotate() error {
+	if err := rf.file.Close(); err != nil {
+		return err
+	}
+	rf.file = nil
+
+	if rf.config.MaxFiles > 0 {
+		// maxx.log.(N-1) → maxx.log.N, ..., maxx.log → maxx.log.1
+		os.Remove(rf.backupPath(rf.config.MaxFiles))
+		for i := rf.config.MaxFiles - 1; i >= 1; i-- {
+			os.Rename(rf.backupPath(i), rf.backupPath(i+1))
+		}
+		if err := os.Rename(rf.path, rf.backupPath(1)); err != nil && !os.IsNotExist(err) {
+			rf.open()
+			return err
+		}
+	} else if err := os.Truncate(rf.path, 0); err != nil && !os.IsNotExist(err) {
+		rf.open()
+		return err
+	}
+
+	if err := rf.open(); err != nil {
+		return err
+	}
+	rf.pruneBackups()
+	return nil
+}
+
+// pruneBackups removes backups beyond MaxFiles or older than MaxAge
+func (rf *rotatingFile) pruneBackups() {
+	matches, err := filepath.Glob(rf.path + ".*")
+	if err != nil {
+		return
+	}
+	for _, m := range matches {
+		idx, err := strconv.Atoi(strings.TrimPrefix(m, rf.path+"."))
+		if err != nil || idx <= 0 {
+			continue // 不是我们生成的备份文件
+		}
+		if idx > rf.config.MaxFiles {
+			os.Remove(m)
+			continue
+		}
+		if rf.config.MaxAge > 0 {
+			if info, err := os.Stat(m); err == nil && time.Since(info.ModTime()) > rf.config.MaxAge {
+				os.Remove(m)
+			}
+		}
+	}
+}
+
+func (rf *rotatingFile) backupPath(i int) string {
+	return rf.path + "." + strconv.Itoa(i)
+}
+
+// Close closes the underlying file
+func (rf *rotatingFile) Close() error {
+	rf.mu.Lock()
+	defer rf.mu.Unlock()
+	if rf.file == nil {
+		return nil
+	}
+	err := rf.file.Close()
+	rf.file = nil
+	return err
+}
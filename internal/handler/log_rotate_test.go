@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRotatingFileRotatesBySize(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "maxx.log")
+	rf, err := openRotatingFile(path, LogRotationConfig{MaxSizeBytes: 100, MaxFiles: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rf.Close()
+
+	line := strings.Repeat("x", 39) + "\n" // 40 bytes
+	for i := 0; i < 10; i++ {
+		if _, err := rf.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, p := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("expected %s to exist: %v", p, err)
+		}
+		if info.Size() > 100 {
+			t.Errorf("%s size = %d, want <= 100", p, info.Size())
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected at most 2 backups, found %s.3", path)
+	}
+}
+
+func TestRotatingFileConcurrentWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "maxx.log")
+	rf, err := openRotatingFile(path, LogRotationConfig{MaxSizeBytes: 1024, MaxFiles: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const goroutines, perGoroutine = 8, 100
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				fmt.Fprintf(rf, "goroutine %d line %03d\n", g, i)
+			}
+		}(g)
+	}
+	wg.Wait()
+	rf.Close()
+
+	// 所有行都应完整保留在当前文件或备份文件中
+	files, _ := filepath.Glob(path + "*")
+	total := 0
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, l := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if !strings.HasPrefix(l, "goroutine ") {
+				t.Fatalf("corrupted line %q in %s", l, f)
+			}
+			total++
+		}
+	}
+	if total != goroutines*perGoroutine {
+		t.Errorf("got %d lines across %d files, want %d", total, len(files), goroutines*perGoroutine)
+	}
+}
@@ -0,0 +1,174 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// logStreamBuffer 每个订阅者缓冲的日志行数，消费不及时时丢弃新日志而不阻塞写日志
+	logStreamBuffer = 256
+	// logStreamHeartbeat SSE 心跳间隔，避免空闲连接被代理断开
+	logStreamHeartbeat = 15 * time.Second
+)
+
+// LogStream fans log lines out to subscribers, each with its own filter, so
+// only matching lines leave the server (GET /admin/logs/stream)
+type LogStream struct {
+	mu   sync.RWMutex
+	subs map[*logSubscriber]struct{}
+}
+
+type logSubscriber struct {
+	filter LogFilter
+	ch     chan string
+}
+
+// NewLogStream creates an empty log stream
+func NewLogStream() *LogStream {
+	return &LogStream{subs: make(map[*logSubscriber]struct{})}
+}
+
+// Subscribe registers a filtered subscriber; the returned function unsubscribes it
+func (s *LogStream) Subscribe(filter LogFilter) (<-chan string, func()) {
+	sub := &logSubscriber{filter: filter, ch: make(chan string, logStreamBuffer)}
+	s.mu.Lock()
+	s.subs[sub] = struct{}{}
+	s.mu.Unlock()
+	return sub.ch, func() {
+		s.mu.Lock()
+		delete(s.subs, sub)
+		s.mu.Unlock()
+	}
+}
+
+// Publish delivers line to every subscriber whose filter matches it. It never
+// blocks: lines for a subscriber whose buffer is full are dropped.
+func (s *LogStream) Publish(line string) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.subs) == 0 {
+		return
+	}
+	lower := strings.ToLower(line)
+	for sub := range s.subs {
+		if !sub.filter.match(line, lower) {
+			continue
+		}
+		select {
+		case sub.ch <- line:
+		default:
+		}
+	}
+}
+
+// LogFilter selects log lines; all space-separated terms must match:
+//
+//	provider:<id>  lines about the provider ("provider 5", "Provider: 5", "providerID=5" or its name)
+//	tag:<name>     lines with the "[name]" prefix, e.g. tag:Executor
+//	anything else  case-insensitive substring
+//
+// An empty filter matches every line.
+type LogFilter struct {
+	terms []func(line, lower string) bool
+}
+
+// ParseLogFilter parses a filter expression; providerName resolves provider
+// IDs to names so lines logged by name match too, and may be nil
+func ParseLogFilter(expr string, providerName func(id uint64) string) (LogFilter, error) {
+	var f LogFilter
+	for _, term := range strings.Fields(expr) {
+		field, value, _ := strings.Cut(term, ":")
+		switch strings.ToLower(field) {
+		case "provider":
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || id == 0 {
+				return f, fmt.Errorf("invalid filter %q: provider must be a provider ID", term)
+			}
+			re := regexp.MustCompile(`(?i)\bprovider(?:[ _]?id)?[\s:=#]*` + strconv.FormatUint(id, 10) + `\b`)
+			var name string
+			if providerName != nil {
+				name = providerName(id)
+			}
+			f.terms = append(f.terms, func(line, _ string) bool {
+				return re.MatchString(line) || (name != "" && strings.Contains(line, name))
+			})
+		case "tag":
+			if value == "" {
+				return f, fmt.Errorf("invalid filter %q: tag is empty", term)
+			}
+			tag := "[" + strings.ToLower(value) + "]"
+			f.terms = append(f.terms, func(_, lower string) bool { return strings.Contains(lower, tag) })
+		default:
+			needle := strings.ToLower(term)
+			f.terms = append(f.terms, func(_, lower string) bool { return strings.Contains(lower, needle) })
+		}
+	}
+	return f, nil
+}
+
+func (f LogFilter) match(line, lower string) bool {
+	for _, term := range f.terms {
+		if !term(line, lower) {
+			return false
+		}
+	}
+	return true
+}
+
+// handleLogStream streams matching log lines as Server-Sent Events until the client disconnects
+// GET /admin/logs/stream?filter=provider:5
+func (h *AdminHandler) handleLogStream(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+		return
+	}
+	if h.logStream == nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"error": "log streaming is not available"})
+		return
+	}
+	filter, err := ParseLogFilter(r.URL.Query().Get("filter"), func(id uint64) string {
+		if p, err := h.svc.GetProvider(id); err == nil {
+			return p.Name
+		}
+		return ""
+	})
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	lines, unsubscribe := h.logStream.Subscribe(filter)
+	defer unsubscribe()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_ = rc.Flush()
+
+	heartbeat := time.NewTicker(logStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return
+		case line := <-lines:
+			// 多行日志按 SSE 规范拆成多个 data 行
+			_, err = fmt.Fprintf(w, "data: %s\n\n", strings.ReplaceAll(line, "\n", "\ndata: "))
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": ping\n\n")
+		}
+		if err != nil {
+			return
+		}
+		_ = rc.Flush()
+	}
+}
@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogFilter(t *testing.T) {
+	names := func(id uint64) string {
+		if id == 5 {
+			return "my-relay"
+		}
+		return ""
+	}
+	tests := []struct {
+		filter string
+		line   string
+		want   bool
+	}{
+		{"", "anything", true},
+		{"provider:5", "[Executor] Provider 5 was removed, skipping route 3", true},
+		{"provider:5", "[Executor] ProxyError - Retryable: true, Provider: 5", true},
+		{"provider:5", "[Cooldown] providerID=5 cooling down", true},
+		{"provider:5", "[Executor] Format conversion needed: claude -> openai for provider my-relay", true},
+		{"provider:5", "[Executor] Provider 50 was removed", false},
+		{"provider:5", "[Executor] skipping route 5", false},
+		{"tag:executor provider:5", "[Router] provider 5 matched", false},
+		{"tag:Executor timeout", "[Executor] request TIMEOUT", true},
+	}
+	for _, tt := range tests {
+		f, err := ParseLogFilter(tt.filter, names)
+		if err != nil {
+			t.Fatalf("ParseLogFilter(%q): %v", tt.filter, err)
+		}
+		if got := f.match(tt.line, strings.ToLower(tt.line)); got != tt.want {
+			t.Errorf("filter %q on %q = %v, want %v", tt.filter, tt.line, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"provider:abc", "provider:0", "tag:"} {
+		if _, err := ParseLogFilter(bad, nil); err == nil {
+			t.Errorf("ParseLogFilter(%q) accepted", bad)
+		}
+	}
+}
+
+func TestLogStreamPublish(t *testing.T) {
+	s := NewLogStream()
+	f, _ := ParseLogFilter("provider:5", nil)
+	lines, unsubscribe := s.Subscribe(f)
+
+	s.Publish("[Executor] provider 7 failed")
+	s.Publish("[Executor] provider 5 failed")
+	if got := <-lines; got != "[Executor] provider 5 failed" {
+		t.Errorf("received %q", got)
+	}
+	select {
+	case extra := <-lines:
+		t.Errorf("unexpected line %q", extra)
+	default:
+	}
+
+	// 订阅者不消费时丢弃新日志，不阻塞写日志
+	for i := 0; i < logStreamBuffer+10; i++ {
+		s.Publish("provider 5")
+	}
+	unsubscribe()
+	s.Publish("provider 5 after unsubscribe")
+	if len(lines) != logStreamBuffer {
+		t.Errorf("buffered %d lines, want %d", len(lines), logStreamBuffer)
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/awsl-project/maxx/internal/domain"
 	"github.com/awsl-project/maxx/internal/repository"
 )
 
@@ -36,12 +38,18 @@ func (h *ProjectProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Look up project by slug
+	// Look up project by slug, then by slugs replaced within the grace period
 	project, err := h.projectRepo.GetBySlug(slug)
 	if err != nil {
-		log.Printf("[ProjectProxy] Project not found for slug: %s", slug)
-		writeError(w, http.StatusNotFound, "project not found")
-		return
+		var previous *domain.PreviousSlug
+		project, previous = h.findByPreviousSlug(slug)
+		if project == nil {
+			log.Printf("[ProjectProxy] Project not found for slug: %s", slug)
+			writeError(w, http.StatusNotFound, "project not found")
+			return
+		}
+		log.Printf("[ProjectProxy] Deprecated slug %s used for project %s (now %s)", slug, project.Name, project.Slug)
+		setDeprecatedSlugHeaders(w, slug, project.Slug, previous.ExpiresAt)
 	}
 
 	log.Printf("[ProjectProxy] Routing request through project: %s (ID: %d)", project.Name, project.ID)
@@ -56,6 +64,31 @@ func (h *ProjectProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request)
 	h.proxyHandler.ServeHTTP(w, r)
 }
 
+// findByPreviousSlug returns the project that used slug before a slug change,
+// if the change is still within its grace period
+func (h *ProjectProxyHandler) findByPreviousSlug(slug string) (*domain.Project, *domain.PreviousSlug) {
+	projects, err := h.projectRepo.List()
+	if err != nil {
+		return nil, nil
+	}
+	now := time.Now()
+	for _, p := range projects {
+		if previous := p.ActivePreviousSlug(slug, now); previous != nil {
+			return p, previous
+		}
+	}
+	return nil, nil
+}
+
+// setDeprecatedSlugHeaders tells clients on an old project URL to move to the
+// current slug before the old one stops resolving (RFC 8594 Sunset)
+func setDeprecatedSlugHeaders(w http.ResponseWriter, oldSlug, newSlug string, expiresAt time.Time) {
+	w.Header().Set("Deprecation", "true")
+	w.Header().Set("Sunset", expiresAt.UTC().Format(http.TimeFormat))
+	w.Header().Set("Link", "</project/"+newSlug+"/>; rel=\"successor-version\"")
+	w.Header().Set("Warning", `299 maxx "project slug '`+oldSlug+`' is deprecated, use /project/`+newSlug+`/"`)
+}
+
 // parseProjectPath extracts the project slug and API path from a project-prefixed URL
 // Input: /project/my-project/v1/messages
 // Output: ("my-project", "/v1/messages", true)
@@ -2,17 +2,24 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/awsl-project/maxx/internal/adapter/client"
 	ctxutil "github.com/awsl-project/maxx/internal/context"
 	"github.com/awsl-project/maxx/internal/domain"
 	"github.com/awsl-project/maxx/internal/executor"
+	"github.com/awsl-project/maxx/internal/ratelimit"
+	"github.com/awsl-project/maxx/internal/repository"
 	"github.com/awsl-project/maxx/internal/repository/cached"
+	"github.com/awsl-project/maxx/internal/service"
 )
 
 // RequestTracker interface for tracking active requests
@@ -22,14 +29,24 @@ type RequestTracker interface {
 	IsShuttingDown() bool
 }
 
+// ProjectRequestTracker is implemented by trackers that also count active
+// requests per project for the concurrency history
+type ProjectRequestTracker interface {
+	TrackProject(projectID uint64) func()
+}
+
 // ProxyHandler handles AI API proxy requests
 type ProxyHandler struct {
 	clientAdapter *client.Adapter
 	executor      *executor.Executor
 	sessionRepo   *cached.SessionRepository
 	tokenAuth     *TokenAuthMiddleware
+	clientIP      *ClientIPResolver
 	tracker       RequestTracker
 	trackerMu     sync.RWMutex
+	storage       StorageHealthChecker
+	settingRepo   repository.SystemSettingRepository
+	tokenQuota    *service.TokenQuotaService
 }
 
 // NewProxyHandler creates a new proxy handler
@@ -38,12 +55,14 @@ func NewProxyHandler(
 	exec *executor.Executor,
 	sessionRepo *cached.SessionRepository,
 	tokenAuth *TokenAuthMiddleware,
+	clientIP *ClientIPResolver,
 ) *ProxyHandler {
 	return &ProxyHandler{
 		clientAdapter: clientAdapter,
 		executor:      exec,
 		sessionRepo:   sessionRepo,
 		tokenAuth:     tokenAuth,
+		clientIP:      clientIP,
 	}
 }
 
@@ -54,6 +73,42 @@ func (h *ProxyHandler) SetRequestTracker(tracker RequestTracker) {
 	h.tracker = tracker
 }
 
+// SetStorageHealth sets the storage health checker; while storage is degraded
+// new proxy requests are rejected with 503
+func (h *ProxyHandler) SetStorageHealth(storage StorageHealthChecker) {
+	h.storage = storage
+}
+
+// SetSettingRepo sets the settings repository used for optional request checks
+// such as enforce_content_type
+func (h *ProxyHandler) SetSettingRepo(settingRepo repository.SystemSettingRepository) {
+	h.settingRepo = settingRepo
+}
+
+// SetTokenQuota sets the checker for per-token model usage quotas
+func (h *ProxyHandler) SetTokenQuota(tokenQuota *service.TokenQuotaService) {
+	h.tokenQuota = tokenQuota
+}
+
+// enforceContentType reports whether the enforce_content_type setting is on
+func (h *ProxyHandler) enforceContentType() bool {
+	if h.settingRepo == nil {
+		return false
+	}
+	val, err := h.settingRepo.Get(domain.SettingKeyEnforceContentType)
+	return err == nil && val == "true"
+}
+
+// queueOnTokenConcurrencyLimit reports whether requests over an API token's
+// concurrency cap wait for a slot instead of being rejected
+func (h *ProxyHandler) queueOnTokenConcurrencyLimit() bool {
+	if h.settingRepo == nil {
+		return false
+	}
+	val, err := h.settingRepo.Get(domain.SettingKeyAPITokenConcurrencyMode)
+	return err == nil && val == "queue"
+}
+
 // ServeHTTP handles proxy requests
 func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	log.Printf("[Proxy] Received request: %s %s", r.Method, r.URL.Path)
@@ -78,6 +133,30 @@ func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Storage rejects writes (read-only data dir, disk full): requests could not
+	// be recorded or billed, so refuse them instead of failing every write
+	if h.storage != nil {
+		if status := h.storage.Status(); status.Degraded {
+			log.Printf("[Proxy] Rejecting request, storage is degraded: %s", status.Reason)
+			writeError(w, http.StatusServiceUnavailable, "storage is unavailable (read-only or full), requests are rejected until writes succeed again")
+			return
+		}
+	}
+
+	// Resolve real client IP (respects trusted_proxies) and apply deny-list
+	clientIP := h.clientIP.Resolve(r)
+	if h.clientIP.IsDenied(clientIP) {
+		log.Printf("[Proxy] Rejecting request from denied IP: %s", clientIP)
+		writeError(w, http.StatusForbidden, "client IP is not allowed")
+		return
+	}
+
+	enforceContentType := h.enforceContentType()
+	if enforceContentType && strings.HasPrefix(r.URL.Path, "/v1/") && !isJSONContentType(r.Header.Get("Content-Type")) {
+		writeError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+		return
+	}
+
 	// Claude Desktop / Anthropic compatibility: count_tokens placeholder
 	if r.URL.Path == "/v1/messages/count_tokens" {
 		_, _ = io.Copy(io.Discard, r.Body)
@@ -100,9 +179,15 @@ func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 	defer r.Body.Close()
 
-	// Detect client type and extract info
-	clientType := h.clientAdapter.DetectClientType(r, body)
-	log.Printf("[Proxy] Detected client type: %s", clientType)
+	// Detect client type and extract info; the X-Maxx-Client-Type header
+	// overrides path detection for integrations on generic paths
+	clientType, fromHeader := clientTypeFromHeader(r)
+	if fromHeader {
+		log.Printf("[Proxy] Client type from %s header: %s", clientTypeHeader, clientType)
+	} else {
+		clientType = h.clientAdapter.DetectClientType(r, body)
+		log.Printf("[Proxy] Detected client type: %s", clientType)
+	}
 	if clientType == "" {
 		writeError(w, http.StatusBadRequest, "unable to detect client type")
 		return
@@ -129,6 +214,14 @@ func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	sessionID := h.clientAdapter.ExtractSessionID(r, body, clientType)
 	stream := h.clientAdapter.IsStreamRequest(r, body)
 
+	// Forced non-stream: the upstream still streams, the executor returns the
+	// accumulated response to the client in one piece
+	nonStreamOverride := stream && resolveForceNonStream(r, apiToken)
+	if nonStreamOverride {
+		stream = false
+		log.Printf("[Proxy] Stream forced off for this request (token id=%d)", apiTokenID)
+	}
+
 	// Build context
 	ctx := r.Context()
 	ctx = ctxutil.WithClientType(ctx, clientType)
@@ -138,7 +231,15 @@ func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	ctx = ctxutil.WithRequestHeaders(ctx, r.Header)
 	ctx = ctxutil.WithRequestURI(ctx, r.URL.RequestURI())
 	ctx = ctxutil.WithIsStream(ctx, stream)
+	ctx = ctxutil.WithNonStreamOverride(ctx, nonStreamOverride)
 	ctx = ctxutil.WithAPITokenID(ctx, apiTokenID)
+	ctx = ctxutil.WithClientIP(ctx, clientIP)
+	if apiToken != nil && len(apiToken.ModelFallbacks) > 0 {
+		ctx = ctxutil.WithModelFallbacks(ctx, apiToken.ModelFallbacks)
+	}
+	if billable, ok := resolveTokenBillable(r, apiToken); ok {
+		ctx = ctxutil.WithBillable(ctx, billable)
+	}
 
 	// Check for project ID from header (set by ProjectProxyHandler)
 	var projectID uint64
@@ -176,25 +277,107 @@ func (h *ProxyHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx = ctxutil.WithProjectID(ctx, projectID)
+	if pt, ok := tracker.(ProjectRequestTracker); ok {
+		defer pt.TrackProject(projectID)()
+	}
 
-	// Execute request (executor handles request recording, project binding, routing, etc.)
-	err = h.executor.Execute(ctx, w, r)
-	if err != nil {
-		proxyErr, ok := err.(*domain.ProxyError)
-		if ok {
-			if stream {
-				writeStreamError(w, proxyErr)
-			} else {
-				writeProxyError(w, proxyErr)
+	// Per-token model usage quotas (day/month, counted from usage_stats)
+	if h.tokenQuota != nil && apiToken != nil {
+		exceeded, err := h.tokenQuota.Check(apiToken, requestModel)
+		if err != nil {
+			log.Printf("[Proxy] Token quota check failed for token id=%d: %v", apiToken.ID, err)
+		} else if exceeded != nil {
+			log.Printf("[Proxy] Rejecting request, %v", exceeded)
+			retryAfter := int(time.Until(exceeded.ResetAt).Seconds()) + 1
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			writeError(w, http.StatusTooManyRequests, exceeded.Error())
+			return
+		}
+	}
+
+	// Per-token concurrency cap; the deferred release also runs on panics and
+	// when the client disconnects mid-request
+	if apiToken != nil && apiToken.MaxConcurrent > 0 {
+		release, err := ratelimit.DefaultConcurrency().Acquire(ctx, apiToken.ID, apiToken.MaxConcurrent, h.queueOnTokenConcurrencyLimit())
+		if err != nil {
+			if errors.Is(err, ratelimit.ErrConcurrencyLimit) {
+				log.Printf("[Proxy] Rejecting request, token id=%d has %d requests in flight", apiToken.ID, apiToken.MaxConcurrent)
+				writeError(w, http.StatusTooManyRequests, fmt.Sprintf("too many concurrent requests for this API token (max %d)", apiToken.MaxConcurrent))
 			}
-		} else {
-			writeError(w, http.StatusInternalServerError, err.Error())
+			// 排队时客户端已断开，无需响应
+			return
 		}
+		defer release()
+	}
+
+	// Upstreams sometimes mislabel responses (e.g. SSE sent as text/plain),
+	// which breaks client parsers
+	if enforceContentType {
+		w = newContentTypeWriter(w, expectedStreamContentType(r, stream))
+	}
+
+	// Execute request (executor handles request recording, project binding, routing, etc.)
+	err = h.executor.ExecuteDeduplicated(ctx, w, r)
+	if err != nil {
+		writeExecuteError(w, err, stream)
 	}
 }
 
 // Helper functions
 
+// clientTypeHeader explicitly selects the client type of a proxy request
+const clientTypeHeader = "X-Maxx-Client-Type"
+
+// clientTypeFromHeader returns the client type selected by the
+// X-Maxx-Client-Type header. ok is false when the header is absent or names
+// an unknown type, in which case the caller falls back to path detection.
+func clientTypeFromHeader(r *http.Request) (domain.ClientType, bool) {
+	v := r.Header.Get(clientTypeHeader)
+	if v == "" {
+		return "", false
+	}
+	clientType, ok := domain.ParseClientType(v)
+	if !ok {
+		log.Printf("[Proxy] Ignoring invalid %s header %q, falling back to path detection", clientTypeHeader, v)
+	}
+	return clientType, ok
+}
+
+// resolveTokenBillable determines the billable flag from the API token.
+// The X-Maxx-Billable header ("true"/"false") is honored only for tokens with
+// AllowBillableOverride; otherwise a NonBillable token marks the request as
+// non-billable. ok is false when the token does not decide, leaving it to the project.
+func resolveTokenBillable(r *http.Request, apiToken *domain.APIToken) (billable bool, ok bool) {
+	if apiToken == nil {
+		return false, false
+	}
+	if apiToken.AllowBillableOverride {
+		if v, err := strconv.ParseBool(strings.TrimSpace(r.Header.Get("X-Maxx-Billable"))); err == nil {
+			return v, true
+		}
+	}
+	if apiToken.NonBillable {
+		return false, true
+	}
+	return false, false
+}
+
+// resolveForceNonStream determines whether a streaming request is answered
+// non-streamed. The X-Maxx-Force-Non-Stream header ("true"/"false") is honored
+// only for tokens with AllowStreamOverride; otherwise the token's
+// ForceNonStream setting decides.
+func resolveForceNonStream(r *http.Request, apiToken *domain.APIToken) bool {
+	if apiToken == nil {
+		return false
+	}
+	if apiToken.AllowStreamOverride {
+		if v, err := strconv.ParseBool(strings.TrimSpace(r.Header.Get("X-Maxx-Force-Non-Stream"))); err == nil {
+			return v
+		}
+	}
+	return apiToken.ForceNonStream
+}
+
 func writeError(w http.ResponseWriter, status int, message string) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
@@ -215,16 +398,54 @@ func writeProxyError(w http.ResponseWriter, err *domain.ProxyError) {
 		}
 		w.Header().Set("Retry-After", strconv.FormatInt(sec, 10))
 	}
-	w.WriteHeader(http.StatusBadGateway)
+	status, errType := proxyErrorStatus(err)
+	w.WriteHeader(status)
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"error": map[string]interface{}{
 			"message":   err.Error(),
-			"type":      "upstream_error",
+			"type":      errType,
 			"retryable": err.Retryable,
 		},
 	})
 }
 
+// proxyErrorStatus returns the HTTP status and error type for a proxy error.
+// A request no provider can take (too large for every route) is the client's
+// to fix, so it gets 413 instead of 502. When every provider is cooling down
+// nothing was sent upstream, so it gets 503 (with Retry-After).
+func proxyErrorStatus(err *domain.ProxyError) (int, string) {
+	if errors.Is(err, domain.ErrRequestTooLarge) {
+		return http.StatusRequestEntityTooLarge, "request_too_large"
+	}
+	if errors.Is(err, domain.ErrNoAvailableProviders) {
+		return http.StatusServiceUnavailable, "no_available_providers"
+	}
+	return http.StatusBadGateway, "upstream_error"
+}
+
+// writeExecuteError writes the error returned by the executor. Streaming
+// requests get an SSE error event unless the request never reached upstream.
+func writeExecuteError(w http.ResponseWriter, err error, stream bool) {
+	proxyErr, ok := err.(*domain.ProxyError)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if stream && !rejectedBeforeUpstream(proxyErr) {
+		writeStreamError(w, proxyErr)
+	} else {
+		writeProxyError(w, proxyErr)
+	}
+}
+
+// rejectedBeforeUpstream reports whether the request was turned away before
+// anything was sent upstream (or to the client). Such errors get their real
+// HTTP status even on streaming requests, so clients see the 413/503 and
+// honor Retry-After instead of an error event on a 200 stream.
+func rejectedBeforeUpstream(err *domain.ProxyError) bool {
+	return errors.Is(err, domain.ErrRequestTooLarge) || errors.Is(err, domain.ErrNoAvailableProviders)
+}
+
 func writeStreamError(w http.ResponseWriter, err *domain.ProxyError) {
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
@@ -237,11 +458,12 @@ func writeStreamError(w http.ResponseWriter, err *domain.ProxyError) {
 	}
 	w.WriteHeader(http.StatusOK)
 
+	_, errType := proxyErrorStatus(err)
 	errorEvent := map[string]interface{}{
 		"type": "error",
 		"error": map[string]interface{}{
 			"message":   err.Error(),
-			"type":      "upstream_error",
+			"type":      errType,
 			"retryable": err.Retryable,
 		},
 	}
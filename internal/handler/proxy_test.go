@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestResolveTokenBillable(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    *domain.APIToken
+		header   string
+		billable bool
+		ok       bool
+	}{
+		{"no token", nil, "false", false, false},
+		{"default token", &domain.APIToken{}, "", false, false},
+		{"header ignored without override", &domain.APIToken{}, "false", false, false},
+		{"non-billable token", &domain.APIToken{NonBillable: true}, "", false, true},
+		{"override to non-billable", &domain.APIToken{AllowBillableOverride: true}, "false", false, true},
+		{"override beats token flag", &domain.APIToken{NonBillable: true, AllowBillableOverride: true}, "true", true, true},
+		{"invalid header falls back", &domain.APIToken{NonBillable: true, AllowBillableOverride: true}, "maybe", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/messages", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Maxx-Billable", tt.header)
+			}
+			billable, ok := resolveTokenBillable(req, tt.token)
+			if billable != tt.billable || ok != tt.ok {
+				t.Errorf("got (%v, %v), want (%v, %v)", billable, ok, tt.billable, tt.ok)
+			}
+		})
+	}
+}
+
+func TestResolveForceNonStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  *domain.APIToken
+		header string
+		want   bool
+	}{
+		{"no token", nil, "true", false},
+		{"default token", &domain.APIToken{}, "", false},
+		{"header ignored without override", &domain.APIToken{}, "true", false},
+		{"token setting", &domain.APIToken{ForceNonStream: true}, "", true},
+		{"header enables", &domain.APIToken{AllowStreamOverride: true}, "true", true},
+		{"header beats token setting", &domain.APIToken{ForceNonStream: true, AllowStreamOverride: true}, "false", false},
+		{"invalid header falls back", &domain.APIToken{ForceNonStream: true, AllowStreamOverride: true}, "maybe", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/v1/messages", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Maxx-Force-Non-Stream", tt.header)
+			}
+			if got := resolveForceNonStream(req, tt.token); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientTypeFromHeader(t *testing.T) {
+	tests := []struct {
+		header     string
+		clientType domain.ClientType
+		ok         bool
+	}{
+		{"", "", false},
+		{"openai", domain.ClientTypeOpenAI, true},
+		{" Claude ", domain.ClientTypeClaude, true},
+		{"anthropic", "", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/api/generic", nil)
+		if tt.header != "" {
+			req.Header.Set("X-Maxx-Client-Type", tt.header)
+		}
+		clientType, ok := clientTypeFromHeader(req)
+		if clientType != tt.clientType || ok != tt.ok {
+			t.Errorf("header %q: got (%q, %v), want (%q, %v)", tt.header, clientType, ok, tt.clientType, tt.ok)
+		}
+	}
+}
+
+func TestWriteExecuteErrorStream(t *testing.T) {
+	cooldown := domain.NewProxyErrorWithMessage(domain.ErrNoAvailableProviders, true, "all providers are in cooldown")
+	cooldown.RetryAfter = 30 * time.Second
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantType   string
+	}{
+		// 未发往上游即被拒绝：流式请求也返回真实状态码
+		{"cooldown", cooldown, http.StatusServiceUnavailable, "application/json"},
+		{"too large", domain.NewProxyErrorWithMessage(domain.ErrRequestTooLarge, false, "too large"), http.StatusRequestEntityTooLarge, "application/json"},
+		// 上游失败：仍以 SSE 错误事件返回
+		{"upstream", domain.NewProxyErrorWithMessage(errors.New("boom"), true, "upstream failed"), http.StatusOK, "text/event-stream"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeExecuteError(rec, tt.err, true)
+			if rec.Code != tt.wantStatus || rec.Header().Get("Content-Type") != tt.wantType {
+				t.Errorf("status = %d, content type %q; want %d %q", rec.Code, rec.Header().Get("Content-Type"), tt.wantStatus, tt.wantType)
+			}
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	writeExecuteError(rec, cooldown, true)
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+	if !strings.Contains(rec.Body.String(), `"no_available_providers"`) {
+		t.Errorf("body = %s", rec.Body.String())
+	}
+}
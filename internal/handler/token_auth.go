@@ -138,12 +138,10 @@ func (m *TokenAuthMiddleware) ValidateRequest(req *http.Request, clientType doma
 		return nil, ErrTokenExpired
 	}
 
-	// Update usage (async to not block request)
-	go func() {
-		if err := m.tokenRepo.IncrementUseCount(apiToken.ID); err != nil {
-			log.Printf("[TokenAuth] Failed to increment token use count for ID %d: %v", apiToken.ID, err)
-		}
-	}()
+	// Record usage (in-memory, flushed to the database in batches by the cached repository)
+	if err := m.tokenRepo.IncrementUseCount(apiToken.ID); err != nil {
+		log.Printf("[TokenAuth] Failed to increment token use count for ID %d: %v", apiToken.ID, err)
+	}
 
 	return apiToken, nil
 }
@@ -20,7 +20,7 @@ var upgrader = websocket.Upgrader{
 }
 
 type WSMessage struct {
-	Type string      `json:"type"` // "proxy_request_update", "proxy_upstream_attempt_update", etc.
+	Type string      `json:"type"` // "proxy_request_update", "proxy_request_summary", "proxy_upstream_attempt_update", etc.
 	Data interface{} `json:"data"`
 }
 
@@ -87,6 +87,14 @@ func (h *WebSocketHub) BroadcastProxyRequest(req *domain.ProxyRequest) {
 	}
 }
 
+// BroadcastProxyRequestSummary sends a compact request update for the live request list
+func (h *WebSocketHub) BroadcastProxyRequestSummary(summary *domain.ProxyRequestSummary) {
+	h.broadcast <- WSMessage{
+		Type: "proxy_request_summary",
+		Data: summary,
+	}
+}
+
 func (h *WebSocketHub) BroadcastProxyUpstreamAttempt(attempt *domain.ProxyUpstreamAttempt) {
 	h.broadcast <- WSMessage{
 		Type: "proxy_upstream_attempt_update",
@@ -113,21 +121,24 @@ func (h *WebSocketHub) BroadcastLog(message string) {
 // WebSocketLogWriter implements io.Writer to capture logs and broadcast via WebSocket
 type WebSocketLogWriter struct {
 	hub      *WebSocketHub
+	stream   *LogStream
 	stdout   io.Writer
-	logFile  *os.File
+	logFile  *rotatingFile
 	filePath string
 }
 
-// NewWebSocketLogWriter creates a writer that broadcasts logs via WebSocket and writes to file
-func NewWebSocketLogWriter(hub *WebSocketHub, stdout io.Writer, logPath string) *WebSocketLogWriter {
+// NewWebSocketLogWriter creates a writer that broadcasts logs via WebSocket and writes to file.
+// The log file is rotated according to rotation (see LoadLogRotationConfig).
+func NewWebSocketLogWriter(hub *WebSocketHub, stdout io.Writer, logPath string, rotation LogRotationConfig) *WebSocketLogWriter {
 	// Open log file in append mode
-	logFile, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	logFile, err := openRotatingFile(logPath, rotation)
 	if err != nil {
 		log.Printf("Warning: Failed to open log file %s: %v", logPath, err)
 	}
 
 	return &WebSocketLogWriter{
 		hub:      hub,
+		stream:   NewLogStream(),
 		stdout:   stdout,
 		logFile:  logFile,
 		filePath: logPath,
@@ -142,20 +153,26 @@ func (w *WebSocketLogWriter) Write(p []byte) (n int, err error) {
 		return n, err
 	}
 
-	// Write to log file
+	// Write to log file (rotation is handled inside, safe for concurrent writes)
 	if w.logFile != nil {
 		w.logFile.Write(p)
 	}
 
-	// Broadcast to WebSocket clients
+	// Broadcast to WebSocket clients and filtered log stream subscribers
 	msg := strings.TrimSpace(string(p))
 	if msg != "" {
 		w.hub.BroadcastLog(msg)
+		w.stream.Publish(msg)
 	}
 
 	return n, nil
 }
 
+// Stream returns the filtered log stream fed by this writer
+func (w *WebSocketLogWriter) Stream() *LogStream {
+	return w.stream
+}
+
 // ReadLastNLines reads the last n lines from the specified log file
 func ReadLastNLines(logPath string, n int) ([]string, error) {
 	file, err := os.Open(logPath)
@@ -0,0 +1,173 @@
+package loadtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/adapter/provider"
+	ctxutil "github.com/awsl-project/maxx/internal/context"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// FakeProviderType is the provider type served by the fake adapter. It is only
+// registered while a load test runs, so the server never offers it.
+const FakeProviderType = "fake"
+
+// fakeStreamChunks 流式响应的文本分片数，延迟均匀分布在各分片之间
+const fakeStreamChunks = 5
+
+// FakeOptions controls the synthetic upstream
+type FakeOptions struct {
+	// 每个请求的模拟上游耗时，实际耗时在 ±Jitter 范围内随机
+	Latency time.Duration
+	Jitter  time.Duration
+
+	// 返回可重试 503 的概率（0-1）
+	ErrorRate float64
+
+	// 每个响应上报的 token 用量
+	InputTokens  uint64
+	OutputTokens uint64
+}
+
+// fakeAdapter answers Claude requests locally after a simulated delay, going
+// through the same event channel and response writer as a real adapter
+type fakeAdapter struct {
+	opts FakeOptions
+}
+
+func registerFakeAdapter(opts FakeOptions) {
+	provider.RegisterAdapterFactory(FakeProviderType, func(p *domain.Provider) (provider.ProviderAdapter, error) {
+		return &fakeAdapter{opts: opts}, nil
+	})
+}
+
+func (a *fakeAdapter) SupportedClientTypes() []domain.ClientType {
+	return []domain.ClientType{domain.ClientTypeClaude}
+}
+
+func (a *fakeAdapter) Execute(ctx context.Context, w http.ResponseWriter, req *http.Request, p *domain.Provider) error {
+	model := ctxutil.GetMappedModel(ctx)
+	var body struct {
+		Stream bool `json:"stream"`
+	}
+	_ = json.Unmarshal(ctxutil.GetRequestBody(ctx), &body)
+
+	latency := a.latency()
+	if rand.Float64() < a.opts.ErrorRate {
+		if err := sleep(ctx, latency); err != nil {
+			return err
+		}
+		proxyErr := domain.NewProxyErrorWithMessage(errors.New("fake upstream error"), true, "upstream returned status 503")
+		proxyErr.HTTPStatusCode = http.StatusServiceUnavailable
+		proxyErr.IsServerError = true
+		return proxyErr
+	}
+
+	eventChan := ctxutil.GetEventChan(ctx)
+	eventChan.SendResponseInfo(&domain.ResponseInfo{Status: http.StatusOK})
+	eventChan.SendMetrics(&domain.AdapterMetrics{
+		InputTokens:  a.opts.InputTokens,
+		OutputTokens: a.opts.OutputTokens,
+	})
+	eventChan.SendResponseModel(model)
+
+	if body.Stream {
+		return a.writeStream(ctx, w, model, latency)
+	}
+
+	if err := sleep(ctx, latency); err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"id":          "msg_loadtest",
+		"type":        "message",
+		"role":        "assistant",
+		"model":       model,
+		"content":     []map[string]any{{"type": "text", "text": "ok"}},
+		"stop_reason": "end_turn",
+		"usage": map[string]any{
+			"input_tokens":  a.opts.InputTokens,
+			"output_tokens": a.opts.OutputTokens,
+		},
+	})
+	return nil
+}
+
+func (a *fakeAdapter) writeStream(ctx context.Context, w http.ResponseWriter, model string, latency time.Duration) error {
+	flusher, _ := w.(http.Flusher)
+	send := func(event string, data any) {
+		payload, _ := json.Marshal(data)
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+
+	step := latency / (fakeStreamChunks + 1)
+	if err := sleep(ctx, step); err != nil {
+		return err
+	}
+	send("message_start", map[string]any{
+		"type": "message_start",
+		"message": map[string]any{
+			"id": "msg_loadtest", "type": "message", "role": "assistant", "model": model,
+			"content": []any{},
+			"usage":   map[string]any{"input_tokens": a.opts.InputTokens, "output_tokens": 0},
+		},
+	})
+	send("content_block_start", map[string]any{
+		"type": "content_block_start", "index": 0,
+		"content_block": map[string]any{"type": "text", "text": ""},
+	})
+	for i := 0; i < fakeStreamChunks; i++ {
+		if err := sleep(ctx, step); err != nil {
+			return err
+		}
+		send("content_block_delta", map[string]any{
+			"type": "content_block_delta", "index": 0,
+			"delta": map[string]any{"type": "text_delta", "text": "ok "},
+		})
+	}
+	send("content_block_stop", map[string]any{"type": "content_block_stop", "index": 0})
+	send("message_delta", map[string]any{
+		"type":  "message_delta",
+		"delta": map[string]any{"stop_reason": "end_turn"},
+		"usage": map[string]any{"output_tokens": a.opts.OutputTokens},
+	})
+	send("message_stop", map[string]any{"type": "message_stop"})
+	return nil
+}
+
+func (a *fakeAdapter) latency() time.Duration {
+	d := a.opts.Latency
+	if a.opts.Jitter > 0 {
+		d += time.Duration(rand.Int63n(int64(2*a.opts.Jitter))) - a.opts.Jitter
+	}
+	return max(d, 0)
+}
+
+// sleep waits for d or until the request is cancelled
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return domain.NewProxyErrorWithMessage(ctx.Err(), false, "request cancelled")
+	}
+}
@@ -0,0 +1,234 @@
+// Package loadtest drives synthetic traffic through the real proxy handler,
+// executor, stats and broadcast paths against a fake upstream, for capacity
+// planning without external providers.
+package loadtest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/core"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// Config 压测配置
+type Config struct {
+	Concurrency int           // 并发 worker 数，每个 worker 串行发送请求
+	Duration    time.Duration // 发送新请求的时长，结束时等待进行中的请求完成
+	Providers   int           // fake Provider 数量，按顺序各建一条路由
+	Model       string
+	Stream      bool
+
+	// 数据目录，为空时使用临时目录并在结束后删除
+	DataDir string
+
+	Fake FakeOptions
+}
+
+// Report 压测结果汇总
+type Report struct {
+	Duration    time.Duration
+	Requests    int
+	Succeeded   int
+	Failed      int
+	StatusCodes map[int]int
+	Throughput  float64 // 每秒完成的请求数
+
+	LatencyMean time.Duration
+	LatencyP50  time.Duration
+	LatencyP90  time.Duration
+	LatencyP99  time.Duration
+	LatencyMax  time.Duration
+
+	// 写入压力：压测期间的数据库写入次数，以及落库的请求/attempt 记录数
+	DBWrites          int64
+	DBWriteErrors     int64
+	DBWritesPerSecond float64
+	RecordedRequests  int64
+	RecordedAttempts  int64
+	StorageDegraded   bool
+}
+
+// result is one request's outcome
+type result struct {
+	latency time.Duration
+	status  int
+}
+
+// Run performs the load test. Cancelling ctx stops it early and aborts the
+// requests still in flight.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	if cfg.Concurrency <= 0 || cfg.Duration <= 0 || cfg.Providers <= 0 {
+		return nil, fmt.Errorf("concurrency, duration and providers must be greater than 0")
+	}
+	if cfg.Fake.ErrorRate < 0 || cfg.Fake.ErrorRate > 1 {
+		return nil, fmt.Errorf("error rate must be between 0 and 1")
+	}
+
+	dataDir := cfg.DataDir
+	if dataDir == "" {
+		dir, err := os.MkdirTemp("", "maxx-loadtest-*")
+		if err != nil {
+			return nil, fmt.Errorf("create temp data directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		dataDir = dir
+	} else if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, fmt.Errorf("create data directory: %w", err)
+	}
+
+	registerFakeAdapter(cfg.Fake)
+
+	repos, err := core.InitializeDatabase(&core.DatabaseConfig{
+		DataDir: dataDir,
+		DBPath:  filepath.Join(dataDir, "maxx.db"),
+		LogPath: filepath.Join(dataDir, "maxx.log"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("initialize database: %w", err)
+	}
+	defer core.CloseDatabase(repos)
+
+	prevLogOutput := log.Writer()
+	components, err := core.InitializeServerComponents(repos, "", fmt.Sprintf("loadtest-%d", time.Now().UnixNano()), filepath.Join(dataDir, "maxx.log"))
+	if err != nil {
+		return nil, fmt.Errorf("initialize server components: %w", err)
+	}
+	// 每个请求都会输出多行日志，压测期间只保留汇总输出
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(prevLogOutput)
+
+	for i := 0; i < cfg.Providers; i++ {
+		p := &domain.Provider{
+			Name:                 fmt.Sprintf("fake-%d", i+1),
+			Type:                 FakeProviderType,
+			Config:               &domain.ProviderConfig{},
+			SupportedClientTypes: []domain.ClientType{domain.ClientTypeClaude},
+		}
+		if err := components.AdminService.CreateProvider(p); err != nil {
+			return nil, fmt.Errorf("create fake provider: %w", err)
+		}
+		route := &domain.Route{IsEnabled: true, ClientType: domain.ClientTypeClaude, ProviderID: p.ID, Position: i + 1}
+		if err := components.AdminService.CreateRoute(route); err != nil {
+			return nil, fmt.Errorf("create route: %w", err)
+		}
+	}
+
+	body := fmt.Appendf(nil, `{"model":%q,"max_tokens":1024,"stream":%t,"messages":[{"role":"user","content":"ping"}]}`, cfg.Model, cfg.Stream)
+	handler := components.ProxyHandler
+	health := repos.DB.WriteHealth()
+	writesBefore, writeErrorsBefore := health.WriteCounts()
+
+	runCtx, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	start := time.Now()
+	results := make([][]result, cfg.Concurrency)
+	var wg sync.WaitGroup
+	for w := 0; w < cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for runCtx.Err() == nil {
+				req := httptest.NewRequest(http.MethodPost, "/v1/messages", bytes.NewReader(body)).WithContext(ctx)
+				req.Header.Set("Content-Type", "application/json")
+				rec := httptest.NewRecorder()
+				begin := time.Now()
+				handler.ServeHTTP(rec, req)
+				results[w] = append(results[w], result{latency: time.Since(begin), status: rec.Code})
+			}
+		}(w)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	writes, writeErrors := health.WriteCounts()
+	report := summarize(results, elapsed)
+	report.DBWrites = writes - writesBefore
+	report.DBWriteErrors = writeErrors - writeErrorsBefore
+	report.DBWritesPerSecond = float64(report.DBWrites) / elapsed.Seconds()
+	report.StorageDegraded = health.Status().Degraded
+	if count, err := repos.ProxyRequestRepo.Count(); err == nil {
+		report.RecordedRequests = count
+	}
+	if count, err := repos.AttemptRepo.CountAll(); err == nil {
+		report.RecordedAttempts = count
+	}
+	return report, nil
+}
+
+// summarize computes throughput and latency percentiles from the per-worker results
+func summarize(results [][]result, elapsed time.Duration) *Report {
+	report := &Report{Duration: elapsed, StatusCodes: make(map[int]int)}
+	var latencies []time.Duration
+	var total time.Duration
+	for _, worker := range results {
+		for _, r := range worker {
+			latencies = append(latencies, r.latency)
+			total += r.latency
+			report.StatusCodes[r.status]++
+			if r.status == http.StatusOK {
+				report.Succeeded++
+			} else {
+				report.Failed++
+			}
+		}
+	}
+	report.Requests = len(latencies)
+	if report.Requests == 0 {
+		return report
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	report.Throughput = float64(report.Requests) / elapsed.Seconds()
+	report.LatencyMean = total / time.Duration(report.Requests)
+	report.LatencyP50 = percentile(latencies, 50)
+	report.LatencyP90 = percentile(latencies, 90)
+	report.LatencyP99 = percentile(latencies, 99)
+	report.LatencyMax = latencies[len(latencies)-1]
+	return report
+}
+
+// percentile returns the nearest-rank percentile of sorted latencies
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
+
+// Print writes the report as a human-readable summary
+func (r *Report) Print(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "Duration\t%v\n", r.Duration.Round(time.Millisecond))
+	fmt.Fprintf(tw, "Requests\t%d (%d ok, %d failed)\n", r.Requests, r.Succeeded, r.Failed)
+	fmt.Fprintf(tw, "Throughput\t%.1f req/s\n", r.Throughput)
+
+	codes := make([]int, 0, len(r.StatusCodes))
+	for code := range r.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		fmt.Fprintf(tw, "  HTTP %d\t%d\n", code, r.StatusCodes[code])
+	}
+
+	round := func(d time.Duration) time.Duration { return d.Round(100 * time.Microsecond) }
+	fmt.Fprintf(tw, "Latency\tmean %v, p50 %v, p90 %v, p99 %v, max %v\n",
+		round(r.LatencyMean), round(r.LatencyP50), round(r.LatencyP90), round(r.LatencyP99), round(r.LatencyMax))
+	fmt.Fprintf(tw, "DB writes\t%d (%.1f/s, %d failed)\n", r.DBWrites, r.DBWritesPerSecond, r.DBWriteErrors)
+	fmt.Fprintf(tw, "Recorded\t%d requests, %d attempts\n", r.RecordedRequests, r.RecordedAttempts)
+	if r.StorageDegraded {
+		fmt.Fprintf(tw, "Storage\tDEGRADED\n")
+	}
+	tw.Flush()
+}
@@ -0,0 +1,46 @@
+package loadtest
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRun(t *testing.T) {
+	report, err := Run(context.Background(), Config{
+		Concurrency: 4,
+		Duration:    300 * time.Millisecond,
+		Providers:   2,
+		Model:       "claude-sonnet-4-5",
+		Stream:      true,
+		DataDir:     t.TempDir(),
+		Fake:        FakeOptions{Latency: 5 * time.Millisecond, InputTokens: 10, OutputTokens: 5},
+	})
+	if err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if report.Requests == 0 || report.Failed != 0 {
+		t.Fatalf("requests = %d, failed = %d (%v); want successful traffic", report.Requests, report.Failed, report.StatusCodes)
+	}
+	if report.RecordedRequests != int64(report.Requests) {
+		t.Errorf("recorded %d requests, sent %d", report.RecordedRequests, report.Requests)
+	}
+	if report.DBWrites == 0 || report.LatencyP50 < 5*time.Millisecond || report.LatencyP99 < report.LatencyP50 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+	for p, want := range map[int]time.Duration{50: 50 * time.Millisecond, 99: 99 * time.Millisecond, 100: 100 * time.Millisecond} {
+		if got := percentile(sorted, p); got != want {
+			t.Errorf("p%d = %v, want %v", p, got, want)
+		}
+	}
+	if got := percentile(sorted[:1], 50); got != time.Millisecond {
+		t.Errorf("p50 of one sample = %v", got)
+	}
+}
@@ -13,8 +13,27 @@ type CostResult struct {
 	Cost         uint64 // 成本（纳美元）
 	ModelPriceID uint64 // 使用的价格记录ID（0 表示使用内置价格表）
 	Multiplier   uint64 // 倍率（10000=1倍）
+
+	PriceOverride bool // 是否使用了 Provider 的价格覆盖
+}
+
+// CostCalculator 成本计算接口，执行器计费和管理端重算都通过它计算成本。
+// Calculator（价格表 + 数据库价格）是默认实现；需要阶梯/批量折扣等按 token
+// 单价无法表达的计费规则时，可实现此接口（通常包装 GlobalCalculator()）并通过
+// Executor.SetCostCalculator / AdminService.SetCostCalculator 注入。
+type CostCalculator interface {
+	// Calculate 计算基础成本（纳美元，不含倍率）
+	Calculate(model string, metrics *usage.Metrics) uint64
+	// CalculateWithModelPrice 使用指定价格记录计算基础成本（不含倍率）
+	CalculateWithModelPrice(mp *domain.ModelPrice, metrics *usage.Metrics) uint64
+	// CalculateWithResult 计算含倍率的成本，ModelPriceID/Multiplier 会记录到 attempt 上供重算使用
+	CalculateWithResult(model string, metrics *usage.Metrics, multiplier uint64) CostResult
+	// CalculateWithOverrides 与 CalculateWithResult 相同，但优先使用 Provider 的价格覆盖
+	CalculateWithOverrides(model string, metrics *usage.Metrics, multiplier uint64, overrides []*domain.ModelPrice) CostResult
 }
 
+var _ CostCalculator = (*Calculator)(nil)
+
 // Calculator 成本计算器
 type Calculator struct {
 	priceTable *PriceTable
@@ -76,25 +95,7 @@ func (c *Calculator) GetModelPrice(model string) *domain.ModelPrice {
 		return nil
 	}
 
-	// 精确匹配
-	if p, ok := c.modelPriceCache[model]; ok {
-		return p
-	}
-
-	// 前缀匹配：找最长匹配
-	var bestMatch *domain.ModelPrice
-	var bestLen int
-
-	for key, price := range c.modelPriceCache {
-		if len(key) > 0 && len(model) >= len(key) && model[:len(key)] == key {
-			if len(key) > bestLen {
-				bestMatch = price
-				bestLen = len(key)
-			}
-		}
-	}
-
-	return bestMatch
+	return c.getModelPriceLocked(model)
 }
 
 // GetModelPriceByID 根据ID获取价格记录
@@ -115,6 +116,9 @@ func (c *Calculator) Calculate(model string, metrics *usage.Metrics) uint64 {
 
 	c.mu.RLock()
 	pricing := c.priceTable.Get(model)
+	if pricing == nil {
+		pricing = c.priceTable.Get(GlobalNormalizer().Normalize(model))
+	}
 	c.mu.RUnlock()
 
 	if pricing == nil {
@@ -149,18 +153,22 @@ func (c *Calculator) CalculateWithPricing(pricing *ModelPricing, metrics *usage.
 		}
 	}
 
-	// 2. 输出成本
-	if metrics.OutputTokens > 0 {
+	// 2. 输出成本（配置了推理价格时，推理 token 单独计费）
+	outputTokens, reasoningTokens := splitReasoningTokens(metrics, pricing.ReasoningPriceMicro)
+	if reasoningTokens > 0 {
+		totalCost += CalculateLinearCost(reasoningTokens, pricing.ReasoningPriceMicro)
+	}
+	if outputTokens > 0 {
 		if pricing.Has1MContext {
 			outputNum, outputDenom := pricing.GetOutputPremiumFraction()
 			totalCost += CalculateTieredCost(
-				metrics.OutputTokens,
+				outputTokens,
 				pricing.OutputPriceMicro,
 				outputNum, outputDenom,
 				pricing.GetContext1MThreshold(),
 			)
 		} else {
-			totalCost += CalculateLinearCost(metrics.OutputTokens, pricing.OutputPriceMicro)
+			totalCost += CalculateLinearCost(outputTokens, pricing.OutputPriceMicro)
 		}
 	}
 
@@ -199,6 +207,14 @@ func (c *Calculator) CalculateWithPricing(pricing *ModelPricing, metrics *usage.
 	return totalCost
 }
 
+// ApplyMultiplier 对成本应用倍率（10000=1倍），0 视为 1 倍
+func ApplyMultiplier(cost, multiplier uint64) uint64 {
+	if multiplier == 0 || multiplier == 10000 {
+		return cost
+	}
+	return cost * multiplier / 10000
+}
+
 // CalculateWithResult 计算成本，返回完整结果（包含 model_price_id 和 multiplier）
 // model: 模型名称
 // metrics: token使用指标
@@ -234,6 +250,9 @@ func (c *Calculator) CalculateWithResult(model string, metrics *usage.Metrics, m
 
 	// 回退到内置价格表
 	pricing := c.priceTable.Get(model)
+	if pricing == nil {
+		pricing = c.priceTable.Get(GlobalNormalizer().Normalize(model))
+	}
 	if pricing == nil {
 		log.Printf("[Pricing] Unknown model: %s, cost will be 0", model)
 		return CostResult{Cost: 0, ModelPriceID: 0, Multiplier: multiplier}
@@ -252,8 +271,21 @@ func (c *Calculator) CalculateWithResult(model string, metrics *usage.Metrics, m
 }
 
 // getModelPriceLocked 获取模型价格（需要持有读锁）
+// 先按原始名称匹配（精确、最长前缀），没有结果时才用归一化名称匹配，
+// 原始名称能匹配到的价格不受归一化影响
 func (c *Calculator) getModelPriceLocked(model string) *domain.ModelPrice {
-	// 精确匹配
+	if p := c.matchModelPriceLocked(model); p != nil {
+		return p
+	}
+	// 归一化后匹配（如 claude-sonnet-3-5-20241022 -> claude-3-5-sonnet）
+	if normalized := GlobalNormalizer().Normalize(model); normalized != model {
+		return c.matchModelPriceLocked(normalized)
+	}
+	return nil
+}
+
+// matchModelPriceLocked 精确匹配，否则取最长前缀匹配（需要持有读锁）
+func (c *Calculator) matchModelPriceLocked(model string) *domain.ModelPrice {
 	if p, ok := c.modelPriceCache[model]; ok {
 		return p
 	}
@@ -332,17 +364,21 @@ func (c *Calculator) calculateWithModelPrice(mp *domain.ModelPrice, metrics *usa
 		}
 	}
 
-	// 2. 输出成本
-	if metrics.OutputTokens > 0 {
+	// 2. 输出成本（配置了推理价格时，推理 token 单独计费）
+	outputTokens, reasoningTokens := splitReasoningTokens(metrics, mp.ReasoningPriceMicro)
+	if reasoningTokens > 0 {
+		totalCost += CalculateLinearCost(reasoningTokens, mp.ReasoningPriceMicro)
+	}
+	if outputTokens > 0 {
 		if mp.Has1MContext {
 			totalCost += CalculateTieredCost(
-				metrics.OutputTokens,
+				outputTokens,
 				mp.OutputPriceMicro,
 				outputNum, outputDenom,
 				threshold,
 			)
 		} else {
-			totalCost += CalculateLinearCost(metrics.OutputTokens, mp.OutputPriceMicro)
+			totalCost += CalculateLinearCost(outputTokens, mp.OutputPriceMicro)
 		}
 	}
 
@@ -369,6 +405,17 @@ func (c *Calculator) calculateWithModelPrice(mp *domain.ModelPrice, metrics *usa
 	return totalCost
 }
 
+// splitReasoningTokens 拆分输出 token：推理 token 是输出 token 的子集，
+// 仅在配置了推理价格时单独计费，否则全部按 output 价格计费
+func splitReasoningTokens(metrics *usage.Metrics, reasoningPriceMicro uint64) (output, reasoning uint64) {
+	output = metrics.OutputTokens
+	if reasoningPriceMicro == 0 || metrics.ReasoningTokens == 0 {
+		return output, 0
+	}
+	reasoning = min(metrics.ReasoningTokens, output)
+	return output - reasoning, reasoning
+}
+
 // SetPriceTable 更新价格表
 func (c *Calculator) SetPriceTable(pt *PriceTable) {
 	c.mu.Lock()
@@ -3,6 +3,7 @@ package pricing
 import (
 	"testing"
 
+	"github.com/awsl-project/maxx/internal/domain"
 	"github.com/awsl-project/maxx/internal/usage"
 )
 
@@ -270,3 +271,35 @@ func TestDefaultCachePrices(t *testing.T) {
 		t.Errorf("GetEffectiveCache1hWritePriceMicro() = %d, want 2000000", got)
 	}
 }
+
+func TestCalculateWithPricing_ReasoningPrice(t *testing.T) {
+	calc := NewCalculator(NewPriceTable("test"))
+	metrics := &usage.Metrics{
+		OutputTokens:    100_000, // 其中 40K 为推理 tokens
+		ReasoningTokens: 40_000,
+	}
+
+	// 未配置推理价格：全部按 output 价格 $10/M 计费 = $1.00
+	pricing := &ModelPricing{OutputPriceMicro: 10_000_000}
+	if got := calc.CalculateWithPricing(pricing, metrics); got != 1_000_000_000 {
+		t.Errorf("without reasoning price = %d, want 1000000000", got)
+	}
+
+	// 推理价格 $20/M: 60K × $10/M + 40K × $20/M = $0.60 + $0.80 = $1.40
+	pricing.ReasoningPriceMicro = 20_000_000
+	if got := calc.CalculateWithPricing(pricing, metrics); got != 1_400_000_000 {
+		t.Errorf("with reasoning price = %d, want 1400000000", got)
+	}
+
+	// 数据库价格同样生效
+	mp := &domain.ModelPrice{OutputPriceMicro: 10_000_000, ReasoningPriceMicro: 20_000_000}
+	if got := calc.calculateWithModelPrice(mp, metrics); got != 1_400_000_000 {
+		t.Errorf("calculateWithModelPrice = %d, want 1400000000", got)
+	}
+
+	// 推理 tokens 超过 output 时按 output 截断: 100K × $20/M = $2.00
+	metrics.ReasoningTokens = 150_000
+	if got := calc.CalculateWithPricing(pricing, metrics); got != 2_000_000_000 {
+		t.Errorf("clamped reasoning = %d, want 2000000000", got)
+	}
+}
@@ -0,0 +1,88 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/usage"
+)
+
+// VolumeDiscountTier 阶梯折扣：单次请求的 token 总量达到 MinTokens 时成本减免 Percent%
+type VolumeDiscountTier struct {
+	MinTokens uint64 `json:"minTokens"`
+	Percent   uint64 `json:"percent"`
+}
+
+// ParseVolumeDiscountTiers 解析 JSON 数组格式的阶梯折扣，空字符串表示不折扣
+func ParseVolumeDiscountTiers(value string) ([]VolumeDiscountTier, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	var tiers []VolumeDiscountTier
+	if err := json.Unmarshal([]byte(value), &tiers); err != nil {
+		return nil, err
+	}
+	for _, tier := range tiers {
+		if tier.Percent > 100 {
+			return nil, fmt.Errorf("discount percent %d out of range 0-100", tier.Percent)
+		}
+	}
+	return tiers, nil
+}
+
+// VolumeDiscountCalculator 在另一个 CostCalculator 的结果上按阶梯折扣计费
+// ModelPriceID / Multiplier 原样保留，重算时使用同一个计算器即可得到相同结果
+type VolumeDiscountCalculator struct {
+	base  CostCalculator
+	tiers []VolumeDiscountTier // 按 MinTokens 从大到小排列
+}
+
+var _ CostCalculator = (*VolumeDiscountCalculator)(nil)
+
+// NewVolumeDiscountCalculator 创建阶梯折扣计算器
+func NewVolumeDiscountCalculator(base CostCalculator, tiers []VolumeDiscountTier) *VolumeDiscountCalculator {
+	sorted := append([]VolumeDiscountTier(nil), tiers...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].MinTokens > sorted[j].MinTokens })
+	return &VolumeDiscountCalculator{base: base, tiers: sorted}
+}
+
+// Calculate 计算折扣后的基础成本
+func (c *VolumeDiscountCalculator) Calculate(model string, metrics *usage.Metrics) uint64 {
+	return c.discount(c.base.Calculate(model, metrics), metrics)
+}
+
+// CalculateWithModelPrice 使用指定价格记录计算折扣后的基础成本
+func (c *VolumeDiscountCalculator) CalculateWithModelPrice(mp *domain.ModelPrice, metrics *usage.Metrics) uint64 {
+	return c.discount(c.base.CalculateWithModelPrice(mp, metrics), metrics)
+}
+
+// CalculateWithResult 计算含倍率、折扣后的成本
+func (c *VolumeDiscountCalculator) CalculateWithResult(model string, metrics *usage.Metrics, multiplier uint64) CostResult {
+	result := c.base.CalculateWithResult(model, metrics, multiplier)
+	result.Cost = c.discount(result.Cost, metrics)
+	return result
+}
+
+// CalculateWithOverrides 与 CalculateWithResult 相同，但优先使用 Provider 的价格覆盖
+func (c *VolumeDiscountCalculator) CalculateWithOverrides(model string, metrics *usage.Metrics, multiplier uint64, overrides []*domain.ModelPrice) CostResult {
+	result := c.base.CalculateWithOverrides(model, metrics, multiplier, overrides)
+	result.Cost = c.discount(result.Cost, metrics)
+	return result
+}
+
+// discount 按请求 token 总量命中的最高阶梯减免成本
+func (c *VolumeDiscountCalculator) discount(cost uint64, metrics *usage.Metrics) uint64 {
+	if metrics == nil || cost == 0 {
+		return cost
+	}
+	total := metrics.InputTokens + metrics.OutputTokens + metrics.CacheReadCount + metrics.CacheCreationCount
+	for _, tier := range c.tiers {
+		if total >= tier.MinTokens {
+			return cost/100*(100-tier.Percent) + cost%100*(100-tier.Percent)/100
+		}
+	}
+	return cost
+}
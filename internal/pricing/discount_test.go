@@ -0,0 +1,61 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/usage"
+)
+
+func TestParseVolumeDiscountTiers(t *testing.T) {
+	tiers, err := ParseVolumeDiscountTiers(" ")
+	if err != nil || tiers != nil {
+		t.Errorf("empty value = %+v, %v, want no tiers", tiers, err)
+	}
+	tiers, err = ParseVolumeDiscountTiers(`[{"minTokens":1000,"percent":10}]`)
+	if err != nil || len(tiers) != 1 || tiers[0].MinTokens != 1000 || tiers[0].Percent != 10 {
+		t.Errorf("tiers = %+v, %v", tiers, err)
+	}
+	for _, value := range []string{`{`, `{"minTokens":1}`, `[{"minTokens":1,"percent":101}]`} {
+		if _, err := ParseVolumeDiscountTiers(value); err == nil {
+			t.Errorf("ParseVolumeDiscountTiers(%s) succeeded, want error", value)
+		}
+	}
+}
+
+func TestVolumeDiscountCalculator(t *testing.T) {
+	base := NewCalculator(DefaultPriceTable())
+	calc := NewVolumeDiscountCalculator(base, []VolumeDiscountTier{
+		{MinTokens: 1000, Percent: 10},
+		{MinTokens: 100000, Percent: 25},
+	})
+	mp := &domain.ModelPrice{ID: 7, ModelID: "m", InputPriceMicro: 3_000_000, OutputPriceMicro: 15_000_000}
+
+	tests := []struct {
+		name    string
+		metrics *usage.Metrics
+		percent uint64
+	}{
+		{"below every tier", &usage.Metrics{InputTokens: 500, OutputTokens: 100}, 0},
+		{"first tier", &usage.Metrics{InputTokens: 900, OutputTokens: 100}, 10},
+		{"highest matching tier", &usage.Metrics{InputTokens: 90000, OutputTokens: 5000, CacheReadCount: 5000}, 25},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := base.CalculateWithModelPrice(mp, tt.metrics) * (100 - tt.percent) / 100
+			if got := calc.CalculateWithModelPrice(mp, tt.metrics); got != want {
+				t.Errorf("CalculateWithModelPrice = %d, want %d", got, want)
+			}
+
+			baseResult := base.CalculateWithResult("claude-sonnet-4", tt.metrics, 15000)
+			result := calc.CalculateWithResult("claude-sonnet-4", tt.metrics, 15000)
+			if result.Cost != baseResult.Cost*(100-tt.percent)/100 {
+				t.Errorf("CalculateWithResult cost = %d, base %d", result.Cost, baseResult.Cost)
+			}
+			// 重算所需的价格记录与倍率原样保留
+			if result.ModelPriceID != baseResult.ModelPriceID || result.Multiplier != 15000 {
+				t.Errorf("result = %+v, want base price record and multiplier", result)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package pricing
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// NormalizationRules 模型名称归一化规则
+// 用于在模型映射和价格查找前统一模型名称，例如
+// "claude-3-5-sonnet-20241022" 与 "claude-3-5-sonnet-latest" 归一化为 "claude-3-5-sonnet"
+type NormalizationRules struct {
+	// StripDateSuffix 是否去掉日期后缀（-20241022 / -2024-10-22 / @20241022）
+	StripDateSuffix bool `json:"stripDateSuffix"`
+	// StripSuffixes 需要去掉的固定后缀（如 "-latest"）
+	StripSuffixes []string `json:"stripSuffixes"`
+	// Aliases 别名表，在后缀处理之后应用，key/value 均为小写不敏感匹配
+	Aliases map[string]string `json:"aliases"`
+}
+
+// DefaultNormalizationRules 返回默认归一化规则
+func DefaultNormalizationRules() *NormalizationRules {
+	return &NormalizationRules{
+		StripDateSuffix: true,
+		StripSuffixes:   []string{"-latest"},
+		Aliases: map[string]string{
+			"claude-sonnet-3-5": "claude-3-5-sonnet",
+			"claude-sonnet-3-7": "claude-3-7-sonnet",
+			"claude-haiku-3-5":  "claude-3-5-haiku",
+		},
+	}
+}
+
+// ParseNormalizationRules 解析 JSON 格式的归一化规则
+// 空字符串返回默认规则；未设置的字段保持零值（即关闭对应规则）
+func ParseNormalizationRules(value string) (*NormalizationRules, error) {
+	if strings.TrimSpace(value) == "" {
+		return DefaultNormalizationRules(), nil
+	}
+	var rules NormalizationRules
+	if err := json.Unmarshal([]byte(value), &rules); err != nil {
+		return nil, err
+	}
+	return &rules, nil
+}
+
+// dateSuffixPattern 匹配模型名称末尾的日期后缀
+var dateSuffixPattern = regexp.MustCompile(`[-@](\d{8}|\d{4}-\d{2}-\d{2})$`)
+
+// ModelNormalizer 模型名称归一化器
+type ModelNormalizer struct {
+	rules *NormalizationRules
+	mu    sync.RWMutex
+}
+
+// 全局归一化器实例
+var (
+	globalNormalizer *ModelNormalizer
+	normalizerOnce   sync.Once
+)
+
+// GlobalNormalizer 返回全局归一化器实例（默认规则）
+func GlobalNormalizer() *ModelNormalizer {
+	normalizerOnce.Do(func() {
+		globalNormalizer = NewModelNormalizer(DefaultNormalizationRules())
+	})
+	return globalNormalizer
+}
+
+// NewModelNormalizer 创建归一化器，rules 为 nil 时使用默认规则
+func NewModelNormalizer(rules *NormalizationRules) *ModelNormalizer {
+	if rules == nil {
+		rules = DefaultNormalizationRules()
+	}
+	return &ModelNormalizer{rules: rules}
+}
+
+// SetRules 替换归一化规则，rules 为 nil 时恢复默认规则
+func (n *ModelNormalizer) SetRules(rules *NormalizationRules) {
+	if rules == nil {
+		rules = DefaultNormalizationRules()
+	}
+	n.mu.Lock()
+	n.rules = rules
+	n.mu.Unlock()
+}
+
+// Rules 返回当前规则
+func (n *ModelNormalizer) Rules() *NormalizationRules {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.rules
+}
+
+// Normalize 归一化模型名称，无法处理时返回原值
+func (n *ModelNormalizer) Normalize(model string) string {
+	if model == "" {
+		return model
+	}
+
+	n.mu.RLock()
+	rules := n.rules
+	n.mu.RUnlock()
+
+	normalized := strings.TrimSpace(model)
+
+	for _, suffix := range rules.StripSuffixes {
+		if trimmed, ok := trimSuffixFold(normalized, suffix); ok && trimmed != "" {
+			normalized = trimmed
+		}
+	}
+
+	if rules.StripDateSuffix {
+		normalized = dateSuffixPattern.ReplaceAllString(normalized, "")
+	}
+
+	for alias, target := range rules.Aliases {
+		if strings.EqualFold(alias, normalized) {
+			normalized = target
+			break
+		}
+	}
+
+	if normalized == "" {
+		return model
+	}
+	return normalized
+}
+
+// trimSuffixFold removes suffix from s ignoring case. Runes are compared from
+// the end, so the cut lands on the bytes of s that actually matched even where
+// case folding changes the encoded length (e.g. "K" vs the Kelvin sign).
+func trimSuffixFold(s, suffix string) (string, bool) {
+	if suffix == "" {
+		return s, false
+	}
+	i, j := len(s), len(suffix)
+	for j > 0 {
+		if i == 0 {
+			return s, false
+		}
+		r1, n1 := utf8.DecodeLastRuneInString(s[:i])
+		r2, n2 := utf8.DecodeLastRuneInString(suffix[:j])
+		if r1 != r2 && !strings.EqualFold(string(r1), string(r2)) {
+			return s, false
+		}
+		i -= n1
+		j -= n2
+	}
+	return s[:i], true
+}
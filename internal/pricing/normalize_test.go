@@ -0,0 +1,116 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestNormalizeDefaultRules(t *testing.T) {
+	n := NewModelNormalizer(nil)
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"claude-3-5-sonnet-20241022", "claude-3-5-sonnet"},
+		{"claude-3-5-sonnet-latest", "claude-3-5-sonnet"},
+		{"claude-3-5-sonnet-LATEST", "claude-3-5-sonnet"},
+		{"claude-sonnet-3-5-20241022", "claude-3-5-sonnet"},
+		{"gemini-2.5-pro@2025-06-17", "gemini-2.5-pro"},
+		{"  gpt-4o  ", "gpt-4o"},
+		{"gpt-4o", "gpt-4o"},
+		{"-latest", "-latest"}, // 整个名称就是后缀时保留原值
+		{"20241022", "20241022"},
+		{"", ""},
+		// 非 ASCII：按实际匹配到的字节截断后缀
+		{"模型-latest", "模型"},
+		{"模型-LATEST", "模型"},
+		{"mödel-latest", "mödel"},
+	}
+	for _, tt := range tests {
+		if got := n.Normalize(tt.model); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeNonASCIISuffix(t *testing.T) {
+	// 开尔文符号 U+212A（3 字节）与 k 大小写等价，后缀长度与名称中的匹配部分字节数不同
+	n := NewModelNormalizer(&NormalizationRules{StripSuffixes: []string{"-\u212a"}})
+	if got := n.Normalize("model-k"); got != "model" {
+		t.Errorf("Normalize(model-k) = %q, want model", got)
+	}
+	n = NewModelNormalizer(&NormalizationRules{StripSuffixes: []string{"-k"}})
+	if got := n.Normalize("模型-\u212a"); got != "模型" {
+		t.Errorf("Normalize(模型-\u212a) = %q, want 模型", got)
+	}
+	if got := n.Normalize("模型-x"); got != "模型-x" {
+		t.Errorf("Normalize(模型-x) = %q, want unchanged", got)
+	}
+}
+
+func TestParseNormalizationRules(t *testing.T) {
+	rules, err := ParseNormalizationRules("  ")
+	if err != nil || !rules.StripDateSuffix || len(rules.StripSuffixes) != 1 {
+		t.Errorf("empty value = %+v, %v, want default rules", rules, err)
+	}
+
+	rules, err = ParseNormalizationRules(`{"stripSuffixes":["-preview"]}`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	// 未设置的字段为零值：不去日期后缀、没有别名
+	if rules.StripDateSuffix || len(rules.Aliases) != 0 {
+		t.Errorf("rules = %+v, want only stripSuffixes", rules)
+	}
+	if got := NewModelNormalizer(rules).Normalize("gpt-5-20250807-preview"); got != "gpt-5-20250807" {
+		t.Errorf("Normalize = %q, want gpt-5-20250807", got)
+	}
+
+	for _, value := range []string{`{`, `[]`, `{"stripDateSuffix":"yes"}`, `{"aliases":["a"]}`} {
+		if _, err := ParseNormalizationRules(value); err == nil {
+			t.Errorf("ParseNormalizationRules(%s) succeeded, want error", value)
+		}
+	}
+}
+
+func TestModelPricePrefixMatchBeforeNormalization(t *testing.T) {
+	calc := NewCalculator(DefaultPriceTable())
+	calc.LoadFromDatabase([]*domain.ModelPrice{
+		{ID: 1, ModelID: "claude-3-5-sonnet"},
+		{ID: 2, ModelID: "claude-3-5-sonnet-2024"},
+		{ID: 3, ModelID: "claude-3-5"},
+	})
+	prices := []*domain.ModelPrice{
+		{ModelID: "claude-3-5-sonnet"},
+		{ModelID: "claude-3-5-sonnet-2024"},
+		{ModelID: "claude-3-5"},
+	}
+
+	tests := []struct {
+		model string
+		want  string
+	}{
+		// 原始名称能前缀匹配时结果不受归一化影响
+		{"claude-3-5-sonnet-20241022", "claude-3-5-sonnet-2024"},
+		{"claude-3-5-haiku-20241022", "claude-3-5"},
+		// 原始名称无法匹配时才使用归一化名称
+		{"claude-sonnet-3-5-20241022", "claude-3-5-sonnet"},
+		{"claude-haiku-3-5-latest", "claude-3-5"},
+		{"gpt-4o", ""},
+	}
+	for _, tt := range tests {
+		for name, got := range map[string]*domain.ModelPrice{
+			"GetModelPrice":   calc.GetModelPrice(tt.model),
+			"MatchModelPrice": MatchModelPrice(prices, tt.model),
+		} {
+			gotID := ""
+			if got != nil {
+				gotID = got.ModelID
+			}
+			if gotID != tt.want {
+				t.Errorf("%s(%q) = %q, want %q", name, tt.model, gotID, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package pricing
+
+import (
+	"strings"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/usage"
+)
+
+// MatchModelPrice finds the price for model in prices using the same rules as
+// the model_prices table: exact match then the longest ModelID prefix, on the
+// model name as given and, only if that finds nothing, on its normalized name.
+// Returns nil if nothing matches.
+func MatchModelPrice(prices []*domain.ModelPrice, model string) *domain.ModelPrice {
+	if len(prices) == 0 || model == "" {
+		return nil
+	}
+	if p := matchModelPriceIn(prices, model); p != nil {
+		return p
+	}
+	if normalized := GlobalNormalizer().Normalize(model); normalized != model {
+		return matchModelPriceIn(prices, normalized)
+	}
+	return nil
+}
+
+// matchModelPriceIn returns the exact match for model, else the longest prefix match
+func matchModelPriceIn(prices []*domain.ModelPrice, model string) *domain.ModelPrice {
+	var bestMatch *domain.ModelPrice
+	for _, p := range prices {
+		if p == nil || p.ModelID == "" || !strings.HasPrefix(model, p.ModelID) {
+			continue
+		}
+		if p.ModelID == model {
+			return p
+		}
+		if bestMatch == nil || len(p.ModelID) > len(bestMatch.ModelID) {
+			bestMatch = p
+		}
+	}
+	return bestMatch
+}
+
+// CalculateWithModelPrice 使用指定价格记录计算成本（不应用倍率）
+func (c *Calculator) CalculateWithModelPrice(mp *domain.ModelPrice, metrics *usage.Metrics) uint64 {
+	return c.calculateWithModelPrice(mp, metrics)
+}
+
+// CalculateWithOverrides 与 CalculateWithResult 相同，但优先使用 Provider 的价格覆盖
+// overrides 中匹配到模型时 PriceOverride 为 true，ModelPriceID 为 0
+func (c *Calculator) CalculateWithOverrides(model string, metrics *usage.Metrics, multiplier uint64, overrides []*domain.ModelPrice) CostResult {
+	mp := MatchModelPrice(overrides, model)
+	if mp == nil || metrics == nil {
+		return c.CalculateWithResult(model, metrics, multiplier)
+	}
+	if multiplier == 0 {
+		multiplier = 10000
+	}
+	return CostResult{
+		Cost:          ApplyMultiplier(c.calculateWithModelPrice(mp, metrics), multiplier),
+		Multiplier:    multiplier,
+		PriceOverride: true,
+	}
+}
@@ -0,0 +1,66 @@
+package pricing
+
+import (
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/usage"
+)
+
+func TestMatchModelPrice(t *testing.T) {
+	prices := []*domain.ModelPrice{
+		{ModelID: "claude"},
+		{ModelID: "claude-sonnet-4"},
+		{ModelID: "gpt-4o"},
+	}
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"gpt-4o", "gpt-4o"},
+		{"claude-sonnet-4-20250514", "claude-sonnet-4"},
+		{"claude-opus-4", "claude"},
+		{"gemini-2.5-pro", ""},
+	}
+	for _, tt := range tests {
+		got := MatchModelPrice(prices, tt.model)
+		if tt.want == "" {
+			if got != nil {
+				t.Errorf("MatchModelPrice(%q) = %q, want nil", tt.model, got.ModelID)
+			}
+			continue
+		}
+		if got == nil || got.ModelID != tt.want {
+			t.Errorf("MatchModelPrice(%q) = %v, want %q", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestCalculateWithOverrides(t *testing.T) {
+	calc := NewCalculator(DefaultPriceTable())
+	metrics := &usage.Metrics{InputTokens: 1_000_000, OutputTokens: 1_000_000}
+	overrides := []*domain.ModelPrice{
+		{ModelID: "claude-sonnet-4", InputPriceMicro: 1_000_000, OutputPriceMicro: 2_000_000}, // $1/M in, $2/M out
+	}
+
+	result := calc.CalculateWithOverrides("claude-sonnet-4-20250514", metrics, 15000, overrides)
+	if !result.PriceOverride {
+		t.Fatal("override not applied")
+	}
+	// ($1 + $2) × 1.5 = $4.5
+	if want := uint64(4_500_000_000); result.Cost != want {
+		t.Errorf("Cost = %d, want %d", result.Cost, want)
+	}
+	if result.Multiplier != 15000 || result.ModelPriceID != 0 {
+		t.Errorf("result = %+v", result)
+	}
+
+	// 未匹配覆盖时回退到全局价格
+	fallback := calc.CalculateWithOverrides("gpt-4o", metrics, 10000, overrides)
+	if fallback.PriceOverride {
+		t.Error("override applied to an unmatched model")
+	}
+	if want := calc.CalculateWithResult("gpt-4o", metrics, 10000); fallback != want {
+		t.Errorf("fallback = %+v, want %+v", fallback, want)
+	}
+}
@@ -22,6 +22,9 @@ type ModelPricing struct {
 	Cache5mWritePriceMicro uint64 `json:"cache5mWritePriceMicro,omitempty"` // 5分钟缓存（默认 input * 5/4）
 	Cache1hWritePriceMicro uint64 `json:"cache1hWritePriceMicro,omitempty"` // 1小时缓存（默认 input * 2）
 
+	// 推理/思考 token 价格 (microUSD/M tokens)，0 表示按 output 价格计费
+	ReasoningPriceMicro uint64 `json:"reasoningPriceMicro,omitempty"`
+
 	// 1M Context Window 分层定价 (Claude Sonnet 4/4.5)
 	Has1MContext       bool   `json:"has1mContext"`                 // 是否支持 1M context
 	Context1MThreshold uint64 `json:"context1mThreshold,omitempty"` // 阈值（默认 200,000）
@@ -189,6 +192,7 @@ func ConvertToDBPrices(pt *PriceTable) []*domain.ModelPrice {
 			CacheReadPriceMicro:    mp.CacheReadPriceMicro,
 			Cache5mWritePriceMicro: mp.Cache5mWritePriceMicro,
 			Cache1hWritePriceMicro: mp.Cache1hWritePriceMicro,
+			ReasoningPriceMicro:    mp.ReasoningPriceMicro,
 			Has1MContext:           mp.Has1MContext,
 			Context1MThreshold:     mp.Context1MThreshold,
 			InputPremiumNum:        mp.InputPremiumNum,
@@ -0,0 +1,71 @@
+package pricing
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultCostDisplayDecimals 成本显示默认保留的美元小数位
+const DefaultCostDisplayDecimals = 4
+
+// maxCostDisplayDecimals 纳美元精度下最多 9 位小数
+const maxCostDisplayDecimals = 9
+
+// ClampCostDisplayDecimals limits decimals to the 0-9 places nanoUSD can represent
+func ClampCostDisplayDecimals(decimals int) int {
+	return min(max(decimals, 0), maxCostDisplayDecimals)
+}
+
+// costDisplayUnit returns how many nanoUSD make one unit of the last displayed decimal place
+func costDisplayUnit(decimals int) uint64 {
+	unit := uint64(1)
+	for i := ClampCostDisplayDecimals(decimals); i < maxCostDisplayDecimals; i++ {
+		unit *= 10
+	}
+	return unit
+}
+
+// NanoToUSDRounded 将纳美元按四舍五入（half-up）保留 decimals 位小数转换为美元
+// 舍入在整数上完成，避免先转浮点再舍入导致 0.00005 之类的边界值舍入方向不一致
+func NanoToUSDRounded(nanoUSD uint64, decimals int) float64 {
+	unit := costDisplayUnit(decimals)
+	return unitsToUSD((nanoUSD+unit/2)/unit, decimals)
+}
+
+// AllocateNanoToUSD converts the parts of a breakdown to USD rounded to decimals
+// places so that the displayed parts add up exactly to the rounded sum of the
+// parts (largest remainder method). Rounding every part on its own can make a
+// breakdown disagree with its total by a few units of the last decimal place.
+func AllocateNanoToUSD(parts []uint64, decimals int) []float64 {
+	unit := costDisplayUnit(decimals)
+	units := make([]uint64, len(parts))
+	var total, floorSum uint64
+	for i, p := range parts {
+		units[i] = p / unit
+		floorSum += units[i]
+		total += p
+	}
+
+	// 余数最大的分项依次进一，直到分项之和等于合计的四舍五入结果
+	extra := (total+unit/2)/unit - floorSum
+	order := make([]int, len(parts))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return parts[order[a]]%unit > parts[order[b]]%unit
+	})
+	for _, i := range order[:extra] {
+		units[i]++
+	}
+
+	result := make([]float64, len(parts))
+	for i, u := range units {
+		result[i] = unitsToUSD(u, decimals)
+	}
+	return result
+}
+
+func unitsToUSD(units uint64, decimals int) float64 {
+	return float64(units) / math.Pow10(ClampCostDisplayDecimals(decimals))
+}
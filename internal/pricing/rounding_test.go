@@ -0,0 +1,71 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestNanoToUSDRounded(t *testing.T) {
+	tests := []struct {
+		nano     uint64
+		decimals int
+		want     float64
+	}{
+		{123_450_000, 4, 0.1235}, // half-up
+		{123_449_999, 4, 0.1234},
+		{50_000, 4, 0.0001},
+		{49_999, 4, 0},
+		{1_999_999_999, 2, 2},
+		{1, 9, 0.000000001},
+		{1_500_000_000, 0, 2},
+		{123_456_789, 12, 0.123456789}, // 超出纳美元精度按 9 位处理
+	}
+	for _, tt := range tests {
+		if got := NanoToUSDRounded(tt.nano, tt.decimals); got != tt.want {
+			t.Errorf("NanoToUSDRounded(%d, %d) = %v, want %v", tt.nano, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestAllocateNanoToUSDMatchesTotal(t *testing.T) {
+	tests := []struct {
+		name     string
+		parts    []uint64
+		decimals int
+	}{
+		// 每项单独四舍五入为 0.0001，合计 0.00015 四舍五入为 0.0002
+		{"half units", []uint64{50_000, 50_000, 50_000}, 4},
+		// 每项单独四舍五入为 0，合计为 0.0001
+		{"below half", []uint64{40_000, 40_000, 40_000}, 4},
+		{"mixed", []uint64{1_234_567_891, 987_654_321, 55_555_555, 5}, 2},
+		{"single", []uint64{123_456_789}, 4},
+		{"empty", nil, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var total uint64
+			for _, p := range tt.parts {
+				total += p
+			}
+			parts := AllocateNanoToUSD(tt.parts, tt.decimals)
+
+			// 按显示格式比较：分项显示值之和必须等于合计的显示值
+			var sum float64
+			for _, p := range parts {
+				sum += p
+			}
+			want := fmt.Sprintf("%.*f", tt.decimals, NanoToUSDRounded(total, tt.decimals))
+			if got := fmt.Sprintf("%.*f", tt.decimals, sum); got != want {
+				t.Errorf("sum of parts %v = %s, want total %s", parts, got, want)
+			}
+			for i, p := range tt.parts {
+				// 每项与其单独舍入的结果最多相差一个最小单位
+				diff := parts[i] - NanoToUSDRounded(p, tt.decimals)
+				if math.Abs(diff) > 1.5/math.Pow10(tt.decimals) {
+					t.Errorf("part %d = %v, too far from %v", i, parts[i], NanoToUSDRounded(p, tt.decimals))
+				}
+			}
+		})
+	}
+}
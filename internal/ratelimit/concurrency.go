@@ -0,0 +1,99 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrConcurrencyLimit is returned by ConcurrencyLimiter.Acquire when all slots
+// are taken and the caller did not ask to wait
+var ErrConcurrencyLimit = errors.New("too many concurrent requests")
+
+// ConcurrencyLimiter caps the number of in-flight requests per key (API token
+// ID). Like Limiter the cap is passed in on every call, so a token edit takes
+// effect on the next request; requests already running keep their slot.
+type ConcurrencyLimiter struct {
+	mu    sync.Mutex
+	slots map[uint64]*slots
+}
+
+// slots 单个 key 的占用数，released 在每次释放时关闭并替换，用于唤醒排队者
+type slots struct {
+	inFlight int
+	released chan struct{}
+}
+
+// NewConcurrencyLimiter creates an empty concurrency limiter
+func NewConcurrencyLimiter() *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{slots: make(map[uint64]*slots)}
+}
+
+// Default global concurrency limiter
+var defaultConcurrencyLimiter = NewConcurrencyLimiter()
+
+// DefaultConcurrency returns the default global concurrency limiter
+func DefaultConcurrency() *ConcurrencyLimiter {
+	return defaultConcurrencyLimiter
+}
+
+// Acquire takes one of key's slots; limit <= 0 means unlimited. When all
+// slots are taken it returns ErrConcurrencyLimit, or with wait set blocks
+// until a slot frees up or ctx is done. The returned release must be called
+// exactly once when the request ends (extra calls are no-ops).
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context, key uint64, limit int, wait bool) (release func(), err error) {
+	if limit <= 0 {
+		return func() {}, nil
+	}
+	for {
+		l.mu.Lock()
+		s := l.slots[key]
+		if s == nil {
+			s = &slots{released: make(chan struct{})}
+			l.slots[key] = s
+		}
+		if s.inFlight < limit {
+			s.inFlight++
+			l.mu.Unlock()
+			var once sync.Once
+			return func() { once.Do(func() { l.release(key) }) }, nil
+		}
+		released := s.released
+		l.mu.Unlock()
+
+		if !wait {
+			return nil, ErrConcurrencyLimit
+		}
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// InFlight returns the number of slots key currently holds
+func (l *ConcurrencyLimiter) InFlight(key uint64) int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s := l.slots[key]; s != nil {
+		return s.inFlight
+	}
+	return 0
+}
+
+func (l *ConcurrencyLimiter) release(key uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := l.slots[key]
+	if s == nil {
+		return
+	}
+	s.inFlight--
+	close(s.released)
+	if s.inFlight <= 0 {
+		delete(l.slots, key)
+		return
+	}
+	s.released = make(chan struct{})
+}
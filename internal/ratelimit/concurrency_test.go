@@ -0,0 +1,95 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyReject(t *testing.T) {
+	l := NewConcurrencyLimiter()
+	ctx := context.Background()
+
+	r1, err := l.Acquire(ctx, 1, 2, false)
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+	r2, err := l.Acquire(ctx, 1, 2, false)
+	if err != nil {
+		t.Fatalf("second acquire: %v", err)
+	}
+	if _, err := l.Acquire(ctx, 1, 2, false); !errors.Is(err, ErrConcurrencyLimit) {
+		t.Fatalf("third acquire err = %v, want ErrConcurrencyLimit", err)
+	}
+	// 其他 key 不受影响
+	if r, err := l.Acquire(ctx, 2, 2, false); err != nil {
+		t.Fatalf("other key rejected: %v", err)
+	} else {
+		r()
+	}
+
+	r1()
+	r1() // 重复释放不应多让出一个名额
+	if _, err := l.Acquire(ctx, 1, 2, false); err != nil {
+		t.Fatalf("acquire after release: %v", err)
+	}
+	if _, err := l.Acquire(ctx, 1, 2, false); !errors.Is(err, ErrConcurrencyLimit) {
+		t.Fatalf("acquire over cap after double release err = %v, want ErrConcurrencyLimit", err)
+	}
+	r2()
+	if n := l.InFlight(1); n != 1 {
+		t.Errorf("in flight = %d, want 1", n)
+	}
+}
+
+func TestConcurrencyQueue(t *testing.T) {
+	l := NewConcurrencyLimiter()
+	release, err := l.Acquire(context.Background(), 1, 1, true)
+	if err != nil {
+		t.Fatalf("first acquire: %v", err)
+	}
+
+	acquired := make(chan func())
+	go func() {
+		r, err := l.Acquire(context.Background(), 1, 1, true)
+		if err != nil {
+			t.Errorf("queued acquire: %v", err)
+		}
+		acquired <- r
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("queued request admitted while slot is taken")
+	case <-time.After(20 * time.Millisecond):
+	}
+	release()
+	select {
+	case r := <-acquired:
+		r()
+	case <-time.After(time.Second):
+		t.Fatal("queued request not admitted after release")
+	}
+
+	// 排队受 ctx 约束
+	hold, _ := l.Acquire(context.Background(), 1, 1, true)
+	defer hold()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx, 1, 1, true); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("queued acquire err = %v, want deadline exceeded", err)
+	}
+}
+
+func TestConcurrencyUnlimited(t *testing.T) {
+	l := NewConcurrencyLimiter()
+	for i := 0; i < 10; i++ {
+		if _, err := l.Acquire(context.Background(), 1, 0, false); err != nil {
+			t.Fatalf("unlimited acquire %d: %v", i, err)
+		}
+	}
+	if n := l.InFlight(1); n != 0 {
+		t.Errorf("in flight = %d, want 0 for unlimited", n)
+	}
+}
@@ -0,0 +1,185 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// Window is the length of the sliding window used for RPM/TPM caps
+const Window = time.Minute
+
+// minWait 被拒绝时建议的最短等待时间，避免排队时空转
+const minWait = 10 * time.Millisecond
+
+// Limiter enforces per-provider RPM/TPM caps over a sliding window.
+// Windows live in memory only. Caps are passed in on every call (taken from
+// the provider snapshot the router matched), so a provider config change
+// takes effect on the next request without any reload step.
+type Limiter struct {
+	mu      sync.Mutex
+	windows map[uint64]*window
+	now     func() time.Time
+}
+
+type tokenEvent struct {
+	at     time.Time
+	tokens uint64
+}
+
+// window 单个 Provider 最近一分钟的请求时间与 Token 用量
+type window struct {
+	requests []time.Time
+	tokens   []tokenEvent
+	tokenSum uint64
+}
+
+// NewLimiter creates an empty limiter
+func NewLimiter() *Limiter {
+	return &Limiter{
+		windows: make(map[uint64]*window),
+		now:     time.Now,
+	}
+}
+
+// Default global limiter
+var defaultLimiter = NewLimiter()
+
+// Default returns the default global limiter
+func Default() *Limiter {
+	return defaultLimiter
+}
+
+// Reserve counts one request against the provider's window if both caps allow
+// it. When rejected it returns false and how long until the window frees up.
+// Token usage is only known after a request finishes (see RecordTokens), so the
+// TPM cap admits requests while the recorded total is below the cap.
+func (l *Limiter) Reserve(providerID uint64, limit *domain.ProviderRateLimit) (bool, time.Duration) {
+	if !limit.Enabled() {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	w := l.windows[providerID]
+	if w == nil {
+		w = &window{}
+		l.windows[providerID] = w
+	}
+	w.prune(now)
+
+	var wait time.Duration
+	if limit.RPM > 0 && uint64(len(w.requests)) >= limit.RPM {
+		// 需要等最早的 len-RPM+1 个请求移出窗口
+		oldest := w.requests[uint64(len(w.requests))-limit.RPM]
+		wait = max(wait, oldest.Add(Window).Sub(now))
+	}
+	if limit.TPM > 0 && w.tokenSum >= limit.TPM {
+		// 需要等足够多的 Token 移出窗口，使总量低于上限
+		sum := w.tokenSum
+		for _, ev := range w.tokens {
+			sum -= ev.tokens
+			if sum < limit.TPM {
+				wait = max(wait, ev.at.Add(Window).Sub(now))
+				break
+			}
+		}
+	}
+	if wait > 0 {
+		return false, max(wait, minWait)
+	}
+
+	w.requests = append(w.requests, now)
+	return true, 0
+}
+
+// Wait blocks until Reserve admits the request or ctx is done
+func (l *Limiter) Wait(ctx context.Context, providerID uint64, limit *domain.ProviderRateLimit) error {
+	for {
+		ok, wait := l.Reserve(providerID, limit)
+		if ok {
+			return nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// RecordTokens adds a finished request's token usage to the provider's window
+func (l *Limiter) RecordTokens(providerID uint64, tokens uint64) {
+	if tokens == 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	w := l.windows[providerID]
+	if w == nil {
+		// 未配置上限的 Provider 不建窗口
+		return
+	}
+	now := l.now()
+	w.prune(now)
+	w.tokens = append(w.tokens, tokenEvent{at: now, tokens: tokens})
+	w.tokenSum += tokens
+}
+
+// Remaining returns the unused share (0-1) of the provider's tighter cap in
+// the current window. ok is false when no cap is configured.
+func (l *Limiter) Remaining(providerID uint64, limit *domain.ProviderRateLimit) (remaining float64, ok bool) {
+	if !limit.Enabled() {
+		return 0, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	remaining = 1
+	w := l.windows[providerID]
+	if w == nil {
+		return remaining, true
+	}
+	w.prune(l.now())
+	if limit.RPM > 0 {
+		remaining = min(remaining, 1-float64(len(w.requests))/float64(limit.RPM))
+	}
+	if limit.TPM > 0 {
+		remaining = min(remaining, 1-float64(w.tokenSum)/float64(limit.TPM))
+	}
+	return max(remaining, 0), true
+}
+
+// Remove drops the provider's window (e.g. when the provider is deleted)
+func (l *Limiter) Remove(providerID uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.windows, providerID)
+}
+
+// prune 移除窗口外的记录
+func (w *window) prune(now time.Time) {
+	cutoff := now.Add(-Window)
+
+	i := 0
+	for i < len(w.requests) && !w.requests[i].After(cutoff) {
+		i++
+	}
+	w.requests = w.requests[i:]
+
+	j := 0
+	for j < len(w.tokens) && !w.tokens[j].at.After(cutoff) {
+		w.tokenSum -= w.tokens[j].tokens
+		j++
+	}
+	w.tokens = w.tokens[j:]
+}
@@ -0,0 +1,119 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func newTestLimiter() (*Limiter, *time.Time) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter()
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestReserveRPM(t *testing.T) {
+	l, now := newTestLimiter()
+	limit := &domain.ProviderRateLimit{RPM: 2}
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := l.Reserve(1, limit); !ok {
+			t.Fatalf("request %d rejected", i)
+		}
+		*now = now.Add(10 * time.Second)
+	}
+
+	ok, wait := l.Reserve(1, limit)
+	if ok {
+		t.Fatal("third request admitted, want rejected")
+	}
+	// 第一个请求在 t=0，当前 t=20s，需再等 40s
+	if wait != 40*time.Second {
+		t.Errorf("wait = %v, want 40s", wait)
+	}
+
+	// 其他 Provider 不受影响
+	if ok, _ := l.Reserve(2, limit); !ok {
+		t.Error("other provider rejected")
+	}
+
+	*now = now.Add(40 * time.Second)
+	if ok, _ := l.Reserve(1, limit); !ok {
+		t.Error("request rejected after window slid")
+	}
+}
+
+func TestReserveTPM(t *testing.T) {
+	l, now := newTestLimiter()
+	limit := &domain.ProviderRateLimit{TPM: 1000}
+
+	if ok, _ := l.Reserve(1, limit); !ok {
+		t.Fatal("first request rejected")
+	}
+	l.RecordTokens(1, 600)
+	*now = now.Add(30 * time.Second)
+	l.RecordTokens(1, 600)
+
+	ok, wait := l.Reserve(1, limit)
+	if ok {
+		t.Fatal("request admitted over TPM, want rejected")
+	}
+	// 第一笔 600 移出窗口后总量低于上限
+	if wait != 30*time.Second {
+		t.Errorf("wait = %v, want 30s", wait)
+	}
+
+	*now = now.Add(30 * time.Second)
+	if ok, _ := l.Reserve(1, limit); !ok {
+		t.Error("request rejected after tokens left the window")
+	}
+}
+
+func TestReserveConfigChangeAppliesImmediately(t *testing.T) {
+	l, _ := newTestLimiter()
+
+	l.Reserve(1, &domain.ProviderRateLimit{RPM: 1})
+	if ok, _ := l.Reserve(1, &domain.ProviderRateLimit{RPM: 1}); ok {
+		t.Fatal("second request admitted with RPM 1")
+	}
+	if ok, _ := l.Reserve(1, &domain.ProviderRateLimit{RPM: 5}); !ok {
+		t.Error("request rejected after RPM was raised")
+	}
+	if ok, _ := l.Reserve(1, nil); !ok {
+		t.Error("request rejected with no limit configured")
+	}
+}
+
+func TestWaitBoundedByContext(t *testing.T) {
+	l := NewLimiter()
+	limit := &domain.ProviderRateLimit{RPM: 1}
+	l.Reserve(1, limit)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, 1, limit); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Wait = %v, want DeadlineExceeded", err)
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	l, _ := newTestLimiter()
+	if _, ok := l.Remaining(1, nil); ok {
+		t.Error("Remaining reported data without a cap")
+	}
+
+	limit := &domain.ProviderRateLimit{RPM: 4, TPM: 1000}
+	if got, ok := l.Remaining(1, limit); !ok || got != 1 {
+		t.Errorf("Remaining before any request = %v, %v; want 1", got, ok)
+	}
+	l.Reserve(1, limit)
+	l.RecordTokens(1, 600)
+	// RPM 剩 3/4，TPM 剩 0.4，取较紧的
+	if got, _ := l.Remaining(1, limit); got < 0.399 || got > 0.401 {
+		t.Errorf("Remaining = %v, want 0.4", got)
+	}
+}
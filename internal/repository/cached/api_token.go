@@ -1,6 +1,8 @@
 package cached
 
 import (
+	"context"
+	"log"
 	"sync"
 	"time"
 
@@ -9,18 +11,31 @@ import (
 )
 
 // APITokenRepository caches API token records around a backing repository.
+// Token usage (use_count / last_used_at) is accumulated in memory and written
+// to the backing repository in batches by FlushUsage.
 type APITokenRepository struct {
 	repo       repository.APITokenRepository
 	cache      map[uint64]*domain.APIToken // by ID
 	tokenCache map[string]*domain.APIToken // by token (plaintext)
 	mu         sync.RWMutex
+
+	usageMu      sync.Mutex
+	pendingUsage map[uint64]*tokenUsage // 尚未写入数据库的使用记录
+	flushMu      sync.Mutex             // 串行化 FlushUsage，保证 last_used_at 写入顺序
+}
+
+// tokenUsage 累积的 token 使用情况
+type tokenUsage struct {
+	count      uint64
+	lastUsedAt time.Time
 }
 
 func NewAPITokenRepository(repo repository.APITokenRepository) *APITokenRepository {
 	return &APITokenRepository{
-		repo:       repo,
-		cache:      make(map[uint64]*domain.APIToken),
-		tokenCache: make(map[string]*domain.APIToken),
+		repo:         repo,
+		cache:        make(map[uint64]*domain.APIToken),
+		tokenCache:   make(map[string]*domain.APIToken),
+		pendingUsage: make(map[uint64]*tokenUsage),
 	}
 }
 
@@ -114,25 +129,103 @@ func (r *APITokenRepository) GetByToken(token string) (*domain.APIToken, error)
 }
 
 func (r *APITokenRepository) List() ([]*domain.APIToken, error) {
+	// 先写入待处理的使用记录，保证 use_count / last_used_at 是最新的
+	if err := r.FlushUsage(); err != nil {
+		log.Printf("[APIToken] Failed to flush token usage before list: %v", err)
+	}
 	return r.repo.List()
 }
 
+// IncrementUseCount records one use of the token. The cached token is updated
+// immediately; the database is updated on the next FlushUsage.
 func (r *APITokenRepository) IncrementUseCount(id uint64) error {
-	if err := r.repo.IncrementUseCount(id); err != nil {
-		return err
+	now := time.Now()
+
+	r.usageMu.Lock()
+	u, ok := r.pendingUsage[id]
+	if !ok {
+		u = &tokenUsage{}
+		r.pendingUsage[id] = u
 	}
+	u.count++
+	if now.After(u.lastUsedAt) {
+		u.lastUsedAt = now
+	}
+	r.usageMu.Unlock()
 
-	// Update cache if exists
+	// 替换为副本而不是原地修改，避免与持有旧指针的读者产生数据竞争
 	r.mu.Lock()
 	if t, ok := r.cache[id]; ok {
-		t.UseCount++
-		now := time.Now()
-		t.LastUsedAt = &now
+		updated := *t
+		updated.UseCount++
+		updated.LastUsedAt = &now
+		r.cache[id] = &updated
+		if r.tokenCache[t.Token] == t {
+			r.tokenCache[t.Token] = &updated
+		}
 	}
 	r.mu.Unlock()
 	return nil
 }
 
+// AddUsage writes usage directly to the backing repository
+func (r *APITokenRepository) AddUsage(id uint64, count uint64, lastUsedAt time.Time) error {
+	return r.repo.AddUsage(id, count, lastUsedAt)
+}
+
+// FlushUsage writes all pending usage to the backing repository.
+// Entries that fail to write are kept and retried on the next flush.
+func (r *APITokenRepository) FlushUsage() error {
+	r.flushMu.Lock()
+	defer r.flushMu.Unlock()
+
+	r.usageMu.Lock()
+	pending := r.pendingUsage
+	r.pendingUsage = make(map[uint64]*tokenUsage)
+	r.usageMu.Unlock()
+
+	var firstErr error
+	for id, u := range pending {
+		if err := r.repo.AddUsage(id, u.count, u.lastUsedAt); err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			r.usageMu.Lock()
+			if cur, ok := r.pendingUsage[id]; ok {
+				cur.count += u.count
+				if u.lastUsedAt.After(cur.lastUsedAt) {
+					cur.lastUsedAt = u.lastUsedAt
+				}
+			} else {
+				r.pendingUsage[id] = u
+			}
+			r.usageMu.Unlock()
+		}
+	}
+	return firstErr
+}
+
+// RunUsageFlusher flushes pending usage every interval until ctx is done,
+// then flushes once more before returning.
+func (r *APITokenRepository) RunUsageFlusher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := r.FlushUsage(); err != nil {
+				log.Printf("[APIToken] Failed to flush token usage: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := r.FlushUsage(); err != nil {
+				log.Printf("[APIToken] Failed to flush token usage: %v", err)
+			}
+		}
+	}
+}
+
 // InvalidateCache clears all cached tokens
 func (r *APITokenRepository) InvalidateCache() {
 	r.mu.Lock()
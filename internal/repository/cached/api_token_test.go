@@ -0,0 +1,111 @@
+package cached
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// fakeAPITokenRepo records AddUsage calls in memory
+type fakeAPITokenRepo struct {
+	mu         sync.Mutex
+	useCount   map[uint64]uint64
+	lastUsedAt map[uint64]time.Time
+	calls      int
+	failNext   bool
+}
+
+func newFakeAPITokenRepo() *fakeAPITokenRepo {
+	return &fakeAPITokenRepo{useCount: map[uint64]uint64{}, lastUsedAt: map[uint64]time.Time{}}
+}
+
+func (f *fakeAPITokenRepo) Create(t *domain.APIToken) error { return nil }
+func (f *fakeAPITokenRepo) Update(t *domain.APIToken) error { return nil }
+func (f *fakeAPITokenRepo) Delete(id uint64) error          { return nil }
+func (f *fakeAPITokenRepo) GetByID(id uint64) (*domain.APIToken, error) {
+	return &domain.APIToken{ID: id, Token: "maxx_test"}, nil
+}
+func (f *fakeAPITokenRepo) GetByToken(token string) (*domain.APIToken, error) {
+	return &domain.APIToken{ID: 1, Token: token}, nil
+}
+func (f *fakeAPITokenRepo) List() ([]*domain.APIToken, error) { return nil, nil }
+func (f *fakeAPITokenRepo) IncrementUseCount(id uint64) error {
+	return f.AddUsage(id, 1, time.Now())
+}
+func (f *fakeAPITokenRepo) AddUsage(id uint64, count uint64, lastUsedAt time.Time) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.failNext {
+		f.failNext = false
+		return errors.New("db unavailable")
+	}
+	f.useCount[id] += count
+	if lastUsedAt.After(f.lastUsedAt[id]) {
+		f.lastUsedAt[id] = lastUsedAt
+	}
+	return nil
+}
+
+func TestAPITokenUsageBatchedUnderConcurrency(t *testing.T) {
+	backing := newFakeAPITokenRepo()
+	repo := NewAPITokenRepository(backing)
+	if _, err := repo.GetByToken("maxx_test"); err != nil {
+		t.Fatal(err)
+	}
+
+	const goroutines, perGoroutine = 20, 50
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				_ = repo.IncrementUseCount(1)
+				if j%10 == 0 {
+					_ = repo.FlushUsage()
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if err := repo.FlushUsage(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := backing.useCount[1]; got != goroutines*perGoroutine {
+		t.Errorf("flushed use_count = %d, want %d", got, goroutines*perGoroutine)
+	}
+	if backing.calls >= goroutines*perGoroutine {
+		t.Errorf("expected batched writes, got %d calls", backing.calls)
+	}
+
+	cachedToken, _ := repo.GetByToken("maxx_test")
+	if cachedToken.UseCount != goroutines*perGoroutine || cachedToken.LastUsedAt == nil {
+		t.Errorf("cached token not updated: useCount=%d lastUsedAt=%v", cachedToken.UseCount, cachedToken.LastUsedAt)
+	}
+}
+
+func TestAPITokenUsageRetriedAfterFlushError(t *testing.T) {
+	backing := newFakeAPITokenRepo()
+	repo := NewAPITokenRepository(backing)
+
+	_ = repo.IncrementUseCount(1)
+	_ = repo.IncrementUseCount(1)
+
+	backing.failNext = true
+	if err := repo.FlushUsage(); err == nil {
+		t.Fatal("expected flush error")
+	}
+	_ = repo.IncrementUseCount(1)
+	if err := repo.FlushUsage(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := backing.useCount[1]; got != 3 {
+		t.Errorf("use_count = %d, want 3", got)
+	}
+}
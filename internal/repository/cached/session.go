@@ -99,3 +99,19 @@ func (r *SessionRepository) GetOrCreate(sessionID string, clientType domain.Clie
 func (r *SessionRepository) List() ([]*domain.Session, error) {
 	return r.repo.List()
 }
+
+func (r *SessionRepository) Merge(primarySessionID string, otherSessionIDs []string, projectID uint64, retagRequests bool) (int64, int64, error) {
+	moved, retagged, err := r.repo.Merge(primarySessionID, otherSessionIDs, projectID, retagRequests)
+	if err != nil {
+		return 0, 0, err
+	}
+	r.mu.Lock()
+	for _, id := range otherSessionIDs {
+		delete(r.cache, id)
+	}
+	if s, ok := r.cache[primarySessionID]; ok {
+		s.ProjectID = projectID
+	}
+	r.mu.Unlock()
+	return moved, retagged, nil
+}
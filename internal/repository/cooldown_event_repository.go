@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// CooldownEventFilter 冷却历史查询条件
+type CooldownEventFilter struct {
+	ProviderID uint64
+	Since      time.Time // 零值表示不限制
+	Limit      int       // 0 表示不限制
+}
+
+// CooldownEventRepository stores the append-only cooldown event history
+type CooldownEventRepository interface {
+	// Create appends an event
+	Create(event *domain.CooldownEvent) error
+
+	// List returns a provider's events matching the filter, newest first
+	List(filter CooldownEventFilter) ([]*domain.CooldownEvent, error)
+
+	// DeleteOlderThan removes events created before the given time
+	DeleteOlderThan(before time.Time) (int64, error)
+}
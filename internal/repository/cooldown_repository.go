@@ -22,6 +22,9 @@ type CooldownRepository interface {
 	// DeleteAll removes all cooldowns for a provider
 	DeleteAll(providerID uint64) error
 
+	// ResetProvider removes all cooldowns and failure counts for a provider in one transaction
+	ResetProvider(providerID uint64) error
+
 	// DeleteExpired removes all expired cooldowns
 	DeleteExpired() error
 
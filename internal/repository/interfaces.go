@@ -57,12 +57,35 @@ type SessionRepository interface {
 	Update(session *domain.Session) error
 	GetBySessionID(sessionID string) (*domain.Session, error)
 	List() ([]*domain.Session, error)
+	// Merge moves the requests of otherSessionIDs to primarySessionID, sets the
+	// primary session's project to projectID and deletes the other sessions, in
+	// one transaction. When retagRequests is true, every request of the merged
+	// session is set to projectID as well.
+	Merge(primarySessionID string, otherSessionIDs []string, projectID uint64, retagRequests bool) (movedRequests, retaggedRequests int64, err error)
 }
 
 // ProxyRequestFilter 请求列表过滤条件
 type ProxyRequestFilter struct {
 	ProviderID *uint64 // Provider ID，nil 表示不过滤
 	Status     *string // 状态，nil 表示不过滤
+	ClientIP   *string // 客户端 IP，nil 表示不过滤
+	Experiment *string // A/B 模型实验名称，nil 表示不过滤
+}
+
+// ProxyRequestDeleteFilter 批量删除请求的过滤条件（各条件之间为 AND）
+type ProxyRequestDeleteFilter struct {
+	Start      time.Time // created_at >= Start，零值表示不限制
+	End        time.Time // created_at < End，零值表示不限制
+	Status     *string   // 状态，nil 表示不过滤
+	ProjectID  *uint64   // 项目 ID，nil 表示不过滤
+	APITokenID *uint64   // API Token ID，nil 表示不过滤
+	ProviderID *uint64   // Provider ID，nil 表示不过滤
+}
+
+// IsEmpty 是否没有设置任何过滤条件
+func (f *ProxyRequestDeleteFilter) IsEmpty() bool {
+	return f.Start.IsZero() && f.End.IsZero() && f.Status == nil &&
+		f.ProjectID == nil && f.APITokenID == nil && f.ProviderID == nil
 }
 
 type ProxyRequestRepository interface {
@@ -89,10 +112,17 @@ type ProxyRequestRepository interface {
 	FixFailedRequestsWithoutEndTime() (int64, error)
 	// DeleteOlderThan 删除指定时间之前的请求记录
 	DeleteOlderThan(before time.Time) (int64, error)
+	// DeleteByFilter 在一个事务中删除最多 limit 条匹配的请求（不含进行中的请求）及其 attempts，
+	// hasMore 表示是否还有剩余的匹配记录
+	DeleteByFilter(filter ProxyRequestDeleteFilter, limit int) (requests, attempts int64, hasMore bool, err error)
 	// HasRecentRequests 检查指定时间之后是否有请求记录
 	HasRecentRequests(since time.Time) (bool, error)
+	// ListCostsSince 按 ID 游标（id > afterID）返回 since 之后创建的已结束请求的成本，最多 limit 条
+	ListCostsSince(since time.Time, afterID uint64, limit int) ([]*domain.RequestCostData, error)
 	// UpdateCost updates only the cost field of a request
 	UpdateCost(id uint64, cost uint64) error
+	// UpdateNotes 只更新请求的运维备注，请求不存在时返回 domain.ErrNotFound
+	UpdateNotes(id uint64, notes string) error
 	// AddCost adds a delta to the cost field of a request (can be negative)
 	AddCost(id uint64, delta int64) error
 	// BatchUpdateCosts updates costs for multiple requests in a single transaction
@@ -103,12 +133,25 @@ type ProxyRequestRepository interface {
 	RecalculateCostsFromAttemptsWithProgress(progress chan<- domain.Progress) (int64, error)
 	// ClearDetailOlderThan 清理指定时间之前请求的详情字段（request_info 和 response_info）
 	ClearDetailOlderThan(before time.Time) (int64, error)
+	// ClearDetailOlderThanWithProgress 分批清理详情字段，并通过 channel 报告进度
+	ClearDetailOlderThanWithProgress(before time.Time, progress chan<- domain.Progress) (int64, error)
+	// ListDetailOlderThan 按 ID 游标（id > afterID）返回 before 之前创建、仍带详情的请求，最多 limit 条（清理前归档用）
+	ListDetailOlderThan(before time.Time, afterID uint64, limit int) ([]*domain.ProxyRequest, error)
+	// StatsByClientIP 按客户端 IP 汇总 [start, end) 内创建的请求，按请求数降序，最多 limit 个 IP
+	StatsByClientIP(start, end time.Time, limit int) ([]*domain.ClientIPStats, error)
+	// SummaryByRequestModelSince 汇总 API Token 自 since 起创建的请求数与 Token 数，按请求模型分组
+	// （每个请求只计一次，不含重试；只填充 TotalRequests / TotalInputTokens / TotalOutputTokens）
+	SummaryByRequestModelSince(apiTokenID uint64, since time.Time) (map[string]*domain.UsageStatsSummary, error)
 }
 
 type ProxyUpstreamAttemptRepository interface {
 	Create(attempt *domain.ProxyUpstreamAttempt) error
 	Update(attempt *domain.ProxyUpstreamAttempt) error
+	// ListByProxyRequestID returns all attempts of a request ordered by start time then ID
 	ListByProxyRequestID(proxyRequestID uint64) ([]*domain.ProxyUpstreamAttempt, error)
+	// ListPageByProxyRequestID returns up to limit attempts of a request in the same
+	// order, starting after the attempt with ID after (0 for the first page)
+	ListPageByProxyRequestID(proxyRequestID uint64, limit int, after uint64) ([]*domain.ProxyUpstreamAttempt, error)
 	// ListAll returns all attempts (for cost recalculation)
 	ListAll() ([]*domain.ProxyUpstreamAttempt, error)
 	// CountAll returns total count of attempts
@@ -116,6 +159,8 @@ type ProxyUpstreamAttemptRepository interface {
 	// StreamForCostCalc iterates through all attempts for cost calculation
 	// Calls the callback with batches of minimal data, returns early if callback returns error
 	StreamForCostCalc(batchSize int, callback func(batch []*domain.AttemptCostData) error) error
+	// ListForCostCalcByRequestIDs returns the cost calculation fields of all attempts of the given requests
+	ListForCostCalcByRequestIDs(requestIDs []uint64) ([]*domain.AttemptCostData, error)
 	// UpdateCost updates only the cost field of an attempt
 	UpdateCost(id uint64, cost uint64) error
 	// BatchUpdateCosts updates costs for multiple attempts in a single transaction
@@ -126,6 +171,14 @@ type ProxyUpstreamAttemptRepository interface {
 	FixFailedAttemptsWithoutEndTime() (int64, error)
 	// ClearDetailOlderThan 清理指定时间之前 attempt 的详情字段（request_info 和 response_info）
 	ClearDetailOlderThan(before time.Time) (int64, error)
+	// ClearDetailOlderThanWithProgress 分批清理详情字段，并通过 channel 报告进度
+	ClearDetailOlderThanWithProgress(before time.Time, progress chan<- domain.Progress) (int64, error)
+	// ListDetailOlderThan 按 ID 游标（id > afterID）返回 before 之前创建、仍带详情的 attempt，最多 limit 条（清理前归档用）
+	ListDetailOlderThan(before time.Time, afterID uint64, limit int) ([]*domain.ProxyUpstreamAttempt, error)
+	// GetMultiplierUsage 按 Provider、客户端类型和倍率分组统计 attempt（start/end 为 nil 表示不限）
+	GetMultiplierUsage(start, end *time.Time) ([]*domain.MultiplierUsage, error)
+	// ListFailures 返回时间范围内 FAILED attempt 的错误字段，按开始时间倒序，最多 limit 条
+	ListFailures(start, end time.Time, limit int) ([]*domain.AttemptFailure, error)
 }
 
 type SystemSettingRepository interface {
@@ -178,6 +231,8 @@ type UsageStatsRepository interface {
 	GetSummaryByAPIToken(filter UsageStatsFilter) (map[uint64]*domain.UsageStatsSummary, error)
 	// GetSummaryByClientType 按 ClientType 维度获取汇总统计
 	GetSummaryByClientType(filter UsageStatsFilter) (map[string]*domain.UsageStatsSummary, error)
+	// GetSummaryByModel 按 Model 维度获取汇总统计
+	GetSummaryByModel(filter UsageStatsFilter) (map[string]*domain.UsageStatsSummary, error)
 	// DeleteOlderThan 删除指定粒度下指定时间之前的统计记录
 	DeleteOlderThan(granularity domain.Granularity, before time.Time) (int64, error)
 	// GetLatestTimeBucket 获取指定粒度的最新时间桶
@@ -192,6 +247,8 @@ type UsageStatsRepository interface {
 	ClearAndRecalculate() error
 	// ClearAndRecalculateWithProgress 清空统计数据并重新计算，通过 channel 报告进度
 	ClearAndRecalculateWithProgress(progress chan<- domain.Progress) error
+	// CompactTimeBuckets 合并与当前时区不对齐的 day/month 统计桶，返回修正的记录数
+	CompactTimeBuckets() (int, error)
 }
 
 // UsageStatsFilter 统计查询过滤条件
@@ -215,6 +272,8 @@ type APITokenRepository interface {
 	GetByToken(token string) (*domain.APIToken, error)
 	List() ([]*domain.APIToken, error)
 	IncrementUseCount(id uint64) error
+	// AddUsage 批量累加使用次数，lastUsedAt 仅在比已有值更新时写入
+	AddUsage(id uint64, count uint64, lastUsedAt time.Time) error
 }
 
 type ModelMappingRepository interface {
@@ -241,6 +300,8 @@ type ResponseModelRepository interface {
 	List() ([]*domain.ResponseModel, error)
 	// ListNames 获取所有 response model 名称
 	ListNames() ([]string, error)
+	// Rebuild 按 attempt 中的 response_model 重建整张表，返回重建前后的模型数
+	Rebuild() (before, after int64, err error)
 }
 
 type ModelPriceRepository interface {
@@ -250,6 +311,8 @@ type ModelPriceRepository interface {
 	BatchCreate(prices []*domain.ModelPrice) error
 	// GetByID 获取指定ID的价格记录
 	GetByID(id uint64) (*domain.ModelPrice, error)
+	// GetHistoricalByID 获取指定ID的价格记录（包括已软删除的历史记录，用于按计费时价格核对成本）
+	GetHistoricalByID(id uint64) (*domain.ModelPrice, error)
 	// GetCurrentByModelID 获取模型的当前价格（最新记录），支持前缀匹配
 	GetCurrentByModelID(modelID string) (*domain.ModelPrice, error)
 	// ListCurrentPrices 获取所有模型的当前价格（用于初始化 Calculator）
@@ -34,12 +34,19 @@ func (r *APITokenRepository) Update(t *domain.APIToken) error {
 	return r.db.gorm.Model(&APIToken{}).
 		Where("id = ?", t.ID).
 		Updates(map[string]any{
-			"updated_at":  toTimestamp(t.UpdatedAt),
-			"name":        t.Name,
-			"description": LongText(t.Description),
-			"project_id":  t.ProjectID,
-			"is_enabled":  boolToInt(t.IsEnabled),
-			"expires_at":  toTimestampPtr(t.ExpiresAt),
+			"updated_at":              toTimestamp(t.UpdatedAt),
+			"name":                    t.Name,
+			"description":             LongText(t.Description),
+			"project_id":              t.ProjectID,
+			"is_enabled":              boolToInt(t.IsEnabled),
+			"expires_at":              toTimestampPtr(t.ExpiresAt),
+			"model_fallbacks":         LongText(toJSON(t.ModelFallbacks)),
+			"non_billable":            boolToInt(t.NonBillable),
+			"allow_billable_override": boolToInt(t.AllowBillableOverride),
+			"force_non_stream":        boolToInt(t.ForceNonStream),
+			"allow_stream_override":   boolToInt(t.AllowStreamOverride),
+			"max_concurrent":          t.MaxConcurrent,
+			"model_quotas":            LongText(toJSON(t.ModelQuotas)),
 		}).Error
 }
 
@@ -89,13 +96,18 @@ func (r *APITokenRepository) List() ([]*domain.APIToken, error) {
 }
 
 func (r *APITokenRepository) IncrementUseCount(id uint64) error {
-	now := time.Now().UnixMilli()
+	return r.AddUsage(id, 1, time.Now())
+}
+
+// AddUsage 累加使用次数并更新最后使用时间（不会把 last_used_at 往回改）
+func (r *APITokenRepository) AddUsage(id uint64, count uint64, lastUsedAt time.Time) error {
+	ts := lastUsedAt.UnixMilli()
 	return r.db.gorm.Model(&APIToken{}).
 		Where("id = ?", id).
 		Updates(map[string]any{
-			"use_count":    gorm.Expr("use_count + 1"),
-			"last_used_at": now,
-			"updated_at":   now,
+			"use_count":    gorm.Expr("use_count + ?", count),
+			"last_used_at": gorm.Expr("CASE WHEN last_used_at > ? THEN last_used_at ELSE ? END", ts, ts),
+			"updated_at":   time.Now().UnixMilli(),
 		}).Error
 }
 
@@ -109,33 +121,47 @@ func (r *APITokenRepository) toModel(t *domain.APIToken) *APIToken {
 			},
 			DeletedAt: toTimestampPtr(t.DeletedAt),
 		},
-		Token:       t.Token,
-		TokenPrefix: t.TokenPrefix,
-		Name:        t.Name,
-		Description: LongText(t.Description),
-		ProjectID:   t.ProjectID,
-		IsEnabled:   boolToInt(t.IsEnabled),
-		ExpiresAt:   toTimestampPtr(t.ExpiresAt),
-		LastUsedAt:  toTimestampPtr(t.LastUsedAt),
-		UseCount:    t.UseCount,
+		Token:                 t.Token,
+		TokenPrefix:           t.TokenPrefix,
+		Name:                  t.Name,
+		Description:           LongText(t.Description),
+		ProjectID:             t.ProjectID,
+		IsEnabled:             boolToInt(t.IsEnabled),
+		ExpiresAt:             toTimestampPtr(t.ExpiresAt),
+		LastUsedAt:            toTimestampPtr(t.LastUsedAt),
+		UseCount:              t.UseCount,
+		ModelFallbacks:        LongText(toJSON(t.ModelFallbacks)),
+		NonBillable:           boolToInt(t.NonBillable),
+		AllowBillableOverride: boolToInt(t.AllowBillableOverride),
+		ForceNonStream:        boolToInt(t.ForceNonStream),
+		AllowStreamOverride:   boolToInt(t.AllowStreamOverride),
+		MaxConcurrent:         t.MaxConcurrent,
+		ModelQuotas:           LongText(toJSON(t.ModelQuotas)),
 	}
 }
 
 func (r *APITokenRepository) toDomain(m *APIToken) *domain.APIToken {
 	return &domain.APIToken{
-		ID:          m.ID,
-		CreatedAt:   fromTimestamp(m.CreatedAt),
-		UpdatedAt:   fromTimestamp(m.UpdatedAt),
-		DeletedAt:   fromTimestampPtr(m.DeletedAt),
-		Token:       m.Token,
-		TokenPrefix: m.TokenPrefix,
-		Name:        m.Name,
-		Description: string(m.Description),
-		ProjectID:   m.ProjectID,
-		IsEnabled:   m.IsEnabled == 1,
-		ExpiresAt:   fromTimestampPtr(m.ExpiresAt),
-		LastUsedAt:  fromTimestampPtr(m.LastUsedAt),
-		UseCount:    m.UseCount,
+		ID:                    m.ID,
+		CreatedAt:             fromTimestamp(m.CreatedAt),
+		UpdatedAt:             fromTimestamp(m.UpdatedAt),
+		DeletedAt:             fromTimestampPtr(m.DeletedAt),
+		Token:                 m.Token,
+		TokenPrefix:           m.TokenPrefix,
+		Name:                  m.Name,
+		Description:           string(m.Description),
+		ProjectID:             m.ProjectID,
+		IsEnabled:             m.IsEnabled == 1,
+		ExpiresAt:             fromTimestampPtr(m.ExpiresAt),
+		LastUsedAt:            fromTimestampPtr(m.LastUsedAt),
+		UseCount:              m.UseCount,
+		ModelFallbacks:        fromJSON[[]domain.ModelFallback](string(m.ModelFallbacks)),
+		NonBillable:           m.NonBillable == 1,
+		AllowBillableOverride: m.AllowBillableOverride == 1,
+		ForceNonStream:        m.ForceNonStream == 1,
+		AllowStreamOverride:   m.AllowStreamOverride == 1,
+		MaxConcurrent:         m.MaxConcurrent,
+		ModelQuotas:           fromJSON[[]domain.TokenModelQuota](string(m.ModelQuotas)),
 	}
 }
 
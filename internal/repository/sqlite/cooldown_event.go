@@ -0,0 +1,78 @@
+package sqlite
+
+import (
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+type CooldownEventRepository struct {
+	db *DB
+}
+
+func NewCooldownEventRepository(db *DB) repository.CooldownEventRepository {
+	return &CooldownEventRepository{db: db}
+}
+
+func (r *CooldownEventRepository) Create(event *domain.CooldownEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	model := &CooldownEvent{
+		CreatedAt:    toTimestamp(event.CreatedAt),
+		ProviderID:   event.ProviderID,
+		ClientType:   event.ClientType,
+		Type:         string(event.Type),
+		Reason:       string(event.Reason),
+		UntilTime:    toTimestampPtr(event.UntilTime),
+		DurationMs:   event.DurationMs,
+		FailureCount: event.FailureCount,
+		ExitCause:    event.ExitCause,
+	}
+	if err := r.db.gorm.Create(model).Error; err != nil {
+		return err
+	}
+	event.ID = model.ID
+	return nil
+}
+
+func (r *CooldownEventRepository) List(filter repository.CooldownEventFilter) ([]*domain.CooldownEvent, error) {
+	query := r.db.gorm.Where("provider_id = ?", filter.ProviderID)
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", toTimestamp(filter.Since))
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+	var models []CooldownEvent
+	if err := query.Order("created_at DESC, id DESC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	events := make([]*domain.CooldownEvent, len(models))
+	for i := range models {
+		events[i] = r.toDomain(&models[i])
+	}
+	return events, nil
+}
+
+// DeleteOlderThan 删除指定时间之前的冷却事件
+func (r *CooldownEventRepository) DeleteOlderThan(before time.Time) (int64, error) {
+	result := r.db.gorm.Where("created_at < ?", toTimestamp(before)).Delete(&CooldownEvent{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *CooldownEventRepository) toDomain(m *CooldownEvent) *domain.CooldownEvent {
+	return &domain.CooldownEvent{
+		ID:           m.ID,
+		CreatedAt:    fromTimestamp(m.CreatedAt),
+		ProviderID:   m.ProviderID,
+		ClientType:   m.ClientType,
+		Type:         domain.CooldownEventType(m.Type),
+		Reason:       domain.CooldownReason(m.Reason),
+		UntilTime:    fromTimestampPtr(m.UntilTime),
+		DurationMs:   m.DurationMs,
+		FailureCount: m.FailureCount,
+		ExitCause:    m.ExitCause,
+	}
+}
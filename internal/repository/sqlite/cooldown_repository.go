@@ -87,6 +87,15 @@ func (r *CooldownRepository) DeleteAll(providerID uint64) error {
 	return r.db.gorm.Where("provider_id = ?", providerID).Delete(&Cooldown{}).Error
 }
 
+func (r *CooldownRepository) ResetProvider(providerID uint64) error {
+	return r.db.gorm.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("provider_id = ?", providerID).Delete(&Cooldown{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("provider_id = ?", providerID).Delete(&FailureCount{}).Error
+	})
+}
+
 func (r *CooldownRepository) DeleteExpired() error {
 	now := time.Now().UnixMilli()
 	return r.db.gorm.Where("until_time <= ?", now).Delete(&Cooldown{}).Error
@@ -15,8 +15,9 @@ import (
 )
 
 type DB struct {
-	gorm      *gorm.DB
-	dialector string // "sqlite", "mysql", or "postgres"
+	gorm        *gorm.DB
+	dialector   string // "sqlite", "mysql", or "postgres"
+	writeHealth *WriteHealth
 }
 
 // GormDB returns the underlying GORM DB instance
@@ -100,6 +101,11 @@ func NewDBWithDSN(dsn string) (*DB, error) {
 		return nil, err
 	}
 
+	d.writeHealth = newWriteHealth(gormDB)
+	if err := d.writeHealth.registerCallbacks(); err != nil {
+		return nil, fmt.Errorf("failed to register write health callbacks: %w", err)
+	}
+
 	log.Printf("[DB] Database connection established successfully (%s)", dialectorName)
 	return d, nil
 }
@@ -67,6 +67,15 @@ func (r *ModelPriceRepository) GetByID(id uint64) (*domain.ModelPrice, error) {
 	return r.toDomain(&m), nil
 }
 
+// GetHistoricalByID 获取指定ID的价格记录，包括已软删除的历史记录
+func (r *ModelPriceRepository) GetHistoricalByID(id uint64) (*domain.ModelPrice, error) {
+	var m ModelPrice
+	if err := r.db.gorm.First(&m, id).Error; err != nil {
+		return nil, err
+	}
+	return r.toDomain(&m), nil
+}
+
 // GetCurrentByModelID 获取模型的当前价格（最新记录），支持前缀匹配
 func (r *ModelPriceRepository) GetCurrentByModelID(modelID string) (*domain.ModelPrice, error) {
 	// 1. 精确匹配
@@ -188,6 +197,7 @@ func (r *ModelPriceRepository) ResetToDefaults() ([]*domain.ModelPrice, error) {
 			CacheReadPriceMicro:    p.CacheReadPriceMicro,
 			Cache5mWritePriceMicro: p.Cache5mWritePriceMicro,
 			Cache1hWritePriceMicro: p.Cache1hWritePriceMicro,
+			ReasoningPriceMicro:    p.ReasoningPriceMicro,
 			Has1MContext:           p.Has1MContext,
 			Context1MThreshold:     p.GetContext1MThreshold(),
 			InputPremiumNum:        p.GetInputPremiumNum(),
@@ -221,6 +231,7 @@ func (r *ModelPriceRepository) toDomain(m *ModelPrice) *domain.ModelPrice {
 		CacheReadPriceMicro:    m.CacheReadPriceMicro,
 		Cache5mWritePriceMicro: m.Cache5mWritePriceMicro,
 		Cache1hWritePriceMicro: m.Cache1hWritePriceMicro,
+		ReasoningPriceMicro:    m.ReasoningPriceMicro,
 		Has1MContext:           m.Has1MContext != 0,
 		Context1MThreshold:     m.Context1MThreshold,
 		InputPremiumNum:        m.InputPremiumNum,
@@ -244,6 +255,7 @@ func (r *ModelPriceRepository) fromDomain(p *domain.ModelPrice) *ModelPrice {
 		CacheReadPriceMicro:    p.CacheReadPriceMicro,
 		Cache5mWritePriceMicro: p.Cache5mWritePriceMicro,
 		Cache1hWritePriceMicro: p.Cache1hWritePriceMicro,
+		ReasoningPriceMicro:    p.ReasoningPriceMicro,
 		Has1MContext:           has1MContext,
 		Context1MThreshold:     p.Context1MThreshold,
 		InputPremiumNum:        p.InputPremiumNum,
@@ -67,6 +67,8 @@ type Provider struct {
 	Config               LongText
 	SupportedClientTypes LongText
 	SupportModels        LongText
+	DisabledClientTypes  LongText
+	Draining             int // 0 = 正常，1 = 排空中
 }
 
 func (Provider) TableName() string { return "providers" }
@@ -77,6 +79,11 @@ type Project struct {
 	Name                string `gorm:"size:255"`
 	Slug                string `gorm:"size:128"`
 	EnabledCustomRoutes LongText
+	Timezone            string `gorm:"size:64"`
+	ModelFallbacks      LongText
+	NonBillable         int
+	MaxRoutesAttempted  int
+	PreviousSlugs       LongText
 }
 
 func (Project) TableName() string { return "projects" }
@@ -102,6 +109,10 @@ type Route struct {
 	ProviderID    uint64
 	Position      int
 	RetryConfigID uint64
+	ForceDetail   int    `gorm:"default:0"`
+	Multiplier    uint64 `gorm:"default:0"` // 成本倍率覆盖，0 表示使用 Provider 倍率
+
+	MaxStreamDurationMs int `gorm:"default:0"`
 }
 
 func (Route) TableName() string { return "routes" }
@@ -139,8 +150,15 @@ type APIToken struct {
 	ProjectID   uint64
 	IsEnabled   int `gorm:"default:1"`
 	ExpiresAt   int64
-	LastUsedAt  int64
-	UseCount    uint64
+	LastUsedAt     int64
+	UseCount       uint64
+	ModelFallbacks LongText
+	NonBillable           int
+	AllowBillableOverride int
+	ForceNonStream        int
+	AllowStreamOverride   int
+	MaxConcurrent         int
+	ModelQuotas           LongText
 }
 
 func (APIToken) TableName() string { return "api_tokens" }
@@ -217,6 +235,7 @@ type ProxyRequest struct {
 	CacheWriteCount             uint64
 	Cache5mWriteCount           uint64 `gorm:"column:cache_5m_write_count"`
 	Cache1hWriteCount           uint64 `gorm:"column:cache_1h_write_count"`
+	ReasoningTokenCount         uint64
 	ModelPriceID                uint64 // 使用的模型价格记录ID
 	Multiplier                  uint64 // 倍率（10000=1倍）
 	Cost                        uint64
@@ -226,6 +245,14 @@ type ProxyRequest struct {
 	StatusCode                  int
 	ProjectID                   uint64
 	APITokenID                  uint64
+	ClientIP                    string `gorm:"size:64;index"`
+	NonBillable                 int    // 0 = 计费（默认），1 = 不计费
+	ComparisonTag               string `gorm:"size:64;index"`
+	Experiment                  string `gorm:"size:128;index"`
+	ExperimentVariant           string `gorm:"size:64"`
+	NonStreamOverride           int    // 1 = 客户端请求流式，被强制为非流式返回
+	RoutingTrace                LongText
+	Notes                       LongText // 运维备注，不随详情清理
 }
 
 func (ProxyRequest) TableName() string { return "proxy_requests" }
@@ -233,29 +260,38 @@ func (ProxyRequest) TableName() string { return "proxy_requests" }
 // ProxyUpstreamAttempt model
 type ProxyUpstreamAttempt struct {
 	BaseModel
-	Status            string `gorm:"size:64"`
-	ProxyRequestID    uint64 `gorm:"index"`
-	RequestInfo       LongText
-	ResponseInfo      LongText
-	RouteID           uint64
-	ProviderID        uint64
-	InputTokenCount   uint64
-	OutputTokenCount  uint64
-	CacheReadCount    uint64
-	CacheWriteCount   uint64
-	Cache5mWriteCount uint64 `gorm:"column:cache_5m_write_count"`
-	Cache1hWriteCount uint64 `gorm:"column:cache_1h_write_count"`
-	ModelPriceID      uint64 // 使用的模型价格记录ID
-	Multiplier        uint64 // 倍率（10000=1倍）
-	Cost              uint64
-	IsStream          int
-	StartTime         int64
-	EndTime           int64
-	DurationMs        int64
-	TTFTMs            int64
-	RequestModel      string `gorm:"size:128"`
-	MappedModel       string `gorm:"size:128"`
-	ResponseModel     string `gorm:"size:128"`
+	Status                  string `gorm:"size:64"`
+	ProxyRequestID          uint64 `gorm:"index"`
+	RequestInfo             LongText
+	ResponseInfo            LongText
+	RouteID                 uint64
+	ProviderID              uint64
+	InputTokenCount         uint64
+	OutputTokenCount        uint64
+	CacheReadCount          uint64
+	CacheWriteCount         uint64
+	Cache5mWriteCount       uint64 `gorm:"column:cache_5m_write_count"`
+	Cache1hWriteCount       uint64 `gorm:"column:cache_1h_write_count"`
+	ReasoningTokenCount     uint64
+	ModelPriceID            uint64 // 使用的模型价格记录ID
+	Multiplier              uint64 // 倍率（10000=1倍）
+	Cost                    uint64
+	IsStream                int
+	StartTime               int64
+	EndTime                 int64
+	DurationMs              int64
+	TTFTMs                  int64
+	RequestModel            string `gorm:"size:128"`
+	MappedModel             string `gorm:"size:128"`
+	ResponseModel           string `gorm:"size:128"`
+	MaxTokensClampedFrom    uint64
+	MinifiedBytesSaved      uint64
+	StrippedParams          string `gorm:"size:255"` // 逗号分隔
+	PriceOverrideProviderID uint64
+	OutputTPS               float64
+	Error                   LongText
+	StatusCode              int
+	StreamTruncated         int `gorm:"default:0"`
 }
 
 func (ProxyUpstreamAttempt) TableName() string { return "proxy_upstream_attempts" }
@@ -293,6 +329,22 @@ type FailureCount struct {
 
 func (FailureCount) TableName() string { return "failure_counts" }
 
+// CooldownEvent model
+type CooldownEvent struct {
+	ID           uint64 `gorm:"primaryKey;autoIncrement"`
+	CreatedAt    int64  `gorm:"index;index:idx_cooldown_events_provider_created,priority:2"`
+	ProviderID   uint64 `gorm:"index:idx_cooldown_events_provider_created,priority:1"`
+	ClientType   string `gorm:"size:255"`
+	Type         string `gorm:"size:16"`
+	Reason       string `gorm:"size:64"`
+	UntilTime    int64
+	DurationMs   int64
+	FailureCount int
+	ExitCause    string `gorm:"size:32"`
+}
+
+func (CooldownEvent) TableName() string { return "cooldown_events" }
+
 // UsageStats model
 type UsageStats struct {
 	ID                 uint64 `gorm:"primaryKey;autoIncrement"`
@@ -310,10 +362,13 @@ type UsageStats struct {
 	FailedRequests     uint64
 	TotalDurationMs    uint64
 	TotalTTFTMs        uint64
+	TPSOutputTokens    uint64
+	TPSDurationMs      uint64
 	InputTokens        uint64
 	OutputTokens       uint64
 	CacheRead          uint64
 	CacheWrite         uint64
+	ReasoningTokens    uint64
 	Cost               uint64
 }
 
@@ -350,6 +405,7 @@ type ModelPrice struct {
 	CacheReadPriceMicro    uint64
 	Cache5mWritePriceMicro uint64 `gorm:"column:cache_5m_write_price_micro"`
 	Cache1hWritePriceMicro uint64 `gorm:"column:cache_1h_write_price_micro"`
+	ReasoningPriceMicro    uint64
 	Has1MContext           int
 	Context1MThreshold     uint64 `gorm:"column:context_1m_threshold"`
 	InputPremiumNum        uint64
@@ -360,6 +416,14 @@ type ModelPrice struct {
 
 func (ModelPrice) TableName() string { return "model_prices" }
 
+// WriteProbe 单行表，数据库写入降级后用于探测写入是否恢复
+type WriteProbe struct {
+	ID        uint64 `gorm:"primaryKey"`
+	CheckedAt int64
+}
+
+func (WriteProbe) TableName() string { return "write_probes" }
+
 // ==================== All Models for AutoMigrate ====================
 
 // AllModels returns all GORM models for auto-migration
@@ -380,9 +444,11 @@ func AllModels() []any {
 		&SystemSetting{},
 		&Cooldown{},
 		&FailureCount{},
+		&CooldownEvent{},
 		&UsageStats{},
 		&ResponseModel{},
 		&ModelPrice{},
 		&SchemaMigration{},
+		&WriteProbe{},
 	}
 }
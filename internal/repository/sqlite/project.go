@@ -125,6 +125,11 @@ func (r *ProjectRepository) toModel(p *domain.Project) *Project {
 		Name:                p.Name,
 		Slug:                p.Slug,
 		EnabledCustomRoutes: LongText(toJSON(p.EnabledCustomRoutes)),
+		Timezone:            p.Timezone,
+		ModelFallbacks:      LongText(toJSON(p.ModelFallbacks)),
+		NonBillable:         boolToInt(p.NonBillable),
+		MaxRoutesAttempted:  p.MaxRoutesAttempted,
+		PreviousSlugs:       LongText(toJSON(p.PreviousSlugs)),
 	}
 }
 
@@ -137,6 +142,11 @@ func (r *ProjectRepository) toDomain(m *Project) *domain.Project {
 		Name:                m.Name,
 		Slug:                m.Slug,
 		EnabledCustomRoutes: fromJSON[[]domain.ClientType](string(m.EnabledCustomRoutes)),
+		Timezone:            m.Timezone,
+		ModelFallbacks:      fromJSON[[]domain.ModelFallback](string(m.ModelFallbacks)),
+		NonBillable:         m.NonBillable == 1,
+		MaxRoutesAttempted:  m.MaxRoutesAttempted,
+		PreviousSlugs:       fromJSON[[]domain.PreviousSlug](string(m.PreviousSlugs)),
 	}
 }
 
@@ -85,6 +85,8 @@ func (r *ProviderRepository) toModel(p *domain.Provider) *Provider {
 		Config:               LongText(toJSON(p.Config)),
 		SupportedClientTypes: LongText(toJSON(p.SupportedClientTypes)),
 		SupportModels:        LongText(toJSON(p.SupportModels)),
+		DisabledClientTypes:  LongText(toJSON(p.DisabledClientTypes)),
+		Draining:             boolToInt(p.Draining),
 	}
 }
 
@@ -100,5 +102,7 @@ func (r *ProviderRepository) toDomain(m *Provider) *domain.Provider {
 		Config:               fromJSON[*domain.ProviderConfig](string(m.Config)),
 		SupportedClientTypes: fromJSON[[]domain.ClientType](string(m.SupportedClientTypes)),
 		SupportModels:        fromJSON[[]string](string(m.SupportModels)),
+		DisabledClientTypes:  fromJSON[[]domain.ClientType](string(m.DisabledClientTypes)),
+		Draining:             m.Draining == 1,
 	}
 }
@@ -52,7 +52,8 @@ func (r *ProxyRequestRepository) Create(p *domain.ProxyRequest) error {
 func (r *ProxyRequestRepository) Update(p *domain.ProxyRequest) error {
 	p.UpdatedAt = time.Now()
 	model := r.toModel(p)
-	return r.db.gorm.Save(model).Error
+	// 备注只由 UpdateNotes 修改，避免代理过程中持有的旧副本覆盖
+	return r.db.gorm.Omit("notes").Save(model).Error
 }
 
 func (r *ProxyRequestRepository) GetByID(id uint64) (*domain.ProxyRequest, error) {
@@ -82,7 +83,7 @@ func (r *ProxyRequestRepository) List(limit, offset int) ([]*domain.ProxyRequest
 func (r *ProxyRequestRepository) ListCursor(limit int, before, after uint64, filter *repository.ProxyRequestFilter) ([]*domain.ProxyRequest, error) {
 	// 使用 Select 排除大字段
 	query := r.db.gorm.Model(&ProxyRequest{}).
-		Select("id, created_at, updated_at, instance_id, request_id, session_id, client_type, request_model, response_model, start_time, end_time, duration_ms, ttft_ms, is_stream, status, status_code, error, proxy_upstream_attempt_count, final_proxy_upstream_attempt_id, route_id, provider_id, project_id, input_token_count, output_token_count, cache_read_count, cache_write_count, cache_5m_write_count, cache_1h_write_count, cost, api_token_id")
+		Select("id, created_at, updated_at, instance_id, request_id, session_id, client_type, request_model, response_model, start_time, end_time, duration_ms, ttft_ms, is_stream, status, status_code, error, proxy_upstream_attempt_count, final_proxy_upstream_attempt_id, route_id, provider_id, project_id, input_token_count, output_token_count, cache_read_count, cache_write_count, cache_5m_write_count, cache_1h_write_count, reasoning_token_count, multiplier, cost, api_token_id, client_ip, non_billable, comparison_tag, experiment, experiment_variant, non_stream_override, notes")
 
 	if after > 0 {
 		query = query.Where("id > ?", after)
@@ -98,6 +99,12 @@ func (r *ProxyRequestRepository) ListCursor(limit int, before, after uint64, fil
 		if filter.Status != nil {
 			query = query.Where("status = ?", *filter.Status)
 		}
+		if filter.ClientIP != nil {
+			query = query.Where("client_ip = ?", *filter.ClientIP)
+		}
+		if filter.Experiment != nil {
+			query = query.Where("experiment = ?", *filter.Experiment)
+		}
 	}
 
 	var models []ProxyRequest
@@ -113,7 +120,7 @@ func (r *ProxyRequestRepository) ListCursor(limit int, before, after uint64, fil
 func (r *ProxyRequestRepository) ListActive() ([]*domain.ProxyRequest, error) {
 	var models []ProxyRequest
 	if err := r.db.gorm.Model(&ProxyRequest{}).
-		Select("id, created_at, updated_at, instance_id, request_id, session_id, client_type, request_model, response_model, start_time, end_time, duration_ms, is_stream, status, status_code, error, proxy_upstream_attempt_count, final_proxy_upstream_attempt_id, route_id, provider_id, project_id, input_token_count, output_token_count, cache_read_count, cache_write_count, cache_5m_write_count, cache_1h_write_count, cost, api_token_id").
+		Select("id, created_at, updated_at, instance_id, request_id, session_id, client_type, request_model, response_model, start_time, end_time, duration_ms, is_stream, status, status_code, error, proxy_upstream_attempt_count, final_proxy_upstream_attempt_id, route_id, provider_id, project_id, input_token_count, output_token_count, cache_read_count, cache_write_count, cache_5m_write_count, cache_1h_write_count, reasoning_token_count, multiplier, cost, api_token_id, client_ip, non_billable, comparison_tag, experiment, experiment_variant, non_stream_override").
 		Where("status IN ?", []string{"PENDING", "IN_PROGRESS"}).
 		Order("id DESC").
 		Find(&models).Error; err != nil {
@@ -129,7 +136,7 @@ func (r *ProxyRequestRepository) Count() (int64, error) {
 // CountWithFilter 带过滤条件的计数
 func (r *ProxyRequestRepository) CountWithFilter(filter *repository.ProxyRequestFilter) (int64, error) {
 	// 如果没有过滤条件，使用缓存的总数
-	if filter == nil || (filter.ProviderID == nil && filter.Status == nil) {
+	if filter == nil || (filter.ProviderID == nil && filter.Status == nil && filter.ClientIP == nil) {
 		return atomic.LoadInt64(&r.count), nil
 	}
 
@@ -142,6 +149,12 @@ func (r *ProxyRequestRepository) CountWithFilter(filter *repository.ProxyRequest
 	if filter.Status != nil {
 		query = query.Where("status = ?", *filter.Status)
 	}
+	if filter.ClientIP != nil {
+		query = query.Where("client_ip = ?", *filter.ClientIP)
+	}
+	if filter.Experiment != nil {
+		query = query.Where("experiment = ?", *filter.Experiment)
+	}
 	if err := query.Count(&count).Error; err != nil {
 		return 0, err
 	}
@@ -255,6 +268,68 @@ func (r *ProxyRequestRepository) DeleteOlderThan(before time.Time) (int64, error
 	return affected, nil
 }
 
+// DeleteByFilter 在一个事务中删除最多 limit 条匹配的请求及其 attempts
+// 进行中（PENDING/IN_PROGRESS）的请求不会被删除
+func (r *ProxyRequestRepository) DeleteByFilter(filter repository.ProxyRequestDeleteFilter, limit int) (int64, int64, bool, error) {
+	query := r.db.gorm.Model(&ProxyRequest{}).Where("status NOT IN ?", []string{"PENDING", "IN_PROGRESS"})
+	if !filter.Start.IsZero() {
+		query = query.Where("created_at >= ?", toTimestamp(filter.Start))
+	}
+	if !filter.End.IsZero() {
+		query = query.Where("created_at < ?", toTimestamp(filter.End))
+	}
+	if filter.Status != nil {
+		query = query.Where("status = ?", *filter.Status)
+	}
+	if filter.ProjectID != nil {
+		query = query.Where("project_id = ?", *filter.ProjectID)
+	}
+	if filter.APITokenID != nil {
+		query = query.Where("api_token_id = ?", *filter.APITokenID)
+	}
+	if filter.ProviderID != nil {
+		query = query.Where("provider_id = ?", *filter.ProviderID)
+	}
+
+	// 多取一条用于判断是否还有剩余
+	var requestIDs []uint64
+	if err := query.Order("id").Limit(limit+1).Pluck("id", &requestIDs).Error; err != nil {
+		return 0, 0, false, err
+	}
+	hasMore := len(requestIDs) > limit
+	if hasMore {
+		requestIDs = requestIDs[:limit]
+	}
+	if len(requestIDs) == 0 {
+		return 0, 0, false, nil
+	}
+
+	var deletedRequests, deletedAttempts int64
+	err := r.db.gorm.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("proxy_request_id IN ?", requestIDs).Delete(&ProxyUpstreamAttempt{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deletedAttempts = result.RowsAffected
+
+		result = tx.Where("id IN ?", requestIDs).Delete(&ProxyRequest{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deletedRequests = result.RowsAffected
+		return nil
+	})
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	// 更新计数缓存
+	if deletedRequests > 0 {
+		atomic.AddInt64(&r.count, -deletedRequests)
+	}
+	return deletedRequests, deletedAttempts, hasMore, nil
+}
+
 // HasRecentRequests 检查指定时间之后是否有请求记录
 func (r *ProxyRequestRepository) HasRecentRequests(since time.Time) (bool, error) {
 	sinceTs := toTimestamp(since)
@@ -265,11 +340,119 @@ func (r *ProxyRequestRepository) HasRecentRequests(since time.Time) (bool, error
 	return count > 0, nil
 }
 
+// StatsByClientIP 按客户端 IP 汇总 [start, end) 内创建的请求（未记录 IP 的请求不计入），
+// 按请求数降序，最多 limit 个 IP
+func (r *ProxyRequestRepository) StatsByClientIP(start, end time.Time, limit int) ([]*domain.ClientIPStats, error) {
+	var rows []struct {
+		ClientIP     string
+		Requests     uint64
+		Failed       uint64
+		InputTokens  uint64
+		OutputTokens uint64
+		Cost         uint64
+		LastSeen     int64
+	}
+	err := r.db.gorm.Model(&ProxyRequest{}).
+		Select(`client_ip,
+			COUNT(*) AS requests,
+			SUM(CASE WHEN status = 'FAILED' THEN 1 ELSE 0 END) AS failed,
+			SUM(input_token_count) AS input_tokens,
+			SUM(output_token_count) AS output_tokens,
+			SUM(CASE WHEN non_billable = 1 THEN 0 ELSE cost END) AS cost,
+			MAX(created_at) AS last_seen`).
+		Where("created_at >= ? AND created_at < ? AND client_ip <> ''", toTimestamp(start), toTimestamp(end)).
+		Group("client_ip").
+		Order("requests DESC, client_ip").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*domain.ClientIPStats, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, &domain.ClientIPStats{
+			ClientIP:     row.ClientIP,
+			Requests:     row.Requests,
+			Failed:       row.Failed,
+			InputTokens:  row.InputTokens,
+			OutputTokens: row.OutputTokens,
+			Cost:         row.Cost,
+			LastSeen:     fromTimestamp(row.LastSeen),
+		})
+	}
+	return result, nil
+}
+
+// SummaryByRequestModelSince 汇总 API Token 自 since 起创建的请求数与 Token 数，按请求模型分组
+func (r *ProxyRequestRepository) SummaryByRequestModelSince(apiTokenID uint64, since time.Time) (map[string]*domain.UsageStatsSummary, error) {
+	var rows []struct {
+		RequestModel string
+		Requests     uint64
+		InputTokens  uint64
+		OutputTokens uint64
+	}
+	err := r.db.gorm.Model(&ProxyRequest{}).
+		Select(`request_model,
+			COUNT(*) AS requests,
+			SUM(input_token_count) AS input_tokens,
+			SUM(output_token_count) AS output_tokens`).
+		Where("api_token_id = ? AND created_at >= ?", apiTokenID, toTimestamp(since)).
+		Group("request_model").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]*domain.UsageStatsSummary, len(rows))
+	for _, row := range rows {
+		result[row.RequestModel] = &domain.UsageStatsSummary{
+			TotalRequests:     row.Requests,
+			TotalInputTokens:  row.InputTokens,
+			TotalOutputTokens: row.OutputTokens,
+		}
+	}
+	return result, nil
+}
+
+// ListCostsSince 按 ID 游标返回 created_at >= since 的已结束请求的成本（不含进行中的请求）
+func (r *ProxyRequestRepository) ListCostsSince(since time.Time, afterID uint64, limit int) ([]*domain.RequestCostData, error) {
+	var results []struct {
+		ID   uint64 `gorm:"column:id"`
+		Cost uint64 `gorm:"column:cost"`
+	}
+	if err := r.db.gorm.Table("proxy_requests").
+		Select("id, cost").
+		Where("created_at >= ? AND id > ?", toTimestamp(since), afterID).
+		Where("status NOT IN ?", []string{"PENDING", "IN_PROGRESS"}).
+		Order("id").
+		Limit(limit).
+		Find(&results).Error; err != nil {
+		return nil, err
+	}
+	costs := make([]*domain.RequestCostData, len(results))
+	for i, res := range results {
+		costs[i] = &domain.RequestCostData{ID: res.ID, Cost: res.Cost}
+	}
+	return costs, nil
+}
+
 // UpdateCost updates only the cost field of a request
 func (r *ProxyRequestRepository) UpdateCost(id uint64, cost uint64) error {
 	return r.db.gorm.Model(&ProxyRequest{}).Where("id = ?", id).Update("cost", cost).Error
 }
 
+// UpdateNotes updates only the operator notes of a request
+func (r *ProxyRequestRepository) UpdateNotes(id uint64, notes string) error {
+	result := r.db.gorm.Model(&ProxyRequest{}).Where("id = ?", id).
+		Updates(map[string]any{"notes": notes, "updated_at": time.Now().UnixMilli()})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
 // AddCost adds a delta to the cost field of a request (can be negative)
 func (r *ProxyRequestRepository) AddCost(id uint64, delta int64) error {
 	return r.db.gorm.Model(&ProxyRequest{}).Where("id = ?", id).
@@ -414,18 +597,86 @@ func (r *ProxyRequestRepository) RecalculateCostsFromAttemptsWithProgress(progre
 
 // ClearDetailOlderThan 清理指定时间之前请求的详情字段（request_info 和 response_info）
 func (r *ProxyRequestRepository) ClearDetailOlderThan(before time.Time) (int64, error) {
-	beforeTs := toTimestamp(before)
-	now := time.Now().UnixMilli()
+	return r.ClearDetailOlderThanWithProgress(before, nil)
+}
 
-	result := r.db.gorm.Model(&ProxyRequest{}).
-		Where("created_at < ? AND (request_info IS NOT NULL OR response_info IS NOT NULL)", beforeTs).
-		Updates(map[string]any{
-			"request_info":  nil,
-			"response_info": nil,
-			"updated_at":    now,
-		})
+// ClearDetailOlderThanWithProgress clears request details in batches with progress reporting via channel
+func (r *ProxyRequestRepository) ClearDetailOlderThanWithProgress(before time.Time, progress chan<- domain.Progress) (int64, error) {
+	return clearDetailInBatches(r.db.gorm, &ProxyRequest{}, before, "clearing_requests", "requests", progress)
+}
+
+// ListDetailOlderThan 按 ID 游标返回 before 之前创建、仍带详情的请求
+func (r *ProxyRequestRepository) ListDetailOlderThan(before time.Time, afterID uint64, limit int) ([]*domain.ProxyRequest, error) {
+	var models []ProxyRequest
+	if err := detailOlderThan(r.db.gorm, before, afterID).Limit(limit).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	return r.toDomainList(models), nil
+}
+
+// detailOlderThan 与 clearDetailInBatches 选择相同的行（before 之前创建、仍带详情），按 ID 游标分页
+func detailOlderThan(db *gorm.DB, before time.Time, afterID uint64) *gorm.DB {
+	return db.Where("created_at < ? AND (request_info IS NOT NULL OR response_info IS NOT NULL) AND id > ?", toTimestamp(before), afterID).
+		Order("id")
+}
+
+// clearDetailBatchSize 每批清理的行数，分批提交避免大表清理时长时间持有写锁
+const clearDetailBatchSize = 500
+
+// clearDetailInBatches 分批清空 model 对应表中早于 before 的 request_info / response_info
+func clearDetailInBatches(db *gorm.DB, model any, before time.Time, phase, noun string, progress chan<- domain.Progress) (int64, error) {
+	sendProgress := func(current, total int, message string) {
+		if progress == nil {
+			return
+		}
+		percentage := 0
+		if total > 0 {
+			percentage = current * 100 / total
+		}
+		progress <- domain.Progress{
+			Phase:      phase,
+			Current:    current,
+			Total:      total,
+			Percentage: percentage,
+			Message:    message,
+		}
+	}
+
+	// 1. 获取待清理的 IDs
+	var ids []uint64
+	err := db.Model(model).
+		Where("created_at < ? AND (request_info IS NOT NULL OR response_info IS NOT NULL)", toTimestamp(before)).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return 0, err
+	}
+
+	total := len(ids)
+	if total == 0 {
+		return 0, nil
+	}
+	sendProgress(0, total, fmt.Sprintf("Clearing details of %d %s...", total, noun))
+
+	// 2. 分批清理，每批单独提交
+	var cleared int64
+	for i := 0; i < total; i += clearDetailBatchSize {
+		end := min(i+clearDetailBatchSize, total)
+		result := db.Model(model).
+			Where("id IN ?", ids[i:end]).
+			Updates(map[string]any{
+				"request_info":  nil,
+				"response_info": nil,
+				"updated_at":    time.Now().UnixMilli(),
+			})
+		if result.Error != nil {
+			return cleared, result.Error
+		}
+		cleared += result.RowsAffected
+
+		sendProgress(end, total, fmt.Sprintf("Clearing %s details: %d/%d", noun, end, total))
+	}
 
-	return result.RowsAffected, result.Error
+	return cleared, nil
 }
 
 func (r *ProxyRequestRepository) toModel(p *domain.ProxyRequest) *ProxyRequest {
@@ -462,10 +713,19 @@ func (r *ProxyRequestRepository) toModel(p *domain.ProxyRequest) *ProxyRequest {
 		CacheWriteCount:            p.CacheWriteCount,
 		Cache5mWriteCount:          p.Cache5mWriteCount,
 		Cache1hWriteCount:          p.Cache1hWriteCount,
+		ReasoningTokenCount:        p.ReasoningTokenCount,
 		ModelPriceID:               p.ModelPriceID,
 		Multiplier:                 p.Multiplier,
 		Cost:                       p.Cost,
 		APITokenID:                 p.APITokenID,
+		ClientIP:                   p.ClientIP,
+		NonBillable:                boolToInt(!p.Billable),
+		ComparisonTag:              p.ComparisonTag,
+		Experiment:                 p.Experiment,
+		ExperimentVariant:          p.ExperimentVariant,
+		NonStreamOverride:          boolToInt(p.NonStreamOverride),
+		RoutingTrace:               LongText(toJSON(p.RoutingTrace)),
+		Notes:                      LongText(p.Notes),
 	}
 }
 
@@ -501,10 +761,19 @@ func (r *ProxyRequestRepository) toDomain(m *ProxyRequest) *domain.ProxyRequest
 		CacheWriteCount:             m.CacheWriteCount,
 		Cache5mWriteCount:           m.Cache5mWriteCount,
 		Cache1hWriteCount:           m.Cache1hWriteCount,
+		ReasoningTokenCount:         m.ReasoningTokenCount,
 		ModelPriceID:                m.ModelPriceID,
 		Multiplier:                  m.Multiplier,
 		Cost:                        m.Cost,
 		APITokenID:                  m.APITokenID,
+		ClientIP:                    m.ClientIP,
+		Billable:                    m.NonBillable == 0,
+		ComparisonTag:               m.ComparisonTag,
+		Experiment:                  m.Experiment,
+		ExperimentVariant:           m.ExperimentVariant,
+		NonStreamOverride:           m.NonStreamOverride == 1,
+		RoutingTrace:                fromJSON[*domain.RoutingTrace](string(m.RoutingTrace)),
+		Notes:                       string(m.Notes),
 	}
 }
 
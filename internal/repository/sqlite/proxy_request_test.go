@@ -0,0 +1,191 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+func TestProxyRequestDeleteByFilter(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	repo := NewProxyRequestRepository(db)
+	attemptRepo := NewProxyUpstreamAttemptRepository(db)
+
+	// project 1: 3 条已完成（每条 2 个 attempt）+ 1 条进行中；project 2: 1 条已完成
+	create := func(projectID uint64, status string) {
+		t.Helper()
+		req := &domain.ProxyRequest{ProjectID: projectID, Status: status}
+		if err := repo.Create(req); err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := attemptRepo.Create(&domain.ProxyUpstreamAttempt{ProxyRequestID: req.ID, Status: status}); err != nil {
+				t.Fatalf("create attempt: %v", err)
+			}
+		}
+	}
+	for i := 0; i < 3; i++ {
+		create(1, "COMPLETED")
+	}
+	create(1, "IN_PROGRESS")
+	create(2, "COMPLETED")
+
+	projectID := uint64(1)
+	filter := repository.ProxyRequestDeleteFilter{ProjectID: &projectID}
+
+	requests, attempts, hasMore, err := repo.DeleteByFilter(filter, 2)
+	if err != nil {
+		t.Fatalf("DeleteByFilter failed: %v", err)
+	}
+	if requests != 2 || attempts != 4 || !hasMore {
+		t.Errorf("first batch = (%d, %d, %v), want (2, 4, true)", requests, attempts, hasMore)
+	}
+
+	requests, attempts, hasMore, err = repo.DeleteByFilter(filter, 2)
+	if err != nil {
+		t.Fatalf("DeleteByFilter failed: %v", err)
+	}
+	if requests != 1 || attempts != 2 || hasMore {
+		t.Errorf("second batch = (%d, %d, %v), want (1, 2, false)", requests, attempts, hasMore)
+	}
+
+	// 进行中的请求和其他项目的请求保留
+	if count, _ := repo.Count(); count != 2 {
+		t.Errorf("remaining requests = %d, want 2", count)
+	}
+}
+
+func TestProxyRequestClearDetailInBatches(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	repo := NewProxyRequestRepository(db)
+
+	// 比一批多一条，应分两批清理
+	total := clearDetailBatchSize + 1
+	for i := 0; i < total; i++ {
+		if err := repo.Create(&domain.ProxyRequest{Status: "COMPLETED", RequestInfo: &domain.RequestInfo{Method: "POST"}}); err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+	}
+
+	progress := make(chan domain.Progress, 10)
+	cleared, err := repo.ClearDetailOlderThanWithProgress(time.Now().Add(time.Minute), progress)
+	close(progress)
+	if err != nil {
+		t.Fatalf("ClearDetailOlderThanWithProgress failed: %v", err)
+	}
+	if cleared != int64(total) {
+		t.Errorf("cleared = %d, want %d", cleared, total)
+	}
+
+	var reports []domain.Progress
+	for p := range progress {
+		reports = append(reports, p)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("got %d progress reports, want 3 (start + 2 batches)", len(reports))
+	}
+	if last := reports[2]; last.Current != total || last.Total != total || last.Percentage != 100 {
+		t.Errorf("last report = %+v, want %d/%d at 100%%", last, total, total)
+	}
+
+	// 再次清理无事可做
+	if cleared, err := repo.ClearDetailOlderThan(time.Now().Add(time.Minute)); err != nil || cleared != 0 {
+		t.Errorf("second clear = %d, %v; want 0, nil", cleared, err)
+	}
+}
+
+func TestProxyRequestNotes(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	repo := NewProxyRequestRepository(db)
+
+	req := &domain.ProxyRequest{Status: "IN_PROGRESS", RequestInfo: &domain.RequestInfo{Method: "POST"}}
+	if err := repo.Create(req); err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	if err := repo.UpdateNotes(req.ID, "reported by customer X"); err != nil {
+		t.Fatalf("UpdateNotes failed: %v", err)
+	}
+
+	// 代理过程中持有的旧副本更新请求时不覆盖备注
+	req.Status = "COMPLETED"
+	if err := repo.Update(req); err != nil {
+		t.Fatalf("update request: %v", err)
+	}
+	// 清理详情后备注保留
+	if _, err := repo.ClearDetailOlderThan(time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("ClearDetailOlderThan failed: %v", err)
+	}
+
+	got, err := repo.GetByID(req.ID)
+	if err != nil {
+		t.Fatalf("GetByID failed: %v", err)
+	}
+	if got.Notes != "reported by customer X" || got.Status != "COMPLETED" || got.RequestInfo != nil {
+		t.Errorf("got notes %q, status %q, requestInfo %+v", got.Notes, got.Status, got.RequestInfo)
+	}
+	list, err := repo.ListCursor(10, 0, 0, nil)
+	if err != nil || len(list) != 1 || list[0].Notes != "reported by customer X" {
+		t.Errorf("ListCursor = %+v, %v; want notes in list", list, err)
+	}
+
+	if err := repo.UpdateNotes(req.ID+100, "x"); err != domain.ErrNotFound {
+		t.Errorf("UpdateNotes on missing request = %v, want ErrNotFound", err)
+	}
+}
+
+func TestProxyRequestSummaryByRequestModelSince(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	repo := NewProxyRequestRepository(db)
+	attemptRepo := NewProxyUpstreamAttemptRepository(db)
+
+	create := func(tokenID uint64, model string, attempts int) {
+		t.Helper()
+		req := &domain.ProxyRequest{APITokenID: tokenID, RequestModel: model, ResponseModel: "mapped-" + model,
+			Status: "COMPLETED", InputTokenCount: 100, OutputTokenCount: 10}
+		if err := repo.Create(req); err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		for i := 0; i < attempts; i++ {
+			if err := attemptRepo.Create(&domain.ProxyUpstreamAttempt{ProxyRequestID: req.ID, Status: "COMPLETED"}); err != nil {
+				t.Fatalf("create attempt: %v", err)
+			}
+		}
+	}
+	// 重试产生的多个 attempt 只计一次请求，按请求模型（而非响应模型）分组
+	create(7, "claude-sonnet-4", 3)
+	create(7, "claude-sonnet-4", 1)
+	create(7, "gpt-5", 2)
+	create(8, "claude-sonnet-4", 1)
+
+	byModel, err := repo.SummaryByRequestModelSince(7, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("SummaryByRequestModelSince failed: %v", err)
+	}
+	claude, gpt := byModel["claude-sonnet-4"], byModel["gpt-5"]
+	if len(byModel) != 2 || claude == nil || gpt == nil {
+		t.Fatalf("byModel = %v, want claude-sonnet-4 and gpt-5", byModel)
+	}
+	if claude.TotalRequests != 2 || claude.TotalInputTokens != 200 || claude.TotalOutputTokens != 20 || gpt.TotalRequests != 1 {
+		t.Errorf("claude = %+v, gpt = %+v", claude, gpt)
+	}
+
+	byModel, err = repo.SummaryByRequestModelSince(7, time.Now().Add(time.Minute))
+	if err != nil || len(byModel) != 0 {
+		t.Errorf("byModel after since = %v, %v; want empty", byModel, err)
+	}
+}
@@ -1,6 +1,7 @@
 package sqlite
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -38,7 +39,29 @@ func (r *ProxyUpstreamAttemptRepository) Update(a *domain.ProxyUpstreamAttempt)
 
 func (r *ProxyUpstreamAttemptRepository) ListByProxyRequestID(proxyRequestID uint64) ([]*domain.ProxyUpstreamAttempt, error) {
 	var models []ProxyUpstreamAttempt
-	if err := r.db.gorm.Where("proxy_request_id = ?", proxyRequestID).Order("id").Find(&models).Error; err != nil {
+	if err := r.db.gorm.Where("proxy_request_id = ?", proxyRequestID).Order("start_time, id").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	return r.toDomainList(models), nil
+}
+
+// ListPageByProxyRequestID 按 (start_time, id) 游标分页，游标为上一页最后一条 attempt 的 ID
+func (r *ProxyUpstreamAttemptRepository) ListPageByProxyRequestID(proxyRequestID uint64, limit int, after uint64) ([]*domain.ProxyUpstreamAttempt, error) {
+	query := r.db.gorm.Where("proxy_request_id = ?", proxyRequestID)
+	if after > 0 {
+		var cursor ProxyUpstreamAttempt
+		if err := r.db.gorm.Select("id", "start_time").
+			Where("id = ? AND proxy_request_id = ?", after, proxyRequestID).
+			First(&cursor).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, domain.ErrNotFound
+			}
+			return nil, err
+		}
+		query = query.Where("start_time > ? OR (start_time = ? AND id > ?)", cursor.StartTime, cursor.StartTime, cursor.ID)
+	}
+	var models []ProxyUpstreamAttempt
+	if err := query.Order("start_time, id").Limit(limit).Find(&models).Error; err != nil {
 		return nil, err
 	}
 	return r.toDomainList(models), nil
@@ -60,29 +83,63 @@ func (r *ProxyUpstreamAttemptRepository) CountAll() (int64, error) {
 	return count, nil
 }
 
+// attemptCostRow 成本计算所需的 attempt 字段
+type attemptCostRow struct {
+	ID                      uint64 `gorm:"column:id"`
+	ProxyRequestID          uint64 `gorm:"column:proxy_request_id"`
+	ResponseModel           string `gorm:"column:response_model"`
+	MappedModel             string `gorm:"column:mapped_model"`
+	RequestModel            string `gorm:"column:request_model"`
+	InputTokenCount         uint64 `gorm:"column:input_token_count"`
+	OutputTokenCount        uint64 `gorm:"column:output_token_count"`
+	CacheReadCount          uint64 `gorm:"column:cache_read_count"`
+	CacheWriteCount         uint64 `gorm:"column:cache_write_count"`
+	Cache5mWriteCount       uint64 `gorm:"column:cache_5m_write_count"`
+	Cache1hWriteCount       uint64 `gorm:"column:cache_1h_write_count"`
+	ReasoningTokenCount     uint64 `gorm:"column:reasoning_token_count"`
+	Multiplier              uint64 `gorm:"column:multiplier"`
+	Cost                    uint64 `gorm:"column:cost"`
+	ModelPriceID            uint64 `gorm:"column:model_price_id"`
+	PriceOverrideProviderID uint64 `gorm:"column:price_override_provider_id"`
+}
+
+const attemptCostColumns = "id, proxy_request_id, response_model, mapped_model, request_model, input_token_count, output_token_count, cache_read_count, cache_write_count, cache_5m_write_count, cache_1h_write_count, reasoning_token_count, multiplier, cost, model_price_id, price_override_provider_id"
+
+func attemptCostRowsToDomain(rows []attemptCostRow) []*domain.AttemptCostData {
+	batch := make([]*domain.AttemptCostData, len(rows))
+	for i, r := range rows {
+		batch[i] = &domain.AttemptCostData{
+			ID:                      r.ID,
+			ProxyRequestID:          r.ProxyRequestID,
+			ResponseModel:           r.ResponseModel,
+			MappedModel:             r.MappedModel,
+			RequestModel:            r.RequestModel,
+			InputTokenCount:         r.InputTokenCount,
+			OutputTokenCount:        r.OutputTokenCount,
+			CacheReadCount:          r.CacheReadCount,
+			CacheWriteCount:         r.CacheWriteCount,
+			Cache5mWriteCount:       r.Cache5mWriteCount,
+			Cache1hWriteCount:       r.Cache1hWriteCount,
+			ReasoningTokenCount:     r.ReasoningTokenCount,
+			Multiplier:              r.Multiplier,
+			Cost:                    r.Cost,
+			ModelPriceID:            r.ModelPriceID,
+			PriceOverrideProviderID: r.PriceOverrideProviderID,
+		}
+	}
+	return batch
+}
+
 // StreamForCostCalc iterates through all attempts in batches for cost calculation
 // Only fetches fields needed for cost calculation, avoiding expensive JSON parsing
 func (r *ProxyUpstreamAttemptRepository) StreamForCostCalc(batchSize int, callback func(batch []*domain.AttemptCostData) error) error {
 	var lastID uint64 = 0
 
 	for {
-		var results []struct {
-			ID                uint64 `gorm:"column:id"`
-			ProxyRequestID    uint64 `gorm:"column:proxy_request_id"`
-			ResponseModel     string `gorm:"column:response_model"`
-			MappedModel       string `gorm:"column:mapped_model"`
-			RequestModel      string `gorm:"column:request_model"`
-			InputTokenCount   uint64 `gorm:"column:input_token_count"`
-			OutputTokenCount  uint64 `gorm:"column:output_token_count"`
-			CacheReadCount    uint64 `gorm:"column:cache_read_count"`
-			CacheWriteCount   uint64 `gorm:"column:cache_write_count"`
-			Cache5mWriteCount uint64 `gorm:"column:cache_5m_write_count"`
-			Cache1hWriteCount uint64 `gorm:"column:cache_1h_write_count"`
-			Cost              uint64 `gorm:"column:cost"`
-		}
+		var results []attemptCostRow
 
 		err := r.db.gorm.Table("proxy_upstream_attempts").
-			Select("id, proxy_request_id, response_model, mapped_model, request_model, input_token_count, output_token_count, cache_read_count, cache_write_count, cache_5m_write_count, cache_1h_write_count, cost").
+			Select(attemptCostColumns).
 			Where("id > ?", lastID).
 			Order("id").
 			Limit(batchSize).
@@ -96,26 +153,7 @@ func (r *ProxyUpstreamAttemptRepository) StreamForCostCalc(batchSize int, callba
 			break
 		}
 
-		// Convert to domain type
-		batch := make([]*domain.AttemptCostData, len(results))
-		for i, r := range results {
-			batch[i] = &domain.AttemptCostData{
-				ID:                r.ID,
-				ProxyRequestID:    r.ProxyRequestID,
-				ResponseModel:     r.ResponseModel,
-				MappedModel:       r.MappedModel,
-				RequestModel:      r.RequestModel,
-				InputTokenCount:   r.InputTokenCount,
-				OutputTokenCount:  r.OutputTokenCount,
-				CacheReadCount:    r.CacheReadCount,
-				CacheWriteCount:   r.CacheWriteCount,
-				Cache5mWriteCount: r.Cache5mWriteCount,
-				Cache1hWriteCount: r.Cache1hWriteCount,
-				Cost:              r.Cost,
-			}
-		}
-
-		if err := callback(batch); err != nil {
+		if err := callback(attemptCostRowsToDomain(results)); err != nil {
 			return err
 		}
 
@@ -129,6 +167,22 @@ func (r *ProxyUpstreamAttemptRepository) StreamForCostCalc(batchSize int, callba
 	return nil
 }
 
+// ListForCostCalcByRequestIDs returns the cost calculation fields of all attempts belonging to the given requests
+func (r *ProxyUpstreamAttemptRepository) ListForCostCalcByRequestIDs(requestIDs []uint64) ([]*domain.AttemptCostData, error) {
+	if len(requestIDs) == 0 {
+		return nil, nil
+	}
+	var results []attemptCostRow
+	if err := r.db.gorm.Table("proxy_upstream_attempts").
+		Select(attemptCostColumns).
+		Where("proxy_request_id IN ?", requestIDs).
+		Order("id").
+		Find(&results).Error; err != nil {
+		return nil, err
+	}
+	return attemptCostRowsToDomain(results), nil
+}
+
 func (r *ProxyUpstreamAttemptRepository) UpdateCost(id uint64, cost uint64) error {
 	return r.db.gorm.Model(&ProxyUpstreamAttempt{}).Where("id = ?", id).Update("cost", cost).Error
 }
@@ -238,18 +292,96 @@ func (r *ProxyUpstreamAttemptRepository) BatchUpdateCosts(updates map[uint64]uin
 
 // ClearDetailOlderThan 清理指定时间之前 attempt 的详情字段（request_info 和 response_info）
 func (r *ProxyUpstreamAttemptRepository) ClearDetailOlderThan(before time.Time) (int64, error) {
-	beforeTs := toTimestamp(before)
-	now := time.Now().UnixMilli()
+	return r.ClearDetailOlderThanWithProgress(before, nil)
+}
 
-	result := r.db.gorm.Model(&ProxyUpstreamAttempt{}).
-		Where("created_at < ? AND (request_info IS NOT NULL OR response_info IS NOT NULL)", beforeTs).
-		Updates(map[string]any{
-			"request_info":  nil,
-			"response_info": nil,
-			"updated_at":    now,
-		})
+// ListDetailOlderThan 按 ID 游标返回 before 之前创建、仍带详情的 attempt
+func (r *ProxyUpstreamAttemptRepository) ListDetailOlderThan(before time.Time, afterID uint64, limit int) ([]*domain.ProxyUpstreamAttempt, error) {
+	var models []ProxyUpstreamAttempt
+	if err := detailOlderThan(r.db.gorm, before, afterID).Limit(limit).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	return r.toDomainList(models), nil
+}
+
+// ClearDetailOlderThanWithProgress clears attempt details in batches with progress reporting via channel
+func (r *ProxyUpstreamAttemptRepository) ClearDetailOlderThanWithProgress(before time.Time, progress chan<- domain.Progress) (int64, error) {
+	return clearDetailInBatches(r.db.gorm, &ProxyUpstreamAttempt{}, before, "clearing_attempts", "attempts", progress)
+}
+
+// GetMultiplierUsage groups attempts by provider, client type and the multiplier
+// applied when they were billed. Only attempts still retained are counted, and
+// attempts of non-billable requests add no cost.
+func (r *ProxyUpstreamAttemptRepository) GetMultiplierUsage(start, end *time.Time) ([]*domain.MultiplierUsage, error) {
+	query := `
+		SELECT
+			a.provider_id,
+			COALESCE(r.client_type, ''),
+			COALESCE(a.multiplier, 0),
+			COUNT(*),
+			COALESCE(SUM(CASE WHEN COALESCE(r.non_billable, 0) = 1 THEN 0 ELSE COALESCE(a.cost, 0) END), 0),
+			MIN(a.start_time),
+			MAX(a.start_time)
+		FROM proxy_upstream_attempts a
+		LEFT JOIN proxy_requests r ON a.proxy_request_id = r.id
+		WHERE 1 = 1`
+	var args []interface{}
+	if start != nil {
+		query += " AND a.start_time >= ?"
+		args = append(args, toTimestamp(*start))
+	}
+	if end != nil {
+		query += " AND a.start_time <= ?"
+		args = append(args, toTimestamp(*end))
+	}
+	query += `
+		GROUP BY a.provider_id, r.client_type, a.multiplier
+		ORDER BY a.provider_id, r.client_type, MAX(a.start_time) DESC`
+
+	rows, err := r.db.gorm.Raw(query, args...).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var results []*domain.MultiplierUsage
+	for rows.Next() {
+		var u domain.MultiplierUsage
+		var clientType string
+		var firstSeen, lastSeen int64
+		if err := rows.Scan(&u.ProviderID, &clientType, &u.Multiplier, &u.Attempts, &u.Cost, &firstSeen, &lastSeen); err != nil {
+			return nil, err
+		}
+		u.ClientType = domain.ClientType(clientType)
+		u.FirstSeen = fromTimestamp(firstSeen)
+		u.LastSeen = fromTimestamp(lastSeen)
+		results = append(results, &u)
+	}
+	return results, rows.Err()
+}
 
-	return result.RowsAffected, result.Error
+func (r *ProxyUpstreamAttemptRepository) ListFailures(start, end time.Time, limit int) ([]*domain.AttemptFailure, error) {
+	var models []ProxyUpstreamAttempt
+	err := r.db.gorm.Select("id", "proxy_request_id", "provider_id", "start_time", "status_code", "error").
+		Where("status = ? AND start_time >= ? AND start_time <= ?", "FAILED", toTimestamp(start), toTimestamp(end)).
+		Order("start_time DESC, id DESC").
+		Limit(limit).
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+	failures := make([]*domain.AttemptFailure, len(models))
+	for i, m := range models {
+		failures[i] = &domain.AttemptFailure{
+			ID:             m.ID,
+			ProxyRequestID: m.ProxyRequestID,
+			ProviderID:     m.ProviderID,
+			StartTime:      fromTimestamp(m.StartTime),
+			StatusCode:     m.StatusCode,
+			Error:          string(m.Error),
+		}
+	}
+	return failures, nil
 }
 
 func (r *ProxyUpstreamAttemptRepository) toModel(a *domain.ProxyUpstreamAttempt) *ProxyUpstreamAttempt {
@@ -259,60 +391,78 @@ func (r *ProxyUpstreamAttemptRepository) toModel(a *domain.ProxyUpstreamAttempt)
 			CreatedAt: toTimestamp(a.CreatedAt),
 			UpdatedAt: toTimestamp(a.UpdatedAt),
 		},
-		StartTime:         toTimestamp(a.StartTime),
-		EndTime:           toTimestamp(a.EndTime),
-		DurationMs:        a.Duration.Milliseconds(),
-		TTFTMs:            a.TTFT.Milliseconds(),
-		Status:            a.Status,
-		ProxyRequestID:    a.ProxyRequestID,
-		IsStream:          boolToInt(a.IsStream),
-		RequestModel:      a.RequestModel,
-		MappedModel:       a.MappedModel,
-		ResponseModel:     a.ResponseModel,
-		RequestInfo:       LongText(toJSON(a.RequestInfo)),
-		ResponseInfo:      LongText(toJSON(a.ResponseInfo)),
-		RouteID:           a.RouteID,
-		ProviderID:        a.ProviderID,
-		InputTokenCount:   a.InputTokenCount,
-		OutputTokenCount:  a.OutputTokenCount,
-		CacheReadCount:    a.CacheReadCount,
-		CacheWriteCount:   a.CacheWriteCount,
-		Cache5mWriteCount: a.Cache5mWriteCount,
-		Cache1hWriteCount: a.Cache1hWriteCount,
-		ModelPriceID:      a.ModelPriceID,
-		Multiplier:        a.Multiplier,
-		Cost:              a.Cost,
+		StartTime:               toTimestamp(a.StartTime),
+		EndTime:                 toTimestamp(a.EndTime),
+		DurationMs:              a.Duration.Milliseconds(),
+		TTFTMs:                  a.TTFT.Milliseconds(),
+		Status:                  a.Status,
+		ProxyRequestID:          a.ProxyRequestID,
+		IsStream:                boolToInt(a.IsStream),
+		RequestModel:            a.RequestModel,
+		MappedModel:             a.MappedModel,
+		ResponseModel:           a.ResponseModel,
+		RequestInfo:             LongText(toJSON(a.RequestInfo)),
+		ResponseInfo:            LongText(toJSON(a.ResponseInfo)),
+		RouteID:                 a.RouteID,
+		ProviderID:              a.ProviderID,
+		InputTokenCount:         a.InputTokenCount,
+		OutputTokenCount:        a.OutputTokenCount,
+		CacheReadCount:          a.CacheReadCount,
+		CacheWriteCount:         a.CacheWriteCount,
+		Cache5mWriteCount:       a.Cache5mWriteCount,
+		Cache1hWriteCount:       a.Cache1hWriteCount,
+		ReasoningTokenCount:     a.ReasoningTokenCount,
+		ModelPriceID:            a.ModelPriceID,
+		Multiplier:              a.Multiplier,
+		Cost:                    a.Cost,
+		MaxTokensClampedFrom:    a.MaxTokensClampedFrom,
+		MinifiedBytesSaved:      a.MinifiedBytesSaved,
+		StrippedParams:          strings.Join(a.StrippedParams, ","),
+		PriceOverrideProviderID: a.PriceOverrideProviderID,
+		OutputTPS:               a.OutputTPS,
+		Error:                   LongText(a.Error),
+		StatusCode:              a.StatusCode,
+		StreamTruncated:         boolToInt(a.StreamTruncated),
 	}
 }
 
 func (r *ProxyUpstreamAttemptRepository) toDomain(m *ProxyUpstreamAttempt) *domain.ProxyUpstreamAttempt {
 	return &domain.ProxyUpstreamAttempt{
-		ID:                m.ID,
-		CreatedAt:         fromTimestamp(m.CreatedAt),
-		UpdatedAt:         fromTimestamp(m.UpdatedAt),
-		StartTime:         fromTimestamp(m.StartTime),
-		EndTime:           fromTimestamp(m.EndTime),
-		Duration:          time.Duration(m.DurationMs) * time.Millisecond,
-		TTFT:              time.Duration(m.TTFTMs) * time.Millisecond,
-		Status:            m.Status,
-		ProxyRequestID:    m.ProxyRequestID,
-		IsStream:          m.IsStream == 1,
-		RequestModel:      m.RequestModel,
-		MappedModel:       m.MappedModel,
-		ResponseModel:     m.ResponseModel,
-		RequestInfo:       fromJSON[*domain.RequestInfo](string(m.RequestInfo)),
-		ResponseInfo:      fromJSON[*domain.ResponseInfo](string(m.ResponseInfo)),
-		RouteID:           m.RouteID,
-		ProviderID:        m.ProviderID,
-		InputTokenCount:   m.InputTokenCount,
-		OutputTokenCount:  m.OutputTokenCount,
-		CacheReadCount:    m.CacheReadCount,
-		CacheWriteCount:   m.CacheWriteCount,
-		Cache5mWriteCount: m.Cache5mWriteCount,
-		Cache1hWriteCount: m.Cache1hWriteCount,
-		ModelPriceID:      m.ModelPriceID,
-		Multiplier:        m.Multiplier,
-		Cost:              m.Cost,
+		ID:                      m.ID,
+		CreatedAt:               fromTimestamp(m.CreatedAt),
+		UpdatedAt:               fromTimestamp(m.UpdatedAt),
+		StartTime:               fromTimestamp(m.StartTime),
+		EndTime:                 fromTimestamp(m.EndTime),
+		Duration:                time.Duration(m.DurationMs) * time.Millisecond,
+		TTFT:                    time.Duration(m.TTFTMs) * time.Millisecond,
+		Status:                  m.Status,
+		ProxyRequestID:          m.ProxyRequestID,
+		IsStream:                m.IsStream == 1,
+		RequestModel:            m.RequestModel,
+		MappedModel:             m.MappedModel,
+		ResponseModel:           m.ResponseModel,
+		RequestInfo:             fromJSON[*domain.RequestInfo](string(m.RequestInfo)),
+		ResponseInfo:            fromJSON[*domain.ResponseInfo](string(m.ResponseInfo)),
+		RouteID:                 m.RouteID,
+		ProviderID:              m.ProviderID,
+		InputTokenCount:         m.InputTokenCount,
+		OutputTokenCount:        m.OutputTokenCount,
+		CacheReadCount:          m.CacheReadCount,
+		CacheWriteCount:         m.CacheWriteCount,
+		Cache5mWriteCount:       m.Cache5mWriteCount,
+		Cache1hWriteCount:       m.Cache1hWriteCount,
+		ReasoningTokenCount:     m.ReasoningTokenCount,
+		ModelPriceID:            m.ModelPriceID,
+		Multiplier:              m.Multiplier,
+		Cost:                    m.Cost,
+		MaxTokensClampedFrom:    m.MaxTokensClampedFrom,
+		MinifiedBytesSaved:      m.MinifiedBytesSaved,
+		StrippedParams:          splitCommaList(m.StrippedParams),
+		PriceOverrideProviderID: m.PriceOverrideProviderID,
+		OutputTPS:               m.OutputTPS,
+		Error:                   string(m.Error),
+		StatusCode:              m.StatusCode,
+		StreamTruncated:         m.StreamTruncated == 1,
 	}
 }
 
@@ -323,3 +473,11 @@ func (r *ProxyUpstreamAttemptRepository) toDomainList(models []ProxyUpstreamAtte
 	}
 	return attempts
 }
+
+// splitCommaList 解析逗号分隔的列表，空字符串返回 nil
+func splitCommaList(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(s, ",")
+}
@@ -0,0 +1,181 @@
+package sqlite
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestGetMultiplierUsage(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	requestRepo := NewProxyRequestRepository(db)
+	attemptRepo := NewProxyUpstreamAttemptRepository(db)
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Provider 1 的 claude 倍率从 1.5 倍改为 2 倍，旧记录保留原倍率
+	for i, a := range []struct {
+		clientType  domain.ClientType
+		multiplier  uint64
+		cost        uint64
+		nonBillable bool
+	}{
+		{domain.ClientTypeClaude, 15000, 150, false},
+		{domain.ClientTypeClaude, 15000, 150, false},
+		{domain.ClientTypeClaude, 15000, 999, true}, // 不计费请求只计次数，不计成本
+		{domain.ClientTypeClaude, 20000, 200, false},
+		{domain.ClientTypeOpenAI, 10000, 100, false},
+	} {
+		req := &domain.ProxyRequest{ClientType: a.clientType, Status: "COMPLETED", Billable: !a.nonBillable}
+		if err := requestRepo.Create(req); err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		attempt := &domain.ProxyUpstreamAttempt{
+			ProxyRequestID: req.ID,
+			ProviderID:     1,
+			Status:         "COMPLETED",
+			StartTime:      base.Add(time.Duration(i) * time.Hour),
+			Multiplier:     a.multiplier,
+			Cost:           a.cost,
+		}
+		if err := attemptRepo.Create(attempt); err != nil {
+			t.Fatalf("create attempt: %v", err)
+		}
+	}
+
+	usage, err := attemptRepo.GetMultiplierUsage(nil, nil)
+	if err != nil {
+		t.Fatalf("GetMultiplierUsage failed: %v", err)
+	}
+	if len(usage) != 3 {
+		t.Fatalf("got %d groups, want 3", len(usage))
+	}
+	// claude 组按最近使用时间倒序
+	if u := usage[0]; u.ClientType != domain.ClientTypeClaude || u.Multiplier != 20000 || u.Attempts != 1 || u.Cost != 200 {
+		t.Errorf("usage[0] = %+v", u)
+	}
+	if u := usage[1]; u.Multiplier != 15000 || u.Attempts != 3 || u.Cost != 300 ||
+		!u.FirstSeen.Equal(base) || !u.LastSeen.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("usage[1] = %+v", u)
+	}
+
+	start := base.Add(3 * time.Hour)
+	usage, err = attemptRepo.GetMultiplierUsage(&start, nil)
+	if err != nil {
+		t.Fatalf("GetMultiplierUsage failed: %v", err)
+	}
+	if len(usage) != 2 {
+		t.Errorf("got %d groups since %v, want 2", len(usage), start)
+	}
+}
+
+func TestListPageByProxyRequestID(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	attemptRepo := NewProxyUpstreamAttemptRepository(db)
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	// 开始时间乱序且有重复，分页按 (start_time, id) 排序
+	offsets := []time.Duration{3, 1, 1, 2, 0}
+	for _, off := range offsets {
+		a := &domain.ProxyUpstreamAttempt{ProxyRequestID: 1, Status: "FAILED", StartTime: base.Add(off * time.Second)}
+		if err := attemptRepo.Create(a); err != nil {
+			t.Fatalf("create attempt: %v", err)
+		}
+	}
+	if err := attemptRepo.Create(&domain.ProxyUpstreamAttempt{ProxyRequestID: 2, StartTime: base}); err != nil {
+		t.Fatalf("create attempt: %v", err)
+	}
+
+	all, err := attemptRepo.ListByProxyRequestID(1)
+	if err != nil {
+		t.Fatalf("ListByProxyRequestID failed: %v", err)
+	}
+	if len(all) != len(offsets) {
+		t.Fatalf("got %d attempts, want %d", len(all), len(offsets))
+	}
+
+	var paged []*domain.ProxyUpstreamAttempt
+	var cursor uint64
+	for {
+		page, err := attemptRepo.ListPageByProxyRequestID(1, 2, cursor)
+		if err != nil {
+			t.Fatalf("ListPageByProxyRequestID failed: %v", err)
+		}
+		if len(page) == 0 {
+			break
+		}
+		paged = append(paged, page...)
+		cursor = page[len(page)-1].ID
+	}
+	if len(paged) != len(all) {
+		t.Fatalf("paged %d attempts, want %d", len(paged), len(all))
+	}
+	for i := range all {
+		if paged[i].ID != all[i].ID {
+			t.Errorf("page order differs at %d: %d vs %d", i, paged[i].ID, all[i].ID)
+		}
+		if i > 0 {
+			prev, cur := all[i-1], all[i]
+			if cur.StartTime.Before(prev.StartTime) || (cur.StartTime.Equal(prev.StartTime) && cur.ID < prev.ID) {
+				t.Errorf("attempts not ordered by start time then ID at %d", i)
+			}
+		}
+	}
+
+	// 游标必须属于同一请求
+	other, _ := attemptRepo.ListByProxyRequestID(2)
+	if _, err := attemptRepo.ListPageByProxyRequestID(1, 2, other[0].ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("foreign cursor err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestListFailures(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	attemptRepo := NewProxyUpstreamAttemptRepository(db)
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, status := range []string{"FAILED", "COMPLETED", "FAILED", "CANCELLED", "FAILED"} {
+		a := &domain.ProxyUpstreamAttempt{
+			ProxyRequestID: uint64(i + 1),
+			ProviderID:     1,
+			Status:         status,
+			StartTime:      base.Add(time.Duration(i) * time.Hour),
+			StatusCode:     500 + i,
+			Error:          "upstream error",
+		}
+		if err := attemptRepo.Create(a); err != nil {
+			t.Fatalf("create attempt: %v", err)
+		}
+	}
+
+	failures, err := attemptRepo.ListFailures(base, base.Add(3*time.Hour), 10)
+	if err != nil {
+		t.Fatalf("ListFailures failed: %v", err)
+	}
+	if len(failures) != 2 {
+		t.Fatalf("got %d failures, want 2", len(failures))
+	}
+	// 最近的在前
+	if f := failures[0]; f.ProxyRequestID != 3 || f.StatusCode != 502 || f.Error != "upstream error" || !f.StartTime.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("failures[0] = %+v", f)
+	}
+
+	failures, err = attemptRepo.ListFailures(base, base.Add(10*time.Hour), 1)
+	if err != nil {
+		t.Fatalf("ListFailures failed: %v", err)
+	}
+	if len(failures) != 1 || failures[0].ProxyRequestID != 5 {
+		t.Errorf("limited failures = %+v", failures)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/awsl-project/maxx/internal/domain"
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
@@ -85,6 +86,32 @@ func (r *ResponseModelRepository) ListNames() ([]string, error) {
 	return names, nil
 }
 
+// Rebuild 清空后按 proxy_upstream_attempts 中出现过的 response_model 重建整张表：
+// 首次/最近出现时间取 attempt 开始时间，使用次数为 attempt 数。结果只取决于当前 attempt，可重复执行。
+// 返回重建前后的模型数
+func (r *ResponseModelRepository) Rebuild() (before, after int64, err error) {
+	err = r.db.gorm.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&ResponseModel{}).Count(&before).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("1 = 1").Delete(&ResponseModel{}).Error; err != nil {
+			return err
+		}
+		result := tx.Exec(`
+			INSERT INTO response_models (created_at, name, last_seen_at, use_count)
+			SELECT MIN(start_time), response_model, MAX(start_time), COUNT(*)
+			FROM proxy_upstream_attempts
+			WHERE response_model <> ''
+			GROUP BY response_model`)
+		if result.Error != nil {
+			return result.Error
+		}
+		after = result.RowsAffected
+		return nil
+	})
+	return before, after, err
+}
+
 func (r *ResponseModelRepository) toDomain(m *ResponseModel) *domain.ResponseModel {
 	return &domain.ResponseModel{
 		ID:         m.ID,
@@ -0,0 +1,54 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestResponseModelRebuild(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	attemptRepo := NewProxyUpstreamAttemptRepository(db)
+	modelRepo := NewResponseModelRepository(db)
+
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, model := range []string{"claude-sonnet-4", "gpt-4o", "claude-sonnet-4", ""} {
+		a := &domain.ProxyUpstreamAttempt{ProxyRequestID: 1, ResponseModel: model, StartTime: base.Add(time.Duration(i) * time.Hour)}
+		if err := attemptRepo.Create(a); err != nil {
+			t.Fatalf("create attempt: %v", err)
+		}
+	}
+	// 已不存在于 attempt 中的模型会被移除
+	if err := modelRepo.BatchUpsert([]string{"deleted-model", "gpt-4o"}); err != nil {
+		t.Fatalf("BatchUpsert failed: %v", err)
+	}
+
+	for run := 0; run < 2; run++ {
+		before, after, err := modelRepo.Rebuild()
+		if err != nil {
+			t.Fatalf("Rebuild failed: %v", err)
+		}
+		if before != 2 || after != 2 {
+			t.Errorf("run %d: before=%d after=%d, want 2 -> 2", run, before, after)
+		}
+		models, err := modelRepo.List()
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		if len(models) != 2 {
+			t.Fatalf("run %d: got %d models, want 2", run, len(models))
+		}
+		m := models[0]
+		if m.Name != "claude-sonnet-4" || m.UseCount != 2 || !m.CreatedAt.Equal(base) || !m.LastSeenAt.Equal(base.Add(2*time.Hour)) {
+			t.Errorf("run %d: models[0] = %+v", run, m)
+		}
+		if models[1].Name != "gpt-4o" || models[1].UseCount != 1 {
+			t.Errorf("run %d: models[1] = %+v", run, models[1])
+		}
+	}
+}
@@ -110,6 +110,10 @@ func (r *RouteRepository) toModel(route *domain.Route) *Route {
 	if route.IsNative {
 		isNative = 1
 	}
+	forceDetail := 0
+	if route.ForceDetail {
+		forceDetail = 1
+	}
 	return &Route{
 		SoftDeleteModel: SoftDeleteModel{
 			BaseModel: BaseModel{
@@ -126,6 +130,10 @@ func (r *RouteRepository) toModel(route *domain.Route) *Route {
 		ProviderID:    route.ProviderID,
 		Position:      route.Position,
 		RetryConfigID: route.RetryConfigID,
+		ForceDetail:   forceDetail,
+		Multiplier:    route.Multiplier,
+
+		MaxStreamDurationMs: route.MaxStreamDurationMs,
 	}
 }
 
@@ -142,5 +150,9 @@ func (r *RouteRepository) toDomain(m *Route) *domain.Route {
 		ProviderID:    m.ProviderID,
 		Position:      m.Position,
 		RetryConfigID: m.RetryConfigID,
+		ForceDetail:   m.ForceDetail == 1,
+		Multiplier:    m.Multiplier,
+
+		MaxStreamDurationMs: m.MaxStreamDurationMs,
 	}
 }
@@ -69,6 +69,56 @@ func (r *SessionRepository) List() ([]*domain.Session, error) {
 	return sessions, nil
 }
 
+func (r *SessionRepository) Merge(primarySessionID string, otherSessionIDs []string, projectID uint64, retagRequests bool) (movedRequests, retaggedRequests int64, err error) {
+	err = r.db.gorm.Transaction(func(tx *gorm.DB) error {
+		now := time.Now().UnixMilli()
+
+		result := tx.Model(&Session{}).
+			Where("session_id = ? AND deleted_at = 0", primarySessionID).
+			Updates(map[string]any{
+				"project_id": projectID,
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return domain.ErrNotFound
+		}
+
+		result = tx.Model(&ProxyRequest{}).
+			Where("session_id IN ?", otherSessionIDs).
+			Updates(map[string]any{
+				"session_id": primarySessionID,
+				"updated_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		movedRequests = result.RowsAffected
+
+		if retagRequests {
+			result = tx.Model(&ProxyRequest{}).
+				Where("session_id = ? AND project_id <> ?", primarySessionID, projectID).
+				Updates(map[string]any{
+					"project_id": projectID,
+					"updated_at": now,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			retaggedRequests = result.RowsAffected
+		}
+
+		// 硬删除：session_id 有唯一索引，软删除会导致客户端再次使用该 ID 时无法重新创建会话
+		return tx.Where("session_id IN ?", otherSessionIDs).Delete(&Session{}).Error
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return movedRequests, retaggedRequests, nil
+}
+
 func (r *SessionRepository) toModel(s *domain.Session) *Session {
 	return &Session{
 		SoftDeleteModel: SoftDeleteModel{
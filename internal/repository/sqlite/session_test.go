@@ -0,0 +1,66 @@
+package sqlite
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestSessionMerge(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	sessionRepo := NewSessionRepository(db)
+	requestRepo := NewProxyRequestRepository(db)
+
+	for _, s := range []*domain.Session{
+		{SessionID: "primary", ProjectID: 1},
+		{SessionID: "other-a", ProjectID: 2},
+		{SessionID: "other-b"},
+	} {
+		if err := sessionRepo.Create(s); err != nil {
+			t.Fatalf("create session: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := requestRepo.Create(&domain.ProxyRequest{SessionID: s.SessionID, ProjectID: s.ProjectID, Status: "COMPLETED"}); err != nil {
+				t.Fatalf("create request: %v", err)
+			}
+		}
+	}
+
+	moved, retagged, err := sessionRepo.Merge("primary", []string{"other-a", "other-b"}, 1, true)
+	if err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	// other-a 的 2 条请求项目为 2，other-b 的 2 条为 0，均改为 1
+	if moved != 4 || retagged != 4 {
+		t.Errorf("Merge = (%d, %d), want (4, 4)", moved, retagged)
+	}
+
+	for _, id := range []string{"other-a", "other-b"} {
+		if _, err := sessionRepo.GetBySessionID(id); !errors.Is(err, domain.ErrNotFound) {
+			t.Errorf("session %s err = %v, want ErrNotFound", id, err)
+		}
+	}
+	// 被合并的会话 ID 可以重新创建
+	if err := sessionRepo.Create(&domain.Session{SessionID: "other-a"}); err != nil {
+		t.Errorf("recreate merged session: %v", err)
+	}
+
+	requests, err := requestRepo.List(100, 0)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	for _, r := range requests {
+		if r.SessionID != "primary" || r.ProjectID != 1 {
+			t.Errorf("request %d = (%s, %d), want (primary, 1)", r.ID, r.SessionID, r.ProjectID)
+		}
+	}
+
+	if _, _, err := sessionRepo.Merge("missing", []string{"primary"}, 0, false); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("Merge into missing session err = %v, want ErrNotFound", err)
+	}
+}
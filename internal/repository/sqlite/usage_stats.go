@@ -3,6 +3,7 @@ package sqlite
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -11,27 +12,36 @@ import (
 	"github.com/awsl-project/maxx/internal/repository"
 	"github.com/awsl-project/maxx/internal/stats"
 	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 type UsageStatsRepository struct {
 	db *DB
+
+	// 串行化 AggregateAndRollUp，定时任务与手动触发的聚合不会同时运行
+	aggregateMu sync.Mutex
 }
 
 func NewUsageStatsRepository(db *DB) *UsageStatsRepository {
 	return &UsageStatsRepository{db: db}
 }
 
-// getConfiguredTimezone 获取配置的时区，默认 Asia/Shanghai
-func (r *UsageStatsRepository) getConfiguredTimezone() *time.Location {
+// configuredTimezoneName 获取配置的时区名称，默认 Asia/Shanghai
+func (r *UsageStatsRepository) configuredTimezoneName() string {
 	var value string
 	err := r.db.gorm.Table("system_settings").
-		Where("key = ?", domain.SettingKeyTimezone).
+		Where("setting_key = ?", domain.SettingKeyTimezone).
 		Pluck("value", &value).Error
 	if err != nil || value == "" {
 		value = "Asia/Shanghai" // 默认时区
 	}
+	return value
+}
 
+// getConfiguredTimezone 获取配置的时区，默认 Asia/Shanghai
+func (r *UsageStatsRepository) getConfiguredTimezone() *time.Location {
+	value := r.configuredTimezoneName()
 	loc, err := time.LoadLocation(value)
 	if err != nil {
 		log.Printf("[UsageStats] Invalid timezone %q, falling back to UTC+8: %v", value, err)
@@ -41,13 +51,35 @@ func (r *UsageStatsRepository) getConfiguredTimezone() *time.Location {
 	return loc
 }
 
+// getProjectTimezone 获取项目配置的统计时区，未配置或无效时返回 nil
+func (r *UsageStatsRepository) getProjectTimezone(projectID uint64) *time.Location {
+	var value string
+	err := r.db.gorm.Model(&Project{}).
+		Where("id = ?", projectID).
+		Pluck("timezone", &value).Error
+	if err != nil || value == "" {
+		return nil
+	}
+
+	loc, err := time.LoadLocation(value)
+	if err != nil {
+		log.Printf("[UsageStats] Invalid timezone %q for project %d, using global timezone: %v", value, projectID, err)
+		return nil
+	}
+	return loc
+}
+
 // Upsert 更新或插入统计记录
 func (r *UsageStatsRepository) Upsert(stats *domain.UsageStats) error {
 	now := time.Now()
 	stats.CreatedAt = now
 
-	model := r.toModel(stats)
-	return r.db.gorm.Clauses(clause.OnConflict{
+	return upsertUsageStats(r.db.gorm, r.toModel(stats))
+}
+
+// upsertUsageStats 按维度唯一键插入或覆盖统计记录
+func upsertUsageStats(db *gorm.DB, model *UsageStats) error {
+	return db.Clauses(clause.OnConflict{
 		Columns: []clause.Column{
 			{Name: "granularity"},
 			{Name: "time_bucket"},
@@ -59,16 +91,19 @@ func (r *UsageStatsRepository) Upsert(stats *domain.UsageStats) error {
 			{Name: "model"},
 		},
 		DoUpdates: clause.Assignments(map[string]any{
-			"total_requests":      stats.TotalRequests,
-			"successful_requests": stats.SuccessfulRequests,
-			"failed_requests":     stats.FailedRequests,
-			"total_duration_ms":   stats.TotalDurationMs,
-			"total_ttft_ms":       stats.TotalTTFTMs,
-			"input_tokens":        stats.InputTokens,
-			"output_tokens":       stats.OutputTokens,
-			"cache_read":          stats.CacheRead,
-			"cache_write":         stats.CacheWrite,
-			"cost":                stats.Cost,
+			"total_requests":      model.TotalRequests,
+			"successful_requests": model.SuccessfulRequests,
+			"failed_requests":     model.FailedRequests,
+			"total_duration_ms":   model.TotalDurationMs,
+			"total_ttft_ms":       model.TotalTTFTMs,
+			"tps_output_tokens":   model.TPSOutputTokens,
+			"tps_duration_ms":     model.TPSDurationMs,
+			"input_tokens":        model.InputTokens,
+			"output_tokens":       model.OutputTokens,
+			"cache_read":          model.CacheRead,
+			"cache_write":         model.CacheWrite,
+			"reasoning_tokens":    model.ReasoningTokens,
+			"cost":                model.Cost,
 		}),
 	}).Create(model).Error
 }
@@ -149,6 +184,14 @@ func (r *UsageStatsRepository) queryHistorical(filter repository.UsageStatsFilte
 //   - 1月17日 10:29-10:30: proxy_upstream_attempts (实时)
 func (r *UsageStatsRepository) Query(filter repository.UsageStatsFilter) ([]*domain.UsageStats, error) {
 	loc := r.getConfiguredTimezone()
+
+	// 按项目过滤的 day/month 查询：如果项目配置了独立时区，在读取时按项目时区重新分桶
+	if filter.ProjectID != nil && (filter.Granularity == domain.GranularityDay || filter.Granularity == domain.GranularityMonth) {
+		if projectLoc := r.getProjectTimezone(*filter.ProjectID); projectLoc != nil && projectLoc.String() != loc.String() {
+			return r.queryInTimezone(filter, projectLoc, loc)
+		}
+	}
+
 	now := time.Now().In(loc)
 	currentBucket := stats.TruncateToGranularity(now, filter.Granularity, loc)
 	currentMonth := stats.TruncateToGranularity(now, domain.GranularityMonth, loc)
@@ -276,6 +319,53 @@ func (r *UsageStatsRepository) Query(filter repository.UsageStatsFilter) ([]*dom
 	return results, nil
 }
 
+// queryInTimezone 以 loc 时区的 day/month 边界重新分桶（仅读取时生效，不影响全局预聚合）
+// 策略：
+//   - hour 数据保留期内：用 hour 粒度数据（含实时补全）按 loc 重新 RollUp，边界精确
+//   - 更早的数据：hour 数据已被清理，只能用全局时区的 day 数据按 loc 近似归桶
+//
+// 注意：对于非整点偏移的时区（如 Asia/Kolkata），hour 数据本身无法精确切分，结果为近似值
+func (r *UsageStatsRepository) queryInTimezone(filter repository.UsageStatsFilter, loc, globalLoc *time.Location) ([]*domain.UsageStats, error) {
+	// hour 数据保留 1 个月（见 core.runCleanupTasks），取其后第一个全局日边界作为分界点，
+	// 保证分界点之前的 day 数据与之后的 hour 数据不重叠
+	cutoff := stats.TruncateToGranularity(time.Now().AddDate(0, -1, 0), domain.GranularityDay, globalLoc).AddDate(0, 0, 1)
+
+	var fine []*domain.UsageStats
+
+	if filter.StartTime == nil || filter.StartTime.Before(cutoff) {
+		dayFilter := filter
+		dayFilter.Granularity = domain.GranularityDay
+		dayEnd := cutoff.Add(-time.Millisecond)
+		if filter.EndTime == nil || filter.EndTime.After(dayEnd) {
+			dayFilter.EndTime = &dayEnd
+		}
+		dayStats, err := r.queryHistorical(dayFilter)
+		if err != nil {
+			return nil, err
+		}
+		fine = append(fine, dayStats...)
+	}
+
+	if filter.EndTime == nil || !filter.EndTime.Before(cutoff) {
+		hourFilter := filter
+		hourFilter.Granularity = domain.GranularityHour
+		if filter.StartTime == nil || filter.StartTime.Before(cutoff) {
+			hourFilter.StartTime = &cutoff
+		}
+		hourStats, err := r.Query(hourFilter)
+		if err != nil {
+			return nil, err
+		}
+		fine = append(fine, hourStats...)
+	}
+
+	results := stats.RollUp(fine, filter.Granularity, loc)
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].TimeBucket.After(results[j].TimeBucket)
+	})
+	return results, nil
+}
+
 // queryStatsInRange 查询指定粒度和时间范围内的统计数据
 func (r *UsageStatsRepository) queryStatsInRange(granularity domain.Granularity, start, end time.Time, filter repository.UsageStatsFilter) ([]*domain.UsageStats, error) {
 	var conditions []string
@@ -349,10 +439,13 @@ func (r *UsageStatsRepository) aggregateToTargetBucket(
 			existing.FailedRequests += s.FailedRequests
 			existing.TotalDurationMs += s.TotalDurationMs
 			existing.TotalTTFTMs += s.TotalTTFTMs
+			existing.TPSOutputTokens += s.TPSOutputTokens
+			existing.TPSDurationMs += s.TPSDurationMs
 			existing.InputTokens += s.InputTokens
 			existing.OutputTokens += s.OutputTokens
 			existing.CacheRead += s.CacheRead
 			existing.CacheWrite += s.CacheWrite
+			existing.ReasoningTokens += s.ReasoningTokens
 			existing.Cost += s.Cost
 		} else {
 			aggregated[key] = &domain.UsageStats{
@@ -369,10 +462,13 @@ func (r *UsageStatsRepository) aggregateToTargetBucket(
 				FailedRequests:     s.FailedRequests,
 				TotalDurationMs:    s.TotalDurationMs,
 				TotalTTFTMs:        s.TotalTTFTMs,
+				TPSOutputTokens:    s.TPSOutputTokens,
+				TPSDurationMs:      s.TPSDurationMs,
 				InputTokens:        s.InputTokens,
 				OutputTokens:       s.OutputTokens,
 				CacheRead:          s.CacheRead,
 				CacheWrite:         s.CacheWrite,
+				ReasoningTokens:    s.ReasoningTokens,
 				Cost:               s.Cost,
 			}
 		}
@@ -495,7 +591,9 @@ func (r *UsageStatsRepository) queryRecentMinutesStats(startMinute time.Time, fi
 			COALESCE(a.output_token_count, 0),
 			COALESCE(a.cache_read_count, 0),
 			COALESCE(a.cache_write_count, 0),
-			COALESCE(a.cost, 0)
+			COALESCE(a.reasoning_token_count, 0),
+			CASE WHEN COALESCE(r.non_billable, 0) = 1 THEN 0 ELSE COALESCE(a.cost, 0) END,
+			COALESCE(a.output_tps, 0)
 		FROM proxy_upstream_attempts a
 		LEFT JOIN proxy_requests r ON a.proxy_request_id = r.id
 		WHERE ` + strings.Join(conditions, " AND ")
@@ -512,34 +610,37 @@ func (r *UsageStatsRepository) queryRecentMinutesStats(startMinute time.Time, fi
 		var endTime int64
 		var routeID, providerID, projectID, apiTokenID uint64
 		var clientType, model, status string
-		var durationMs, ttftMs, inputTokens, outputTokens, cacheRead, cacheWrite, cost uint64
+		var durationMs, ttftMs, inputTokens, outputTokens, cacheRead, cacheWrite, reasoningTokens, cost uint64
+		var outputTPS float64
 
 		err := rows.Scan(
 			&endTime, &routeID, &providerID, &projectID, &apiTokenID, &clientType,
 			&model, &status, &durationMs, &ttftMs,
-			&inputTokens, &outputTokens, &cacheRead, &cacheWrite, &cost,
+			&inputTokens, &outputTokens, &cacheRead, &cacheWrite, &reasoningTokens, &cost, &outputTPS,
 		)
 		if err != nil {
 			continue
 		}
 
 		records = append(records, stats.AttemptRecord{
-			EndTime:      fromTimestamp(endTime),
-			RouteID:      routeID,
-			ProviderID:   providerID,
-			ProjectID:    projectID,
-			APITokenID:   apiTokenID,
-			ClientType:   clientType,
-			Model:        model,
-			IsSuccessful: status == "COMPLETED",
-			IsFailed:     status == "FAILED" || status == "CANCELLED",
-			DurationMs:   durationMs,
-			TTFTMs:       ttftMs,
-			InputTokens:  inputTokens,
-			OutputTokens: outputTokens,
-			CacheRead:    cacheRead,
-			CacheWrite:   cacheWrite,
-			Cost:         cost,
+			EndTime:         fromTimestamp(endTime),
+			RouteID:         routeID,
+			ProviderID:      providerID,
+			ProjectID:       projectID,
+			APITokenID:      apiTokenID,
+			ClientType:      clientType,
+			Model:           model,
+			IsSuccessful:    status == "COMPLETED",
+			IsFailed:        status == "FAILED" || status == "CANCELLED",
+			DurationMs:      durationMs,
+			TTFTMs:          ttftMs,
+			InputTokens:     inputTokens,
+			OutputTokens:    outputTokens,
+			CacheRead:       cacheRead,
+			CacheWrite:      cacheWrite,
+			ReasoningTokens: reasoningTokens,
+			Cost:            cost,
+			OutputTPS:       outputTPS,
 		})
 	}
 
@@ -563,6 +664,7 @@ func (r *UsageStatsRepository) GetSummary(filter repository.UsageStatsFilter) (*
 
 	// 聚合所有数据
 	var s domain.UsageStatsSummary
+	var tpsTokens, tpsDurationMs uint64
 	for _, stat := range allStats {
 		s.TotalRequests += stat.TotalRequests
 		s.SuccessfulRequests += stat.SuccessfulRequests
@@ -571,12 +673,17 @@ func (r *UsageStatsRepository) GetSummary(filter repository.UsageStatsFilter) (*
 		s.TotalOutputTokens += stat.OutputTokens
 		s.TotalCacheRead += stat.CacheRead
 		s.TotalCacheWrite += stat.CacheWrite
+		s.TotalReasoning += stat.ReasoningTokens
 		s.TotalCost += stat.Cost
+		tpsTokens += stat.TPSOutputTokens
+		tpsDurationMs += stat.TPSDurationMs
 	}
 
 	if s.TotalRequests > 0 {
 		s.SuccessRate = float64(s.SuccessfulRequests) / float64(s.TotalRequests) * 100
 	}
+	s.AvgOutputTPS = stats.AverageTPS(tpsTokens, tpsDurationMs)
+	s.CacheHitRatio = stats.CacheHitRatio(s.TotalInputTokens, s.TotalCacheRead)
 	return &s, nil
 }
 
@@ -611,6 +718,7 @@ func (r *UsageStatsRepository) getSummaryByDimension(filter repository.UsageStat
 
 	// 按维度聚合
 	results := make(map[uint64]*domain.UsageStatsSummary)
+	tps := make(map[uint64][2]uint64) // dimID -> [TPSOutputTokens, TPSDurationMs]
 	for _, stat := range allStats {
 		var dimID uint64
 		switch dimension {
@@ -623,6 +731,8 @@ func (r *UsageStatsRepository) getSummaryByDimension(filter repository.UsageStat
 		case "api_token_id":
 			dimID = stat.APITokenID
 		}
+		t := tps[dimID]
+		tps[dimID] = [2]uint64{t[0] + stat.TPSOutputTokens, t[1] + stat.TPSDurationMs}
 
 		if existing, ok := results[dimID]; ok {
 			existing.TotalRequests += stat.TotalRequests
@@ -632,6 +742,7 @@ func (r *UsageStatsRepository) getSummaryByDimension(filter repository.UsageStat
 			existing.TotalOutputTokens += stat.OutputTokens
 			existing.TotalCacheRead += stat.CacheRead
 			existing.TotalCacheWrite += stat.CacheWrite
+			existing.TotalReasoning += stat.ReasoningTokens
 			existing.TotalCost += stat.Cost
 		} else {
 			results[dimID] = &domain.UsageStatsSummary{
@@ -642,16 +753,19 @@ func (r *UsageStatsRepository) getSummaryByDimension(filter repository.UsageStat
 				TotalOutputTokens:  stat.OutputTokens,
 				TotalCacheRead:     stat.CacheRead,
 				TotalCacheWrite:    stat.CacheWrite,
+				TotalReasoning:     stat.ReasoningTokens,
 				TotalCost:          stat.Cost,
 			}
 		}
 	}
 
-	// 计算成功率
-	for _, s := range results {
+	// 计算成功率、平均输出速度和缓存命中率
+	for dimID, s := range results {
 		if s.TotalRequests > 0 {
 			s.SuccessRate = float64(s.SuccessfulRequests) / float64(s.TotalRequests) * 100
 		}
+		s.AvgOutputTPS = stats.AverageTPS(tps[dimID][0], tps[dimID][1])
+		s.CacheHitRatio = stats.CacheHitRatio(s.TotalInputTokens, s.TotalCacheRead)
 	}
 
 	return results, nil
@@ -668,8 +782,11 @@ func (r *UsageStatsRepository) GetSummaryByClientType(filter repository.UsageSta
 
 	// 按 ClientType 聚合
 	results := make(map[string]*domain.UsageStatsSummary)
+	tps := make(map[string][2]uint64) // clientType -> [TPSOutputTokens, TPSDurationMs]
 	for _, stat := range allStats {
 		clientType := stat.ClientType
+		t := tps[clientType]
+		tps[clientType] = [2]uint64{t[0] + stat.TPSOutputTokens, t[1] + stat.TPSDurationMs}
 
 		if existing, ok := results[clientType]; ok {
 			existing.TotalRequests += stat.TotalRequests
@@ -679,6 +796,7 @@ func (r *UsageStatsRepository) GetSummaryByClientType(filter repository.UsageSta
 			existing.TotalOutputTokens += stat.OutputTokens
 			existing.TotalCacheRead += stat.CacheRead
 			existing.TotalCacheWrite += stat.CacheWrite
+			existing.TotalReasoning += stat.ReasoningTokens
 			existing.TotalCost += stat.Cost
 		} else {
 			results[clientType] = &domain.UsageStatsSummary{
@@ -689,16 +807,73 @@ func (r *UsageStatsRepository) GetSummaryByClientType(filter repository.UsageSta
 				TotalOutputTokens:  stat.OutputTokens,
 				TotalCacheRead:     stat.CacheRead,
 				TotalCacheWrite:    stat.CacheWrite,
+				TotalReasoning:     stat.ReasoningTokens,
 				TotalCost:          stat.Cost,
 			}
 		}
 	}
 
-	// 计算成功率
-	for _, s := range results {
+	// 计算成功率、平均输出速度和缓存命中率
+	for key, s := range results {
 		if s.TotalRequests > 0 {
 			s.SuccessRate = float64(s.SuccessfulRequests) / float64(s.TotalRequests) * 100
 		}
+		s.AvgOutputTPS = stats.AverageTPS(tps[key][0], tps[key][1])
+		s.CacheHitRatio = stats.CacheHitRatio(s.TotalInputTokens, s.TotalCacheRead)
+	}
+
+	return results, nil
+}
+
+// GetSummaryByModel 按 Model 维度获取汇总统计
+// 复用 queryAllWithRealtime 获取实时数据
+func (r *UsageStatsRepository) GetSummaryByModel(filter repository.UsageStatsFilter) (map[string]*domain.UsageStatsSummary, error) {
+	// 使用通用的分层查询获取所有数据
+	allStats, err := r.queryAllWithRealtime(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	// 按 Model 聚合
+	results := make(map[string]*domain.UsageStatsSummary)
+	tps := make(map[string][2]uint64) // model -> [TPSOutputTokens, TPSDurationMs]
+	for _, stat := range allStats {
+		model := stat.Model
+		t := tps[model]
+		tps[model] = [2]uint64{t[0] + stat.TPSOutputTokens, t[1] + stat.TPSDurationMs}
+
+		if existing, ok := results[model]; ok {
+			existing.TotalRequests += stat.TotalRequests
+			existing.SuccessfulRequests += stat.SuccessfulRequests
+			existing.FailedRequests += stat.FailedRequests
+			existing.TotalInputTokens += stat.InputTokens
+			existing.TotalOutputTokens += stat.OutputTokens
+			existing.TotalCacheRead += stat.CacheRead
+			existing.TotalCacheWrite += stat.CacheWrite
+			existing.TotalReasoning += stat.ReasoningTokens
+			existing.TotalCost += stat.Cost
+		} else {
+			results[model] = &domain.UsageStatsSummary{
+				TotalRequests:      stat.TotalRequests,
+				SuccessfulRequests: stat.SuccessfulRequests,
+				FailedRequests:     stat.FailedRequests,
+				TotalInputTokens:   stat.InputTokens,
+				TotalOutputTokens:  stat.OutputTokens,
+				TotalCacheRead:     stat.CacheRead,
+				TotalCacheWrite:    stat.CacheWrite,
+				TotalReasoning:     stat.ReasoningTokens,
+				TotalCost:          stat.Cost,
+			}
+		}
+	}
+
+	// 计算成功率、平均输出速度和缓存命中率
+	for key, s := range results {
+		if s.TotalRequests > 0 {
+			s.SuccessRate = float64(s.SuccessfulRequests) / float64(s.TotalRequests) * 100
+		}
+		s.AvgOutputTPS = stats.AverageTPS(tps[key][0], tps[key][1])
+		s.CacheHitRatio = stats.CacheHitRatio(s.TotalInputTokens, s.TotalCacheRead)
 	}
 
 	return results, nil
@@ -948,7 +1123,9 @@ func (r *UsageStatsRepository) aggregateMinute() (count int, startTime, endTime
 			COALESCE(a.output_token_count, 0),
 			COALESCE(a.cache_read_count, 0),
 			COALESCE(a.cache_write_count, 0),
-			COALESCE(a.cost, 0)
+			COALESCE(a.reasoning_token_count, 0),
+			CASE WHEN COALESCE(r.non_billable, 0) = 1 THEN 0 ELSE COALESCE(a.cost, 0) END,
+			COALESCE(a.output_tps, 0)
 		FROM proxy_upstream_attempts a
 		LEFT JOIN proxy_requests r ON a.proxy_request_id = r.id
 		WHERE a.end_time >= ? AND a.end_time < ?
@@ -969,12 +1146,13 @@ func (r *UsageStatsRepository) aggregateMinute() (count int, startTime, endTime
 		var endTime int64
 		var routeID, providerID, projectID, apiTokenID uint64
 		var clientType, model, status string
-		var durationMs, ttftMs, inputTokens, outputTokens, cacheRead, cacheWrite, cost uint64
+		var durationMs, ttftMs, inputTokens, outputTokens, cacheRead, cacheWrite, reasoningTokens, cost uint64
+		var outputTPS float64
 
 		err := rows.Scan(
 			&endTime, &routeID, &providerID, &projectID, &apiTokenID, &clientType,
 			&model, &status, &durationMs, &ttftMs,
-			&inputTokens, &outputTokens, &cacheRead, &cacheWrite, &cost,
+			&inputTokens, &outputTokens, &cacheRead, &cacheWrite, &reasoningTokens, &cost, &outputTPS,
 		)
 		if err != nil {
 			continue
@@ -986,22 +1164,24 @@ func (r *UsageStatsRepository) aggregateMinute() (count int, startTime, endTime
 		}
 
 		records = append(records, stats.AttemptRecord{
-			EndTime:      fromTimestamp(endTime),
-			RouteID:      routeID,
-			ProviderID:   providerID,
-			ProjectID:    projectID,
-			APITokenID:   apiTokenID,
-			ClientType:   clientType,
-			Model:        model,
-			IsSuccessful: status == "COMPLETED",
-			IsFailed:     status == "FAILED" || status == "CANCELLED",
-			DurationMs:   durationMs,
-			TTFTMs:       ttftMs,
-			InputTokens:  inputTokens,
-			OutputTokens: outputTokens,
-			CacheRead:    cacheRead,
-			CacheWrite:   cacheWrite,
-			Cost:         cost,
+			EndTime:         fromTimestamp(endTime),
+			RouteID:         routeID,
+			ProviderID:      providerID,
+			ProjectID:       projectID,
+			APITokenID:      apiTokenID,
+			ClientType:      clientType,
+			Model:           model,
+			IsSuccessful:    status == "COMPLETED",
+			IsFailed:        status == "FAILED" || status == "CANCELLED",
+			DurationMs:      durationMs,
+			TTFTMs:          ttftMs,
+			InputTokens:     inputTokens,
+			OutputTokens:    outputTokens,
+			CacheRead:       cacheRead,
+			CacheWrite:      cacheWrite,
+			ReasoningTokens: reasoningTokens,
+			Cost:            cost,
+			OutputTPS:       outputTPS,
 		})
 	}
 
@@ -1034,12 +1214,16 @@ func (r *UsageStatsRepository) aggregateMinute() (count int, startTime, endTime
 // AggregateAndRollUp 聚合原始数据到分钟级别，并自动 rollup 到各个粗粒度
 // 返回一个 channel，发送每个阶段的进度事件，channel 会在完成后关闭
 // 调用者可以 range 遍历 channel 获取进度，或直接忽略（异步执行）
+// 同一时间只运行一次，后调用的会等待前一次完成后再开始
 func (r *UsageStatsRepository) AggregateAndRollUp() <-chan domain.AggregateEvent {
 	ch := make(chan domain.AggregateEvent, 5) // buffered to avoid blocking
 
 	go func() {
 		defer close(ch)
 
+		r.aggregateMu.Lock()
+		defer r.aggregateMu.Unlock()
+
 		// 1. 聚合原始数据到分钟级别
 		count, startTime, endTime, err := r.aggregateMinute()
 		ch <- domain.AggregateEvent{
@@ -1066,6 +1250,22 @@ func (r *UsageStatsRepository) AggregateAndRollUp() <-chan domain.AggregateEvent
 		}
 
 		for _, ru := range rollups {
+			// 时区设置变更后，先按新时区重建 day/month 统计桶，再继续增量上卷
+			if ru.to == domain.GranularityDay {
+				count, changed, err := r.rebuildForTimezoneChange()
+				if changed || err != nil {
+					ch <- domain.AggregateEvent{
+						Phase: "rebuild_timezone",
+						To:    domain.GranularityDay,
+						Count: count,
+						Error: err,
+					}
+				}
+				if err != nil {
+					return
+				}
+			}
+
 			count, startTime, endTime, err := r.rollUp(ru.from, ru.to)
 			ch <- domain.AggregateEvent{
 				Phase:     ru.phase,
@@ -1288,7 +1488,9 @@ func (r *UsageStatsRepository) aggregateAllMinutesWithProgress(progressFn func(c
 			COALESCE(a.output_token_count, 0),
 			COALESCE(a.cache_read_count, 0),
 			COALESCE(a.cache_write_count, 0),
-			COALESCE(a.cost, 0)
+			COALESCE(a.reasoning_token_count, 0),
+			CASE WHEN COALESCE(r.non_billable, 0) = 1 THEN 0 ELSE COALESCE(a.cost, 0) END,
+			COALESCE(a.output_tps, 0)
 		FROM proxy_upstream_attempts a
 		LEFT JOIN proxy_requests r ON a.proxy_request_id = r.id
 		WHERE a.end_time < ? AND a.status IN ('COMPLETED', 'FAILED', 'CANCELLED')
@@ -1312,12 +1514,13 @@ func (r *UsageStatsRepository) aggregateAllMinutesWithProgress(progressFn func(c
 		var endTime int64
 		var routeID, providerID, projectID, apiTokenID uint64
 		var clientType, model, status string
-		var durationMs, ttftMs, inputTokens, outputTokens, cacheRead, cacheWrite, cost uint64
+		var durationMs, ttftMs, inputTokens, outputTokens, cacheRead, cacheWrite, reasoningTokens, cost uint64
+		var outputTPS float64
 
 		err := rows.Scan(
 			&endTime, &routeID, &providerID, &projectID, &apiTokenID, &clientType,
 			&model, &status, &durationMs, &ttftMs,
-			&inputTokens, &outputTokens, &cacheRead, &cacheWrite, &cost,
+			&inputTokens, &outputTokens, &cacheRead, &cacheWrite, &reasoningTokens, &cost, &outputTPS,
 		)
 		if err != nil {
 			log.Printf("[aggregateAllMinutes] Scan error: %v", err)
@@ -1336,22 +1539,24 @@ func (r *UsageStatsRepository) aggregateAllMinutesWithProgress(progressFn func(c
 		}
 
 		records = append(records, stats.AttemptRecord{
-			EndTime:      fromTimestamp(endTime),
-			RouteID:      routeID,
-			ProviderID:   providerID,
-			ProjectID:    projectID,
-			APITokenID:   apiTokenID,
-			ClientType:   clientType,
-			Model:        model,
-			IsSuccessful: status == "COMPLETED",
-			IsFailed:     status == "FAILED" || status == "CANCELLED",
-			DurationMs:   durationMs,
-			TTFTMs:       ttftMs,
-			InputTokens:  inputTokens,
-			OutputTokens: outputTokens,
-			CacheRead:    cacheRead,
-			CacheWrite:   cacheWrite,
-			Cost:         cost,
+			EndTime:         fromTimestamp(endTime),
+			RouteID:         routeID,
+			ProviderID:      providerID,
+			ProjectID:       projectID,
+			APITokenID:      apiTokenID,
+			ClientType:      clientType,
+			Model:           model,
+			IsSuccessful:    status == "COMPLETED",
+			IsFailed:        status == "FAILED" || status == "CANCELLED",
+			DurationMs:      durationMs,
+			TTFTMs:          ttftMs,
+			InputTokens:     inputTokens,
+			OutputTokens:    outputTokens,
+			CacheRead:       cacheRead,
+			CacheWrite:      cacheWrite,
+			ReasoningTokens: reasoningTokens,
+			Cost:            cost,
+			OutputTPS:       outputTPS,
 		})
 	}
 
@@ -1404,10 +1609,13 @@ func (r *UsageStatsRepository) toModel(s *domain.UsageStats) *UsageStats {
 		FailedRequests:     s.FailedRequests,
 		TotalDurationMs:    s.TotalDurationMs,
 		TotalTTFTMs:        s.TotalTTFTMs,
+		TPSOutputTokens:    s.TPSOutputTokens,
+		TPSDurationMs:      s.TPSDurationMs,
 		InputTokens:        s.InputTokens,
 		OutputTokens:       s.OutputTokens,
 		CacheRead:          s.CacheRead,
 		CacheWrite:         s.CacheWrite,
+		ReasoningTokens:    s.ReasoningTokens,
 		Cost:               s.Cost,
 	}
 }
@@ -1429,10 +1637,13 @@ func (r *UsageStatsRepository) toDomain(m *UsageStats) *domain.UsageStats {
 		FailedRequests:     m.FailedRequests,
 		TotalDurationMs:    m.TotalDurationMs,
 		TotalTTFTMs:        m.TotalTTFTMs,
+		TPSOutputTokens:    m.TPSOutputTokens,
+		TPSDurationMs:      m.TPSDurationMs,
 		InputTokens:        m.InputTokens,
 		OutputTokens:       m.OutputTokens,
 		CacheRead:          m.CacheRead,
 		CacheWrite:         m.CacheWrite,
+		ReasoningTokens:    m.ReasoningTokens,
 		Cost:               m.Cost,
 	}
 }
@@ -0,0 +1,252 @@
+package sqlite
+
+import (
+	"log"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/stats"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// compactSources 各粒度的上卷来源粒度
+var compactSources = map[domain.Granularity]domain.Granularity{
+	domain.GranularityDay:   domain.GranularityHour,
+	domain.GranularityMonth: domain.GranularityDay,
+}
+
+// CompactTimeBuckets merges day/month usage_stats rows whose time_bucket is not
+// aligned to the configured timezone, and returns how many rows were fixed.
+//
+// Detection: minute/hour buckets are truncated in UTC and can't drift, but
+// day/month buckets start at local midnight. After the timezone setting
+// changes, rows rolled up under the old timezone keep their old start, so the
+// same dimensions end up in buckets like 2026-01-01T00:00+08:00 next to
+// 2026-01-01T00:00Z. A bucket is misaligned when truncating it with the current
+// timezone does not give the bucket itself. Aligned buckets are never touched,
+// so two legitimately distinct buckets are never merged with each other.
+//
+// Fixing: rollUp restarts from the latest bucket, so after a timezone change
+// the most recent period is rolled up again under the new alignment, and a
+// misaligned row can overlap aligned rows that already contain part of its
+// data. Summing would double count there. So while the source granularity
+// (hour for day, day for month) still covers the misaligned bucket, the row is
+// dropped and the overlapping aligned buckets are rebuilt from the source.
+// Only rows older than the source retention are summed into the aligned bucket
+// containing their midpoint; those were never rolled up again.
+func (r *UsageStatsRepository) CompactTimeBuckets() (int, error) {
+	r.aggregateMu.Lock()
+	defer r.aggregateMu.Unlock()
+
+	return r.compactAll(r.db.gorm, r.getConfiguredTimezone())
+}
+
+// rebuildForTimezoneChange re-rolls day/month buckets in the new timezone when
+// the timezone setting differs from the one recorded at the last rebuild, and
+// returns how many rows were fixed. changed is false when nothing was done.
+//
+// Trigger: AggregateAndRollUp calls this before rolling up to day, so a changed
+// timezone is picked up within one aggregation cycle (30s). Until then no
+// rollup has run under the new timezone, so old and new buckets never overlap.
+//
+// Double counting: every old-timezone bucket is misaligned under the new one,
+// so compaction drops it and rebuilds the overlapping new buckets from hour
+// rows (day) and the rebuilt day rows (month); buckets older than the hour
+// retention are merged instead, see CompactTimeBuckets. All of it runs in one
+// transaction together with recording the new timezone, so readers see either
+// the old buckets or the new ones, and a failed rebuild is retried next cycle.
+// The caller must hold aggregateMu so no rollup writes in between.
+func (r *UsageStatsRepository) rebuildForTimezoneChange() (fixed int, changed bool, err error) {
+	name := r.configuredTimezoneName()
+	var recorded string
+	if err := r.db.gorm.Table("system_settings").
+		Where("setting_key = ?", domain.SettingKeyStatsTimezone).
+		Pluck("value", &recorded).Error; err != nil {
+		return 0, false, err
+	}
+	if recorded == name {
+		return 0, false, nil
+	}
+
+	loc := r.getConfiguredTimezone()
+	err = r.db.gorm.Transaction(func(tx *gorm.DB) error {
+		n, err := r.compactAll(tx, loc)
+		fixed = n
+		if err != nil {
+			return err
+		}
+		now := time.Now().UnixMilli()
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "setting_key"}},
+			DoUpdates: clause.Assignments(map[string]any{"value": LongText(name), "updated_at": now}),
+		}).Create(&SystemSetting{Key: domain.SettingKeyStatsTimezone, Value: LongText(name), CreatedAt: now, UpdatedAt: now}).Error
+	})
+	if err != nil {
+		return 0, true, err
+	}
+	if recorded != "" {
+		log.Printf("[UsageStats] Timezone changed from %s to %s, rebuilt day/month stats (%d rows)", recorded, name, fixed)
+	}
+	return fixed, true, nil
+}
+
+// compactAll compacts day before month, since month is rebuilt from day rows
+func (r *UsageStatsRepository) compactAll(db *gorm.DB, loc *time.Location) (int, error) {
+	total := 0
+	for _, g := range []domain.Granularity{domain.GranularityDay, domain.GranularityMonth} {
+		n, err := r.compactGranularity(db, g, loc)
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (r *UsageStatsRepository) compactGranularity(db *gorm.DB, g domain.Granularity, loc *time.Location) (int, error) {
+	var buckets []int64
+	if err := db.Model(&UsageStats{}).
+		Where("granularity = ?", g).
+		Distinct().
+		Pluck("time_bucket", &buckets).Error; err != nil {
+		return 0, err
+	}
+
+	var misaligned []int64
+	for _, b := range buckets {
+		if stats.TruncateToGranularity(fromTimestamp(b), g, loc).UnixMilli() != b {
+			misaligned = append(misaligned, b)
+		}
+	}
+	if len(misaligned) == 0 {
+		return 0, nil
+	}
+
+	var earliestSource *int64
+	if err := db.Model(&UsageStats{}).
+		Select("MIN(time_bucket)").
+		Where("granularity = ?", compactSources[g]).
+		Scan(&earliestSource).Error; err != nil {
+		return 0, err
+	}
+
+	fixed := 0
+	for _, b := range misaligned {
+		var n int
+		var err error
+		if earliestSource != nil && *earliestSource != 0 && *earliestSource <= b {
+			n, err = r.rebuildMisalignedBucket(db, g, b, loc)
+		} else {
+			n, err = r.mergeMisalignedBucket(db, g, b, loc)
+		}
+		if err != nil {
+			return fixed, err
+		}
+		fixed += n
+	}
+	log.Printf("[UsageStats] Compacted %d misaligned %s buckets (%d rows)", len(misaligned), g, fixed)
+	return fixed, nil
+}
+
+// rebuildMisalignedBucket deletes the rows of a misaligned bucket and rolls up
+// the aligned buckets it overlaps again from the source granularity
+func (r *UsageStatsRepository) rebuildMisalignedBucket(db *gorm.DB, g domain.Granularity, bucket int64, loc *time.Location) (int, error) {
+	start := fromTimestamp(bucket)
+	from := stats.TruncateToGranularity(start, g, loc)
+	to := compactBucketEnd(stats.TruncateToGranularity(compactBucketEnd(start, g, loc).Add(-time.Millisecond), g, loc), g, loc)
+	// 与 rollUp 一致，不写入尚未结束的当前桶
+	if current := stats.TruncateToGranularity(time.Now(), g, loc); to.After(current) {
+		to = current
+	}
+
+	n := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("granularity = ? AND time_bucket = ?", g, bucket).Delete(&UsageStats{})
+		if result.Error != nil {
+			return result.Error
+		}
+		n = int(result.RowsAffected)
+
+		var models []UsageStats
+		if err := tx.Where("granularity = ? AND time_bucket >= ? AND time_bucket < ?",
+			compactSources[g], toTimestamp(from), toTimestamp(to)).
+			Find(&models).Error; err != nil {
+			return err
+		}
+		for _, s := range stats.RollUp(r.toDomainList(models), g, loc) {
+			s.CreatedAt = time.Now()
+			if err := upsertUsageStats(tx, r.toModel(s)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	return n, err
+}
+
+// mergeMisalignedBucket sums the rows of a misaligned bucket into the aligned
+// bucket containing its midpoint
+func (r *UsageStatsRepository) mergeMisalignedBucket(db *gorm.DB, g domain.Granularity, bucket int64, loc *time.Location) (int, error) {
+	start := fromTimestamp(bucket)
+	end := compactBucketEnd(start, g, loc)
+	target := stats.TruncateToGranularity(start.Add(end.Sub(start)/2), g, loc).UnixMilli()
+
+	n := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var rows []UsageStats
+		if err := tx.Where("granularity = ? AND time_bucket = ?", g, bucket).Find(&rows).Error; err != nil {
+			return err
+		}
+		for i := range rows {
+			row := &rows[i]
+			var existing UsageStats
+			err := tx.Where("granularity = ? AND time_bucket = ? AND route_id = ? AND provider_id = ? AND project_id = ? AND api_token_id = ? AND client_type = ? AND model = ?",
+				g, target, row.RouteID, row.ProviderID, row.ProjectID, row.APITokenID, row.ClientType, row.Model).
+				First(&existing).Error
+			switch {
+			case err == gorm.ErrRecordNotFound:
+				if err := tx.Model(row).Update("time_bucket", target).Error; err != nil {
+					return err
+				}
+			case err != nil:
+				return err
+			default:
+				addUsageStatsMetrics(&existing, row)
+				if err := tx.Save(&existing).Error; err != nil {
+					return err
+				}
+				if err := tx.Delete(row).Error; err != nil {
+					return err
+				}
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+// compactBucketEnd returns the end of the bucket starting at start
+func compactBucketEnd(start time.Time, g domain.Granularity, loc *time.Location) time.Time {
+	if g == domain.GranularityMonth {
+		return start.In(loc).AddDate(0, 1, 0)
+	}
+	return start.Add(24 * time.Hour)
+}
+
+func addUsageStatsMetrics(dst, src *UsageStats) {
+	dst.TotalRequests += src.TotalRequests
+	dst.SuccessfulRequests += src.SuccessfulRequests
+	dst.FailedRequests += src.FailedRequests
+	dst.TotalDurationMs += src.TotalDurationMs
+	dst.TotalTTFTMs += src.TotalTTFTMs
+	dst.TPSOutputTokens += src.TPSOutputTokens
+	dst.TPSDurationMs += src.TPSDurationMs
+	dst.InputTokens += src.InputTokens
+	dst.OutputTokens += src.OutputTokens
+	dst.CacheRead += src.CacheRead
+	dst.CacheWrite += src.CacheWrite
+	dst.ReasoningTokens += src.ReasoningTokens
+	dst.Cost += src.Cost
+}
@@ -0,0 +1,203 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestCompactTimeBuckets(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	if err := NewSystemSettingRepository(db).Set(domain.SettingKeyTimezone, "UTC"); err != nil {
+		t.Fatalf("set timezone: %v", err)
+	}
+	repo := NewUsageStatsRepository(db)
+
+	cst := time.FixedZone("UTC+8", 8*60*60)
+	day := func(bucket time.Time, requests, cost uint64) *domain.UsageStats {
+		return &domain.UsageStats{
+			TimeBucket: bucket, Granularity: domain.GranularityDay,
+			ProviderID: 1, ClientType: "claude", Model: "claude-sonnet-4",
+			TotalRequests: requests, SuccessfulRequests: requests, Cost: cost,
+		}
+	}
+
+	// 2024-01-01 在 UTC 下已有数据，另有一条切换时区前按 UTC+8 上卷的同日记录；
+	// 2024-01-02 只有 UTC+8 的记录；2024-01-03 是合法的独立桶
+	if err := repo.BatchUpsert([]*domain.UsageStats{
+		day(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 10, 100),
+		day(time.Date(2024, 1, 1, 0, 0, 0, 0, cst), 5, 50),
+		day(time.Date(2024, 1, 2, 0, 0, 0, 0, cst), 3, 30),
+		day(time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), 7, 70),
+	}); err != nil {
+		t.Fatalf("BatchUpsert failed: %v", err)
+	}
+
+	fixed, err := repo.CompactTimeBuckets()
+	if err != nil {
+		t.Fatalf("CompactTimeBuckets failed: %v", err)
+	}
+	if fixed != 2 {
+		t.Errorf("fixed = %d, want 2", fixed)
+	}
+
+	var rows []UsageStats
+	if err := db.gorm.Where("granularity = ?", domain.GranularityDay).Order("time_bucket").Find(&rows).Error; err != nil {
+		t.Fatalf("query rows: %v", err)
+	}
+	want := []struct {
+		bucket   time.Time
+		requests uint64
+		cost     uint64
+	}{
+		{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), 15, 150},
+		{time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), 3, 30},
+		{time.Date(2024, 1, 3, 0, 0, 0, 0, time.UTC), 7, 70},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		if rows[i].TimeBucket != w.bucket.UnixMilli() || rows[i].TotalRequests != w.requests || rows[i].Cost != w.cost {
+			t.Errorf("row %d = bucket %v requests %d cost %d, want %v %d %d",
+				i, fromTimestamp(rows[i].TimeBucket).UTC(), rows[i].TotalRequests, rows[i].Cost, w.bucket, w.requests, w.cost)
+		}
+	}
+
+	// 再次运行不应有任何变化
+	if fixed, err := repo.CompactTimeBuckets(); err != nil || fixed != 0 {
+		t.Errorf("second run = (%d, %v), want (0, nil)", fixed, err)
+	}
+}
+
+func TestCompactTimeBucketsRebuildsFromSource(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	if err := NewSystemSettingRepository(db).Set(domain.SettingKeyTimezone, "UTC"); err != nil {
+		t.Fatalf("set timezone: %v", err)
+	}
+	repo := NewUsageStatsRepository(db)
+
+	cst := time.FixedZone("UTC+8", 8*60*60)
+	hour := func(bucket time.Time) *domain.UsageStats {
+		return &domain.UsageStats{
+			TimeBucket: bucket, Granularity: domain.GranularityHour,
+			ProviderID: 1, ClientType: "claude", Model: "claude-sonnet-4",
+			TotalRequests: 1, SuccessfulRequests: 1, Cost: 10,
+		}
+	}
+	// 小时数据覆盖 UTC+8 的 2024-01-01（UTC 2023-12-31 16:00 起），
+	// 且 UTC 的 2024-01-01 已按新时区重新上卷过一部分
+	stats := []*domain.UsageStats{
+		hour(time.Date(2023, 12, 31, 16, 0, 0, 0, time.UTC)),
+		hour(time.Date(2023, 12, 31, 20, 0, 0, 0, time.UTC)),
+		hour(time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)),
+		{
+			TimeBucket: time.Date(2024, 1, 1, 0, 0, 0, 0, cst), Granularity: domain.GranularityDay,
+			ProviderID: 1, ClientType: "claude", Model: "claude-sonnet-4",
+			TotalRequests: 3, SuccessfulRequests: 3, Cost: 30,
+		},
+		{
+			TimeBucket: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), Granularity: domain.GranularityDay,
+			ProviderID: 1, ClientType: "claude", Model: "claude-sonnet-4",
+			TotalRequests: 1, SuccessfulRequests: 1, Cost: 10,
+		},
+	}
+	if err := repo.BatchUpsert(stats); err != nil {
+		t.Fatalf("BatchUpsert failed: %v", err)
+	}
+
+	if _, err := repo.CompactTimeBuckets(); err != nil {
+		t.Fatalf("CompactTimeBuckets failed: %v", err)
+	}
+
+	var rows []UsageStats
+	if err := db.gorm.Where("granularity = ?", domain.GranularityDay).Order("time_bucket").Find(&rows).Error; err != nil {
+		t.Fatalf("query rows: %v", err)
+	}
+	// 从小时数据重建，不重复计数：12-31 两条、01-01 一条
+	if len(rows) != 2 {
+		t.Fatalf("got %d rows, want 2", len(rows))
+	}
+	if rows[0].TimeBucket != time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC).UnixMilli() || rows[0].TotalRequests != 2 {
+		t.Errorf("rows[0] = %+v", rows[0])
+	}
+	if rows[1].TimeBucket != time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli() || rows[1].TotalRequests != 1 || rows[1].Cost != 10 {
+		t.Errorf("rows[1] = %+v", rows[1])
+	}
+}
+
+func TestRebuildForTimezoneChange(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	settings := NewSystemSettingRepository(db)
+	if err := settings.Set(domain.SettingKeyTimezone, "Asia/Shanghai"); err != nil {
+		t.Fatalf("set timezone: %v", err)
+	}
+	repo := NewUsageStatsRepository(db)
+
+	// 首次运行只记录时区
+	if _, changed, err := repo.rebuildForTimezoneChange(); err != nil || !changed {
+		t.Fatalf("first run = (%v, %v), want (true, nil)", changed, err)
+	}
+
+	cst := time.FixedZone("UTC+8", 8*60*60)
+	row := func(g domain.Granularity, bucket time.Time, requests uint64) *domain.UsageStats {
+		return &domain.UsageStats{
+			TimeBucket: bucket, Granularity: g,
+			ProviderID: 1, ClientType: "claude", Model: "claude-sonnet-4",
+			TotalRequests: requests, SuccessfulRequests: requests, Cost: requests * 10,
+		}
+	}
+	// 按 UTC+8 上卷的 day/month 数据，以及对应的小时数据
+	if err := repo.BatchUpsert([]*domain.UsageStats{
+		row(domain.GranularityHour, time.Date(2024, 1, 1, 2, 0, 0, 0, time.UTC), 1),
+		row(domain.GranularityHour, time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC), 1),
+		row(domain.GranularityHour, time.Date(2024, 1, 1, 20, 0, 0, 0, time.UTC), 1),
+		row(domain.GranularityDay, time.Date(2024, 1, 1, 0, 0, 0, 0, cst), 2),
+		row(domain.GranularityDay, time.Date(2024, 1, 2, 0, 0, 0, 0, cst), 1),
+		row(domain.GranularityMonth, time.Date(2024, 1, 1, 0, 0, 0, 0, cst), 3),
+	}); err != nil {
+		t.Fatalf("BatchUpsert failed: %v", err)
+	}
+
+	// 时区未变化时不做任何事
+	if _, changed, err := repo.rebuildForTimezoneChange(); err != nil || changed {
+		t.Fatalf("unchanged run = (%v, %v), want (false, nil)", changed, err)
+	}
+
+	if err := settings.Set(domain.SettingKeyTimezone, "UTC"); err != nil {
+		t.Fatalf("set timezone: %v", err)
+	}
+	if _, changed, err := repo.rebuildForTimezoneChange(); err != nil || !changed {
+		t.Fatalf("rebuild = (%v, %v), want (true, nil)", changed, err)
+	}
+
+	for _, g := range []domain.Granularity{domain.GranularityDay, domain.GranularityMonth} {
+		var rows []UsageStats
+		if err := db.gorm.Where("granularity = ?", g).Find(&rows).Error; err != nil {
+			t.Fatalf("query rows: %v", err)
+		}
+		// 重建后只剩 UTC 对齐的 2024-01-01 / 2024-01 桶，总数不变
+		if len(rows) != 1 {
+			t.Fatalf("%s: got %d rows, want 1", g, len(rows))
+		}
+		if rows[0].TimeBucket != time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).UnixMilli() || rows[0].TotalRequests != 3 || rows[0].Cost != 30 {
+			t.Errorf("%s: row = bucket %v requests %d cost %d", g, fromTimestamp(rows[0].TimeBucket).UTC(), rows[0].TotalRequests, rows[0].Cost)
+		}
+	}
+
+	recorded, err := settings.Get(domain.SettingKeyStatsTimezone)
+	if err != nil || recorded != "UTC" {
+		t.Errorf("recorded timezone = (%q, %v), want UTC", recorded, err)
+	}
+}
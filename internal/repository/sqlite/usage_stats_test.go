@@ -0,0 +1,180 @@
+package sqlite
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+func TestGetSummaryByModel(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	repo := NewUsageStatsRepository(db)
+
+	// 使用历史月份数据，避免触发实时数据补全
+	bucket := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	stats := []*domain.UsageStats{
+		{
+			TimeBucket: bucket, Granularity: domain.GranularityMonth,
+			ProviderID: 1, ClientType: "claude", Model: "claude-sonnet-4",
+			TotalRequests: 10, SuccessfulRequests: 9, FailedRequests: 1,
+			InputTokens: 1000, OutputTokens: 500, CacheRead: 100, CacheWrite: 50, Cost: 3000,
+			TPSOutputTokens: 500, TPSDurationMs: 5000,
+		},
+		{
+			TimeBucket: bucket, Granularity: domain.GranularityMonth,
+			ProviderID: 2, ClientType: "claude", Model: "claude-sonnet-4",
+			TotalRequests: 10, SuccessfulRequests: 10,
+			InputTokens: 2000, OutputTokens: 1000, Cost: 6000,
+			TPSOutputTokens: 1500, TPSDurationMs: 5000,
+		},
+		{
+			TimeBucket: bucket, Granularity: domain.GranularityMonth,
+			ProviderID: 1, ClientType: "openai", Model: "gpt-4o",
+			TotalRequests: 4, SuccessfulRequests: 2, FailedRequests: 2,
+			InputTokens: 400, OutputTokens: 200, Cost: 800,
+			TPSOutputTokens: 100, TPSDurationMs: 1000,
+		},
+	}
+	if err := repo.BatchUpsert(stats); err != nil {
+		t.Fatalf("BatchUpsert failed: %v", err)
+	}
+
+	start := bucket.Add(-time.Hour)
+	end := bucket.Add(time.Hour)
+	result, err := repo.GetSummaryByModel(repository.UsageStatsFilter{
+		Granularity: domain.GranularityMonth,
+		StartTime:   &start,
+		EndTime:     &end,
+	})
+	if err != nil {
+		t.Fatalf("GetSummaryByModel failed: %v", err)
+	}
+
+	if len(result) != 2 {
+		t.Fatalf("expected 2 models, got %d", len(result))
+	}
+
+	sonnet := result["claude-sonnet-4"]
+	if sonnet == nil {
+		t.Fatal("missing claude-sonnet-4 summary")
+	}
+	if sonnet.TotalRequests != 20 || sonnet.SuccessfulRequests != 19 || sonnet.FailedRequests != 1 {
+		t.Errorf("claude-sonnet-4 requests = %d/%d/%d, want 20/19/1",
+			sonnet.TotalRequests, sonnet.SuccessfulRequests, sonnet.FailedRequests)
+	}
+	if sonnet.TotalInputTokens != 3000 || sonnet.TotalOutputTokens != 1500 {
+		t.Errorf("claude-sonnet-4 tokens = %d/%d, want 3000/1500", sonnet.TotalInputTokens, sonnet.TotalOutputTokens)
+	}
+	if sonnet.TotalCacheRead != 100 || sonnet.TotalCacheWrite != 50 {
+		t.Errorf("claude-sonnet-4 cache = %d/%d, want 100/50", sonnet.TotalCacheRead, sonnet.TotalCacheWrite)
+	}
+	if sonnet.TotalCost != 9000 {
+		t.Errorf("claude-sonnet-4 cost = %d, want 9000", sonnet.TotalCost)
+	}
+	if sonnet.SuccessRate != 95 {
+		t.Errorf("claude-sonnet-4 success rate = %v, want 95", sonnet.SuccessRate)
+	}
+	// 2000 tokens / 10s
+	if sonnet.AvgOutputTPS != 200 {
+		t.Errorf("claude-sonnet-4 avg output TPS = %v, want 200", sonnet.AvgOutputTPS)
+	}
+
+	gpt := result["gpt-4o"]
+	if gpt == nil {
+		t.Fatal("missing gpt-4o summary")
+	}
+	if gpt.TotalRequests != 4 || gpt.TotalCost != 800 || gpt.SuccessRate != 50 {
+		t.Errorf("gpt-4o = %d requests, cost %d, rate %v; want 4, 800, 50",
+			gpt.TotalRequests, gpt.TotalCost, gpt.SuccessRate)
+	}
+
+	if gpt.AvgOutputTPS != 100 {
+		t.Errorf("gpt-4o avg output TPS = %v, want 100", gpt.AvgOutputTPS)
+	}
+
+	// 按 ClientType 汇总同样计算平均输出速度
+	byClient, err := repo.GetSummaryByClientType(repository.UsageStatsFilter{
+		Granularity: domain.GranularityMonth,
+		StartTime:   &start,
+		EndTime:     &end,
+	})
+	if err != nil {
+		t.Fatalf("GetSummaryByClientType failed: %v", err)
+	}
+	if c, o := byClient["claude"], byClient["openai"]; c == nil || o == nil || c.AvgOutputTPS != 200 || o.AvgOutputTPS != 100 {
+		t.Errorf("by client type = %+v / %+v, want avg output TPS 200 / 100", c, o)
+	}
+
+	// 模型过滤条件同样生效
+	model := "gpt-4o"
+	filtered, err := repo.GetSummaryByModel(repository.UsageStatsFilter{
+		Granularity: domain.GranularityMonth,
+		StartTime:   &start,
+		EndTime:     &end,
+		Model:       &model,
+	})
+	if err != nil {
+		t.Fatalf("GetSummaryByModel with model filter failed: %v", err)
+	}
+	if len(filtered) != 1 || filtered["gpt-4o"] == nil {
+		t.Errorf("expected only gpt-4o in filtered result, got %v", filtered)
+	}
+}
+
+func TestQueryProjectTimezoneRebucket(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	project := &domain.Project{Name: "us-team", Slug: "us-team", Timezone: "America/New_York"}
+	if err := NewProjectRepository(db).Create(project); err != nil {
+		t.Fatalf("Create project failed: %v", err)
+	}
+	repo := NewUsageStatsRepository(db)
+
+	// 两个小时桶在全局时区（Asia/Shanghai）属于不同的天，在 America/New_York 属于同一天
+	day := time.Now().UTC().AddDate(0, 0, -3)
+	first := time.Date(day.Year(), day.Month(), day.Day(), 15, 0, 0, 0, time.UTC)
+	second := first.Add(2 * time.Hour)
+	var stats []*domain.UsageStats
+	for _, bucket := range []time.Time{first, second} {
+		stats = append(stats, &domain.UsageStats{
+			TimeBucket: bucket, Granularity: domain.GranularityHour,
+			ProviderID: 1, ProjectID: project.ID, ClientType: "claude", Model: "claude-sonnet-4",
+			TotalRequests: 1, SuccessfulRequests: 1, InputTokens: 100,
+		})
+	}
+	if err := repo.BatchUpsert(stats); err != nil {
+		t.Fatalf("BatchUpsert failed: %v", err)
+	}
+
+	start := first.AddDate(0, 0, -1)
+	end := first.AddDate(0, 0, 1)
+	result, err := repo.Query(repository.UsageStatsFilter{
+		Granularity: domain.GranularityDay,
+		StartTime:   &start,
+		EndTime:     &end,
+		ProjectID:   &project.ID,
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	if len(result) != 1 {
+		t.Fatalf("expected 1 day bucket in project timezone, got %d", len(result))
+	}
+	ny, _ := time.LoadLocation("America/New_York")
+	wantBucket := time.Date(first.In(ny).Year(), first.In(ny).Month(), first.In(ny).Day(), 0, 0, 0, 0, ny)
+	if !result[0].TimeBucket.Equal(wantBucket) {
+		t.Errorf("bucket = %v, want %v", result[0].TimeBucket, wantBucket)
+	}
+	if result[0].TotalRequests != 2 || result[0].InputTokens != 200 {
+		t.Errorf("requests/tokens = %d/%d, want 2/200", result[0].TotalRequests, result[0].InputTokens)
+	}
+}
@@ -0,0 +1,186 @@
+package sqlite
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"gorm.io/gorm"
+)
+
+const (
+	// writeFailureThreshold 连续多少次持续性写入失败后进入降级
+	writeFailureThreshold = 3
+	// writeProbeInterval 降级期间探测写入恢复的间隔
+	writeProbeInterval = 15 * time.Second
+)
+
+// persistentWriteErrors are error fragments that mean the storage itself can't
+// take writes (read-only file or mount, full disk, I/O errors, lost
+// permissions), across SQLite, MySQL and PostgreSQL.
+var persistentWriteErrors = []string{
+	"readonly",
+	"read-only",
+	"read only",
+	"disk is full",
+	"no space left",
+	"could not extend file",
+	"disk i/o error",
+	"unable to open database file",
+	"permission denied",
+}
+
+// WriteHealth watches every GORM write and switches to degraded mode when the
+// database persistently rejects writes.
+//
+// Transient vs persistent: only errors matching persistentWriteErrors count as
+// storage failures. Lock contention ("database is locked", busy timeouts),
+// constraint violations, cancelled contexts and record-not-found are ignored:
+// they are per-statement problems and the next write can succeed. A storage
+// failure also only degrades after writeFailureThreshold of them in a row;
+// any successful write resets the count, so a single failed write during a
+// brief disk hiccup doesn't take the proxy down.
+//
+// While degraded, a probe writes to write_probes every writeProbeInterval.
+// The first successful write (probe or otherwise) leaves degraded mode.
+type WriteHealth struct {
+	db *gorm.DB
+
+	mu          sync.Mutex
+	consecutive int
+	degraded    bool
+	reason      string
+	since       time.Time
+	probing     bool
+
+	// 累计写入次数（create/update/delete；Raw 语句仅在失败时计入），用于观察写入压力
+	writes      atomic.Int64
+	writeErrors atomic.Int64
+}
+
+func newWriteHealth(db *gorm.DB) *WriteHealth {
+	return &WriteHealth{db: db}
+}
+
+// WriteHealth returns the database write health monitor
+func (d *DB) WriteHealth() *WriteHealth {
+	return d.writeHealth
+}
+
+// registerCallbacks hooks the monitor into GORM's create/update/delete and raw
+// (Exec) callbacks. Raw statements are also used for reads, so only their
+// failures are observed.
+func (h *WriteHealth) registerCallbacks() error {
+	cb := h.db.Callback()
+	if err := cb.Create().After("gorm:create").Register("maxx:write_health", h.observeWrite); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("maxx:write_health", h.observeWrite); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("maxx:write_health", h.observeWrite); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("maxx:write_health", func(tx *gorm.DB) {
+		if tx.Error != nil {
+			h.observeWrite(tx)
+		}
+	})
+}
+
+func (h *WriteHealth) observeWrite(tx *gorm.DB) {
+	h.writes.Add(1)
+	if tx.Error != nil {
+		h.writeErrors.Add(1)
+	}
+	if tx.Error == nil {
+		h.recordSuccess()
+		return
+	}
+	if isPersistentWriteError(tx.Error) {
+		h.recordFailure(tx.Error)
+	}
+}
+
+func (h *WriteHealth) recordSuccess() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.consecutive = 0
+	if h.degraded {
+		log.Printf("[DB] Writes succeed again after %s, leaving degraded mode", time.Since(h.since).Round(time.Second))
+		h.degraded = false
+		h.reason = ""
+	}
+}
+
+func (h *WriteHealth) recordFailure(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.consecutive++
+	h.reason = err.Error()
+	if h.degraded || h.consecutive < writeFailureThreshold {
+		return
+	}
+	h.degraded = true
+	h.since = time.Now()
+	log.Printf("[DB] %d consecutive write failures, entering degraded mode (proxy requests get 503): %v", h.consecutive, err)
+	if !h.probing {
+		h.probing = true
+		go h.probe()
+	}
+}
+
+// probe retries a tiny write until one succeeds
+func (h *WriteHealth) probe() {
+	ticker := time.NewTicker(writeProbeInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.db.Save(&WriteProbe{ID: 1, CheckedAt: time.Now().UnixMilli()})
+
+		h.mu.Lock()
+		if !h.degraded {
+			h.probing = false
+			h.mu.Unlock()
+			return
+		}
+		h.mu.Unlock()
+	}
+}
+
+// Status returns the current write health
+func (h *WriteHealth) Status() domain.StorageHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	status := domain.StorageHealth{
+		Degraded:            h.degraded,
+		ConsecutiveFailures: h.consecutive,
+	}
+	if h.degraded {
+		since := h.since
+		status.Since = &since
+		status.Reason = h.reason
+	}
+	return status
+}
+
+// WriteCounts returns the number of writes observed since startup and how many of them failed
+func (h *WriteHealth) WriteCounts() (writes, failed int64) {
+	return h.writes.Load(), h.writeErrors.Load()
+}
+
+func isPersistentWriteError(err error) bool {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range persistentWriteErrors {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestIsPersistentWriteError(t *testing.T) {
+	tests := []struct {
+		err  string
+		want bool
+	}{
+		{"attempt to write a readonly database (8)", true},
+		{"database or disk is full (13)", true},
+		{"disk I/O error (10)", true},
+		{"Error 1290: The MySQL server is running with the --read-only option", true},
+		{"pq: could not extend file \"base/16384/16385\": No space left on device", true},
+		{"database is locked (5)", false},
+		{"UNIQUE constraint failed: sessions.session_id", false},
+		{"context canceled", false},
+	}
+	for _, tt := range tests {
+		if got := isPersistentWriteError(errors.New(tt.err)); got != tt.want {
+			t.Errorf("isPersistentWriteError(%q) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+	if isPersistentWriteError(gorm.ErrRecordNotFound) {
+		t.Error("record not found classified as persistent")
+	}
+}
+
+func TestWriteHealthDegradesAndRecovers(t *testing.T) {
+	db, err := NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	h := db.WriteHealth()
+	readonly := errors.New("attempt to write a readonly database (8)")
+
+	for i := 0; i < writeFailureThreshold-1; i++ {
+		h.recordFailure(readonly)
+	}
+	// 阈值前的一次成功写入会重新计数
+	h.recordSuccess()
+	for i := 0; i < writeFailureThreshold-1; i++ {
+		h.recordFailure(readonly)
+	}
+	if h.Status().Degraded {
+		t.Fatal("degraded before reaching the threshold of consecutive failures")
+	}
+
+	h.recordFailure(readonly)
+	status := h.Status()
+	if !status.Degraded || status.Since == nil || status.Reason != readonly.Error() {
+		t.Fatalf("status = %+v, want degraded", status)
+	}
+
+	// 任意一次成功写入（经 GORM 回调）即退出降级
+	if err := db.GormDB().Save(&WriteProbe{ID: 1, CheckedAt: 1}).Error; err != nil {
+		t.Fatalf("probe write failed: %v", err)
+	}
+	if status := h.Status(); status.Degraded || status.ConsecutiveFailures != 0 {
+		t.Errorf("status after successful write = %+v, want healthy", status)
+	}
+}
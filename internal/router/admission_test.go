@@ -0,0 +1,46 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/cooldown"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestCooldownRetryAfter(t *testing.T) {
+	r, first := newTestRouter(t)
+	r.cooldownManager = cooldown.NewManager()
+	second := &domain.Provider{Name: "second", Type: hotReloadProviderType}
+	createRoutedProvider(t, r.providerRepo, r.routeRepo, second, 2)
+
+	if _, blocked := r.CooldownRetryAfter(domain.ClientTypeClaude, 0); blocked {
+		t.Fatal("blocked with no cooldowns")
+	}
+
+	r.cooldownManager.SetCooldownDuration(first.ID, string(domain.ClientTypeClaude), 10*time.Minute)
+	if _, blocked := r.CooldownRetryAfter(domain.ClientTypeClaude, 0); blocked {
+		t.Fatal("blocked while second provider is available")
+	}
+
+	// 所有 Provider 都在冷却：返回最早结束的冷却
+	r.cooldownManager.SetCooldownDuration(second.ID, "", 2*time.Minute)
+	retryAfter, blocked := r.CooldownRetryAfter(domain.ClientTypeClaude, 0)
+	if !blocked || retryAfter <= time.Minute || retryAfter > 2*time.Minute {
+		t.Fatalf("CooldownRetryAfter = %v, %v; want ~2m, true", retryAfter, blocked)
+	}
+
+	// 排空的 Provider 不可用，但不参与 Retry-After 计算
+	second.Draining = true
+	if err := r.providerRepo.Update(second); err != nil {
+		t.Fatalf("update provider: %v", err)
+	}
+	retryAfter, blocked = r.CooldownRetryAfter(domain.ClientTypeClaude, 0)
+	if !blocked || retryAfter <= 2*time.Minute {
+		t.Errorf("CooldownRetryAfter = %v, %v; want ~10m, true", retryAfter, blocked)
+	}
+
+	if _, blocked := r.CooldownRetryAfter(domain.ClientTypeOpenAI, 0); blocked {
+		t.Error("blocked for a client type without routes")
+	}
+}
@@ -0,0 +1,40 @@
+package router
+
+import (
+	"sync/atomic"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// BeginAttempt counts an upstream attempt on the provider as in flight until
+// the returned func is called
+func (r *Router) BeginAttempt(providerID uint64) (done func()) {
+	v, _ := r.inFlight.LoadOrStore(providerID, new(atomic.Int64))
+	counter := v.(*atomic.Int64)
+	counter.Add(1)
+	return func() { counter.Add(-1) }
+}
+
+// ProviderInFlight returns the number of upstream attempts running on the provider
+func (r *Router) ProviderInFlight(providerID uint64) int64 {
+	if v, ok := r.inFlight.Load(providerID); ok {
+		return v.(*atomic.Int64).Load()
+	}
+	return 0
+}
+
+// ProviderDrainStatus reports whether the provider is draining and how many
+// requests still run on it. Returns nil if the provider doesn't exist.
+func (r *Router) ProviderDrainStatus(providerID uint64) *domain.ProviderDrainStatus {
+	p := r.GetProvider(providerID)
+	if p == nil {
+		return nil
+	}
+	inFlight := r.ProviderInFlight(providerID)
+	return &domain.ProviderDrainStatus{
+		ProviderID: providerID,
+		Draining:   p.Draining,
+		InFlight:   inFlight,
+		Drained:    p.Draining && inFlight == 0,
+	}
+}
@@ -0,0 +1,46 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestMatchSkipsDrainingProvider(t *testing.T) {
+	r, first := newTestRouter(t)
+	createRoutedProvider(t, r.providerRepo, r.routeRepo, &domain.Provider{Name: "standby", Type: hotReloadProviderType}, 2)
+	if err := r.InitAdapters(); err != nil {
+		t.Fatalf("InitAdapters failed: %v", err)
+	}
+
+	// 排空前发起的请求仍在进行中
+	done := r.BeginAttempt(first.ID)
+
+	first.Draining = true
+	if err := r.providerRepo.Update(first); err != nil {
+		t.Fatalf("update provider: %v", err)
+	}
+
+	matched, err := r.Match(&MatchContext{ClientType: domain.ClientTypeClaude})
+	if err != nil {
+		t.Fatalf("Match failed: %v", err)
+	}
+	if len(matched) != 1 || matched[0].Provider.Name != "standby" {
+		t.Fatalf("matched %d routes, want only standby", len(matched))
+	}
+
+	status := r.ProviderDrainStatus(first.ID)
+	if status == nil || !status.Draining || status.InFlight != 1 || status.Drained {
+		t.Fatalf("status while request in flight = %+v", status)
+	}
+
+	done()
+	status = r.ProviderDrainStatus(first.ID)
+	if status.InFlight != 0 || !status.Drained {
+		t.Errorf("status after request finished = %+v, want drained", status)
+	}
+
+	if r.ProviderDrainStatus(9999) != nil {
+		t.Error("ProviderDrainStatus for unknown provider should be nil")
+	}
+}
@@ -0,0 +1,98 @@
+package router
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/adapter/provider"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// ProviderCheckResult is the outcome of a single provider health check
+type ProviderCheckResult struct {
+	ProviderID   uint64
+	ProviderName string
+	// Skipped is true when the adapter does not implement provider.HealthChecker
+	Skipped bool
+	// Disabled is true when no enabled route uses the provider; such providers are not checked
+	Disabled bool
+	Err      error
+	Duration time.Duration
+}
+
+// CheckProviders concurrently runs health checks for all providers with an initialized adapter.
+// Providers that no enabled route uses are reported as Disabled without being checked.
+// Checks still running when ctx is done are reported with ctx.Err().
+func (r *Router) CheckProviders(ctx context.Context) []*ProviderCheckResult {
+	r.mu.RLock()
+	adapters := make(map[uint64]provider.ProviderAdapter, len(r.adapters))
+	for id, a := range r.adapters {
+		adapters[id] = a
+	}
+	r.mu.RUnlock()
+
+	var (
+		wg      sync.WaitGroup
+		results []*ProviderCheckResult
+	)
+	routed := r.enabledProviderIDs()
+	for _, p := range r.providerRepo.GetAll() {
+		a, ok := adapters[p.ID]
+		if !ok {
+			continue
+		}
+		result := &ProviderCheckResult{ProviderID: p.ID, ProviderName: p.Name}
+		results = append(results, result)
+
+		if !routed[p.ID] {
+			result.Disabled = true
+			continue
+		}
+
+		checker, ok := a.(provider.HealthChecker)
+		if !ok {
+			result.Skipped = true
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			done := make(chan error, 1)
+			go func() { done <- checker.CheckHealth(ctx) }()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+
+			result.Err = err
+			result.Duration = time.Since(start)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].ProviderID < results[j].ProviderID })
+	return results
+}
+
+// enabledProviderIDs returns the providers used by at least one enabled route
+// whose client type is not disabled on the provider
+func (r *Router) enabledProviderIDs() map[uint64]bool {
+	providers := make(map[uint64]*domain.Provider)
+	for _, p := range r.providerRepo.GetAll() {
+		providers[p.ID] = p
+	}
+	ids := make(map[uint64]bool)
+	for _, route := range r.routeRepo.GetAll() {
+		if p := providers[route.ProviderID]; route.IsEnabled && p != nil && !p.IsClientTypeDisabled(route.ClientType) {
+			ids[p.ID] = true
+		}
+	}
+	return ids
+}
@@ -0,0 +1,304 @@
+package router
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/cooldown"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+	"github.com/awsl-project/maxx/internal/repository/cached"
+	"github.com/awsl-project/maxx/internal/stats"
+)
+
+const (
+	// HealthScoreWindow 成功率与延迟的统计窗口
+	HealthScoreWindow = 15 * time.Minute
+	// HealthScoreRefreshInterval 成功率、延迟与配额的刷新间隔；冷却状态每次读取时实时计算
+	HealthScoreRefreshInterval = 30 * time.Second
+	// DefaultHealthLatencySLO 未配置 provider_health_latency_slo_ms 时的延迟 SLO
+	DefaultHealthLatencySLO = 30 * time.Second
+)
+
+// healthSnapshot 定期刷新的单个 Provider 的统计与配额
+type healthSnapshot struct {
+	requests        uint64
+	successful      uint64
+	totalDurationMs uint64
+	quotaPercent    float64
+	quotaSource     string
+	quotaKnown      bool
+}
+
+// HealthScorer 计算各 Provider 的 0-100 综合健康分（见 stats.HealthScore）：
+// 最近 HealthScoreWindow 内的成功率与平均耗时、冷却状态和剩余配额。
+// 统计与配额由 Run 每 HealthScoreRefreshInterval 刷新一次，路由时只读快照，不查询数据库
+type HealthScorer struct {
+	providerRepo    *cached.ProviderRepository
+	usageStatsRepo  repository.UsageStatsRepository
+	settingRepo     repository.SystemSettingRepository
+	quotaSource     QuotaSource
+	cooldownManager *cooldown.Manager
+	now             func() time.Time
+
+	mu        sync.RWMutex
+	snapshots map[uint64]*healthSnapshot
+	weights   stats.HealthWeights
+	sloMs     float64
+	updatedAt time.Time
+}
+
+// NewHealthScorer creates a health scorer; quotaSource may be nil
+func NewHealthScorer(
+	providerRepo *cached.ProviderRepository,
+	usageStatsRepo repository.UsageStatsRepository,
+	settingRepo repository.SystemSettingRepository,
+	quotaSource QuotaSource,
+) *HealthScorer {
+	return &HealthScorer{
+		providerRepo:    providerRepo,
+		usageStatsRepo:  usageStatsRepo,
+		settingRepo:     settingRepo,
+		quotaSource:     quotaSource,
+		cooldownManager: cooldown.Default(),
+		now:             time.Now,
+		snapshots:       make(map[uint64]*healthSnapshot),
+		weights:         stats.DefaultHealthWeights(),
+		sloMs:           float64(DefaultHealthLatencySLO.Milliseconds()),
+	}
+}
+
+// Run refreshes the scores immediately and then every interval until ctx is done
+func (s *HealthScorer) Run(ctx context.Context, interval time.Duration) {
+	if err := s.Refresh(); err != nil {
+		log.Printf("[HealthScore] Refresh failed: %v", err)
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(); err != nil {
+				log.Printf("[HealthScore] Refresh failed: %v", err)
+			}
+		}
+	}
+}
+
+// Refresh reloads the weights/SLO settings, the usage stats of the last
+// HealthScoreWindow and the remaining quota of every provider
+func (s *HealthScorer) Refresh() error {
+	weights, sloMs := stats.DefaultHealthWeights(), float64(DefaultHealthLatencySLO.Milliseconds())
+	if s.settingRepo != nil {
+		if v, _ := s.settingRepo.Get(domain.SettingKeyProviderHealthWeights); v != "" {
+			if w, err := stats.ParseHealthWeights(v); err == nil {
+				weights = w
+			}
+		}
+		if v, _ := s.settingRepo.Get(domain.SettingKeyProviderHealthLatencySLOMs); v != "" {
+			if ms, err := strconv.Atoi(v); err == nil && ms > 0 {
+				sloMs = float64(ms)
+			}
+		}
+	}
+
+	snapshots := make(map[uint64]*healthSnapshot)
+	snapshot := func(id uint64) *healthSnapshot {
+		if snap, ok := snapshots[id]; ok {
+			return snap
+		}
+		snap := &healthSnapshot{}
+		snapshots[id] = snap
+		return snap
+	}
+
+	now := s.now()
+	if s.usageStatsRepo != nil {
+		start := now.Add(-HealthScoreWindow)
+		rows, err := s.usageStatsRepo.Query(repository.UsageStatsFilter{Granularity: domain.GranularityMinute, StartTime: &start})
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			if row.ProviderID == 0 {
+				continue
+			}
+			snap := snapshot(row.ProviderID)
+			snap.requests += row.TotalRequests
+			snap.successful += row.SuccessfulRequests
+			snap.totalDurationMs += row.TotalDurationMs
+		}
+	}
+	if s.quotaSource != nil {
+		for _, p := range s.providerRepo.GetAll() {
+			if percent, source, ok := s.quotaSource.RemainingQuota(p, ""); ok {
+				snap := snapshot(p.ID)
+				snap.quotaPercent, snap.quotaSource, snap.quotaKnown = percent, source, true
+			}
+		}
+	}
+
+	s.mu.Lock()
+	s.snapshots = snapshots
+	s.weights = weights
+	s.sloMs = sloMs
+	s.updatedAt = now
+	s.mu.Unlock()
+	return nil
+}
+
+// Scores returns the health score of every provider, ordered by provider ID
+func (s *HealthScorer) Scores() []*domain.ProviderHealthScore {
+	cooldowns := s.cooldownClientTypes()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	providers := s.providerRepo.GetAll()
+	result := make([]*domain.ProviderHealthScore, 0, len(providers))
+	for _, p := range providers {
+		snap := s.snapshots[p.ID]
+		if snap == nil {
+			snap = &healthSnapshot{}
+		}
+		score := &domain.ProviderHealthScore{
+			ProviderID:          p.ID,
+			Name:                p.Name,
+			Score:               round1(stats.HealthScore(s.signals(snap, cooldowns[p.ID]), s.weights, s.sloMs)),
+			Requests:            snap.requests,
+			CooldownClientTypes: cooldowns[p.ID],
+			UpdatedAt:           s.updatedAt,
+		}
+		if score.CooldownClientTypes == nil {
+			score.CooldownClientTypes = []string{}
+		}
+		if snap.requests >= stats.HealthMinSamples {
+			rate := round1(float64(snap.successful) / float64(snap.requests) * 100)
+			latency := round1(float64(snap.totalDurationMs) / float64(snap.requests))
+			score.SuccessRate, score.AvgLatencyMs = &rate, &latency
+		}
+		if snap.quotaKnown {
+			percent := round1(snap.quotaPercent)
+			score.QuotaPercent, score.QuotaSource = &percent, snap.quotaSource
+		}
+		result = append(result, score)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ProviderID < result[j].ProviderID })
+	return result
+}
+
+// scoreMap returns the current score of every provider keyed by provider ID
+func (s *HealthScorer) scoreMap() map[uint64]float64 {
+	cooldowns := s.cooldownClientTypes()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	scores := make(map[uint64]float64)
+	for id := range s.providerRepo.GetAll() {
+		snap := s.snapshots[id]
+		if snap == nil {
+			snap = &healthSnapshot{}
+		}
+		scores[id] = stats.HealthScore(s.signals(snap, cooldowns[id]), s.weights, s.sloMs)
+	}
+	return scores
+}
+
+func (s *HealthScorer) signals(snap *healthSnapshot, cooldownClientTypes []string) stats.HealthSignals {
+	signals := stats.HealthSignals{
+		Requests:           snap.requests,
+		SuccessfulRequests: snap.successful,
+		TotalDurationMs:    snap.totalDurationMs,
+		QuotaPercent:       snap.quotaPercent,
+		QuotaKnown:         snap.quotaKnown,
+	}
+	// 全局冷却计为完全冷却，仅部分 ClientType 冷却计为一半
+	for _, ct := range cooldownClientTypes {
+		if ct == "" {
+			signals.Cooldown = 1
+			break
+		}
+		signals.Cooldown = 0.5
+	}
+	return signals
+}
+
+// cooldownClientTypes returns the client types each provider is cooling down for
+func (s *HealthScorer) cooldownClientTypes() map[uint64][]string {
+	result := make(map[uint64][]string)
+	for key := range s.cooldownManager.GetAllCooldowns() {
+		result[key.ProviderID] = append(result[key.ProviderID], key.ClientType)
+	}
+	for _, types := range result {
+		sort.Strings(types)
+	}
+	return result
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// SetHealthScorer enables the health_weighted routing strategy and provider health scores
+func (r *Router) SetHealthScorer(scorer *HealthScorer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.healthScorer = scorer
+}
+
+// ProviderHealthScores returns the health score of every provider
+func (r *Router) ProviderHealthScores() []*domain.ProviderHealthScore {
+	r.mu.RLock()
+	scorer := r.healthScorer
+	r.mu.RUnlock()
+	if scorer == nil {
+		return []*domain.ProviderHealthScore{}
+	}
+	return scorer.Scores()
+}
+
+// orderByHealth draws routes without replacement, weighted by the square of
+// their provider's health score, so healthier providers are tried first more
+// often while weaker ones still get some traffic to show recovery. Routes
+// whose provider scores 0 go last. Routes are expected in priority order,
+// which breaks ties and is kept among zero-score routes.
+func orderByHealth(routes []*domain.Route, scores map[uint64]float64, rnd func() float64) {
+	var pool, zero []*domain.Route
+	for _, route := range routes {
+		if scores[route.ProviderID] <= 0 {
+			zero = append(zero, route)
+		} else {
+			pool = append(pool, route)
+		}
+	}
+
+	ordered := make([]*domain.Route, 0, len(routes))
+	for len(pool) > 0 {
+		var total float64
+		for _, route := range pool {
+			score := scores[route.ProviderID]
+			total += score * score
+		}
+		pick := len(pool) - 1
+		target := rnd() * total
+		for i, route := range pool {
+			score := scores[route.ProviderID]
+			target -= score * score
+			if target < 0 {
+				pick = i
+				break
+			}
+		}
+		ordered = append(ordered, pool[pick])
+		pool = append(pool[:pick], pool[pick+1:]...)
+	}
+	copy(routes, append(ordered, zero...))
+}
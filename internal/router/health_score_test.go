@@ -0,0 +1,96 @@
+package router
+
+import (
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/cooldown"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+// healthUsageRepo 返回固定的分钟统计
+type healthUsageRepo struct {
+	repository.UsageStatsRepository
+	rows []*domain.UsageStats
+}
+
+func (r *healthUsageRepo) Query(filter repository.UsageStatsFilter) ([]*domain.UsageStats, error) {
+	return r.rows, nil
+}
+
+func TestHealthWeightedRouting(t *testing.T) {
+	r, flaky := newTestRouter(t)
+	healthy := &domain.Provider{Name: "healthy", Type: hotReloadProviderType}
+	createRoutedProvider(t, r.providerRepo, r.routeRepo, healthy, 2)
+	if err := r.InitAdapters(); err != nil {
+		t.Fatalf("InitAdapters failed: %v", err)
+	}
+	if err := r.routingStrategyRepo.Create(&domain.RoutingStrategy{Type: domain.RoutingStrategyHealthWeighted}); err != nil {
+		t.Fatalf("create strategy: %v", err)
+	}
+
+	usage := &healthUsageRepo{rows: []*domain.UsageStats{
+		// flaky 成功率 50%，平均耗时 1s（在 SLO 内）
+		{ProviderID: flaky.ID, TotalRequests: 6, SuccessfulRequests: 3, TotalDurationMs: 6000},
+		{ProviderID: flaky.ID, TotalRequests: 4, SuccessfulRequests: 2, TotalDurationMs: 4000},
+		// healthy 全部成功，平均耗时 60s，为 SLO 的两倍
+		{ProviderID: healthy.ID, TotalRequests: 10, SuccessfulRequests: 10, TotalDurationMs: 600000},
+	}}
+	scorer := NewHealthScorer(r.providerRepo, usage, nil, fakeQuotaSource{"healthy": 50})
+	scorer.cooldownManager = cooldown.NewManager()
+	if err := scorer.Refresh(); err != nil {
+		t.Fatalf("Refresh failed: %v", err)
+	}
+
+	// flaky:   (40×0.5 + 20×1 + 20×1) / 80 = 75（无配额数据，配额不参与评分）
+	// healthy: (40×1 + 20×0.5 + 20×1 + 20×0.5) / 100 = 80
+	scores := scorer.Scores()
+	if len(scores) != 2 || scores[0].Score != 75 || scores[1].Score != 80 {
+		t.Fatalf("scores = %+v, %+v; want 75 and 80", scores[0], scores[1])
+	}
+	if s := scores[1]; s.SuccessRate == nil || *s.SuccessRate != 100 || *s.AvgLatencyMs != 60000 || *s.QuotaPercent != 50 || s.QuotaSource != QuotaSourceRateLimit {
+		t.Errorf("healthy detail = %+v", s)
+	}
+
+	// 部分 ClientType 冷却扣一半冷却分：(40 + 10 + 10 + 10) / 100 = 70
+	scorer.cooldownManager.SetCooldownDuration(healthy.ID, string(domain.ClientTypeOpenAI), time.Minute)
+	if s := scorer.Scores()[1]; s.Score != 70 || len(s.CooldownClientTypes) != 1 || s.CooldownClientTypes[0] != "openai" {
+		t.Errorf("healthy with openai cooldown = %+v, want score 70", s)
+	}
+
+	names := func(rnd float64) []string {
+		r.rand = func() float64 { return rnd }
+		matched, err := r.Match(&MatchContext{ClientType: domain.ClientTypeClaude})
+		if err != nil {
+			t.Fatalf("Match failed: %v", err)
+		}
+		var got []string
+		for _, m := range matched {
+			got = append(got, m.Provider.Name)
+		}
+		return got
+	}
+
+	// 未设置 HealthScorer 时按优先级排序
+	if got := names(0.9); got[0] != "v1" {
+		t.Errorf("order without scorer = %v, want v1 first", got)
+	}
+
+	// 权重为健康分的平方：75² = 5625，70² = 4900，rnd=0.9 落在 healthy 的区间
+	r.SetHealthScorer(scorer)
+	if got := names(0.9); got[0] != "healthy" || got[1] != "v1" {
+		t.Errorf("order with rnd=0.9 = %v, want [healthy v1]", got)
+	}
+	if got := names(0.1); got[0] != "v1" {
+		t.Errorf("order with rnd=0.1 = %v, want v1 first", got)
+	}
+}
+
+func TestOrderByHealthPutsZeroScoresLast(t *testing.T) {
+	routes := []*domain.Route{{ID: 1, ProviderID: 1}, {ID: 2, ProviderID: 2}, {ID: 3, ProviderID: 3}}
+	orderByHealth(routes, map[uint64]float64{1: 0, 2: 40, 3: 90}, func() float64 { return 0 })
+	if routes[0].ID != 2 || routes[1].ID != 3 || routes[2].ID != 1 {
+		t.Errorf("order = [%d %d %d], want [2 3 1]", routes[0].ID, routes[1].ID, routes[2].ID)
+	}
+}
@@ -0,0 +1,111 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/adapter/provider"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository/cached"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+const healthCheckProviderType = "health-check-test"
+
+// healthCheckAdapter fails its health check when the provider name is "down"
+type healthCheckAdapter struct {
+	name string
+}
+
+func init() {
+	provider.RegisterAdapterFactory(healthCheckProviderType, func(p *domain.Provider) (provider.ProviderAdapter, error) {
+		return &healthCheckAdapter{name: p.Name}, nil
+	})
+}
+
+func (a *healthCheckAdapter) SupportedClientTypes() []domain.ClientType {
+	return []domain.ClientType{domain.ClientTypeClaude}
+}
+
+func (a *healthCheckAdapter) Execute(ctx context.Context, w http.ResponseWriter, req *http.Request, p *domain.Provider) error {
+	return nil
+}
+
+func (a *healthCheckAdapter) CheckHealth(ctx context.Context) error {
+	if a.name == "down" {
+		return errors.New("unreachable")
+	}
+	return nil
+}
+
+func TestCheckProvidersSkipsDisabledProviders(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	providerRepo := cached.NewProviderRepository(sqlite.NewProviderRepository(db))
+	routeRepo := cached.NewRouteRepository(sqlite.NewRouteRepository(db))
+
+	providers := []struct {
+		provider     *domain.Provider
+		routeEnabled bool
+	}{
+		{&domain.Provider{Name: "up", Type: healthCheckProviderType}, true},
+		{&domain.Provider{Name: "down", Type: healthCheckProviderType}, true},
+		{&domain.Provider{Name: "no-checker", Type: hotReloadProviderType}, true},
+		{&domain.Provider{Name: "disabled", Type: healthCheckProviderType}, false},
+		{&domain.Provider{Name: "client-type-disabled", Type: healthCheckProviderType, DisabledClientTypes: []domain.ClientType{domain.ClientTypeClaude}}, true},
+		{&domain.Provider{Name: "unrouted", Type: healthCheckProviderType}, false},
+	}
+	for i, p := range providers {
+		if err := providerRepo.Create(p.provider); err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+		if p.provider.Name == "unrouted" {
+			continue
+		}
+		route := &domain.Route{IsEnabled: p.routeEnabled, ClientType: domain.ClientTypeClaude, ProviderID: p.provider.ID, Position: i}
+		if err := routeRepo.Create(route); err != nil {
+			t.Fatalf("create route: %v", err)
+		}
+	}
+
+	r := NewRouter(routeRepo, providerRepo,
+		cached.NewRoutingStrategyRepository(sqlite.NewRoutingStrategyRepository(db)),
+		cached.NewRetryConfigRepository(sqlite.NewRetryConfigRepository(db)),
+		cached.NewProjectRepository(sqlite.NewProjectRepository(db)),
+	)
+	if err := r.InitAdapters(); err != nil {
+		t.Fatalf("InitAdapters failed: %v", err)
+	}
+
+	want := map[string]string{
+		"up":                   "ok",
+		"down":                 "failed",
+		"no-checker":           "skipped",
+		"disabled":             "disabled",
+		"client-type-disabled": "disabled",
+		"unrouted":             "disabled",
+	}
+	results := r.CheckProviders(context.Background())
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d", len(results), len(want))
+	}
+	for _, result := range results {
+		got := "ok"
+		switch {
+		case result.Disabled:
+			got = "disabled"
+		case result.Skipped:
+			got = "skipped"
+		case result.Err != nil:
+			got = "failed"
+		}
+		if got != want[result.ProviderName] {
+			t.Errorf("%s = %s, want %s", result.ProviderName, got, want[result.ProviderName])
+		}
+	}
+}
@@ -0,0 +1,278 @@
+package router
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/ratelimit"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+// Quota sources reported in ProviderGroupMember.QuotaSource
+const (
+	QuotaSourceCodex       = "codex"
+	QuotaSourceAntigravity = "antigravity"
+	QuotaSourceRateLimit   = "rateLimit"
+)
+
+// QuotaSource reports how much quota a provider has left
+type QuotaSource interface {
+	// RemainingQuota returns the remaining quota in percent (0-100) for the
+	// model ("" for the whole account) and where it came from. ok is false
+	// when the provider has no quota data.
+	RemainingQuota(p *domain.Provider, model string) (percent float64, source string, ok bool)
+}
+
+// repoQuotaSource reads the quotas refreshed by the Codex/Antigravity tasks,
+// and falls back to the RPM/TPM headroom for providers with a rate limit
+type repoQuotaSource struct {
+	antigravityQuotaRepo repository.AntigravityQuotaRepository
+	codexQuotaRepo       repository.CodexQuotaRepository
+	limiter              *ratelimit.Limiter
+}
+
+// NewQuotaSource creates a QuotaSource backed by the quota repositories
+func NewQuotaSource(antigravityQuotaRepo repository.AntigravityQuotaRepository, codexQuotaRepo repository.CodexQuotaRepository) QuotaSource {
+	return &repoQuotaSource{
+		antigravityQuotaRepo: antigravityQuotaRepo,
+		codexQuotaRepo:       codexQuotaRepo,
+		limiter:              ratelimit.Default(),
+	}
+}
+
+func (s *repoQuotaSource) RemainingQuota(p *domain.Provider, model string) (float64, string, bool) {
+	if p.Config == nil {
+		return 0, "", false
+	}
+	if cfg := p.Config.Codex; cfg != nil && cfg.Email != "" && s.codexQuotaRepo != nil {
+		if quota, err := s.codexQuotaRepo.GetByEmail(cfg.Email); err == nil && quota != nil {
+			if percent, ok := codexRemaining(quota); ok {
+				return percent, QuotaSourceCodex, true
+			}
+		}
+	}
+	if cfg := p.Config.Antigravity; cfg != nil && cfg.Email != "" && s.antigravityQuotaRepo != nil {
+		if quota, err := s.antigravityQuotaRepo.GetByEmail(cfg.Email); err == nil && quota != nil {
+			if percent, ok := antigravityRemaining(quota, model); ok {
+				return percent, QuotaSourceAntigravity, true
+			}
+		}
+	}
+	if remaining, ok := s.limiter.Remaining(p.ID, p.Config.RateLimit); ok {
+		return remaining * 100, QuotaSourceRateLimit, true
+	}
+	return 0, "", false
+}
+
+// codexRemaining 取主窗口（5 小时）与次级窗口（周）中剩余较少者
+func codexRemaining(quota *domain.CodexQuota) (float64, bool) {
+	if quota.IsForbidden {
+		return 0, true
+	}
+	remaining, ok := 100.0, false
+	for _, w := range []*domain.CodexQuotaWindow{quota.PrimaryWindow, quota.SecondaryWindow} {
+		if w != nil && w.UsedPercent != nil {
+			remaining = min(remaining, 100-*w.UsedPercent)
+			ok = true
+		}
+	}
+	return max(remaining, 0), ok
+}
+
+// antigravityRemaining 优先使用请求模型的配额，未知模型取所有模型中最少的剩余
+func antigravityRemaining(quota *domain.AntigravityQuota, model string) (float64, bool) {
+	if quota.IsForbidden {
+		return 0, true
+	}
+	if len(quota.Models) == 0 {
+		return 0, false
+	}
+	if model != "" {
+		var best *domain.AntigravityModelQuota
+		for i := range quota.Models {
+			m := &quota.Models[i]
+			if m.Name == model {
+				return float64(m.Percentage), true
+			}
+			if strings.HasPrefix(model, m.Name) && (best == nil || len(m.Name) > len(best.Name)) {
+				best = m
+			}
+		}
+		if best != nil {
+			return float64(best.Percentage), true
+		}
+	}
+	remaining := 100
+	for _, m := range quota.Models {
+		remaining = min(remaining, m.Percentage)
+	}
+	return float64(remaining), true
+}
+
+// SetQuotaSource enables quota-aware balancing within provider groups
+func (r *Router) SetQuotaSource(source QuotaSource) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.quotaSource = source
+}
+
+// groupMember is a matched route of a grouped provider with its quota
+type groupMember struct {
+	slot    int // index in the matched list
+	route   *MatchedRoute
+	percent float64
+	known   bool
+}
+
+// balanceGroups reorders the routes of each provider group within the slots
+// the group occupies, so a group acts as one pool in the strategy's order.
+// Members are drawn by weighted random on remaining quota: fuller accounts
+// are tried first more often, spreading load so all members stay under their
+// limits. Members without quota data weigh as much as the group's average;
+// exhausted members always go last.
+func (r *Router) balanceGroups(matched []*MatchedRoute, requestModel string, trace *domain.RoutingTrace) {
+	if r.quotaSource == nil {
+		return
+	}
+	groups := make(map[string][]*groupMember)
+	var names []string
+	for i, m := range matched {
+		name := m.Provider.GroupName()
+		if name == "" {
+			continue
+		}
+		if _, ok := groups[name]; !ok {
+			names = append(names, name)
+		}
+		member := &groupMember{slot: i, route: m}
+		member.percent, _, member.known = r.quotaSource.RemainingQuota(m.Provider, requestModel)
+		groups[name] = append(groups[name], member)
+	}
+
+	for _, name := range names {
+		members := groups[name]
+		if len(members) < 2 {
+			continue
+		}
+		slots := make([]int, len(members))
+		for i, m := range members {
+			slots[i] = m.slot
+		}
+		for i, m := range orderByQuota(members, r.rand) {
+			matched[slots[i]] = m.route
+		}
+		if trace != nil {
+			trace.Add(domain.RoutingTraceStep{
+				Action: domain.RoutingTraceMatched,
+				Model:  requestModel,
+				Reason: "provider group " + name + " balanced by remaining quota",
+			})
+		}
+	}
+}
+
+// orderByQuota draws members without replacement, weighted by remaining quota
+func orderByQuota(members []*groupMember, rnd func() float64) []*groupMember {
+	var knownSum float64
+	var knownCount int
+	for _, m := range members {
+		if m.known {
+			knownSum += m.percent
+			knownCount++
+		}
+	}
+	unknownWeight := 100.0
+	if knownCount > 0 {
+		unknownWeight = knownSum / float64(knownCount)
+	}
+
+	var pool, exhausted []*groupMember
+	weights := make(map[*groupMember]float64, len(members))
+	for _, m := range members {
+		w := unknownWeight
+		if m.known {
+			w = m.percent
+		}
+		if w <= 0 {
+			exhausted = append(exhausted, m)
+			continue
+		}
+		weights[m] = w
+		pool = append(pool, m)
+	}
+
+	ordered := make([]*groupMember, 0, len(members))
+	for len(pool) > 0 {
+		var total float64
+		for _, m := range pool {
+			total += weights[m]
+		}
+		pick := len(pool) - 1
+		target := rnd() * total
+		for i, m := range pool {
+			target -= weights[m]
+			if target < 0 {
+				pick = i
+				break
+			}
+		}
+		ordered = append(ordered, pool[pick])
+		pool = append(pool[:pick], pool[pick+1:]...)
+	}
+	// 耗尽的成员保持原有顺序排在最后
+	return append(ordered, exhausted...)
+}
+
+// ProviderGroups returns the quota status of every provider group
+func (r *Router) ProviderGroups() []*domain.ProviderGroupStatus {
+	r.mu.RLock()
+	source := r.quotaSource
+	r.mu.RUnlock()
+
+	byName := make(map[string]*domain.ProviderGroupStatus)
+	for _, p := range r.providerRepo.GetAll() {
+		name := p.GroupName()
+		if name == "" {
+			continue
+		}
+		group, ok := byName[name]
+		if !ok {
+			group = &domain.ProviderGroupStatus{Name: name}
+			byName[name] = group
+		}
+		member := &domain.ProviderGroupMember{
+			ProviderID: p.ID,
+			Name:       p.Name,
+			Type:       p.Type,
+			InCooldown: r.cooldownManager.IsInCooldown(p.ID, ""),
+		}
+		if source != nil {
+			if percent, src, ok := source.RemainingQuota(p, ""); ok {
+				member.RemainingPercent = &percent
+				member.QuotaSource = src
+			}
+		}
+		group.Members = append(group.Members, member)
+	}
+
+	groups := make([]*domain.ProviderGroupStatus, 0, len(byName))
+	for _, group := range byName {
+		sort.Slice(group.Members, func(i, j int) bool { return group.Members[i].ProviderID < group.Members[j].ProviderID })
+		var sum float64
+		var count int
+		for _, m := range group.Members {
+			if m.RemainingPercent != nil {
+				sum += *m.RemainingPercent
+				count++
+			}
+		}
+		if count > 0 {
+			avg := sum / float64(count)
+			group.RemainingPercent = &avg
+		}
+		groups = append(groups, group)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
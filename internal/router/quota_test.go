@@ -0,0 +1,125 @@
+package router
+
+import (
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository/cached"
+)
+
+// fakeQuotaSource returns fixed quotas by provider name
+type fakeQuotaSource map[string]float64
+
+func (f fakeQuotaSource) RemainingQuota(p *domain.Provider, model string) (float64, string, bool) {
+	percent, ok := f[p.Name]
+	return percent, QuotaSourceRateLimit, ok
+}
+
+func TestMatchBalancesProviderGroupByQuota(t *testing.T) {
+	r, first := newTestRouter(t)
+	routeRepo := r.routeRepo
+
+	// 组成员 v1、pool-b 之间夹着未分组的 solo，分组只在成员占据的位置内重排
+	first.Config = &domain.ProviderConfig{Group: "pool"}
+	if err := r.providerRepo.Update(first); err != nil {
+		t.Fatalf("update provider: %v", err)
+	}
+	for i, p := range []*domain.Provider{
+		{Name: "solo", Type: hotReloadProviderType},
+		{Name: "pool-b", Type: hotReloadProviderType, Config: &domain.ProviderConfig{Group: "pool"}},
+	} {
+		createRoutedProvider(t, r.providerRepo, routeRepo, p, i+2)
+	}
+	if err := r.InitAdapters(); err != nil {
+		t.Fatalf("InitAdapters failed: %v", err)
+	}
+
+	names := func() []string {
+		matched, err := r.Match(&MatchContext{ClientType: domain.ClientTypeClaude})
+		if err != nil {
+			t.Fatalf("Match failed: %v", err)
+		}
+		var got []string
+		for _, m := range matched {
+			got = append(got, m.Provider.Name)
+		}
+		return got
+	}
+
+	// 未设置配额来源时保持优先级顺序
+	if got := names(); got[0] != "v1" || got[1] != "solo" || got[2] != "pool-b" {
+		t.Fatalf("order without quota source = %v", got)
+	}
+
+	// v1 配额耗尽：pool-b 换到组内第一个位置
+	r.SetQuotaSource(fakeQuotaSource{"v1": 0, "pool-b": 80})
+	if got := names(); got[0] != "pool-b" || got[1] != "solo" || got[2] != "v1" {
+		t.Errorf("order with v1 exhausted = %v, want [pool-b solo v1]", got)
+	}
+}
+
+func createRoutedProvider(t *testing.T, providerRepo *cached.ProviderRepository, routeRepo *cached.RouteRepository, p *domain.Provider, position int) {
+	t.Helper()
+	if err := providerRepo.Create(p); err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	route := &domain.Route{IsEnabled: true, ClientType: domain.ClientTypeClaude, ProviderID: p.ID, Position: position}
+	if err := routeRepo.Create(route); err != nil {
+		t.Fatalf("create route: %v", err)
+	}
+}
+
+func TestOrderByQuota(t *testing.T) {
+	members := []*groupMember{
+		{slot: 0, percent: 0, known: true},
+		{slot: 1, percent: 25, known: true},
+		{slot: 2, percent: 75, known: true},
+		{slot: 3},
+	}
+	slots := func(ordered []*groupMember) []int {
+		var got []int
+		for _, m := range ordered {
+			got = append(got, m.slot)
+		}
+		return got
+	}
+
+	// 权重 25/75/50（未知成员取已知平均值 (0+25+75)/3），耗尽的成员排在最后
+	// rnd=0 总是抽到池中第一个
+	if got := slots(orderByQuota(append([]*groupMember(nil), members...), func() float64 { return 0 })); got[0] != 1 || got[1] != 2 || got[2] != 3 || got[3] != 0 {
+		t.Errorf("order with rnd=0 = %v, want [1 2 3 0]", got)
+	}
+	// rnd=0.3 落在第一轮的 75 权重区间（25 ≤ 45 < 100）
+	if got := slots(orderByQuota(append([]*groupMember(nil), members...), func() float64 { return 0.3 })); got[0] != 2 || got[3] != 0 {
+		t.Errorf("order with rnd=0.3 = %v, want slot 2 first and 0 last", got)
+	}
+}
+
+func TestQuotaRemaining(t *testing.T) {
+	used := func(v float64) *float64 { return &v }
+	codex := &domain.CodexQuota{
+		PrimaryWindow:   &domain.CodexQuotaWindow{UsedPercent: used(30)},
+		SecondaryWindow: &domain.CodexQuotaWindow{UsedPercent: used(90)},
+	}
+	if got, ok := codexRemaining(codex); !ok || got != 10 {
+		t.Errorf("codexRemaining = %v, %v; want 10 (weekly window is tighter)", got, ok)
+	}
+	if got, ok := codexRemaining(&domain.CodexQuota{}); ok {
+		t.Errorf("codexRemaining without windows = %v, want no data", got)
+	}
+
+	ag := &domain.AntigravityQuota{Models: []domain.AntigravityModelQuota{
+		{Name: "gemini-2.5-pro", Percentage: 40},
+		{Name: "claude-sonnet-4-5", Percentage: 90},
+	}}
+	for model, want := range map[string]float64{
+		"claude-sonnet-4-5":          90,
+		"claude-sonnet-4-5-thinking": 90,
+		"unknown-model":              40,
+		"":                           40,
+	} {
+		if got, ok := antigravityRemaining(ag, model); !ok || got != want {
+			t.Errorf("antigravityRemaining(%q) = %v, %v; want %v", model, got, ok, want)
+		}
+	}
+}
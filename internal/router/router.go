@@ -1,17 +1,24 @@
 package router
 
 import (
+	"fmt"
 	"math/rand"
 	"sort"
 	"sync"
+	"time"
 
 	"github.com/awsl-project/maxx/internal/adapter/provider"
 	"github.com/awsl-project/maxx/internal/cooldown"
 	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/ratelimit"
 	"github.com/awsl-project/maxx/internal/repository/cached"
 )
 
-// MatchedRoute contains all data needed to execute a proxy request
+// MatchedRoute contains all data needed to execute a proxy request.
+// Provider and ProviderAdapter are snapshots taken at match time: adapter
+// refreshes and removals replace the router's cache entry but never modify
+// or close an adapter that was already handed out, so in-flight requests
+// keep a valid reference for the duration of the attempt.
 type MatchedRoute struct {
 	Route           *domain.Route
 	Provider        *domain.Provider
@@ -25,6 +32,9 @@ type MatchContext struct {
 	ProjectID    uint64
 	RequestModel string
 	APITokenID   uint64
+
+	// Trace collects routing decisions when non-nil (routing_trace_enabled)
+	Trace *domain.RoutingTrace
 }
 
 // Router handles route matching and selection
@@ -39,8 +49,22 @@ type Router struct {
 	adapters map[uint64]provider.ProviderAdapter
 	mu       sync.RWMutex
 
+	// 串行化 RefreshAdapter/RemoveAdapter，保证按调用顺序生效，
+	// 避免较慢的旧配置刷新覆盖较新的适配器
+	refreshMu sync.Mutex
+
 	// Cooldown manager
 	cooldownManager *cooldown.Manager
+
+	// Provider 分组配额来源，nil 时不做组内均衡
+	quotaSource QuotaSource
+	rand        func() float64
+
+	// Provider 健康分，health_weighted 策略使用，nil 时该策略退化为按优先级排序
+	healthScorer *HealthScorer
+
+	// 各 Provider 正在执行的上游请求数（排空状态使用）
+	inFlight sync.Map // providerID -> *atomic.Int64
 }
 
 // NewRouter creates a new router
@@ -59,6 +83,7 @@ func NewRouter(
 		projectRepo:         projectRepo,
 		adapters:            make(map[uint64]provider.ProviderAdapter),
 		cooldownManager:     cooldown.Default(),
+		rand:                rand.Float64,
 	}
 }
 
@@ -82,12 +107,18 @@ func (r *Router) InitAdapters() error {
 	return nil
 }
 
-// RefreshAdapter refreshes the adapter for a specific provider
+// RefreshAdapter refreshes the adapter for a specific provider.
+// The new adapter is built outside the cache lock and swapped in atomically;
+// requests already holding the old adapter continue to use it.
 func (r *Router) RefreshAdapter(p *domain.Provider) error {
 	factory, ok := provider.GetAdapterFactory(p.Type)
 	if !ok {
 		return nil
 	}
+
+	r.refreshMu.Lock()
+	defer r.refreshMu.Unlock()
+
 	a, err := factory(p)
 	if err != nil {
 		return err
@@ -98,19 +129,165 @@ func (r *Router) RefreshAdapter(p *domain.Provider) error {
 	return nil
 }
 
-// RemoveAdapter removes the adapter for a provider
+// RemoveAdapter removes the adapter for a provider.
+// In-flight requests holding the adapter are unaffected; new matches skip the provider.
 func (r *Router) RemoveAdapter(providerID uint64) {
+	r.refreshMu.Lock()
+	defer r.refreshMu.Unlock()
+
 	r.mu.Lock()
 	delete(r.adapters, providerID)
 	r.mu.Unlock()
+	ratelimit.Default().Remove(providerID)
+}
+
+// GetProvider returns the cached provider, or nil if it doesn't exist
+func (r *Router) GetProvider(providerID uint64) *domain.Provider {
+	return r.providerRepo.GetAll()[providerID]
 }
 
-// Match returns matched routes for a client type and project
+// HasAdapter reports whether an adapter is currently registered for the provider
+func (r *Router) HasAdapter(providerID uint64) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.adapters[providerID]
+	return ok
+}
+
+// Match returns matched routes for a client type and project.
+// Filtering precedence: a disabled route is never matched; an enabled route is
+// skipped when its provider has the client type in DisabledClientTypes
+// (persistent, manual) or the provider is draining (retiring, manual);
+// otherwise it is skipped while the provider is in cooldown for the client
+// type (temporary, automatic).
 func (r *Router) Match(ctx *MatchContext) ([]*MatchedRoute, error) {
 	clientType := ctx.ClientType
 	projectID := ctx.ProjectID
 	requestModel := ctx.RequestModel
 
+	filtered, hasProjectRoutes := r.candidateRoutes(clientType, projectID)
+
+	if len(filtered) == 0 {
+		ctx.Trace.Add(domain.RoutingTraceStep{
+			Action: domain.RoutingTraceSkipped,
+			Model:  requestModel,
+			Reason: "no enabled routes for client type " + string(clientType),
+		})
+		return nil, domain.ErrNoRoutes
+	}
+
+	// Get routing strategy
+	strategy := r.getRoutingStrategy(projectID)
+	if ctx.Trace != nil {
+		ctx.Trace.Strategy = strategy.Type
+	}
+
+	// Sort routes by strategy
+	r.sortRoutes(filtered, strategy)
+
+	// Get default retry config
+	defaultRetry, _ := r.retryConfigRepo.GetDefault()
+
+	// Build matched routes
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var matched []*MatchedRoute
+	providers := r.providerRepo.GetAll()
+
+	for _, route := range filtered {
+		skip := func(reason string) {
+			ctx.Trace.Add(domain.RoutingTraceStep{
+				Action:     domain.RoutingTraceSkipped,
+				RouteID:    route.ID,
+				ProviderID: route.ProviderID,
+				Model:      requestModel,
+				Reason:     reason,
+			})
+		}
+
+		prov, ok := providers[route.ProviderID]
+		if !ok {
+			skip("provider not found")
+			continue
+		}
+
+		// Skip providers that disabled this client type
+		if prov.IsClientTypeDisabled(clientType) {
+			skip("client type disabled on provider")
+			continue
+		}
+
+		// Skip draining providers: only requests matched before draining keep using them
+		if prov.Draining {
+			skip("provider draining")
+			continue
+		}
+
+		// Skip providers in cooldown
+		if r.cooldownManager.IsInCooldown(route.ProviderID, string(clientType)) {
+			skip("provider in cooldown")
+			continue
+		}
+
+		adp, ok := r.adapters[route.ProviderID]
+		if !ok {
+			skip("no adapter for provider type")
+			continue
+		}
+
+		// Check if provider supports the request model
+		// SupportModels check is done BEFORE mapping
+		// If SupportModels is configured, check if the request model is supported
+		if len(prov.SupportModels) > 0 && requestModel != "" {
+			if !r.isModelSupported(requestModel, prov.SupportModels) {
+				skip("model not in provider supportModels")
+				continue
+			}
+		}
+
+		var retryConfig *domain.RetryConfig
+		if route.RetryConfigID != 0 {
+			retryConfig, _ = r.retryConfigRepo.GetByID(route.RetryConfigID)
+		}
+		if retryConfig == nil {
+			retryConfig = defaultRetry
+		}
+
+		matched = append(matched, &MatchedRoute{
+			Route:           route,
+			Provider:        prov,
+			ProviderAdapter: adp,
+			RetryConfig:     retryConfig,
+		})
+		if ctx.Trace != nil {
+			reason := fmt.Sprintf("candidate #%d", len(matched))
+			if hasProjectRoutes {
+				reason += " (project route)"
+			}
+			ctx.Trace.Add(domain.RoutingTraceStep{
+				Action:     domain.RoutingTraceMatched,
+				RouteID:    route.ID,
+				ProviderID: route.ProviderID,
+				Model:      requestModel,
+				Reason:     reason,
+			})
+		}
+	}
+
+	if len(matched) == 0 {
+		return nil, domain.ErrNoRoutes
+	}
+
+	r.balanceGroups(matched, requestModel, ctx.Trace)
+
+	return matched, nil
+}
+
+// candidateRoutes returns the enabled routes a request of clientType in
+// projectID may use: the project's own routes when the project enables custom
+// routes for the client type and has any, otherwise the global routes.
+func (r *Router) candidateRoutes(clientType domain.ClientType, projectID uint64) ([]*domain.Route, bool) {
 	routes := r.routeRepo.GetAll()
 
 	// Check if ClientType has custom routes enabled for this project
@@ -166,72 +343,112 @@ func (r *Router) Match(ctx *MatchContext) ([]*MatchedRoute, error) {
 		}
 	}
 
+	return filtered, hasProjectRoutes
+}
+
+// CooldownRetryAfter reports whether every candidate route for clientType in
+// projectID is unusable and at least one is only waiting out a cooldown, so
+// a request can be refused before any routing work. retryAfter is the time
+// until the soonest cooldown expires. A route counts as usable when its
+// provider exists, is not draining, has not disabled the client type and is
+// not in cooldown; model support is not considered.
+func (r *Router) CooldownRetryAfter(clientType domain.ClientType, projectID uint64) (retryAfter time.Duration, blocked bool) {
+	filtered, _ := r.candidateRoutes(clientType, projectID)
 	if len(filtered) == 0 {
-		return nil, domain.ErrNoRoutes
+		return 0, false
 	}
 
-	// Get routing strategy
-	strategy := r.getRoutingStrategy(projectID)
-
-	// Sort routes by strategy
-	r.sortRoutes(filtered, strategy)
-
-	// Get default retry config
-	defaultRetry, _ := r.retryConfigRepo.GetDefault()
-
-	// Build matched routes
-	r.mu.RLock()
-	defer r.mu.RUnlock()
-
-	var matched []*MatchedRoute
 	providers := r.providerRepo.GetAll()
-
+	now := time.Now()
+	var soonest time.Time
 	for _, route := range filtered {
 		prov, ok := providers[route.ProviderID]
-		if !ok {
+		if !ok || prov.IsClientTypeDisabled(clientType) || prov.Draining {
 			continue
 		}
-
-		// Skip providers in cooldown
-		if r.cooldownManager.IsInCooldown(route.ProviderID, string(clientType)) {
-			continue
+		until := r.cooldownManager.GetCooldownUntil(route.ProviderID, string(clientType))
+		if !until.After(now) {
+			return 0, false
 		}
-
-		adp, ok := r.adapters[route.ProviderID]
-		if !ok {
-			continue
+		if soonest.IsZero() || until.Before(soonest) {
+			soonest = until
 		}
+	}
+	if soonest.IsZero() {
+		return 0, false
+	}
+	return soonest.Sub(now), true
+}
 
-		// Check if provider supports the request model
-		// SupportModels check is done BEFORE mapping
-		// If SupportModels is configured, check if the request model is supported
-		if len(prov.SupportModels) > 0 && requestModel != "" {
-			if !r.isModelSupported(requestModel, prov.SupportModels) {
-				continue
-			}
-		}
+// MatchRoute builds a MatchedRoute for a specific route, bypassing ordering,
+// cooldown and the enabled flags (route and provider client type). Used to replay requests against a chosen route.
+func (r *Router) MatchRoute(routeID uint64) (*MatchedRoute, error) {
+	route, err := r.routeRepo.GetByID(routeID)
+	if err != nil {
+		return nil, err
+	}
+	prov, ok := r.providerRepo.GetAll()[route.ProviderID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
 
-		var retryConfig *domain.RetryConfig
-		if route.RetryConfigID != 0 {
-			retryConfig, _ = r.retryConfigRepo.GetByID(route.RetryConfigID)
-		}
-		if retryConfig == nil {
-			retryConfig = defaultRetry
-		}
+	r.mu.RLock()
+	adp, ok := r.adapters[route.ProviderID]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, domain.ErrNoRoutes
+	}
 
-		matched = append(matched, &MatchedRoute{
-			Route:           route,
-			Provider:        prov,
-			ProviderAdapter: adp,
-			RetryConfig:     retryConfig,
-		})
+	var retryConfig *domain.RetryConfig
+	if route.RetryConfigID != 0 {
+		retryConfig, _ = r.retryConfigRepo.GetByID(route.RetryConfigID)
+	}
+	if retryConfig == nil {
+		retryConfig, _ = r.retryConfigRepo.GetDefault()
 	}
 
-	if len(matched) == 0 {
-		return nil, domain.ErrNoRoutes
+	return &MatchedRoute{
+		Route:           route,
+		Provider:        prov,
+		ProviderAdapter: adp,
+		RetryConfig:     retryConfig,
+	}, nil
+}
+
+// getProject returns the project, or nil if projectID is 0 or not found
+func (r *Router) getProject(projectID uint64) *domain.Project {
+	if projectID == 0 {
+		return nil
 	}
+	project, err := r.projectRepo.GetByID(projectID)
+	if err != nil {
+		return nil
+	}
+	return project
+}
 
-	return matched, nil
+// ProjectModelFallbacks returns the model fallback chains configured on a project
+func (r *Router) ProjectModelFallbacks(projectID uint64) []domain.ModelFallback {
+	if project := r.getProject(projectID); project != nil {
+		return project.ModelFallbacks
+	}
+	return nil
+}
+
+// ProjectMaxRoutesAttempted returns the project's cap on distinct routes tried per request, 0 if unset
+func (r *Router) ProjectMaxRoutesAttempted(projectID uint64) int {
+	if project := r.getProject(projectID); project != nil {
+		return project.MaxRoutesAttempted
+	}
+	return 0
+}
+
+// ProjectBillable reports whether requests for the project are billable (default true)
+func (r *Router) ProjectBillable(projectID uint64) bool {
+	if project := r.getProject(projectID); project != nil {
+		return !project.NonBillable
+	}
+	return true
 }
 
 // isModelSupported checks if a model matches any pattern in the support list
@@ -266,6 +483,16 @@ func (r *Router) sortRoutes(routes []*domain.Route, strategy *domain.RoutingStra
 		rand.Shuffle(len(routes), func(i, j int) {
 			routes[i], routes[j] = routes[j], routes[i]
 		})
+	case domain.RoutingStrategyHealthWeighted:
+		sort.SliceStable(routes, func(i, j int) bool {
+			return routes[i].Position < routes[j].Position
+		})
+		r.mu.RLock()
+		scorer := r.healthScorer
+		r.mu.RUnlock()
+		if scorer != nil {
+			orderByHealth(routes, scorer.scoreMap(), r.rand)
+		}
 	default: // priority
 		sort.Slice(routes, func(i, j int) bool {
 			return routes[i].Position < routes[j].Position
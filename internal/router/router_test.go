@@ -0,0 +1,219 @@
+package router
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/adapter/provider"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository/cached"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+const hotReloadProviderType = "hot-reload-test"
+
+// hotReloadAdapter records the provider name it was built with and blocks in
+// Execute until released, so refreshes can happen mid-request
+type hotReloadAdapter struct {
+	name    string
+	started chan struct{}
+	release chan struct{}
+	calls   atomic.Int32
+}
+
+func init() {
+	provider.RegisterAdapterFactory(hotReloadProviderType, func(p *domain.Provider) (provider.ProviderAdapter, error) {
+		return &hotReloadAdapter{
+			name:    p.Name,
+			started: make(chan struct{}),
+			release: make(chan struct{}),
+		}, nil
+	})
+}
+
+func (a *hotReloadAdapter) SupportedClientTypes() []domain.ClientType {
+	return []domain.ClientType{domain.ClientTypeClaude}
+}
+
+func (a *hotReloadAdapter) Execute(ctx context.Context, w http.ResponseWriter, req *http.Request, p *domain.Provider) error {
+	if a.calls.Add(1) == 1 {
+		close(a.started)
+		<-a.release
+	}
+	_, err := fmt.Fprint(w, a.name)
+	return err
+}
+
+func newTestRouter(t *testing.T) (*Router, *domain.Provider) {
+	t.Helper()
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+
+	providerRepo := cached.NewProviderRepository(sqlite.NewProviderRepository(db))
+	routeRepo := cached.NewRouteRepository(sqlite.NewRouteRepository(db))
+	p := &domain.Provider{Name: "v1", Type: hotReloadProviderType}
+	if err := providerRepo.Create(p); err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	route := &domain.Route{IsEnabled: true, ClientType: domain.ClientTypeClaude, ProviderID: p.ID}
+	if err := routeRepo.Create(route); err != nil {
+		t.Fatalf("create route: %v", err)
+	}
+
+	r := NewRouter(
+		routeRepo,
+		providerRepo,
+		cached.NewRoutingStrategyRepository(sqlite.NewRoutingStrategyRepository(db)),
+		cached.NewRetryConfigRepository(sqlite.NewRetryConfigRepository(db)),
+		cached.NewProjectRepository(sqlite.NewProjectRepository(db)),
+	)
+	if err := r.InitAdapters(); err != nil {
+		t.Fatalf("InitAdapters failed: %v", err)
+	}
+	return r, p
+}
+
+func TestRefreshAdapterDuringInFlightRequest(t *testing.T) {
+	r, p := newTestRouter(t)
+
+	matched, err := r.Match(&MatchContext{ClientType: domain.ClientTypeClaude, RequestModel: "claude-sonnet-4"})
+	if err != nil || len(matched) != 1 {
+		t.Fatalf("Match = %v, %v; want one route", matched, err)
+	}
+	inFlight := matched[0].ProviderAdapter.(*hotReloadAdapter)
+
+	// 请求执行中（阻塞在 Execute 内）
+	rec := httptest.NewRecorder()
+	execErr := make(chan error, 1)
+	go func() {
+		req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+		execErr <- matched[0].ProviderAdapter.Execute(context.Background(), rec, req, matched[0].Provider)
+	}()
+	<-inFlight.started
+
+	// 并发刷新、删除、匹配
+	const workers = 8
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				updated := &domain.Provider{ID: p.ID, Name: fmt.Sprintf("v%d-%d", i, j), Type: hotReloadProviderType}
+				if err := r.RefreshAdapter(updated); err != nil {
+					t.Errorf("RefreshAdapter failed: %v", err)
+					return
+				}
+				if j%10 == 0 {
+					r.RemoveAdapter(p.ID)
+				}
+				_, _ = r.Match(&MatchContext{ClientType: domain.ClientTypeClaude, RequestModel: "claude-sonnet-4"})
+				_ = r.HasAdapter(p.ID)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	close(inFlight.release)
+	if err := <-execErr; err != nil {
+		t.Fatalf("in-flight Execute failed: %v", err)
+	}
+	// 执行中的请求始终使用匹配时的适配器
+	if got := rec.Body.String(); got != "v1" {
+		t.Errorf("in-flight response = %q, want v1", got)
+	}
+
+	// 刷新按调用顺序生效：最后一次操作之后的适配器即为最新配置
+	final := &domain.Provider{ID: p.ID, Name: "final", Type: hotReloadProviderType}
+	if err := r.RefreshAdapter(final); err != nil {
+		t.Fatalf("RefreshAdapter failed: %v", err)
+	}
+	matched, err = r.Match(&MatchContext{ClientType: domain.ClientTypeClaude, RequestModel: "claude-sonnet-4"})
+	if err != nil || len(matched) != 1 {
+		t.Fatalf("Match after refresh = %v, %v; want one route", matched, err)
+	}
+	if got := matched[0].ProviderAdapter.(*hotReloadAdapter).name; got != "final" {
+		t.Errorf("adapter after refresh = %q, want final", got)
+	}
+}
+
+func TestRemoveAdapterSkipsProviderForNewMatches(t *testing.T) {
+	r, p := newTestRouter(t)
+
+	matched, err := r.Match(&MatchContext{ClientType: domain.ClientTypeClaude})
+	if err != nil || len(matched) != 1 {
+		t.Fatalf("Match = %v, %v; want one route", matched, err)
+	}
+
+	r.RemoveAdapter(p.ID)
+	if r.HasAdapter(p.ID) {
+		t.Error("HasAdapter = true after RemoveAdapter")
+	}
+	if _, err := r.Match(&MatchContext{ClientType: domain.ClientTypeClaude}); err != domain.ErrNoRoutes {
+		t.Errorf("Match after remove err = %v, want ErrNoRoutes", err)
+	}
+
+	// 已匹配的路由仍持有可用的适配器
+	a := matched[0].ProviderAdapter.(*hotReloadAdapter)
+	close(a.release)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/v1/messages", nil)
+	if err := a.Execute(context.Background(), rec, req, matched[0].Provider); err != nil {
+		t.Fatalf("Execute with removed adapter failed: %v", err)
+	}
+}
+
+func TestMatchSkipsProviderDisabledForClientType(t *testing.T) {
+	r, p := newTestRouter(t)
+
+	p.DisabledClientTypes = []domain.ClientType{domain.ClientTypeOpenAI}
+	if err := r.providerRepo.Update(p); err != nil {
+		t.Fatalf("update provider: %v", err)
+	}
+	if matched, err := r.Match(&MatchContext{ClientType: domain.ClientTypeClaude}); err != nil || len(matched) != 1 {
+		t.Fatalf("Match claude = %v, %v; want one route", matched, err)
+	}
+
+	p.DisabledClientTypes = []domain.ClientType{domain.ClientTypeClaude}
+	if err := r.providerRepo.Update(p); err != nil {
+		t.Fatalf("update provider: %v", err)
+	}
+	if _, err := r.Match(&MatchContext{ClientType: domain.ClientTypeClaude}); err != domain.ErrNoRoutes {
+		t.Errorf("Match after disabling claude err = %v, want ErrNoRoutes", err)
+	}
+}
+
+func TestMatchRecordsRoutingTrace(t *testing.T) {
+	r, p := newTestRouter(t)
+
+	trace := &domain.RoutingTrace{}
+	if _, err := r.Match(&MatchContext{ClientType: domain.ClientTypeClaude, RequestModel: "claude-sonnet-4", Trace: trace}); err != nil {
+		t.Fatalf("Match failed: %v", err)
+	}
+	if trace.Strategy != domain.RoutingStrategyPriority {
+		t.Errorf("strategy = %q, want priority", trace.Strategy)
+	}
+	if len(trace.Steps) != 1 || trace.Steps[0].Action != domain.RoutingTraceMatched || trace.Steps[0].ProviderID != p.ID {
+		t.Fatalf("steps = %+v, want one matched step for provider %d", trace.Steps, p.ID)
+	}
+
+	p.SupportModels = []string{"gpt-*"}
+	if err := r.providerRepo.Update(p); err != nil {
+		t.Fatalf("update provider: %v", err)
+	}
+	trace = &domain.RoutingTrace{}
+	if _, err := r.Match(&MatchContext{ClientType: domain.ClientTypeClaude, RequestModel: "claude-sonnet-4", Trace: trace}); err != domain.ErrNoRoutes {
+		t.Fatalf("Match err = %v, want ErrNoRoutes", err)
+	}
+	if len(trace.Steps) != 1 || trace.Steps[0].Action != domain.RoutingTraceSkipped || trace.Steps[0].Reason != "model not in provider supportModels" {
+		t.Errorf("steps = %+v, want one skipped step for unsupported model", trace.Steps)
+	}
+}
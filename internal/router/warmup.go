@@ -0,0 +1,93 @@
+package router
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/adapter/provider"
+)
+
+const (
+	// ConnectionWarmupTick 连接预热任务检查各 Provider 是否到期的间隔
+	ConnectionWarmupTick = 5 * time.Second
+	// connectionWarmupTimeout 单次预热（含 TLS 握手）的超时
+	connectionWarmupTimeout = 20 * time.Second
+)
+
+// connectionWarmup tracks when each provider was last warmed
+type connectionWarmup struct {
+	mu      sync.Mutex
+	last    map[uint64]time.Time
+	running map[uint64]bool
+}
+
+// RunConnectionWarmup keeps the connection pools of providers with
+// ProviderTransport.WarmConnections warm until ctx is done. Each provider is
+// warmed every provider.WarmInterval (a third of its idle connection timeout
+// by default, 30s), so the connections are refreshed before the pool closes
+// them. Warming goes straight through the adapter's HTTP client, bypassing the
+// executor: it creates no request records, stats, rate limit or cooldown effects.
+func (r *Router) RunConnectionWarmup(ctx context.Context) {
+	w := &connectionWarmup{last: make(map[uint64]time.Time), running: make(map[uint64]bool)}
+	ticker := time.NewTicker(ConnectionWarmupTick)
+	defer ticker.Stop()
+	for {
+		r.warmDueProviders(ctx, w, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// warmDueProviders starts warming every provider whose interval has elapsed
+// and returns their IDs. Draining providers are skipped, and a provider is
+// never warmed twice concurrently.
+func (r *Router) warmDueProviders(ctx context.Context, w *connectionWarmup, now time.Time) []uint64 {
+	r.mu.RLock()
+	adapters := make(map[uint64]provider.ProviderAdapter, len(r.adapters))
+	for id, a := range r.adapters {
+		adapters[id] = a
+	}
+	r.mu.RUnlock()
+
+	var started []uint64
+	for _, p := range r.providerRepo.GetAll() {
+		if p.Config == nil || p.Config.Transport == nil || p.Config.Transport.WarmConnections <= 0 ||
+			p.Config.Transport.DisableKeepAlives || p.Draining {
+			continue
+		}
+		warmer, ok := adapters[p.ID].(provider.ConnectionWarmer)
+		if !ok {
+			continue
+		}
+
+		w.mu.Lock()
+		due := !w.running[p.ID] && now.Sub(w.last[p.ID]) >= provider.WarmInterval(p.Config.Transport)
+		if due {
+			w.running[p.ID] = true
+			w.last[p.ID] = now
+		}
+		w.mu.Unlock()
+		if !due {
+			continue
+		}
+
+		started = append(started, p.ID)
+		id, name, n := p.ID, p.Name, p.Config.Transport.WarmConnections
+		go func() {
+			warmCtx, cancel := context.WithTimeout(ctx, connectionWarmupTimeout)
+			defer cancel()
+			if err := warmer.WarmConnections(warmCtx, n); err != nil && ctx.Err() == nil {
+				log.Printf("[Warmup] Warming connections for provider %d (%s) failed: %v", id, name, err)
+			}
+			w.mu.Lock()
+			delete(w.running, id)
+			w.mu.Unlock()
+		}()
+	}
+	return started
+}
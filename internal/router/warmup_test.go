@@ -0,0 +1,89 @@
+package router
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/adapter/provider"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+const warmProviderType = "warm-test"
+
+// warmAdapter reports each warming call's connection count
+type warmAdapter struct {
+	calls chan int
+}
+
+var warmCalls = make(chan int, 10)
+
+func init() {
+	provider.RegisterAdapterFactory(warmProviderType, func(p *domain.Provider) (provider.ProviderAdapter, error) {
+		return &warmAdapter{calls: warmCalls}, nil
+	})
+}
+
+func (a *warmAdapter) SupportedClientTypes() []domain.ClientType {
+	return []domain.ClientType{domain.ClientTypeClaude}
+}
+
+func (a *warmAdapter) Execute(ctx context.Context, w http.ResponseWriter, req *http.Request, p *domain.Provider) error {
+	return nil
+}
+
+func (a *warmAdapter) WarmConnections(ctx context.Context, n int) error {
+	a.calls <- n
+	return nil
+}
+
+func TestConnectionWarmup(t *testing.T) {
+	r, _ := newTestRouter(t)
+	warm := &domain.Provider{Name: "warm", Type: warmProviderType, Config: &domain.ProviderConfig{
+		Transport: &domain.ProviderTransport{WarmConnections: 3, WarmIntervalSeconds: 20},
+	}}
+	createRoutedProvider(t, r.providerRepo, r.routeRepo, warm, 2)
+	// 未启用预热的 Provider 不会被预热
+	createRoutedProvider(t, r.providerRepo, r.routeRepo, &domain.Provider{Name: "cold", Type: warmProviderType}, 3)
+	if err := r.InitAdapters(); err != nil {
+		t.Fatalf("InitAdapters failed: %v", err)
+	}
+
+	w := &connectionWarmup{last: make(map[uint64]time.Time), running: make(map[uint64]bool)}
+	now := time.Now()
+	if started := r.warmDueProviders(context.Background(), w, now); len(started) != 1 || started[0] != warm.ID {
+		t.Fatalf("started = %v, want [%d]", started, warm.ID)
+	}
+	select {
+	case n := <-warmCalls:
+		if n != 3 {
+			t.Errorf("warmed %d connections, want 3", n)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("warming not started")
+	}
+
+	waitIdle := func() {
+		for i := 0; i < 100; i++ {
+			w.mu.Lock()
+			running := len(w.running)
+			w.mu.Unlock()
+			if running == 0 {
+				return
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatal("warming did not finish")
+	}
+	waitIdle()
+
+	if started := r.warmDueProviders(context.Background(), w, now.Add(10*time.Second)); len(started) != 0 {
+		t.Errorf("warmed again before the interval: %v", started)
+	}
+	if started := r.warmDueProviders(context.Background(), w, now.Add(20*time.Second)); len(started) != 1 {
+		t.Errorf("not warmed after the interval: %v", started)
+	}
+	<-warmCalls
+	waitIdle()
+}
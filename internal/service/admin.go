@@ -3,16 +3,23 @@ package service
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
+	"github.com/awsl-project/maxx/internal/adapter/provider"
+	"github.com/awsl-project/maxx/internal/cooldown"
 	"github.com/awsl-project/maxx/internal/domain"
 	"github.com/awsl-project/maxx/internal/event"
+	"github.com/awsl-project/maxx/internal/executor"
 	"github.com/awsl-project/maxx/internal/pricing"
 	"github.com/awsl-project/maxx/internal/repository"
 	"github.com/awsl-project/maxx/internal/usage"
@@ -29,24 +36,37 @@ type ProviderAdapterRefresher interface {
 // AdminService provides business logic for admin operations
 // Both HTTP handlers and Wails bindings call this service
 type AdminService struct {
-	providerRepo        repository.ProviderRepository
-	routeRepo           repository.RouteRepository
-	projectRepo         repository.ProjectRepository
-	sessionRepo         repository.SessionRepository
-	retryConfigRepo     repository.RetryConfigRepository
-	routingStrategyRepo repository.RoutingStrategyRepository
-	proxyRequestRepo    repository.ProxyRequestRepository
-	attemptRepo         repository.ProxyUpstreamAttemptRepository
-	settingRepo         repository.SystemSettingRepository
-	apiTokenRepo        repository.APITokenRepository
-	modelMappingRepo    repository.ModelMappingRepository
-	usageStatsRepo      repository.UsageStatsRepository
-	responseModelRepo   repository.ResponseModelRepository
-	modelPriceRepo      repository.ModelPriceRepository
-	serverAddr          string
-	adapterRefresher    ProviderAdapterRefresher
-	broadcaster         event.Broadcaster
-	pprofReloader       PprofReloader
+	providerRepo         repository.ProviderRepository
+	routeRepo            repository.RouteRepository
+	projectRepo          repository.ProjectRepository
+	sessionRepo          repository.SessionRepository
+	retryConfigRepo      repository.RetryConfigRepository
+	routingStrategyRepo  repository.RoutingStrategyRepository
+	proxyRequestRepo     repository.ProxyRequestRepository
+	attemptRepo          repository.ProxyUpstreamAttemptRepository
+	settingRepo          repository.SystemSettingRepository
+	apiTokenRepo         repository.APITokenRepository
+	modelMappingRepo     repository.ModelMappingRepository
+	usageStatsRepo       repository.UsageStatsRepository
+	responseModelRepo    repository.ResponseModelRepository
+	modelPriceRepo       repository.ModelPriceRepository
+	antigravityQuotaRepo repository.AntigravityQuotaRepository
+	codexQuotaRepo       repository.CodexQuotaRepository
+	serverAddr           string
+	adapterRefresher     ProviderAdapterRefresher
+	broadcaster          event.Broadcaster
+	pprofReloader        PprofReloader
+	requestReplayer      RequestReplayer
+	requestResolver      RequestResolver
+	statusReporter       ProviderStatusReporter
+	requestWatcher       RequestWatcher
+	routeProber          RouteProber
+	concurrencyHistory   ConcurrencyHistorySource
+	costCalculator       pricing.CostCalculator
+
+	compareMu   sync.Mutex // 同一时间只允许一个路由对比任务
+	aggregateMu sync.Mutex // 同一时间只允许一个手动聚合请求
+	reconcileMu sync.Mutex // 同一时间只允许一个成本核对
 }
 
 // PprofReloader is an interface for reloading pprof configuration
@@ -70,33 +90,56 @@ func NewAdminService(
 	usageStatsRepo repository.UsageStatsRepository,
 	responseModelRepo repository.ResponseModelRepository,
 	modelPriceRepo repository.ModelPriceRepository,
+	antigravityQuotaRepo repository.AntigravityQuotaRepository,
+	codexQuotaRepo repository.CodexQuotaRepository,
 	serverAddr string,
 	adapterRefresher ProviderAdapterRefresher,
 	broadcaster event.Broadcaster,
 	pprofReloader PprofReloader,
+	requestReplayer RequestReplayer,
+	requestResolver RequestResolver,
+	statusReporter ProviderStatusReporter,
+	requestWatcher RequestWatcher,
+	routeProber RouteProber,
+	concurrencyHistory ConcurrencyHistorySource,
 ) *AdminService {
 	return &AdminService{
-		providerRepo:        providerRepo,
-		routeRepo:           routeRepo,
-		projectRepo:         projectRepo,
-		sessionRepo:         sessionRepo,
-		retryConfigRepo:     retryConfigRepo,
-		routingStrategyRepo: routingStrategyRepo,
-		proxyRequestRepo:    proxyRequestRepo,
-		attemptRepo:         attemptRepo,
-		settingRepo:         settingRepo,
-		apiTokenRepo:        apiTokenRepo,
-		modelMappingRepo:    modelMappingRepo,
-		usageStatsRepo:      usageStatsRepo,
-		responseModelRepo:   responseModelRepo,
-		modelPriceRepo:      modelPriceRepo,
-		serverAddr:          serverAddr,
-		adapterRefresher:    adapterRefresher,
-		broadcaster:         broadcaster,
-		pprofReloader:       pprofReloader,
+		providerRepo:         providerRepo,
+		routeRepo:            routeRepo,
+		projectRepo:          projectRepo,
+		sessionRepo:          sessionRepo,
+		retryConfigRepo:      retryConfigRepo,
+		routingStrategyRepo:  routingStrategyRepo,
+		proxyRequestRepo:     proxyRequestRepo,
+		attemptRepo:          attemptRepo,
+		settingRepo:          settingRepo,
+		apiTokenRepo:         apiTokenRepo,
+		modelMappingRepo:     modelMappingRepo,
+		usageStatsRepo:       usageStatsRepo,
+		responseModelRepo:    responseModelRepo,
+		modelPriceRepo:       modelPriceRepo,
+		antigravityQuotaRepo: antigravityQuotaRepo,
+		codexQuotaRepo:       codexQuotaRepo,
+		serverAddr:           serverAddr,
+		adapterRefresher:     adapterRefresher,
+		broadcaster:          broadcaster,
+		pprofReloader:        pprofReloader,
+		requestReplayer:      requestReplayer,
+		requestResolver:      requestResolver,
+		statusReporter:       statusReporter,
+		requestWatcher:       requestWatcher,
+		routeProber:          routeProber,
+		concurrencyHistory:   concurrencyHistory,
+		costCalculator:       pricing.GlobalCalculator(),
 	}
 }
 
+// SetCostCalculator replaces the calculator used to recalculate costs
+// (default pricing.GlobalCalculator()); use the same one as the executor
+func (s *AdminService) SetCostCalculator(calculator pricing.CostCalculator) {
+	s.costCalculator = calculator
+}
+
 // ===== Provider API =====
 
 func (s *AdminService) GetProviders() ([]*domain.Provider, error) {
@@ -108,6 +151,24 @@ func (s *AdminService) GetProvider(id uint64) (*domain.Provider, error) {
 }
 
 func (s *AdminService) CreateProvider(provider *domain.Provider) error {
+	if err := validateProviderMultipliers(provider); err != nil {
+		return err
+	}
+	if err := validateProviderTransport(provider); err != nil {
+		return err
+	}
+	if err := validateProviderSoftFailurePatterns(provider); err != nil {
+		return err
+	}
+	if err := validateProviderUnsupportedParams(provider); err != nil {
+		return err
+	}
+	if err := validateProviderStreamTextRewrites(provider); err != nil {
+		return err
+	}
+	if err := validateProviderMaxStreamDuration(provider); err != nil {
+		return err
+	}
 	// Auto-set SupportedClientTypes based on provider type
 	s.autoSetSupportedClientTypes(provider)
 
@@ -122,6 +183,24 @@ func (s *AdminService) CreateProvider(provider *domain.Provider) error {
 }
 
 func (s *AdminService) UpdateProvider(provider *domain.Provider) error {
+	if err := validateProviderMultipliers(provider); err != nil {
+		return err
+	}
+	if err := validateProviderTransport(provider); err != nil {
+		return err
+	}
+	if err := validateProviderSoftFailurePatterns(provider); err != nil {
+		return err
+	}
+	if err := validateProviderUnsupportedParams(provider); err != nil {
+		return err
+	}
+	if err := validateProviderStreamTextRewrites(provider); err != nil {
+		return err
+	}
+	if err := validateProviderMaxStreamDuration(provider); err != nil {
+		return err
+	}
 	// Auto-set SupportedClientTypes based on provider type
 	s.autoSetSupportedClientTypes(provider)
 
@@ -135,6 +214,103 @@ func (s *AdminService) UpdateProvider(provider *domain.Provider) error {
 	return nil
 }
 
+// validateProviderTransport rejects negative connection settings (0 means default)
+// and connection warming that the pool settings would defeat
+func validateProviderTransport(p *domain.Provider) error {
+	if p.Config == nil || p.Config.Transport == nil {
+		return nil
+	}
+	t := p.Config.Transport
+	if t.MaxIdleConnsPerHost < 0 || t.IdleConnTimeoutSeconds < 0 || t.KeepAliveSeconds < 0 ||
+		t.WarmConnections < 0 || t.WarmIntervalSeconds < 0 {
+		return fmt.Errorf("%w: transport settings must not be negative (0 uses the default)", domain.ErrInvalidInput)
+	}
+	if t.WarmConnections > 0 {
+		if t.DisableKeepAlives {
+			return fmt.Errorf("%w: warmConnections needs connection reuse, it can't be combined with disableKeepAlives", domain.ErrInvalidInput)
+		}
+		if maxIdle := provider.EffectiveMaxIdleConnsPerHost(t); t.WarmConnections > maxIdle {
+			return fmt.Errorf("%w: warmConnections (%d) exceeds maxIdleConnsPerHost (%d)", domain.ErrInvalidInput, t.WarmConnections, maxIdle)
+		}
+		if interval, idle := provider.WarmInterval(t), provider.EffectiveIdleConnTimeout(t); interval >= idle {
+			return fmt.Errorf("%w: warmIntervalSeconds (%v) must be shorter than idleConnTimeoutSeconds (%v)", domain.ErrInvalidInput, interval, idle)
+		}
+	}
+	return nil
+}
+
+// validateProviderSoftFailurePatterns rejects soft failure rules that are not valid regular expressions
+func validateProviderSoftFailurePatterns(provider *domain.Provider) error {
+	if provider.Config == nil {
+		return nil
+	}
+	for _, pattern := range provider.Config.SoftFailurePatterns {
+		if pattern == "" {
+			return fmt.Errorf("%w: soft failure pattern must not be empty", domain.ErrInvalidInput)
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("%w: invalid soft failure pattern %q: %v", domain.ErrInvalidInput, pattern, err)
+		}
+	}
+	return nil
+}
+
+// validateProviderUnsupportedParams rejects empty parameter names and names
+// with commas (attempts record stripped parameters as a comma-separated list)
+func validateProviderUnsupportedParams(provider *domain.Provider) error {
+	if provider.Config == nil {
+		return nil
+	}
+	for _, param := range provider.Config.UnsupportedParams {
+		if strings.TrimSpace(param) == "" || strings.Contains(param, ",") {
+			return fmt.Errorf("%w: invalid unsupported param %q", domain.ErrInvalidInput, param)
+		}
+	}
+	return nil
+}
+
+// validateProviderStreamTextRewrites rejects rules with an empty or oversized
+// find string; the writer holds back up to len(find) bytes across chunks
+func validateProviderStreamTextRewrites(provider *domain.Provider) error {
+	if provider.Config == nil {
+		return nil
+	}
+	for i, rule := range provider.Config.StreamTextRewrites {
+		if rule.Find == "" {
+			return fmt.Errorf("%w: stream text rewrite #%d has an empty find string", domain.ErrInvalidInput, i+1)
+		}
+		if len(rule.Find) > domain.StreamTextRewriteMaxFind {
+			return fmt.Errorf("%w: stream text rewrite #%d find string exceeds %d bytes",
+				domain.ErrInvalidInput, i+1, domain.StreamTextRewriteMaxFind)
+		}
+	}
+	return nil
+}
+
+// validateProviderMaxStreamDuration rejects a negative stream duration cap
+func validateProviderMaxStreamDuration(provider *domain.Provider) error {
+	if provider.Config != nil && provider.Config.MaxStreamDurationMs < 0 {
+		return fmt.Errorf("%w: maxStreamDurationMs must not be negative (0 = unlimited)", domain.ErrInvalidInput)
+	}
+	return nil
+}
+
+// validateProviderMultipliers rejects zero client multipliers: billing ignores
+// them and charges 1x, so a 0 entered to make a provider free would be silently
+// wrong. Free usage is expressed with non-billable tokens/projects instead.
+func validateProviderMultipliers(provider *domain.Provider) error {
+	if provider.Config == nil || provider.Config.Custom == nil {
+		return nil
+	}
+	for clientType, multiplier := range provider.Config.Custom.ClientMultiplier {
+		if multiplier == 0 {
+			return fmt.Errorf("%w: multiplier for %s must be greater than 0 (10000 = 1x), remove it to use the default",
+				domain.ErrInvalidInput, clientType)
+		}
+	}
+	return nil
+}
+
 func (s *AdminService) DeleteProvider(id uint64) error {
 	// Delete related routes first
 	routes, _ := s.routeRepo.List()
@@ -162,8 +338,9 @@ func (s *AdminService) ExportProviders() ([]*domain.Provider, error) {
 }
 
 // ImportProviders imports providers from exported data
-// Creates new providers, skipping duplicates by name
-func (s *AdminService) ImportProviders(providers []*domain.Provider) (*ImportResult, error) {
+// Creates new providers, skipping duplicates by name unless renameOnConflict,
+// which imports them as "name (2)", "name (3)", ...
+func (s *AdminService) ImportProviders(providers []*domain.Provider, renameOnConflict bool) (*ImportResult, error) {
 	result := &ImportResult{
 		Imported: 0,
 		Skipped:  0,
@@ -181,6 +358,11 @@ func (s *AdminService) ImportProviders(providers []*domain.Provider) (*ImportRes
 	}
 
 	for _, provider := range providers {
+		originalName := provider.Name
+		if existingNames[provider.Name] && renameOnConflict {
+			provider.Name = uniqueProviderName(provider.Name, func(name string) bool { return existingNames[name] })
+		}
+
 		// Skip if name already exists
 		if existingNames[provider.Name] {
 			result.Skipped++
@@ -200,6 +382,12 @@ func (s *AdminService) ImportProviders(providers []*domain.Provider) (*ImportRes
 
 		result.Imported++
 		existingNames[provider.Name] = true
+		if provider.Name != originalName {
+			if result.Renamed == nil {
+				result.Renamed = make(map[string]string)
+			}
+			result.Renamed[originalName] = provider.Name
+		}
 	}
 
 	return result, nil
@@ -207,9 +395,10 @@ func (s *AdminService) ImportProviders(providers []*domain.Provider) (*ImportRes
 
 // ImportResult holds the result of an import operation
 type ImportResult struct {
-	Imported int      `json:"imported"`
-	Skipped  int      `json:"skipped"`
-	Errors   []string `json:"errors"`
+	Imported int               `json:"imported"`
+	Skipped  int               `json:"skipped"`
+	Errors   []string          `json:"errors"`
+	Renamed  map[string]string `json:"renamed,omitempty"` // original name -> imported name
 }
 
 // ===== Route API =====
@@ -253,13 +442,79 @@ func (s *AdminService) GetProjectBySlug(slug string) (*domain.Project, error) {
 }
 
 func (s *AdminService) CreateProject(project *domain.Project) error {
+	if err := validateProjectTimezone(project.Timezone); err != nil {
+		return err
+	}
+	if err := validateModelFallbacks(project.ModelFallbacks); err != nil {
+		return err
+	}
+	if project.MaxRoutesAttempted < 0 {
+		return fmt.Errorf("maxRoutesAttempted must be >= 0")
+	}
+	// 未指定 slug 时由名称生成并避开其他项目的曾用 slug；自定义 slug 需合法且不能占用其他项目的曾用 slug
+	if project.Slug == "" {
+		slug, err := s.generateProjectSlug(project.Name, time.Now())
+		if err != nil {
+			return err
+		}
+		project.Slug = slug
+	} else {
+		if err := domain.ValidateSlug(project.Slug); err != nil {
+			return err
+		}
+		if err := s.checkProjectSlugAvailable(project.Slug, 0, time.Now()); err != nil {
+			return err
+		}
+	}
+	project.PreviousSlugs = nil
 	return s.projectRepo.Create(project)
 }
 
 func (s *AdminService) UpdateProject(project *domain.Project) error {
+	if err := validateProjectTimezone(project.Timezone); err != nil {
+		return err
+	}
+	if err := validateModelFallbacks(project.ModelFallbacks); err != nil {
+		return err
+	}
+	if project.MaxRoutesAttempted < 0 {
+		return fmt.Errorf("maxRoutesAttempted must be >= 0")
+	}
+	if err := s.trackProjectSlugChange(project, time.Now()); err != nil {
+		return err
+	}
 	return s.projectRepo.Update(project)
 }
 
+// validateProjectTimezone 校验项目时区，空字符串表示使用全局时区
+func validateProjectTimezone(tz string) error {
+	if tz == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", tz, err)
+	}
+	return nil
+}
+
+// validateModelFallbacks 校验模型回退链：Pattern 和回退模型均不能为空
+func validateModelFallbacks(fallbacks []domain.ModelFallback) error {
+	for i, f := range fallbacks {
+		if f.Pattern == "" {
+			return fmt.Errorf("modelFallbacks[%d]: pattern is required", i)
+		}
+		if len(f.Fallbacks) == 0 {
+			return fmt.Errorf("modelFallbacks[%d]: at least one fallback model is required", i)
+		}
+		for _, m := range f.Fallbacks {
+			if m == "" {
+				return fmt.Errorf("modelFallbacks[%d]: fallback model cannot be empty", i)
+			}
+		}
+	}
+	return nil
+}
+
 func (s *AdminService) DeleteProject(id uint64) error {
 	return s.projectRepo.Delete(id)
 }
@@ -302,6 +557,79 @@ func (s *AdminService) UpdateSessionProject(sessionID string, projectID uint64)
 	}, nil
 }
 
+// MergeSessionsResult holds the result of merging sessions
+type MergeSessionsResult struct {
+	Session          *domain.Session `json:"session"`
+	MergedSessions   int             `json:"mergedSessions"`
+	MovedRequests    int64           `json:"movedRequests"`
+	RetaggedRequests int64           `json:"retaggedRequests"`
+	ProjectConflict  bool            `json:"projectConflict"` // 被合并的会话绑定了与主会话不同的项目
+}
+
+// MergeSessions folds otherIDs into the primary session: their requests are
+// reassigned to primaryID and the other session records are deleted, in one
+// transaction. The merged session keeps the primary's project; if the primary
+// has none, it takes the first project found among the others. Requests are
+// re-tagged to that project unless the sessions had conflicting projects, in
+// which case they are only re-tagged when retagRequests is set.
+func (s *AdminService) MergeSessions(primaryID string, otherIDs []string, retagRequests bool) (*MergeSessionsResult, error) {
+	if primaryID == "" {
+		return nil, fmt.Errorf("%w: primary session ID is required", domain.ErrInvalidInput)
+	}
+	seen := map[string]bool{primaryID: true}
+	var others []string
+	for _, id := range otherIDs {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		others = append(others, id)
+	}
+	if len(others) == 0 {
+		return nil, fmt.Errorf("%w: at least one other session ID is required", domain.ErrInvalidInput)
+	}
+
+	primary, err := s.sessionRepo.GetBySessionID(primaryID)
+	if err != nil {
+		return nil, fmt.Errorf("session %s: %w", primaryID, err)
+	}
+	projectID := primary.ProjectID
+	conflict := false
+	for _, id := range others {
+		other, err := s.sessionRepo.GetBySessionID(id)
+		if err != nil {
+			return nil, fmt.Errorf("session %s: %w", id, err)
+		}
+		switch {
+		case other.ProjectID == 0 || other.ProjectID == projectID:
+		case projectID == 0:
+			projectID = other.ProjectID
+		default:
+			conflict = true
+		}
+	}
+
+	moved, retagged, err := s.sessionRepo.Merge(primaryID, others, projectID, retagRequests || !conflict)
+	if err != nil {
+		return nil, err
+	}
+	if conflict {
+		log.Printf("[MergeSessions] Sessions merged into %s had conflicting projects, kept project %d", primaryID, projectID)
+	}
+
+	session, err := s.sessionRepo.GetBySessionID(primaryID)
+	if err != nil {
+		return nil, err
+	}
+	return &MergeSessionsResult{
+		Session:          session,
+		MergedSessions:   len(others),
+		MovedRequests:    moved,
+		RetaggedRequests: retagged,
+		ProjectConflict:  conflict,
+	}, nil
+}
+
 // RejectSession marks a session as rejected with current timestamp
 func (s *AdminService) RejectSession(sessionID string) (*domain.Session, error) {
 	// Get the session first
@@ -410,10 +738,63 @@ func (s *AdminService) GetProxyRequestsCountWithFilter(filter *repository.ProxyR
 	return s.proxyRequestRepo.CountWithFilter(filter)
 }
 
+// maxBulkDeleteRequests 单次批量删除最多删除的请求数，剩余的需再次调用
+const maxBulkDeleteRequests = 10000
+
+// DeleteRequestsResult 批量删除请求的结果
+type DeleteRequestsResult struct {
+	DeletedRequests int64 `json:"deletedRequests"`
+	DeletedAttempts int64 `json:"deletedAttempts"`
+	HasMore         bool  `json:"hasMore"` // 还有匹配的请求未删除（超过单次上限）
+}
+
+// DeleteRequests 按过滤条件批量删除请求及其 attempts（用于清理测试数据）
+// 至少需要设置一个过滤条件；进行中的请求不会被删除；已聚合的使用统计不受影响。
+// 完成后广播 requests_deleted 事件（不逐条广播）
+func (s *AdminService) DeleteRequests(filter repository.ProxyRequestDeleteFilter) (*DeleteRequestsResult, error) {
+	if filter.IsEmpty() {
+		return nil, fmt.Errorf("%w: at least one filter is required", domain.ErrInvalidInput)
+	}
+	if !filter.Start.IsZero() && !filter.End.IsZero() && !filter.Start.Before(filter.End) {
+		return nil, fmt.Errorf("%w: start must be before end", domain.ErrInvalidInput)
+	}
+
+	requests, attempts, hasMore, err := s.proxyRequestRepo.DeleteByFilter(filter, maxBulkDeleteRequests)
+	if err != nil {
+		return nil, err
+	}
+	result := &DeleteRequestsResult{
+		DeletedRequests: requests,
+		DeletedAttempts: attempts,
+		HasMore:         hasMore,
+	}
+	log.Printf("[Admin] Bulk deleted %d requests and %d attempts (hasMore=%v)", requests, attempts, hasMore)
+
+	if s.broadcaster != nil {
+		s.broadcaster.BroadcastMessage("requests_deleted", result)
+	}
+	return result, nil
+}
+
 func (s *AdminService) GetProxyRequest(id uint64) (*domain.ProxyRequest, error) {
 	return s.proxyRequestRepo.GetByID(id)
 }
 
+// MaxProxyRequestNotesLength 请求备注的最大长度（字符数）
+const MaxProxyRequestNotesLength = 4000
+
+// UpdateProxyRequestNotes 设置请求的运维备注，空字符串表示清除
+func (s *AdminService) UpdateProxyRequestNotes(id uint64, notes string) (*domain.ProxyRequest, error) {
+	notes = strings.TrimSpace(notes)
+	if utf8.RuneCountInString(notes) > MaxProxyRequestNotesLength {
+		return nil, fmt.Errorf("%w: notes must be at most %d characters", domain.ErrInvalidInput, MaxProxyRequestNotesLength)
+	}
+	if err := s.proxyRequestRepo.UpdateNotes(id, notes); err != nil {
+		return nil, err
+	}
+	return s.proxyRequestRepo.GetByID(id)
+}
+
 func (s *AdminService) GetActiveProxyRequests() ([]*domain.ProxyRequest, error) {
 	return s.proxyRequestRepo.ListActive()
 }
@@ -422,8 +803,52 @@ func (s *AdminService) GetProxyUpstreamAttempts(proxyRequestID uint64) ([]*domai
 	return s.attemptRepo.ListByProxyRequestID(proxyRequestID)
 }
 
+// AttemptPaginationResult 请求 attempts 的游标分页结果，按开始时间、ID 排序
+type AttemptPaginationResult struct {
+	Items   []*domain.ProxyUpstreamAttempt `json:"items"`
+	HasMore bool                           `json:"hasMore"`
+	// 下一页的游标（当前页最后一条 attempt 的 ID），没有更多时为 0
+	NextCursor uint64 `json:"nextCursor,omitempty"`
+}
+
+// GetProxyUpstreamAttemptsPage returns up to limit attempts of a request after
+// the cursor (the last attempt ID of the previous page, 0 for the first page)
+func (s *AdminService) GetProxyUpstreamAttemptsPage(proxyRequestID uint64, limit int, cursor uint64) (*AttemptPaginationResult, error) {
+	items, err := s.attemptRepo.ListPageByProxyRequestID(proxyRequestID, limit+1, cursor)
+	if err != nil {
+		return nil, err
+	}
+	result := &AttemptPaginationResult{Items: items}
+	if len(items) > limit {
+		result.Items = items[:limit]
+		result.HasMore = true
+		result.NextCursor = result.Items[limit-1].ID
+	}
+	return result, nil
+}
+
 func (s *AdminService) GetProviderStats(clientType string, projectID uint64) (map[uint64]*domain.ProviderStats, error) {
-	return s.usageStatsRepo.GetProviderStats(clientType, projectID)
+	stats, err := s.usageStatsRepo.GetProviderStats(clientType, projectID)
+	if err != nil || clientType == "" {
+		return stats, err
+	}
+
+	// 标记禁用了该 ClientType 的 Provider（即使没有统计数据也返回）
+	providers, err := s.providerRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range providers {
+		if !p.IsClientTypeDisabled(domain.ClientType(clientType)) {
+			continue
+		}
+		if ps, ok := stats[p.ID]; ok {
+			ps.ClientTypeDisabled = true
+		} else {
+			stats[p.ID] = &domain.ProviderStats{ProviderID: p.ID, ClientTypeDisabled: true}
+		}
+	}
+	return stats, nil
 }
 
 // ===== Settings API =====
@@ -445,10 +870,28 @@ func (s *AdminService) GetSetting(key string) (string, error) {
 }
 
 func (s *AdminService) UpdateSetting(key, value string) error {
+	// 保存前按设置项的取值规则校验
+	if err := ValidateSetting(key, value); err != nil {
+		return err
+	}
+
 	if err := s.settingRepo.Set(key, value); err != nil {
 		return err
 	}
 
+	// 已通过校验，以下解析不会失败
+	switch key {
+	case domain.SettingKeyModelNormalizationRules:
+		rules, _ := pricing.ParseNormalizationRules(value)
+		pricing.GlobalNormalizer().SetRules(rules)
+	case domain.SettingKeyCooldownFailureWeights:
+		weights, _ := cooldown.ParseFailureWeights(value)
+		cooldown.Default().SetFailureWeights(weights)
+	case domain.SettingKeyCooldownEventRetentionDays:
+		retention, _ := cooldown.ParseEventRetentionDays(value)
+		cooldown.Default().SetEventRetention(retention)
+	}
+
 	// 如果更新的是 pprof 相关设置，触发重载
 	switch key {
 	case domain.SettingKeyEnablePprof, domain.SettingKeyPprofPort, domain.SettingKeyPprofPassword:
@@ -467,6 +910,19 @@ func (s *AdminService) DeleteSetting(key string) error {
 		return err
 	}
 
+	// 删除归一化规则后恢复默认规则
+	if key == domain.SettingKeyModelNormalizationRules {
+		pricing.GlobalNormalizer().SetRules(nil)
+	}
+	// 删除失败权重后每次失败恢复计 1
+	if key == domain.SettingKeyCooldownFailureWeights {
+		cooldown.Default().SetFailureWeights(nil)
+	}
+	// 删除历史保留设置后恢复默认保留天数
+	if key == domain.SettingKeyCooldownEventRetentionDays {
+		cooldown.Default().SetEventRetention(cooldown.DefaultEventRetention)
+	}
+
 	// 如果删除的是 pprof 相关设置，触发重载
 	switch key {
 	case domain.SettingKeyEnablePprof, domain.SettingKeyPprofPort, domain.SettingKeyPprofPassword:
@@ -551,31 +1007,16 @@ func (s *AdminService) GetLogs(limit int) (*LogsResult, error) {
 
 // autoSetSupportedClientTypes sets SupportedClientTypes based on provider type
 func (s *AdminService) autoSetSupportedClientTypes(provider *domain.Provider) {
-	switch provider.Type {
-	case "antigravity":
-		// Antigravity natively supports Claude and Gemini
-		// OpenAI requests will be converted to Claude format by Executor
-		provider.SupportedClientTypes = []domain.ClientType{
-			domain.ClientTypeClaude,
-			domain.ClientTypeGemini,
-		}
-	case "kiro":
-		// Kiro natively supports Claude protocol only
-		provider.SupportedClientTypes = []domain.ClientType{
-			domain.ClientTypeClaude,
-		}
-	case "codex":
-		// Codex natively supports Codex protocol only
-		provider.SupportedClientTypes = []domain.ClientType{
-			domain.ClientTypeCodex,
-		}
-	case "custom":
-		// Custom providers use their configured SupportedClientTypes
-		// If not set, default to OpenAI
-		if len(provider.SupportedClientTypes) == 0 {
-			provider.SupportedClientTypes = []domain.ClientType{domain.ClientTypeOpenAI}
-		}
+	// 原生支持的客户端类型见 providerTypeCapabilities（GET /admin/capabilities）
+	native, configurable := nativeClientTypes(provider.Type)
+	if native == nil {
+		return
+	}
+	// Custom providers use their configured SupportedClientTypes, defaulting to OpenAI
+	if configurable && len(provider.SupportedClientTypes) > 0 {
+		return
 	}
+	provider.SupportedClientTypes = native
 }
 
 // ===== API Token API =====
@@ -584,6 +1025,28 @@ func (s *AdminService) GetAPITokens() ([]*domain.APIToken, error) {
 	return s.apiTokenRepo.List()
 }
 
+// GetStaleAPITokens returns tokens not used in the last `days` days.
+// Tokens that were never used count as stale once they are older than `days` days.
+func (s *AdminService) GetStaleAPITokens(days int) ([]*domain.APIToken, error) {
+	tokens, err := s.apiTokenRepo.List()
+	if err != nil {
+		return nil, err
+	}
+
+	cutoff := time.Now().AddDate(0, 0, -days)
+	stale := make([]*domain.APIToken, 0)
+	for _, t := range tokens {
+		lastActive := t.CreatedAt
+		if t.LastUsedAt != nil {
+			lastActive = *t.LastUsedAt
+		}
+		if lastActive.Before(cutoff) {
+			stale = append(stale, t)
+		}
+	}
+	return stale, nil
+}
+
 func (s *AdminService) GetAPIToken(id uint64) (*domain.APIToken, error) {
 	return s.apiTokenRepo.GetByID(id)
 }
@@ -617,6 +1080,9 @@ func (s *AdminService) CreateAPIToken(name, description string, projectID uint64
 }
 
 func (s *AdminService) UpdateAPIToken(token *domain.APIToken) error {
+	if err := validateModelFallbacks(token.ModelFallbacks); err != nil {
+		return err
+	}
 	return s.apiTokenRepo.Update(token)
 }
 
@@ -680,6 +1146,55 @@ func (s *AdminService) ClearAllModelMappings() error {
 	return s.modelMappingRepo.ClearAll()
 }
 
+// ModelMappingTestResult is the result of resolving a model name against model mappings
+type ModelMappingTestResult struct {
+	Model           string               `json:"model"`
+	NormalizedModel string               `json:"normalizedModel"`
+	Matched         bool                 `json:"matched"`
+	Rule            *domain.ModelMapping `json:"rule,omitempty"`
+	Target          string               `json:"target"`
+	Candidates      int                  `json:"candidates"` // 作用域内参与匹配的规则数
+}
+
+// TestModelMapping resolves a model name through executor.ResolveModelMapping, the
+// same rule resolution the executor uses, and returns the winning rule (if any) and
+// the resolved target. routeID and apiTokenID are optional; with a route, its
+// provider and client type are used just as for a request on that route, so
+// route-scoped rules apply.
+func (s *AdminService) TestModelMapping(clientType domain.ClientType, providerType string, providerID, projectID, routeID, apiTokenID uint64, model string) (*ModelMappingTestResult, error) {
+	query := &domain.ModelMappingQuery{
+		ClientType:   clientType,
+		ProviderType: providerType,
+		ProviderID:   providerID,
+		ProjectID:    projectID,
+		APITokenID:   apiTokenID,
+	}
+	if routeID != 0 {
+		route, err := s.routeRepo.GetByID(routeID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: route %d: %v", domain.ErrInvalidInput, routeID, err)
+		}
+		p, err := s.providerRepo.GetByID(route.ProviderID)
+		if err != nil {
+			return nil, fmt.Errorf("%w: provider %d of route %d: %v", domain.ErrInvalidInput, route.ProviderID, routeID, err)
+		}
+		query = executor.RouteModelMappingQuery(route, p, route.ClientType, projectID, apiTokenID)
+	}
+
+	res, err := executor.ResolveModelMapping(s.modelMappingRepo, query, model)
+	if err != nil {
+		return nil, err
+	}
+	return &ModelMappingTestResult{
+		Model:           model,
+		NormalizedModel: res.NormalizedModel,
+		Matched:         res.Rule != nil,
+		Rule:            res.Rule,
+		Target:          res.Target,
+		Candidates:      res.Candidates,
+	}, nil
+}
+
 // ===== Response Model API =====
 
 // GetResponseModelNames returns all unique response model names
@@ -687,6 +1202,24 @@ func (s *AdminService) GetResponseModelNames() ([]string, error) {
 	return s.responseModelRepo.ListNames()
 }
 
+// RebuildResponseModelsResult reports the response_models rebuild
+type RebuildResponseModelsResult struct {
+	Before int64 `json:"before"` // 重建前的模型数
+	Models int64 `json:"models"` // 重建后的模型数
+}
+
+// RebuildResponseModels repopulates response_models from the distinct
+// response models of the stored upstream attempts, dropping names no longer
+// seen (e.g. after bulk deletes). Idempotent.
+func (s *AdminService) RebuildResponseModels() (*RebuildResponseModelsResult, error) {
+	before, after, err := s.responseModelRepo.Rebuild()
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[Admin] Rebuilt response models: %d -> %d", before, after)
+	return &RebuildResponseModelsResult{Before: before, Models: after}, nil
+}
+
 // ResetModelMappingsToDefaults re-seeds default builtin mappings
 func (s *AdminService) ResetModelMappingsToDefaults() error {
 	return s.modelMappingRepo.SeedDefaults()
@@ -709,6 +1242,13 @@ func (s *AdminService) GetUsageStats(filter repository.UsageStatsFilter) ([]*dom
 	return s.usageStatsRepo.Query(filter)
 }
 
+// GetMultiplierUsage returns the multipliers actually applied per provider and
+// client type, so past costs can be traced back after a multiplier changes.
+// Limited to attempts still within the request retention period.
+func (s *AdminService) GetMultiplierUsage(start, end *time.Time) ([]*domain.MultiplierUsage, error) {
+	return s.attemptRepo.GetMultiplierUsage(start, end)
+}
+
 // GetDashboardData returns all dashboard data in a single query
 func (s *AdminService) GetDashboardData() (*domain.DashboardData, error) {
 	return s.usageStatsRepo.QueryDashboardData()
@@ -753,6 +1293,66 @@ func (s *AdminService) RecalculateUsageStats() error {
 	return err
 }
 
+// ErrAggregationInProgress is returned when a manual aggregation is already running
+var ErrAggregationInProgress = errors.New("a stats aggregation is already running")
+
+// AggregateStatsPhase is the outcome of one aggregation phase
+type AggregateStatsPhase struct {
+	Phase     string             `json:"phase"` // "aggregate_minute", "rollup_hour", "rollup_day", "rollup_month"
+	From      domain.Granularity `json:"from,omitempty"`
+	To        domain.Granularity `json:"to"`
+	StartTime int64              `json:"startTime"` // unix ms
+	EndTime   int64              `json:"endTime"`   // unix ms
+	Count     int                `json:"count"`
+	Error     string             `json:"error,omitempty"`
+}
+
+// AggregateStatsResult holds the phases of a manual aggregation
+type AggregateStatsResult struct {
+	Phases     []AggregateStatsPhase `json:"phases"`
+	DurationMs int64                 `json:"durationMs"`
+}
+
+// AggregateStatsNow runs minute aggregation and rollups immediately instead of
+// waiting for the periodic task. Each phase is broadcast as
+// "stats_aggregate_phase" as it completes. If the periodic task is running,
+// this waits for it; overlapping manual triggers get ErrAggregationInProgress.
+func (s *AdminService) AggregateStatsNow() (*AggregateStatsResult, error) {
+	if !s.aggregateMu.TryLock() {
+		return nil, ErrAggregationInProgress
+	}
+	defer s.aggregateMu.Unlock()
+
+	start := time.Now()
+	result := &AggregateStatsResult{Phases: []AggregateStatsPhase{}}
+	var firstErr error
+	for event := range s.usageStatsRepo.AggregateAndRollUp() {
+		phase := AggregateStatsPhase{
+			Phase:     event.Phase,
+			From:      event.From,
+			To:        event.To,
+			StartTime: event.StartTime,
+			EndTime:   event.EndTime,
+			Count:     event.Count,
+		}
+		if event.Error != nil {
+			phase.Error = event.Error.Error()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", event.Phase, event.Error)
+			}
+		}
+		result.Phases = append(result.Phases, phase)
+		if s.broadcaster != nil {
+			s.broadcaster.BroadcastMessage("stats_aggregate_phase", phase)
+		}
+	}
+	result.DurationMs = time.Since(start).Milliseconds()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return result, nil
+}
+
 // RecalculateCostsResult holds the result of cost recalculation
 type RecalculateCostsResult struct {
 	TotalAttempts   int    `json:"totalAttempts"`
@@ -770,6 +1370,35 @@ type RecalculateCostsProgress struct {
 	Message     string `json:"message"`     // Human-readable message
 }
 
+// priceOverrideLookup caches provider price overrides during a cost recalculation
+type priceOverrideLookup struct {
+	providerRepo repository.ProviderRepository
+	cache        map[uint64][]*domain.ModelPrice
+}
+
+func newPriceOverrideLookup(providerRepo repository.ProviderRepository) *priceOverrideLookup {
+	return &priceOverrideLookup{providerRepo: providerRepo, cache: make(map[uint64][]*domain.ModelPrice)}
+}
+
+// baseCost returns the cost before multiplier. Attempts billed with a provider
+// price override use that provider's current override for the model; if the
+// provider or its override is gone, the global price table applies.
+func (o *priceOverrideLookup) baseCost(calculator pricing.CostCalculator, providerID uint64, model string, metrics *usage.Metrics) uint64 {
+	if providerID != 0 {
+		overrides, ok := o.cache[providerID]
+		if !ok {
+			if p, err := o.providerRepo.GetByID(providerID); err == nil && p.Config != nil {
+				overrides = p.Config.PriceOverrides
+			}
+			o.cache[providerID] = overrides
+		}
+		if mp := pricing.MatchModelPrice(overrides, model); mp != nil {
+			return calculator.CalculateWithModelPrice(mp, metrics)
+		}
+	}
+	return calculator.Calculate(model, metrics)
+}
+
 // RecalculateCosts recalculates cost for all attempts using the current price table
 // and updates the parent requests' cost accordingly (with streaming batch processing)
 func (s *AdminService) RecalculateCosts() (*RecalculateCostsResult, error) {
@@ -809,7 +1438,8 @@ func (s *AdminService) RecalculateCosts() (*RecalculateCostsResult, error) {
 
 	broadcastProgress("calculating", 0, int(totalCount), fmt.Sprintf("Processing %d attempts...", totalCount))
 
-	calculator := pricing.GlobalCalculator()
+	calculator := s.costCalculator
+	overrides := newPriceOverrideLookup(s.providerRepo)
 	processedCount := 0
 	const batchSize = 100
 	affectedRequestIDs := make(map[uint64]struct{})
@@ -836,10 +1466,12 @@ func (s *AdminService) RecalculateCosts() (*RecalculateCostsResult, error) {
 				CacheCreationCount:   attempt.CacheWriteCount,
 				Cache5mCreationCount: attempt.Cache5mWriteCount,
 				Cache1hCreationCount: attempt.Cache1hWriteCount,
+				ReasoningTokens:      attempt.ReasoningTokenCount,
 			}
 
-			// Calculate new cost
-			newCost := calculator.Calculate(model, metrics)
+			// Calculate new cost, keeping the price override and multiplier applied when the attempt was billed
+			baseCost := overrides.baseCost(calculator, attempt.PriceOverrideProviderID, model, metrics)
+			newCost := pricing.ApplyMultiplier(baseCost, attempt.Multiplier)
 
 			// Track affected request IDs
 			affectedRequestIDs[attempt.ProxyRequestID] = struct{}{}
@@ -930,7 +1562,8 @@ func (s *AdminService) RecalculateRequestCost(requestID uint64) (*RecalculateReq
 		return nil, fmt.Errorf("failed to list attempts: %w", err)
 	}
 
-	calculator := pricing.GlobalCalculator()
+	calculator := s.costCalculator
+	overrides := newPriceOverrideLookup(s.providerRepo)
 	var totalCost uint64
 
 	// 3. Recalculate cost for each attempt
@@ -952,10 +1585,12 @@ func (s *AdminService) RecalculateRequestCost(requestID uint64) (*RecalculateReq
 			CacheCreationCount:   attempt.CacheWriteCount,
 			Cache5mCreationCount: attempt.Cache5mWriteCount,
 			Cache1hCreationCount: attempt.Cache1hWriteCount,
+			ReasoningTokens:      attempt.ReasoningTokenCount,
 		}
 
-		// Calculate new cost
-		newCost := calculator.Calculate(model, metrics)
+		// Calculate new cost, keeping the price override and multiplier applied when the attempt was billed
+		baseCost := overrides.baseCost(calculator, attempt.PriceOverrideProviderID, model, metrics)
+		newCost := pricing.ApplyMultiplier(baseCost, attempt.Multiplier)
 		totalCost += newCost
 
 		// Update attempt cost if changed
@@ -2,6 +2,7 @@ package service
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/awsl-project/maxx/internal/domain"
@@ -55,6 +56,8 @@ type importContext struct {
 	apiTokenNameToID    map[string]uint64
 	// routeKey format: "projectSlug:clientType:providerName"
 	routeKeyToID map[string]uint64
+	// backup provider name -> name it was imported under (rename on conflict)
+	providerRenames map[string]string
 }
 
 func newImportContext() *importContext {
@@ -64,6 +67,51 @@ func newImportContext() *importContext {
 		retryConfigNameToID: make(map[string]uint64),
 		apiTokenNameToID:    make(map[string]uint64),
 		routeKeyToID:        make(map[string]uint64),
+		providerRenames:     make(map[string]string),
+	}
+}
+
+// providerName returns the name a backup provider was imported under
+func (c *importContext) providerName(name string) string {
+	if renamed, ok := c.providerRenames[name]; ok {
+		return renamed
+	}
+	return name
+}
+
+// routeKey rewrites a backup route key ("providerName:clientType:projectSlug")
+// to use the provider's imported name
+func (c *importContext) routeKey(key string) string {
+	for original, renamed := range c.providerRenames {
+		if rest, ok := strings.CutPrefix(key, original+":"); ok {
+			return renamed + ":" + rest
+		}
+	}
+	return key
+}
+
+// providerNameTaken reports whether a provider name is in use, including
+// names assigned earlier in this import (dry runs never create them)
+func (c *importContext) providerNameTaken(name string) bool {
+	if _, exists := c.providerNameToID[name]; exists {
+		return true
+	}
+	for _, renamed := range c.providerRenames {
+		if renamed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueProviderName returns name with the smallest " (N)" suffix, N >= 2,
+// that is not taken
+func uniqueProviderName(name string, taken func(string) bool) string {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)", name, n)
+		if !taken(candidate) {
+			return candidate
+		}
 	}
 }
 
@@ -106,6 +154,7 @@ func (s *BackupService) Export() (*domain.BackupFile, error) {
 			Config:               p.Config,
 			SupportedClientTypes: p.SupportedClientTypes,
 			SupportModels:        p.SupportModels,
+			DisabledClientTypes:  p.DisabledClientTypes,
 		})
 	}
 
@@ -167,6 +216,10 @@ func (s *BackupService) Export() (*domain.BackupFile, error) {
 			ProviderName:    providerIDToName[r.ProviderID],
 			Position:        r.Position,
 			RetryConfigName: retryConfigIDToName[r.RetryConfigID],
+			ForceDetail:     r.ForceDetail,
+			Multiplier:      r.Multiplier,
+
+			MaxStreamDurationMs: r.MaxStreamDurationMs,
 		})
 	}
 
@@ -417,7 +470,10 @@ func (s *BackupService) importProviders(providers []domain.BackupProvider, opts
 	summary := domain.ImportSummary{}
 
 	for _, bp := range providers {
-		if _, exists := ctx.providerNameToID[bp.Name]; exists {
+		name := bp.Name
+		if _, exists := ctx.providerNameToID[bp.Name]; exists && opts.RenameOnConflict {
+			name = uniqueProviderName(bp.Name, ctx.providerNameTaken)
+		} else if exists {
 			switch opts.ConflictStrategy {
 			case "skip", "":
 				summary.Skipped++
@@ -435,11 +491,12 @@ func (s *BackupService) importProviders(providers []domain.BackupProvider, opts
 		}
 
 		p := &domain.Provider{
-			Name:                 bp.Name,
+			Name:                 name,
 			Type:                 bp.Type,
 			Config:               bp.Config,
 			SupportedClientTypes: bp.SupportedClientTypes,
 			SupportModels:        bp.SupportModels,
+			DisabledClientTypes:  bp.DisabledClientTypes,
 		}
 
 		if !opts.DryRun {
@@ -447,12 +504,19 @@ func (s *BackupService) importProviders(providers []domain.BackupProvider, opts
 				result.Warnings = append(result.Warnings, fmt.Sprintf("Failed to import Provider '%s': %v", bp.Name, err))
 				continue
 			}
-			ctx.providerNameToID[bp.Name] = p.ID
+			ctx.providerNameToID[name] = p.ID
 			// Refresh adapter
 			if s.adapterRefresher != nil {
 				s.adapterRefresher.RefreshAdapter(p)
 			}
 		}
+		if name != bp.Name {
+			ctx.providerRenames[bp.Name] = name
+			if result.ProviderRenames == nil {
+				result.ProviderRenames = make(map[string]string)
+			}
+			result.ProviderRenames[bp.Name] = name
+		}
 		summary.Imported++
 	}
 
@@ -557,8 +621,9 @@ func (s *BackupService) importRoutes(routes []domain.BackupRoute, opts domain.Im
 	summary := domain.ImportSummary{}
 
 	for _, br := range routes {
-		// Resolve provider
-		providerID, ok := ctx.providerNameToID[br.ProviderName]
+		// Resolve provider (by its imported name if it was renamed)
+		providerName := ctx.providerName(br.ProviderName)
+		providerID, ok := ctx.providerNameToID[providerName]
 		if !ok {
 			result.Warnings = append(result.Warnings, fmt.Sprintf("Route skipped: provider '%s' not found", br.ProviderName))
 			summary.Skipped++
@@ -583,7 +648,7 @@ func (s *BackupService) importRoutes(routes []domain.BackupRoute, opts domain.Im
 		}
 
 		// Check for existing route
-		routeKey := fmt.Sprintf("%s:%s:%s", br.ProviderName, br.ClientType, br.ProjectSlug)
+		routeKey := fmt.Sprintf("%s:%s:%s", providerName, br.ClientType, br.ProjectSlug)
 		if _, exists := ctx.routeKeyToID[routeKey]; exists {
 			switch opts.ConflictStrategy {
 			case "skip", "":
@@ -608,6 +673,10 @@ func (s *BackupService) importRoutes(routes []domain.BackupRoute, opts domain.Im
 			ProviderID:    providerID,
 			Position:      br.Position,
 			RetryConfigID: retryConfigID,
+			ForceDetail:   br.ForceDetail,
+			Multiplier:    br.Multiplier,
+
+			MaxStreamDurationMs: br.MaxStreamDurationMs,
 		}
 
 		if !opts.DryRun {
@@ -710,7 +779,7 @@ func (s *BackupService) importModelMappings(mappings []domain.BackupModelMapping
 
 		if bm.ProviderName != "" {
 			var ok bool
-			providerID, ok = ctx.providerNameToID[bm.ProviderName]
+			providerID, ok = ctx.providerNameToID[ctx.providerName(bm.ProviderName)]
 			if !ok {
 				result.Warnings = append(result.Warnings, fmt.Sprintf("ModelMapping skipped: provider '%s' not found", bm.ProviderName))
 				summary.Skipped++
@@ -730,7 +799,7 @@ func (s *BackupService) importModelMappings(mappings []domain.BackupModelMapping
 
 		if bm.RouteName != "" {
 			var ok bool
-			routeID, ok = ctx.routeKeyToID[bm.RouteName]
+			routeID, ok = ctx.routeKeyToID[ctx.routeKey(bm.RouteName)]
 			if !ok {
 				result.Warnings = append(result.Warnings, fmt.Sprintf("ModelMapping skipped: route '%s' not found", bm.RouteName))
 				summary.Skipped++
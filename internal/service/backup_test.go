@@ -0,0 +1,34 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestImportProvidersRenameOnConflict(t *testing.T) {
+	ctx := newImportContext()
+	ctx.providerNameToID["relay"] = 1
+	ctx.providerNameToID["relay (2)"] = 2
+
+	result := domain.NewImportResult()
+	opts := domain.ImportOptions{ConflictStrategy: "skip", DryRun: true, RenameOnConflict: true}
+	(&BackupService{}).importProviders([]domain.BackupProvider{{Name: "relay"}, {Name: "other"}}, opts, result, ctx)
+
+	if got := result.Summary["providers"]; got.Imported != 2 || got.Skipped != 0 {
+		t.Fatalf("summary = %+v, want 2 imported", got)
+	}
+	if len(result.ProviderRenames) != 1 || result.ProviderRenames["relay"] != "relay (3)" {
+		t.Errorf("renames = %v, want relay -> relay (3)", result.ProviderRenames)
+	}
+	// 备份中引用原名称的路由/模型映射解析到重命名后的 Provider
+	if got := ctx.providerName("relay"); got != "relay (3)" {
+		t.Errorf("providerName(relay) = %q", got)
+	}
+	if got := ctx.routeKey("relay:claude:web"); got != "relay (3):claude:web" {
+		t.Errorf("routeKey = %q", got)
+	}
+	if got := ctx.routeKey("other:claude:"); got != "other:claude:" {
+		t.Errorf("routeKey for unrenamed provider = %q", got)
+	}
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"github.com/awsl-project/maxx/internal/converter"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// allClientTypes 所有客户端类型，按展示顺序
+var allClientTypes = []domain.ClientType{
+	domain.ClientTypeClaude,
+	domain.ClientTypeOpenAI,
+	domain.ClientTypeCodex,
+	domain.ClientTypeGemini,
+}
+
+// providerTypeCapabilities 各 Provider 类型原生支持的客户端类型与功能
+// 所有类型都支持流式与工具调用；嵌入（embeddings）接口目前不经过代理
+var providerTypeCapabilities = []domain.ProviderTypeCapabilities{
+	// Custom 使用配置的 SupportedClientTypes，未配置时默认为 OpenAI
+	{Type: "custom", NativeClientTypes: []domain.ClientType{domain.ClientTypeOpenAI}, ConfigurableClientTypes: true, Streaming: true, Tools: true},
+	// Antigravity 原生支持 Claude 和 Gemini，OpenAI 请求由 Executor 转换为 Claude 格式
+	{Type: "antigravity", NativeClientTypes: []domain.ClientType{domain.ClientTypeClaude, domain.ClientTypeGemini}, Streaming: true, Tools: true},
+	// Kiro 只支持 Claude 协议
+	{Type: "kiro", NativeClientTypes: []domain.ClientType{domain.ClientTypeClaude}, Streaming: true, Tools: true},
+	// Codex 只支持 Codex 协议
+	{Type: "codex", NativeClientTypes: []domain.ClientType{domain.ClientTypeCodex}, Streaming: true, Tools: true},
+}
+
+// nativeClientTypes returns the client types a provider type supports natively
+// (the default for configurable types), or nil for an unknown type
+func nativeClientTypes(providerType string) (types []domain.ClientType, configurable bool) {
+	for _, c := range providerTypeCapabilities {
+		if c.Type == providerType {
+			return append([]domain.ClientType(nil), c.NativeClientTypes...), c.ConfigurableClientTypes
+		}
+	}
+	return nil, false
+}
+
+// GetCapabilities returns which client types each provider type supports,
+// natively or through format conversion, and which features it supports
+func (s *AdminService) GetCapabilities() *domain.Capabilities {
+	registry := converter.GetGlobalRegistry()
+	caps := &domain.Capabilities{}
+
+	for _, ct := range allClientTypes {
+		c := &domain.ClientTypeCapabilities{Type: ct, ConvertsTo: []domain.ClientType{}}
+		for _, target := range allClientTypes {
+			if target != ct && registry.CanConvert(ct, target) {
+				c.ConvertsTo = append(c.ConvertsTo, target)
+			}
+		}
+		caps.ClientTypes = append(caps.ClientTypes, c)
+	}
+
+	for _, pc := range providerTypeCapabilities {
+		c := pc
+		c.NativeClientTypes, _ = nativeClientTypes(pc.Type)
+		c.ConvertedClientTypes = []domain.ClientType{}
+		for _, ct := range allClientTypes {
+			if containsClientType(c.NativeClientTypes, ct) {
+				continue
+			}
+			// Executor 转换到 Provider 支持的任一类型即可接入
+			for _, target := range c.NativeClientTypes {
+				if registry.CanConvert(ct, target) {
+					c.ConvertedClientTypes = append(c.ConvertedClientTypes, ct)
+					break
+				}
+			}
+		}
+		caps.ProviderTypes = append(caps.ProviderTypes, &c)
+	}
+	return caps
+}
+
+func containsClientType(types []domain.ClientType, ct domain.ClientType) bool {
+	for _, t := range types {
+		if t == ct {
+			return true
+		}
+	}
+	return false
+}
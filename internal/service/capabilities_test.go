@@ -0,0 +1,48 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestGetCapabilities(t *testing.T) {
+	caps := (&AdminService{}).GetCapabilities()
+
+	byType := make(map[string]*domain.ProviderTypeCapabilities)
+	for _, c := range caps.ProviderTypes {
+		byType[c.Type] = c
+	}
+	kiro := byType["kiro"]
+	if kiro == nil || len(kiro.NativeClientTypes) != 1 || kiro.NativeClientTypes[0] != domain.ClientTypeClaude {
+		t.Fatalf("kiro capabilities = %+v, want native claude", kiro)
+	}
+	// 其他格式都能转换为 Claude 接入 Kiro
+	if len(kiro.ConvertedClientTypes) != 3 || containsClientType(kiro.ConvertedClientTypes, domain.ClientTypeClaude) {
+		t.Errorf("kiro converted client types = %v, want every other client type", kiro.ConvertedClientTypes)
+	}
+	if custom := byType["custom"]; custom == nil || !custom.ConfigurableClientTypes {
+		t.Errorf("custom capabilities = %+v, want configurable client types", custom)
+	}
+
+	for _, c := range caps.ClientTypes {
+		if containsClientType(c.ConvertsTo, c.Type) {
+			t.Errorf("client type %s lists itself as a conversion target", c.Type)
+		}
+	}
+}
+
+func TestAutoSetSupportedClientTypes(t *testing.T) {
+	s := &AdminService{}
+	p := &domain.Provider{Type: "antigravity"}
+	s.autoSetSupportedClientTypes(p)
+	if len(p.SupportedClientTypes) != 2 {
+		t.Errorf("antigravity supported client types = %v, want claude and gemini", p.SupportedClientTypes)
+	}
+
+	custom := &domain.Provider{Type: "custom", SupportedClientTypes: []domain.ClientType{domain.ClientTypeClaude}}
+	s.autoSetSupportedClientTypes(custom)
+	if len(custom.SupportedClientTypes) != 1 || custom.SupportedClientTypes[0] != domain.ClientTypeClaude {
+		t.Errorf("custom supported client types = %v, want configured value kept", custom.SupportedClientTypes)
+	}
+}
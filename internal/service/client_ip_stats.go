@@ -0,0 +1,36 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// Client IP stats limits
+const (
+	clientIPStatsDefaultWindow = 24 * time.Hour // 未指定 start 时统计最近 24 小时
+	clientIPStatsDefaultLimit  = 100
+	clientIPStatsMaxLimit      = 1000
+)
+
+// GetClientIPStats 按客户端 IP 汇总 [start, end) 内的请求，按请求数降序。
+// end 默认为当前时间，start 默认为 end 前 24 小时；limit <= 0 时取默认值
+func (s *AdminService) GetClientIPStats(start, end time.Time, limit int) ([]*domain.ClientIPStats, error) {
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	if start.IsZero() {
+		start = end.Add(-clientIPStatsDefaultWindow)
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("%w: start must be before end", domain.ErrInvalidInput)
+	}
+	if limit <= 0 {
+		limit = clientIPStatsDefaultLimit
+	}
+	if limit > clientIPStatsMaxLimit {
+		return nil, fmt.Errorf("%w: limit must be at most %d", domain.ErrInvalidInput, clientIPStatsMaxLimit)
+	}
+	return s.proxyRequestRepo.StatsByClientIP(start, end, limit)
+}
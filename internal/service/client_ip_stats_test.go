@@ -0,0 +1,59 @@
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+func TestGetClientIPStats(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	requestRepo := sqlite.NewProxyRequestRepository(db)
+	svc := &AdminService{proxyRequestRepo: requestRepo}
+
+	for _, req := range []*domain.ProxyRequest{
+		{ClientIP: "198.51.100.7", Status: "COMPLETED", InputTokenCount: 10, OutputTokenCount: 5, Cost: 100, Billable: true},
+		{ClientIP: "198.51.100.7", Status: "FAILED", Cost: 50, Billable: true},
+		{ClientIP: "198.51.100.7", Status: "COMPLETED", Cost: 1000}, // 不计费
+		{ClientIP: "2001:db8::1", Status: "COMPLETED", InputTokenCount: 1, Cost: 7, Billable: true},
+		{Status: "COMPLETED", Cost: 9, Billable: true}, // 未记录 IP
+	} {
+		if err := requestRepo.Create(req); err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+	}
+
+	stats, err := svc.GetClientIPStats(time.Time{}, time.Now().Add(time.Second), 0)
+	if err != nil {
+		t.Fatalf("GetClientIPStats: %v", err)
+	}
+	if len(stats) != 2 {
+		t.Fatalf("stats = %+v, want 2 client IPs", stats)
+	}
+	top := stats[0]
+	if top.ClientIP != "198.51.100.7" || top.Requests != 3 || top.Failed != 1 ||
+		top.InputTokens != 10 || top.OutputTokens != 5 || top.Cost != 150 || top.LastSeen.IsZero() {
+		t.Errorf("top = %+v", top)
+	}
+	if stats[1].ClientIP != "2001:db8::1" || stats[1].Requests != 1 {
+		t.Errorf("second = %+v", stats[1])
+	}
+
+	if stats, _ := svc.GetClientIPStats(time.Time{}, time.Now().Add(time.Second), 1); len(stats) != 1 {
+		t.Errorf("limit 1 returned %d IPs", len(stats))
+	}
+	if stats, _ := svc.GetClientIPStats(time.Now().Add(time.Minute), time.Now().Add(time.Hour), 0); len(stats) != 0 {
+		t.Errorf("window without requests returned %+v", stats)
+	}
+	now := time.Now()
+	if _, err := svc.GetClientIPStats(now, now.Add(-time.Hour), 0); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("reversed window err = %v, want ErrInvalidInput", err)
+	}
+}
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// ConcurrencyHistorySource provides sampled active request counts.
+// Implemented by core.RequestTracker.
+type ConcurrencyHistorySource interface {
+	ConcurrencyHistory(since time.Time, projectID uint64) []domain.ConcurrencySample
+}
+
+// GetConcurrencyHistory returns the sampled in-flight request counts after
+// since, oldest first; projectID > 0 restricts the counts to that project
+func (s *AdminService) GetConcurrencyHistory(since time.Time, projectID uint64) []domain.ConcurrencySample {
+	if s.concurrencyHistory == nil {
+		return []domain.ConcurrencySample{}
+	}
+	return s.concurrencyHistory.ConcurrencyHistory(since, projectID)
+}
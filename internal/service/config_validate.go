@@ -0,0 +1,323 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/pricing"
+	"github.com/awsl-project/maxx/internal/repository"
+	"github.com/awsl-project/maxx/internal/router"
+)
+
+// ConfigCheckTimeout 在线检测 Provider 连通性的总超时
+const ConfigCheckTimeout = 15 * time.Second
+
+// ConfigIssueSeverity 配置问题的严重程度
+type ConfigIssueSeverity string
+
+const (
+	ConfigIssueError   ConfigIssueSeverity = "error"
+	ConfigIssueWarning ConfigIssueSeverity = "warning"
+)
+
+// ConfigIssue 一条配置问题
+type ConfigIssue struct {
+	Severity ConfigIssueSeverity `json:"severity"`
+	Category string              `json:"category"` // provider / route / model_mapping / setting
+	Subject  string              `json:"subject"`  // 如 provider #3 "relay"
+	Message  string              `json:"message"`
+}
+
+// ConfigValidationReport 配置校验结果
+type ConfigValidationReport struct {
+	Issues   []*ConfigIssue `json:"issues"`
+	Errors   int            `json:"errors"`
+	Warnings int            `json:"warnings"`
+}
+
+func (r *ConfigValidationReport) add(severity ConfigIssueSeverity, category, subject, format string, args ...any) {
+	r.Issues = append(r.Issues, &ConfigIssue{Severity: severity, Category: category, Subject: subject, Message: fmt.Sprintf(format, args...)})
+	if severity == ConfigIssueError {
+		r.Errors++
+	} else {
+		r.Warnings++
+	}
+}
+
+// ConfigRouter 路由预览与 Provider 连通性检测，由 *router.Router 实现
+type ConfigRouter interface {
+	Match(ctx *router.MatchContext) ([]*router.MatchedRoute, error)
+	CheckProviders(ctx context.Context) []*router.ProviderCheckResult
+}
+
+// ConfigValidator 上线前检查数据库中的配置：Provider 凭证、路由、模型映射和系统设置
+type ConfigValidator struct {
+	providerRepo      repository.ProviderRepository
+	routeRepo         repository.RouteRepository
+	modelMappingRepo  repository.ModelMappingRepository
+	modelPriceRepo    repository.ModelPriceRepository
+	responseModelRepo repository.ResponseModelRepository
+	settingRepo       repository.SystemSettingRepository
+	router            ConfigRouter
+}
+
+// NewConfigValidator creates a new config validator
+func NewConfigValidator(
+	providerRepo repository.ProviderRepository,
+	routeRepo repository.RouteRepository,
+	modelMappingRepo repository.ModelMappingRepository,
+	modelPriceRepo repository.ModelPriceRepository,
+	responseModelRepo repository.ResponseModelRepository,
+	settingRepo repository.SystemSettingRepository,
+	configRouter ConfigRouter,
+) *ConfigValidator {
+	return &ConfigValidator{
+		providerRepo:      providerRepo,
+		routeRepo:         routeRepo,
+		modelMappingRepo:  modelMappingRepo,
+		modelPriceRepo:    modelPriceRepo,
+		responseModelRepo: responseModelRepo,
+		settingRepo:       settingRepo,
+		router:            configRouter,
+	}
+}
+
+// Validate 检查配置并返回所有问题；live 为 true 时额外检测各 Provider 的连通性/认证
+func (v *ConfigValidator) Validate(ctx context.Context, live bool) (*ConfigValidationReport, error) {
+	report := &ConfigValidationReport{Issues: []*ConfigIssue{}}
+
+	providers, err := v.providerRepo.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list providers: %w", err)
+	}
+	byID := make(map[uint64]*domain.Provider, len(providers))
+	for _, p := range providers {
+		byID[p.ID] = p
+		v.validateProvider(report, p)
+	}
+
+	routes, err := v.routeRepo.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list routes: %w", err)
+	}
+	v.validateRoutes(report, routes, byID)
+
+	if err := v.validateModelMappings(report, providers, byID, routes); err != nil {
+		return nil, err
+	}
+
+	settings, err := v.settingRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list settings: %w", err)
+	}
+	for _, s := range settings {
+		if err := ValidateSetting(s.Key, s.Value); err != nil {
+			report.add(ConfigIssueError, "setting", s.Key, "%v", err)
+		}
+	}
+
+	if live && v.router != nil {
+		checkCtx, cancel := context.WithTimeout(ctx, ConfigCheckTimeout)
+		defer cancel()
+		for _, result := range v.router.CheckProviders(checkCtx) {
+			if result.Err != nil {
+				report.add(ConfigIssueError, "provider", providerSubject(byID[result.ProviderID], result.ProviderID), "unreachable: %v", result.Err)
+			}
+		}
+	}
+
+	return report, nil
+}
+
+// validateProvider 检查凭证是否完整，以及保存 Provider 时的校验规则
+func (v *ConfigValidator) validateProvider(report *ConfigValidationReport, p *domain.Provider) {
+	subject := providerSubject(p, p.ID)
+	cfg := p.Config
+	if cfg == nil {
+		cfg = &domain.ProviderConfig{}
+	}
+
+	var missing []string
+	switch p.Type {
+	case "custom":
+		if cfg.Custom == nil || cfg.Custom.BaseURL == "" {
+			missing = append(missing, "baseURL")
+		}
+		if cfg.Custom == nil || cfg.Custom.APIKey == "" {
+			missing = append(missing, "apiKey")
+		}
+	case "antigravity":
+		if cfg.Antigravity == nil || cfg.Antigravity.RefreshToken == "" {
+			missing = append(missing, "refreshToken")
+		}
+	case "kiro":
+		if cfg.Kiro == nil || cfg.Kiro.RefreshToken == "" {
+			missing = append(missing, "refreshToken")
+		}
+		if cfg.Kiro != nil && cfg.Kiro.AuthMethod == "idc" && (cfg.Kiro.ClientID == "" || cfg.Kiro.ClientSecret == "") {
+			missing = append(missing, "clientId/clientSecret")
+		}
+	case "codex":
+		if cfg.Codex == nil || cfg.Codex.RefreshToken == "" {
+			missing = append(missing, "refreshToken")
+		}
+	default:
+		report.add(ConfigIssueError, "provider", subject, "unknown provider type %q", p.Type)
+		return
+	}
+	if len(missing) > 0 {
+		report.add(ConfigIssueError, "provider", subject, "missing credentials: %s", strings.Join(missing, ", "))
+	}
+
+	for _, validate := range []func(*domain.Provider) error{
+		validateProviderMultipliers,
+		validateProviderTransport,
+		validateProviderSoftFailurePatterns,
+		validateProviderUnsupportedParams,
+		validateProviderStreamTextRewrites,
+		validateProviderMaxStreamDuration,
+	} {
+		if err := validate(p); err != nil {
+			report.add(ConfigIssueError, "provider", subject, "%v", err)
+		}
+	}
+}
+
+// validateRoutes 用路由预览（Router.Match）检查每个 ClientType/项目是否有可用路由，
+// 并报告指向已删除、禁用了该 ClientType 或排空中 Provider 的路由
+func (v *ConfigValidator) validateRoutes(report *ConfigValidationReport, routes []*domain.Route, providers map[uint64]*domain.Provider) {
+	type scope struct {
+		clientType domain.ClientType
+		projectID  uint64
+	}
+	var scopes []scope
+	seen := make(map[scope]bool)
+	for _, route := range routes {
+		if !route.IsEnabled {
+			if _, ok := providers[route.ProviderID]; !ok {
+				report.add(ConfigIssueWarning, "route", routeSubject(route), "disabled route points at deleted provider #%d", route.ProviderID)
+			}
+			continue
+		}
+		s := scope{route.ClientType, route.ProjectID}
+		if !seen[s] {
+			seen[s] = true
+			scopes = append(scopes, s)
+		}
+	}
+	if v.router == nil {
+		return
+	}
+
+	reported := make(map[uint64]bool)
+	for _, s := range scopes {
+		trace := &domain.RoutingTrace{}
+		_, err := v.router.Match(&router.MatchContext{ClientType: s.clientType, ProjectID: s.projectID, Trace: trace})
+		for _, step := range trace.Steps {
+			if step.Action != domain.RoutingTraceSkipped || step.RouteID == 0 || reported[step.RouteID] {
+				continue
+			}
+			reported[step.RouteID] = true
+			subject := fmt.Sprintf("route #%d (%s)", step.RouteID, s.clientType)
+			switch step.Reason {
+			case "provider not found":
+				report.add(ConfigIssueError, "route", subject, "points at deleted provider #%d", step.ProviderID)
+			case "no adapter for provider type":
+				report.add(ConfigIssueError, "route", subject, "provider %s has no adapter (unknown type or adapter failed to initialize)", providerSubject(providers[step.ProviderID], step.ProviderID))
+			default:
+				report.add(ConfigIssueWarning, "route", subject, "skipped: %s (%s)", step.Reason, providerSubject(providers[step.ProviderID], step.ProviderID))
+			}
+		}
+		if errors.Is(err, domain.ErrNoRoutes) {
+			where := "global routes"
+			if s.projectID != 0 {
+				where = fmt.Sprintf("project #%d", s.projectID)
+			}
+			report.add(ConfigIssueError, "route", fmt.Sprintf("%s / %s", s.clientType, where), "no usable route: every enabled route is skipped")
+		}
+	}
+}
+
+// validateModelMappings 报告不匹配任何已知模型（默认/自定义价格表、响应中出现过的模型、Provider 支持的模型）
+// 或作用域指向已删除的 Provider/路由的模型映射
+func (v *ConfigValidator) validateModelMappings(report *ConfigValidationReport, providers []*domain.Provider, byID map[uint64]*domain.Provider, routes []*domain.Route) error {
+	mappings, err := v.modelMappingRepo.List()
+	if err != nil {
+		return fmt.Errorf("failed to list model mappings: %w", err)
+	}
+	if len(mappings) == 0 {
+		return nil
+	}
+
+	known := make(map[string]bool)
+	for _, p := range pricing.DefaultPriceTable().All() {
+		known[p.ModelID] = true
+	}
+	if prices, err := v.modelPriceRepo.ListCurrentPrices(); err == nil {
+		for _, p := range prices {
+			known[p.ModelID] = true
+		}
+	}
+	if names, err := v.responseModelRepo.ListNames(); err == nil {
+		for _, name := range names {
+			known[name] = true
+		}
+	}
+	for _, p := range providers {
+		for _, m := range p.SupportModels {
+			if !strings.Contains(m, "*") {
+				known[m] = true
+			}
+		}
+	}
+	models := make([]string, 0, len(known))
+	for m := range known {
+		models = append(models, m)
+	}
+	sort.Strings(models)
+
+	routeIDs := make(map[uint64]bool, len(routes))
+	for _, r := range routes {
+		routeIDs[r.ID] = true
+	}
+
+	for _, m := range mappings {
+		subject := fmt.Sprintf("model mapping #%d %q -> %q", m.ID, m.Pattern, m.Target)
+		if m.ProviderID != 0 && byID[m.ProviderID] == nil {
+			report.add(ConfigIssueWarning, "model_mapping", subject, "scoped to deleted provider #%d", m.ProviderID)
+		}
+		if m.RouteID != 0 && !routeIDs[m.RouteID] {
+			report.add(ConfigIssueWarning, "model_mapping", subject, "scoped to deleted route #%d", m.RouteID)
+		}
+		if m.Pattern == "*" || len(models) == 0 {
+			continue
+		}
+		matched := false
+		for _, model := range models {
+			if domain.MatchWildcard(m.Pattern, model) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			report.add(ConfigIssueWarning, "model_mapping", subject, "pattern matches no known model")
+		}
+	}
+	return nil
+}
+
+func providerSubject(p *domain.Provider, id uint64) string {
+	if p == nil {
+		return fmt.Sprintf("provider #%d", id)
+	}
+	return fmt.Sprintf("provider #%d %q", p.ID, p.Name)
+}
+
+func routeSubject(r *domain.Route) string {
+	return fmt.Sprintf("route #%d (%s)", r.ID, r.ClientType)
+}
@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/awsl-project/maxx/internal/adapter/provider/custom"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository/cached"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+	"github.com/awsl-project/maxx/internal/router"
+)
+
+func TestConfigValidator(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	providerRepo := sqlite.NewProviderRepository(db)
+	routeRepo := sqlite.NewRouteRepository(db)
+	mappingRepo := sqlite.NewModelMappingRepository(db)
+	settingRepo := sqlite.NewSystemSettingRepository(db)
+	responseModelRepo := sqlite.NewResponseModelRepository(db)
+
+	good := &domain.Provider{Name: "good", Type: "custom", Config: &domain.ProviderConfig{Custom: &domain.ProviderConfigCustom{BaseURL: "https://relay.example.com", APIKey: "sk-1"}}}
+	noKey := &domain.Provider{Name: "no-key", Type: "custom", Config: &domain.ProviderConfig{Custom: &domain.ProviderConfigCustom{BaseURL: "https://relay.example.com"}}}
+	gone := &domain.Provider{Name: "gone", Type: "custom", Config: &domain.ProviderConfig{Custom: &domain.ProviderConfigCustom{BaseURL: "https://relay.example.com", APIKey: "sk-2"}}}
+	for _, p := range []*domain.Provider{good, noKey, gone} {
+		if err := providerRepo.Create(p); err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+	}
+	deadRoute := &domain.Route{IsEnabled: true, ClientType: domain.ClientTypeClaude, ProviderID: gone.ID, Position: 1}
+	for _, r := range []*domain.Route{
+		{IsEnabled: true, ClientType: domain.ClientTypeClaude, ProviderID: good.ID, Position: 0},
+		deadRoute,
+		// openai 只有指向已删除 Provider 的路由，没有可用路由
+		{IsEnabled: true, ClientType: domain.ClientTypeOpenAI, ProviderID: gone.ID},
+	} {
+		if err := routeRepo.Create(r); err != nil {
+			t.Fatalf("create route: %v", err)
+		}
+	}
+	if err := providerRepo.Delete(gone.ID); err != nil {
+		t.Fatalf("delete provider: %v", err)
+	}
+
+	_ = responseModelRepo.Upsert("claude-sonnet-4")
+	for _, m := range []*domain.ModelMapping{
+		{Scope: domain.ModelMappingScopeGlobal, Pattern: "claude-*", Target: "claude-sonnet-4"},
+		{Scope: domain.ModelMappingScopeGlobal, Pattern: "gpt-4o-typo*", Target: "gpt-4o"},
+	} {
+		if err := mappingRepo.Create(m); err != nil {
+			t.Fatalf("create mapping: %v", err)
+		}
+	}
+	_ = settingRepo.Set(domain.SettingKeyAPITokenConcurrencyMode, "drop")
+	_ = settingRepo.Set(domain.SettingKeyTimezone, "Asia/Shanghai")
+
+	cachedProviders := cached.NewProviderRepository(providerRepo)
+	cachedRoutes := cached.NewRouteRepository(routeRepo)
+	cachedRetry := cached.NewRetryConfigRepository(sqlite.NewRetryConfigRepository(db))
+	cachedStrategies := cached.NewRoutingStrategyRepository(sqlite.NewRoutingStrategyRepository(db))
+	cachedProjects := cached.NewProjectRepository(sqlite.NewProjectRepository(db))
+	for _, load := range []func() error{cachedProviders.Load, cachedRoutes.Load, cachedRetry.Load, cachedStrategies.Load, cachedProjects.Load} {
+		if err := load(); err != nil {
+			t.Fatalf("load cache: %v", err)
+		}
+	}
+	r := router.NewRouter(cachedRoutes, cachedProviders, cachedStrategies, cachedRetry, cachedProjects)
+	if err := r.InitAdapters(); err != nil {
+		t.Fatalf("InitAdapters: %v", err)
+	}
+
+	v := NewConfigValidator(providerRepo, routeRepo, mappingRepo, sqlite.NewModelPriceRepository(db), responseModelRepo, settingRepo, r)
+	report, err := v.Validate(context.Background(), false)
+	if err != nil {
+		t.Fatalf("Validate failed: %v", err)
+	}
+
+	var got []string
+	for _, issue := range report.Issues {
+		got = append(got, string(issue.Severity)+" "+issue.Category+" "+issue.Subject+": "+issue.Message)
+	}
+	joined := strings.Join(got, "\n")
+	for _, want := range []string{
+		`error provider provider #2 "no-key": missing credentials: apiKey`,
+		"error route route #2 (claude): points at deleted provider",
+		"error route openai / global routes: no usable route",
+		`warning model_mapping`,
+		"gpt-4o-typo*",
+		"error setting api_token_concurrency_mode",
+	} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("report missing %q, got:\n%s", want, joined)
+		}
+	}
+	if strings.Contains(joined, `"claude-*"`) || strings.Contains(joined, "timezone") {
+		t.Errorf("valid config reported as a problem:\n%s", joined)
+	}
+	if report.Errors != 5 {
+		t.Errorf("errors = %d, want 5:\n%s", report.Errors, joined)
+	}
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/event"
+	"github.com/awsl-project/maxx/internal/pricing"
+	"github.com/awsl-project/maxx/internal/repository"
+	"github.com/awsl-project/maxx/internal/stats"
+)
+
+// Defaults for cost anomaly detection
+const (
+	defaultCostAnomalyWindowHours   = 1
+	defaultCostAnomalyBaselineHours = 24
+	defaultCostAnomalyThreshold     = 2.0
+	defaultCostAnomalyMinRequests   = 10
+
+	// 近期窗口使用分钟数据，分钟数据只保留 1 天
+	maxCostAnomalyWindowHours = 24
+)
+
+// CostAnomalyService periodically compares each model's recent average cost per
+// request against the preceding baseline period and broadcasts a "cost_anomaly"
+// event when it rises significantly.
+type CostAnomalyService struct {
+	usageStatsRepo repository.UsageStatsRepository
+	settingRepo    repository.SystemSettingRepository
+	broadcaster    event.Broadcaster
+
+	mu        sync.Mutex
+	lastAlert map[string]time.Time // model -> 上次告警时间，同一窗口内不重复告警
+	now       func() time.Time
+}
+
+// NewCostAnomalyService creates a new CostAnomalyService
+func NewCostAnomalyService(
+	usageStatsRepo repository.UsageStatsRepository,
+	settingRepo repository.SystemSettingRepository,
+	broadcaster event.Broadcaster,
+) *CostAnomalyService {
+	return &CostAnomalyService{
+		usageStatsRepo: usageStatsRepo,
+		settingRepo:    settingRepo,
+		broadcaster:    broadcaster,
+		lastAlert:      make(map[string]time.Time),
+		now:            time.Now,
+	}
+}
+
+// IsEnabled returns whether cost anomaly detection is enabled
+func (s *CostAnomalyService) IsEnabled() bool {
+	val, _ := s.settingRepo.Get(domain.SettingKeyCostAnomalyEnabled)
+	return val == "true"
+}
+
+// Evaluate runs one detection pass and broadcasts newly detected anomalies.
+// Returns the anomalies that were reported.
+func (s *CostAnomalyService) Evaluate() []*domain.CostAnomaly {
+	window := time.Duration(min(s.getSettingInt(domain.SettingKeyCostAnomalyWindowHours, defaultCostAnomalyWindowHours), maxCostAnomalyWindowHours)) * time.Hour
+	baseline := time.Duration(s.getSettingInt(domain.SettingKeyCostAnomalyBaselineHours, defaultCostAnomalyBaselineHours)) * time.Hour
+	cfg := stats.CostAnomalyConfig{
+		Threshold:   s.getSettingFloat(domain.SettingKeyCostAnomalyThreshold, defaultCostAnomalyThreshold),
+		MinRequests: uint64(s.getSettingInt(domain.SettingKeyCostAnomalyMinRequests, defaultCostAnomalyMinRequests)),
+	}
+
+	data, recentStart, err := s.queryWindows(window, baseline)
+	if err != nil {
+		log.Printf("[CostAnomaly] Failed to query usage stats: %v", err)
+		return nil
+	}
+	now := s.now()
+
+	var reported []*domain.CostAnomaly
+	s.mu.Lock()
+	for _, a := range stats.DetectCostAnomalies(data, recentStart, cfg, now) {
+		if last, ok := s.lastAlert[a.Model]; ok && now.Sub(last) < window {
+			continue
+		}
+		s.lastAlert[a.Model] = now
+		reported = append(reported, a)
+	}
+	s.mu.Unlock()
+
+	for _, a := range reported {
+		log.Printf("[CostAnomaly] Model %s average cost per request rose %.1fx: $%.6f (%d requests) vs baseline $%.6f (%d requests)",
+			a.Model, a.Ratio, pricing.NanoToUSD(a.RecentAvgCost), a.RecentRequests,
+			pricing.NanoToUSD(a.BaselineAvgCost), a.BaselineRequests)
+		if s.broadcaster != nil {
+			s.broadcaster.BroadcastMessage("cost_anomaly", a)
+		}
+	}
+	return reported
+}
+
+// queryWindows 查询近期窗口与基线窗口的统计数据，返回近期窗口的开始时间。
+// 近期窗口是截至当前的滑动窗口，使用分钟数据（从 recentStart 所在小时开始，
+// recentStart 之前的分钟计入基线）；更早的基线部分使用小时数据（保留 1 个月），
+// 基线开始时间向下取整到整点
+func (s *CostAnomalyService) queryWindows(window, baseline time.Duration) ([]*domain.UsageStats, time.Time, error) {
+	now := s.now()
+	recentStart := now.Add(-window).Truncate(time.Minute)
+	splitHour := recentStart.Truncate(time.Hour)
+	start := recentStart.Add(-baseline).Truncate(time.Hour)
+
+	hourEnd := splitHour.Add(-time.Millisecond)
+	data, err := s.usageStatsRepo.Query(repository.UsageStatsFilter{
+		Granularity: domain.GranularityHour,
+		StartTime:   &start,
+		EndTime:     &hourEnd,
+	})
+	if err != nil {
+		return nil, recentStart, err
+	}
+	minutes, err := s.usageStatsRepo.Query(repository.UsageStatsFilter{
+		Granularity: domain.GranularityMinute,
+		StartTime:   &splitHour,
+		EndTime:     &now,
+	})
+	if err != nil {
+		return nil, recentStart, err
+	}
+	return append(data, minutes...), recentStart, nil
+}
+
+func (s *CostAnomalyService) getSettingInt(key string, defaultValue int) int {
+	val, err := s.settingRepo.Get(key)
+	if err != nil || val == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n <= 0 {
+		return defaultValue
+	}
+	return n
+}
+
+func (s *CostAnomalyService) getSettingFloat(key string, defaultValue float64) float64 {
+	val, err := s.settingRepo.Get(key)
+	if err != nil || val == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil || f <= 0 {
+		return defaultValue
+	}
+	return f
+}
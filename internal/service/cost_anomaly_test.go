@@ -0,0 +1,80 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+// anomalyUsageRepo 按粒度和时间范围返回固定的统计数据并记录查询
+type anomalyUsageRepo struct {
+	repository.UsageStatsRepository
+	rows    []*domain.UsageStats
+	filters []repository.UsageStatsFilter
+}
+
+func (r *anomalyUsageRepo) Query(filter repository.UsageStatsFilter) ([]*domain.UsageStats, error) {
+	r.filters = append(r.filters, filter)
+	var result []*domain.UsageStats
+	for _, row := range r.rows {
+		if row.Granularity == filter.Granularity && !row.TimeBucket.Before(*filter.StartTime) && !row.TimeBucket.After(*filter.EndTime) {
+			result = append(result, row)
+		}
+	}
+	return result, nil
+}
+
+func TestCostAnomalyTrailingWindow(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	settingRepo := sqlite.NewSystemSettingRepository(db)
+	if err := settingRepo.Set(domain.SettingKeyCostAnomalyMinRequests, "5"); err != nil {
+		t.Fatalf("set setting: %v", err)
+	}
+
+	now := time.Date(2026, 3, 15, 13, 37, 20, 0, time.UTC)
+	at := func(hour, minute int) time.Time { return time.Date(2026, 3, 15, hour, minute, 0, 0, time.UTC) }
+	repo := &anomalyUsageRepo{rows: []*domain.UsageStats{
+		// 基线：1000/请求
+		{Granularity: domain.GranularityHour, TimeBucket: at(11, 0), Model: "claude", TotalRequests: 10, Cost: 10_000},
+		{Granularity: domain.GranularityMinute, TimeBucket: at(12, 10), Model: "claude", TotalRequests: 10, Cost: 10_000},
+		// 近期 60 分钟（12:37 起）跨两个小时：5000/请求
+		{Granularity: domain.GranularityMinute, TimeBucket: at(12, 50), Model: "claude", TotalRequests: 4, Cost: 20_000},
+		{Granularity: domain.GranularityMinute, TimeBucket: at(13, 20), Model: "claude", TotalRequests: 4, Cost: 20_000},
+		// 已被分钟数据覆盖的小时桶不重复计入
+		{Granularity: domain.GranularityHour, TimeBucket: at(12, 0), Model: "claude", TotalRequests: 18, Cost: 30_000},
+	}}
+	s := NewCostAnomalyService(repo, settingRepo, nil)
+	s.now = func() time.Time { return now }
+
+	got := s.Evaluate()
+	if len(got) != 1 {
+		t.Fatalf("anomalies = %+v, want 1", got)
+	}
+	a := got[0]
+	if a.RecentRequests != 8 || a.RecentAvgCost != 5000 || a.BaselineRequests != 20 || a.BaselineAvgCost != 1000 {
+		t.Errorf("anomaly = %+v, want 8 recent requests at 5000 vs 20 baseline at 1000", a)
+	}
+
+	if len(repo.filters) != 2 {
+		t.Fatalf("queries = %d, want 2", len(repo.filters))
+	}
+	hour, minute := repo.filters[0], repo.filters[1]
+	if hour.Granularity != domain.GranularityHour || !hour.StartTime.Equal(at(12, 0).Add(-24*time.Hour)) || !hour.EndTime.Before(at(12, 0)) {
+		t.Errorf("hour filter = %s [%v, %v]", hour.Granularity, hour.StartTime, hour.EndTime)
+	}
+	if minute.Granularity != domain.GranularityMinute || !minute.StartTime.Equal(at(12, 0)) || !minute.EndTime.Equal(now) {
+		t.Errorf("minute filter = %s [%v, %v]", minute.Granularity, minute.StartTime, minute.EndTime)
+	}
+
+	// 同一窗口内不重复告警
+	if got := s.Evaluate(); len(got) != 0 {
+		t.Errorf("repeated anomalies = %+v", got)
+	}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/pricing"
+	"github.com/awsl-project/maxx/internal/usage"
+)
+
+const (
+	// CostReconcileInterval 定期成本核对的间隔（未启用时只检查开关）
+	CostReconcileInterval = time.Hour
+
+	defaultCostReconcileLookbackHours = 24
+	costReconcileBatchSize            = 200
+	costReconcileMaxSamples           = 50
+)
+
+// ErrCostReconcileRunning 已有成本核对在执行
+var ErrCostReconcileRunning = errors.New("cost reconciliation already running")
+
+// CostDiscrepancy 单个请求的成本偏差
+type CostDiscrepancy struct {
+	RequestID     uint64 `json:"requestId"`
+	StoredCost    uint64 `json:"storedCost"`    // 请求上记录的成本
+	AttemptCost   uint64 `json:"attemptCost"`   // attempts 上记录的成本之和
+	ComputedCost  uint64 `json:"computedCost"`  // 按计费时的价格记录和倍率重算的成本之和
+	DriftAttempts int    `json:"driftAttempts"` // 记录成本与重算成本不一致的 attempt 数
+}
+
+// ReconcileCostsResult 成本核对汇总
+type ReconcileCostsResult struct {
+	Since             time.Time          `json:"since"`
+	Fix               bool               `json:"fix"`
+	CheckedRequests   int                `json:"checkedRequests"`
+	CheckedAttempts   int                `json:"checkedAttempts"`
+	Discrepancies     int                `json:"discrepancies"`
+	FixedRequests     int                `json:"fixedRequests"`
+	FixedAttempts     int                `json:"fixedAttempts"`
+	StoredCostTotal   uint64             `json:"storedCostTotal"`
+	ComputedCostTotal uint64             `json:"computedCostTotal"`
+	Samples           []*CostDiscrepancy `json:"samples"` // 最多 50 条偏差明细
+	DurationMs        int64              `json:"durationMs"`
+}
+
+// ReconcileCosts 核对 since 之后创建的已结束请求：按 attempt 计费时使用的价格记录
+// （ModelPriceID，含已被替换的历史价格）、价格覆盖和倍率重算每个 attempt 的成本，
+// 与请求上记录的成本比较。fix 为 false 时仅报告，为 true 时把 attempt 与请求成本
+// 修正为重算结果。
+func (s *AdminService) ReconcileCosts(since time.Time, fix bool) (*ReconcileCostsResult, error) {
+	if !s.reconcileMu.TryLock() {
+		return nil, ErrCostReconcileRunning
+	}
+	defer s.reconcileMu.Unlock()
+
+	start := time.Now()
+	result := &ReconcileCostsResult{Since: since, Fix: fix, Samples: []*CostDiscrepancy{}}
+	overrides := newPriceOverrideLookup(s.providerRepo)
+	prices := make(map[uint64]*domain.ModelPrice)
+
+	var afterID uint64
+	for {
+		requests, err := s.proxyRequestRepo.ListCostsSince(since, afterID, costReconcileBatchSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list request costs: %w", err)
+		}
+		if len(requests) == 0 {
+			break
+		}
+		afterID = requests[len(requests)-1].ID
+
+		ids := make([]uint64, len(requests))
+		for i, req := range requests {
+			ids[i] = req.ID
+		}
+		attempts, err := s.attemptRepo.ListForCostCalcByRequestIDs(ids)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list attempts: %w", err)
+		}
+
+		byRequest := make(map[uint64][]*domain.AttemptCostData, len(requests))
+		for _, attempt := range attempts {
+			byRequest[attempt.ProxyRequestID] = append(byRequest[attempt.ProxyRequestID], attempt)
+		}
+
+		attemptUpdates := make(map[uint64]uint64)
+		requestUpdates := make(map[uint64]uint64)
+		for _, req := range requests {
+			d := &CostDiscrepancy{RequestID: req.ID, StoredCost: req.Cost}
+			for _, attempt := range byRequest[req.ID] {
+				cost := s.reconcileAttemptCost(attempt, overrides, prices)
+				d.AttemptCost += attempt.Cost
+				d.ComputedCost += cost
+				if cost != attempt.Cost {
+					d.DriftAttempts++
+					attemptUpdates[attempt.ID] = cost
+				}
+			}
+			result.CheckedRequests++
+			result.CheckedAttempts += len(byRequest[req.ID])
+			result.StoredCostTotal += d.StoredCost
+			result.ComputedCostTotal += d.ComputedCost
+
+			if d.StoredCost == d.ComputedCost && d.DriftAttempts == 0 {
+				continue
+			}
+			result.Discrepancies++
+			if len(result.Samples) < costReconcileMaxSamples {
+				result.Samples = append(result.Samples, d)
+			}
+			if d.StoredCost != d.ComputedCost {
+				requestUpdates[req.ID] = d.ComputedCost
+			}
+		}
+
+		if fix {
+			if err := s.attemptRepo.BatchUpdateCosts(attemptUpdates); err != nil {
+				return nil, fmt.Errorf("failed to update attempt costs: %w", err)
+			}
+			result.FixedAttempts += len(attemptUpdates)
+			if err := s.proxyRequestRepo.BatchUpdateCosts(requestUpdates); err != nil {
+				return nil, fmt.Errorf("failed to update request costs: %w", err)
+			}
+			result.FixedRequests += len(requestUpdates)
+		}
+
+		if len(requests) < costReconcileBatchSize {
+			break
+		}
+	}
+
+	result.DurationMs = time.Since(start).Milliseconds()
+	return result, nil
+}
+
+// reconcileAttemptCost 按 attempt 计费时的价格来源重算成本（含倍率）：
+// 记录了 ModelPriceID 时使用该价格记录，否则按价格覆盖/当前价格表计算
+func (s *AdminService) reconcileAttemptCost(attempt *domain.AttemptCostData, overrides *priceOverrideLookup, prices map[uint64]*domain.ModelPrice) uint64 {
+	model := attempt.ResponseModel
+	if model == "" {
+		model = attempt.MappedModel
+	}
+	if model == "" {
+		model = attempt.RequestModel
+	}
+
+	metrics := &usage.Metrics{
+		InputTokens:          attempt.InputTokenCount,
+		OutputTokens:         attempt.OutputTokenCount,
+		CacheReadCount:       attempt.CacheReadCount,
+		CacheCreationCount:   attempt.CacheWriteCount,
+		Cache5mCreationCount: attempt.Cache5mWriteCount,
+		Cache1hCreationCount: attempt.Cache1hWriteCount,
+		ReasoningTokens:      attempt.ReasoningTokenCount,
+	}
+
+	var baseCost uint64
+	if mp := s.historicalPrice(attempt.ModelPriceID, prices); mp != nil {
+		baseCost = s.costCalculator.CalculateWithModelPrice(mp, metrics)
+	} else {
+		baseCost = overrides.baseCost(s.costCalculator, attempt.PriceOverrideProviderID, model, metrics)
+	}
+	return pricing.ApplyMultiplier(baseCost, attempt.Multiplier)
+}
+
+// historicalPrice 查找（并缓存）价格记录，记录不存在时返回 nil
+func (s *AdminService) historicalPrice(id uint64, cache map[uint64]*domain.ModelPrice) *domain.ModelPrice {
+	if id == 0 {
+		return nil
+	}
+	mp, ok := cache[id]
+	if !ok {
+		mp, _ = s.modelPriceRepo.GetHistoricalByID(id)
+		cache[id] = mp
+	}
+	return mp
+}
+
+// RunCostReconciler 每隔 interval 核对最近 cost_reconcile_lookback_hours 小时内的请求成本，
+// 未开启 cost_reconcile_enabled 时跳过；cost_reconcile_fix 为 "true" 时自动修正偏差
+func (s *AdminService) RunCostReconciler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if val, _ := s.settingRepo.Get(domain.SettingKeyCostReconcileEnabled); val != "true" {
+			continue
+		}
+		hours := defaultCostReconcileLookbackHours
+		if val, err := s.settingRepo.Get(domain.SettingKeyCostReconcileLookbackHours); err == nil && val != "" {
+			if n, err := strconv.Atoi(val); err == nil && n > 0 {
+				hours = n
+			}
+		}
+		fixVal, _ := s.settingRepo.Get(domain.SettingKeyCostReconcileFix)
+
+		result, err := s.ReconcileCosts(time.Now().Add(-time.Duration(hours)*time.Hour), fixVal == "true")
+		if err != nil {
+			log.Printf("[CostReconcile] Failed: %v", err)
+			continue
+		}
+		if result.Discrepancies > 0 {
+			log.Printf("[CostReconcile] Checked %d requests, %d with cost drift (stored %d, computed %d nanoUSD), fixed %d requests and %d attempts",
+				result.CheckedRequests, result.Discrepancies, result.StoredCostTotal, result.ComputedCostTotal, result.FixedRequests, result.FixedAttempts)
+		}
+	}
+}
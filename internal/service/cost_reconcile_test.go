@@ -0,0 +1,103 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/pricing"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+	"github.com/awsl-project/maxx/internal/usage"
+)
+
+func TestReconcileCosts(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	requestRepo := sqlite.NewProxyRequestRepository(db)
+	attemptRepo := sqlite.NewProxyUpstreamAttemptRepository(db)
+	priceRepo := sqlite.NewModelPriceRepository(db)
+
+	// 计费时的价格之后被替换（软删除），核对仍按原价格记录计算
+	oldPrice := &domain.ModelPrice{ModelID: "reconcile-model", InputPriceMicro: 2_000_000, OutputPriceMicro: 8_000_000}
+	if err := priceRepo.Create(oldPrice); err != nil {
+		t.Fatalf("create price: %v", err)
+	}
+	if err := priceRepo.Delete(oldPrice.ID); err != nil {
+		t.Fatalf("delete price: %v", err)
+	}
+	calculator := pricing.GlobalCalculator()
+	metrics := &usage.Metrics{InputTokens: 1000, OutputTokens: 500}
+	want := pricing.ApplyMultiplier(calculator.CalculateWithModelPrice(oldPrice, metrics), 15000)
+
+	newRequest := func(status string, cost uint64, attemptCosts ...uint64) *domain.ProxyRequest {
+		req := &domain.ProxyRequest{Status: status, Cost: cost}
+		if err := requestRepo.Create(req); err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		for _, c := range attemptCosts {
+			a := &domain.ProxyUpstreamAttempt{
+				ProxyRequestID:   req.ID,
+				Status:           "COMPLETED",
+				ResponseModel:    "reconcile-model",
+				InputTokenCount:  1000,
+				OutputTokenCount: 500,
+				Multiplier:       15000,
+				ModelPriceID:     oldPrice.ID,
+				Cost:             c,
+			}
+			if err := attemptRepo.Create(a); err != nil {
+				t.Fatalf("create attempt: %v", err)
+			}
+		}
+		return req
+	}
+	newRequest("COMPLETED", 2*want, want, want)
+	requestDrift := newRequest("FAILED", want+7, want)
+	attemptDrift := newRequest("COMPLETED", 2*want, want+3, want-3)
+	newRequest("IN_PROGRESS", 0, want)
+
+	s := &AdminService{proxyRequestRepo: requestRepo, attemptRepo: attemptRepo, modelPriceRepo: priceRepo, costCalculator: calculator}
+	since := time.Now().Add(-time.Hour)
+
+	report, err := s.ReconcileCosts(since, false)
+	if err != nil {
+		t.Fatalf("ReconcileCosts failed: %v", err)
+	}
+	if report.CheckedRequests != 3 || report.CheckedAttempts != 5 {
+		t.Errorf("checked %d requests / %d attempts, want 3 / 5 (in-progress request skipped)", report.CheckedRequests, report.CheckedAttempts)
+	}
+	if report.Discrepancies != 2 || report.FixedRequests != 0 || report.FixedAttempts != 0 {
+		t.Fatalf("report = %+v, want 2 discrepancies and nothing fixed", report)
+	}
+	if d := report.Samples[0]; d.RequestID != requestDrift.ID || d.StoredCost != want+7 || d.ComputedCost != want || d.DriftAttempts != 0 {
+		t.Errorf("sample[0] = %+v", d)
+	}
+	if d := report.Samples[1]; d.RequestID != attemptDrift.ID || d.ComputedCost != 2*want || d.DriftAttempts != 2 {
+		t.Errorf("sample[1] = %+v", d)
+	}
+	if stored, _ := requestRepo.GetByID(requestDrift.ID); stored.Cost != want+7 {
+		t.Errorf("report-only mode changed cost to %d", stored.Cost)
+	}
+
+	report, err = s.ReconcileCosts(since, true)
+	if err != nil {
+		t.Fatalf("ReconcileCosts(fix) failed: %v", err)
+	}
+	if report.FixedRequests != 1 || report.FixedAttempts != 2 {
+		t.Errorf("fixed %d requests / %d attempts, want 1 / 2", report.FixedRequests, report.FixedAttempts)
+	}
+	if stored, _ := requestRepo.GetByID(requestDrift.ID); stored.Cost != want {
+		t.Errorf("fixed request cost = %d, want %d", stored.Cost, want)
+	}
+
+	report, err = s.ReconcileCosts(since, false)
+	if err != nil {
+		t.Fatalf("ReconcileCosts after fix failed: %v", err)
+	}
+	if report.Discrepancies != 0 {
+		t.Errorf("discrepancies after fix = %d, samples %+v", report.Discrepancies, report.Samples)
+	}
+}
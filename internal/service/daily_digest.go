@@ -0,0 +1,400 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/smtp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/pricing"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+const (
+	defaultDailyDigestTime = "09:00"
+	dailyDigestTopModels   = 5
+	dailyDigestMaxAttempts = 3
+)
+
+// dailyDigestRetryDelays 推送失败后的重试间隔
+var dailyDigestRetryDelays = []time.Duration{10 * time.Second, time.Minute}
+
+// 摘要可选指标
+const (
+	DigestMetricRequests  = "requests"
+	DigestMetricTokens    = "tokens"
+	DigestMetricCost      = "cost"
+	DigestMetricModels    = "models"
+	DigestMetricProviders = "providers"
+)
+
+// DailyDigestSMTPConfig 每日摘要邮件配置（daily_digest_smtp）
+type DailyDigestSMTPConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"` // 默认 587
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+// DailyDigestService assembles the previous day's usage from usage_stats and
+// delivers it via webhook and/or SMTP once a day at the configured time.
+type DailyDigestService struct {
+	usageStatsRepo repository.UsageStatsRepository
+	providerRepo   repository.ProviderRepository
+	settingRepo    repository.SystemSettingRepository
+	httpClient     *http.Client
+	sleep          func(time.Duration) // 重试前等待，测试中可替换
+}
+
+// NewDailyDigestService creates a new DailyDigestService
+func NewDailyDigestService(
+	usageStatsRepo repository.UsageStatsRepository,
+	providerRepo repository.ProviderRepository,
+	settingRepo repository.SystemSettingRepository,
+) *DailyDigestService {
+	return &DailyDigestService{
+		usageStatsRepo: usageStatsRepo,
+		providerRepo:   providerRepo,
+		settingRepo:    settingRepo,
+		httpClient:     &http.Client{Timeout: 15 * time.Second},
+		sleep:          time.Sleep,
+	}
+}
+
+// IsEnabled returns whether the daily digest is enabled
+func (s *DailyDigestService) IsEnabled() bool {
+	val, _ := s.settingRepo.Get(domain.SettingKeyDailyDigestEnabled)
+	return val == "true"
+}
+
+// RunIfDue sends the digest for the previous day once the configured time has
+// passed today and it has not been sent yet. Safe to call every minute.
+func (s *DailyDigestService) RunIfDue(now time.Time) {
+	loc := s.getTimezone()
+	now = now.In(loc)
+	if now.Before(s.scheduledAt(now)) {
+		return
+	}
+
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc).AddDate(0, 0, -1)
+	date := day.Format("2006-01-02")
+	if last, _ := s.settingRepo.Get(domain.SettingKeyDailyDigestLastDate); last == date {
+		return
+	}
+	// 先记录日期：即使推送最终失败也不在下一分钟重复尝试
+	if err := s.settingRepo.Set(domain.SettingKeyDailyDigestLastDate, date); err != nil {
+		log.Printf("[DailyDigest] Failed to record digest date: %v", err)
+		return
+	}
+
+	digest, err := s.Build(day)
+	if err != nil {
+		log.Printf("[DailyDigest] Failed to build digest for %s: %v", date, err)
+		return
+	}
+	if err := s.Deliver(digest); err != nil {
+		log.Printf("[DailyDigest] Failed to deliver digest for %s: %v", date, err)
+		return
+	}
+	log.Printf("[DailyDigest] Delivered digest for %s", date)
+}
+
+// Build assembles the digest for the day starting at day (midnight in the
+// configured timezone), including only the configured metrics
+func (s *DailyDigestService) Build(day time.Time) (*domain.DailyDigest, error) {
+	start := day
+	end := day.AddDate(0, 0, 1).Add(-time.Millisecond)
+	filter := repository.UsageStatsFilter{
+		Granularity: domain.GranularityDay,
+		StartTime:   &start,
+		EndTime:     &end,
+	}
+	metrics := s.getMetrics()
+	decimals := s.getCostDisplayDecimals()
+	digest := &domain.DailyDigest{
+		Date:         day.Format("2006-01-02"),
+		Timezone:     day.Location().String(),
+		CostDecimals: decimals,
+	}
+
+	if metrics[DigestMetricRequests] || metrics[DigestMetricTokens] || metrics[DigestMetricCost] {
+		summary, err := s.usageStatsRepo.GetSummary(filter)
+		if err != nil {
+			return nil, err
+		}
+		if metrics[DigestMetricRequests] {
+			digest.Requests = &domain.DailyDigestRequests{
+				Total:       summary.TotalRequests,
+				Successful:  summary.SuccessfulRequests,
+				Failed:      summary.FailedRequests,
+				SuccessRate: summary.SuccessRate,
+			}
+		}
+		if metrics[DigestMetricTokens] {
+			digest.Tokens = &domain.DailyDigestTokens{
+				Input:      summary.TotalInputTokens,
+				Output:     summary.TotalOutputTokens,
+				CacheRead:  summary.TotalCacheRead,
+				CacheWrite: summary.TotalCacheWrite,
+				Reasoning:  summary.TotalReasoning,
+			}
+		}
+		if metrics[DigestMetricCost] {
+			cost := summary.TotalCost
+			costUSD := pricing.NanoToUSDRounded(cost, decimals)
+			digest.Cost = &cost
+			digest.CostUSD = &costUSD
+		}
+	}
+
+	if metrics[DigestMetricModels] {
+		byModel, err := s.usageStatsRepo.GetSummaryByModel(filter)
+		if err != nil {
+			return nil, err
+		}
+		for model, summary := range byModel {
+			digest.TopModels = append(digest.TopModels, newDailyDigestEntry(model, 0, summary))
+		}
+		sortDailyDigestEntries(digest.TopModels)
+		// 截取前几个之前分配，分项金额与完整分项一致
+		setDailyDigestCostUSD(digest.TopModels, decimals)
+		if len(digest.TopModels) > dailyDigestTopModels {
+			digest.TopModels = digest.TopModels[:dailyDigestTopModels]
+		}
+	}
+
+	if metrics[DigestMetricProviders] {
+		byProvider, err := s.usageStatsRepo.GetSummaryByProvider(filter)
+		if err != nil {
+			return nil, err
+		}
+		for providerID, summary := range byProvider {
+			name := fmt.Sprintf("Provider #%d", providerID)
+			if p, err := s.providerRepo.GetByID(providerID); err == nil {
+				name = p.Name
+			}
+			digest.Providers = append(digest.Providers, newDailyDigestEntry(name, providerID, summary))
+		}
+		sortDailyDigestEntries(digest.Providers)
+		setDailyDigestCostUSD(digest.Providers, decimals)
+	}
+
+	return digest, nil
+}
+
+// Deliver sends the digest to every configured channel, retrying each one
+// independently. Returns the combined error of channels that kept failing.
+func (s *DailyDigestService) Deliver(digest *domain.DailyDigest) error {
+	webhookURL, _ := s.settingRepo.Get(domain.SettingKeyDailyDigestWebhookURL)
+	smtpConfig := s.getSMTPConfig()
+	if webhookURL == "" && smtpConfig == nil {
+		return errors.New("no webhook or SMTP configured")
+	}
+
+	var errs []error
+	if webhookURL != "" {
+		if err := s.withRetry(func() error { return s.postWebhook(webhookURL, digest) }); err != nil {
+			errs = append(errs, fmt.Errorf("webhook: %w", err))
+		}
+	}
+	if smtpConfig != nil {
+		if err := s.withRetry(func() error { return sendDigestMail(smtpConfig, digest) }); err != nil {
+			errs = append(errs, fmt.Errorf("smtp: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *DailyDigestService) postWebhook(url string, digest *domain.DailyDigest) error {
+	body, err := json.Marshal(digest)
+	if err != nil {
+		return err
+	}
+	resp, err := s.httpClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func sendDigestMail(cfg *DailyDigestSMTPConfig, digest *domain.DailyDigest) error {
+	port := cfg.Port
+	if port == 0 {
+		port = 587
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: maxx daily digest %s\r\n", digest.Date)
+	msg.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n")
+	msg.WriteString(strings.ReplaceAll(FormatDailyDigest(digest), "\n", "\r\n"))
+
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	return smtp.SendMail(addr, auth, cfg.From, cfg.To, []byte(msg.String()))
+}
+
+// FormatDailyDigest renders the digest as plain text (used for email)
+func FormatDailyDigest(digest *domain.DailyDigest) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "maxx daily digest for %s (%s)\n", digest.Date, digest.Timezone)
+	if r := digest.Requests; r != nil {
+		fmt.Fprintf(&b, "\nRequests: %d (successful %d, failed %d, success rate %.1f%%)\n",
+			r.Total, r.Successful, r.Failed, r.SuccessRate)
+	}
+	if t := digest.Tokens; t != nil {
+		fmt.Fprintf(&b, "Tokens: input %d, output %d, cache read %d, cache write %d\n",
+			t.Input, t.Output, t.CacheRead, t.CacheWrite)
+	}
+	if digest.CostUSD != nil {
+		fmt.Fprintf(&b, "Cost: $%.*f\n", digest.CostDecimals, *digest.CostUSD)
+	}
+	writeEntries := func(title string, entries []*domain.DailyDigestEntry) {
+		if len(entries) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "\n%s:\n", title)
+		for _, e := range entries {
+			fmt.Fprintf(&b, "  %s: %d requests, %d tokens, $%.*f\n",
+				e.Name, e.Requests, e.Tokens, digest.CostDecimals, e.CostUSD)
+		}
+	}
+	writeEntries("Top models", digest.TopModels)
+	writeEntries("Providers", digest.Providers)
+	return b.String()
+}
+
+func newDailyDigestEntry(name string, providerID uint64, summary *domain.UsageStatsSummary) *domain.DailyDigestEntry {
+	return &domain.DailyDigestEntry{
+		Name:       name,
+		ProviderID: providerID,
+		Requests:   summary.TotalRequests,
+		Tokens:     summary.TotalInputTokens + summary.TotalOutputTokens,
+		Cost:       summary.TotalCost,
+	}
+}
+
+// setDailyDigestCostUSD fills CostUSD so the entries add up to their rounded total
+func setDailyDigestCostUSD(entries []*domain.DailyDigestEntry, decimals int) {
+	costs := make([]uint64, len(entries))
+	for i, e := range entries {
+		costs[i] = e.Cost
+	}
+	for i, usd := range pricing.AllocateNanoToUSD(costs, decimals) {
+		entries[i].CostUSD = usd
+	}
+}
+
+// sortDailyDigestEntries 按成本降序，其次请求数降序，最后按名称
+func sortDailyDigestEntries(entries []*domain.DailyDigestEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Cost != entries[j].Cost {
+			return entries[i].Cost > entries[j].Cost
+		}
+		if entries[i].Requests != entries[j].Requests {
+			return entries[i].Requests > entries[j].Requests
+		}
+		return entries[i].Name < entries[j].Name
+	})
+}
+
+// withRetry 执行推送，失败后按 dailyDigestRetryDelays 重试
+func (s *DailyDigestService) withRetry(send func() error) error {
+	var err error
+	for attempt := 0; attempt < dailyDigestMaxAttempts; attempt++ {
+		if attempt > 0 {
+			s.sleep(dailyDigestRetryDelays[min(attempt-1, len(dailyDigestRetryDelays)-1)])
+		}
+		if err = send(); err == nil {
+			return nil
+		}
+		log.Printf("[DailyDigest] Delivery attempt %d/%d failed: %v", attempt+1, dailyDigestMaxAttempts, err)
+	}
+	return err
+}
+
+// scheduledAt returns today's send time (daily_digest_time) in now's location
+func (s *DailyDigestService) scheduledAt(now time.Time) time.Time {
+	val, _ := s.settingRepo.Get(domain.SettingKeyDailyDigestTime)
+	t, err := time.Parse("15:04", strings.TrimSpace(val))
+	if err != nil {
+		t, _ = time.Parse("15:04", defaultDailyDigestTime)
+	}
+	return time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, now.Location())
+}
+
+// getMetrics 获取摘要包含的指标，未配置时包含全部
+func (s *DailyDigestService) getMetrics() map[string]bool {
+	val, _ := s.settingRepo.Get(domain.SettingKeyDailyDigestMetrics)
+	metrics := make(map[string]bool)
+	for _, m := range strings.Split(val, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			metrics[m] = true
+		}
+	}
+	if len(metrics) == 0 {
+		for _, m := range []string{DigestMetricRequests, DigestMetricTokens, DigestMetricCost, DigestMetricModels, DigestMetricProviders} {
+			metrics[m] = true
+		}
+	}
+	return metrics
+}
+
+// getSMTPConfig 解析邮件配置，未配置或不完整时返回 nil
+func (s *DailyDigestService) getSMTPConfig() *DailyDigestSMTPConfig {
+	val, err := s.settingRepo.Get(domain.SettingKeyDailyDigestSMTP)
+	if err != nil || val == "" {
+		return nil
+	}
+	var cfg DailyDigestSMTPConfig
+	if err := json.Unmarshal([]byte(val), &cfg); err != nil {
+		log.Printf("[DailyDigest] Invalid SMTP config: %v", err)
+		return nil
+	}
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return nil
+	}
+	return &cfg
+}
+
+// getCostDisplayDecimals 读取 cost_display_decimals，未配置或无效时使用默认值
+func (s *DailyDigestService) getCostDisplayDecimals() int {
+	val, _ := s.settingRepo.Get(domain.SettingKeyCostDisplayDecimals)
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 0 {
+		return pricing.DefaultCostDisplayDecimals
+	}
+	return pricing.ClampCostDisplayDecimals(n)
+}
+
+// getTimezone 获取配置的时区，与统计聚合使用同一设置
+func (s *DailyDigestService) getTimezone() *time.Location {
+	val, _ := s.settingRepo.Get(domain.SettingKeyTimezone)
+	if val == "" {
+		val = "Asia/Shanghai" // 默认时区
+	}
+	loc, err := time.LoadLocation(val)
+	if err != nil {
+		return time.FixedZone("UTC+8", 8*60*60)
+	}
+	return loc
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/pricing"
+	"github.com/awsl-project/maxx/internal/repository"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+func TestDailyDigestCostBreakdownMatchesTotal(t *testing.T) {
+	// 各 Provider 单独四舍五入为 $0.0001，合计 $0.00045 四舍五入为 $0.0005：
+	// 逐项舍入时分项之和 $0.0003 与合计不一致
+	providers := []*domain.DailyDigestEntry{
+		{Name: "a", Cost: 150_000},
+		{Name: "b", Cost: 150_000},
+		{Name: "c", Cost: 150_000},
+	}
+	var total uint64
+	for _, p := range providers {
+		total += p.Cost
+	}
+
+	for _, decimals := range []int{2, 4, 6} {
+		setDailyDigestCostUSD(providers, decimals)
+		costUSD := pricing.NanoToUSDRounded(total, decimals)
+		digest := &domain.DailyDigest{Cost: &total, CostUSD: &costUSD, CostDecimals: decimals, Providers: providers}
+
+		var sum float64
+		for _, p := range digest.Providers {
+			sum += p.CostUSD
+		}
+		if got, want := fmt.Sprintf("%.*f", decimals, sum), fmt.Sprintf("%.*f", decimals, *digest.CostUSD); got != want {
+			t.Errorf("decimals %d: providers sum to $%s, total is $%s", decimals, got, want)
+		}
+	}
+
+	costUSD := pricing.NanoToUSDRounded(total, 4)
+	digest := &domain.DailyDigest{Date: "2024-01-01", Timezone: "UTC", Cost: &total, CostUSD: &costUSD, CostDecimals: 4, Providers: providers}
+	setDailyDigestCostUSD(digest.Providers, 4)
+	want := "maxx daily digest for 2024-01-01 (UTC)\n" +
+		"Cost: $0.0005\n" +
+		"\nProviders:\n" +
+		"  a: 0 requests, 0 tokens, $0.0002\n" +
+		"  b: 0 requests, 0 tokens, $0.0002\n" +
+		"  c: 0 requests, 0 tokens, $0.0001\n"
+	if got := FormatDailyDigest(digest); got != want {
+		t.Errorf("FormatDailyDigest =\n%s\nwant\n%s", got, want)
+	}
+}
+
+// digestUsageRepo 返回固定汇总并记录查询
+type digestUsageRepo struct {
+	repository.UsageStatsRepository
+	filters []repository.UsageStatsFilter
+}
+
+func (r *digestUsageRepo) GetSummary(filter repository.UsageStatsFilter) (*domain.UsageStatsSummary, error) {
+	r.filters = append(r.filters, filter)
+	return &domain.UsageStatsSummary{TotalRequests: 3, SuccessfulRequests: 2, FailedRequests: 1}, nil
+}
+
+// newTestDailyDigest 创建只推送请求数的摘要服务，webhook 按 statuses 依次返回（用完后返回 200）
+func newTestDailyDigest(t *testing.T, settings map[string]string, statuses ...int) (*DailyDigestService, *digestUsageRepo, *[]*domain.DailyDigest, *[]time.Duration) {
+	t.Helper()
+	var calls atomic.Int32
+	var received []*domain.DailyDigest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if n := int(calls.Add(1)); n <= len(statuses) && statuses[n-1] != http.StatusOK {
+			w.WriteHeader(statuses[n-1])
+			return
+		}
+		var digest domain.DailyDigest
+		if err := json.NewDecoder(r.Body).Decode(&digest); err != nil {
+			t.Errorf("decode digest: %v", err)
+		}
+		received = append(received, &digest)
+	}))
+	t.Cleanup(server.Close)
+
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	settingRepo := sqlite.NewSystemSettingRepository(db)
+	settings[domain.SettingKeyDailyDigestWebhookURL] = server.URL
+	settings[domain.SettingKeyDailyDigestMetrics] = DigestMetricRequests
+	for key, value := range settings {
+		if err := settingRepo.Set(key, value); err != nil {
+			t.Fatalf("set %s: %v", key, err)
+		}
+	}
+
+	usageRepo := &digestUsageRepo{}
+	s := NewDailyDigestService(usageRepo, nil, settingRepo)
+	var sleeps []time.Duration
+	s.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	return s, usageRepo, &received, &sleeps
+}
+
+func TestDailyDigestDayBoundaryAcrossTimezones(t *testing.T) {
+	mustLoad := func(name string) *time.Location {
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			t.Skipf("timezone %s not available: %v", name, err)
+		}
+		return loc
+	}
+
+	tests := []struct {
+		name     string
+		timezone string
+		time     string
+		now      time.Time
+		wantDate string // 空表示尚未到推送时间
+		start    time.Time
+		end      time.Time
+	}{
+		{
+			// 同一时刻在 UTC+8 已是 3 月 10 日上午，摘要为 3 月 9 日
+			name: "east of UTC", timezone: "Asia/Shanghai", now: time.Date(2024, 3, 10, 1, 30, 0, 0, time.UTC),
+			wantDate: "2024-03-09",
+			start:    time.Date(2024, 3, 8, 16, 0, 0, 0, time.UTC),
+			end:      time.Date(2024, 3, 9, 16, 0, 0, 0, time.UTC),
+		},
+		{
+			// 在洛杉矶仍是 3 月 9 日傍晚，摘要为 3 月 8 日
+			name: "west of UTC", timezone: "America/Los_Angeles", time: "17:00", now: time.Date(2024, 3, 10, 1, 30, 0, 0, time.UTC),
+			wantDate: "2024-03-08",
+			start:    time.Date(2024, 3, 8, 8, 0, 0, 0, time.UTC),
+			end:      time.Date(2024, 3, 9, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "not due yet", timezone: "UTC", now: time.Date(2024, 3, 10, 1, 30, 0, 0, time.UTC),
+		},
+		{
+			// 夏令时开始当天只有 23 小时
+			name: "DST day", timezone: "America/New_York", now: time.Date(2024, 3, 11, 13, 30, 0, 0, time.UTC),
+			wantDate: "2024-03-10",
+			start:    time.Date(2024, 3, 10, 5, 0, 0, 0, time.UTC),
+			end:      time.Date(2024, 3, 11, 4, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mustLoad(tt.timezone)
+			settings := map[string]string{domain.SettingKeyTimezone: tt.timezone}
+			if tt.time != "" {
+				settings[domain.SettingKeyDailyDigestTime] = tt.time
+			}
+			s, usageRepo, received, _ := newTestDailyDigest(t, settings)
+
+			s.RunIfDue(tt.now)
+			if tt.wantDate == "" {
+				if len(*received) != 0 || len(usageRepo.filters) != 0 {
+					t.Fatalf("digest sent before the configured time: %+v", *received)
+				}
+				return
+			}
+			if len(*received) != 1 || (*received)[0].Date != tt.wantDate || (*received)[0].Timezone != tt.timezone {
+				t.Fatalf("received = %+v, want one digest for %s", *received, tt.wantDate)
+			}
+			if (*received)[0].Requests == nil || (*received)[0].Requests.Total != 3 {
+				t.Errorf("requests = %+v, want the day's summary", (*received)[0].Requests)
+			}
+			f := usageRepo.filters[0]
+			if !f.StartTime.Equal(tt.start) || !f.EndTime.Equal(tt.end.Add(-time.Millisecond)) {
+				t.Errorf("window = %v - %v, want %v - %v", f.StartTime.UTC(), f.EndTime.UTC(), tt.start, tt.end)
+			}
+
+			// 同一天内再次检查不重复推送
+			s.RunIfDue(tt.now.Add(time.Hour))
+			if len(*received) != 1 {
+				t.Errorf("digest sent %d times, want once per day", len(*received))
+			}
+		})
+	}
+}
+
+func TestDailyDigestWebhookRetry(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	t.Run("recovers after failures", func(t *testing.T) {
+		s, _, received, sleeps := newTestDailyDigest(t, map[string]string{domain.SettingKeyTimezone: "UTC"},
+			http.StatusInternalServerError, http.StatusBadGateway)
+		s.RunIfDue(now)
+		if len(*received) != 1 {
+			t.Fatalf("received %d digests, want 1 after retries", len(*received))
+		}
+		if want := dailyDigestRetryDelays; len(*sleeps) != 2 || (*sleeps)[0] != want[0] || (*sleeps)[1] != want[1] {
+			t.Errorf("backoff = %v, want %v", *sleeps, want)
+		}
+	})
+
+	t.Run("gives up after max attempts", func(t *testing.T) {
+		failures := make([]int, 2*dailyDigestMaxAttempts)
+		for i := range failures {
+			failures[i] = http.StatusInternalServerError
+		}
+		s, _, received, sleeps := newTestDailyDigest(t, map[string]string{domain.SettingKeyTimezone: "UTC"}, failures...)
+		digest, err := s.Build(time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("Build: %v", err)
+		}
+		err = s.Deliver(digest)
+		if err == nil || !strings.Contains(err.Error(), "webhook: unexpected status 500") {
+			t.Fatalf("Deliver err = %v, want webhook failure", err)
+		}
+		if len(*received) != 0 || len(*sleeps) != dailyDigestMaxAttempts-1 {
+			t.Errorf("received %d, backoff %v, want %d attempts", len(*received), *sleeps, dailyDigestMaxAttempts)
+		}
+
+		// 推送失败后当天不再重复尝试
+		s.RunIfDue(now)
+		s.RunIfDue(now.Add(time.Minute))
+		if len(*sleeps) != 2*(dailyDigestMaxAttempts-1) {
+			t.Errorf("backoff = %v, want a single failed run for the day", *sleeps)
+		}
+	})
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"github.com/awsl-project/maxx/internal/cooldown"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// DashboardProviderStatus 单个 Provider 的 Dashboard 状态：统计、冷却与配额
+type DashboardProviderStatus struct {
+	ProviderID uint64 `json:"providerID"`
+	Name       string `json:"name"`
+	Type       string `json:"type"`
+	Draining   bool   `json:"draining,omitempty"`
+
+	// 30 天请求数/成功率，以及今日 RPM/TPM
+	Stats domain.DashboardProviderStats `json:"stats"`
+
+	// 当前生效的冷却（按 clientType 区分），无冷却时为空数组
+	Cooldowns []*cooldown.CooldownInfo `json:"cooldowns"`
+
+	// 配额窗口，仅 Codex/Antigravity Provider 且已刷新过配额时返回
+	CodexQuota       *domain.CodexQuota       `json:"codexQuota,omitempty"`
+	AntigravityQuota *domain.AntigravityQuota `json:"antigravityQuota,omitempty"`
+}
+
+// GetDashboardProviders returns stats, live cooldowns and quotas for every
+// provider in one response, so the dashboard doesn't stitch separate endpoints
+func (s *AdminService) GetDashboardProviders() ([]*DashboardProviderStatus, error) {
+	providers, err := s.providerRepo.List()
+	if err != nil {
+		return nil, err
+	}
+	data, err := s.usageStatsRepo.QueryDashboardData()
+	if err != nil {
+		return nil, err
+	}
+
+	cm := cooldown.Default()
+	cooldowns := make(map[uint64][]*cooldown.CooldownInfo)
+	names := make(map[uint64]string, len(providers))
+	for _, p := range providers {
+		names[p.ID] = p.Name
+	}
+	for key := range cm.GetAllCooldowns() {
+		if info := cm.GetCooldownInfo(key.ProviderID, key.ClientType, names[key.ProviderID]); info != nil {
+			cooldowns[key.ProviderID] = append(cooldowns[key.ProviderID], info)
+		}
+	}
+
+	result := make([]*DashboardProviderStatus, 0, len(providers))
+	for _, p := range providers {
+		status := &DashboardProviderStatus{
+			ProviderID: p.ID,
+			Name:       p.Name,
+			Type:       p.Type,
+			Draining:   p.Draining,
+			Stats:      data.ProviderStats[p.ID],
+			Cooldowns:  cooldowns[p.ID],
+		}
+		if status.Cooldowns == nil {
+			status.Cooldowns = []*cooldown.CooldownInfo{}
+		}
+		if p.Config != nil {
+			if cfg := p.Config.Codex; cfg != nil && cfg.Email != "" && s.codexQuotaRepo != nil {
+				if quota, err := s.codexQuotaRepo.GetByEmail(cfg.Email); err == nil {
+					status.CodexQuota = quota
+				}
+			}
+			if cfg := p.Config.Antigravity; cfg != nil && cfg.Email != "" && s.antigravityQuotaRepo != nil {
+				if quota, err := s.antigravityQuotaRepo.GetByEmail(cfg.Email); err == nil {
+					status.AntigravityQuota = quota
+				}
+			}
+		}
+		result = append(result, status)
+	}
+	return result, nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/cooldown"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+// failingDashboardRepo 查询 Dashboard 数据时返回错误
+type failingDashboardRepo struct {
+	repository.UsageStatsRepository
+}
+
+func (failingDashboardRepo) QueryDashboardData() (*domain.DashboardData, error) {
+	return nil, errors.New("database is locked")
+}
+
+func TestGetDashboardProviders(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	providerRepo := sqlite.NewProviderRepository(db)
+	codexQuotaRepo := sqlite.NewCodexQuotaRepository(db)
+	svc := &AdminService{
+		providerRepo:         providerRepo,
+		usageStatsRepo:       sqlite.NewUsageStatsRepository(db),
+		codexQuotaRepo:       codexQuotaRepo,
+		antigravityQuotaRepo: sqlite.NewAntigravityQuotaRepository(db),
+	}
+
+	codex := &domain.Provider{Name: "codex", Type: "codex", Config: &domain.ProviderConfig{Codex: &domain.ProviderConfigCodex{Email: "a@example.com"}}}
+	custom := &domain.Provider{Name: "custom", Type: "custom", Draining: true}
+	for _, p := range []*domain.Provider{codex, custom} {
+		if err := providerRepo.Create(p); err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+	}
+	if err := codexQuotaRepo.Upsert(&domain.CodexQuota{Email: "a@example.com", PlanType: "chatgptplusplan"}); err != nil {
+		t.Fatalf("upsert quota: %v", err)
+	}
+	cooldown.Default().SetCooldownDuration(custom.ID, string(domain.ClientTypeClaude), time.Minute)
+	t.Cleanup(func() { _ = cooldown.Default().ResetProvider(custom.ID) })
+
+	statuses, err := svc.GetDashboardProviders()
+	if err != nil {
+		t.Fatalf("GetDashboardProviders: %v", err)
+	}
+	if len(statuses) != 2 {
+		t.Fatalf("statuses = %+v, want both providers", statuses)
+	}
+	for _, status := range statuses {
+		switch status.ProviderID {
+		case codex.ID:
+			if status.CodexQuota == nil || status.CodexQuota.PlanType != "chatgptplusplan" || len(status.Cooldowns) != 0 || status.Cooldowns == nil {
+				t.Errorf("codex status = %+v, want its quota and an empty cooldown list", status)
+			}
+		case custom.ID:
+			if !status.Draining || status.CodexQuota != nil || len(status.Cooldowns) != 1 || status.Cooldowns[0].ClientType != string(domain.ClientTypeClaude) {
+				t.Errorf("custom status = %+v, want draining with one claude cooldown", status)
+			}
+		}
+	}
+}
+
+func TestGetDashboardProvidersStatsError(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	svc := &AdminService{providerRepo: sqlite.NewProviderRepository(db), usageStatsRepo: failingDashboardRepo{}}
+	if statuses, err := svc.GetDashboardProviders(); err == nil || statuses != nil {
+		t.Errorf("GetDashboardProviders = %+v, %v, want the stats error", statuses, err)
+	}
+}
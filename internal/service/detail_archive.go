@@ -0,0 +1,241 @@
+package service
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+const (
+	detailArchiveBatchSize = 100
+
+	// detailArchivePrefix / detailArchiveSuffix 本地归档文件名：maxx-details-20261016T120000Z.jsonl.gz
+	detailArchivePrefix = "maxx-details-"
+	detailArchiveSuffix = ".jsonl.gz"
+	// detailArchivePartial 写入中的文件后缀，写完后去掉；不会被当作归档文件
+	detailArchivePartial = ".partial"
+)
+
+// DetailArchiveRecord 归档文件中的一行：一条请求或 attempt 的完整记录（含详情）
+type DetailArchiveRecord struct {
+	Kind    string                       `json:"kind"` // "request" 或 "attempt"
+	Request *domain.ProxyRequest         `json:"request,omitempty"`
+	Attempt *domain.ProxyUpstreamAttempt `json:"attempt,omitempty"`
+}
+
+// DetailArchiveSink 归档目标，每轮清理 Open 一次
+type DetailArchiveSink interface {
+	Open(runAt time.Time) (DetailArchiveWriter, error)
+}
+
+// DetailArchiveWriter 一轮归档的写入端。Close 成功表示归档已持久化，之后才会清理详情；
+// 写入失败时调用 Abort 丢弃已写入的部分
+type DetailArchiveWriter interface {
+	Write(record *DetailArchiveRecord) error
+	Close() error
+	Abort()
+}
+
+// DetailArchiveResult 一轮归档的数量
+type DetailArchiveResult struct {
+	Requests int
+	Attempts int
+}
+
+// RequestDetailArchiver 在详情清理前把即将被清理的请求/attempt 详情写入归档，
+// 默认按 request_detail_archive_dir 写本地文件，可通过 WithSink 换成其他目标（如对象存储）
+type RequestDetailArchiver struct {
+	proxyRequestRepo repository.ProxyRequestRepository
+	attemptRepo      repository.ProxyUpstreamAttemptRepository
+	settingRepo      repository.SystemSettingRepository
+	sink             DetailArchiveSink
+}
+
+// NewRequestDetailArchiver creates a new RequestDetailArchiver
+func NewRequestDetailArchiver(
+	proxyRequestRepo repository.ProxyRequestRepository,
+	attemptRepo repository.ProxyUpstreamAttemptRepository,
+	settingRepo repository.SystemSettingRepository,
+) *RequestDetailArchiver {
+	return &RequestDetailArchiver{
+		proxyRequestRepo: proxyRequestRepo,
+		attemptRepo:      attemptRepo,
+		settingRepo:      settingRepo,
+	}
+}
+
+// WithSink 使用自定义归档目标，忽略 request_detail_archive_dir
+func (a *RequestDetailArchiver) WithSink(sink DetailArchiveSink) *RequestDetailArchiver {
+	a.sink = sink
+	return a
+}
+
+// currentSink 返回本轮使用的归档目标，未配置时返回 nil（不归档）
+func (a *RequestDetailArchiver) currentSink() DetailArchiveSink {
+	if a.sink != nil {
+		return a.sink
+	}
+	dir, err := a.settingRepo.Get(domain.SettingKeyRequestDetailArchiveDir)
+	if err != nil || dir == "" {
+		return nil
+	}
+	days := 0
+	if v, err := a.settingRepo.Get(domain.SettingKeyRequestDetailArchiveDays); err == nil && v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			days = n
+		}
+	}
+	return &FileDetailArchiveSink{Dir: dir, RetentionDays: days}
+}
+
+// Archive 归档 before 之前创建、仍带详情的请求和 attempt（与详情清理选择的行一致）。
+// 未开启归档时返回 nil, nil；返回错误时调用方应跳过本轮清理，避免详情未归档就被删除
+func (a *RequestDetailArchiver) Archive(before, now time.Time) (*DetailArchiveResult, error) {
+	sink := a.currentSink()
+	if sink == nil {
+		return nil, nil
+	}
+	w, err := sink.Open(now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open detail archive: %w", err)
+	}
+	result := &DetailArchiveResult{}
+	if err := a.writeAll(w, before, result); err != nil {
+		w.Abort()
+		return nil, err
+	}
+	if result.Requests == 0 && result.Attempts == 0 {
+		w.Abort()
+		return result, nil
+	}
+	if err := w.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish detail archive: %w", err)
+	}
+	return result, nil
+}
+
+func (a *RequestDetailArchiver) writeAll(w DetailArchiveWriter, before time.Time, result *DetailArchiveResult) error {
+	var afterID uint64
+	for {
+		requests, err := a.proxyRequestRepo.ListDetailOlderThan(before, afterID, detailArchiveBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to list request details: %w", err)
+		}
+		if len(requests) == 0 {
+			break
+		}
+		for _, req := range requests {
+			if err := w.Write(&DetailArchiveRecord{Kind: "request", Request: req}); err != nil {
+				return fmt.Errorf("failed to write detail archive: %w", err)
+			}
+		}
+		result.Requests += len(requests)
+		afterID = requests[len(requests)-1].ID
+	}
+
+	if a.attemptRepo == nil {
+		return nil
+	}
+	afterID = 0
+	for {
+		attempts, err := a.attemptRepo.ListDetailOlderThan(before, afterID, detailArchiveBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to list attempt details: %w", err)
+		}
+		if len(attempts) == 0 {
+			break
+		}
+		for _, attempt := range attempts {
+			if err := w.Write(&DetailArchiveRecord{Kind: "attempt", Attempt: attempt}); err != nil {
+				return fmt.Errorf("failed to write detail archive: %w", err)
+			}
+		}
+		result.Attempts += len(attempts)
+		afterID = attempts[len(attempts)-1].ID
+	}
+	return nil
+}
+
+// FileDetailArchiveSink 每轮归档在 Dir 下写一个 gzip 压缩的 JSONL 文件，文件名为
+// maxx-details-<清理时刻 UTC，如 20261016T120000Z>.jsonl.gz。写入时带 .partial 后缀，
+// 完成后重命名，中途失败则删除。RetentionDays > 0 时每次归档前删除超过保留天数的归档文件
+type FileDetailArchiveSink struct {
+	Dir           string
+	RetentionDays int
+}
+
+// Open 创建本轮归档文件
+func (s *FileDetailArchiveSink) Open(runAt time.Time) (DetailArchiveWriter, error) {
+	if err := os.MkdirAll(s.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	if s.RetentionDays > 0 {
+		s.prune(runAt.Add(-time.Duration(s.RetentionDays) * 24 * time.Hour))
+	}
+
+	path := filepath.Join(s.Dir, detailArchivePrefix+runAt.UTC().Format("20060102T150405Z")+detailArchiveSuffix)
+	f, err := os.OpenFile(path+detailArchivePartial, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	gz := gzip.NewWriter(f)
+	return &fileDetailArchiveWriter{path: path, f: f, gz: gz, enc: json.NewEncoder(gz)}, nil
+}
+
+// prune 删除修改时间早于 cutoff 的归档文件
+func (s *FileDetailArchiveSink) prune(cutoff time.Time) {
+	entries, err := os.ReadDir(s.Dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, detailArchivePrefix) || !strings.HasSuffix(name, detailArchiveSuffix) {
+			continue
+		}
+		if info, err := entry.Info(); err == nil && info.ModTime().Before(cutoff) {
+			_ = os.Remove(filepath.Join(s.Dir, name))
+		}
+	}
+}
+
+type fileDetailArchiveWriter struct {
+	path string
+	f    *os.File
+	gz   *gzip.Writer
+	enc  *json.Encoder
+}
+
+func (w *fileDetailArchiveWriter) Write(record *DetailArchiveRecord) error {
+	return w.enc.Encode(record)
+}
+
+func (w *fileDetailArchiveWriter) Close() error {
+	if err := w.gz.Close(); err != nil {
+		w.Abort()
+		return err
+	}
+	if err := w.f.Sync(); err != nil {
+		w.Abort()
+		return err
+	}
+	if err := w.f.Close(); err != nil {
+		_ = os.Remove(w.path + detailArchivePartial)
+		return err
+	}
+	return os.Rename(w.path+detailArchivePartial, w.path)
+}
+
+func (w *fileDetailArchiveWriter) Abort() {
+	_ = w.gz.Close()
+	_ = w.f.Close()
+	_ = os.Remove(w.path + detailArchivePartial)
+}
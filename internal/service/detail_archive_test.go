@@ -0,0 +1,144 @@
+package service
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+func TestRequestDetailArchiver(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	requestRepo := sqlite.NewProxyRequestRepository(db)
+	attemptRepo := sqlite.NewProxyUpstreamAttemptRepository(db)
+	settingRepo := sqlite.NewSystemSettingRepository(db)
+	archiver := NewRequestDetailArchiver(requestRepo, attemptRepo, settingRepo)
+
+	// 创建时间由仓库写入：before 取在旧记录与新记录之间
+	old := &domain.ProxyRequest{RequestInfo: &domain.RequestInfo{Body: `{"model":"old"}`}}
+	if err := requestRepo.Create(old); err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	attempt := &domain.ProxyUpstreamAttempt{ProxyRequestID: old.ID, ResponseInfo: &domain.ResponseInfo{Status: 200, Body: "hi"}}
+	if err := attemptRepo.Create(attempt); err != nil {
+		t.Fatalf("create attempt: %v", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	before := time.Now()
+	time.Sleep(5 * time.Millisecond)
+	if err := requestRepo.Create(&domain.ProxyRequest{RequestInfo: &domain.RequestInfo{Body: `{"model":"recent"}`}}); err != nil {
+		t.Fatalf("create request: %v", err)
+	}
+	now := time.Now()
+
+	// 未配置归档目录时不归档
+	if result, err := archiver.Archive(before, now); err != nil || result != nil {
+		t.Fatalf("disabled archive = %+v, %v", result, err)
+	}
+
+	dir := filepath.Join(t.TempDir(), "archive")
+	if err := settingRepo.Set(domain.SettingKeyRequestDetailArchiveDir, dir); err != nil {
+		t.Fatalf("set archive dir: %v", err)
+	}
+	result, err := archiver.Archive(before, now)
+	if err != nil {
+		t.Fatalf("Archive: %v", err)
+	}
+	if result.Requests != 1 || result.Attempts != 1 {
+		t.Fatalf("archived %+v, want 1 request and 1 attempt", result)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("archive dir entries = %v, %v", entries, err)
+	}
+	wantName := "maxx-details-" + now.UTC().Format("20060102T150405Z") + ".jsonl.gz"
+	if entries[0].Name() != wantName {
+		t.Errorf("archive file = %s, want %s", entries[0].Name(), wantName)
+	}
+	records := readDetailArchive(t, filepath.Join(dir, entries[0].Name()))
+	if len(records) != 2 || records[0].Kind != "request" || records[0].Request.ID != old.ID ||
+		records[0].Request.RequestInfo.Body != `{"model":"old"}` ||
+		records[1].Kind != "attempt" || records[1].Attempt.ResponseInfo.Body != "hi" {
+		t.Errorf("archived records = %+v", records)
+	}
+
+	// 清理后再次归档：没有可归档的详情，不产生新文件
+	if _, err := requestRepo.ClearDetailOlderThan(before); err != nil {
+		t.Fatalf("clear request details: %v", err)
+	}
+	if _, err := attemptRepo.ClearDetailOlderThan(before); err != nil {
+		t.Fatalf("clear attempt details: %v", err)
+	}
+	result, err = archiver.Archive(before, now.Add(time.Minute))
+	if err != nil || result.Requests != 0 || result.Attempts != 0 {
+		t.Fatalf("second archive = %+v, %v", result, err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("empty archive left files: %v", entries)
+	}
+}
+
+func TestFileDetailArchiveSinkPrunesExpiredFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	expired := filepath.Join(dir, "maxx-details-20200101T000000Z.jsonl.gz")
+	unrelated := filepath.Join(dir, "notes.txt")
+	for _, path := range []string{expired, unrelated} {
+		if err := os.WriteFile(path, []byte("x"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.AddDate(0, 0, -10), now.AddDate(0, 0, -10)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sink := &FileDetailArchiveSink{Dir: dir, RetentionDays: 7}
+	w, err := sink.Open(now)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	w.Abort()
+
+	if _, err := os.Stat(expired); !os.IsNotExist(err) {
+		t.Errorf("expired archive not pruned: %v", err)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("unrelated file removed: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("aborted archive left files: %v", entries)
+	}
+}
+
+func readDetailArchive(t *testing.T, path string) []DetailArchiveRecord {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var records []DetailArchiveRecord
+	scanner := bufio.NewScanner(gz)
+	for scanner.Scan() {
+		var rec DetailArchiveRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
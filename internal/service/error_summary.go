@@ -0,0 +1,189 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// Error summary limits
+const (
+	errorSummaryDefaultWindow = 24 * time.Hour // 未指定 start 时统计最近 24 小时
+	errorSummaryMaxAttempts   = 50000          // 单次最多扫描的失败 attempt 数（最近的优先）
+	errorSummaryExamples      = 5              // 每组保留的示例请求数
+	errorPatternMaxBytes      = 300            // 归一化后错误信息的最大长度
+	errorNormalizeMaxBytes    = 1024           // 只归一化错误信息的前 1KB
+)
+
+// errorNormalizers strip the variable parts of upstream error messages so
+// that failures with the same cause share one pattern. Applied in order:
+// UUIDs, timestamps, URLs (host kept, path and query dropped), IP addresses,
+// long IDs mixing letters and digits (request/message IDs, hex hashes), and
+// finally standalone numbers with an optional duration unit. Numbers glued to
+// letters (gpt-4o, o3) are kept so model names stay distinguishable.
+var errorNormalizers = []struct {
+	re   *regexp.Regexp
+	repl func(string) string
+}{
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), placeholder("<uuid>")},
+	{regexp.MustCompile(`\b\d{4}-\d{2}-\d{2}(?:[T ]\d{2}:\d{2}(?::\d{2}(?:\.\d+)?)?(?:Z|[+-]\d{2}:?\d{2})?)?`), placeholder("<time>")},
+	{regexp.MustCompile(`https?://[^\s"'<>]+`), func(s string) string {
+		scheme, rest, _ := strings.Cut(s, "://")
+		if i := strings.IndexAny(rest, "/?#"); i >= 0 {
+			rest = rest[:i]
+		}
+		return scheme + "://" + rest
+	}},
+	{regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`), placeholder("<ip>")},
+	{regexp.MustCompile(`\b[A-Za-z0-9_-]{8,}\b`), func(s string) string {
+		if isVariableID(s) {
+			return "<id>"
+		}
+		return s
+	}},
+	{regexp.MustCompile(`\b\d+(?:\.\d+)?(?:ms|s|m|h)?\b`), placeholder("<n>")},
+	{regexp.MustCompile(`\s+`), placeholder(" ")},
+}
+
+func placeholder(p string) func(string) string {
+	return func(string) string { return p }
+}
+
+// isVariableID reports whether a word looks like a generated identifier: a
+// hex string of 8+ characters with at least one digit, or 12+ characters
+// mixing letters and digits (req_011CXa..., chatcmpl-9f3...)
+func isVariableID(s string) bool {
+	var letters, digits, hex int
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+			hex++
+		case c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F':
+			letters++
+			hex++
+		case c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			letters++
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+	return hex == len(s) || (len(s) >= 12 && letters > 0)
+}
+
+// normalizeErrorMessage reduces an error message to its grouping pattern
+func normalizeErrorMessage(msg string) string {
+	msg = truncateUTF8(msg, errorNormalizeMaxBytes)
+	for _, n := range errorNormalizers {
+		msg = n.re.ReplaceAllStringFunc(msg, n.repl)
+	}
+	msg = truncateUTF8(strings.TrimSpace(msg), errorPatternMaxBytes)
+	if msg == "" {
+		return "(no error message)"
+	}
+	return msg
+}
+
+func truncateUTF8(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	for max > 0 && !utf8.RuneStart(s[max]) {
+		max--
+	}
+	return s[:max]
+}
+
+// GetErrorSummary groups the failed upstream attempts in [start, end] by
+// status code and normalized error message, across all providers. A zero end
+// means now and a zero start means 24 hours before end. At most
+// errorSummaryMaxAttempts recent failures are scanned; Truncated reports when
+// older ones were left out.
+func (s *AdminService) GetErrorSummary(start, end time.Time) (*domain.ErrorSummary, error) {
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	if start.IsZero() {
+		start = end.Add(-errorSummaryDefaultWindow)
+	}
+	if !start.Before(end) {
+		return nil, fmt.Errorf("%w: start must be before end", domain.ErrInvalidInput)
+	}
+	failures, err := s.attemptRepo.ListFailures(start, end, errorSummaryMaxAttempts+1)
+	if err != nil {
+		return nil, err
+	}
+	summary := &domain.ErrorSummary{Start: start, End: end}
+	if len(failures) > errorSummaryMaxAttempts {
+		failures = failures[:errorSummaryMaxAttempts]
+		summary.Truncated = true
+	}
+	summary.TotalFailures = uint64(len(failures))
+	summary.Groups = groupAttemptFailures(failures)
+	return summary, nil
+}
+
+// groupAttemptFailures buckets failures (newest first) by status code and
+// normalized message, largest bucket first
+func groupAttemptFailures(failures []*domain.AttemptFailure) []*domain.ErrorGroup {
+	type bucket struct {
+		group     *domain.ErrorGroup
+		providers map[uint64]bool
+		requests  map[uint64]bool
+	}
+	buckets := make(map[string]*bucket)
+	groups := []*domain.ErrorGroup{}
+	for _, f := range failures {
+		pattern := normalizeErrorMessage(f.Error)
+		key := fmt.Sprintf("%d\x00%s", f.StatusCode, pattern)
+		b := buckets[key]
+		if b == nil {
+			b = &bucket{
+				group: &domain.ErrorGroup{
+					Pattern:      pattern,
+					StatusCode:   f.StatusCode,
+					ExampleError: f.Error,
+					FirstSeen:    f.StartTime,
+					LastSeen:     f.StartTime,
+				},
+				providers: make(map[uint64]bool),
+				requests:  make(map[uint64]bool),
+			}
+			buckets[key] = b
+			groups = append(groups, b.group)
+		}
+		g := b.group
+		g.Count++
+		if f.StartTime.Before(g.FirstSeen) {
+			g.FirstSeen = f.StartTime
+		}
+		if f.StartTime.After(g.LastSeen) {
+			g.LastSeen = f.StartTime
+		}
+		if !b.providers[f.ProviderID] {
+			b.providers[f.ProviderID] = true
+			g.ProviderIDs = append(g.ProviderIDs, f.ProviderID)
+		}
+		if len(g.ExampleRequestIDs) < errorSummaryExamples && !b.requests[f.ProxyRequestID] {
+			b.requests[f.ProxyRequestID] = true
+			g.ExampleRequestIDs = append(g.ExampleRequestIDs, f.ProxyRequestID)
+		}
+	}
+
+	for _, g := range groups {
+		sort.Slice(g.ProviderIDs, func(i, j int) bool { return g.ProviderIDs[i] < g.ProviderIDs[j] })
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].LastSeen.After(groups[j].LastSeen)
+	})
+	return groups
+}
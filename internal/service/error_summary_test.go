@@ -0,0 +1,68 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestNormalizeErrorMessage(t *testing.T) {
+	tests := []struct {
+		msg, want string
+	}{
+		{
+			`upstream error: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"},"request_id":"req_011CXaBcDeFgHiJkLmN"}`,
+			`upstream error: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"},"request_id":"<id>"}`,
+		},
+		{
+			"Post \"https://api.example.com/v1/messages?beta=true\": dial tcp 10.0.0.12:443: i/o timeout",
+			"Post \"https://api.example.com\": dial tcp <ip>: i/o timeout",
+		},
+		{
+			"rate limited until 2026-10-16T08:30:00Z, retry after 30s (trace 550e8400-e29b-41d4-a716-446655440000)",
+			"rate limited until <time>, retry after <n> (trace <uuid>)",
+		},
+		{"model gpt-4o not found, hash deadbeef42", "model gpt-4o not found, hash <id>"},
+		{"  context   deadline\nexceeded ", "context deadline exceeded"},
+		{"", "(no error message)"},
+	}
+	for _, tt := range tests {
+		if got := normalizeErrorMessage(tt.msg); got != tt.want {
+			t.Errorf("normalizeErrorMessage(%q)\n got %q\nwant %q", tt.msg, got, tt.want)
+		}
+	}
+}
+
+func TestGroupAttemptFailures(t *testing.T) {
+	base := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	failures := []*domain.AttemptFailure{
+		{ProxyRequestID: 9, ProviderID: 2, StartTime: base.Add(5 * time.Minute), StatusCode: 529, Error: "overloaded, request_id req_011CXaBcDeFgHi"},
+		{ProxyRequestID: 8, ProviderID: 1, StartTime: base.Add(4 * time.Minute), StatusCode: 0, Error: "dial tcp 10.0.0.1:443: connection refused"},
+		{ProxyRequestID: 7, ProviderID: 1, StartTime: base.Add(3 * time.Minute), StatusCode: 529, Error: "overloaded, request_id req_011CXzYxWvUtSr"},
+		{ProxyRequestID: 7, ProviderID: 1, StartTime: base.Add(2 * time.Minute), StatusCode: 529, Error: "overloaded, request_id req_011CXkKkKkKkKk"},
+		// 同样的信息但状态码不同，单独成组
+		{ProxyRequestID: 6, ProviderID: 1, StartTime: base.Add(1 * time.Minute), StatusCode: 503, Error: "overloaded, request_id req_011CXqQqQqQqQq"},
+	}
+	groups := groupAttemptFailures(failures)
+	if len(groups) != 3 {
+		t.Fatalf("got %d groups, want 3: %+v", len(groups), groups)
+	}
+	g := groups[0]
+	if g.StatusCode != 529 || g.Count != 3 || g.Pattern != "overloaded, request_id <id>" {
+		t.Errorf("top group = %+v", g)
+	}
+	if len(g.ProviderIDs) != 2 || g.ProviderIDs[0] != 1 || g.ProviderIDs[1] != 2 {
+		t.Errorf("providers = %v, want [1 2]", g.ProviderIDs)
+	}
+	if len(g.ExampleRequestIDs) != 2 || g.ExampleRequestIDs[0] != 9 || g.ExampleRequestIDs[1] != 7 {
+		t.Errorf("example requests = %v, want [9 7]", g.ExampleRequestIDs)
+	}
+	if g.ExampleError != failures[0].Error || !g.FirstSeen.Equal(base.Add(2*time.Minute)) || !g.LastSeen.Equal(base.Add(5*time.Minute)) {
+		t.Errorf("example/first/last = %q %v %v", g.ExampleError, g.FirstSeen, g.LastSeen)
+	}
+	// 数量相同时最近出现的组在前
+	if groups[1].StatusCode != 0 || groups[2].StatusCode != 503 {
+		t.Errorf("tie order = %d, %d", groups[1].StatusCode, groups[2].StatusCode)
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+func TestTestModelMappingRouteScope(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	providerRepo := sqlite.NewProviderRepository(db)
+	routeRepo := sqlite.NewRouteRepository(db)
+	mappingRepo := sqlite.NewModelMappingRepository(db)
+	svc := &AdminService{providerRepo: providerRepo, routeRepo: routeRepo, modelMappingRepo: mappingRepo}
+
+	p := &domain.Provider{Name: "relay", Type: "custom"}
+	if err := providerRepo.Create(p); err != nil {
+		t.Fatalf("create provider: %v", err)
+	}
+	scoped := &domain.Route{IsEnabled: true, ClientType: domain.ClientTypeClaude, ProviderID: p.ID}
+	plain := &domain.Route{IsEnabled: true, ClientType: domain.ClientTypeClaude, ProviderID: p.ID, Position: 1}
+	for _, r := range []*domain.Route{scoped, plain} {
+		if err := routeRepo.Create(r); err != nil {
+			t.Fatalf("create route: %v", err)
+		}
+	}
+	for _, m := range []*domain.ModelMapping{
+		{Scope: domain.ModelMappingScopeGlobal, Pattern: "claude-*", Target: "global-target", Priority: 10},
+		{Scope: domain.ModelMappingScopeRoute, RouteID: scoped.ID, Pattern: "claude-*", Target: "route-target"},
+		{Scope: domain.ModelMappingScopeGlobal, APITokenID: 7, Pattern: "claude-haiku*", Target: "token-target"},
+	} {
+		if err := mappingRepo.Create(m); err != nil {
+			t.Fatalf("create mapping: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		routeID    uint64
+		apiTokenID uint64
+		model      string
+		want       string
+	}{
+		{"no route", 0, 0, "claude-sonnet-4", "global-target"},
+		{"route-scoped rule overrides global", scoped.ID, 0, "claude-sonnet-4", "route-target"},
+		{"other route", plain.ID, 0, "claude-sonnet-4", "global-target"},
+		{"token-scoped rule", plain.ID, 7, "claude-haiku-4", "token-target"},
+	}
+	for _, tt := range tests {
+		result, err := svc.TestModelMapping(domain.ClientTypeClaude, "", 0, 0, tt.routeID, tt.apiTokenID, tt.model)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if result.Target != tt.want || !result.Matched {
+			t.Errorf("%s: target = %q, want %q", tt.name, result.Target, tt.want)
+		}
+	}
+
+	if _, err := svc.TestModelMapping(domain.ClientTypeClaude, "", 0, 0, 999, 0, "claude-sonnet-4"); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("unknown route err = %v, want ErrInvalidInput", err)
+	}
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// defaultProjectSlugGraceDays 未配置 project_slug_grace_days 时旧 slug 的保留天数
+const defaultProjectSlugGraceDays = 30
+
+// projectSlugGrace returns how long a replaced slug keeps resolving
+func (s *AdminService) projectSlugGrace() time.Duration {
+	days := defaultProjectSlugGraceDays
+	if s.settingRepo != nil {
+		if v, err := s.settingRepo.Get(domain.SettingKeyProjectSlugGraceDays); err == nil && v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+				days = n
+			}
+		}
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// trackProjectSlugChange 维护项目的曾用 slug：slug 变更时校验新 slug 并记录旧 slug（宽限期为 0 时不记录），
+// 同时去掉已过期的记录和与新 slug 相同的记录（改回旧 slug）。曾用记录只由服务端维护，忽略客户端传入的值
+func (s *AdminService) trackProjectSlugChange(project *domain.Project, now time.Time) error {
+	existing, err := s.projectRepo.GetByID(project.ID)
+	if err != nil {
+		return err
+	}
+	if project.Slug != existing.Slug {
+		if err := domain.ValidateSlug(project.Slug); err != nil {
+			return err
+		}
+		if err := s.checkProjectSlugAvailable(project.Slug, project.ID, now); err != nil {
+			return err
+		}
+	}
+	var history []domain.PreviousSlug
+	for _, prev := range existing.PreviousSlugs {
+		if prev.Slug != project.Slug && now.Before(prev.ExpiresAt) {
+			history = append(history, prev)
+		}
+	}
+	if existing.Slug != "" && existing.Slug != project.Slug {
+		if grace := s.projectSlugGrace(); grace > 0 {
+			history = append(history, domain.PreviousSlug{Slug: existing.Slug, ExpiresAt: now.Add(grace)})
+		}
+	}
+	project.PreviousSlugs = history
+	return nil
+}
+
+// checkProjectSlugAvailable 拒绝其他项目仍在宽限期内的曾用 slug，避免旧地址上的客户端被转到别的项目
+func (s *AdminService) checkProjectSlugAvailable(slug string, projectID uint64, now time.Time) error {
+	projects, err := s.projectRepo.List()
+	if err != nil {
+		return err
+	}
+	for _, p := range projects {
+		if p.ID == projectID {
+			continue
+		}
+		if prev := p.ActivePreviousSlug(slug, now); prev != nil {
+			return fmt.Errorf("%w: %q is still a previous slug of project %q until %s",
+				domain.ErrSlugExists, slug, p.Name, prev.ExpiresAt.Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// generateProjectSlug 由名称生成 slug，已被其他项目使用或仍在宽限期内的曾用 slug 时
+// 依次追加 -2、-3…，避免新项目接管旧地址
+func (s *AdminService) generateProjectSlug(name string, now time.Time) (string, error) {
+	projects, err := s.projectRepo.List()
+	if err != nil {
+		return "", err
+	}
+	taken := func(slug string) bool {
+		for _, p := range projects {
+			if p.Slug == slug || p.ActivePreviousSlug(slug, now) != nil {
+				return true
+			}
+		}
+		return false
+	}
+	base := domain.GenerateSlug(name)
+	slug := base
+	for n := 2; taken(slug); n++ {
+		slug = base + "-" + strconv.Itoa(n)
+	}
+	return slug, nil
+}
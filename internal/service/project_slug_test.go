@@ -0,0 +1,82 @@
+package service
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+func TestProjectSlugHistory(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	settingRepo := sqlite.NewSystemSettingRepository(db)
+	svc := &AdminService{projectRepo: sqlite.NewProjectRepository(db), settingRepo: settingRepo}
+
+	alpha := &domain.Project{Name: "Alpha", Slug: "alpha"}
+	if err := svc.CreateProject(alpha); err != nil {
+		t.Fatalf("create alpha: %v", err)
+	}
+	if err := svc.CreateProject(&domain.Project{Name: "Bad", Slug: "Bad Slug"}); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("create with invalid slug err = %v, want ErrInvalidInput", err)
+	}
+
+	rename := func(p *domain.Project, slug string) error {
+		updated := *p
+		updated.Slug = slug
+		updated.PreviousSlugs = []domain.PreviousSlug{{Slug: "forged", ExpiresAt: time.Now().Add(time.Hour)}}
+		if err := svc.UpdateProject(&updated); err != nil {
+			return err
+		}
+		*p = updated
+		return nil
+	}
+
+	if err := rename(alpha, "alpha-v2"); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+	stored, _ := svc.GetProject(alpha.ID)
+	if len(stored.PreviousSlugs) != 1 || stored.PreviousSlugs[0].Slug != "alpha" {
+		t.Fatalf("previous slugs = %+v, want [alpha]", stored.PreviousSlugs)
+	}
+	if left := time.Until(stored.PreviousSlugs[0].ExpiresAt); left < 29*24*time.Hour || left > 30*24*time.Hour {
+		t.Errorf("old slug expires in %v, want the default 30 days", left)
+	}
+	if stored.ActivePreviousSlug("alpha", time.Now()) == nil {
+		t.Error("old slug should still resolve")
+	}
+
+	// 其他项目不能占用仍在宽限期内的曾用 slug
+	if err := svc.CreateProject(&domain.Project{Name: "Other", Slug: "alpha"}); !errors.Is(err, domain.ErrSlugExists) {
+		t.Errorf("create with previous slug err = %v, want ErrSlugExists", err)
+	}
+
+	// 由名称自动生成的 slug 同样不能接管曾用 slug
+	auto := &domain.Project{Name: "Alpha"}
+	if err := svc.CreateProject(auto); err != nil {
+		t.Fatalf("create with generated slug: %v", err)
+	}
+	if auto.Slug != "alpha-2" {
+		t.Errorf("generated slug = %q, want alpha-2 (alpha is a previous slug)", auto.Slug)
+	}
+	if got, err := svc.GetProjectBySlug("alpha"); err == nil {
+		t.Errorf("previous slug alpha now belongs to project %d", got.ID)
+	}
+
+	// 改回旧 slug 时从曾用记录中移除；宽限期为 0 时不记录旧 slug
+	if err := settingRepo.Set(domain.SettingKeyProjectSlugGraceDays, "0"); err != nil {
+		t.Fatalf("set grace: %v", err)
+	}
+	if err := rename(alpha, "alpha"); err != nil {
+		t.Fatalf("rename back: %v", err)
+	}
+	stored, _ = svc.GetProject(alpha.ID)
+	if len(stored.PreviousSlugs) != 0 {
+		t.Errorf("previous slugs after rename back = %+v, want none", stored.PreviousSlugs)
+	}
+}
@@ -0,0 +1,260 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// 凭证导出的加密方式：
+//   - 密钥派生：PBKDF2-HMAC-SHA256(口令, 16 字节随机 salt, 600000 次) 得到 32 字节密钥
+//   - 加密：AES-256-GCM，12 字节随机 nonce；Provider 类型作为附加认证数据（AAD），
+//     防止把密文导入到其他类型的 Provider
+//   - 每次导出都生成新的 salt 和 nonce，口令错误或密文被篡改时 GCM 校验失败
+//
+// 口令只用于本次请求，不落库也不写日志
+const (
+	CredentialBundleVersion = 1
+	credentialKDF           = "pbkdf2-sha256"
+	credentialCipher        = "aes-256-gcm"
+	credentialIterations    = 600_000
+	credentialMaxIterations = 10_000_000 // 导入时的上限，避免构造的包消耗过多 CPU
+	credentialSaltSize      = 16
+	credentialKeySize       = 32
+
+	// MinCredentialPassphraseLength 导出/导入口令的最小长度
+	MinCredentialPassphraseLength = 8
+)
+
+// CredentialBundle 加密后的 Provider 凭证，可安全地放入备份
+type CredentialBundle struct {
+	Version      int    `json:"version"`
+	ProviderType string `json:"providerType"`
+	KDF          string `json:"kdf"`
+	Iterations   int    `json:"iterations"`
+	Cipher       string `json:"cipher"`
+	Salt         []byte `json:"salt"`       // base64
+	Nonce        []byte `json:"nonce"`      // base64
+	Ciphertext   []byte `json:"ciphertext"` // base64，含 GCM tag
+}
+
+// providerCredentials 凭证明文：只包含各类型的敏感字段和标识帐号的字段，
+// 模型映射等普通配置不在其中，导入时保留目标 Provider 的原有配置
+type providerCredentials struct {
+	APIKey      string                            `json:"apiKey,omitempty"`
+	Antigravity *domain.ProviderConfigAntigravity `json:"antigravity,omitempty"`
+	Kiro        *domain.ProviderConfigKiro        `json:"kiro,omitempty"`
+	Codex       *domain.ProviderConfigCodex       `json:"codex,omitempty"`
+}
+
+// ExportProviderCredentials 用口令加密导出 Provider 的凭证（API Key / OAuth token）
+func (s *AdminService) ExportProviderCredentials(id uint64, passphrase string) (*CredentialBundle, error) {
+	if err := validateCredentialPassphrase(passphrase); err != nil {
+		return nil, err
+	}
+	provider, err := s.providerRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := extractProviderCredentials(provider)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &CredentialBundle{
+		Version:      CredentialBundleVersion,
+		ProviderType: provider.Type,
+		KDF:          credentialKDF,
+		Iterations:   credentialIterations,
+		Cipher:       credentialCipher,
+		Salt:         make([]byte, credentialSaltSize),
+	}
+	if _, err := rand.Read(bundle.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := credentialAEAD(passphrase, bundle.Salt, bundle.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	bundle.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(bundle.Nonce); err != nil {
+		return nil, err
+	}
+	bundle.Ciphertext = aead.Seal(nil, bundle.Nonce, plaintext, []byte(provider.Type))
+	return bundle, nil
+}
+
+// ImportProviderCredentials 解密凭证并写入已存在的同类型 Provider，其余配置保持不变
+func (s *AdminService) ImportProviderCredentials(id uint64, bundle *CredentialBundle, passphrase string) (*domain.Provider, error) {
+	if err := validateCredentialPassphrase(passphrase); err != nil {
+		return nil, err
+	}
+	if bundle == nil || bundle.Version != CredentialBundleVersion || bundle.KDF != credentialKDF || bundle.Cipher != credentialCipher {
+		return nil, fmt.Errorf("%w: unsupported credential bundle", domain.ErrInvalidInput)
+	}
+	if bundle.Iterations <= 0 || bundle.Iterations > credentialMaxIterations || len(bundle.Salt) == 0 {
+		return nil, fmt.Errorf("%w: invalid key derivation parameters", domain.ErrInvalidInput)
+	}
+
+	provider, err := s.providerRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if provider.Type != bundle.ProviderType {
+		return nil, fmt.Errorf("%w: credentials are for a %q provider, target is %q", domain.ErrInvalidInput, bundle.ProviderType, provider.Type)
+	}
+
+	aead, err := credentialAEAD(passphrase, bundle.Salt, bundle.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	if len(bundle.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("%w: invalid nonce", domain.ErrInvalidInput)
+	}
+	plaintext, err := aead.Open(nil, bundle.Nonce, bundle.Ciphertext, []byte(bundle.ProviderType))
+	if err != nil {
+		return nil, fmt.Errorf("%w: wrong passphrase or corrupted credentials", domain.ErrInvalidInput)
+	}
+	var creds providerCredentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return nil, fmt.Errorf("%w: invalid credential payload", domain.ErrInvalidInput)
+	}
+
+	if err := applyProviderCredentials(provider, &creds); err != nil {
+		return nil, err
+	}
+	if err := s.UpdateProvider(provider); err != nil {
+		return nil, err
+	}
+	return provider, nil
+}
+
+func validateCredentialPassphrase(passphrase string) error {
+	if len(passphrase) < MinCredentialPassphraseLength {
+		return fmt.Errorf("%w: passphrase must be at least %d characters", domain.ErrInvalidInput, MinCredentialPassphraseLength)
+	}
+	return nil
+}
+
+func credentialAEAD(passphrase string, salt []byte, iterations int) (cipher.AEAD, error) {
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, credentialKeySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// extractProviderCredentials 取出 Provider 的敏感字段
+func extractProviderCredentials(provider *domain.Provider) (*providerCredentials, error) {
+	cfg := provider.Config
+	if cfg == nil {
+		cfg = &domain.ProviderConfig{}
+	}
+	creds := &providerCredentials{}
+	switch provider.Type {
+	case "custom":
+		if cfg.Custom != nil {
+			creds.APIKey = cfg.Custom.APIKey
+		}
+	case "antigravity":
+		if c := cfg.Antigravity; c != nil {
+			creds.Antigravity = &domain.ProviderConfigAntigravity{Email: c.Email, RefreshToken: c.RefreshToken, ProjectID: c.ProjectID}
+		}
+	case "kiro":
+		if c := cfg.Kiro; c != nil {
+			creds.Kiro = &domain.ProviderConfigKiro{
+				AuthMethod:   c.AuthMethod,
+				RefreshToken: c.RefreshToken,
+				Region:       c.Region,
+				ClientID:     c.ClientID,
+				ClientSecret: c.ClientSecret,
+				Email:        c.Email,
+			}
+		}
+	case "codex":
+		if c := cfg.Codex; c != nil {
+			creds.Codex = &domain.ProviderConfigCodex{
+				Email:        c.Email,
+				RefreshToken: c.RefreshToken,
+				AccessToken:  c.AccessToken,
+				ExpiresAt:    c.ExpiresAt,
+				AccountID:    c.AccountID,
+				UserID:       c.UserID,
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: provider type %q has no exportable credentials", domain.ErrInvalidInput, provider.Type)
+	}
+	return creds, nil
+}
+
+// applyProviderCredentials 把凭证写入 Provider 配置，只覆盖凭证字段
+func applyProviderCredentials(provider *domain.Provider, creds *providerCredentials) error {
+	if provider.Config == nil {
+		provider.Config = &domain.ProviderConfig{}
+	}
+	cfg := provider.Config
+	switch provider.Type {
+	case "custom":
+		if cfg.Custom == nil {
+			cfg.Custom = &domain.ProviderConfigCustom{}
+		}
+		cfg.Custom.APIKey = creds.APIKey
+	case "antigravity":
+		c := creds.Antigravity
+		if c == nil {
+			return fmt.Errorf("%w: bundle has no antigravity credentials", domain.ErrInvalidInput)
+		}
+		if cfg.Antigravity == nil {
+			cfg.Antigravity = &domain.ProviderConfigAntigravity{}
+		}
+		cfg.Antigravity.Email = c.Email
+		cfg.Antigravity.RefreshToken = c.RefreshToken
+		cfg.Antigravity.ProjectID = c.ProjectID
+	case "kiro":
+		c := creds.Kiro
+		if c == nil {
+			return fmt.Errorf("%w: bundle has no kiro credentials", domain.ErrInvalidInput)
+		}
+		if cfg.Kiro == nil {
+			cfg.Kiro = &domain.ProviderConfigKiro{}
+		}
+		cfg.Kiro.AuthMethod = c.AuthMethod
+		cfg.Kiro.RefreshToken = c.RefreshToken
+		cfg.Kiro.Region = c.Region
+		cfg.Kiro.ClientID = c.ClientID
+		cfg.Kiro.ClientSecret = c.ClientSecret
+		cfg.Kiro.Email = c.Email
+	case "codex":
+		c := creds.Codex
+		if c == nil {
+			return fmt.Errorf("%w: bundle has no codex credentials", domain.ErrInvalidInput)
+		}
+		if cfg.Codex == nil {
+			cfg.Codex = &domain.ProviderConfigCodex{}
+		}
+		cfg.Codex.Email = c.Email
+		cfg.Codex.RefreshToken = c.RefreshToken
+		cfg.Codex.AccessToken = c.AccessToken
+		cfg.Codex.ExpiresAt = c.ExpiresAt
+		cfg.Codex.AccountID = c.AccountID
+		cfg.Codex.UserID = c.UserID
+	default:
+		return fmt.Errorf("%w: provider type %q has no importable credentials", domain.ErrInvalidInput, provider.Type)
+	}
+	return nil
+}
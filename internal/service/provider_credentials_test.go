@@ -0,0 +1,73 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+func TestProviderCredentialsRoundTrip(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	providerRepo := sqlite.NewProviderRepository(db)
+	s := &AdminService{providerRepo: providerRepo}
+
+	source := &domain.Provider{Name: "codex-a", Type: "codex", Config: &domain.ProviderConfig{Codex: &domain.ProviderConfigCodex{
+		Email: "a@example.com", RefreshToken: "rt-secret", AccessToken: "at-secret", AccountID: "acct-1",
+		ModelMapping: map[string]string{"gpt-5": "gpt-5-codex"},
+	}}}
+	target := &domain.Provider{Name: "codex-b", Type: "codex", Config: &domain.ProviderConfig{Codex: &domain.ProviderConfigCodex{
+		ModelMapping: map[string]string{"x": "y"},
+	}}}
+	custom := &domain.Provider{Name: "relay", Type: "custom", Config: &domain.ProviderConfig{Custom: &domain.ProviderConfigCustom{BaseURL: "https://relay.example.com"}}}
+	for _, p := range []*domain.Provider{source, target, custom} {
+		if err := providerRepo.Create(p); err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+	}
+
+	const passphrase = "correct horse battery"
+	if _, err := s.ExportProviderCredentials(source.ID, "short"); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("short passphrase err = %v, want ErrInvalidInput", err)
+	}
+	bundle, err := s.ExportProviderCredentials(source.ID, passphrase)
+	if err != nil {
+		t.Fatalf("ExportProviderCredentials failed: %v", err)
+	}
+	if bytes.Contains(bundle.Ciphertext, []byte("rt-secret")) || bundle.ProviderType != "codex" {
+		t.Fatalf("bundle = %+v, want encrypted codex credentials", bundle)
+	}
+
+	if _, err := s.ImportProviderCredentials(target.ID, bundle, "wrong passphrase"); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("wrong passphrase err = %v, want ErrInvalidInput", err)
+	}
+	if _, err := s.ImportProviderCredentials(custom.ID, bundle, passphrase); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("type mismatch err = %v, want ErrInvalidInput", err)
+	}
+	tampered := *bundle
+	tampered.ProviderType = "custom"
+	if _, err := s.ImportProviderCredentials(custom.ID, &tampered, passphrase); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("tampered provider type err = %v, want ErrInvalidInput", err)
+	}
+
+	if _, err := s.ImportProviderCredentials(target.ID, bundle, passphrase); err != nil {
+		t.Fatalf("ImportProviderCredentials failed: %v", err)
+	}
+	stored, err := providerRepo.GetByID(target.ID)
+	if err != nil {
+		t.Fatalf("get target: %v", err)
+	}
+	c := stored.Config.Codex
+	if c.RefreshToken != "rt-secret" || c.AccessToken != "at-secret" || c.Email != "a@example.com" || c.AccountID != "acct-1" {
+		t.Errorf("imported codex config = %+v", c)
+	}
+	if c.ModelMapping["x"] != "y" || len(c.ModelMapping) != 1 {
+		t.Errorf("model mapping = %v, want target's own mapping kept", c.ModelMapping)
+	}
+}
@@ -0,0 +1,63 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// ProviderStatusReporter reports runtime provider state kept by the router
+type ProviderStatusReporter interface {
+	ProviderGroups() []*domain.ProviderGroupStatus
+	ProviderDrainStatus(providerID uint64) *domain.ProviderDrainStatus
+	ProviderHealthScores() []*domain.ProviderHealthScore
+}
+
+// GetProviderGroups returns every provider group with per-member and pooled remaining quota
+func (s *AdminService) GetProviderGroups() []*domain.ProviderGroupStatus {
+	if s.statusReporter == nil {
+		return []*domain.ProviderGroupStatus{}
+	}
+	return s.statusReporter.ProviderGroups()
+}
+
+// GetProviderHealthScores returns the 0-100 health score of every provider
+func (s *AdminService) GetProviderHealthScores() []*domain.ProviderHealthScore {
+	if s.statusReporter == nil {
+		return []*domain.ProviderHealthScore{}
+	}
+	return s.statusReporter.ProviderHealthScores()
+}
+
+// GetProviderDrainStatus returns whether the provider is draining and its in-flight requests
+func (s *AdminService) GetProviderDrainStatus(id uint64) (*domain.ProviderDrainStatus, error) {
+	provider, err := s.providerRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if s.statusReporter != nil {
+		if status := s.statusReporter.ProviderDrainStatus(id); status != nil {
+			return status, nil
+		}
+	}
+	return &domain.ProviderDrainStatus{ProviderID: id, Draining: provider.Draining, Drained: provider.Draining}, nil
+}
+
+// SetProviderDraining starts or stops draining a provider. A draining provider
+// gets no new requests; requests already running on it finish normally.
+func (s *AdminService) SetProviderDraining(id uint64, draining bool) (*domain.ProviderDrainStatus, error) {
+	provider, err := s.providerRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if provider.Draining != draining {
+		provider.Draining = draining
+		if err := s.providerRepo.Update(provider); err != nil {
+			return nil, fmt.Errorf("update provider: %w", err)
+		}
+		if s.adapterRefresher != nil {
+			s.adapterRefresher.RefreshAdapter(provider)
+		}
+	}
+	return s.GetProviderDrainStatus(id)
+}
@@ -0,0 +1,56 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/cooldown"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// ResetProviderHealth clears a provider's cooldowns and failure counts across
+// all client types, returning it to rotation immediately. Used after an admin
+// fixed the provider, so the next failure starts the backoff from scratch.
+func (s *AdminService) ResetProviderHealth(providerID uint64) error {
+	if _, err := s.providerRepo.GetByID(providerID); err != nil {
+		return err
+	}
+	if err := cooldown.Default().ResetProvider(providerID); err != nil {
+		return fmt.Errorf("reset provider health: %w", err)
+	}
+	if s.broadcaster != nil {
+		s.broadcaster.BroadcastMessage("cooldown_update", map[string]interface{}{
+			"providerID": providerID,
+		})
+	}
+	return nil
+}
+
+// GetBreakerMetrics returns each provider's breaker metrics (trips, time spent
+// open/half-open/closed, probe success rate) over cooldown.BreakerMetricsWindow.
+// The counters live in memory and restart from zero with the process.
+func (s *AdminService) GetBreakerMetrics() []*cooldown.BreakerMetrics {
+	metrics := cooldown.Default().BreakerMetrics(time.Now())
+	if len(metrics) == 0 {
+		return metrics
+	}
+	names := make(map[uint64]string)
+	if providers, err := s.providerRepo.List(); err == nil {
+		for _, p := range providers {
+			names[p.ID] = p.Name
+		}
+	}
+	for _, m := range metrics {
+		m.ProviderName = names[m.ProviderID]
+	}
+	return metrics
+}
+
+// GetCooldownHistory returns a provider's cooldown enter/exit events since the
+// given time (zero for all retained events), newest first
+func (s *AdminService) GetCooldownHistory(providerID uint64, since time.Time, limit int) ([]*domain.CooldownEvent, error) {
+	if _, err := s.providerRepo.GetByID(providerID); err != nil {
+		return nil, err
+	}
+	return cooldown.Default().History(providerID, since, limit)
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	ctxutil "github.com/awsl-project/maxx/internal/context"
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// RequestResolver resolves the routing decision for a hypothetical request
+// without dispatching it. Implemented by Executor.
+type RequestResolver interface {
+	Resolve(ctx context.Context, clientType domain.ClientType, projectID uint64, requestModel string, apiTokenID uint64, stream bool) *domain.RequestResolution
+}
+
+// ResolveRequest returns the routing decision the proxy would make for req:
+// matched routes in order, model mapping, retry config, format conversion and
+// provider cooldowns. The project is resolved like the proxy handler does:
+// explicit projectID, then the token's project, then X-Maxx-Project-ID.
+func (s *AdminService) ResolveRequest(ctx context.Context, req *domain.ResolveRequest) (*domain.RequestResolution, error) {
+	if s.requestResolver == nil {
+		return nil, fmt.Errorf("request resolution is not available")
+	}
+	if req == nil || req.ClientType == "" {
+		return nil, fmt.Errorf("%w: clientType is required", domain.ErrInvalidInput)
+	}
+
+	headers := make(map[string]string, len(req.Headers))
+	for k, v := range req.Headers {
+		headers[strings.ToLower(k)] = v
+	}
+
+	token := req.Token
+	if token == "" {
+		token = tokenFromHeaders(headers)
+	}
+	var apiToken *domain.APIToken
+	if token != "" {
+		t, err := s.apiTokenRepo.GetByToken(token)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown API token", domain.ErrInvalidInput)
+		}
+		apiToken = t
+	}
+
+	projectID := req.ProjectID
+	if projectID == 0 && apiToken != nil {
+		projectID = apiToken.ProjectID
+	}
+	if projectID == 0 {
+		if pid, err := strconv.ParseUint(headers["x-maxx-project-id"], 10, 64); err == nil {
+			projectID = pid
+		}
+	}
+
+	var apiTokenID uint64
+	if apiToken != nil {
+		apiTokenID = apiToken.ID
+		if len(apiToken.ModelFallbacks) > 0 {
+			ctx = ctxutil.WithModelFallbacks(ctx, apiToken.ModelFallbacks)
+		}
+	}
+
+	return s.requestResolver.Resolve(ctx, req.ClientType, projectID, req.Model, apiTokenID, req.Stream), nil
+}
+
+// tokenFromHeaders extracts an API token from lower-cased request headers
+// (Authorization: Bearer, x-api-key, x-goog-api-key)
+func tokenFromHeaders(headers map[string]string) string {
+	if auth := headers["authorization"]; auth != "" {
+		return strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+	}
+	if token := headers["x-api-key"]; token != "" {
+		return token
+	}
+	return headers["x-goog-api-key"]
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// RequestWatcher subscribes the admin UI to every broadcast update of a single
+// proxy request while global broadcasting is sampled. Implemented by Executor.
+type RequestWatcher interface {
+	WatchRequest(proxyRequestID uint64) time.Time
+}
+
+// WatchProxyRequestResult 请求的完整详情，以及完整广播的订阅到期时间
+type WatchProxyRequestResult struct {
+	Request    *domain.ProxyRequest           `json:"request"`
+	Attempts   []*domain.ProxyUpstreamAttempt `json:"attempts"`
+	WatchUntil time.Time                      `json:"watchUntil"`
+}
+
+// WatchProxyRequest returns the request with all its attempts and keeps
+// broadcasting its intermediate updates in full until WatchUntil, even when
+// attempt_broadcast_max_qps suppresses them for other requests
+func (s *AdminService) WatchProxyRequest(id uint64) (*WatchProxyRequestResult, error) {
+	req, err := s.proxyRequestRepo.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	result := &WatchProxyRequestResult{Request: req}
+	if s.requestWatcher != nil {
+		result.WatchUntil = s.requestWatcher.WatchRequest(id)
+	}
+	attempts, err := s.attemptRepo.ListByProxyRequestID(id)
+	if err != nil {
+		return nil, err
+	}
+	result.Attempts = attempts
+	return result, nil
+}
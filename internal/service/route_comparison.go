@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// Route comparison limits
+const (
+	maxCompareRequests    = 20                     // 单次对比最多重放的请求数
+	compareReplayInterval = 500 * time.Millisecond // 相邻两次重放之间的最小间隔
+)
+
+// ErrComparisonInProgress is returned when another route comparison is still running
+var ErrComparisonInProgress = errors.New("a route comparison is already running")
+
+// RequestReplayer replays a recorded request on a specific route.
+// Implemented by Executor.
+type RequestReplayer interface {
+	ReplayOnRoute(ctx context.Context, original *domain.ProxyRequest, routeID uint64, comparisonTag string) (*domain.ProxyRequest, string, error)
+}
+
+// CompareRoutes replays each request on routeA and routeB and returns a
+// comparison report (latency, tokens, cost and a rough response similarity).
+// Replays are real upstream requests: they are recorded as proxy requests tagged
+// with the report's ComparisonTag, non-billable and not attributed to the
+// original API token (so they don't count against its quotas). Only one comparison
+// runs at a time, at most maxCompareRequests requests are replayed, and replays
+// are spaced by compareReplayInterval.
+func (s *AdminService) CompareRoutes(ctx context.Context, requestIDs []uint64, routeA, routeB uint64) (*domain.RouteComparisonReport, error) {
+	if s.requestReplayer == nil {
+		return nil, fmt.Errorf("request replay is not available")
+	}
+	if routeA == 0 || routeB == 0 || routeA == routeB {
+		return nil, fmt.Errorf("%w: two different routes are required", domain.ErrInvalidInput)
+	}
+	ids := uniqueIDs(requestIDs)
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("%w: requestIDs is required", domain.ErrInvalidInput)
+	}
+	if len(ids) > maxCompareRequests {
+		return nil, fmt.Errorf("%w: at most %d requests can be compared at once", domain.ErrInvalidInput, maxCompareRequests)
+	}
+	for _, id := range []uint64{routeA, routeB} {
+		if _, err := s.routeRepo.GetByID(id); err != nil {
+			return nil, fmt.Errorf("route %d: %w", id, err)
+		}
+	}
+
+	// 先加载全部原始请求，避免重放到一半才发现数据缺失
+	originals := make([]*domain.ProxyRequest, 0, len(ids))
+	for _, id := range ids {
+		req, err := s.proxyRequestRepo.GetByID(id)
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %w", id, err)
+		}
+		if req.RequestInfo == nil || req.RequestInfo.Body == "" {
+			return nil, fmt.Errorf("%w: request %d has no retained request detail", domain.ErrInvalidInput, id)
+		}
+		originals = append(originals, req)
+	}
+
+	if !s.compareMu.TryLock() {
+		return nil, ErrComparisonInProgress
+	}
+	defer s.compareMu.Unlock()
+
+	tag := fmt.Sprintf("cmp-%d", time.Now().UnixMilli())
+	log.Printf("[CompareRoutes] %s: replaying %d requests on routes %d and %d", tag, len(originals), routeA, routeB)
+
+	samples := make([]*domain.RouteComparisonSample, 0, len(originals))
+	for i, original := range originals {
+		if i > 0 {
+			if err := waitReplayInterval(ctx); err != nil {
+				return nil, err
+			}
+		}
+		sample := &domain.RouteComparisonSample{RequestID: original.ID}
+		var textA, textB string
+		sample.A, textA = s.replayForComparison(ctx, original, routeA, tag)
+		if err := waitReplayInterval(ctx); err != nil {
+			return nil, err
+		}
+		sample.B, textB = s.replayForComparison(ctx, original, routeB, tag)
+		if sample.A.Status == "COMPLETED" && sample.B.Status == "COMPLETED" {
+			sample.Similarity = textSimilarity(textA, textB)
+		}
+		samples = append(samples, sample)
+	}
+
+	return summarizeRouteComparison(tag, routeA, routeB, samples), nil
+}
+
+func waitReplayInterval(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(compareReplayInterval):
+		return nil
+	}
+}
+
+func (s *AdminService) replayForComparison(ctx context.Context, original *domain.ProxyRequest, routeID uint64, tag string) (*domain.RouteComparisonRun, string) {
+	replayed, text, err := s.requestReplayer.ReplayOnRoute(ctx, original, routeID, tag)
+	if replayed == nil {
+		run := &domain.RouteComparisonRun{Status: "FAILED"}
+		if err != nil {
+			run.Error = err.Error()
+		}
+		return run, ""
+	}
+	return &domain.RouteComparisonRun{
+		ProxyRequestID: replayed.ID,
+		Status:         replayed.Status,
+		Error:          replayed.Error,
+		DurationMs:     replayed.Duration.Milliseconds(),
+		TTFTMs:         replayed.TTFT.Milliseconds(),
+		InputTokens:    replayed.InputTokenCount,
+		OutputTokens:   replayed.OutputTokenCount,
+		Cost:           replayed.Cost,
+	}, text
+}
+
+// summarizeRouteComparison aggregates per-route totals and B-minus-A deltas
+func summarizeRouteComparison(tag string, routeA, routeB uint64, samples []*domain.RouteComparisonSample) *domain.RouteComparisonReport {
+	report := &domain.RouteComparisonReport{
+		ComparisonTag: tag,
+		A:             domain.RouteComparisonSide{RouteID: routeA},
+		B:             domain.RouteComparisonSide{RouteID: routeB},
+		Samples:       samples,
+	}
+
+	var accA, accB comparisonAccumulator
+	var similaritySum float64
+	var similarityCount int
+	for _, sample := range samples {
+		accA.add(&report.A, sample.A)
+		accB.add(&report.B, sample.B)
+		if sample.A.Status == "COMPLETED" && sample.B.Status == "COMPLETED" {
+			similaritySum += sample.Similarity
+			similarityCount++
+		}
+	}
+	accA.finish(&report.A)
+	accB.finish(&report.B)
+
+	report.AvgDurationDeltaMs = report.B.AvgDurationMs - report.A.AvgDurationMs
+	report.OutputTokensDelta = int64(report.B.OutputTokens) - int64(report.A.OutputTokens)
+	report.CostDelta = int64(report.B.TotalCost) - int64(report.A.TotalCost)
+	if similarityCount > 0 {
+		report.AvgSimilarity = similaritySum / float64(similarityCount)
+	}
+	return report
+}
+
+// comparisonAccumulator 累计一条路由成功重放的耗时，用于计算平均值
+type comparisonAccumulator struct {
+	durationSum int64
+	ttftSum     int64
+	ttftCount   int64
+}
+
+func (acc *comparisonAccumulator) add(side *domain.RouteComparisonSide, run *domain.RouteComparisonRun) {
+	if run.Status != "COMPLETED" {
+		side.Failed++
+		return
+	}
+	side.Successful++
+	side.InputTokens += run.InputTokens
+	side.OutputTokens += run.OutputTokens
+	side.TotalCost += run.Cost
+	acc.durationSum += run.DurationMs
+	if run.TTFTMs > 0 {
+		acc.ttftSum += run.TTFTMs
+		acc.ttftCount++
+	}
+}
+
+func (acc *comparisonAccumulator) finish(side *domain.RouteComparisonSide) {
+	if side.Successful > 0 {
+		side.AvgDurationMs = acc.durationSum / int64(side.Successful)
+	}
+	if acc.ttftCount > 0 {
+		side.AvgTTFTMs = acc.ttftSum / acc.ttftCount
+	}
+}
+
+// textSimilarity is the Jaccard similarity of the two texts' lowercase word sets
+func textSimilarity(a, b string) float64 {
+	wordsA := wordSet(a)
+	wordsB := wordSet(b)
+	if len(wordsA) == 0 && len(wordsB) == 0 {
+		return 1
+	}
+	intersection := 0
+	for w := range wordsA {
+		if wordsB[w] {
+			intersection++
+		}
+	}
+	union := len(wordsA) + len(wordsB) - intersection
+	return float64(intersection) / float64(union)
+}
+
+func wordSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(strings.ToLower(s)) {
+		set[w] = true
+	}
+	return set
+}
+
+func uniqueIDs(ids []uint64) []uint64 {
+	seen := make(map[uint64]bool, len(ids))
+	result := make([]uint64, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
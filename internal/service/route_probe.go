@@ -0,0 +1,175 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// Route test limits
+const (
+	routeProbeTimeout       = 60 * time.Second // 单条路由测试的超时
+	routeProbeConcurrency   = 4                // 同时测试的路由数
+	defaultRouteProbePrompt = "Reply with the single word OK."
+)
+
+// routeProbeModels 各 ClientType 测试请求的默认模型，Provider 配置了具体的支持模型时优先使用其第一个
+var routeProbeModels = map[domain.ClientType]string{
+	domain.ClientTypeClaude: "claude-sonnet-4-5",
+	domain.ClientTypeOpenAI: "gpt-4o-mini",
+	domain.ClientTypeCodex:  "gpt-5-codex",
+	domain.ClientTypeGemini: "gemini-2.5-flash",
+}
+
+// RouteProber sends a canned request pinned to one route.
+// Implemented by Executor.
+type RouteProber interface {
+	ProbeRoute(ctx context.Context, routeID uint64, clientType domain.ClientType, projectID uint64, model, prompt, tag string) (*domain.ProxyRequest, error)
+}
+
+// TestProjectRoutes sends a canned request to every enabled route the project
+// uses for clientType (its custom routes, or the global routes when it has none
+// for that client type; projectID 0 tests the global routes). Each probe is
+// pinned to its route: no failover, no retry, and no effect on cooldowns or
+// failure counts. Probes run concurrently, each bounded by routeProbeTimeout,
+// and are recorded as proxy requests tagged with the report's ProbeTag.
+func (s *AdminService) TestProjectRoutes(ctx context.Context, projectID uint64, clientType domain.ClientType, prompt string) (*domain.ProjectRouteTestReport, error) {
+	if s.routeProber == nil {
+		return nil, fmt.Errorf("route testing is not available")
+	}
+	if _, ok := routeProbeModels[clientType]; !ok {
+		return nil, fmt.Errorf("%w: unsupported client type %q", domain.ErrInvalidInput, clientType)
+	}
+	if strings.TrimSpace(prompt) == "" {
+		prompt = defaultRouteProbePrompt
+	}
+
+	routes, projectRoutes, err := s.projectRoutesFor(projectID, clientType)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := fmt.Sprintf("probe-%d", time.Now().UnixMilli())
+	log.Printf("[TestProjectRoutes] %s: testing %d %s routes of project %d", tag, len(routes), clientType, projectID)
+
+	results := make([]*domain.RouteTestResult, len(routes))
+	sem := make(chan struct{}, routeProbeConcurrency)
+	var wg sync.WaitGroup
+	for i, route := range routes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = s.probeRoute(ctx, route, clientType, projectID, prompt, tag)
+		}()
+	}
+	wg.Wait()
+
+	report := &domain.ProjectRouteTestReport{
+		ProjectID:     projectID,
+		ClientType:    clientType,
+		ProjectRoutes: projectRoutes,
+		ProbeTag:      tag,
+		Results:       results,
+	}
+	for _, r := range results {
+		if r.Success {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// projectRoutesFor returns the enabled routes the router would consider for the
+// project and client type, ordered by position, and whether they are the
+// project's custom routes
+func (s *AdminService) projectRoutesFor(projectID uint64, clientType domain.ClientType) ([]*domain.Route, bool, error) {
+	useProjectRoutes := false
+	if projectID != 0 {
+		project, err := s.projectRepo.GetByID(projectID)
+		if err != nil {
+			return nil, false, err
+		}
+		useProjectRoutes = slices.Contains(project.EnabledCustomRoutes, clientType)
+	}
+
+	all, err := s.routeRepo.List()
+	if err != nil {
+		return nil, false, err
+	}
+	var projectRoutes, globalRoutes []*domain.Route
+	for _, route := range all {
+		if !route.IsEnabled || route.ClientType != clientType {
+			continue
+		}
+		if useProjectRoutes && route.ProjectID == projectID {
+			projectRoutes = append(projectRoutes, route)
+		} else if route.ProjectID == 0 {
+			globalRoutes = append(globalRoutes, route)
+		}
+	}
+	routes, isProject := globalRoutes, false
+	if len(projectRoutes) > 0 {
+		routes, isProject = projectRoutes, true
+	}
+	sort.SliceStable(routes, func(i, j int) bool { return routes[i].Position < routes[j].Position })
+	return routes, isProject, nil
+}
+
+func (s *AdminService) probeRoute(ctx context.Context, route *domain.Route, clientType domain.ClientType, projectID uint64, prompt, tag string) *domain.RouteTestResult {
+	result := &domain.RouteTestResult{
+		RouteID:    route.ID,
+		Position:   route.Position,
+		ProviderID: route.ProviderID,
+		Model:      routeProbeModels[clientType],
+	}
+	if provider, err := s.providerRepo.GetByID(route.ProviderID); err == nil {
+		result.ProviderName = provider.Name
+		if model := firstConcreteModel(provider.SupportModels); model != "" {
+			result.Model = model
+		}
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, routeProbeTimeout)
+	defer cancel()
+	proxyReq, err := s.routeProber.ProbeRoute(probeCtx, route.ID, clientType, projectID, result.Model, prompt, tag)
+	if proxyReq == nil {
+		result.Status = "FAILED"
+		if err != nil {
+			result.Error = err.Error()
+		}
+		return result
+	}
+	result.ProxyRequestID = proxyReq.ID
+	result.Status = proxyReq.Status
+	result.Success = proxyReq.Status == "COMPLETED"
+	result.Error = proxyReq.Error
+	if result.Error == "" && !result.Success && err != nil {
+		result.Error = err.Error()
+	}
+	result.DurationMs = proxyReq.Duration.Milliseconds()
+	result.InputTokens = proxyReq.InputTokenCount
+	result.OutputTokens = proxyReq.OutputTokenCount
+	result.Cost = proxyReq.Cost
+	return result
+}
+
+// firstConcreteModel returns the first supported model that is not a wildcard pattern
+func firstConcreteModel(patterns []string) string {
+	for _, p := range patterns {
+		if p != "" && !strings.Contains(p, "*") {
+			return p
+		}
+	}
+	return ""
+}
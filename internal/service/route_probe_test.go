@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+// fakeRouteProber 记录被测试的路由，provider 2 的路由失败
+type fakeRouteProber struct {
+	mu     sync.Mutex
+	routes []uint64
+	models map[uint64]string
+}
+
+func (f *fakeRouteProber) ProbeRoute(ctx context.Context, routeID uint64, clientType domain.ClientType, projectID uint64, model, prompt, tag string) (*domain.ProxyRequest, error) {
+	f.mu.Lock()
+	f.routes = append(f.routes, routeID)
+	f.models[routeID] = model
+	f.mu.Unlock()
+	if routeID == 0 || prompt == "" || tag == "" {
+		return nil, errors.New("bad probe")
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		return nil, errors.New("probe without timeout")
+	}
+	req := &domain.ProxyRequest{ID: routeID * 100, Status: "COMPLETED", Duration: 50 * time.Millisecond, OutputTokenCount: 3}
+	if routeID%2 == 0 {
+		req.Status, req.Error = "FAILED", "upstream error"
+	}
+	return req, nil
+}
+
+func TestTestProjectRoutes(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	providerRepo := sqlite.NewProviderRepository(db)
+	routeRepo := sqlite.NewRouteRepository(db)
+	projectRepo := sqlite.NewProjectRepository(db)
+
+	var providers []*domain.Provider
+	for _, name := range []string{"a", "b", "c"} {
+		p := &domain.Provider{Name: name, Type: "custom", SupportModels: []string{"claude-*", "claude-haiku-4-5"}}
+		if err := providerRepo.Create(p); err != nil {
+			t.Fatalf("create provider: %v", err)
+		}
+		providers = append(providers, p)
+	}
+	project := &domain.Project{Name: "p", Slug: "p", EnabledCustomRoutes: []domain.ClientType{domain.ClientTypeClaude}}
+	if err := projectRepo.Create(project); err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	routes := []*domain.Route{
+		{IsEnabled: true, ProjectID: project.ID, ClientType: domain.ClientTypeClaude, ProviderID: providers[1].ID, Position: 2},
+		{IsEnabled: true, ProjectID: project.ID, ClientType: domain.ClientTypeClaude, ProviderID: providers[0].ID, Position: 1},
+		{IsEnabled: false, ProjectID: project.ID, ClientType: domain.ClientTypeClaude, ProviderID: providers[2].ID, Position: 3},
+		{IsEnabled: true, ProjectID: 0, ClientType: domain.ClientTypeClaude, ProviderID: providers[2].ID, Position: 1},
+		{IsEnabled: true, ProjectID: project.ID, ClientType: domain.ClientTypeOpenAI, ProviderID: providers[2].ID, Position: 1},
+	}
+	for _, r := range routes {
+		enabled := r.IsEnabled
+		if err := routeRepo.Create(r); err != nil {
+			t.Fatalf("create route: %v", err)
+		}
+		// 创建时 IsEnabled=false 会被数据库默认值覆盖
+		if !enabled {
+			r.IsEnabled = false
+			if err := routeRepo.Update(r); err != nil {
+				t.Fatalf("disable route: %v", err)
+			}
+		}
+	}
+
+	prober := &fakeRouteProber{models: make(map[uint64]string)}
+	s := &AdminService{providerRepo: providerRepo, routeRepo: routeRepo, projectRepo: projectRepo, routeProber: prober}
+
+	report, err := s.TestProjectRoutes(context.Background(), project.ID, domain.ClientTypeClaude, "")
+	if err != nil {
+		t.Fatalf("TestProjectRoutes failed: %v", err)
+	}
+	if !report.ProjectRoutes || len(report.Results) != 2 {
+		t.Fatalf("report = %+v, want the project's 2 enabled claude routes", report)
+	}
+	// 按路由位置排序
+	if report.Results[0].RouteID != routes[1].ID || report.Results[1].RouteID != routes[0].ID {
+		t.Errorf("results not ordered by position: %d, %d", report.Results[0].RouteID, report.Results[1].RouteID)
+	}
+	if report.Results[0].ProviderName != "a" || prober.models[routes[1].ID] != "claude-haiku-4-5" {
+		t.Errorf("result = %+v, want provider a probed with its first concrete model", report.Results[0])
+	}
+	for _, r := range report.Results {
+		if r.Success != (r.Status == "COMPLETED") || r.ProxyRequestID != r.RouteID*100 {
+			t.Errorf("result %+v does not reflect the probe's request record", r)
+		}
+	}
+	if report.Succeeded+report.Failed != 2 {
+		t.Errorf("succeeded %d + failed %d, want 2", report.Succeeded, report.Failed)
+	}
+
+	// 项目未启用自定义路由的 ClientType 使用全局路由
+	report, err = s.TestProjectRoutes(context.Background(), project.ID, domain.ClientTypeOpenAI, "hi")
+	if err != nil {
+		t.Fatalf("TestProjectRoutes(openai) failed: %v", err)
+	}
+	if report.ProjectRoutes || len(report.Results) != 0 {
+		t.Errorf("openai report = %+v, want no global openai routes", report)
+	}
+
+	if _, err := s.TestProjectRoutes(context.Background(), project.ID, "unknown", ""); !errors.Is(err, domain.ErrInvalidInput) {
+		t.Errorf("unknown client type err = %v, want ErrInvalidInput", err)
+	}
+	if _, err := s.TestProjectRoutes(context.Background(), project.ID+100, domain.ClientTypeClaude, ""); !errors.Is(err, domain.ErrNotFound) {
+		t.Errorf("missing project err = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/cooldown"
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/pricing"
+	"github.com/awsl-project/maxx/internal/stats"
+)
+
+// settingValidators 各设置项取值的校验规则（与 domain.SettingKey* 注释中的取值说明一致），
+// 未列出的设置项不校验。空值表示使用默认值，总是合法
+var settingValidators = map[string]func(string) error{
+	domain.SettingKeyProxyPort:                     intRange(1, 65535),
+	domain.SettingKeyRequestRetentionHours:         intRange(0, -1),
+	domain.SettingKeyRequestDetailRetentionSeconds: intRange(-1, -1),
+	domain.SettingKeyTimezone:                      validateTimezone,
+	domain.SettingKeyQuotaRefreshInterval:          intRange(0, -1),
+	domain.SettingKeyAutoSortAntigravity:           validateBool,
+	domain.SettingKeyAutoSortCodex:                 validateBool,
+	domain.SettingKeyEnablePprof:                   validateBool,
+	domain.SettingKeyPprofPort:                     intRange(1, 65535),
+	domain.SettingKeyModelNormalizationRules: func(v string) error {
+		_, err := pricing.ParseNormalizationRules(v)
+		return err
+	},
+	domain.SettingKeyTrustedProxies:              validateIPList,
+	domain.SettingKeyIPDenyList:                  validateIPList,
+	domain.SettingKeyStreamBufferMaxBytes:        intRange(0, -1),
+	domain.SettingKeyStreamStallTimeoutSeconds:   intRange(0, -1),
+	domain.SettingKeyCooldownBroadcastIntervalMs: intRange(0, -1),
+	domain.SettingKeyCooldownFailureWeights: func(v string) error {
+		_, err := cooldown.ParseFailureWeights(v)
+		return err
+	},
+	domain.SettingKeyCooldownEventRetentionDays: func(v string) error {
+		_, err := cooldown.ParseEventRetentionDays(v)
+		return err
+	},
+	domain.SettingKeyAttemptBroadcastMaxQPS:   intRange(0, -1),
+	domain.SettingKeyStartupProviderSelfTest:  validateBool,
+	domain.SettingKeyStartupSelfTestStrict:    validateBool,
+	domain.SettingKeyCostAnomalyEnabled:       validateBool,
+	domain.SettingKeyCostAnomalyWindowHours:   intRange(1, -1),
+	domain.SettingKeyCostAnomalyBaselineHours: intRange(1, -1),
+	domain.SettingKeyCostAnomalyThreshold:     validatePositiveFloat,
+	domain.SettingKeyCostAnomalyMinRequests:   intRange(0, -1),
+	domain.SettingKeyLogMaxSizeMB:             intRange(0, -1),
+	domain.SettingKeyLogMaxFiles:              intRange(0, -1),
+	domain.SettingKeyLogMaxAgeDays:            intRange(0, -1),
+	domain.SettingKeyProviderRateLimitMode:    oneOf("skip", "queue"),
+	domain.SettingKeyAPITokenConcurrencyMode:  oneOf("reject", "queue"),
+	domain.SettingKeyRoutingTraceEnabled:      validateBool,
+	domain.SettingKeyDailyDigestEnabled:       validateBool,
+	domain.SettingKeyDailyDigestTime:          validateClockTime,
+	domain.SettingKeyDailyDigestWebhookURL:    validateHTTPURL,
+	domain.SettingKeyDailyDigestSMTP:          validateDigestSMTP,
+	domain.SettingKeyDailyDigestMetrics:       validateDigestMetrics,
+	domain.SettingKeyCostDisplayDecimals:      intRange(0, 9),
+	domain.SettingKeyStreamDedupEnabled:       validateBool,
+	domain.SettingKeyEnforceContentType:       validateBool,
+	domain.SettingKeyModelExperiments: func(v string) error {
+		_, err := domain.ParseModelExperiments(v)
+		return err
+	},
+	domain.SettingKeyDisconnectGraceSeconds:     intRange(0, -1),
+	domain.SettingKeyCostReconcileEnabled:       validateBool,
+	domain.SettingKeyCostReconcileFix:           validateBool,
+	domain.SettingKeyCostReconcileLookbackHours: intRange(1, -1),
+	domain.SettingKeyMaxRoutesAttempted:         intRange(0, -1),
+	domain.SettingKeyProjectSlugGraceDays:       intRange(0, -1),
+	domain.SettingKeyRetryAfterJitterPercent:    intRange(0, 100),
+	domain.SettingKeyRetryAfterMaxWaitSeconds:   intRange(0, -1),
+	domain.SettingKeyProviderHealthWeights: func(v string) error {
+		_, err := stats.ParseHealthWeights(v)
+		return err
+	},
+	domain.SettingKeyProviderHealthLatencySLOMs: intRange(1, -1),
+	domain.SettingKeyRequestDetailArchiveDir:    validateAbsPath,
+	domain.SettingKeyRequestDetailArchiveDays:   intRange(0, -1),
+	domain.SettingKeyCostVolumeDiscounts: func(v string) error {
+		_, err := pricing.ParseVolumeDiscountTiers(v)
+		return err
+	},
+}
+
+// ValidateSetting 校验设置项的取值，错误包装 domain.ErrInvalidInput
+func ValidateSetting(key, value string) error {
+	validate, ok := settingValidators[key]
+	if !ok || value == "" {
+		return nil
+	}
+	if err := validate(value); err != nil {
+		return fmt.Errorf("%w: invalid value for %s: %v", domain.ErrInvalidInput, key, err)
+	}
+	return nil
+}
+
+func validateBool(v string) error {
+	if v != "true" && v != "false" {
+		return fmt.Errorf("must be \"true\" or \"false\"")
+	}
+	return nil
+}
+
+// intRange 整数取值范围，max 为 -1 表示无上限
+func intRange(min, max int) func(string) error {
+	return func(v string) error {
+		n, err := strconv.Atoi(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("must be an integer")
+		}
+		if n < min || (max >= 0 && n > max) {
+			if max < 0 {
+				return fmt.Errorf("must be >= %d", min)
+			}
+			return fmt.Errorf("must be between %d and %d", min, max)
+		}
+		return nil
+	}
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(v string) error {
+		for _, allowed := range values {
+			if v == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+	}
+}
+
+func validatePositiveFloat(v string) error {
+	f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+	if err != nil || f <= 0 {
+		return fmt.Errorf("must be a positive number")
+	}
+	return nil
+}
+
+func validateTimezone(v string) error {
+	_, err := time.LoadLocation(v)
+	return err
+}
+
+func validateClockTime(v string) error {
+	if _, err := time.Parse("15:04", strings.TrimSpace(v)); err != nil {
+		return fmt.Errorf("must be HH:MM")
+	}
+	return nil
+}
+
+func validateHTTPURL(v string) error {
+	u, err := url.Parse(v)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("must be an http(s) URL")
+	}
+	return nil
+}
+
+// validateAbsPath 要求绝对路径
+func validateAbsPath(v string) error {
+	if !filepath.IsAbs(v) {
+		return fmt.Errorf("must be an absolute path")
+	}
+	return nil
+}
+
+// validateIPList 逗号分隔的 IP 或 CIDR
+func validateIPList(v string) error {
+	for _, item := range strings.Split(v, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if strings.Contains(item, "/") {
+			if _, _, err := net.ParseCIDR(item); err != nil {
+				return fmt.Errorf("invalid CIDR %q", item)
+			}
+		} else if net.ParseIP(item) == nil {
+			return fmt.Errorf("invalid IP %q", item)
+		}
+	}
+	return nil
+}
+
+func validateDigestSMTP(v string) error {
+	var cfg DailyDigestSMTPConfig
+	if err := json.Unmarshal([]byte(v), &cfg); err != nil {
+		return err
+	}
+	if cfg.Host == "" || cfg.From == "" || len(cfg.To) == 0 {
+		return fmt.Errorf("host, from and to are required")
+	}
+	return nil
+}
+
+func validateDigestMetrics(v string) error {
+	allowed := oneOf(DigestMetricRequests, DigestMetricTokens, DigestMetricCost, DigestMetricModels, DigestMetricProviders)
+	for _, m := range strings.Split(v, ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			if err := allowed(m); err != nil {
+				return fmt.Errorf("unknown metric %q", m)
+			}
+		}
+	}
+	return nil
+}
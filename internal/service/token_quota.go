@@ -0,0 +1,170 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+)
+
+// tokenQuotaCacheTTL 周期用量缓存时间：期间内的请求不重新聚合用量，
+// 因此配额可能被短暂超出（最多为该时间内的用量）
+const tokenQuotaCacheTTL = 10 * time.Second
+
+// TokenQuotaExceeded 描述被触发的配额
+type TokenQuotaExceeded struct {
+	Quota   domain.TokenModelQuota
+	Used    uint64    // 已用请求数或 Token 数（对应触发的上限）
+	Limit   uint64    // 触发的上限
+	Unit    string    // "requests" / "tokens"
+	ResetAt time.Time // 当前周期结束时间
+}
+
+func (e *TokenQuotaExceeded) Error() string {
+	model := e.Quota.Model
+	if model == "" {
+		model = "all models"
+	}
+	return fmt.Sprintf("API token %s quota exceeded for %s (%d/%d %s), resets at %s",
+		e.Quota.Period, model, e.Used, e.Limit, e.Unit, e.ResetAt.Format(time.RFC3339))
+}
+
+type tokenQuotaUsage struct {
+	byModel   map[string]*domain.UsageStatsSummary // 按请求模型的请求数与 Token 数
+	fetchedAt time.Time
+}
+
+type tokenQuotaKey struct {
+	tokenID     uint64
+	period      domain.QuotaPeriod
+	periodStart int64
+}
+
+// TokenQuotaService 按 proxy_requests 检查 API Token 的模型用量配额。
+// 配额按客户端请求的模型匹配，因此用量也按请求模型统计（usage_stats 按上游尝试和
+// 响应模型统计：重试会被重复计入，模型映射后的请求也匹配不到配额）。
+// 每个 Token 每个周期的按模型用量短暂缓存，避免每个请求都聚合统计
+type TokenQuotaService struct {
+	proxyRequestRepo repository.ProxyRequestRepository
+	settingRepo      repository.SystemSettingRepository
+
+	mu    sync.Mutex
+	cache map[tokenQuotaKey]*tokenQuotaUsage
+	now   func() time.Time
+}
+
+// NewTokenQuotaService creates a new token quota service
+func NewTokenQuotaService(proxyRequestRepo repository.ProxyRequestRepository, settingRepo repository.SystemSettingRepository) *TokenQuotaService {
+	return &TokenQuotaService{
+		proxyRequestRepo: proxyRequestRepo,
+		settingRepo:      settingRepo,
+		cache:            make(map[tokenQuotaKey]*tokenQuotaUsage),
+		now:              time.Now,
+	}
+}
+
+// Check 检查 token 对 model 的请求是否超出配额，超出时返回 *TokenQuotaExceeded
+func (s *TokenQuotaService) Check(token *domain.APIToken, model string) (*TokenQuotaExceeded, error) {
+	if token == nil || len(token.ModelQuotas) == 0 {
+		return nil, nil
+	}
+	now := s.now().In(s.timezone())
+	for _, quota := range token.ModelQuotas {
+		if quota.MaxRequests == 0 && quota.MaxTokens == 0 {
+			continue
+		}
+		if quota.Model != "" && !domain.MatchWildcard(quota.Model, model) {
+			continue
+		}
+		start, reset := quotaPeriodBounds(now, quota.Period)
+		usage, err := s.periodUsage(token.ID, quota.Period, start)
+		if err != nil {
+			return nil, err
+		}
+
+		var requests, tokens uint64
+		for m, summary := range usage {
+			if quota.Model != "" && !domain.MatchWildcard(quota.Model, m) {
+				continue
+			}
+			requests += summary.TotalRequests
+			tokens += summary.TotalInputTokens + summary.TotalOutputTokens
+		}
+		if quota.MaxRequests > 0 && requests >= quota.MaxRequests {
+			return &TokenQuotaExceeded{Quota: quota, Used: requests, Limit: quota.MaxRequests, Unit: "requests", ResetAt: reset}, nil
+		}
+		if quota.MaxTokens > 0 && tokens >= quota.MaxTokens {
+			return &TokenQuotaExceeded{Quota: quota, Used: tokens, Limit: quota.MaxTokens, Unit: "tokens", ResetAt: reset}, nil
+		}
+	}
+	return nil, nil
+}
+
+// periodUsage 返回 token 自 start 起的按模型用量（带缓存）
+func (s *TokenQuotaService) periodUsage(tokenID uint64, period domain.QuotaPeriod, start time.Time) (map[string]*domain.UsageStatsSummary, error) {
+	key := tokenQuotaKey{tokenID: tokenID, period: period, periodStart: start.Unix()}
+	now := s.now()
+
+	s.mu.Lock()
+	if cached, ok := s.cache[key]; ok && now.Sub(cached.fetchedAt) < tokenQuotaCacheTTL {
+		s.mu.Unlock()
+		return cached.byModel, nil
+	}
+	s.mu.Unlock()
+
+	byModel, err := s.proxyRequestRepo.SummaryByRequestModelSince(tokenID, start)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 丢弃已过期的条目（包括上个周期的）
+	for k, v := range s.cache {
+		if now.Sub(v.fetchedAt) >= tokenQuotaCacheTTL {
+			delete(s.cache, k)
+		}
+	}
+	s.cache[key] = &tokenQuotaUsage{byModel: byModel, fetchedAt: now}
+	return byModel, nil
+}
+
+func (s *TokenQuotaService) timezone() *time.Location {
+	val := ""
+	if s.settingRepo != nil {
+		val, _ = s.settingRepo.Get(domain.SettingKeyTimezone)
+	}
+	if val == "" {
+		val = "Asia/Shanghai" // 默认时区
+	}
+	loc, err := time.LoadLocation(val)
+	if err != nil {
+		return time.FixedZone("UTC+8", 8*60*60)
+	}
+	return loc
+}
+
+// quotaPeriodBounds 返回 now 所在周期的开始和结束时间（now 的时区即配置的时区）
+func quotaPeriodBounds(now time.Time, period domain.QuotaPeriod) (start, end time.Time) {
+	if period == domain.QuotaPeriodMonth {
+		start = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+		return start, start.AddDate(0, 1, 0)
+	}
+	start = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return start, start.AddDate(0, 0, 1)
+}
+
+// ValidateTokenModelQuotas rejects quotas with an unknown period or no limit
+func ValidateTokenModelQuotas(quotas []domain.TokenModelQuota) error {
+	for i, q := range quotas {
+		if q.Period != domain.QuotaPeriodDay && q.Period != domain.QuotaPeriodMonth {
+			return fmt.Errorf("%w: modelQuotas[%d]: period must be \"day\" or \"month\"", domain.ErrInvalidInput, i)
+		}
+		if q.MaxRequests == 0 && q.MaxTokens == 0 {
+			return fmt.Errorf("%w: modelQuotas[%d]: set maxRequests or maxTokens", domain.ErrInvalidInput, i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/awsl-project/maxx/internal/repository"
+	"github.com/awsl-project/maxx/internal/repository/sqlite"
+)
+
+// quotaRequestRepo 返回固定的按请求模型用量并记录查询
+type quotaRequestRepo struct {
+	repository.ProxyRequestRepository
+	byModel map[string]*domain.UsageStatsSummary
+	tokens  []uint64
+	since   []time.Time
+}
+
+func (r *quotaRequestRepo) SummaryByRequestModelSince(apiTokenID uint64, since time.Time) (map[string]*domain.UsageStatsSummary, error) {
+	r.tokens = append(r.tokens, apiTokenID)
+	r.since = append(r.since, since)
+	return r.byModel, nil
+}
+
+func TestTokenQuotaCheck(t *testing.T) {
+	repo := &quotaRequestRepo{byModel: map[string]*domain.UsageStatsSummary{
+		"claude-sonnet-4": {TotalRequests: 8, TotalInputTokens: 600, TotalOutputTokens: 300},
+		"claude-haiku-4":  {TotalRequests: 3, TotalInputTokens: 100, TotalOutputTokens: 50},
+		"gpt-5":           {TotalRequests: 50, TotalInputTokens: 9000, TotalOutputTokens: 9000},
+	}}
+	loc := time.FixedZone("UTC+8", 8*60*60)
+	now := time.Date(2026, 3, 15, 23, 30, 0, 0, loc)
+	s := NewTokenQuotaService(repo, nil)
+	s.now = func() time.Time { return now }
+
+	token := &domain.APIToken{ID: 7, ModelQuotas: []domain.TokenModelQuota{
+		{Model: "claude-*", Period: domain.QuotaPeriodDay, MaxRequests: 20, MaxTokens: 1000},
+		{Model: "gpt-5", Period: domain.QuotaPeriodMonth, MaxRequests: 50},
+	}}
+
+	// claude-* 合计 11 次请求、1050 tokens，超出 token 上限
+	exceeded, err := s.Check(token, "claude-haiku-4")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if exceeded == nil || exceeded.Unit != "tokens" || exceeded.Used != 1050 || exceeded.Limit != 1000 {
+		t.Fatalf("exceeded = %+v, want token quota 1050/1000", exceeded)
+	}
+	if want := time.Date(2026, 3, 16, 0, 0, 0, 0, loc); !exceeded.ResetAt.Equal(want) {
+		t.Errorf("ResetAt = %v, want %v", exceeded.ResetAt, want)
+	}
+	if repo.tokens[0] != 7 || !repo.since[0].Equal(time.Date(2026, 3, 15, 0, 0, 0, 0, loc)) {
+		t.Errorf("query = token %d since %v", repo.tokens[0], repo.since[0])
+	}
+
+	exceeded, _ = s.Check(token, "gpt-5")
+	if exceeded == nil || exceeded.Unit != "requests" || !exceeded.ResetAt.Equal(time.Date(2026, 4, 1, 0, 0, 0, 0, loc)) {
+		t.Fatalf("exceeded = %+v, want monthly request quota", exceeded)
+	}
+
+	// 未被任何配额匹配的模型不限制
+	if exceeded, _ = s.Check(token, "gemini-2.5-pro"); exceeded != nil {
+		t.Errorf("unmatched model exceeded = %+v", exceeded)
+	}
+
+	// 缓存期内不重新聚合，过期后重新查询
+	queries := len(repo.since)
+	repo.byModel = map[string]*domain.UsageStatsSummary{}
+	if exceeded, _ = s.Check(token, "claude-sonnet-4"); exceeded == nil || len(repo.since) != queries {
+		t.Errorf("cached check: exceeded = %+v, queries %d -> %d", exceeded, queries, len(repo.since))
+	}
+	now = now.Add(tokenQuotaCacheTTL)
+	if exceeded, _ = s.Check(token, "claude-sonnet-4"); exceeded != nil {
+		t.Errorf("after cache expiry exceeded = %+v, want nil", exceeded)
+	}
+}
+
+func TestTokenQuotaCountsClientRequests(t *testing.T) {
+	db, err := sqlite.NewDB(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("NewDB failed: %v", err)
+	}
+	requestRepo := sqlite.NewProxyRequestRepository(db)
+	attemptRepo := sqlite.NewProxyUpstreamAttemptRepository(db)
+
+	// claude-sonnet-4 的请求被路由映射到 glm-4.6，每个请求重试 3 次：
+	// 按请求模型计 2 次请求、600 tokens，而不是按响应模型或按上游尝试计
+	for i := 0; i < 2; i++ {
+		req := &domain.ProxyRequest{APITokenID: 7, RequestModel: "claude-sonnet-4", ResponseModel: "glm-4.6",
+			Status: "COMPLETED", InputTokenCount: 200, OutputTokenCount: 100}
+		if err := requestRepo.Create(req); err != nil {
+			t.Fatalf("create request: %v", err)
+		}
+		for j := 0; j < 3; j++ {
+			if err := attemptRepo.Create(&domain.ProxyUpstreamAttempt{ProxyRequestID: req.ID, Status: "COMPLETED", ResponseModel: "glm-4.6"}); err != nil {
+				t.Fatalf("create attempt: %v", err)
+			}
+		}
+	}
+	s := NewTokenQuotaService(requestRepo, nil)
+
+	token := &domain.APIToken{ID: 7, ModelQuotas: []domain.TokenModelQuota{
+		{Model: "claude-*", Period: domain.QuotaPeriodDay, MaxRequests: 3, MaxTokens: 600},
+	}}
+	exceeded, err := s.Check(token, "claude-sonnet-4")
+	if err != nil {
+		t.Fatalf("Check failed: %v", err)
+	}
+	if exceeded == nil || exceeded.Unit != "tokens" || exceeded.Used != 600 {
+		t.Fatalf("exceeded = %+v, want token quota 600/600", exceeded)
+	}
+
+	token.ModelQuotas[0].MaxTokens = 0
+	s = NewTokenQuotaService(requestRepo, nil)
+	if exceeded, err = s.Check(token, "claude-sonnet-4"); err != nil || exceeded != nil {
+		t.Errorf("Check = %+v, %v; want 2/3 requests allowed", exceeded, err)
+	}
+}
+
+func TestValidateTokenModelQuotas(t *testing.T) {
+	if err := ValidateTokenModelQuotas([]domain.TokenModelQuota{{Period: domain.QuotaPeriodDay, MaxTokens: 1}}); err != nil {
+		t.Errorf("valid quota rejected: %v", err)
+	}
+	if err := ValidateTokenModelQuotas([]domain.TokenModelQuota{{Period: "week", MaxTokens: 1}}); err == nil {
+		t.Error("unknown period accepted")
+	}
+	if err := ValidateTokenModelQuotas([]domain.TokenModelQuota{{Period: domain.QuotaPeriodMonth}}); err == nil {
+		t.Error("quota without limits accepted")
+	}
+}
@@ -0,0 +1,84 @@
+package stats
+
+import (
+	"sort"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+// CostAnomalyConfig configures cost-per-request anomaly detection.
+type CostAnomalyConfig struct {
+	// Threshold is the minimum ratio of recent to baseline average cost per request.
+	Threshold float64
+	// MinRequests is the minimum number of requests required in both windows.
+	MinRequests uint64
+}
+
+// DetectCostAnomalies compares each model's average cost per request in the
+// recent window (TimeBucket >= recentStart) against the baseline window
+// (TimeBucket < recentStart) and returns the models whose ratio reaches the threshold.
+// Stats with an empty model are ignored. Results are sorted by ratio descending.
+func DetectCostAnomalies(stats []*domain.UsageStats, recentStart time.Time, cfg CostAnomalyConfig, now time.Time) []*domain.CostAnomaly {
+	type totals struct {
+		recentReq, recentCost     uint64
+		baselineReq, baselineCost uint64
+	}
+
+	byModel := make(map[string]*totals)
+	for _, s := range stats {
+		if s.Model == "" {
+			continue
+		}
+		t, ok := byModel[s.Model]
+		if !ok {
+			t = &totals{}
+			byModel[s.Model] = t
+		}
+		if s.TimeBucket.Before(recentStart) {
+			t.baselineReq += s.TotalRequests
+			t.baselineCost += s.Cost
+		} else {
+			t.recentReq += s.TotalRequests
+			t.recentCost += s.Cost
+		}
+	}
+
+	minRequests := cfg.MinRequests
+	if minRequests == 0 {
+		minRequests = 1
+	}
+
+	var anomalies []*domain.CostAnomaly
+	for model, t := range byModel {
+		if t.recentReq < minRequests || t.baselineReq < minRequests || t.baselineCost == 0 {
+			continue
+		}
+		recentAvg := t.recentCost / t.recentReq
+		baselineAvg := t.baselineCost / t.baselineReq
+		if baselineAvg == 0 {
+			continue
+		}
+		ratio := float64(recentAvg) / float64(baselineAvg)
+		if ratio < cfg.Threshold {
+			continue
+		}
+		anomalies = append(anomalies, &domain.CostAnomaly{
+			Model:            model,
+			DetectedAt:       now,
+			RecentRequests:   t.recentReq,
+			RecentAvgCost:    recentAvg,
+			BaselineRequests: t.baselineReq,
+			BaselineAvgCost:  baselineAvg,
+			Ratio:            ratio,
+		})
+	}
+
+	sort.Slice(anomalies, func(i, j int) bool {
+		if anomalies[i].Ratio != anomalies[j].Ratio {
+			return anomalies[i].Ratio > anomalies[j].Ratio
+		}
+		return anomalies[i].Model < anomalies[j].Model
+	})
+	return anomalies
+}
@@ -0,0 +1,62 @@
+package stats
+
+import (
+	"testing"
+	"time"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func TestDetectCostAnomalies(t *testing.T) {
+	now := time.Date(2024, 1, 17, 12, 30, 0, 0, time.UTC)
+	recentStart := time.Date(2024, 1, 17, 12, 0, 0, 0, time.UTC)
+	baseline := recentStart.Add(-3 * time.Hour)
+
+	stats := []*domain.UsageStats{
+		// claude: 1000 → 5000 per request (5x)
+		{TimeBucket: baseline, Model: "claude", TotalRequests: 10, Cost: 10_000},
+		{TimeBucket: baseline.Add(time.Hour), Model: "claude", TotalRequests: 10, Cost: 10_000},
+		{TimeBucket: recentStart, Model: "claude", TotalRequests: 10, Cost: 50_000},
+		// gpt: 1000 → 1500 per request (1.5x, below threshold)
+		{TimeBucket: baseline, Model: "gpt", TotalRequests: 10, Cost: 10_000},
+		{TimeBucket: recentStart, Model: "gpt", TotalRequests: 10, Cost: 15_000},
+		// gemini: 3x but too few recent requests
+		{TimeBucket: baseline, Model: "gemini", TotalRequests: 10, Cost: 10_000},
+		{TimeBucket: recentStart, Model: "gemini", TotalRequests: 2, Cost: 6_000},
+		// no baseline
+		{TimeBucket: recentStart, Model: "new-model", TotalRequests: 50, Cost: 500_000},
+		// empty model is ignored
+		{TimeBucket: baseline, Model: "", TotalRequests: 10, Cost: 10},
+		{TimeBucket: recentStart, Model: "", TotalRequests: 10, Cost: 10_000},
+	}
+
+	got := DetectCostAnomalies(stats, recentStart, CostAnomalyConfig{Threshold: 2, MinRequests: 5}, now)
+	if len(got) != 1 {
+		t.Fatalf("expected 1 anomaly, got %d: %+v", len(got), got)
+	}
+
+	a := got[0]
+	if a.Model != "claude" || a.RecentAvgCost != 5000 || a.BaselineAvgCost != 1000 || a.Ratio != 5 {
+		t.Errorf("unexpected anomaly: %+v", a)
+	}
+	if a.RecentRequests != 10 || a.BaselineRequests != 20 || !a.DetectedAt.Equal(now) {
+		t.Errorf("unexpected anomaly counts: %+v", a)
+	}
+}
+
+func TestDetectCostAnomaliesSortedByRatio(t *testing.T) {
+	recentStart := time.Date(2024, 1, 17, 12, 0, 0, 0, time.UTC)
+	baseline := recentStart.Add(-time.Hour)
+
+	stats := []*domain.UsageStats{
+		{TimeBucket: baseline, Model: "a", TotalRequests: 1, Cost: 100},
+		{TimeBucket: recentStart, Model: "a", TotalRequests: 1, Cost: 300},
+		{TimeBucket: baseline, Model: "b", TotalRequests: 1, Cost: 100},
+		{TimeBucket: recentStart, Model: "b", TotalRequests: 1, Cost: 1000},
+	}
+
+	got := DetectCostAnomalies(stats, recentStart, CostAnomalyConfig{Threshold: 2}, recentStart)
+	if len(got) != 2 || got[0].Model != "b" || got[1].Model != "a" {
+		t.Errorf("unexpected order: %+v", got)
+	}
+}
@@ -0,0 +1,111 @@
+package stats
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HealthMinSamples 成功率/延迟分项所需的最少请求数，样本不足时该分项不参与评分
+const HealthMinSamples = 5
+
+// HealthWeights 健康分各分项的权重，只需相对大小，计算时按参与评分的分项归一化
+type HealthWeights struct {
+	SuccessRate float64 `json:"success"`
+	Latency     float64 `json:"latency"`
+	Cooldown    float64 `json:"cooldown"`
+	Quota       float64 `json:"quota"`
+}
+
+// DefaultHealthWeights 默认权重：成功率 40，延迟 20，冷却 20，剩余配额 20
+func DefaultHealthWeights() HealthWeights {
+	return HealthWeights{SuccessRate: 40, Latency: 20, Cooldown: 20, Quota: 20}
+}
+
+// ParseHealthWeights 解析 provider_health_weights 设置，格式如
+// "success=40,latency=20,cooldown=20,quota=20"，未列出的分项使用默认权重，空字符串返回默认值
+func ParseHealthWeights(s string) (HealthWeights, error) {
+	w := DefaultHealthWeights()
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return w, fmt.Errorf("invalid weight %q, expected name=value", item)
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || f < 0 {
+			return w, fmt.Errorf("invalid weight %q, must be a non-negative number", item)
+		}
+		switch strings.TrimSpace(name) {
+		case "success":
+			w.SuccessRate = f
+		case "latency":
+			w.Latency = f
+		case "cooldown":
+			w.Cooldown = f
+		case "quota":
+			w.Quota = f
+		default:
+			return w, fmt.Errorf("unknown weight %q (want success, latency, cooldown or quota)", name)
+		}
+	}
+	if w.SuccessRate+w.Latency+w.Cooldown+w.Quota <= 0 {
+		return w, fmt.Errorf("at least one weight must be positive")
+	}
+	return w, nil
+}
+
+// HealthSignals 计算健康分的输入
+type HealthSignals struct {
+	// 统计窗口内的上游请求
+	Requests           uint64
+	SuccessfulRequests uint64
+	TotalDurationMs    uint64
+
+	// 冷却程度：0 无冷却，1 全局冷却，介于两者之间表示部分 ClientType 冷却
+	Cooldown float64
+
+	// 剩余配额百分比（0-100），QuotaKnown 为 false 表示无配额数据
+	QuotaPercent float64
+	QuotaKnown   bool
+}
+
+// HealthScore 计算 0-100 的健康分：
+//
+//	success  = 成功请求数 / 请求数
+//	latency  = 平均耗时 <= SLO 时为 1，否则为 SLO / 平均耗时
+//	cooldown = 1 - 冷却程度
+//	quota    = 剩余配额 / 100
+//	score    = 100 * Σ(权重 × 分项) / Σ(参与评分分项的权重)
+//
+// 请求数少于 HealthMinSamples 时成功率与延迟不参与评分，无配额数据时配额不参与评分，
+// 因此新 Provider 不会因缺少数据被判定为不健康
+func HealthScore(s HealthSignals, w HealthWeights, latencySLOMs float64) float64 {
+	var sum, weights float64
+	add := func(weight, value float64) {
+		sum += weight * min(max(value, 0), 1)
+		weights += weight
+	}
+	if s.Requests >= HealthMinSamples {
+		add(w.SuccessRate, float64(s.SuccessfulRequests)/float64(s.Requests))
+		if latencySLOMs > 0 {
+			avg := float64(s.TotalDurationMs) / float64(s.Requests)
+			latency := 1.0
+			if avg > latencySLOMs {
+				latency = latencySLOMs / avg
+			}
+			add(w.Latency, latency)
+		}
+	}
+	add(w.Cooldown, 1-s.Cooldown)
+	if s.QuotaKnown {
+		add(w.Quota, s.QuotaPercent/100)
+	}
+	if weights <= 0 {
+		return 100
+	}
+	return 100 * sum / weights
+}
@@ -0,0 +1,43 @@
+package stats
+
+import "testing"
+
+func TestHealthScore(t *testing.T) {
+	w := DefaultHealthWeights()
+	tests := []struct {
+		name    string
+		signals HealthSignals
+		want    float64
+	}{
+		{"no data", HealthSignals{}, 100},
+		{"too few samples ignore success and latency", HealthSignals{Requests: 4, SuccessfulRequests: 0}, 100},
+		{"all good", HealthSignals{Requests: 10, SuccessfulRequests: 10, TotalDurationMs: 1000, QuotaPercent: 100, QuotaKnown: true}, 100},
+		{"half failing", HealthSignals{Requests: 10, SuccessfulRequests: 5, TotalDurationMs: 1000}, 75},
+		// 平均 60s，SLO 30s：延迟分项 0.5
+		{"slow", HealthSignals{Requests: 10, SuccessfulRequests: 10, TotalDurationMs: 600000}, 87.5},
+		{"global cooldown, quota exhausted", HealthSignals{Cooldown: 1, QuotaPercent: 0, QuotaKnown: true}, 0},
+		{"partial cooldown", HealthSignals{Cooldown: 0.5}, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := HealthScore(tt.signals, w, 30000); got != tt.want {
+				t.Errorf("HealthScore = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseHealthWeights(t *testing.T) {
+	w, err := ParseHealthWeights("success=60, quota=0")
+	if err != nil {
+		t.Fatalf("ParseHealthWeights failed: %v", err)
+	}
+	if w.SuccessRate != 60 || w.Latency != 20 || w.Cooldown != 20 || w.Quota != 0 {
+		t.Errorf("weights = %+v", w)
+	}
+	for _, bad := range []string{"speed=1", "success", "success=-1", "success=0,latency=0,cooldown=0,quota=0"} {
+		if _, err := ParseHealthWeights(bad); err == nil {
+			t.Errorf("ParseHealthWeights(%q) accepted", bad)
+		}
+	}
+}
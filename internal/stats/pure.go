@@ -12,22 +12,24 @@ import (
 // AttemptRecord represents a single upstream attempt record for aggregation.
 // This is a simplified representation of the data needed for minute-level aggregation.
 type AttemptRecord struct {
-	EndTime      time.Time
-	RouteID      uint64
-	ProviderID   uint64
-	ProjectID    uint64
-	APITokenID   uint64
-	ClientType   string
-	Model        string // response_model
-	IsSuccessful bool
-	IsFailed     bool
-	DurationMs   uint64
-	TTFTMs       uint64 // Time To First Token (milliseconds)
-	InputTokens  uint64
-	OutputTokens uint64
-	CacheRead    uint64
-	CacheWrite   uint64
-	Cost         uint64
+	EndTime         time.Time
+	RouteID         uint64
+	ProviderID      uint64
+	ProjectID       uint64
+	APITokenID      uint64
+	ClientType      string
+	Model           string // response_model
+	IsSuccessful    bool
+	IsFailed        bool
+	DurationMs      uint64
+	TTFTMs          uint64 // Time To First Token (milliseconds)
+	InputTokens     uint64
+	OutputTokens    uint64
+	CacheRead       uint64
+	CacheWrite      uint64
+	ReasoningTokens uint64
+	Cost            uint64
+	OutputTPS       float64 // 流式输出速度（tokens/s），0 表示未测量
 }
 
 // TruncateToGranularity truncates a time to the start of its time bucket
@@ -88,6 +90,12 @@ func AggregateAttempts(records []AttemptRecord, loc *time.Location) []*domain.Us
 		if r.IsFailed {
 			failed = 1
 		}
+		// 只有测得 TPS 的 attempt 计入输出速度，流式阶段耗时 = 总耗时 - 首字时长
+		var tpsTokens, tpsDurationMs uint64
+		if r.OutputTPS > 0 && r.DurationMs > r.TTFTMs {
+			tpsTokens = r.OutputTokens
+			tpsDurationMs = r.DurationMs - r.TTFTMs
+		}
 
 		if s, ok := statsMap[key]; ok {
 			s.TotalRequests++
@@ -95,10 +103,13 @@ func AggregateAttempts(records []AttemptRecord, loc *time.Location) []*domain.Us
 			s.FailedRequests += failed
 			s.TotalDurationMs += r.DurationMs
 			s.TotalTTFTMs += r.TTFTMs
+			s.TPSOutputTokens += tpsTokens
+			s.TPSDurationMs += tpsDurationMs
 			s.InputTokens += r.InputTokens
 			s.OutputTokens += r.OutputTokens
 			s.CacheRead += r.CacheRead
 			s.CacheWrite += r.CacheWrite
+			s.ReasoningTokens += r.ReasoningTokens
 			s.Cost += r.Cost
 		} else {
 			statsMap[key] = &domain.UsageStats{
@@ -115,10 +126,13 @@ func AggregateAttempts(records []AttemptRecord, loc *time.Location) []*domain.Us
 				FailedRequests:     failed,
 				TotalDurationMs:    r.DurationMs,
 				TotalTTFTMs:        r.TTFTMs,
+				TPSOutputTokens:    tpsTokens,
+				TPSDurationMs:      tpsDurationMs,
 				InputTokens:        r.InputTokens,
 				OutputTokens:       r.OutputTokens,
 				CacheRead:          r.CacheRead,
 				CacheWrite:         r.CacheWrite,
+				ReasoningTokens:    r.ReasoningTokens,
 				Cost:               r.Cost,
 			}
 		}
@@ -169,10 +183,13 @@ func RollUp(stats []*domain.UsageStats, to domain.Granularity, loc *time.Locatio
 			existing.FailedRequests += s.FailedRequests
 			existing.TotalDurationMs += s.TotalDurationMs
 			existing.TotalTTFTMs += s.TotalTTFTMs
+			existing.TPSOutputTokens += s.TPSOutputTokens
+			existing.TPSDurationMs += s.TPSDurationMs
 			existing.InputTokens += s.InputTokens
 			existing.OutputTokens += s.OutputTokens
 			existing.CacheRead += s.CacheRead
 			existing.CacheWrite += s.CacheWrite
+			existing.ReasoningTokens += s.ReasoningTokens
 			existing.Cost += s.Cost
 		} else {
 			statsMap[key] = &domain.UsageStats{
@@ -189,10 +206,13 @@ func RollUp(stats []*domain.UsageStats, to domain.Granularity, loc *time.Locatio
 				FailedRequests:     s.FailedRequests,
 				TotalDurationMs:    s.TotalDurationMs,
 				TotalTTFTMs:        s.TotalTTFTMs,
+				TPSOutputTokens:    s.TPSOutputTokens,
+				TPSDurationMs:      s.TPSDurationMs,
 				InputTokens:        s.InputTokens,
 				OutputTokens:       s.OutputTokens,
 				CacheRead:          s.CacheRead,
 				CacheWrite:         s.CacheWrite,
+				ReasoningTokens:    s.ReasoningTokens,
 				Cost:               s.Cost,
 			}
 		}
@@ -239,10 +259,13 @@ func MergeStats(statsList ...[]*domain.UsageStats) []*domain.UsageStats {
 				existing.FailedRequests += s.FailedRequests
 				existing.TotalDurationMs += s.TotalDurationMs
 				existing.TotalTTFTMs += s.TotalTTFTMs
+				existing.TPSOutputTokens += s.TPSOutputTokens
+				existing.TPSDurationMs += s.TPSDurationMs
 				existing.InputTokens += s.InputTokens
 				existing.OutputTokens += s.OutputTokens
 				existing.CacheRead += s.CacheRead
 				existing.CacheWrite += s.CacheWrite
+				existing.ReasoningTokens += s.ReasoningTokens
 				existing.Cost += s.Cost
 			} else {
 				// Make a copy to avoid modifying the original
@@ -276,6 +299,24 @@ func SumStats(stats []*domain.UsageStats) (totalReq, successReq, failedReq, inpu
 	return
 }
 
+// AverageTPS returns the aggregate streaming output speed in tokens per second
+// from summed TPSOutputTokens and TPSDurationMs, or 0 when nothing was measured.
+func AverageTPS(outputTokens, durationMs uint64) float64 {
+	if outputTokens == 0 || durationMs == 0 {
+		return 0
+	}
+	return float64(outputTokens) * 1000 / float64(durationMs)
+}
+
+// CacheHitRatio returns the share of prompt tokens served from cache,
+// cacheRead / (inputTokens + cacheRead), or 0 when there were no prompt tokens.
+func CacheHitRatio(inputTokens, cacheRead uint64) float64 {
+	if inputTokens+cacheRead == 0 {
+		return 0
+	}
+	return float64(cacheRead) / float64(inputTokens+cacheRead)
+}
+
 // GroupByProvider groups stats by provider ID and sums them.
 // Returns a map of provider ID to aggregated totals.
 func GroupByProvider(stats []*domain.UsageStats) map[uint64]*domain.ProviderStats {
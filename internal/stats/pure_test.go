@@ -280,6 +280,53 @@ func TestAggregateAttempts_SameMinute(t *testing.T) {
 	}
 }
 
+func TestAggregateAttempts_OutputTPS(t *testing.T) {
+	baseTime := time.Date(2024, 1, 17, 10, 30, 0, 0, time.UTC)
+
+	records := []AttemptRecord{
+		// 流式阶段 2000ms 输出 100 tokens
+		{EndTime: baseTime, ProviderID: 1, Model: "m", IsSuccessful: true, OutputTokens: 100, DurationMs: 3000, TTFTMs: 1000, OutputTPS: 50},
+		// 流式阶段 1000ms 输出 200 tokens
+		{EndTime: baseTime.Add(10 * time.Second), ProviderID: 1, Model: "m", IsSuccessful: true, OutputTokens: 200, DurationMs: 1500, TTFTMs: 500, OutputTPS: 200},
+		// 未测得 TPS（非流式）不计入
+		{EndTime: baseTime.Add(20 * time.Second), ProviderID: 1, Model: "m", IsSuccessful: true, OutputTokens: 500, DurationMs: 4000},
+	}
+
+	result := AggregateAttempts(records, time.UTC)
+	if len(result) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(result))
+	}
+	s := result[0]
+	if s.TPSOutputTokens != 300 || s.TPSDurationMs != 3000 {
+		t.Errorf("TPSOutputTokens/TPSDurationMs = %d/%d, want 300/3000", s.TPSOutputTokens, s.TPSDurationMs)
+	}
+
+	rolled := RollUp(result, domain.GranularityHour, time.UTC)
+	if got := AverageTPS(rolled[0].TPSOutputTokens, rolled[0].TPSDurationMs); got != 100 {
+		t.Errorf("AverageTPS after rollup = %v, want 100", got)
+	}
+	if got := AverageTPS(0, 0); got != 0 {
+		t.Errorf("AverageTPS(0, 0) = %v, want 0", got)
+	}
+}
+
+func TestCacheHitRatio(t *testing.T) {
+	tests := []struct {
+		input, cacheRead uint64
+		want             float64
+	}{
+		{0, 0, 0},
+		{100, 0, 0},
+		{0, 100, 1},
+		{300, 100, 0.25},
+	}
+	for _, tt := range tests {
+		if got := CacheHitRatio(tt.input, tt.cacheRead); got != tt.want {
+			t.Errorf("CacheHitRatio(%d, %d) = %v, want %v", tt.input, tt.cacheRead, got, tt.want)
+		}
+	}
+}
+
 func TestAggregateAttempts_DifferentMinutes(t *testing.T) {
 	baseTime := time.Date(2024, 1, 17, 10, 30, 0, 0, time.UTC)
 
@@ -1,23 +1,24 @@
-package kiro
+package tokenizer
 
 import (
 	"math"
 	"strings"
 
 	"github.com/awsl-project/maxx/internal/converter"
+	"github.com/bytedance/sonic"
 )
 
-// TokenEstimator 本地 token 估算器
+// Estimator 本地 token 估算器，上游未返回 usage 时用于估算 token 数量
 // 匹配 kiro2api/utils/token_estimator.go
-type TokenEstimator struct{}
+type Estimator struct{}
 
-// NewTokenEstimator 创建 token 估算器实例
-func NewTokenEstimator() *TokenEstimator {
-	return &TokenEstimator{}
+// NewEstimator 创建 token 估算器实例
+func NewEstimator() *Estimator {
+	return &Estimator{}
 }
 
 // EstimateInputTokens 估算请求的 input token 数量
-func (e *TokenEstimator) EstimateInputTokens(req *converter.ClaudeRequest) int {
+func (e *Estimator) EstimateInputTokens(req *converter.ClaudeRequest) int {
 	totalTokens := 0
 
 	// 1. 系统提示词
@@ -75,7 +76,7 @@ func (e *TokenEstimator) EstimateInputTokens(req *converter.ClaudeRequest) int {
 
 			// 工具 schema（JSON Schema）
 			if tool.InputSchema != nil {
-				if jsonBytes, err := FastMarshal(tool.InputSchema); err == nil {
+				if jsonBytes, err := sonic.ConfigFastest.Marshal(tool.InputSchema); err == nil {
 					// Schema 编码密度：根据工具数量自适应
 					var schemaCharsPerToken float64
 					if toolCount == 1 {
@@ -123,7 +124,7 @@ func (e *TokenEstimator) EstimateInputTokens(req *converter.ClaudeRequest) int {
 
 // EstimateTextTokens 估算纯文本的 token 数量
 // 匹配 kiro2api/utils/token_estimator.go:EstimateTextTokens
-func (e *TokenEstimator) EstimateTextTokens(text string) int {
+func (e *Estimator) EstimateTextTokens(text string) int {
 	if text == "" {
 		return 0
 	}
@@ -199,7 +200,7 @@ func (e *TokenEstimator) EstimateTextTokens(text string) int {
 }
 
 // estimateToolName 估算工具名称的 token 数量
-func (e *TokenEstimator) estimateToolName(name string) int {
+func (e *Estimator) estimateToolName(name string) int {
 	if name == "" {
 		return 0
 	}
@@ -227,7 +228,7 @@ func (e *TokenEstimator) estimateToolName(name string) int {
 
 // estimateContentBlock 估算单个内容块的 token 数量
 // 匹配 kiro2api/utils/token_estimator.go:estimateContentBlock
-func (e *TokenEstimator) estimateContentBlock(block any) int {
+func (e *Estimator) estimateContentBlock(block any) int {
 	blockMap, ok := block.(map[string]interface{})
 	if !ok {
 		return 10 // 未知格式，保守估算
@@ -275,7 +276,7 @@ func (e *TokenEstimator) estimateContentBlock(block any) int {
 
 	default:
 		// 未知类型：JSON 长度估算
-		if jsonBytes, err := FastMarshal(block); err == nil {
+		if jsonBytes, err := sonic.ConfigFastest.Marshal(block); err == nil {
 			return len(jsonBytes) / 4
 		}
 		return 10
@@ -284,7 +285,7 @@ func (e *TokenEstimator) estimateContentBlock(block any) int {
 
 // EstimateToolUseTokens 精确估算工具调用的 token 数量
 // 匹配 kiro2api/utils/token_estimator.go:EstimateToolUseTokens
-func (e *TokenEstimator) EstimateToolUseTokens(toolName string, toolInput map[string]any) int {
+func (e *Estimator) EstimateToolUseTokens(toolName string, toolInput map[string]any) int {
 	totalTokens := 0
 
 	// 1. JSON 结构字段开销
@@ -307,7 +308,7 @@ func (e *TokenEstimator) EstimateToolUseTokens(toolName string, toolInput map[st
 	// 4. 参数内容（JSON 序列化）
 	// 匹配 kiro2api: 使用标准的 4 字符/token 比率
 	if len(toolInput) > 0 {
-		if jsonBytes, err := FastMarshal(toolInput); err == nil {
+		if jsonBytes, err := sonic.ConfigFastest.Marshal(toolInput); err == nil {
 			inputTokens := len(jsonBytes) / 4
 			totalTokens += inputTokens
 		}
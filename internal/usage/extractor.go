@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/awsl-project/maxx/internal/domain"
+	"github.com/tidwall/gjson"
 )
 
 // Metrics represents extracted usage information from an API response.
@@ -14,6 +15,10 @@ type Metrics struct {
 	InputTokens  uint64 `json:"inputTokens"`
 	OutputTokens uint64 `json:"outputTokens"`
 
+	// Reasoning/thinking tokens. This is a breakdown of OutputTokens (already
+	// included in it), reported separately so it can be priced at its own rate.
+	ReasoningTokens uint64 `json:"reasoningTokens"`
+
 	// Cache metrics
 	CacheCreationCount   uint64 `json:"cacheCreationCount"`   // Total cache write tokens (= Cache5mCreation + Cache1hCreation)
 	CacheReadCount       uint64 `json:"cacheReadCount"`       // Cache read/hit tokens
@@ -48,6 +53,68 @@ func ExtractFromResponse(body string) *Metrics {
 	return nil
 }
 
+// ExtractFromResponseWithMapping extracts usage using a provider-specific field
+// mapping first, falling back to standard extraction when the mapping is not
+// configured or finds nothing. Works for both JSON and SSE bodies.
+func ExtractFromResponseWithMapping(body string, mapping *domain.UsageFieldMapping) *Metrics {
+	if metrics := ExtractWithMapping(body, mapping); metrics != nil {
+		return metrics
+	}
+	return ExtractFromResponse(body)
+}
+
+// ExtractWithMapping extracts usage from the configured field paths only.
+// Returns nil when the mapping is not configured or finds nothing.
+func ExtractWithMapping(body string, mapping *domain.UsageFieldMapping) *Metrics {
+	if body == "" || mapping.IsEmpty() {
+		return nil
+	}
+	if metrics := extractWithMapping(body, mapping); metrics != nil && !metrics.IsEmpty() {
+		return metrics
+	}
+	return nil
+}
+
+// extractWithMapping reads token counts from the configured JSON paths.
+// For SSE bodies each data event is matched and later non-zero values win.
+func extractWithMapping(body string, mapping *domain.UsageFieldMapping) *Metrics {
+	if gjson.Valid(body) {
+		return extractMappedFields(gjson.Parse(body), mapping)
+	}
+
+	var lastMetrics *Metrics
+	for _, line := range strings.Split(body, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		jsonStr := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if jsonStr == "[DONE]" || !gjson.Valid(jsonStr) {
+			continue
+		}
+		if metrics := extractMappedFields(gjson.Parse(jsonStr), mapping); !metrics.IsEmpty() {
+			lastMetrics = mergeMetrics(lastMetrics, metrics)
+		}
+	}
+	return lastMetrics
+}
+
+func extractMappedFields(data gjson.Result, mapping *domain.UsageFieldMapping) *Metrics {
+	get := func(path string) uint64 {
+		if path == "" {
+			return 0
+		}
+		return data.Get(path).Uint()
+	}
+	return &Metrics{
+		InputTokens:        get(mapping.InputTokens),
+		OutputTokens:       get(mapping.OutputTokens),
+		CacheReadCount:     get(mapping.CacheReadTokens),
+		CacheCreationCount: get(mapping.CacheWriteTokens),
+		ReasoningTokens:    get(mapping.ReasoningTokens),
+	}
+}
+
 // extractFromJSON tries to parse usage from a JSON response body.
 func extractFromJSON(body string) *Metrics {
 	var data map[string]interface{}
@@ -87,43 +154,63 @@ func extractFromSSE(body string) *Metrics {
 		}
 
 		// Try to extract metrics from this event
+		// Claude SSE splits usage across events: message_start carries input/cache tokens,
+		// message_delta carries the final (cumulative) output tokens, and for tool_use
+		// responses message_delta may omit input_tokens. Merge instead of replacing so
+		// neither side gets lost.
 		metrics := extractUsageFromMap(data)
 		if metrics != nil && !metrics.IsEmpty() {
-			lastMetrics = metrics
-		}
-
-		// Claude SSE: Check for message_delta type which contains final usage
-		if eventType, ok := data["type"].(string); ok {
-			if eventType == "message_delta" {
-				if usage, ok := data["usage"].(map[string]interface{}); ok {
-					m := extractClaudeUsage(usage)
-					if m != nil && !m.IsEmpty() {
-						lastMetrics = m
-					}
-				}
-			}
-			// Codex SSE: Check for response.completed type which contains final usage
-			if eventType == "response.completed" {
-				if response, ok := data["response"].(map[string]interface{}); ok {
-					if usage, ok := response["usage"].(map[string]interface{}); ok {
-						m := extractOpenAIUsage(usage)
-						if m != nil && !m.IsEmpty() {
-							lastMetrics = m
-						}
-					}
-				}
-			}
+			lastMetrics = mergeMetrics(lastMetrics, metrics)
 		}
 	}
 
 	return lastMetrics
 }
 
+// mergeMetrics overlays non-zero fields of src onto dst.
+// Usage values in SSE events are cumulative, so later non-zero values win.
+func mergeMetrics(dst, src *Metrics) *Metrics {
+	if dst == nil {
+		return src
+	}
+	if src == nil {
+		return dst
+	}
+	if src.InputTokens > 0 {
+		dst.InputTokens = src.InputTokens
+	}
+	if src.OutputTokens > 0 {
+		dst.OutputTokens = src.OutputTokens
+	}
+	if src.ReasoningTokens > 0 {
+		dst.ReasoningTokens = src.ReasoningTokens
+	}
+	if src.CacheCreationCount > 0 {
+		dst.CacheCreationCount = src.CacheCreationCount
+	}
+	if src.CacheReadCount > 0 {
+		dst.CacheReadCount = src.CacheReadCount
+	}
+	if src.Cache5mCreationCount > 0 {
+		dst.Cache5mCreationCount = src.Cache5mCreationCount
+	}
+	if src.Cache1hCreationCount > 0 {
+		dst.Cache1hCreationCount = src.Cache1hCreationCount
+	}
+	return dst
+}
+
 // extractUsageFromMap extracts usage metrics from a parsed JSON map.
 // Handles multiple API formats.
 func extractUsageFromMap(data map[string]interface{}) *Metrics {
-	// Try Claude/Anthropic format: { "usage": { ... } }
+	// Root level usage: { "usage": { ... } }
+	// OpenAI Chat Completions (including tool_calls responses and the final
+	// stream_options.include_usage chunk) uses prompt_tokens/completion_tokens,
+	// Claude/Anthropic uses input_tokens/output_tokens.
 	if usage, ok := data["usage"].(map[string]interface{}); ok {
+		if isOpenAIUsage(usage) {
+			return extractOpenAIUsage(usage)
+		}
 		return extractClaudeUsage(usage)
 	}
 
@@ -152,21 +239,25 @@ func extractUsageFromMap(data map[string]interface{}) *Metrics {
 		}
 	}
 
-	// Try OpenAI choices format for some responses
-	if choices, ok := data["choices"].([]interface{}); ok && len(choices) > 0 {
-		// Usage might be at root level alongside choices
-		if usage, ok := data["usage"].(map[string]interface{}); ok {
-			return extractOpenAIUsage(usage)
+	return nil
+}
+
+// isOpenAIUsage reports whether a usage object uses OpenAI field names
+func isOpenAIUsage(usage map[string]interface{}) bool {
+	for _, key := range []string{"prompt_tokens", "completion_tokens", "prompt_tokens_details", "input_tokens_details", "output_tokens_details"} {
+		if _, ok := usage[key]; ok {
+			return true
 		}
 	}
-
-	return nil
+	return false
 }
 
 // extractClaudeUsage extracts metrics from Claude/Anthropic usage format.
 // Example: { "input_tokens": 100, "output_tokens": 50, "cache_read_input_tokens": 20,
 //            "cache_creation_input_tokens": 30, "cache_creation_5m_input_tokens": 10,
-//            "cache_creation_1h_input_tokens": 20 }
+//            "cache_creation_1h_input_tokens": 20, "thinking_tokens": 30 }
+// Extended-thinking tokens are billed as output and included in output_tokens;
+// when reported separately they are captured as ReasoningTokens.
 func extractClaudeUsage(usage map[string]interface{}) *Metrics {
 	metrics := &Metrics{}
 
@@ -200,6 +291,14 @@ func extractClaudeUsage(usage map[string]interface{}) *Metrics {
 		metrics.CacheReadCount = uint64(v)
 	}
 
+	// Extended thinking tokens (subset of output_tokens)
+	for _, key := range []string{"thinking_tokens", "reasoning_tokens"} {
+		if v, ok := usage[key].(float64); ok {
+			metrics.ReasoningTokens = uint64(v)
+			break
+		}
+	}
+
 	return metrics
 }
 
@@ -249,6 +348,16 @@ func extractOpenAIUsage(usage map[string]interface{}) *Metrics {
 		metrics.CacheReadCount = uint64(v)
 	}
 
+	// Reasoning tokens (subset of completion/output tokens):
+	// completion_tokens_details (Chat Completions) / output_tokens_details (Response API)
+	for _, key := range []string{"completion_tokens_details", "output_tokens_details"} {
+		if details, ok := usage[key].(map[string]interface{}); ok {
+			if v, ok := details["reasoning_tokens"].(float64); ok {
+				metrics.ReasoningTokens = uint64(v)
+			}
+		}
+	}
+
 	return metrics
 }
 
@@ -281,9 +390,11 @@ func extractGeminiUsage(usage map[string]interface{}) *Metrics {
 		metrics.OutputTokens = uint64(v)
 	}
 
-	// Gemini thinking tokens (add to output)
+	// Gemini thinking tokens are reported outside candidatesTokenCount:
+	// add to output and record as reasoning
 	if v, ok := usage["thoughtsTokenCount"].(float64); ok {
 		metrics.OutputTokens += uint64(v)
+		metrics.ReasoningTokens = uint64(v)
 	}
 
 	return metrics
@@ -0,0 +1,208 @@
+package usage
+
+import (
+	"testing"
+
+	"github.com/awsl-project/maxx/internal/domain"
+)
+
+func assertMetrics(t *testing.T, got *Metrics, input, output, cacheRead, cacheWrite uint64) {
+	t.Helper()
+	if got == nil {
+		t.Fatal("expected metrics, got nil")
+	}
+	if got.InputTokens != input {
+		t.Errorf("InputTokens = %d, want %d", got.InputTokens, input)
+	}
+	if got.OutputTokens != output {
+		t.Errorf("OutputTokens = %d, want %d", got.OutputTokens, output)
+	}
+	if got.CacheReadCount != cacheRead {
+		t.Errorf("CacheReadCount = %d, want %d", got.CacheReadCount, cacheRead)
+	}
+	if got.CacheCreationCount != cacheWrite {
+		t.Errorf("CacheCreationCount = %d, want %d", got.CacheCreationCount, cacheWrite)
+	}
+}
+
+func TestExtractOpenAIToolCallsJSON(t *testing.T) {
+	body := `{
+		"id": "chatcmpl-1",
+		"object": "chat.completion",
+		"model": "gpt-4o",
+		"choices": [{
+			"index": 0,
+			"message": {
+				"role": "assistant",
+				"content": null,
+				"tool_calls": [
+					{"id": "call_1", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}},
+					{"id": "call_2", "type": "function", "function": {"name": "get_weather", "arguments": "{\"city\":\"Tokyo\"}"}}
+				]
+			},
+			"finish_reason": "tool_calls"
+		}],
+		"usage": {
+			"prompt_tokens": 120,
+			"completion_tokens": 45,
+			"total_tokens": 165,
+			"prompt_tokens_details": {"cached_tokens": 64},
+			"completion_tokens_details": {"reasoning_tokens": 0}
+		}
+	}`
+
+	assertMetrics(t, ExtractFromResponse(body), 120, 45, 64, 0)
+}
+
+func TestExtractOpenAIToolCallsStream(t *testing.T) {
+	body := `data: {"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"role":"assistant","tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"get_weather","arguments":""}}]}}],"usage":null}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"city\":\"Paris\"}"}}]}}],"usage":null}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{"tool_calls":[{"index":1,"id":"call_2","type":"function","function":{"name":"get_weather","arguments":"{\"city\":\"Tokyo\"}"}}]}}],"usage":null}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}],"usage":null}
+
+data: {"id":"chatcmpl-1","object":"chat.completion.chunk","choices":[],"usage":{"prompt_tokens":120,"completion_tokens":45,"total_tokens":165}}
+
+data: [DONE]
+`
+
+	assertMetrics(t, ExtractFromResponse(body), 120, 45, 0, 0)
+}
+
+func TestExtractClaudeToolUseJSON(t *testing.T) {
+	body := `{
+		"id": "msg_1",
+		"type": "message",
+		"role": "assistant",
+		"model": "claude-sonnet-4-20250514",
+		"content": [
+			{"type": "text", "text": "Let me check."},
+			{"type": "tool_use", "id": "toolu_1", "name": "get_weather", "input": {"city": "Paris"}},
+			{"type": "tool_use", "id": "toolu_2", "name": "get_weather", "input": {"city": "Tokyo"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {
+			"input_tokens": 300,
+			"output_tokens": 88,
+			"cache_read_input_tokens": 1000,
+			"cache_creation_input_tokens": 200
+		}
+	}`
+
+	assertMetrics(t, ExtractFromResponse(body), 300, 88, 1000, 200)
+}
+
+func TestExtractClaudeToolUseStream(t *testing.T) {
+	// message_delta only carries output_tokens; input/cache tokens come from message_start
+	body := `event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"model":"claude-sonnet-4-20250514","usage":{"input_tokens":300,"output_tokens":1,"cache_read_input_tokens":1000,"cache_creation_input_tokens":200}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"city\":\"Paris\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use","stop_sequence":null},"usage":{"output_tokens":88}}
+
+event: message_stop
+data: {"type":"message_stop"}
+`
+
+	assertMetrics(t, ExtractFromResponse(body), 300, 88, 1000, 200)
+}
+
+func TestExtractCodexResponseCompletedStream(t *testing.T) {
+	body := `data: {"type":"response.output_item.added","item":{"type":"function_call","name":"shell","arguments":""}}
+
+data: {"type":"response.completed","response":{"id":"resp_1","usage":{"input_tokens":500,"input_tokens_details":{"cached_tokens":200},"output_tokens":60,"output_tokens_details":{"reasoning_tokens":10}}}}
+`
+
+	assertMetrics(t, ExtractFromResponse(body), 500, 60, 200, 0)
+}
+
+func TestExtractWithMappingJSON(t *testing.T) {
+	body := `{"id":"x","choices":[],"token_usage":{"prompt":120,"completion":30,"cached":"40","reasoning":12}}`
+	mapping := &domain.UsageFieldMapping{
+		InputTokens:     "token_usage.prompt",
+		OutputTokens:    "token_usage.completion",
+		CacheReadTokens: "token_usage.cached",
+		ReasoningTokens: "token_usage.reasoning",
+	}
+
+	if got := ExtractFromResponse(body); got != nil {
+		t.Fatalf("standard extraction should miss token_usage, got %+v", got)
+	}
+	got := ExtractFromResponseWithMapping(body, mapping)
+	assertMetrics(t, got, 120, 30, 40, 0)
+	if got.ReasoningTokens != 12 {
+		t.Errorf("ReasoningTokens = %d, want 12", got.ReasoningTokens)
+	}
+}
+
+func TestExtractWithMappingSSE(t *testing.T) {
+	body := "data: {\"token_usage\":{\"prompt\":10}}\n\n" +
+		"data: {\"choices\":[{\"delta\":{\"content\":\"hi\"}}]}\n\n" +
+		"data: {\"token_usage\":{\"prompt\":10,\"completion\":5}}\n\n" +
+		"data: [DONE]\n\n"
+	mapping := &domain.UsageFieldMapping{InputTokens: "token_usage.prompt", OutputTokens: "token_usage.completion"}
+
+	assertMetrics(t, ExtractFromResponseWithMapping(body, mapping), 10, 5, 0, 0)
+}
+
+func TestExtractWithMappingFallsBackToStandard(t *testing.T) {
+	body := `{"usage":{"prompt_tokens":7,"completion_tokens":3}}`
+
+	assertMetrics(t, ExtractFromResponseWithMapping(body, nil), 7, 3, 0, 0)
+	assertMetrics(t, ExtractFromResponseWithMapping(body, &domain.UsageFieldMapping{InputTokens: "token_usage.prompt"}), 7, 3, 0, 0)
+}
+
+func TestExtractReasoningTokens(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		output    uint64
+		reasoning uint64
+	}{
+		{
+			"claude thinking",
+			`{"usage":{"input_tokens":100,"output_tokens":80,"thinking_tokens":50}}`,
+			80, 50,
+		},
+		{
+			"openai chat",
+			`{"usage":{"prompt_tokens":100,"completion_tokens":80,"completion_tokens_details":{"reasoning_tokens":64}}}`,
+			80, 64,
+		},
+		{
+			"codex response",
+			`{"type":"response.completed","response":{"usage":{"input_tokens":100,"output_tokens":60,"output_tokens_details":{"reasoning_tokens":10}}}}`,
+			60, 10,
+		},
+		{
+			"gemini thoughts",
+			`{"usageMetadata":{"promptTokenCount":100,"candidatesTokenCount":20,"thoughtsTokenCount":30}}`,
+			50, 30,
+		},
+		{
+			"no reasoning",
+			`{"usage":{"input_tokens":100,"output_tokens":80}}`,
+			80, 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ExtractFromResponse(tt.body)
+			assertMetrics(t, got, 100, tt.output, 0, 0)
+			if got.ReasoningTokens != tt.reasoning {
+				t.Errorf("ReasoningTokens = %d, want %d", got.ReasoningTokens, tt.reasoning)
+			}
+		})
+	}
+}
@@ -128,7 +128,11 @@ export {
 } from './use-aggregated-stats';
 
 // Response Model hooks
-export { responseModelKeys, useResponseModels } from './use-response-models';
+export {
+  responseModelKeys,
+  useResponseModels,
+  useRebuildResponseModels,
+} from './use-response-models';
 
 // Dashboard Stats hooks
 export {
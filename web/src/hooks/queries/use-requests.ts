@@ -7,6 +7,7 @@ import { useEffect } from 'react';
 import {
   getTransport,
   type ProxyRequest,
+  type ProxyRequestSummary,
   type ProxyUpstreamAttempt,
   type CursorPaginationParams,
   type CursorPaginationResult,
@@ -63,105 +64,124 @@ export function useProxyRequestUpdates() {
   useEffect(() => {
     const transport = getTransport();
 
-    // 订阅 ProxyRequest 更新事件 (连接由 main.tsx 统一管理)
-    const unsubscribeRequest = transport.subscribe<ProxyRequest>(
-      'proxy_request_update',
-      (updatedRequest) => {
-        // 检查是否是新请求（通过详情缓存判断）
-        const existingDetail = queryClient.getQueryData(requestKeys.detail(updatedRequest.id));
-        const isNewRequest = !existingDetail;
-
-        // 更新单个请求的缓存
-        queryClient.setQueryData(requestKeys.detail(updatedRequest.id), updatedRequest);
-
-        // 更新列表缓存（乐观更新）- 适配 CursorPaginationResult 结构
-        // 使用 queryCache 遍历所有匹配的查询，以获取每个查询的过滤参数
-        const queryCache = queryClient.getQueryCache();
-        const listQueries = queryCache.findAll({ queryKey: requestKeys.lists() });
-
-        for (const query of listQueries) {
-          const queryKey = query.queryKey as ReturnType<typeof requestKeys.list>;
-          // 从 queryKey 中提取过滤参数: ['requests', 'list', params]
-          const params = queryKey[2] as CursorPaginationParams | undefined;
-          const filterProviderId = params?.providerId;
-          const filterStatus = params?.status;
-
-          // 检查是否匹配过滤条件的辅助函数
-          const matchesFilter = (request: ProxyRequest) => {
-            if (filterProviderId !== undefined && request.providerID !== filterProviderId) {
-              return false;
-            }
-            if (filterStatus !== undefined && request.status !== filterStatus) {
-              return false;
-            }
-            return true;
-          };
-
-          queryClient.setQueryData<CursorPaginationResult<ProxyRequest>>(queryKey, (old) => {
-            if (!old || !old.items) return old;
+    const applyRequestUpdate = (updatedRequest: ProxyRequest) => {
+      // 检查是否是新请求（通过详情缓存判断）
+      const existingDetail = queryClient.getQueryData(requestKeys.detail(updatedRequest.id));
+      const isNewRequest = !existingDetail;
+
+      // 更新单个请求的缓存
+      queryClient.setQueryData(requestKeys.detail(updatedRequest.id), updatedRequest);
+
+      // 更新列表缓存（乐观更新）- 适配 CursorPaginationResult 结构
+      // 使用 queryCache 遍历所有匹配的查询，以获取每个查询的过滤参数
+      const queryCache = queryClient.getQueryCache();
+      const listQueries = queryCache.findAll({ queryKey: requestKeys.lists() });
+
+      for (const query of listQueries) {
+        const queryKey = query.queryKey as ReturnType<typeof requestKeys.list>;
+        // 从 queryKey 中提取过滤参数: ['requests', 'list', params]
+        const params = queryKey[2] as CursorPaginationParams | undefined;
+        const filterProviderId = params?.providerId;
+        const filterStatus = params?.status;
+
+        // 检查是否匹配过滤条件的辅助函数
+        const matchesFilter = (request: ProxyRequest) => {
+          if (filterProviderId !== undefined && request.providerID !== filterProviderId) {
+            return false;
+          }
+          if (filterStatus !== undefined && request.status !== filterStatus) {
+            return false;
+          }
+          return true;
+        };
 
-            const index = old.items.findIndex((r) => r.id === updatedRequest.id);
-            if (index >= 0) {
-              // 已存在的请求：检查是否仍然匹配过滤条件
-              if (!matchesFilter(updatedRequest)) {
-                // 不再匹配过滤条件，从列表中移除
-                const newItems = old.items.filter((r) => r.id !== updatedRequest.id);
-                return { ...old, items: newItems };
-              }
-              // 仍然匹配，更新
-              const newItems = [...old.items];
-              newItems[index] = updatedRequest;
-              return { ...old, items: newItems };
-            }
+        queryClient.setQueryData<CursorPaginationResult<ProxyRequest>>(queryKey, (old) => {
+          if (!old || !old.items) return old;
 
-            // 新请求：检查是否匹配过滤条件
+          const index = old.items.findIndex((r) => r.id === updatedRequest.id);
+          if (index >= 0) {
+            // 已存在的请求：检查是否仍然匹配过滤条件
             if (!matchesFilter(updatedRequest)) {
-              // 不匹配过滤条件，不添加
-              return old;
+              // 不再匹配过滤条件，从列表中移除
+              const newItems = old.items.filter((r) => r.id !== updatedRequest.id);
+              return { ...old, items: newItems };
             }
+            // 仍然匹配，更新
+            const newItems = [...old.items];
+            newItems[index] = updatedRequest;
+            return { ...old, items: newItems };
+          }
 
-            // 新请求添加到列表开头（只在首页，即没有 before 参数的查询）
-            if (params?.before) {
-              // 不是首页，不添加新请求
-              return old;
-            }
+          // 新请求：检查是否匹配过滤条件
+          if (!matchesFilter(updatedRequest)) {
+            // 不匹配过滤条件，不添加
+            return old;
+          }
 
-            return {
-              ...old,
-              items: [updatedRequest, ...old.items],
-              firstId: updatedRequest.id,
-            };
-          });
-        }
+          // 新请求添加到列表开头（只在首页，即没有 before 参数的查询）
+          if (params?.before) {
+            // 不是首页，不添加新请求
+            return old;
+          }
 
-        // 新请求时乐观更新 count（需要考虑每个 count 查询的过滤条件）
-        if (isNewRequest) {
-          // 遍历所有 requestsCount 缓存
-          const countQueries = queryCache.findAll({ queryKey: ['requestsCount'] });
-          for (const query of countQueries) {
-            // queryKey: ['requestsCount', providerId, status]
-            const filterProviderId = query.queryKey[1] as number | undefined;
-            const filterStatus = query.queryKey[2] as string | undefined;
-            // 如果有过滤条件且不匹配，不更新计数
-            if (filterProviderId !== undefined && updatedRequest.providerID !== filterProviderId) {
-              continue;
-            }
-            if (filterStatus !== undefined && updatedRequest.status !== filterStatus) {
-              continue;
-            }
-            queryClient.setQueryData<number>(query.queryKey, (old) => (old ?? 0) + 1);
+          return {
+            ...old,
+            items: [updatedRequest, ...old.items],
+            firstId: updatedRequest.id,
+          };
+        });
+      }
+
+      // 新请求时乐观更新 count（需要考虑每个 count 查询的过滤条件）
+      if (isNewRequest) {
+        // 遍历所有 requestsCount 缓存
+        const countQueries = queryCache.findAll({ queryKey: ['requestsCount'] });
+        for (const query of countQueries) {
+          // queryKey: ['requestsCount', providerId, status]
+          const filterProviderId = query.queryKey[1] as number | undefined;
+          const filterStatus = query.queryKey[2] as string | undefined;
+          // 如果有过滤条件且不匹配，不更新计数
+          if (filterProviderId !== undefined && updatedRequest.providerID !== filterProviderId) {
+            continue;
+          }
+          if (filterStatus !== undefined && updatedRequest.status !== filterStatus) {
+            continue;
           }
+          queryClient.setQueryData<number>(query.queryKey, (old) => (old ?? 0) + 1);
         }
+      }
+
+      // 请求完成或失败时刷新相关数据
+      if (updatedRequest.status === 'COMPLETED' || updatedRequest.status === 'FAILED') {
+        // 刷新 dashboard 数据
+        queryClient.invalidateQueries({ queryKey: ['dashboard'] });
+        // 刷新 provider stats（因为统计数据变化了）
+        queryClient.invalidateQueries({ queryKey: ['providers', 'stats'] });
+        // 刷新 cooldowns（请求可能触发了冷却，即使最终成功也可能有 provider 进入冷却）
+        queryClient.invalidateQueries({ queryKey: ['cooldowns'] });
+      }
+    };
 
-        // 请求完成或失败时刷新相关数据
-        if (updatedRequest.status === 'COMPLETED' || updatedRequest.status === 'FAILED') {
-          // 刷新 dashboard 数据
-          queryClient.invalidateQueries({ queryKey: ['dashboard'] });
-          // 刷新 provider stats（因为统计数据变化了）
-          queryClient.invalidateQueries({ queryKey: ['providers', 'stats'] });
-          // 刷新 cooldowns（请求可能触发了冷却，即使最终成功也可能有 provider 进入冷却）
-          queryClient.invalidateQueries({ queryKey: ['cooldowns'] });
-        }
+    // 订阅 ProxyRequest 完整更新事件（被订阅的请求）(连接由 main.tsx 统一管理)
+    const unsubscribeRequest = transport.subscribe<ProxyRequest>(
+      'proxy_request_update',
+      applyRequestUpdate,
+    );
+
+    // 订阅精简更新事件（实时列表）：合并到已缓存的记录，详情在打开时按需获取完整记录
+    const unsubscribeSummary = transport.subscribe<ProxyRequestSummary>(
+      'proxy_request_summary',
+      (summary) => {
+        const detailKey = requestKeys.detail(summary.id);
+        const cached = queryClient.getQueryData<ProxyRequest>(detailKey);
+        applyRequestUpdate({ requestInfo: null, responseInfo: null, ...cached, ...summary });
+        // 缓存的详情缺少最新的请求/响应内容：标记为过期，正在查看的详情在请求结束时刷新
+        const finished = summary.status !== 'PENDING' && summary.status !== 'IN_PROGRESS';
+        queryClient.invalidateQueries({
+          queryKey: detailKey,
+          exact: true,
+          refetchType: finished ? 'active' : 'none',
+        });
       },
     );
 
@@ -188,6 +208,7 @@ export function useProxyRequestUpdates() {
 
     return () => {
       unsubscribeRequest();
+      unsubscribeSummary();
       unsubscribeAttempt();
     };
   }, [queryClient]);
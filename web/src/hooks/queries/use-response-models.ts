@@ -3,7 +3,7 @@
  * 获取所有已使用的响应模型名称列表
  */
 
-import { useQuery } from '@tanstack/react-query';
+import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
 import { getTransport } from '@/lib/transport';
 
 // Query Keys
@@ -22,3 +22,17 @@ export function useResponseModels() {
     staleTime: 5 * 60 * 1000, // 5 分钟
   });
 }
+
+/**
+ * 按已记录的 attempt 重建响应模型列表
+ */
+export function useRebuildResponseModels() {
+  const queryClient = useQueryClient();
+
+  return useMutation({
+    mutationFn: () => getTransport().rebuildResponseModels(),
+    onSuccess: () => {
+      queryClient.invalidateQueries({ queryKey: responseModelKeys.all });
+    },
+  });
+}
@@ -33,7 +33,8 @@ export function useCooldowns() {
 
   // Mutation for clearing cooldown
   const clearCooldownMutation = useMutation({
-    mutationFn: (providerId: number) => getTransport().clearCooldown(providerId),
+    // 解冻同时清零失败计数，Provider 立即回到轮转且下次失败从头退避
+    mutationFn: (providerId: number) => getTransport().resetProviderHealth(providerId),
     onSuccess: () => {
       // Invalidate and refetch cooldowns after successful deletion
       queryClient.invalidateQueries({ queryKey: ['cooldowns'] });
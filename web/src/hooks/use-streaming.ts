@@ -4,7 +4,12 @@
  */
 
 import { useState, useEffect, useCallback, useRef } from 'react';
-import { getTransport, type ProxyRequest, type ClientType } from '@/lib/transport';
+import {
+  getTransport,
+  type ProxyRequest,
+  type ProxyRequestSummary,
+  type ClientType,
+} from '@/lib/transport';
 
 export interface StreamingState {
   /** 当前活动请求总数 */
@@ -60,9 +65,16 @@ export function useStreamingRequests(): StreamingState {
   }, []);
 
   // 处理请求更新
-  const handleRequestUpdate = useCallback((request: ProxyRequest) => {
+  const handleRequestUpdate = useCallback((update: ProxyRequest | ProxyRequestSummary) => {
     setActiveRequests((prev) => {
       const next = new Map(prev);
+      // 精简推送不含请求/响应详情，保留已有记录上的字段
+      const request: ProxyRequest = {
+        requestInfo: null,
+        responseInfo: null,
+        ...prev.get(update.requestID),
+        ...update,
+      };
 
       if (isActiveRequest(request)) {
         // PENDING 或 IN_PROGRESS 的请求添加到活动列表
@@ -87,11 +99,15 @@ export function useStreamingRequests(): StreamingState {
       loadActiveRequests();
     }
 
-    // 订阅请求更新事件 (连接由 main.tsx 统一管理)
+    // 订阅请求更新事件：完整推送与精简推送 (连接由 main.tsx 统一管理)
     const unsubscribe = transport.subscribe<ProxyRequest>(
       'proxy_request_update',
       handleRequestUpdate,
     );
+    const unsubscribeSummary = transport.subscribe<ProxyRequestSummary>(
+      'proxy_request_summary',
+      handleRequestUpdate,
+    );
 
     // 订阅 WebSocket 重连事件，重新加载活跃请求
     // 因为断开期间可能有请求完成或新增
@@ -101,6 +117,7 @@ export function useStreamingRequests(): StreamingState {
 
     return () => {
       unsubscribe();
+      unsubscribeSummary();
       unsubscribeReconnect();
     };
   }, [handleRequestUpdate, loadActiveRequests]);
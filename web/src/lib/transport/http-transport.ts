@@ -22,7 +22,12 @@ import type {
   ProxyStatus,
   ProviderStats,
   CursorPaginationParams,
+  DeleteProxyRequestsFilter,
+  DeleteProxyRequestsResult,
+  MergeSessionsData,
+  MergeSessionsResult,
   CursorPaginationResult,
+  AttemptPaginationResult,
   WSMessageType,
   WSMessage,
   EventCallback,
@@ -34,6 +39,8 @@ import type {
   ModelMappingInput,
   ImportResult,
   Cooldown,
+  CooldownEvent,
+  BreakerMetrics,
   KiroTokenValidationResult,
   KiroQuotaData,
   CodexTokenValidationResult,
@@ -45,11 +52,29 @@ import type {
   APITokenCreateResult,
   CreateAPITokenData,
   RoutePositionUpdate,
+  CompareRoutesData,
+  RouteComparisonReport,
+  TestProjectRoutesData,
+  ProjectRouteTestReport,
+  ResolveRequestData,
+  RequestResolution,
+  ProviderGroupStatus,
+  ProviderHealthScore,
+  Capabilities,
+  ProviderDrainStatus,
+  CredentialBundle,
   UsageStats,
   UsageStatsFilter,
   RecalculateCostsResult,
+  ReconcileCostsResult,
+  AggregateStatsResult,
+  MultiplierUsage,
   RecalculateRequestCostResult,
+  WatchProxyRequestResult,
   DashboardData,
+  DashboardProviderStatus,
+  ConcurrencySample,
+  RebuildResponseModelsResult,
   BackupFile,
   BackupImportOptions,
   BackupImportResult,
@@ -124,8 +149,58 @@ export class HttpTransport implements Transport {
     return data ?? [];
   }
 
-  async importProviders(providers: Provider[]): Promise<ImportResult> {
-    const { data } = await this.client.post<ImportResult>('/providers/import', providers);
+  async importProviders(providers: Provider[], renameOnConflict?: boolean): Promise<ImportResult> {
+    const { data } = await this.client.post<ImportResult>('/providers/import', providers, {
+      params: renameOnConflict ? { renameOnConflict: true } : undefined,
+    });
+    return data;
+  }
+
+  async getProviderGroups(): Promise<ProviderGroupStatus[]> {
+    const { data } = await this.client.get<ProviderGroupStatus[]>('/provider-groups');
+    return data;
+  }
+
+  async getProviderHealthScores(): Promise<ProviderHealthScore[]> {
+    const { data } = await this.client.get<ProviderHealthScore[]>('/provider-health');
+    return data;
+  }
+
+  async getCapabilities(): Promise<Capabilities> {
+    const { data } = await this.client.get<Capabilities>('/capabilities');
+    return data;
+  }
+
+  async getProviderDrainStatus(id: number): Promise<ProviderDrainStatus> {
+    const { data } = await this.client.get<ProviderDrainStatus>(`/providers/${id}/drain`);
+    return data;
+  }
+
+  async drainProvider(id: number): Promise<ProviderDrainStatus> {
+    const { data } = await this.client.post<ProviderDrainStatus>(`/providers/${id}/drain`);
+    return data;
+  }
+
+  async undrainProvider(id: number): Promise<ProviderDrainStatus> {
+    const { data } = await this.client.delete<ProviderDrainStatus>(`/providers/${id}/drain`);
+    return data;
+  }
+
+  async exportProviderCredentials(id: number, passphrase: string): Promise<CredentialBundle> {
+    const { data } = await this.client.get<CredentialBundle>(`/providers/${id}/credentials/export`, {
+      headers: { 'X-Credential-Passphrase': passphrase },
+    });
+    return data;
+  }
+
+  async importProviderCredentials(
+    id: number,
+    bundle: CredentialBundle,
+    passphrase: string,
+  ): Promise<Provider> {
+    const { data } = await this.client.post<Provider>(`/providers/${id}/credentials/import`, bundle, {
+      headers: { 'X-Credential-Passphrase': passphrase },
+    });
     return data;
   }
 
@@ -190,6 +265,27 @@ export class HttpTransport implements Transport {
     await this.client.put('/routes/batch-positions', updates);
   }
 
+  async compareRoutes(payload: CompareRoutesData): Promise<RouteComparisonReport> {
+    const { data } = await this.client.post<RouteComparisonReport>('/routes/compare', payload);
+    return data;
+  }
+
+  async testProjectRoutes(
+    projectId: number,
+    payload: TestProjectRoutesData,
+  ): Promise<ProjectRouteTestReport> {
+    const { data } = await this.client.post<ProjectRouteTestReport>(
+      `/projects/${projectId}/test-routes`,
+      payload,
+    );
+    return data;
+  }
+
+  async resolveRequest(payload: ResolveRequestData): Promise<RequestResolution> {
+    const { data } = await this.client.post<RequestResolution>('/debug/resolve', payload);
+    return data;
+  }
+
   // ===== Session API =====
 
   async getSessions(): Promise<Session[]> {
@@ -215,6 +311,11 @@ export class HttpTransport implements Transport {
     return data;
   }
 
+  async mergeSessions(payload: MergeSessionsData): Promise<MergeSessionsResult> {
+    const { data } = await this.client.post<MergeSessionsResult>('/sessions/merge', payload);
+    return data;
+  }
+
   // ===== RetryConfig API =====
 
   async getRetryConfigs(): Promise<RetryConfig[]> {
@@ -302,6 +403,11 @@ export class HttpTransport implements Transport {
     return data;
   }
 
+  async deleteProxyRequests(filter: DeleteProxyRequestsFilter): Promise<DeleteProxyRequestsResult> {
+    const { data } = await this.client.post<DeleteProxyRequestsResult>('/requests/delete', filter);
+    return data;
+  }
+
   async getProxyRequest(id: number): Promise<ProxyRequest> {
     const { data } = await this.client.get<ProxyRequest>(`/requests/${id}`);
     return data;
@@ -314,6 +420,20 @@ export class HttpTransport implements Transport {
     return data ?? [];
   }
 
+  async getProxyUpstreamAttemptsPage(
+    proxyRequestId: number,
+    limit: number,
+    cursor?: number,
+  ): Promise<AttemptPaginationResult> {
+    const params: Record<string, number> = { limit };
+    if (cursor) params.cursor = cursor;
+    const { data } = await this.client.get<AttemptPaginationResult>(
+      `/requests/${proxyRequestId}/attempts`,
+      { params },
+    );
+    return data;
+  }
+
   // ===== Proxy Status API =====
 
   async getProxyStatus(): Promise<ProxyStatus> {
@@ -545,10 +665,33 @@ export class HttpTransport implements Transport {
     await this.client.delete(`/cooldowns/${providerId}`);
   }
 
+  async resetProviderHealth(providerId: number): Promise<void> {
+    await this.client.post(`/cooldowns/${providerId}/reset`);
+  }
+
   async setCooldown(providerId: number, untilTime: string, clientType?: string): Promise<void> {
     await this.client.put(`/cooldowns/${providerId}`, { untilTime, clientType });
   }
 
+  async getCooldownHistory(
+    providerId: number,
+    since?: string,
+    limit?: number,
+  ): Promise<CooldownEvent[]> {
+    const params: Record<string, string | number> = {};
+    if (since) params.since = since;
+    if (limit !== undefined) params.limit = limit;
+    const { data } = await this.client.get<CooldownEvent[]>(`/cooldowns/${providerId}/history`, {
+      params: Object.keys(params).length > 0 ? params : undefined,
+    });
+    return data ?? [];
+  }
+
+  async getBreakerMetrics(): Promise<BreakerMetrics[]> {
+    const { data } = await this.client.get<BreakerMetrics[]>('/cooldowns/metrics');
+    return data ?? [];
+  }
+
   // ===== Auth API =====
 
   async getAuthStatus(): Promise<AuthStatus> {
@@ -576,6 +719,11 @@ export class HttpTransport implements Transport {
     return data ?? [];
   }
 
+  async getStaleAPITokens(days: number): Promise<APIToken[]> {
+    const { data } = await this.client.get<APIToken[]>('/api-tokens/stale', { params: { days } });
+    return data ?? [];
+  }
+
   async getAPIToken(id: number): Promise<APIToken> {
     const { data } = await this.client.get<APIToken>(`/api-tokens/${id}`);
     return data;
@@ -626,6 +774,27 @@ export class HttpTransport implements Transport {
     return data;
   }
 
+  async reconcileCosts(hours?: number, fix?: boolean): Promise<ReconcileCostsResult> {
+    const { data } = await this.client.post<ReconcileCostsResult>(
+      '/usage-stats/reconcile-costs',
+      null,
+      { params: { hours, fix } },
+    );
+    return data;
+  }
+
+  async getMultiplierUsage(start?: string, end?: string): Promise<MultiplierUsage[]> {
+    const { data } = await this.client.get<MultiplierUsage[]>('/usage-stats/multipliers', {
+      params: { start, end },
+    });
+    return data ?? [];
+  }
+
+  async aggregateStatsNow(): Promise<AggregateStatsResult> {
+    const { data } = await this.client.post<AggregateStatsResult>('/stats/aggregate-now');
+    return data;
+  }
+
   async recalculateRequestCost(requestId: number): Promise<RecalculateRequestCostResult> {
     const { data } = await this.client.post<RecalculateRequestCostResult>(
       `/requests/${requestId}/recalculate-cost`,
@@ -633,6 +802,16 @@ export class HttpTransport implements Transport {
     return data;
   }
 
+  async updateProxyRequestNotes(requestId: number, notes: string): Promise<ProxyRequest> {
+    const { data } = await this.client.patch<ProxyRequest>(`/requests/${requestId}/notes`, { notes });
+    return data;
+  }
+
+  async watchProxyRequest(requestId: number): Promise<WatchProxyRequestResult> {
+    const { data } = await this.client.post<WatchProxyRequestResult>(`/requests/${requestId}/watch`);
+    return data;
+  }
+
   // ===== Dashboard API =====
 
   async getDashboardData(): Promise<DashboardData> {
@@ -640,6 +819,18 @@ export class HttpTransport implements Transport {
     return data;
   }
 
+  async getDashboardProviders(): Promise<DashboardProviderStatus[]> {
+    const { data } = await this.client.get<DashboardProviderStatus[]>('/dashboard/providers');
+    return data;
+  }
+
+  async getConcurrencyHistory(since?: string, projectId?: number): Promise<ConcurrencySample[]> {
+    const { data } = await this.client.get<ConcurrencySample[]>('/dashboard/concurrency', {
+      params: { since, projectId },
+    });
+    return data;
+  }
+
   // ===== Response Model API =====
 
   async getResponseModels(): Promise<string[]> {
@@ -647,6 +838,11 @@ export class HttpTransport implements Transport {
     return data ?? [];
   }
 
+  async rebuildResponseModels(): Promise<RebuildResponseModelsResult> {
+    const { data } = await this.client.post<RebuildResponseModelsResult>('/response-models/rebuild');
+    return data;
+  }
+
   // ===== Backup API =====
 
   async exportBackup(): Promise<BackupFile> {
@@ -658,6 +854,7 @@ export class HttpTransport implements Transport {
     const params = new URLSearchParams();
     if (options?.conflictStrategy) params.set('conflictStrategy', options.conflictStrategy);
     if (options?.dryRun) params.set('dryRun', 'true');
+    if (options?.renameOnConflict) params.set('renameOnConflict', 'true');
 
     const query = params.toString();
     const url = query ? `/backup/import?${query}` : '/backup/import';
@@ -8,6 +8,8 @@ export type {
   ClientType,
   Provider,
   ProviderConfig,
+  ProviderRateLimit,
+  ProviderTransport,
   ProviderConfigCustom,
   ProviderConfigAntigravity,
   CreateProviderData,
@@ -24,6 +26,10 @@ export type {
   RoutingStrategyConfig,
   CreateRoutingStrategyData,
   ProxyRequest,
+  ProxyRequestSummary,
+  RoutingTrace,
+  RoutingTraceAction,
+  RoutingTraceStep,
   ProxyRequestStatus,
   ProxyUpstreamAttempt,
   ProxyUpstreamAttemptStatus,
@@ -33,10 +39,40 @@ export type {
   // 分页
   PaginationParams,
   CursorPaginationParams,
+  DeleteProxyRequestsFilter,
+  DeleteProxyRequestsResult,
+  MergeSessionsData,
+  MergeSessionsResult,
   CursorPaginationResult,
+  AttemptPaginationResult,
   // WebSocket
   WSMessageType,
   WSMessage,
+  CostAnomaly,
+  UsageFieldMapping,
+  ModelFallback,
+  CompareRoutesData,
+  RouteComparisonRun,
+  RouteComparisonSample,
+  RouteComparisonSide,
+  RouteComparisonReport,
+  TestProjectRoutesData,
+  RouteTestResult,
+  ProjectRouteTestReport,
+  ResolveRequestData,
+  ResolvedRoute,
+  ResolvedProviderCooldown,
+  RequestResolution,
+  ProviderGroupMember,
+  ModelVariant,
+  ModelExperiment,
+  ProviderGroupStatus,
+  ProviderHealthScore,
+  Capabilities,
+  ClientTypeCapabilities,
+  ProviderTypeCapabilities,
+  ProviderDrainStatus,
+  CredentialBundle,
   // 回调
   EventCallback,
   UnsubscribeFn,
@@ -66,18 +102,28 @@ export type {
   ImportResult,
   // Cooldown
   Cooldown,
+  CooldownEvent,
+  BreakerMetrics,
   // API Token
   APIToken,
   APITokenCreateResult,
+  TokenModelQuota,
   CreateAPITokenData,
   // Usage Stats
   UsageStats,
   UsageStatsFilter,
   StatsGranularity,
   RecalculateRequestCostResult,
+  WatchProxyRequestResult,
   RecalculateCostsResult,
+  ReconcileCostsResult,
+  CostDiscrepancy,
+  AggregateStatsPhase,
+  AggregateStatsResult,
+  MultiplierUsage,
   RecalculateCostsProgress,
   RecalculateStatsProgress,
+  RequestDetailCleanupProgress,
   // Dashboard
   DashboardData,
   DashboardDaySummary,
@@ -86,6 +132,10 @@ export type {
   DashboardModelStats,
   DashboardTrendPoint,
   DashboardProviderStats,
+  DashboardProviderCooldown,
+  DashboardProviderStatus,
+  ConcurrencySample,
+  RebuildResponseModelsResult,
   // Pricing
   ModelPricing,
   PriceTable,
@@ -18,7 +18,12 @@ import type {
   ProxyRequest,
   ProxyUpstreamAttempt,
   CursorPaginationParams,
+  DeleteProxyRequestsFilter,
+  DeleteProxyRequestsResult,
+  MergeSessionsData,
+  MergeSessionsResult,
   CursorPaginationResult,
+  AttemptPaginationResult,
   ProxyStatus,
   ProviderStats,
   WSMessageType,
@@ -31,6 +36,8 @@ import type {
   ModelMappingInput,
   ImportResult,
   Cooldown,
+  CooldownEvent,
+  BreakerMetrics,
   KiroTokenValidationResult,
   KiroQuotaData,
   CodexTokenValidationResult,
@@ -43,11 +50,29 @@ import type {
   APITokenCreateResult,
   CreateAPITokenData,
   RoutePositionUpdate,
+  CompareRoutesData,
+  RouteComparisonReport,
+  TestProjectRoutesData,
+  ProjectRouteTestReport,
+  ResolveRequestData,
+  RequestResolution,
+  ProviderGroupStatus,
+  ProviderHealthScore,
+  Capabilities,
+  ProviderDrainStatus,
+  CredentialBundle,
   UsageStats,
   UsageStatsFilter,
   RecalculateCostsResult,
+  ReconcileCostsResult,
+  AggregateStatsResult,
+  MultiplierUsage,
   RecalculateRequestCostResult,
+  WatchProxyRequestResult,
   DashboardData,
+  DashboardProviderStatus,
+  ConcurrencySample,
+  RebuildResponseModelsResult,
   BackupFile,
   BackupImportOptions,
   BackupImportResult,
@@ -67,7 +92,15 @@ export interface Transport {
   updateProvider(id: number, data: Partial<Provider>): Promise<Provider>;
   deleteProvider(id: number): Promise<void>;
   exportProviders(): Promise<Provider[]>;
-  importProviders(providers: Provider[]): Promise<ImportResult>;
+  importProviders(providers: Provider[], renameOnConflict?: boolean): Promise<ImportResult>;
+  getProviderGroups(): Promise<ProviderGroupStatus[]>;
+  getProviderHealthScores(): Promise<ProviderHealthScore[]>;
+  getCapabilities(): Promise<Capabilities>;
+  getProviderDrainStatus(id: number): Promise<ProviderDrainStatus>;
+  drainProvider(id: number): Promise<ProviderDrainStatus>;
+  undrainProvider(id: number): Promise<ProviderDrainStatus>;
+  exportProviderCredentials(id: number, passphrase: string): Promise<CredentialBundle>;
+  importProviderCredentials(id: number, bundle: CredentialBundle, passphrase: string): Promise<Provider>;
 
   // ===== Project API =====
   getProjects(): Promise<Project[]>;
@@ -84,6 +117,9 @@ export interface Transport {
   updateRoute(id: number, data: Partial<Route>): Promise<Route>;
   deleteRoute(id: number): Promise<void>;
   batchUpdateRoutePositions(updates: RoutePositionUpdate[]): Promise<void>;
+  compareRoutes(data: CompareRoutesData): Promise<RouteComparisonReport>;
+  testProjectRoutes(projectId: number, data: TestProjectRoutesData): Promise<ProjectRouteTestReport>;
+  resolveRequest(data: ResolveRequestData): Promise<RequestResolution>;
 
   // ===== Session API =====
   getSessions(): Promise<Session[]>;
@@ -92,6 +128,7 @@ export interface Transport {
     projectID: number,
   ): Promise<{ session: Session; updatedRequests: number }>;
   rejectSession(sessionID: string): Promise<Session>;
+  mergeSessions(data: MergeSessionsData): Promise<MergeSessionsResult>;
 
   // ===== RetryConfig API =====
   getRetryConfigs(): Promise<RetryConfig[]>;
@@ -107,12 +144,18 @@ export interface Transport {
   updateRoutingStrategy(id: number, data: Partial<RoutingStrategy>): Promise<RoutingStrategy>;
   deleteRoutingStrategy(id: number): Promise<void>;
 
-  // ===== ProxyRequest API (只读) =====
+  // ===== ProxyRequest API =====
   getProxyRequests(params?: CursorPaginationParams): Promise<CursorPaginationResult<ProxyRequest>>;
   getProxyRequestsCount(providerId?: number, status?: string): Promise<number>;
   getActiveProxyRequests(): Promise<ProxyRequest[]>;
   getProxyRequest(id: number): Promise<ProxyRequest>;
   getProxyUpstreamAttempts(proxyRequestId: number): Promise<ProxyUpstreamAttempt[]>;
+  getProxyUpstreamAttemptsPage(
+    proxyRequestId: number,
+    limit: number,
+    cursor?: number,
+  ): Promise<AttemptPaginationResult>;
+  deleteProxyRequests(filter: DeleteProxyRequestsFilter): Promise<DeleteProxyRequestsResult>;
 
   // ===== Proxy Status API =====
   getProxyStatus(): Promise<ProxyStatus>;
@@ -167,7 +210,10 @@ export interface Transport {
   // ===== Cooldown API =====
   getCooldowns(): Promise<Cooldown[]>;
   clearCooldown(providerId: number): Promise<void>;
+  resetProviderHealth(providerId: number): Promise<void>; // 清除所有 clientType 的冷却和失败计数
   setCooldown(providerId: number, untilTime: string, clientType?: string): Promise<void>;
+  getCooldownHistory(providerId: number, since?: string, limit?: number): Promise<CooldownEvent[]>; // 按时间倒序
+  getBreakerMetrics(): Promise<BreakerMetrics[]>; // 最近 24 小时的熔断指标
 
   // ===== Auth API =====
   getAuthStatus(): Promise<AuthStatus>;
@@ -177,6 +223,7 @@ export interface Transport {
 
   // ===== API Token API =====
   getAPITokens(): Promise<APIToken[]>;
+  getStaleAPITokens(days: number): Promise<APIToken[]>;
   getAPIToken(id: number): Promise<APIToken>;
   createAPIToken(data: CreateAPITokenData): Promise<APITokenCreateResult>;
   updateAPIToken(id: number, data: Partial<APIToken>): Promise<APIToken>;
@@ -186,13 +233,21 @@ export interface Transport {
   getUsageStats(filter?: UsageStatsFilter): Promise<UsageStats[]>;
   recalculateUsageStats(): Promise<void>;
   recalculateCosts(): Promise<RecalculateCostsResult>;
+  reconcileCosts(hours?: number, fix?: boolean): Promise<ReconcileCostsResult>;
+  aggregateStatsNow(): Promise<AggregateStatsResult>;
+  getMultiplierUsage(start?: string, end?: string): Promise<MultiplierUsage[]>;
   recalculateRequestCost(requestId: number): Promise<RecalculateRequestCostResult>;
+  updateProxyRequestNotes(requestId: number, notes: string): Promise<ProxyRequest>;
+  watchProxyRequest(requestId: number): Promise<WatchProxyRequestResult>;
 
   // ===== Dashboard API =====
   getDashboardData(): Promise<DashboardData>;
+  getDashboardProviders(): Promise<DashboardProviderStatus[]>;
+  getConcurrencyHistory(since?: string, projectId?: number): Promise<ConcurrencySample[]>;
 
   // ===== Response Model API =====
   getResponseModels(): Promise<string[]>;
+  rebuildResponseModels(): Promise<RebuildResponseModelsResult>;
 
   // ===== Backup API =====
   exportBackup(): Promise<BackupFile>;
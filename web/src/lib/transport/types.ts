@@ -13,8 +13,34 @@ export interface ProviderConfigCustom {
   baseURL: string;
   apiKey: string;
   clientBaseURL?: Partial<Record<ClientType, string>>;
+  clientPathOverride?: Partial<Record<ClientType, PathOverride>>; // 上游路径不标准时覆盖请求路径
   clientMultiplier?: Partial<Record<ClientType, number>>; // 10000=1倍
   modelMapping?: Record<string, string>;
+  streamMode?: '' | 'stream' | 'non-stream'; // 上游流式模式，为空表示跟随客户端
+  usageMapping?: UsageFieldMapping; // 非标准 usage 字段映射
+  userAgent?: string; // 发往上游的 User-Agent，为空表示默认行为
+  passthroughUserAgent?: boolean; // 优先透传客户端 User-Agent
+  openaiOrganization?: string; // 覆盖 OpenAI-Organization，为空表示透传客户端请求头
+  openaiProject?: string; // 覆盖 OpenAI-Project，为空表示透传客户端请求头
+  anthropicVersion?: string; // 覆盖 Anthropic-Version，为空表示透传客户端请求头
+  anthropicBeta?: string; // 覆盖 Anthropic-Beta（逗号分隔），为空表示透传客户端请求头
+  passthroughErrorBodies?: boolean; // 非流式 200 响应体为错误结构时仍按成功透传（默认识别为可重试的上游错误）
+  stripSSEComments?: boolean; // 丢弃上游 SSE 注释/心跳行（默认转发给客户端）
+}
+
+/** 上游请求路径覆盖 */
+export interface PathOverride {
+  path: string; // 拼接在 baseURL 之后，支持 {model} 占位符
+  appendStandardPath?: boolean; // 在 path 之后追加客户端请求的标准路径
+}
+
+// 非标准响应的 usage 字段路径（gjson 路径，如 "token_usage.prompt"）
+export interface UsageFieldMapping {
+  inputTokens?: string;
+  outputTokens?: string;
+  cacheReadTokens?: string;
+  cacheWriteTokens?: string;
+  reasoningTokens?: string; // 推理 token（输出 token 的一部分）
 }
 
 export interface ProviderConfigAntigravity {
@@ -50,11 +76,48 @@ export interface ProviderConfigCodex {
   modelMapping?: Record<string, string>;
 }
 
+export interface ProviderRateLimit {
+  rpm?: number; // 每分钟最大请求数，0 表示不限制
+  tpm?: number; // 每分钟最大 Token 数，0 表示不限制
+}
+
+// 上游 HTTP 连接配置，字段为 0 / 未设置时使用默认值
+// HTTP/2 下多个流式响应复用同一连接；遇到反向代理缓冲导致的流式卡顿时可关闭 HTTP/2
+export interface ProviderTransport {
+  disableHTTP2?: boolean; // 强制 HTTP/1.1
+  maxIdleConnsPerHost?: number; // 默认 16
+  idleConnTimeoutSeconds?: number; // 默认 90
+  keepAliveSeconds?: number; // TCP keep-alive 间隔，默认 60
+  disableKeepAlives?: boolean; // 每个请求新建连接
+  warmConnections?: number; // 预热保持的空闲连接数（HEAD 请求，不计入统计），0/未设置表示不预热
+  warmIntervalSeconds?: number; // 预热间隔，默认为空闲超时的 1/3
+}
+
 export interface ProviderConfig {
   custom?: ProviderConfigCustom;
   antigravity?: ProviderConfigAntigravity;
   kiro?: ProviderConfigKiro;
   codex?: ProviderConfigCodex;
+  rateLimit?: ProviderRateLimit;
+  transport?: ProviderTransport; // 对 Custom、Codex、Antigravity 生效
+  maxOutputTokens?: number; // 输出 token 上限，超出时下调请求的 max_tokens（0/未设置表示不限制）
+  maxInputBytes?: number; // 请求体字节上限，超出时跳过该 Provider 的路由（0/未设置表示不限制）
+  maxInputTokens?: number; // 估算输入 token 上限，超出时跳过该 Provider 的路由（0/未设置表示不限制）
+  priceOverrides?: ModelPriceInput[]; // 价格覆盖，优先于全局 model_prices（modelId 支持前缀匹配）
+  conversionPreference?: Partial<Record<ClientType, ClientType[]>>; // 格式转换目标的优先顺序，未设置时优先 Claude
+  group?: string; // Provider 分组，同名分组共享配额池，路由时组内按剩余配额均衡
+  softFailurePatterns?: string[]; // 软失败正则：2xx 响应内容匹配时视为可重试失败并切换路由
+  retryEmptyResponses?: boolean; // 2xx 响应为空或输出 token 为 0 时视为可重试失败并切换路由
+  unsupportedParams?: string[]; // 发往该 Provider 前删除的请求参数（如 top_k、seed）
+  streamTextRewrites?: StreamTextRewrite[]; // 流式文本改写规则（字面替换），为空时不启用
+  maxStreamDurationMs?: number; // 流式响应总时长上限（毫秒），0/未设置表示不限制
+  minifyRequestBody?: boolean; // 发往上游前压缩请求体 JSON（去掉空白），不改变内容
+}
+
+// 流式文本改写规则：find 为字面字符串，跨 chunk 的匹配同样生效
+export interface StreamTextRewrite {
+  find: string;
+  replace: string;
 }
 
 export interface Provider {
@@ -67,6 +130,96 @@ export interface Provider {
   config: ProviderConfig | null;
   supportedClientTypes: ClientType[];
   supportModels?: string[]; // 支持的模型列表（通配符模式），空数组表示支持所有模型
+  disabledClientTypes?: ClientType[]; // 在该 Provider 上禁用的 ClientType，匹配时跳过
+  draining?: boolean; // 排空中：不再分配新请求，进行中的请求正常完成
+}
+
+// Provider 排空状态
+export interface ProviderDrainStatus {
+  providerID: number;
+  draining: boolean;
+  inFlight: number; // 仍在该 Provider 上进行中的请求数
+  drained: boolean; // 排空中且已无进行中的请求，可安全下线
+}
+
+// 口令加密的 Provider 凭证（PBKDF2-SHA256 派生密钥 + AES-256-GCM），口令通过 X-Credential-Passphrase 请求头传递
+export interface CredentialBundle {
+  version: number;
+  providerType: string;
+  kdf: string;
+  iterations: number;
+  cipher: string;
+  salt: string; // base64
+  nonce: string; // base64
+  ciphertext: string; // base64
+}
+
+// A/B 模型实验（system setting model_experiments 的 JSON 数组元素）
+export interface ModelVariant {
+  name: string;
+  model: string;
+  weight: number;
+}
+
+export interface ModelExperiment {
+  name: string;
+  enabled: boolean;
+  model?: string; // 请求模型匹配模式（通配符），为空匹配所有模型
+  assignBy?: 'token' | 'session'; // 默认 token，无 Token 时按 Session
+  variants: ModelVariant[];
+}
+
+// Provider 分组成员配额状态
+export interface ProviderGroupMember {
+  providerID: number;
+  name: string;
+  type: string;
+  remainingPercent?: number; // 剩余配额百分比 0-100，未设置表示无配额数据
+  quotaSource?: 'codex' | 'antigravity' | 'rateLimit';
+  inCooldown: boolean;
+}
+
+// Provider 分组（共享配额池）
+export interface ProviderGroupStatus {
+  name: string;
+  members: ProviderGroupMember[];
+  remainingPercent?: number; // 有配额数据成员的平均剩余百分比
+}
+
+// Provider 综合健康分（0-100）：成功率、延迟、冷却状态与剩余配额加权
+export interface ProviderHealthScore {
+  providerID: number;
+  name: string;
+  score: number;
+  requests: number; // 最近 15 分钟的上游请求数
+  successRate?: number; // 0-100，样本不足时未设置
+  avgLatencyMs?: number;
+  cooldownClientTypes: string[]; // '' 表示全局冷却
+  quotaPercent?: number;
+  quotaSource?: 'codex' | 'antigravity' | 'rateLimit';
+  updatedAt: string;
+}
+
+// 客户端类型可转换到的上游格式
+export interface ClientTypeCapabilities {
+  type: ClientType;
+  convertsTo: ClientType[];
+}
+
+// Provider 类型能力：原生/可转换接入的客户端类型及支持的功能
+export interface ProviderTypeCapabilities {
+  type: string;
+  nativeClientTypes: ClientType[]; // configurableClientTypes 为 true 时是未配置时的默认值
+  configurableClientTypes: boolean;
+  convertedClientTypes: ClientType[];
+  streaming: boolean;
+  tools: boolean;
+  embeddings: boolean;
+}
+
+export interface Capabilities {
+  clientTypes: ClientTypeCapabilities[];
+  providerTypes: ProviderTypeCapabilities[];
 }
 
 // supportedClientTypes 可选，后端会根据 provider type 自动设置
@@ -87,6 +240,23 @@ export interface Project {
   name: string;
   slug: string;
   enabledCustomRoutes: ClientType[];
+  timezone?: string;
+  modelFallbacks?: ModelFallback[];
+  nonBillable?: boolean; // 不计费项目
+  maxRoutesAttempted?: number; // 每个请求最多尝试的不同路由数，0/未设置使用全局 max_routes_attempted
+  previousSlugs?: PreviousSlug[]; // 曾用 slug，宽限期内仍可用于 /project/{slug}/ 代理路径（服务端维护）
+}
+
+// 项目的曾用 slug，expiresAt 之后不再解析
+export interface PreviousSlug {
+  slug: string;
+  expiresAt: string;
+}
+
+// 模型回退链：请求模型匹配 pattern 且所有路由都失败后，依次改用 fallbacks 中的模型
+export interface ModelFallback {
+  pattern: string; // 支持通配符 *
+  fallbacks: string[];
 }
 
 export type CreateProjectData = Omit<Project, 'id' | 'createdAt' | 'updatedAt' | 'slug'> & {
@@ -104,6 +274,20 @@ export interface Session {
   projectID: number;
 }
 
+export interface MergeSessionsData {
+  primarySessionID: string;
+  sessionIDs: string[];
+  retagRequests?: boolean; // 项目冲突时也将请求改为主会话的项目
+}
+
+export interface MergeSessionsResult {
+  session: Session;
+  mergedSessions: number;
+  movedRequests: number;
+  retaggedRequests: number;
+  projectConflict: boolean;
+}
+
 // ===== Route =====
 
 export interface Route {
@@ -117,6 +301,9 @@ export interface Route {
   providerID: number;
   position: number;
   retryConfigID: number;
+  forceDetail?: boolean; // 强制保存该路由的请求/响应详情，不受详情保留设置影响
+  multiplier?: number; // 成本倍率覆盖（10000=1倍），优先于 Provider 倍率，0/未设置表示不覆盖
+  maxStreamDurationMs?: number; // 流式响应总时长上限（毫秒），优先于 Provider 配置，0/未设置表示不覆盖
   modelMapping?: Record<string, string>;
 }
 
@@ -127,6 +314,133 @@ export interface RoutePositionUpdate {
   position: number;
 }
 
+// 路由对比（POST /routes/compare）
+export interface CompareRoutesData {
+  requestIDs: number[]; // 最多 20 个，需保留请求详情
+  routeA: number;
+  routeB: number;
+}
+
+export interface RouteComparisonRun {
+  proxyRequestID: number; // 重放产生的请求记录 ID，0 表示未能发起
+  status: string;
+  error?: string;
+  durationMs: number;
+  ttftMs: number;
+  inputTokens: number;
+  outputTokens: number;
+  cost: number; // 纳美元
+}
+
+export interface RouteComparisonSample {
+  requestID: number; // 原始请求 ID
+  a: RouteComparisonRun;
+  b: RouteComparisonRun;
+  similarity: number; // 响应文本粗略相似度 0-1
+}
+
+export interface RouteComparisonSide {
+  routeID: number;
+  successful: number;
+  failed: number;
+  avgDurationMs: number;
+  avgTtftMs: number;
+  inputTokens: number;
+  outputTokens: number;
+  totalCost: number;
+}
+
+// Delta 字段均为 B - A
+export interface RouteComparisonReport {
+  comparisonTag: string;
+  a: RouteComparisonSide;
+  b: RouteComparisonSide;
+  samples: RouteComparisonSample[];
+  avgDurationDeltaMs: number;
+  outputTokensDelta: number;
+  costDelta: number;
+  avgSimilarity: number;
+}
+
+// 项目路由测试（POST /projects/{id}/test-routes），id 为 0 时测试全局路由
+export interface TestProjectRoutesData {
+  clientType: ClientType;
+  prompt?: string; // 为空时使用默认提示词
+}
+
+export interface RouteTestResult {
+  routeID: number;
+  position: number;
+  providerID: number;
+  providerName: string;
+  model: string; // 测试请求使用的模型
+  proxyRequestID: number; // 测试产生的请求记录 ID，0 表示未能发起
+  success: boolean;
+  status: string;
+  error?: string;
+  durationMs: number;
+  inputTokens: number;
+  outputTokens: number;
+  cost: number; // 纳美元
+}
+
+export interface ProjectRouteTestReport {
+  projectID: number;
+  clientType: ClientType;
+  projectRoutes: boolean; // false 表示测试的是全局路由
+  probeTag: string;
+  succeeded: number;
+  failed: number;
+  results: RouteTestResult[];
+}
+
+// ===== Routing debug =====
+
+// 假想请求，只解析路由决策，不会发往上游
+export interface ResolveRequestData {
+  clientType: ClientType;
+  projectID?: number;
+  token?: string;
+  model: string;
+  stream?: boolean;
+  headers?: Record<string, string>;
+}
+
+export interface ResolvedRoute {
+  position: number;
+  routeID: number;
+  providerID: number;
+  providerName: string;
+  providerType: string;
+  requestModel: string;
+  fallback: boolean;
+  mappedModel: string;
+  needsConversion: boolean;
+  targetClientType: ClientType;
+  upstreamStream: boolean;
+  retryConfig: RetryConfig | null;
+}
+
+export interface ResolvedProviderCooldown {
+  providerID: number;
+  providerName: string;
+  inCooldown: boolean;
+  until?: string;
+  reason?: string;
+}
+
+export interface RequestResolution {
+  clientType: ClientType;
+  projectID: number;
+  apiTokenID: number;
+  requestModel: string;
+  fallbackModels: string[] | null;
+  routes: ResolvedRoute[];
+  cooldowns: ResolvedProviderCooldown[];
+  trace: RoutingTrace;
+  error?: string;
+}
+
 // ===== RetryConfig =====
 
 export interface RetryConfig {
@@ -145,7 +459,7 @@ export type CreateRetryConfigData = Omit<RetryConfig, 'id' | 'createdAt' | 'upda
 
 // ===== RoutingStrategy =====
 
-export type RoutingStrategyType = 'priority' | 'weighted_random';
+export type RoutingStrategyType = 'priority' | 'weighted_random' | 'health_weighted';
 
 export interface RoutingStrategyConfig {
   // 扩展字段
@@ -217,12 +531,48 @@ export interface ProxyRequest {
   cacheWriteCount: number;
   cache5mWriteCount: number;
   cache1hWriteCount: number;
+  reasoningTokenCount: number; // 推理 tokens（已包含在 outputTokenCount 中）
   // 价格信息（来自最终 Attempt）
   modelPriceId: number; // 使用的模型价格记录ID
   multiplier: number; // 倍率（10000=1倍）
   cost: number;
   // API Token ID
   apiTokenID: number;
+  // 客户端 IP
+  clientIP: string;
+  // 是否计费（不计费请求不计入成本统计）
+  billable: boolean;
+  // 路由对比标记（仅路由对比的重放请求）
+  comparisonTag?: string;
+  // 命中的 A/B 模型实验及分配到的变体（requestModel 为客户端原始模型）
+  experiment?: string;
+  experimentVariant?: string;
+  nonStreamOverride?: boolean; // 客户端请求流式，被强制为非流式返回
+  // 路由决策记录（仅开启 routing_trace_enabled 时，且只在详情接口返回）
+  routingTrace?: RoutingTrace;
+  // 运维备注，清理详情后仍保留
+  notes?: string;
+}
+
+/** ProxyRequestSummary - 实时列表推送的精简请求（proxy_request_summary），详情需按需获取 */
+export type ProxyRequestSummary = Omit<
+  ProxyRequest,
+  'requestInfo' | 'responseInfo' | 'routingTrace' | 'notes'
+>;
+
+export type RoutingTraceAction = 'matched' | 'skipped' | 'failed' | 'selected';
+
+export interface RoutingTraceStep {
+  action: RoutingTraceAction;
+  routeId?: number;
+  providerId?: number;
+  model?: string;
+  reason?: string;
+}
+
+export interface RoutingTrace {
+  strategy: RoutingStrategyType;
+  steps: RoutingTraceStep[];
 }
 
 // ===== ProxyUpstreamAttempt =====
@@ -259,10 +609,19 @@ export interface ProxyUpstreamAttempt {
   cacheWriteCount: number;
   cache5mWriteCount: number;
   cache1hWriteCount: number;
+  reasoningTokenCount: number; // 推理 tokens（已包含在 outputTokenCount 中）
   // 价格信息
   modelPriceId: number; // 使用的模型价格记录ID
   multiplier: number; // 倍率（10000=1倍）
   cost: number;
+  maxTokensClampedFrom?: number; // 被 Provider 输出上限下调前的 max_tokens
+  strippedParams?: string[]; // 按 Provider 配置删除的请求参数
+  minifiedBytesSaved?: number; // 压缩请求体 JSON 节省的字节数
+  priceOverrideProviderID?: number; // 按该 Provider 的价格覆盖计费
+  outputTps?: number; // 流式输出速度（tokens/s），无法测量时为空
+  error?: string; // 失败原因（仅 FAILED）
+  statusCode?: number; // 失败时的上游状态码，0/未设置表示未收到响应
+  streamTruncated?: boolean; // 流式输出超过总时长上限被截断
 }
 
 // ===== 分页 =====
@@ -283,6 +642,30 @@ export interface CursorPaginationParams {
   providerId?: number;
   /** 按状态过滤 */
   status?: string;
+  /** 按客户端 IP 过滤 */
+  clientIp?: string;
+  /** 按 A/B 模型实验名称过滤 */
+  experiment?: string;
+}
+
+/** 批量删除请求的过滤条件（至少设置一项） */
+export interface DeleteProxyRequestsFilter {
+  /** created_at >= start (RFC3339) */
+  start?: string;
+  /** created_at < end (RFC3339) */
+  end?: string;
+  status?: string;
+  projectId?: number;
+  apiTokenId?: number;
+  providerId?: number;
+}
+
+/** 批量删除请求的结果 */
+export interface DeleteProxyRequestsResult {
+  deletedRequests: number;
+  deletedAttempts: number;
+  /** 还有匹配的请求未删除（超过单次上限），可再次调用 */
+  hasMore: boolean;
 }
 
 /** 游标分页响应 */
@@ -295,10 +678,19 @@ export interface CursorPaginationResult<T> {
   lastId?: number;
 }
 
+/** 请求 attempts 的分页结果，按开始时间、ID 排序 */
+export interface AttemptPaginationResult {
+  items: ProxyUpstreamAttempt[];
+  hasMore: boolean;
+  /** 下一页的游标，没有更多时为空 */
+  nextCursor?: number;
+}
+
 // ===== WebSocket 消息 =====
 
 export type WSMessageType =
   | 'proxy_request_update'
+  | 'proxy_request_summary'
   | 'proxy_upstream_attempt_update'
   | 'stats_update'
   | 'log_message'
@@ -309,6 +701,9 @@ export type WSMessageType =
   | 'cooldown_update'
   | 'recalculate_costs_progress'
   | 'recalculate_stats_progress'
+  | 'request_detail_cleanup_progress'
+  | 'cost_anomaly'
+  | 'breaker_metrics'
   | '_ws_reconnected'; // 内部事件：WebSocket 重连成功
 
 export interface WSMessage<T = unknown> {
@@ -352,6 +747,7 @@ export interface ProviderStats {
   totalCacheRead: number;
   totalCacheWrite: number;
   totalCost: number; // 微美元
+  clientTypeDisabled?: boolean; // 按 client_type 查询时，该 Provider 是否禁用了此 ClientType
 }
 
 // ===== Antigravity 相关 =====
@@ -558,6 +954,7 @@ export interface ImportResult {
   imported: number;
   skipped: number;
   errors: string[];
+  renamed?: Record<string, string>; // 原名称 -> 导入后的名称
 }
 
 // ===== Cooldown =====
@@ -584,6 +981,42 @@ export interface Cooldown {
   reason: CooldownReason;
 }
 
+/**
+ * 冷却历史事件 - 与 Go domain.CooldownEvent 同步
+ */
+export interface CooldownEvent {
+  id: number;
+  createdAt: string; // 事件时间（到期事件为冷却结束时间）
+  providerID: number;
+  clientType: string; // 空字符串表示全局冷却
+  type: 'enter' | 'exit';
+  reason: CooldownReason;
+  untilTime?: string; // enter 事件的冷却结束时间
+  durationMs: number; // enter：计划时长；exit：实际冷却时长
+  failureCount?: number; // enter 事件当时的失败计数
+  exitCause?: 'expired' | 'success' | 'cleared' | 'reset';
+}
+
+/**
+ * Provider 熔断指标（由冷却推导，最近 24 小时滚动窗口）- 与 Go cooldown.BreakerMetrics 同步
+ * 仅保存在内存中，重启后清零；WebSocket breaker_metrics 事件每 30 秒推送（不含 providerName）
+ */
+export interface BreakerMetrics {
+  providerID: number;
+  providerName?: string;
+  clientType?: string; // 空表示全局冷却
+  state: 'closed' | 'open' | 'half_open'; // half_open：冷却已到期，等待下一个请求的结果
+  windowMs: number; // 统计覆盖时长（重启后不足 24 小时）
+  openMs: number;
+  halfOpenMs: number;
+  closedMs: number;
+  trips: number;
+  tripsPerHour: number;
+  probes: number; // 结束半开状态的请求数
+  probeSuccesses: number;
+  probeSuccessRate: number; // 无探测时为 0
+}
+
 // ===== Auth 相关 =====
 
 export interface AuthStatus {
@@ -611,6 +1044,21 @@ export interface APIToken {
   expiresAt?: string;
   lastUsedAt?: string;
   useCount: number;
+  modelFallbacks?: ModelFallback[]; // 优先于项目配置
+  nonBillable?: boolean; // 不计费 Token，优先于项目配置
+  allowBillableOverride?: boolean; // 允许 X-Maxx-Billable 请求头覆盖计费标记
+  forceNonStream?: boolean; // 流式请求聚合为完整响应后一次性返回
+  allowStreamOverride?: boolean; // 允许 X-Maxx-Force-Non-Stream 请求头覆盖强制非流式
+  maxConcurrent?: number; // 同时进行中的请求数上限，0 表示不限制
+  modelQuotas?: TokenModelQuota[]; // 按周期的模型用量配额，用尽时返回 429
+}
+
+// API Token 在一个周期内对匹配模型的用量上限（周期边界按配置的时区）
+export interface TokenModelQuota {
+  model: string; // 模型名，支持通配符，空表示所有模型
+  period: 'day' | 'month';
+  maxRequests?: number; // 0 表示不限制
+  maxTokens?: number; // 输入 + 输出，0 表示不限制
 }
 
 export interface APITokenCreateResult {
@@ -646,10 +1094,13 @@ export interface UsageStats {
   failedRequests: number;
   totalDurationMs: number; // 累计请求耗时（毫秒）
   totalTtftMs: number; // 累计首字时长（毫秒）
+  tpsOutputTokens: number; // 测得 TPS 的 attempt 累计输出 tokens
+  tpsDurationMs: number; // 测得 TPS 的 attempt 累计首字到结束耗时（毫秒）
   inputTokens: number;
   outputTokens: number;
   cacheRead: number;
   cacheWrite: number;
+  reasoningTokens: number; // 推理 tokens（已包含在 outputTokens 中）
   cost: number;
 }
 
@@ -663,7 +1114,21 @@ export interface UsageStatsSummary {
   totalOutputTokens: number;
   totalCacheRead: number;
   totalCacheWrite: number;
+  totalReasoning: number; // 推理 tokens（已包含在 totalOutputTokens 中）
   totalCost: number; // 微美元
+  avgOutputTps: number; // 流式输出平均速度（tokens/s），无测量数据时为 0
+  cacheHitRatio: number; // 缓存命中率 cacheRead / (input + cacheRead)，0-1，无输入时为 0
+}
+
+// 模型成本异常事件（WebSocket: cost_anomaly）
+export interface CostAnomaly {
+  model: string;
+  detectedAt: string;
+  recentRequests: number;
+  recentAvgCost: number; // 纳美元
+  baselineRequests: number;
+  baselineAvgCost: number; // 纳美元
+  ratio: number;
 }
 
 export interface UsageStatsFilter {
@@ -686,6 +1151,90 @@ export interface RecalculateCostsResult {
   message: string;
 }
 
+/** CostDiscrepancy - 单个请求的成本偏差 */
+export interface CostDiscrepancy {
+  requestId: number;
+  storedCost: number; // 请求上记录的成本
+  attemptCost: number; // attempts 上记录的成本之和
+  computedCost: number; // 按计费时的价格记录和倍率重算的成本之和
+  driftAttempts: number;
+}
+
+/** ReconcileCostsResult - 请求成本核对汇总 */
+export interface ReconcileCostsResult {
+  since: string;
+  fix: boolean;
+  checkedRequests: number;
+  checkedAttempts: number;
+  discrepancies: number;
+  fixedRequests: number;
+  fixedAttempts: number;
+  storedCostTotal: number;
+  computedCostTotal: number;
+  samples: CostDiscrepancy[];
+  durationMs: number;
+}
+
+/** MultiplierUsage - 某 Provider 在某客户端类型下实际生效过的倍率 */
+export interface MultiplierUsage {
+  providerID: number;
+  clientType: ClientType;
+  multiplier: number; // 10000=1倍，0 表示旧记录未保存倍率
+  attempts: number;
+  cost: number; // 纳美元
+  firstSeen: string;
+  lastSeen: string;
+}
+
+/** ErrorGroup - 归一化错误信息和状态码相同的一组失败 attempt */
+export interface ErrorGroup {
+  pattern: string; // 归一化后的错误信息（ID、时间、数字等替换为占位符）
+  statusCode: number; // 上游状态码，0 表示未收到响应
+  count: number;
+  providerIDs: number[];
+  exampleRequestIDs: number[];
+  exampleError: string;
+  firstSeen: string;
+  lastSeen: string;
+}
+
+/** ErrorSummary - GET /admin/errors/summary */
+export interface ErrorSummary {
+  start: string;
+  end: string;
+  totalFailures: number;
+  truncated: boolean; // 失败数超过扫描上限，只统计了最近的部分
+  groups: ErrorGroup[];
+}
+
+/** ClientIPStats - GET /admin/client-ips 单个客户端 IP 的请求统计 */
+export interface ClientIPStats {
+  clientIP: string;
+  requests: number;
+  failed: number;
+  inputTokens: number;
+  outputTokens: number;
+  cost: number; // 纳美元，不含不计费请求
+  lastSeen: string;
+}
+
+/** AggregateStatsPhase - 手动聚合的单个阶段结果（也通过 stats_aggregate_phase 广播） */
+export interface AggregateStatsPhase {
+  phase: 'aggregate_minute' | 'rollup_hour' | 'rebuild_timezone' | 'rollup_day' | 'rollup_month';
+  from?: StatsGranularity;
+  to: StatsGranularity;
+  startTime: number; // unix ms
+  endTime: number; // unix ms
+  count: number;
+  error?: string;
+}
+
+/** AggregateStatsResult - 手动聚合结果 */
+export interface AggregateStatsResult {
+  phases: AggregateStatsPhase[];
+  durationMs: number;
+}
+
 /** RecalculateCostsProgress - 成本重算进度更新 */
 export interface RecalculateCostsProgress {
   phase: 'calculating' | 'updating_attempts' | 'updating_requests' | 'completed';
@@ -704,6 +1253,15 @@ export interface RecalculateStatsProgress {
   message: string;
 }
 
+/** RequestDetailCleanupProgress - 请求详情清理进度（积压较多时广播） */
+export interface RequestDetailCleanupProgress {
+  phase: 'clearing_requests' | 'clearing_attempts' | 'completed';
+  current: number;
+  total: number;
+  percentage: number;
+  message: string;
+}
+
 /** RecalculateRequestCostResult - 单条请求成本重算结果 */
 export interface RecalculateRequestCostResult {
   requestId: number;
@@ -713,6 +1271,13 @@ export interface RecalculateRequestCostResult {
   message: string;
 }
 
+/** WatchProxyRequestResult - 请求完整详情，watchUntil 前该请求的所有更新都会完整广播 */
+export interface WatchProxyRequestResult {
+  request: ProxyRequest;
+  attempts: ProxyUpstreamAttempt[];
+  watchUntil: string;
+}
+
 /** Response Model - 记录所有出现过的 response model */
 export interface ResponseModel {
   id: number;
@@ -754,6 +1319,7 @@ export interface BackupProvider {
   config?: ProviderConfig;
   supportedClientTypes?: ClientType[];
   supportModels?: string[];
+  disabledClientTypes?: ClientType[];
 }
 
 export interface BackupProject {
@@ -814,6 +1380,7 @@ export interface BackupModelMapping {
 export interface BackupImportOptions {
   conflictStrategy?: 'skip' | 'overwrite' | 'error';
   dryRun?: boolean;
+  renameOnConflict?: boolean; // Provider 重名时以 "name (2)" 导入，备份中的路由随之映射
 }
 
 /** 导入摘要 */
@@ -829,6 +1396,7 @@ export interface BackupImportResult {
   summary: Record<string, BackupImportSummary>;
   errors: string[];
   warnings: string[];
+  providerRenames?: Record<string, string>; // 备份中的 Provider 名称 -> 导入后的名称
 }
 
 // ===== Dashboard API Types =====
@@ -891,6 +1459,55 @@ export interface DashboardData {
   timezone: string; // 配置的时区，如 "Asia/Shanghai"
 }
 
+/** Dashboard Provider 当前生效的冷却 */
+export interface DashboardProviderCooldown {
+  providerID: number;
+  providerName?: string;
+  clientType?: string; // 空表示全部 clientType
+  until: string;
+  remaining: string;
+  reason: CooldownReason;
+}
+
+/** Dashboard Provider 状态 - 统计、冷却与配额合并返回 */
+export interface DashboardProviderStatus {
+  providerID: number;
+  name: string;
+  type: string;
+  draining?: boolean;
+  stats: DashboardProviderStats;
+  cooldowns: DashboardProviderCooldown[];
+  codexQuota?: {
+    email: string;
+    planType: string;
+    isForbidden: boolean;
+    updatedAt: string;
+    primaryWindow?: CodexUsageWindow;
+    secondaryWindow?: CodexUsageWindow;
+    codeReviewWindow?: CodexUsageWindow;
+  };
+  antigravityQuota?: {
+    email: string;
+    subscriptionTier: string;
+    isForbidden: boolean;
+    updatedAt: string;
+    models: AntigravityModelQuota[] | null;
+  };
+}
+
+/** RebuildResponseModelsResult - POST /admin/response-models/rebuild */
+export interface RebuildResponseModelsResult {
+  before: number; // 重建前的模型数
+  models: number; // 重建后的模型数
+}
+
+/** ConcurrencySample - 在途请求数采样（每 5 秒，保留 1 小时） */
+export interface ConcurrencySample {
+  time: string;
+  active: number;
+  projects?: Record<number, number>; // projectID -> 在途请求数，按项目过滤时省略
+}
+
 // ===== Pricing API Types =====
 
 /** 单个模型的价格配置 - 价格单位：微美元/百万tokens */
@@ -901,6 +1518,7 @@ export interface ModelPricing {
   cacheReadPriceMicro?: number; // 缓存读取价格，默认 input / 10
   cache5mWritePriceMicro?: number; // 5分钟缓存写入，默认 input * 1.25
   cache1hWritePriceMicro?: number; // 1小时缓存写入，默认 input * 2
+  reasoningPriceMicro?: number; // 推理 token 价格，默认按 output 价格
   has1mContext?: boolean; // 是否支持 1M context
   context1mThreshold?: number; // 1M context 阈值，默认 200000
   inputPremiumNum?: number; // 超阈值 input 倍率分子
@@ -927,6 +1545,7 @@ export interface ModelPrice {
   cacheReadPriceMicro: number;
   cache5mWritePriceMicro: number;
   cache1hWritePriceMicro: number;
+  reasoningPriceMicro: number;
   has1mContext: boolean;
   context1mThreshold: number;
   inputPremiumNum: number;
@@ -943,6 +1562,7 @@ export interface ModelPriceInput {
   cacheReadPriceMicro?: number;
   cache5mWritePriceMicro?: number;
   cache1hWritePriceMicro?: number;
+  reasoningPriceMicro?: number;
   has1mContext?: boolean;
   context1mThreshold?: number;
   inputPremiumNum?: number;
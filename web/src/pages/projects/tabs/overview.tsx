@@ -80,6 +80,21 @@ export function OverviewTab({ project }: OverviewTabProps) {
                 placeholder={t('projects.slugPlaceholder')}
               />
               <p className="text-xs text-text-muted">{t('projects.slugDesc')}</p>
+              {project.previousSlugs && project.previousSlugs.length > 0 && (
+                <p className="text-xs text-text-muted">
+                  {t('projects.previousSlugs')}{' '}
+                  <span className="font-mono">
+                    {project.previousSlugs
+                      .map((s) =>
+                        t('projects.previousSlugUntil', {
+                          slug: s.slug,
+                          date: new Date(s.expiresAt).toLocaleDateString(),
+                        }),
+                      )
+                      .join(', ')}
+                  </span>
+                </p>
+              )}
             </div>
           </div>
 
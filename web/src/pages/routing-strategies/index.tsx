@@ -129,6 +129,7 @@ export function RoutingStrategiesPage() {
                   >
                     <option value="priority">{t('routingStrategies.priorityByPosition')}</option>
                     <option value="weighted_random">{t('routingStrategies.weightedRandom')}</option>
+                    <option value="health_weighted">{t('routingStrategies.healthWeighted')}</option>
                   </select>
                 </div>
               </div>
@@ -179,7 +180,9 @@ export function RoutingStrategiesPage() {
                       <Badge variant={strategy.type === 'priority' ? 'info' : 'warning'}>
                         {strategy.type === 'priority'
                           ? t('routingStrategies.priority')
-                          : t('routingStrategies.weightedRandom')}
+                          : strategy.type === 'health_weighted'
+                            ? t('routingStrategies.healthWeighted')
+                            : t('routingStrategies.weightedRandom')}
                       </Badge>
                     </TableCell>
                     <TableCell>
@@ -30,6 +30,7 @@ import {
   SelectItem,
   SelectTrigger,
   SelectValue,
+  Progress,
   Tabs,
   TabsList,
   TabsTrigger,
@@ -38,7 +39,11 @@ import {
 import { PageHeader } from '@/components/layout/page-header';
 import { useSettings, useUpdateSetting, useDeleteSetting } from '@/hooks/queries';
 import { useTransport } from '@/lib/transport/context';
-import type { BackupFile, BackupImportResult } from '@/lib/transport/types';
+import type {
+  BackupFile,
+  BackupImportResult,
+  RequestDetailCleanupProgress,
+} from '@/lib/transport/types';
 import { getDefaultThemes, getLuxuryThemes } from '@/lib/theme';
 import { cn } from '@/lib/utils';
 
@@ -282,6 +287,25 @@ function DataRetentionSection() {
   const [requestDraft, setRequestDraft] = useState('');
   const [detailDraft, setDetailDraft] = useState('');
   const [initialized, setInitialized] = useState(false);
+  const [cleanupProgress, setCleanupProgress] = useState<RequestDetailCleanupProgress | null>(
+    null,
+  );
+  const { transport } = useTransport();
+
+  // Subscribe to request detail cleanup progress (broadcast for large backlogs)
+  useEffect(() => {
+    const unsubscribe = transport.subscribe<RequestDetailCleanupProgress>(
+      'request_detail_cleanup_progress',
+      (data) => {
+        setCleanupProgress(data);
+        // Clear progress after completion (with a delay to show final message)
+        if (data.phase === 'completed') {
+          setTimeout(() => setCleanupProgress(null), 3000);
+        }
+      },
+    );
+    return unsubscribe;
+  }, [transport]);
 
   useEffect(() => {
     if (!isLoading && !initialized) {
@@ -370,6 +394,15 @@ function DataRetentionSection() {
           <span className="text-xs text-muted-foreground">{t('common.seconds')}</span>
         </div>
         <p className="text-xs text-muted-foreground">{t('settings.requestDetailRetentionDesc')}</p>
+        {cleanupProgress && (
+          <div className="bg-muted/50 rounded-lg p-3 space-y-2">
+            <div className="flex items-center justify-between text-xs text-muted-foreground">
+              <span>{cleanupProgress.message}</span>
+              <span>{cleanupProgress.percentage}%</span>
+            </div>
+            <Progress value={cleanupProgress.percentage} className="h-2" />
+          </div>
+        )}
       </CardContent>
     </Card>
   );
@@ -488,6 +488,7 @@ export function StatsPage() {
         avgRpm: 0,
         avgTpm: 0,
         avgTtft: 0,
+        avgTps: 0,
       };
     }
 
@@ -504,6 +505,8 @@ export function StatsPage() {
         totalCost: acc.totalCost + s.cost,
         totalDurationMs: acc.totalDurationMs + s.totalDurationMs,
         totalTtftMs: acc.totalTtftMs + (s.totalTtftMs || 0),
+        tpsOutputTokens: acc.tpsOutputTokens + (s.tpsOutputTokens || 0),
+        tpsDurationMs: acc.tpsDurationMs + (s.tpsDurationMs || 0),
       }),
       {
         totalRequests: 0,
@@ -516,6 +519,8 @@ export function StatsPage() {
         totalCost: 0,
         totalDurationMs: 0,
         totalTtftMs: 0,
+        tpsOutputTokens: 0,
+        tpsDurationMs: 0,
       },
     );
 
@@ -531,6 +536,10 @@ export function StatsPage() {
       ? totals.totalTtftMs / totals.successfulRequests / 1000
       : 0;
 
+    // 流式输出平均速度 (tokens/s)，仅统计测得 TPS 的 attempt
+    const avgTps =
+      totals.tpsDurationMs > 0 ? (totals.tpsOutputTokens / totals.tpsDurationMs) * 1000 : 0;
+
     // 基于 totalDurationMs 计算 RPM 和 TPM
     // RPM = (totalRequests / totalDurationMs) * 60000
     // TPM = (totalTokens / totalDurationMs) * 60000
@@ -545,6 +554,7 @@ export function StatsPage() {
       avgRpm,
       avgTpm,
       avgTtft,
+      avgTps,
     };
   }, [stats]);
 
@@ -835,7 +845,7 @@ export function StatsPage() {
               <StatCard
                 title={t('stats.requests')}
                 value={summary.totalRequests.toLocaleString()}
-                subtitle={`${formatNumber(summary.avgRpm)} RPM · ${summary.avgTtft.toFixed(2)}s TTFT`}
+                subtitle={`${formatNumber(summary.avgRpm)} RPM · ${summary.avgTtft.toFixed(2)}s TTFT · ${summary.avgTps.toFixed(1)} tok/s`}
                 icon={Activity}
                 iconClassName="text-blue-600 dark:text-blue-400"
               />